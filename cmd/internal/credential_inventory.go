@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"code.cloudfoundry.org/cf-operator/pkg/kube/controllers"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/controllers/boshdeployment"
+	"code.cloudfoundry.org/quarks-utils/pkg/cmd"
+)
+
+const credentialInventoryFailedMessage = "credential-inventory command failed."
+
+// credentialInventoryCmd prints the credentials of a BOSH deployment
+var credentialInventoryCmd = &cobra.Command{
+	Use:   "credential-inventory [flags]",
+	Short: "Lists the credentials of a BOSH deployment",
+	Long: `Lists the credentials of a BOSH deployment.
+
+This will print a JSON array of the variables of the deployment's with-ops
+manifest to STDOUT, with their type, secret names, whether they are generated,
+external or synced from CredHub, and when they were last generated.
+Only the labels of the secrets are inspected, values are never printed.
+
+`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		deploymentNameFlagViperBind(cmd.Flags())
+		kubeConfigFlagViperBind(cmd.Flags())
+		namespaceFlagViperBind(cmd.Flags())
+		secretNamePrefixFlagViperBind(cmd.Flags())
+	},
+	RunE: func(_ *cobra.Command, args []string) error {
+		log = cmd.Logger()
+		defer log.Sync()
+
+		deploymentName, err := deploymentNameFlagValidation()
+		if err != nil {
+			return errors.Wrap(err, credentialInventoryFailedMessage)
+		}
+		namespace, err := namespaceFlagValidation()
+		if err != nil {
+			return errors.Wrap(err, credentialInventoryFailedMessage)
+		}
+		if err := secretNamePrefixFlagValidation(); err != nil {
+			return errors.Wrap(err, credentialInventoryFailedMessage)
+		}
+
+		restConfig, err := cmd.KubeConfig(log)
+		if err != nil {
+			return errors.Wrap(err, credentialInventoryFailedMessage)
+		}
+
+		scheme := runtime.NewScheme()
+		if err := clientgoscheme.AddToScheme(scheme); err != nil {
+			return errors.Wrap(err, credentialInventoryFailedMessage)
+		}
+		if err := controllers.AddToScheme(scheme); err != nil {
+			return errors.Wrap(err, credentialInventoryFailedMessage)
+		}
+		client, err := crc.New(restConfig, crc.Options{Scheme: scheme})
+		if err != nil {
+			return errors.Wrapf(err, "%s Creating kube client failed.", credentialInventoryFailedMessage)
+		}
+
+		credentials, err := boshdeployment.NewCredentialInventory(client, namespace).List(context.Background(), deploymentName)
+		if err != nil {
+			return errors.Wrap(err, credentialInventoryFailedMessage)
+		}
+
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(credentials)
+	},
+}
+
+func init() {
+	utilCmd.AddCommand(credentialInventoryCmd)
+
+	pf := credentialInventoryCmd.Flags()
+	argToEnv := map[string]string{}
+
+	deploymentNameFlagCobraSet(pf, argToEnv)
+	kubeConfigFlagCobraSet(pf, argToEnv)
+	namespaceFlagCobraSet(pf, argToEnv)
+	secretNamePrefixFlagCobraSet(pf, argToEnv)
+	cmd.AddEnvToUsage(credentialInventoryCmd, argToEnv)
+}
@@ -39,6 +39,7 @@ Also calculates and prints the BPM configurations for all BOSH jobs of that inst
 		instanceGroupFlagViperBind(cmd.Flags())
 		outputFilePathFlagViperBind(cmd.Flags())
 		initialRolloutFlagViperBind(cmd.Flags())
+		renderCacheDirFlagViperBind(cmd.Flags())
 	},
 
 	RunE: func(_ *cobra.Command, args []string) (err error) {
@@ -101,6 +102,10 @@ Also calculates and prints the BPM configurations for all BOSH jobs of that inst
 			return errors.Wrap(err, igFailedMessage)
 		}
 
+		if renderCacheDir := viper.GetString("render-cache-dir"); renderCacheDir != "" {
+			igr.UseRenderCache(manifest.NewRenderCache(afero.NewOsFs(), renderCacheDir))
+		}
+
 		err = igr.CollectQuarksLinks(filepath.Dir(converter.VolumeLinksPath))
 		if err != nil {
 			return errors.Wrapf(err, "%s failed to collect quarks links.", igFailedMessage)
@@ -179,5 +184,6 @@ func init() {
 	instanceGroupFlagCobraSet(pf, argToEnv)
 	outputFilePathFlagCobraSet(pf, argToEnv)
 	initialRolloutFlagCobraSet(pf, argToEnv)
+	renderCacheDirFlagCobraSet(pf, argToEnv)
 	cmd.AddEnvToUsage(instanceGroupCmd, argToEnv)
 }
@@ -0,0 +1,16 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// manifestCmd represents the manifest subcommand
+var manifestCmd = &cobra.Command{
+	Use:   "manifest",
+	Short: "Calls a manifest subcommand",
+	Long:  `Calls a subcommand, which transforms a BOSH manifest.`,
+}
+
+func init() {
+	rootCmd.AddCommand(manifestCmd)
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+	"code.cloudfoundry.org/quarks-utils/pkg/cmd"
+)
+
+const manifestScaleFailedMessage = "manifest scale command failed."
+
+// manifestScaleCmd overrides the instance counts of a manifest's instance groups
+var manifestScaleCmd = &cobra.Command{
+	Use:   "scale [flags]",
+	Short: "Overrides the instance counts of instance groups",
+	Long: `Overrides the instance counts of instance groups in a BOSH manifest:
+
+This will replace the instances of each instance group given by a
+'--set <instance group>=<instances>' flag, e.g. '--set api=1 --set db=3',
+and write the scaled manifest to STDOUT. No ops files are needed, e.g. to
+scale down non-critical instance groups in CI pipelines.
+
+`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		boshManifestFlagViperBind(cmd.Flags())
+	},
+	RunE: func(c *cobra.Command, args []string) error {
+		boshManifestPath, err := boshManifestFlagValidation()
+		if err != nil {
+			return errors.Wrap(err, manifestScaleFailedMessage)
+		}
+
+		sets, err := c.Flags().GetStringArray("set")
+		if err != nil {
+			return errors.Wrap(err, manifestScaleFailedMessage)
+		}
+		overrides, err := parseInstanceCounts(sets)
+		if err != nil {
+			return errors.Wrap(err, manifestScaleFailedMessage)
+		}
+
+		// the manifest may be gzip compressed
+		boshManifestBytes, err := manifest.ReadFile(boshManifestPath)
+		if err != nil {
+			return errors.Wrapf(err, "%s Reading file specified in the bosh-manifest-path flag failed", manifestScaleFailedMessage)
+		}
+		m, err := manifest.LoadYAML(boshManifestBytes)
+		if err != nil {
+			return errors.Wrapf(err, "%s Loading BOSH manifest failed", manifestScaleFailedMessage)
+		}
+
+		for name := range overrides {
+			if _, ok := m.InstanceGroups.InstanceGroupByName(name); !ok {
+				return errors.Errorf("%s Instance group '%s' doesn't exist in the manifest", manifestScaleFailedMessage, name)
+			}
+		}
+
+		scaled, err := m.OverrideInstanceCount(overrides).Marshal()
+		if err != nil {
+			return errors.Wrapf(err, "%s Marshalling scaled manifest failed", manifestScaleFailedMessage)
+		}
+
+		_, err = os.Stdout.Write(scaled)
+		return err
+	},
+}
+
+// parseInstanceCounts parses '<instance group>=<instances>' values
+func parseInstanceCounts(sets []string) (map[string]int, error) {
+	overrides := map[string]int{}
+	for _, set := range sets {
+		parts := strings.SplitN(set, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid instance count '%s', must be '<instance group>=<instances>'", set)
+		}
+		instances, err := strconv.Atoi(parts[1])
+		if err != nil || instances < 0 {
+			return nil, fmt.Errorf("invalid instance count '%s', instances must be a non-negative integer", set)
+		}
+		overrides[parts[0]] = instances
+	}
+	return overrides, nil
+}
+
+func init() {
+	manifestCmd.AddCommand(manifestScaleCmd)
+
+	pf := manifestScaleCmd.Flags()
+	argToEnv := map[string]string{}
+
+	boshManifestFlagCobraSet(pf, argToEnv)
+	pf.StringArray("set", []string{}, "Instance count of an instance group as '<instance group>=<instances>', can be repeated")
+	cmd.AddEnvToUsage(manifestScaleCmd, argToEnv)
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/controllers"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/controllers/boshdeployment"
+	"code.cloudfoundry.org/quarks-utils/pkg/cmd"
+)
+
+const reconcilePlanFailedMessage = "reconcile-plan command failed."
+
+// reconcilePlanCmd prints the changes the reconcile of a BOSHDeployment would apply
+var reconcilePlanCmd = &cobra.Command{
+	Use:   "reconcile-plan [flags]",
+	Short: "Lists the changes the reconcile of a BOSHDeployment would apply",
+	Long: `Lists the changes the reconcile of a BOSHDeployment would apply.
+
+This will run the reconcile of the BOSHDeployment in the given file against
+the cluster without writing to it, and print a JSON plan of the objects it
+would create, update or delete to STDOUT. Objects created later by the
+QuarksJobs of the deployment are not listed.
+
+`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		boshDeploymentFlagViperBind(cmd.Flags())
+		kubeConfigFlagViperBind(cmd.Flags())
+		namespaceFlagViperBind(cmd.Flags())
+		secretNamePrefixFlagViperBind(cmd.Flags())
+	},
+	RunE: func(_ *cobra.Command, args []string) error {
+		log = cmd.Logger()
+		defer log.Sync()
+
+		boshDeploymentPath, err := boshDeploymentFlagValidation()
+		if err != nil {
+			return errors.Wrap(err, reconcilePlanFailedMessage)
+		}
+		namespace, err := namespaceFlagValidation()
+		if err != nil {
+			return errors.Wrap(err, reconcilePlanFailedMessage)
+		}
+		if err := secretNamePrefixFlagValidation(); err != nil {
+			return errors.Wrap(err, reconcilePlanFailedMessage)
+		}
+
+		data, err := ioutil.ReadFile(boshDeploymentPath)
+		if err != nil {
+			return errors.Wrapf(err, "%s Reading BOSHDeployment file failed.", reconcilePlanFailedMessage)
+		}
+		proposed := &bdv1.BOSHDeployment{}
+		if err := yaml.Unmarshal(data, proposed); err != nil {
+			return errors.Wrapf(err, "%s Unmarshaling BOSHDeployment file failed.", reconcilePlanFailedMessage)
+		}
+		if proposed.Namespace != "" && proposed.Namespace != namespace {
+			return errors.Errorf("%s BOSHDeployment namespace '%s' doesn't match the namespace flag '%s'.", reconcilePlanFailedMessage, proposed.Namespace, namespace)
+		}
+
+		restConfig, err := cmd.KubeConfig(log)
+		if err != nil {
+			return errors.Wrap(err, reconcilePlanFailedMessage)
+		}
+
+		scheme := runtime.NewScheme()
+		if err := clientgoscheme.AddToScheme(scheme); err != nil {
+			return errors.Wrap(err, reconcilePlanFailedMessage)
+		}
+		if err := controllers.AddToScheme(scheme); err != nil {
+			return errors.Wrap(err, reconcilePlanFailedMessage)
+		}
+		client, err := crc.New(restConfig, crc.Options{Scheme: scheme})
+		if err != nil {
+			return errors.Wrapf(err, "%s Creating kube client failed.", reconcilePlanFailedMessage)
+		}
+		kclient, err := kubernetes.NewForConfig(restConfig)
+		if err != nil {
+			return errors.Wrapf(err, "%s Creating kube clientset failed.", reconcilePlanFailedMessage)
+		}
+
+		planner := boshdeployment.NewReconcilePlanner(client, scheme, boshdeployment.NewPodLogs(kclient), namespace)
+		plan, err := planner.Plan(context.Background(), proposed)
+		if err != nil {
+			return errors.Wrap(err, reconcilePlanFailedMessage)
+		}
+
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(plan)
+	},
+}
+
+func init() {
+	utilCmd.AddCommand(reconcilePlanCmd)
+
+	pf := reconcilePlanCmd.Flags()
+	argToEnv := map[string]string{}
+
+	boshDeploymentFlagCobraSet(pf, argToEnv)
+	kubeConfigFlagCobraSet(pf, argToEnv)
+	namespaceFlagCobraSet(pf, argToEnv)
+	secretNamePrefixFlagCobraSet(pf, argToEnv)
+	cmd.AddEnvToUsage(reconcilePlanCmd, argToEnv)
+}
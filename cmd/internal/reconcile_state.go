@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"code.cloudfoundry.org/cf-operator/pkg/kube/controllers"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/controllers/boshdeployment"
+	"code.cloudfoundry.org/quarks-utils/pkg/cmd"
+)
+
+const (
+	reconcileStateExportFailedMessage = "reconcile-state-export command failed."
+	reconcileStateImportFailedMessage = "reconcile-state-import command failed."
+)
+
+// reconcileStateExportCmd writes the reconcile state of the BOSHDeployments of a namespace to a file
+var reconcileStateExportCmd = &cobra.Command{
+	Use:   "reconcile-state-export [flags]",
+	Short: "Exports the reconcile state of the BOSHDeployments of a namespace",
+	Long: `Exports the reconcile state of the BOSHDeployments of a namespace.
+
+This will write the reconcile state of each BOSHDeployment in the namespace,
+the generation which produced its last desired manifest, the hash of its
+with-ops manifest, the version of its last desired manifest and its phase,
+as versioned JSON to the reconcile state file. Secret values are not exported.
+Use reconcile-state-import to restore it on a rebuilt cluster.
+
+`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		kubeConfigFlagViperBind(cmd.Flags())
+		namespaceFlagViperBind(cmd.Flags())
+		secretNamePrefixFlagViperBind(cmd.Flags())
+		reconcileStateFlagViperBind(cmd.Flags())
+	},
+	RunE: func(_ *cobra.Command, args []string) error {
+		log = cmd.Logger()
+		defer log.Sync()
+
+		namespace, err := namespaceFlagValidation()
+		if err != nil {
+			return errors.Wrap(err, reconcileStateExportFailedMessage)
+		}
+		if err := secretNamePrefixFlagValidation(); err != nil {
+			return errors.Wrap(err, reconcileStateExportFailedMessage)
+		}
+		reconcileStatePath, err := reconcileStateFlagValidation()
+		if err != nil {
+			return errors.Wrap(err, reconcileStateExportFailedMessage)
+		}
+
+		client, err := reconcileStateClient()
+		if err != nil {
+			return errors.Wrap(err, reconcileStateExportFailedMessage)
+		}
+
+		state, err := boshdeployment.NewReconcileStateStore(client, namespace).Export(context.Background())
+		if err != nil {
+			return errors.Wrap(err, reconcileStateExportFailedMessage)
+		}
+
+		data, err := json.MarshalIndent(state, "", "  ")
+		if err != nil {
+			return errors.Wrapf(err, "%s Marshaling reconcile state failed.", reconcileStateExportFailedMessage)
+		}
+		if err := ioutil.WriteFile(reconcileStatePath, data, 0600); err != nil {
+			return errors.Wrapf(err, "%s Writing reconcile state file failed.", reconcileStateExportFailedMessage)
+		}
+
+		log.Infof("Exported the reconcile state of %d BOSHDeployments to '%s'", len(state.Deployments), reconcileStatePath)
+		return nil
+	},
+}
+
+// reconcileStateImportCmd restores the reconcile state of the BOSHDeployments of a namespace from a file
+var reconcileStateImportCmd = &cobra.Command{
+	Use:   "reconcile-state-import [flags]",
+	Short: "Imports the reconcile state of the BOSHDeployments of a namespace",
+	Long: `Imports the reconcile state of the BOSHDeployments of a namespace.
+
+This will read a reconcile state file written by reconcile-state-export and
+record the state of each BOSHDeployment in its status.restoredState.
+The BOSHDeployments have to be restored from a backup first, deployments
+which don't exist are skipped.
+
+`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		kubeConfigFlagViperBind(cmd.Flags())
+		namespaceFlagViperBind(cmd.Flags())
+		secretNamePrefixFlagViperBind(cmd.Flags())
+		reconcileStateFlagViperBind(cmd.Flags())
+	},
+	RunE: func(_ *cobra.Command, args []string) error {
+		log = cmd.Logger()
+		defer log.Sync()
+
+		namespace, err := namespaceFlagValidation()
+		if err != nil {
+			return errors.Wrap(err, reconcileStateImportFailedMessage)
+		}
+		if err := secretNamePrefixFlagValidation(); err != nil {
+			return errors.Wrap(err, reconcileStateImportFailedMessage)
+		}
+		reconcileStatePath, err := reconcileStateFlagValidation()
+		if err != nil {
+			return errors.Wrap(err, reconcileStateImportFailedMessage)
+		}
+
+		data, err := ioutil.ReadFile(reconcileStatePath)
+		if err != nil {
+			return errors.Wrapf(err, "%s Reading reconcile state file failed.", reconcileStateImportFailedMessage)
+		}
+		state := &boshdeployment.ReconcileState{}
+		if err := json.Unmarshal(data, state); err != nil {
+			return errors.Wrapf(err, "%s Unmarshaling reconcile state file failed.", reconcileStateImportFailedMessage)
+		}
+
+		client, err := reconcileStateClient()
+		if err != nil {
+			return errors.Wrap(err, reconcileStateImportFailedMessage)
+		}
+
+		skipped, err := boshdeployment.NewReconcileStateStore(client, namespace).Import(context.Background(), state)
+		if err != nil {
+			return errors.Wrap(err, reconcileStateImportFailedMessage)
+		}
+		for _, name := range skipped {
+			log.Infof("Skipped BOSHDeployment '%s', it doesn't exist in namespace '%s'", name, namespace)
+		}
+
+		log.Infof("Imported the reconcile state of %d BOSHDeployments", len(state.Deployments)-len(skipped))
+		return nil
+	},
+}
+
+// reconcileStateClient returns a client for BOSHDeployments and secrets
+func reconcileStateClient() (crc.Client, error) {
+	restConfig, err := cmd.KubeConfig(log)
+	if err != nil {
+		return nil, err
+	}
+
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	if err := controllers.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	client, err := crc.New(restConfig, crc.Options{Scheme: scheme})
+	if err != nil {
+		return nil, errors.Wrap(err, "Creating kube client failed.")
+	}
+	return client, nil
+}
+
+func init() {
+	utilCmd.AddCommand(reconcileStateExportCmd)
+
+	pf := reconcileStateExportCmd.Flags()
+	argToEnv := map[string]string{}
+
+	kubeConfigFlagCobraSet(pf, argToEnv)
+	namespaceFlagCobraSet(pf, argToEnv)
+	secretNamePrefixFlagCobraSet(pf, argToEnv)
+	reconcileStateFlagCobraSet(pf, argToEnv)
+	cmd.AddEnvToUsage(reconcileStateExportCmd, argToEnv)
+
+	utilCmd.AddCommand(reconcileStateImportCmd)
+
+	pf = reconcileStateImportCmd.Flags()
+	argToEnv = map[string]string{}
+
+	kubeConfigFlagCobraSet(pf, argToEnv)
+	namespaceFlagCobraSet(pf, argToEnv)
+	secretNamePrefixFlagCobraSet(pf, argToEnv)
+	reconcileStateFlagCobraSet(pf, argToEnv)
+	cmd.AddEnvToUsage(reconcileStateImportCmd, argToEnv)
+}
@@ -16,9 +16,18 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/manager/signals"
 
+	"code.cloudfoundry.org/cf-operator/pkg/bosh/bpmconverter"
+	"code.cloudfoundry.org/cf-operator/pkg/bosh/qjobs"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/controllers/boshdeployment"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/controllers/quarkssecret"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/operator"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/audit"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/backpressure"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/util/boshdns"
+	bdnames "code.cloudfoundry.org/cf-operator/pkg/kube/util/names"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/util/operatorimage"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/watchdog"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/withops"
 	"code.cloudfoundry.org/cf-operator/version"
 	"code.cloudfoundry.org/quarks-utils/pkg/cmd"
 	"code.cloudfoundry.org/quarks-utils/pkg/config"
@@ -72,8 +81,154 @@ var rootCmd = &cobra.Command{
 			return wrapError(errors.New("watched namespace cannot be the same as the operators namespace"), "")
 		}
 
+		err = audit.SetupLogger(viper.GetString("audit-log-output"))
+		if err != nil {
+			return wrapError(err, "")
+		}
+
 		boshdns.SetBoshDNSDockerImage(viper.GetString("bosh-dns-docker-image"))
+		bpmconverter.SetBPMDebugImage(viper.GetString("bpm-debug-image"))
 		boshdns.SetClusterDomain(viper.GetString("cluster-domain"))
+		qjobs.SetRenderCacheClaim(viper.GetString("render-cache-claim"))
+		qjobs.SetTrustedCABundle(viper.GetString("trusted-ca-bundle-secret"))
+		withops.SetTrustedCABundle(viper.GetString("trusted-ca-bundle-secret"))
+
+		err = boshdeployment.SetEmptyPodIPPolicy(viper.GetString("link-empty-pod-ip-policy"))
+		if err != nil {
+			return wrapError(err, "")
+		}
+
+		err = boshdeployment.SetChangeWindow(viper.GetString("change-window"))
+		if err != nil {
+			return wrapError(err, "")
+		}
+
+		err = boshdeployment.SetQJobConflictRequeue(viper.GetDuration("boshdeployment-qjob-conflict-requeue-after"), viper.GetInt("boshdeployment-qjob-conflict-attempts"))
+		if err != nil {
+			return wrapError(err, "")
+		}
+
+		err = boshdeployment.SetStatusUpdateAttempts(viper.GetInt("boshdeployment-status-update-attempts"))
+		if err != nil {
+			return wrapError(err, "")
+		}
+
+		err = boshdeployment.SetVariableWorkers(viper.GetInt("boshdeployment-variable-workers"))
+		if err != nil {
+			return wrapError(err, "")
+		}
+
+		err = boshdeployment.SetManifestNormalization(viper.GetString("manifest-normalization"))
+		if err != nil {
+			return wrapError(err, "")
+		}
+
+		err = boshdeployment.SetInterpolationTimeout(viper.GetDuration("interpolation-timeout"), viper.GetString("interpolation-timeout-action"))
+		if err != nil {
+			return wrapError(err, "")
+		}
+
+		secretNamer, err := bdnames.NewSecretNamer(viper.GetString("secret-name-prefix"))
+		if err != nil {
+			return wrapError(err, "")
+		}
+
+		err = boshdeployment.SetSecretNamer(secretNamer)
+		if err != nil {
+			return wrapError(err, "")
+		}
+
+		err = withops.SetMaxManifestDepth(viper.GetInt("max-manifest-depth"))
+		if err != nil {
+			return wrapError(err, "")
+		}
+
+		err = withops.SetTrustedOpsKeys(viper.GetString("trusted-ops-keys"))
+		if err != nil {
+			return wrapError(err, "")
+		}
+
+		err = boshdeployment.SetCloudTagAnnotations(viper.GetString("cloud-tag-annotations"))
+		if err != nil {
+			return wrapError(err, "")
+		}
+
+		err = boshdeployment.SetDeprecatedAPIVersions(viper.GetString("deprecated-api-versions"))
+		if err != nil {
+			return wrapError(err, "")
+		}
+
+		err = boshdeployment.SetEnvironmentProfiles(viper.GetString("environment-profiles"))
+		if err != nil {
+			return wrapError(err, "")
+		}
+
+		err = boshdeployment.SetStorageClassMapping(viper.GetString("storage-class-mapping"))
+		if err != nil {
+			return wrapError(err, "")
+		}
+
+		err = boshdeployment.SetTenantQuotas(viper.GetString("tenant-quotas"))
+		if err != nil {
+			return wrapError(err, "")
+		}
+
+		err = boshdeployment.SetGitPollInterval(viper.GetDuration("git-poll-interval"))
+		if err != nil {
+			return wrapError(err, "")
+		}
+
+		err = boshdeployment.SetLinkCycleCheckInterval(viper.GetDuration("link-cycle-check-interval"))
+		if err != nil {
+			return wrapError(err, "")
+		}
+
+		err = boshdeployment.SetLinkDrainPeriod(viper.GetDuration("link-drain-period"))
+		if err != nil {
+			return wrapError(err, "")
+		}
+
+		err = boshdeployment.SetConfigServer(viper.GetString("config-server-endpoint"), viper.GetString("config-server-client-secret"))
+		if err != nil {
+			return wrapError(err, "")
+		}
+
+		err = boshdeployment.SetExternalLinkPublisher(viper.GetString("external-link-publisher-endpoint"), viper.GetString("external-link-publisher-client-secret"))
+		if err != nil {
+			return wrapError(err, "")
+		}
+
+		err = boshdeployment.SetPhaseNotification(viper.GetString("phase-notification-url"), viper.GetDuration("phase-notification-timeout"), viper.GetInt("phase-notification-retries"))
+		if err != nil {
+			return wrapError(err, "")
+		}
+
+		err = quarkssecret.SetCredHub(viper.GetString("credhub-url"), viper.GetString("credhub-client-secret"), viper.GetDuration("credhub-sync-interval"))
+		if err != nil {
+			return wrapError(err, "")
+		}
+
+		err = watchdog.SetReconcileWarnThreshold(viper.GetDuration("reconcile-warn-threshold"))
+		if err != nil {
+			return wrapError(err, "")
+		}
+
+		err = backpressure.SetBackpressure(viper.GetDuration("backpressure-latency-threshold"), viper.GetFloat64("backpressure-factor"), viper.GetDuration("backpressure-max-delay"))
+		if err != nil {
+			return wrapError(err, "")
+		}
+		backpressure.WrapTransport(restConfig)
+
+		err = boshdeployment.SetReconcilePreviewTimeout(viper.GetDuration("operator-webhook-reconcile-preview-timeout"))
+		if err != nil {
+			return wrapError(err, "")
+		}
+
+		boshdeployment.SetLinkNetworkPolicyCheck(viper.GetBool("link-network-policy-check"))
+
+		boshdeployment.SetReportNoOpOps(viper.GetBool("report-no-op-ops-files"))
+
+		boshdeployment.SetStagingCluster(viper.GetString("staging-kubeconfig"), viper.GetString("staging-context"))
 
 		log.Infof("Starting cf-operator %s with namespace %s", version.Version, cfg.Namespace)
 		log.Infof("cf-operator docker image: %s", config.GetOperatorDockerImage())
@@ -105,7 +260,7 @@ var rootCmd = &cobra.Command{
 
 		mgr, err := operator.NewManager(ctx, cfg, restConfig, manager.Options{
 			Namespace:          cfg.Namespace,
-			MetricsBindAddress: "0",
+			MetricsBindAddress: viper.GetString("metrics-bind-address"),
 			LeaderElection:     false,
 			Port:               managerPort,
 			Host:               "0.0.0.0",
@@ -151,36 +306,168 @@ func init() {
 	cmd.DockerImageFlags(pf, argToEnv, "cf-operator", version.Version)
 	cmd.ApplyCRDsFlags(pf, argToEnv)
 
+	pf.String("audit-log-output", "", "Path of the audit log of all write operations, 'stdout' or 'stderr', empty disables audit logs")
+	pf.Float64("backpressure-factor", backpressure.DefaultFactor, "Factor, by which requeue delays of reconciles are scaled while the API server latency exceeds the backpressure threshold")
+	pf.Duration("backpressure-latency-threshold", 0, "Average API server latency, above which requeue delays of reconciles are increased, zero disables the backpressure")
+	pf.Duration("backpressure-max-delay", backpressure.DefaultMaxDelay, "Upper limit of requeue delays increased by the backpressure")
 	pf.StringP("bosh-dns-docker-image", "", "coredns/coredns:1.6.3", "The docker image used for emulating bosh DNS (a CoreDNS image)")
+	pf.Int("boshdeployment-qjob-conflict-attempts", 5, "Number of consecutive conflicts when updating the QuarksJobs of a BOSHDeployment, which are requeued fast, before they are reported as errors")
+	pf.Duration("boshdeployment-qjob-conflict-requeue-after", time.Second, "Delay before a BOSHDeployment reconcile is retried, whose QuarksJob update conflicted with a concurrent change")
+	pf.Int("boshdeployment-status-update-attempts", 4, "Number of attempts to update the status of a BOSHDeployment on conflicts")
+	pf.Int("boshdeployment-variable-workers", 1, "Number of QuarksSecrets, which are created or updated concurrently for the variables of a BOSH deployment")
+	pf.String("bpm-debug-image", "ubuntu:22.04", "The docker image of the 'bpm-debug' container, which is added to instance group pods of BOSH deployments with the enableBPMDebug feature")
+	pf.String("change-window", "", "Daily UTC time window like '22:00-06:00', in which BOSH deployment changes are applied and instance groups are rolled out, empty means always")
+	pf.String("cloud-tag-annotations", "", "Path to a YAML file mapping the keys of BOSH deployment tags to lists of cloud provider specific annotation keys, empty disables the mapping")
 	pf.String("cluster-domain", "cluster.local", "The Kubernetes cluster domain")
+	pf.String("config-server-client-secret", "", "Name of the secret in the watched namespace with the mTLS client certificate for the BOSH Config Server (keys tls.crt, tls.key and ca.crt)")
+	pf.String("config-server-endpoint", "", "URL of the BOSH Config Server, to which the values of BOSH deployment variables are synced, empty disables the sync")
+	pf.String("credhub-client-secret", "", "Name of the secret in the watched namespace with the mTLS client certificate for CredHub (keys tls.crt, tls.key and ca.crt)")
+	pf.Duration("credhub-sync-interval", 5*time.Minute, "Interval in which credhub variables are synced from CredHub")
+	pf.String("credhub-url", "", "URL of the CredHub server, from which credhub variables are synced, empty disables the sync")
+	pf.String("deprecated-api-versions", "", "Comma separated API versions of BOSHDeployments like 'quarks.cloudfoundry.org/v1alpha1', whose use is reported with warning events and a metric, empty disables the report")
+	pf.String("environment-profiles", "", "Path to a YAML file mapping the environments of the environment annotation to policy profiles, empty disables the profiles")
+	pf.String("external-link-publisher-client-secret", "", "Name of the secret in the watched namespace with the mTLS client certificate for the external link publisher's Config Server (keys tls.crt, tls.key and ca.crt)")
+	pf.String("external-link-publisher-endpoint", "", "URL of the BOSH Config Server, to which the links of kube native providers are published for consumers outside of Kubernetes, empty disables publishing")
+	pf.Duration("git-poll-interval", 5*time.Minute, "Interval in which git manifest and ops references, which are not pinned to a commit, are fetched again, zero disables polling")
+	pf.Duration("interpolation-timeout", 0, "Time a variable interpolation job may be active before it is recovered, zero disables the timeout")
+	pf.String("interpolation-timeout-action", "recreate", "Recovery of variable interpolation jobs exceeding the timeout: 'recreate' or 'degrade'")
+	pf.Duration("link-cycle-check-interval", 0, "Interval in which the links between BOSH deployments of a namespace are checked for cycles, zero disables the check")
+	pf.Duration("link-drain-period", 0, "Time consumers of kube native link providers get to stop using a removed provider pod, which is rendered as draining until then, zero disables draining")
+	pf.String("link-empty-pod-ip-policy", "error", "How link resolution handles pods without an IP: 'error', 'skip' or 'wait'")
+	pf.Bool("link-network-policy-check", false, "If true, links of BOSH deployments, whose traffic is likely blocked by network policies, are reported as warning events")
+	pf.String("manifest-normalization", "addons,releases,stemcells,variables", "Comma separated manifest sections, whose order is ignored when detecting manifest changes")
 	pf.Int("max-boshdeployment-workers", 1, "Maximum number of workers concurrently running BOSHDeployment controller")
+	pf.Int("max-manifest-depth", withops.DefaultMaxManifestDepth, "Maximum nesting depth of maps and lists in resolved BOSH manifests, zero disables the check")
 	pf.Int("max-quarks-secret-workers", 5, "Maximum number of workers concurrently running QuarksSecret controller")
 	pf.Int("max-quarks-statefulset-workers", 1, "Maximum number of workers concurrently running QuarksStatefulSet controller")
+	pf.String("metrics-bind-address", "0", "Address like ':8080', on which the operator serves its Prometheus metrics, '0' disables the metrics endpoint")
+	pf.Duration("operator-webhook-reconcile-preview-timeout", 0, "Timeout of the reconcile preview, which the BOSH deployment validating webhook runs to reject changes whose manifest, cloud config, trigger manifest or variables can't be resolved, links are not resolved, zero disables the preview")
 	pf.StringP("operator-webhook-service-host", "w", "", "Hostname/IP under which the webhook server can be reached from the cluster")
 	pf.StringP("operator-webhook-service-port", "p", "2999", "Port the webhook server listens on")
 	pf.BoolP("operator-webhook-use-service-reference", "x", false, "If true the webhook service is targeted using a service reference instead of a URL")
+	pf.Int("phase-notification-retries", 3, "Number of retries of failed phase transition notifications")
+	pf.Duration("phase-notification-timeout", 5*time.Second, "Timeout of each phase transition notification request")
+	pf.String("phase-notification-url", "", "URL of a webhook, to which phase transitions of BOSH deployments are posted, empty disables the notifications")
+	pf.Duration("reconcile-warn-threshold", watchdog.DefaultReconcileWarnThreshold, "Duration of a reconcile, after which it is reported as slow with a goroutine stack dump and a SlowReconcile event, zero disables the watchdog")
+	pf.String("render-cache-claim", "", "Name of a persistent volume claim in the watched namespace, on which instance group manifest jobs cache rendered templates, empty disables the cache")
+	pf.Bool("report-no-op-ops-files", false, "If true, ops files of BOSH deployments, which don't change the manifest, are reported as warning events")
+	pf.String("secret-name-prefix", "", "Prefix of the names of the secrets, which are generated for BOSH deployments, empty keeps the default names")
+	pf.String("staging-context", "", "Context in the staging kubeconfig, empty means its current context")
+	pf.String("staging-kubeconfig", "", "Path to a kubeconfig of a staging cluster, on which BOSH deployment changes are validated in dry-run mode")
+	pf.String("storage-class-mapping", "", "Path to a YAML file mapping the persistent disk types of instance groups to StorageClass names, which are checked to exist before BOSH deployments are rolled out, empty disables the check")
+	pf.String("tenant-quotas", "", "Path to a YAML file mapping the tenants of the namespace tenant label to quotas of BOSH deployments, empty disables the quotas")
+	pf.String("trusted-ca-bundle-secret", "", "Name of a secret in the watched namespace, whose CA bundle in the 'ca.crt' key is trusted when the operator and the generated jobs fetch URL and git references, empty disables the CA bundle")
+	pf.String("trusted-ops-keys", "", "Path to a file of PEM encoded ed25519 public keys, whose signatures ops files of BOSH deployments require, empty disables the verification")
 
 	for _, name := range []string{
+		"audit-log-output",
+		"backpressure-factor",
+		"backpressure-latency-threshold",
+		"backpressure-max-delay",
 		"bosh-dns-docker-image",
+		"boshdeployment-qjob-conflict-attempts",
+		"boshdeployment-qjob-conflict-requeue-after",
+		"boshdeployment-status-update-attempts",
+		"boshdeployment-variable-workers",
+		"bpm-debug-image",
+		"change-window",
+		"cloud-tag-annotations",
 		"cluster-domain",
+		"config-server-client-secret",
+		"config-server-endpoint",
+		"credhub-client-secret",
+		"credhub-sync-interval",
+		"credhub-url",
+		"deprecated-api-versions",
+		"environment-profiles",
+		"external-link-publisher-client-secret",
+		"external-link-publisher-endpoint",
+		"git-poll-interval",
+		"interpolation-timeout",
+		"interpolation-timeout-action",
+		"link-cycle-check-interval",
+		"link-drain-period",
+		"link-empty-pod-ip-policy",
+		"link-network-policy-check",
+		"manifest-normalization",
 		"max-boshdeployment-workers",
+		"max-manifest-depth",
 		"max-quarks-secret-workers",
 		"max-quarks-statefulset-workers",
+		"metrics-bind-address",
+		"operator-webhook-reconcile-preview-timeout",
 		"operator-webhook-service-host",
 		"operator-webhook-service-port",
 		"operator-webhook-use-service-reference",
+		"phase-notification-retries",
+		"phase-notification-timeout",
+		"phase-notification-url",
+		"reconcile-warn-threshold",
+		"render-cache-claim",
+		"report-no-op-ops-files",
+		"secret-name-prefix",
+		"staging-context",
+		"staging-kubeconfig",
+		"storage-class-mapping",
+		"tenant-quotas",
+		"trusted-ca-bundle-secret",
+		"trusted-ops-keys",
 	} {
 		viper.BindPFlag(name, pf.Lookup(name))
 	}
 
+	argToEnv["audit-log-output"] = "AUDIT_LOG_OUTPUT"
+	argToEnv["backpressure-factor"] = "BACKPRESSURE_FACTOR"
+	argToEnv["backpressure-latency-threshold"] = "BACKPRESSURE_LATENCY_THRESHOLD"
+	argToEnv["backpressure-max-delay"] = "BACKPRESSURE_MAX_DELAY"
 	argToEnv["bosh-dns-docker-image"] = "BOSH_DNS_DOCKER_IMAGE"
+	argToEnv["boshdeployment-qjob-conflict-attempts"] = "BOSHDEPLOYMENT_QJOB_CONFLICT_ATTEMPTS"
+	argToEnv["boshdeployment-qjob-conflict-requeue-after"] = "BOSHDEPLOYMENT_QJOB_CONFLICT_REQUEUE_AFTER"
+	argToEnv["boshdeployment-status-update-attempts"] = "BOSHDEPLOYMENT_STATUS_UPDATE_ATTEMPTS"
+	argToEnv["boshdeployment-variable-workers"] = "BOSHDEPLOYMENT_VARIABLE_WORKERS"
+	argToEnv["bpm-debug-image"] = "BPM_DEBUG_IMAGE"
+	argToEnv["change-window"] = "CHANGE_WINDOW"
+	argToEnv["cloud-tag-annotations"] = "CLOUD_TAG_ANNOTATIONS"
 	argToEnv["cluster-domain"] = "CLUSTER_DOMAIN"
+	argToEnv["config-server-client-secret"] = "CONFIG_SERVER_CLIENT_SECRET"
+	argToEnv["config-server-endpoint"] = "CONFIG_SERVER_ENDPOINT"
+	argToEnv["credhub-client-secret"] = "CREDHUB_CLIENT_SECRET"
+	argToEnv["credhub-sync-interval"] = "CREDHUB_SYNC_INTERVAL"
+	argToEnv["credhub-url"] = "CREDHUB_URL"
+	argToEnv["deprecated-api-versions"] = "DEPRECATED_API_VERSIONS"
+	argToEnv["environment-profiles"] = "ENVIRONMENT_PROFILES"
+	argToEnv["external-link-publisher-client-secret"] = "EXTERNAL_LINK_PUBLISHER_CLIENT_SECRET"
+	argToEnv["external-link-publisher-endpoint"] = "EXTERNAL_LINK_PUBLISHER_ENDPOINT"
+	argToEnv["git-poll-interval"] = "GIT_POLL_INTERVAL"
+	argToEnv["interpolation-timeout"] = "INTERPOLATION_TIMEOUT"
+	argToEnv["interpolation-timeout-action"] = "INTERPOLATION_TIMEOUT_ACTION"
+	argToEnv["link-cycle-check-interval"] = "LINK_CYCLE_CHECK_INTERVAL"
+	argToEnv["link-drain-period"] = "LINK_DRAIN_PERIOD"
+	argToEnv["link-empty-pod-ip-policy"] = "LINK_EMPTY_POD_IP_POLICY"
+	argToEnv["link-network-policy-check"] = "LINK_NETWORK_POLICY_CHECK"
+	argToEnv["manifest-normalization"] = "MANIFEST_NORMALIZATION"
 	argToEnv["max-boshdeployment-workers"] = "MAX_BOSHDEPLOYMENT_WORKERS"
+	argToEnv["max-manifest-depth"] = "MAX_MANIFEST_DEPTH"
 	argToEnv["max-quarks-secret-workers"] = "MAX_QUARKS_SECRET_WORKERS"
 	argToEnv["max-quarks-statefulset-workers"] = "MAX_QUARKS_STATEFULSET_WORKERS"
+	argToEnv["metrics-bind-address"] = "METRICS_BIND_ADDRESS"
+	argToEnv["operator-webhook-reconcile-preview-timeout"] = "CF_OPERATOR_WEBHOOK_RECONCILE_PREVIEW_TIMEOUT"
 	argToEnv["operator-webhook-service-host"] = "CF_OPERATOR_WEBHOOK_SERVICE_HOST"
 	argToEnv["operator-webhook-service-port"] = "CF_OPERATOR_WEBHOOK_SERVICE_PORT"
 	argToEnv["operator-webhook-use-service-reference"] = "CF_OPERATOR_WEBHOOK_USE_SERVICE_REFERENCE"
+	argToEnv["phase-notification-retries"] = "PHASE_NOTIFICATION_RETRIES"
+	argToEnv["phase-notification-timeout"] = "PHASE_NOTIFICATION_TIMEOUT"
+	argToEnv["phase-notification-url"] = "PHASE_NOTIFICATION_URL"
+	argToEnv["reconcile-warn-threshold"] = "RECONCILE_WARN_THRESHOLD"
+	argToEnv["render-cache-claim"] = "RENDER_CACHE_CLAIM"
+	argToEnv["report-no-op-ops-files"] = "REPORT_NO_OP_OPS_FILES"
+	argToEnv["secret-name-prefix"] = "SECRET_NAME_PREFIX"
+	argToEnv["staging-context"] = "STAGING_CONTEXT"
+	argToEnv["staging-kubeconfig"] = "STAGING_KUBECONFIG"
+	argToEnv["storage-class-mapping"] = "STORAGE_CLASS_MAPPING"
+	argToEnv["tenant-quotas"] = "TENANT_QUOTAS"
+	argToEnv["trusted-ca-bundle-secret"] = "TRUSTED_CA_BUNDLE_SECRET"
+	argToEnv["trusted-ops-keys"] = "TRUSTED_OPS_KEYS"
 
 	// Add env variables to help
 	cmd.AddEnvToUsage(rootCmd, argToEnv)
@@ -5,6 +5,9 @@ import (
 	"github.com/spf13/cobra"
 	flag "github.com/spf13/pflag"
 	"github.com/spf13/viper"
+
+	"code.cloudfoundry.org/cf-operator/pkg/kube/controllers/boshdeployment"
+	bdnames "code.cloudfoundry.org/cf-operator/pkg/kube/util/names"
 )
 
 // UtilCmd represents the util subcommand
@@ -53,6 +56,23 @@ func baseDirFlagViperBind(pf *flag.FlagSet) {
 	viper.BindPFlag("base-dir", pf.Lookup("base-dir"))
 }
 
+func boshDeploymentFlagValidation() (string, error) {
+	boshDeploymentPath := viper.GetString("bosh-deployment-path")
+	if len(boshDeploymentPath) == 0 {
+		return "", errors.New("bosh-deployment-path flag is empty")
+	}
+	return boshDeploymentPath, nil
+}
+
+func boshDeploymentFlagCobraSet(pf *flag.FlagSet, argToEnv map[string]string) {
+	pf.StringP("bosh-deployment-path", "f", "", "path to the BOSHDeployment resource file")
+	argToEnv["bosh-deployment-path"] = "BOSH_DEPLOYMENT_PATH"
+}
+
+func boshDeploymentFlagViperBind(pf *flag.FlagSet) {
+	viper.BindPFlag("bosh-deployment-path", pf.Lookup("bosh-deployment-path"))
+}
+
 func deploymentNameFlagValidation() (string, error) {
 	deploymentNameName := viper.GetString("deployment-name")
 	if len(deploymentNameName) == 0 {
@@ -112,3 +132,77 @@ func initialRolloutFlagCobraSet(pf *flag.FlagSet, argToEnv map[string]string) {
 func initialRolloutFlagViperBind(pf *flag.FlagSet) {
 	viper.BindPFlag("initial-rollout", pf.Lookup("initial-rollout"))
 }
+
+func renderCacheDirFlagCobraSet(pf *flag.FlagSet, argToEnv map[string]string) {
+	pf.StringP("render-cache-dir", "", "", "Directory in which rendered templates are cached. Caching is disabled if empty.")
+	argToEnv["render-cache-dir"] = "RENDER_CACHE_DIR"
+}
+
+func renderCacheDirFlagViperBind(pf *flag.FlagSet) {
+	viper.BindPFlag("render-cache-dir", pf.Lookup("render-cache-dir"))
+}
+
+func namespaceFlagValidation() (string, error) {
+	namespace := viper.GetString("namespace")
+	if len(namespace) == 0 {
+		return "", errors.New("namespace flag is empty")
+	}
+	return namespace, nil
+}
+
+func namespaceFlagCobraSet(pf *flag.FlagSet, argToEnv map[string]string) {
+	pf.StringP("namespace", "", "", "namespace of the bdpl resource")
+	argToEnv["namespace"] = "NAMESPACE"
+}
+
+func namespaceFlagViperBind(pf *flag.FlagSet) {
+	viper.BindPFlag("namespace", pf.Lookup("namespace"))
+}
+
+func reconcileStateFlagValidation() (string, error) {
+	reconcileStatePath := viper.GetString("reconcile-state-path")
+	if len(reconcileStatePath) == 0 {
+		return "", errors.New("reconcile-state-path flag is empty")
+	}
+	return reconcileStatePath, nil
+}
+
+func reconcileStateFlagCobraSet(pf *flag.FlagSet, argToEnv map[string]string) {
+	pf.StringP("reconcile-state-path", "", "", "path to the reconcile state file")
+	argToEnv["reconcile-state-path"] = "RECONCILE_STATE_PATH"
+}
+
+func reconcileStateFlagViperBind(pf *flag.FlagSet) {
+	viper.BindPFlag("reconcile-state-path", pf.Lookup("reconcile-state-path"))
+}
+
+// kubeConfigFlagCobraSet adds the kubeconfig flag to util subcommands, which
+// access the cluster. Unlike cmd.KubeConfigFlags it doesn't bind the flag,
+// so it doesn't replace the binding of the operator's flag.
+func kubeConfigFlagCobraSet(pf *flag.FlagSet, argToEnv map[string]string) {
+	pf.StringP("kubeconfig", "c", "", "Path to a kubeconfig, not required in-cluster")
+	argToEnv["kubeconfig"] = "KUBECONFIG"
+}
+
+func kubeConfigFlagViperBind(pf *flag.FlagSet) {
+	viper.BindPFlag("kubeconfig", pf.Lookup("kubeconfig"))
+}
+
+// secretNamePrefixFlagValidation configures the secret namer, which names the
+// secrets of the bdpl resource, like the operator does
+func secretNamePrefixFlagValidation() error {
+	namer, err := bdnames.NewSecretNamer(viper.GetString("secret-name-prefix"))
+	if err != nil {
+		return err
+	}
+	return boshdeployment.SetSecretNamer(namer)
+}
+
+func secretNamePrefixFlagCobraSet(pf *flag.FlagSet, argToEnv map[string]string) {
+	pf.StringP("secret-name-prefix", "", "", "prefix of the names of the secrets of the bdpl resource, has to match the operator's prefix")
+	argToEnv["secret-name-prefix"] = "SECRET_NAME_PREFIX"
+}
+
+func secretNamePrefixFlagViperBind(pf *flag.FlagSet) {
+	viper.BindPFlag("secret-name-prefix", pf.Lookup("secret-name-prefix"))
+}
@@ -1,7 +1,6 @@
 package cmd
 
 import (
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"time"
@@ -52,7 +51,7 @@ interpolated manifest to STDOUT
 
 		variablesDir := filepath.Clean(viper.GetString("variables-dir"))
 
-		if _, err := os.Stat(boshManifestPath); os.IsNotExist(err) {
+		if !manifest.FileExists(boshManifestPath) {
 			return errors.Errorf("%s bosh-manifest-path file doesn't exist : %s", vInterpolateFailedMessage, boshManifestPath)
 		}
 
@@ -66,8 +65,8 @@ interpolated manifest to STDOUT
 			return errors.Errorf("%s %s is not a directory", vInterpolateFailedMessage, variablesDir)
 		}
 
-		// Read files
-		boshManifestBytes, err := ioutil.ReadFile(boshManifestPath)
+		// Read files, the manifest may be gzip compressed
+		boshManifestBytes, err := manifest.ReadFile(boshManifestPath)
 		if err != nil {
 			return errors.Wrapf(err, "%s Reading file specified in the bosh-manifest-path flag failed", vInterpolateFailedMessage)
 		}
@@ -12,6 +12,7 @@ import (
 // Hooks from a BPM config
 type Hooks struct {
 	PreStart string `yaml:"pre_start,omitempty" json:"pre_start,omitempty"`
+	Drain    string `yaml:"drain,omitempty" json:"drain,omitempty"`
 }
 
 // Limits from a BPM config
@@ -50,6 +51,7 @@ type Process struct {
 	PersistentDisk    bool                `yaml:"persistent_disk,omitempty" json:"persistent_disk,omitempty"`
 	AdditionalVolumes []Volume            `yaml:"additional_volumes,omitempty" json:"additional_volumes,omitempty"`
 	Unsafe            Unsafe              `yaml:"unsafe,omitempty" json:"unsafe,omitempty"`
+	HealthCheck       *HealthCheck        `yaml:"health_check,omitempty" json:"health_check,omitempty"`
 }
 
 // Port represents the port to be opened up for this job only for tracing changes.
@@ -107,12 +109,15 @@ func indexOfBPMProcess(processes []Process, processName string) (int, bool) {
 	return -1, false
 }
 
-// ValidateProcesses checks if all processes have an executable
+// ValidateProcesses checks if all processes have an executable and valid health checks
 func (c Config) ValidateProcesses() error {
 	for _, process := range c.Processes {
 		if process.Executable == "" {
 			return errors.Errorf("no executable specified for process %s", process.Name)
 		}
+		if _, _, err := process.Probes(); err != nil {
+			return err
+		}
 	}
 	return nil
 }
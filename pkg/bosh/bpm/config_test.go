@@ -68,6 +68,82 @@ var _ = Describe("BPM Config", func() {
 		})
 	})
 
+	Describe("Probes", func() {
+		var process bpm.Process
+
+		BeforeEach(func() {
+			config, err := bpm.NewConfig([]byte(`processes:
+  - name: server
+    executable: /var/vcap/data/packages/server/serve.sh
+    health_check:
+      readiness:
+        http_get:
+          path: /health
+          port: 8443
+          scheme: https
+        initial_delay_seconds: 10
+        failure_threshold: 3
+      liveness:
+        exec:
+        - /var/vcap/jobs/server/bin/alive
+        timeout_seconds: 2`))
+			Expect(err).ToNot(HaveOccurred())
+			process = config.Processes[0]
+		})
+
+		It("translates the health checks to probes", func() {
+			readiness, liveness, err := process.Probes()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(readiness.HTTPGet.Path).To(Equal("/health"))
+			Expect(readiness.HTTPGet.Port.IntValue()).To(Equal(8443))
+			Expect(readiness.HTTPGet.Scheme).To(Equal(corev1.URISchemeHTTPS))
+			Expect(readiness.InitialDelaySeconds).To(Equal(int32(10)))
+			Expect(readiness.FailureThreshold).To(Equal(int32(3)))
+			Expect(liveness.Exec.Command).To(Equal([]string{"/var/vcap/jobs/server/bin/alive"}))
+			Expect(liveness.TimeoutSeconds).To(Equal(int32(2)))
+		})
+
+		It("returns no probes without health checks", func() {
+			process.HealthCheck = nil
+			readiness, liveness, err := process.Probes()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(readiness).To(BeNil())
+			Expect(liveness).To(BeNil())
+		})
+
+		It("rejects checks without or with several handlers", func() {
+			process.HealthCheck.Liveness.TCPPort = 8080
+			_, _, err := process.Probes()
+			Expect(err).To(MatchError(ContainSubstring("invalid liveness check of process server: exactly one of exec, http_get or tcp_port must be set")))
+
+			process.HealthCheck.Liveness = &bpm.Check{}
+			_, _, err = process.Probes()
+			Expect(err).To(MatchError(ContainSubstring("exactly one of exec, http_get or tcp_port must be set")))
+		})
+
+		It("rejects invalid probe settings", func() {
+			process.HealthCheck.Readiness.HTTPGet.Scheme = "ftp"
+			_, _, err := process.Probes()
+			Expect(err).To(MatchError(ContainSubstring("unsupported http_get scheme 'ftp'")))
+
+			process.HealthCheck.Readiness.HTTPGet.Scheme = ""
+			process.HealthCheck.Readiness.HTTPGet.Path = "health"
+			_, _, err = process.Probes()
+			Expect(err).To(MatchError(ContainSubstring("http_get path 'health' must start with '/'")))
+
+			process.HealthCheck.Readiness.HTTPGet.Path = ""
+			process.HealthCheck.Readiness.PeriodSeconds = -1
+			_, _, err = process.Probes()
+			Expect(err).To(MatchError(ContainSubstring("period_seconds must not be negative")))
+		})
+
+		It("is part of the process validation", func() {
+			config := bpm.Config{Processes: []bpm.Process{process}}
+			config.Processes[0].HealthCheck.Readiness.HTTPGet.Port = 0
+			Expect(config.ValidateProcesses()).To(MatchError(ContainSubstring("invalid readiness check of process server: invalid http_get port")))
+		})
+	})
+
 	Describe("NewEnvs", func() {
 		var (
 			process   bpm.Process
@@ -0,0 +1,142 @@
+package bpm
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// HealthCheck from a BPM config, which is translated to the probes of the
+// process' container
+type HealthCheck struct {
+	Readiness *Check `yaml:"readiness,omitempty" json:"readiness,omitempty"`
+	Liveness  *Check `yaml:"liveness,omitempty" json:"liveness,omitempty"`
+}
+
+// Check is a health check of a BPM process. Exactly one of exec, http_get or
+// tcp_port has to be set.
+type Check struct {
+	Exec                []string   `yaml:"exec,omitempty" json:"exec,omitempty"`
+	HTTPGet             *HTTPCheck `yaml:"http_get,omitempty" json:"http_get,omitempty"`
+	TCPPort             int        `yaml:"tcp_port,omitempty" json:"tcp_port,omitempty"`
+	InitialDelaySeconds int32      `yaml:"initial_delay_seconds,omitempty" json:"initial_delay_seconds,omitempty"`
+	PeriodSeconds       int32      `yaml:"period_seconds,omitempty" json:"period_seconds,omitempty"`
+	TimeoutSeconds      int32      `yaml:"timeout_seconds,omitempty" json:"timeout_seconds,omitempty"`
+	FailureThreshold    int32      `yaml:"failure_threshold,omitempty" json:"failure_threshold,omitempty"`
+}
+
+// HTTPCheck is a health check, which sends a GET request to the process
+type HTTPCheck struct {
+	Path   string `yaml:"path,omitempty" json:"path,omitempty"`
+	Port   int    `yaml:"port,omitempty" json:"port,omitempty"`
+	Scheme string `yaml:"scheme,omitempty" json:"scheme,omitempty"`
+}
+
+// Probes returns the readiness and liveness probes of the process, which are
+// nil if the process has no such health check
+func (p *Process) Probes() (*corev1.Probe, *corev1.Probe, error) {
+	if p.HealthCheck == nil {
+		return nil, nil, nil
+	}
+
+	readiness, err := p.HealthCheck.Readiness.Probe()
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "invalid readiness check of process %s", p.Name)
+	}
+	liveness, err := p.HealthCheck.Liveness.Probe()
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "invalid liveness check of process %s", p.Name)
+	}
+	return readiness, liveness, nil
+}
+
+// Probe translates the check to a kubernetes probe, it returns nil for a nil check
+func (c *Check) Probe() (*corev1.Probe, error) {
+	if c == nil {
+		return nil, nil
+	}
+
+	handler, err := c.handler()
+	if err != nil {
+		return nil, err
+	}
+
+	for name, value := range map[string]int32{
+		"initial_delay_seconds": c.InitialDelaySeconds,
+		"period_seconds":        c.PeriodSeconds,
+		"timeout_seconds":       c.TimeoutSeconds,
+		"failure_threshold":     c.FailureThreshold,
+	} {
+		if value < 0 {
+			return nil, errors.Errorf("%s must not be negative, got %d", name, value)
+		}
+	}
+
+	return &corev1.Probe{
+		Handler:             handler,
+		InitialDelaySeconds: c.InitialDelaySeconds,
+		PeriodSeconds:       c.PeriodSeconds,
+		TimeoutSeconds:      c.TimeoutSeconds,
+		FailureThreshold:    c.FailureThreshold,
+	}, nil
+}
+
+func (c *Check) handler() (corev1.Handler, error) {
+	handlers := 0
+	if len(c.Exec) > 0 {
+		handlers++
+	}
+	if c.HTTPGet != nil {
+		handlers++
+	}
+	if c.TCPPort != 0 {
+		handlers++
+	}
+	if handlers != 1 {
+		return corev1.Handler{}, errors.New("exactly one of exec, http_get or tcp_port must be set")
+	}
+
+	switch {
+	case len(c.Exec) > 0:
+		return corev1.Handler{Exec: &corev1.ExecAction{Command: c.Exec}}, nil
+	case c.HTTPGet != nil:
+		if err := validatePort(c.HTTPGet.Port); err != nil {
+			return corev1.Handler{}, errors.Wrap(err, "invalid http_get port")
+		}
+		scheme := corev1.URIScheme(strings.ToUpper(c.HTTPGet.Scheme))
+		switch scheme {
+		case "":
+			scheme = corev1.URISchemeHTTP
+		case corev1.URISchemeHTTP, corev1.URISchemeHTTPS:
+		default:
+			return corev1.Handler{}, errors.Errorf("unsupported http_get scheme '%s'", c.HTTPGet.Scheme)
+		}
+		path := c.HTTPGet.Path
+		if path == "" {
+			path = "/"
+		}
+		if !strings.HasPrefix(path, "/") {
+			return corev1.Handler{}, errors.Errorf("http_get path '%s' must start with '/'", path)
+		}
+		return corev1.Handler{HTTPGet: &corev1.HTTPGetAction{
+			Path:   path,
+			Port:   intstr.FromInt(c.HTTPGet.Port),
+			Scheme: scheme,
+		}}, nil
+	default:
+		if err := validatePort(c.TCPPort); err != nil {
+			return corev1.Handler{}, errors.Wrap(err, "invalid tcp_port")
+		}
+		return corev1.Handler{TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(c.TCPPort)}}, nil
+	}
+}
+
+func validatePort(port int) error {
+	if errs := validation.IsValidPortNum(port); len(errs) > 0 {
+		return errors.New(strings.Join(errs, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package bpmconverter
+
+import (
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"code.cloudfoundry.org/cf-operator/pkg/bosh/bpm"
+	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+	qstsv1a1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/quarksstatefulset/v1alpha1"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util"
+)
+
+// clusterServiceSuffix distinguishes the ClusterIP service from the headless
+// service, which is named '<deployment>-<ig>' already
+const clusterServiceSuffix = "-svc"
+
+// BPMPorts returns the sorted, unique ports declared in the BPM configs of
+// the instance group's jobs
+func BPMPorts(bpmConfigs bpm.Configs) []int32 {
+	seen := map[int32]bool{}
+	ports := []int32{}
+	for _, config := range bpmConfigs {
+		for _, port := range config.Ports {
+			p := int32(port.Internal)
+			if p == 0 || seen[p] {
+				continue
+			}
+			seen[p] = true
+			ports = append(ports, p)
+		}
+	}
+	sort.Slice(ports, func(i, j int) bool { return ports[i] < ports[j] })
+	return ports
+}
+
+// GenerateServicePerIG returns a ClusterIP service, which load balances
+// bpmPorts over the pods of the instance group's StatefulSet. This gives
+// link providers a stable address for the whole instance group. It is named
+// '<deployment>-<ig>-svc', since the headless service uses '<deployment>-<ig>'.
+// It returns nil if there are no ports, or the instance group isn't converted
+// to a StatefulSet.
+func (kc *BPMConverter) GenerateServicePerIG(ig bdm.InstanceGroup, namespace string, bpmPorts []int32) *corev1.Service {
+	if len(bpmPorts) == 0 {
+		return nil
+	}
+	if ig.LifeCycle != bdm.IGTypeService && ig.LifeCycle != "" {
+		return nil
+	}
+
+	deploymentName := ig.Env.AgentEnvBoshConfig.Agent.Settings.Labels[bdm.LabelDeploymentName]
+	selector := map[string]string{
+		bdm.LabelDeploymentName:    deploymentName,
+		bdm.LabelInstanceGroupName: ig.Name,
+	}
+	for _, job := range ig.Jobs {
+		if len(job.Properties.Quarks.ActivePassiveProbes) > 0 {
+			selector[qstsv1a1.LabelActivePod] = "active"
+		}
+	}
+
+	ports := make([]corev1.ServicePort, 0, len(bpmPorts))
+	for _, port := range bpmPorts {
+		ports = append(ports, corev1.ServicePort{
+			Name:     fmt.Sprintf("port-%d", port),
+			Protocol: corev1.ProtocolTCP,
+			Port:     port,
+		})
+	}
+
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      util.ServiceName(ig.Name, deploymentName, 63-len(clusterServiceSuffix)) + clusterServiceSuffix,
+			Namespace: namespace,
+			Labels: map[string]string{
+				bdm.LabelDeploymentName:    deploymentName,
+				bdm.LabelInstanceGroupName: ig.Name,
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:     corev1.ServiceTypeClusterIP,
+			Ports:    ports,
+			Selector: selector,
+		},
+	}
+}
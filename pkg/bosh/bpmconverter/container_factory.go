@@ -14,6 +14,7 @@ import (
 	"code.cloudfoundry.org/cf-operator/pkg/bosh/bpm"
 	"code.cloudfoundry.org/cf-operator/pkg/bosh/disk"
 	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+	bdnames "code.cloudfoundry.org/cf-operator/pkg/kube/util/names"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/util/operatorimage"
 	qjv1a1 "code.cloudfoundry.org/quarks-job/pkg/kube/apis/quarksjob/v1alpha1"
 	log "code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
@@ -42,10 +43,11 @@ type ContainerFactoryImpl struct {
 	disableLogSidecar    bool
 	releaseImageProvider bdm.ReleaseImageProvider
 	bpmConfigs           bpm.Configs
+	secretNamer          bdnames.SecretNamer
 }
 
 // NewContainerFactory returns a concrete implementation of ContainerFactory.
-func NewContainerFactory(deploymentName string, instanceGroupName string, version string, disableLogSidecar bool, releaseImageProvider bdm.ReleaseImageProvider, bpmConfigs bpm.Configs) *ContainerFactoryImpl {
+func NewContainerFactory(deploymentName string, instanceGroupName string, version string, disableLogSidecar bool, releaseImageProvider bdm.ReleaseImageProvider, bpmConfigs bpm.Configs, secretNamer bdnames.SecretNamer) *ContainerFactoryImpl {
 	return &ContainerFactoryImpl{
 		deploymentName:       deploymentName,
 		instanceGroupName:    instanceGroupName,
@@ -53,6 +55,7 @@ func NewContainerFactory(deploymentName string, instanceGroupName string, versio
 		disableLogSidecar:    disableLogSidecar,
 		releaseImageProvider: releaseImageProvider,
 		bpmConfigs:           bpmConfigs,
+		secretNamer:          secretNamer,
 	}
 }
 
@@ -137,7 +140,7 @@ func (c *ContainerFactoryImpl) JobsToInitContainers(
 		boshPreStartInitContainers = append(boshPreStartInitContainers, *boshPreStartInitContainer.DeepCopy())
 	}
 
-	resolvedPropertiesSecretName := names.InstanceGroupSecretName(
+	resolvedPropertiesSecretName := c.secretNamer.InstanceGroupSecretName(
 		names.DeploymentSecretTypeInstanceGroupResolvedProperties, // ig-resolved
 		c.deploymentName,
 		c.instanceGroupName,
@@ -240,6 +243,11 @@ func (c *ContainerFactoryImpl) JobsToContainers(
 				}
 			}
 
+			readinessProbe, livenessProbe, err := process.Probes()
+			if err != nil {
+				return []corev1.Container{}, errors.Wrapf(err, "invalid BPM health check for job '%s'", job.Name)
+			}
+
 			container := bpmProcessContainer(
 				job.Name,
 				process.Name,
@@ -247,6 +255,8 @@ func (c *ContainerFactoryImpl) JobsToContainers(
 				process,
 				processVolumeMounts,
 				job.Properties.Quarks.Run.HealthCheck,
+				readinessProbe,
+				livenessProbe,
 				job.Properties.Quarks.Envs,
 				job.Properties.Quarks.Run.SecurityContext.DeepCopy(),
 				postStart,
@@ -496,6 +506,8 @@ func bpmProcessContainer(
 	process bpm.Process,
 	volumeMounts []corev1.VolumeMount,
 	healthchecks map[string]bdm.HealthCheck,
+	readinessProbe *corev1.Probe,
+	livenessProbe *corev1.Probe,
 	quarksEnvs []corev1.EnvVar,
 	securityContext *corev1.SecurityContext,
 	postStart postStart,
@@ -547,57 +559,28 @@ func bpmProcessContainer(
 		},
 	}
 
-	// Setup the job drain script handler.
-	drainGlob := filepath.Join(VolumeJobsDirMountPath, jobName, "bin", "drain", "*")
-	container.Lifecycle.PreStop = &corev1.Handler{
-		Exec: &corev1.ExecAction{
-			Command: []string{
-				"/bin/sh",
-				"-c",
-				`
-shopt -s nullglob
-for s in ` + drainGlob + `; do
-	(
-		echo "Running drain script $s"
-		while true; do
-			out=$($s)
-			status=$?
-
-			if [ "$status" -ne "0" ]; then
-				echo "$s FAILED with exit code $status"
-				exit $status
-			fi
-
-			if [ "$out" -lt "0" ]; then
-				echo "Sleeping dynamic draining wait time for $s..."
-				sleep ${out:1}
-				echo "Running $s again"
-			else
-				echo "Sleeping static draining wait time for $s..."
-				sleep $out
-				echo "$s done"
-				exit 0
-			fi
-		done
-	)&
-done
-echo "Waiting for subprocesses to finish..."
-wait
-echo "Done"`,
-			},
-		},
+	var healthCheck *bdm.HealthCheck
+	if hc, ok := healthchecks[process.Name]; ok {
+		healthCheck = &hc
+		if hc.ReadinessProbe != nil {
+			container.ReadinessProbe = hc.ReadinessProbe
+		}
+		if hc.LivenessProbe != nil {
+			container.LivenessProbe = hc.LivenessProbe
+		}
 	}
 
-	for name, hc := range healthchecks {
-		if name == process.Name {
-			if hc.ReadinessProbe != nil {
-				container.ReadinessProbe = hc.ReadinessProbe
-			}
-			if hc.LivenessProbe != nil {
-				container.LivenessProbe = hc.LivenessProbe
-			}
-		}
+	// Probes of the manifest's health checks take precedence over BPM's
+	if container.ReadinessProbe == nil {
+		container.ReadinessProbe = readinessProbe
 	}
+	if container.LivenessProbe == nil {
+		container.LivenessProbe = livenessProbe
+	}
+
+	// Setup the job drain handler.
+	container.Lifecycle.PreStop = GeneratePreStopLifecycleHook(jobName, process, healthCheck)
+
 	return container
 }
 
@@ -4,8 +4,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"path"
+	"strings"
 
 	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/intstr"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -17,6 +19,7 @@ import (
 	"code.cloudfoundry.org/cf-operator/pkg/bosh/converter/fakes"
 	"code.cloudfoundry.org/cf-operator/pkg/bosh/disk"
 	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+	bdnames "code.cloudfoundry.org/cf-operator/pkg/kube/util/names"
 )
 
 var _ = Describe("ContainerFactory", func() {
@@ -166,7 +169,7 @@ var _ = Describe("ContainerFactory", func() {
 	})
 
 	JustBeforeEach(func() {
-		containerFactory = NewContainerFactory("fake-manifest", "fake-ig", "v1", false, releaseImageProvider, bpmConfigs)
+		containerFactory = NewContainerFactory("fake-manifest", "fake-ig", "v1", false, releaseImageProvider, bpmConfigs, bdnames.DefaultSecretNamer{})
 	})
 
 	Context("JobsToContainers", func() {
@@ -321,7 +324,7 @@ var _ = Describe("ContainerFactory", func() {
 					},
 				},
 			}
-			containerFactory = NewContainerFactory("fake-manifest", "fake-ig", "v1", false, releaseImageProvider, bpmConfigsWithError)
+			containerFactory = NewContainerFactory("fake-manifest", "fake-ig", "v1", false, releaseImageProvider, bpmConfigsWithError, bdnames.DefaultSecretNamer{})
 			actWithError := func() ([]corev1.Container, error) {
 				return containerFactory.JobsToContainers(jobs, []corev1.VolumeMount{}, disk.BPMResourceDisks{})
 			}
@@ -431,6 +434,68 @@ var _ = Describe("ContainerFactory", func() {
 			Expect(string(bytes)).To(Equal("{}"))
 		})
 
+		Context("with BPM health checks", func() {
+			BeforeEach(func() {
+				jobs = []bdm.Job{
+					{Name: "fake-job"},
+				}
+				bpmConfigs["fake-job"] = bpm.Config{
+					Processes: []bpm.Process{
+						{
+							Name: "fake-process",
+							HealthCheck: &bpm.HealthCheck{
+								Readiness: &bpm.Check{HTTPGet: &bpm.HTTPCheck{Path: "/ready", Port: 8080}, PeriodSeconds: 5},
+								Liveness:  &bpm.Check{TCPPort: 8080},
+							},
+						},
+						{Name: "other-process"},
+					},
+				}
+			})
+
+			It("translates them to readiness and liveness probes", func() {
+				containers, err := act()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(containers[0].ReadinessProbe).To(Equal(&corev1.Probe{
+					Handler: corev1.Handler{
+						HTTPGet: &corev1.HTTPGetAction{
+							Path:   "/ready",
+							Port:   intstr.FromInt(8080),
+							Scheme: corev1.URISchemeHTTP,
+						},
+					},
+					PeriodSeconds: 5,
+				}))
+				Expect(containers[0].LivenessProbe.TCPSocket.Port).To(Equal(intstr.FromInt(8080)))
+			})
+
+			It("adds no probes to processes without health checks", func() {
+				containers, err := act()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(containers[1].ReadinessProbe).To(BeNil())
+				Expect(containers[1].LivenessProbe).To(BeNil())
+			})
+
+			It("prefers the probes of the manifest's health checks", func() {
+				probe := &corev1.Probe{Handler: corev1.Handler{Exec: &corev1.ExecAction{Command: []string{"true"}}}}
+				jobs[0].Properties.Quarks.Run.HealthCheck = map[string]bdm.HealthCheck{
+					"fake-process": {ReadinessProbe: probe},
+				}
+
+				containers, err := act()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(containers[0].ReadinessProbe).To(Equal(probe))
+				Expect(containers[0].LivenessProbe.TCPSocket).ToNot(BeNil())
+			})
+
+			It("fails for invalid health checks", func() {
+				bpmConfigs["fake-job"].Processes[0].HealthCheck.Liveness.TCPPort = 70000
+
+				_, err := act()
+				Expect(err).To(MatchError(ContainSubstring("invalid BPM health check for job 'fake-job': invalid liveness check of process fake-process: invalid tcp_port")))
+			})
+		})
+
 		Context("with lifecycle events", func() {
 			It("creates a preStop handler per job", func() {
 				containers, err := act()
@@ -445,6 +510,91 @@ var _ = Describe("ContainerFactory", func() {
 				Expect(containers[1].Lifecycle.PreStop.Exec.Command).To(ContainElement(ContainSubstring("/var/vcap/jobs/other-job/bin/drain/")))
 			})
 
+			It("runs the drain hook declared in BPM", func() {
+				jobs = []bdm.Job{
+					{Name: "fake-job"},
+				}
+				bpmConfigs["fake-job"] = bpm.Config{
+					Processes: []bpm.Process{
+						{
+							Name:  "fake-process",
+							Hooks: bpm.Hooks{Drain: "/var/vcap/jobs/fake-job/bin/custom-drain"},
+						},
+					},
+				}
+
+				containers, err := act()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(containers[0].Lifecycle.PreStop.Exec.Command).To(Equal([]string{"/bin/sh", "-c", "/var/vcap/jobs/fake-job/bin/custom-drain"}))
+				Expect(containers[0].Lifecycle.PreStop.HTTPGet).To(BeNil())
+			})
+
+			It("requests /drain on the HTTP health check port before the drain scripts", func() {
+				jobs = []bdm.Job{
+					{
+						Name: "fake-job",
+						Properties: bdm.JobProperties{
+							Quarks: bdm.Quarks{
+								Run: bdm.RunConfig{
+									HealthCheck: map[string]bdm.HealthCheck{
+										"fake-process": {
+											ReadinessProbe: &corev1.Probe{
+												Handler: corev1.Handler{
+													HTTPGet: &corev1.HTTPGetAction{
+														Path: "/health",
+														Port: intstr.FromInt(8080),
+													},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				}
+
+				containers, err := act()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(containers[0].Lifecycle.PreStop.HTTPGet).To(BeNil())
+				command := containers[0].Lifecycle.PreStop.Exec.Command[2]
+				Expect(command).To(ContainSubstring("curl --silent --show-error --insecure --max-time 30 http://127.0.0.1:8080/drain"))
+				Expect(command).To(ContainSubstring("/var/vcap/jobs/fake-job/bin/drain/*"))
+				Expect(strings.Index(command, "curl")).To(BeNumerically("<", strings.Index(command, "bin/drain")))
+			})
+
+			It("only runs the drain scripts for a TCP health check", func() {
+				jobs = []bdm.Job{
+					{
+						Name: "fake-job",
+						Properties: bdm.JobProperties{
+							Quarks: bdm.Quarks{
+								Run: bdm.RunConfig{
+									HealthCheck: map[string]bdm.HealthCheck{
+										"fake-process": {
+											ReadinessProbe: &corev1.Probe{
+												Handler: corev1.Handler{
+													TCPSocket: &corev1.TCPSocketAction{
+														Port: intstr.FromInt(8080),
+													},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				}
+
+				containers, err := act()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(containers[0].Lifecycle.PreStop.HTTPGet).To(BeNil())
+				command := containers[0].Lifecycle.PreStop.Exec.Command[2]
+				Expect(command).NotTo(ContainSubstring("curl"))
+				Expect(command).To(ContainSubstring("/var/vcap/jobs/fake-job/bin/drain/*"))
+			})
+
 			It("creates a postStart condition command", func() {
 				jobs = []bdm.Job{
 					bdm.Job{
@@ -515,7 +665,7 @@ var _ = Describe("ContainerFactory", func() {
 
 				disableSideCar := ig.Env.AgentEnvBoshConfig.Agent.Settings.DisableLogSidecar
 
-				containerFactory := NewContainerFactory("fake-manifest", ig.Name, "v1", disableSideCar, releaseImageProvider, bpmJobConfigs)
+				containerFactory := NewContainerFactory("fake-manifest", ig.Name, "v1", disableSideCar, releaseImageProvider, bpmJobConfigs, bdnames.DefaultSecretNamer{})
 				act := func() ([]corev1.Container, error) {
 					return containerFactory.JobsToContainers(ig.Jobs, []corev1.VolumeMount{}, disk.BPMResourceDisks{})
 				}
@@ -542,7 +692,7 @@ var _ = Describe("ContainerFactory", func() {
 
 				disableSideCar := ig.Env.AgentEnvBoshConfig.Agent.Settings.DisableLogSidecar
 
-				containerFactory := NewContainerFactory("fake-manifest", ig.Name, "v1", disableSideCar, releaseImageProvider, bpmJobConfigs)
+				containerFactory := NewContainerFactory("fake-manifest", ig.Name, "v1", disableSideCar, releaseImageProvider, bpmJobConfigs, bdnames.DefaultSecretNamer{})
 				act := func() ([]corev1.Container, error) {
 					return containerFactory.JobsToContainers(ig.Jobs, []corev1.VolumeMount{}, disk.BPMResourceDisks{})
 				}
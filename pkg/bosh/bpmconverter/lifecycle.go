@@ -0,0 +1,106 @@
+package bpmconverter
+
+import (
+	"fmt"
+	"path/filepath"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"code.cloudfoundry.org/cf-operator/pkg/bosh/bpm"
+	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+)
+
+const (
+	// DrainHTTPPath is the path requested on the port of an HTTP health check,
+	// when a process has no drain hook in its BPM config.
+	DrainHTTPPath = "/drain"
+)
+
+// GeneratePreStopLifecycleHook returns the preStop handler for a BPM process container.
+// A drain hook declared in BPM is executed directly. Without one, all drain
+// scripts of the BOSH job are run. If the process has an HTTP health check, a
+// GET request to /drain is sent to its port before the drain scripts run.
+func GeneratePreStopLifecycleHook(jobName string, process bpm.Process, healthCheck *bdm.HealthCheck) *corev1.Handler {
+	if process.Hooks.Drain != "" {
+		return &corev1.Handler{
+			Exec: &corev1.ExecAction{
+				Command: []string{"/bin/sh", "-c", process.Hooks.Drain},
+			},
+		}
+	}
+
+	return jobDrainScriptsHandler(jobName, drainRequest(healthCheck))
+}
+
+// drainRequest returns a shell command, which requests /drain on the port of
+// the health check's HTTP probe. TCP probes don't imply an HTTP server and
+// named ports can't be resolved in the container, so they are skipped.
+func drainRequest(healthCheck *bdm.HealthCheck) string {
+	if healthCheck == nil {
+		return ""
+	}
+
+	for _, probe := range []*corev1.Probe{healthCheck.ReadinessProbe, healthCheck.LivenessProbe} {
+		if probe == nil || probe.HTTPGet == nil || probe.HTTPGet.Port.Type != intstr.Int {
+			continue
+		}
+
+		scheme := "http"
+		if probe.HTTPGet.Scheme == corev1.URISchemeHTTPS {
+			scheme = "https"
+		}
+		url := fmt.Sprintf("%s://127.0.0.1:%d%s", scheme, probe.HTTPGet.Port.IntValue(), DrainHTTPPath)
+		return `
+if command -v curl > /dev/null; then
+	echo "Requesting ` + url + `"
+	curl --silent --show-error --insecure --max-time 30 ` + url + ` || echo "Request to ` + url + ` FAILED"
+fi`
+	}
+
+	return ""
+}
+
+// jobDrainScriptsHandler runs the drain request, if any, and then all drain
+// scripts of a BOSH job and waits for them to finish
+func jobDrainScriptsHandler(jobName string, drainRequest string) *corev1.Handler {
+	drainGlob := filepath.Join(VolumeJobsDirMountPath, jobName, "bin", "drain", "*")
+	return &corev1.Handler{
+		Exec: &corev1.ExecAction{
+			Command: []string{
+				"/bin/sh",
+				"-c",
+				drainRequest + `
+shopt -s nullglob
+for s in ` + drainGlob + `; do
+	(
+		echo "Running drain script $s"
+		while true; do
+			out=$($s)
+			status=$?
+
+			if [ "$status" -ne "0" ]; then
+				echo "$s FAILED with exit code $status"
+				exit $status
+			fi
+
+			if [ "$out" -lt "0" ]; then
+				echo "Sleeping dynamic draining wait time for $s..."
+				sleep ${out:1}
+				echo "Running $s again"
+			else
+				echo "Sleeping static draining wait time for $s..."
+				sleep $out
+				echo "$s done"
+				exit 0
+			fi
+		done
+	)&
+done
+echo "Waiting for subprocesses to finish..."
+wait
+echo "Done"`,
+			},
+		},
+	}
+}
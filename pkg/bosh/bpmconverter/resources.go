@@ -15,6 +15,7 @@ import (
 	"code.cloudfoundry.org/cf-operator/pkg/bosh/bpm"
 	"code.cloudfoundry.org/cf-operator/pkg/bosh/disk"
 	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
 	qstsv1a1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/quarksstatefulset/v1alpha1"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/controllers/statefulset"
 	qjv1a1 "code.cloudfoundry.org/quarks-job/pkg/kube/apis/quarksjob/v1alpha1"
@@ -23,8 +24,24 @@ import (
 
 var (
 	admGroupID = int64(1000)
+
+	// bpmDebugImage is the image of the BPM debug container
+	bpmDebugImage = "ubuntu:22.04"
+)
+
+const (
+	// BPMDebugContainerName is the name of the container, which is added to
+	// the instance group pods, if the BPM debug feature is enabled
+	BPMDebugContainerName = "bpm-debug"
 )
 
+// SetBPMDebugImage initializes the package scoped image of the BPM debug container
+func SetBPMDebugImage(image string) {
+	if image != "" {
+		bpmDebugImage = image
+	}
+}
+
 // BPMConverter converts BPM information to kubernetes resources
 type BPMConverter struct {
 	namespace               string
@@ -73,6 +90,225 @@ type Resources struct {
 	PersistentVolumeClaims []corev1.PersistentVolumeClaim
 }
 
+// MergePodAnnotations adds the annotations to the pod templates of the
+// converted instance groups and errands, replacing existing keys
+func (r *Resources) MergePodAnnotations(annotations map[string]string) {
+	if len(annotations) == 0 {
+		return
+	}
+
+	for i := range r.InstanceGroups {
+		meta := &r.InstanceGroups[i].Spec.Template.Spec.Template.ObjectMeta
+		meta.Annotations = mergeAnnotations(meta.Annotations, annotations)
+	}
+	for i := range r.Errands {
+		meta := &r.Errands[i].Spec.Template.Spec.Template.ObjectMeta
+		meta.Annotations = mergeAnnotations(meta.Annotations, annotations)
+	}
+}
+
+// MergePodSecurityContext merges the pod security context into the pod
+// templates of the converted instance groups and errands. Fields set in psc
+// replace the generated ones.
+func (r *Resources) MergePodSecurityContext(psc *corev1.PodSecurityContext) {
+	if psc == nil {
+		return
+	}
+
+	for i := range r.InstanceGroups {
+		mergePodSecurityContext(&r.InstanceGroups[i].Spec.Template.Spec.Template.Spec, psc)
+	}
+	for i := range r.Errands {
+		mergePodSecurityContext(&r.Errands[i].Spec.Template.Spec.Template.Spec, psc)
+	}
+}
+
+func mergePodSecurityContext(spec *corev1.PodSpec, psc *corev1.PodSecurityContext) {
+	if spec.SecurityContext == nil {
+		spec.SecurityContext = &corev1.PodSecurityContext{}
+	}
+	bdv1.MergePodSecurityContext(spec.SecurityContext, psc)
+}
+
+// MergePodAntiAffinity adds a pod anti-affinity to the pod templates of the
+// converted instance groups, which spreads the pods of an instance group
+// across nodes. The affinity from the manifest's agent settings is kept.
+func (r *Resources) MergePodAntiAffinity(policy bdv1.PodAntiAffinityPolicy) {
+	if policy != bdv1.PodAntiAffinityPreferred && policy != bdv1.PodAntiAffinityRequired {
+		return
+	}
+
+	for i := range r.InstanceGroups {
+		template := &r.InstanceGroups[i].Spec.Template.Spec.Template
+		term := corev1.PodAffinityTerm{
+			LabelSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					bdm.LabelDeploymentName:    template.Labels[bdm.LabelDeploymentName],
+					bdm.LabelInstanceGroupName: template.Labels[bdm.LabelInstanceGroupName],
+				},
+			},
+			TopologyKey: corev1.LabelHostname,
+		}
+
+		// The affinity is shared with the instance group of the manifest
+		affinity := template.Spec.Affinity.DeepCopy()
+		if affinity == nil {
+			affinity = &corev1.Affinity{}
+		}
+		if affinity.PodAntiAffinity == nil {
+			affinity.PodAntiAffinity = &corev1.PodAntiAffinity{}
+		}
+
+		antiAffinity := affinity.PodAntiAffinity
+		if policy == bdv1.PodAntiAffinityRequired {
+			antiAffinity.RequiredDuringSchedulingIgnoredDuringExecution = append(antiAffinity.RequiredDuringSchedulingIgnoredDuringExecution, term)
+		} else {
+			antiAffinity.PreferredDuringSchedulingIgnoredDuringExecution = append(antiAffinity.PreferredDuringSchedulingIgnoredDuringExecution, corev1.WeightedPodAffinityTerm{
+				Weight:          100,
+				PodAffinityTerm: term,
+			})
+		}
+		template.Spec.Affinity = affinity
+	}
+}
+
+// MergeDeploymentTags adds the labels and annotations of the deployment tags
+// to the converted QuarksStatefulSets, their StatefulSet templates, the
+// QuarksJobs, services and persistent volume claims. Generated labels and
+// annotations take precedence over the tags. Pod templates and selectors are
+// left unchanged, so tags don't restart pods. Volume claim templates are left
+// unchanged as well, since they are immutable, the claims created from them
+// are tagged by the BPM reconciler.
+func (r *Resources) MergeDeploymentTags(labels map[string]string, annotations map[string]string) {
+	if len(labels) == 0 && len(annotations) == 0 {
+		return
+	}
+
+	for i := range r.InstanceGroups {
+		mergeTags(&r.InstanceGroups[i].ObjectMeta, labels, annotations)
+		sts := &r.InstanceGroups[i].Spec.Template
+		mergeTags(&sts.ObjectMeta, labels, annotations)
+	}
+	for i := range r.Errands {
+		mergeTags(&r.Errands[i].ObjectMeta, labels, annotations)
+	}
+	for i := range r.Services {
+		mergeTags(&r.Services[i].ObjectMeta, labels, annotations)
+	}
+	for i := range r.PersistentVolumeClaims {
+		mergeTags(&r.PersistentVolumeClaims[i].ObjectMeta, labels, annotations)
+	}
+}
+
+func mergeTags(meta *metav1.ObjectMeta, labels map[string]string, annotations map[string]string) {
+	if len(labels) > 0 {
+		meta.Labels = mergeAnnotations(labels, meta.Labels)
+	}
+	if len(annotations) > 0 {
+		meta.Annotations = mergeAnnotations(annotations, meta.Annotations)
+	}
+}
+
+// PrependInitContainers adds the containers in front of the generated init
+// containers of the converted instance groups. The data and sys directories,
+// which are shared with the BOSH job processes, are mounted into them.
+func (r *Resources) PrependInitContainers(containers []corev1.Container) {
+	if len(containers) == 0 {
+		return
+	}
+
+	for i := range r.InstanceGroups {
+		spec := &r.InstanceGroups[i].Spec.Template.Spec.Template.Spec
+		initContainers := make([]corev1.Container, 0, len(containers)+len(spec.InitContainers))
+		for _, container := range containers {
+			container := *container.DeepCopy()
+			for _, mount := range []*corev1.VolumeMount{dataDirVolumeMount(), sysDirVolumeMount()} {
+				if hasVolume(spec.Volumes, mount.Name) && !hasVolumeMount(container.VolumeMounts, mount.MountPath) {
+					container.VolumeMounts = append(container.VolumeMounts, *mount)
+				}
+			}
+			initContainers = append(initContainers, container)
+		}
+		spec.InitContainers = append(initContainers, spec.InitContainers...)
+	}
+}
+
+// AddBPMDebugContainer adds the 'bpm-debug' container to the pods of the
+// converted instance groups. It mounts the volumes of all containers and may
+// trace their processes, since the pod shares its process namespace. Errands
+// are left unchanged, as the sleeping container would keep them from
+// completing.
+func (r *Resources) AddBPMDebugContainer() {
+	for i := range r.InstanceGroups {
+		spec := &r.InstanceGroups[i].Spec.Template.Spec.Template.Spec
+		if hasContainer(spec.Containers, BPMDebugContainerName) {
+			continue
+		}
+
+		mounts := []corev1.VolumeMount{}
+		for _, container := range spec.Containers {
+			for _, mount := range container.VolumeMounts {
+				if !hasVolumeMount(mounts, mount.MountPath) {
+					mounts = append(mounts, mount)
+				}
+			}
+		}
+
+		spec.Containers = append(spec.Containers, corev1.Container{
+			Name:         BPMDebugContainerName,
+			Image:        bpmDebugImage,
+			Command:      []string{"sleep", "infinity"},
+			VolumeMounts: mounts,
+			SecurityContext: &corev1.SecurityContext{
+				Capabilities: &corev1.Capabilities{
+					Add: []corev1.Capability{"SYS_PTRACE"},
+				},
+			},
+		})
+		spec.ShareProcessNamespace = pointers.Bool(true)
+	}
+}
+
+func hasContainer(containers []corev1.Container, name string) bool {
+	for _, container := range containers {
+		if container.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func hasVolume(volumes []corev1.Volume, name string) bool {
+	for _, volume := range volumes {
+		if volume.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func hasVolumeMount(mounts []corev1.VolumeMount, mountPath string) bool {
+	for _, mount := range mounts {
+		if mount.MountPath == mountPath {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeAnnotations returns a new map, since the pod template shares its
+// annotations with other objects of the instance group
+func mergeAnnotations(annotations map[string]string, overrides map[string]string) map[string]string {
+	merged := make(map[string]string, len(annotations)+len(overrides))
+	for k, v := range annotations {
+		merged[k] = v
+	}
+	for k, v := range overrides {
+		merged[k] = v
+	}
+	return merged
+}
+
 // Resources uses BOSH Process Manager information to create k8s container specs from single BOSH instance group.
 // It returns quarks stateful sets, services and quarks jobs.
 func (kc *BPMConverter) Resources(manifestName string, dns DomainNameService, qStsVersion string, instanceGroup *bdm.InstanceGroup, releaseImageProvider bdm.ReleaseImageProvider, bpmConfigs bpm.Configs, igResolvedSecretVersion string) (*Resources, error) {
@@ -10,6 +10,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 
 	"code.cloudfoundry.org/cf-operator/pkg/bosh/bpm"
 	"code.cloudfoundry.org/cf-operator/pkg/bosh/bpmconverter"
@@ -17,6 +18,7 @@ import (
 	"code.cloudfoundry.org/cf-operator/pkg/bosh/disk"
 	"code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
 	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
 	qstsv1a1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/quarksstatefulset/v1alpha1"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/controllers/statefulset"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/util/boshdns"
@@ -361,6 +363,86 @@ var _ = Describe("BPM Converter", func() {
 				Expect(extStS.Spec.Template.Annotations).To(HaveKeyWithValue("custom-annotation", "bar"))
 			})
 
+			It("merges pod annotations, which take precedence over the agent settings", func() {
+				m.InstanceGroups[1].Env.AgentEnvBoshConfig.Agent.Settings.Annotations = map[string]string{
+					"custom-annotation": "bar",
+					"sidecar":           "false",
+				}
+				resources, err := act(bpmConfigs[1], m.InstanceGroups[1])
+				Expect(err).ShouldNot(HaveOccurred())
+
+				resources.MergePodAnnotations(map[string]string{"sidecar": "true"})
+
+				qSts := resources.InstanceGroups[0]
+				Expect(qSts.Spec.Template.Spec.Template.Annotations).To(HaveKeyWithValue("custom-annotation", "bar"))
+				Expect(qSts.Spec.Template.Spec.Template.Annotations).To(HaveKeyWithValue("sidecar", "true"))
+				Expect(qSts.Annotations).To(HaveKeyWithValue("sidecar", "false"))
+			})
+
+			It("adds deployment tags to the generated resources, but not to pods, selectors and volume claim templates", func() {
+				resources, err := act(bpmConfigs[1], m.InstanceGroups[1])
+				Expect(err).ShouldNot(HaveOccurred())
+				resources.PersistentVolumeClaims = []corev1.PersistentVolumeClaim{{ObjectMeta: metav1.ObjectMeta{Name: "fake-pvc"}}}
+				resources.InstanceGroups[0].Spec.Template.Spec.VolumeClaimTemplates = []corev1.PersistentVolumeClaim{{ObjectMeta: metav1.ObjectMeta{Name: "fake-claim"}}}
+
+				resources.MergeDeploymentTags(
+					map[string]string{"cost-center": "4711", bdm.LabelDeploymentName: "other"},
+					map[string]string{"example.com/cost-center": "4711"},
+				)
+
+				qSts := resources.InstanceGroups[0]
+				sts := qSts.Spec.Template
+				for _, meta := range []metav1.ObjectMeta{qSts.ObjectMeta, sts.ObjectMeta, resources.Services[0].ObjectMeta, resources.PersistentVolumeClaims[0].ObjectMeta} {
+					Expect(meta.Labels).To(HaveKeyWithValue("cost-center", "4711"))
+					Expect(meta.Annotations).To(HaveKeyWithValue("example.com/cost-center", "4711"))
+				}
+				Expect(sts.Labels).To(HaveKeyWithValue(bdm.LabelDeploymentName, deploymentName))
+				Expect(sts.Spec.Selector.MatchLabels).ToNot(HaveKey("cost-center"))
+				Expect(sts.Spec.Template.Labels).ToNot(HaveKey("cost-center"))
+				Expect(sts.Spec.VolumeClaimTemplates[0].Labels).ToNot(HaveKey("cost-center"))
+				Expect(sts.Spec.VolumeClaimTemplates[0].Annotations).ToNot(HaveKey("example.com/cost-center"))
+			})
+
+			It("merges the pod security context, with instance group overrides taking precedence", func() {
+				resources, err := act(bpmConfigs[1], m.InstanceGroups[1])
+				Expect(err).ShouldNot(HaveOccurred())
+
+				spec := bdv1.BOSHDeploymentSpec{
+					PodSecurityContext: &corev1.PodSecurityContext{
+						RunAsUser:  pointers.Int64(1000),
+						RunAsGroup: pointers.Int64(1000),
+					},
+					InstanceGroups: []bdv1.InstanceGroupOverride{
+						{
+							Name: m.InstanceGroups[1].Name,
+							PodSecurityContext: &corev1.PodSecurityContext{
+								RunAsUser: pointers.Int64(2000),
+								Sysctls:   []corev1.Sysctl{{Name: "net.core.somaxconn", Value: "1024"}},
+							},
+						},
+					},
+				}
+				resources.MergePodSecurityContext(spec.InstanceGroupPodSecurityContext(m.InstanceGroups[1].Name))
+
+				psc := resources.InstanceGroups[0].Spec.Template.Spec.Template.Spec.SecurityContext
+				Expect(*psc.FSGroup).To(Equal(int64(1000)))
+				Expect(*psc.RunAsUser).To(Equal(int64(2000)))
+				Expect(*psc.RunAsGroup).To(Equal(int64(1000)))
+				Expect(psc.Sysctls).To(ConsistOf(corev1.Sysctl{Name: "net.core.somaxconn", Value: "1024"}))
+				Expect(spec.PodSecurityContext.Sysctls).To(BeEmpty())
+			})
+
+			It("keeps the generated pod security context, if none is configured", func() {
+				resources, err := act(bpmConfigs[1], m.InstanceGroups[1])
+				Expect(err).ShouldNot(HaveOccurred())
+
+				spec := bdv1.BOSHDeploymentSpec{}
+				resources.MergePodSecurityContext(spec.InstanceGroupPodSecurityContext(m.InstanceGroups[1].Name))
+
+				psc := resources.InstanceGroups[0].Spec.Template.Spec.Template.Spec.SecurityContext
+				Expect(*psc).To(Equal(corev1.PodSecurityContext{FSGroup: pointers.Int64(1000)}))
+			})
+
 			It("converts the AgentEnvBoshConfig information", func() {
 				serviceAccount := "fake-service-account"
 				automountServiceAccountToken := true
@@ -667,6 +749,147 @@ var _ = Describe("BPM Converter", func() {
 					},
 				}))
 			})
+
+			It("merges an injected pod anti-affinity with the manifest's affinity", func() {
+				r, err := act(bpmConfigs[0], m.InstanceGroups[2])
+				Expect(err).ShouldNot(HaveOccurred())
+
+				r.MergePodAntiAffinity(bdv1.PodAntiAffinityPreferred)
+
+				antiAffinity := r.InstanceGroups[0].Spec.Template.Spec.Template.Spec.Affinity.PodAntiAffinity
+				Expect(antiAffinity.PreferredDuringSchedulingIgnoredDuringExecution).To(HaveLen(2))
+				Expect(antiAffinity.PreferredDuringSchedulingIgnoredDuringExecution[0].PodAffinityTerm.TopologyKey).To(Equal("beta.kubernetes.io/os"))
+				Expect(antiAffinity.PreferredDuringSchedulingIgnoredDuringExecution[1]).To(Equal(corev1.WeightedPodAffinityTerm{
+					Weight: 100,
+					PodAffinityTerm: corev1.PodAffinityTerm{
+						LabelSelector: &metav1.LabelSelector{
+							MatchLabels: map[string]string{
+								bdm.LabelDeploymentName:    deploymentName,
+								bdm.LabelInstanceGroupName: m.InstanceGroups[2].Name,
+							},
+						},
+						TopologyKey: corev1.LabelHostname,
+					},
+				}))
+				Expect(m.InstanceGroups[2].Env.AgentEnvBoshConfig.Agent.Settings.Affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution).To(HaveLen(1))
+			})
+
+			It("injects a required pod anti-affinity into pods without affinity", func() {
+				m.InstanceGroups[0].Env.AgentEnvBoshConfig.Agent.Settings.Affinity = nil
+				r, err := act(bpmConfigs[0], m.InstanceGroups[0])
+				Expect(err).ShouldNot(HaveOccurred())
+
+				r.MergePodAntiAffinity(bdv1.PodAntiAffinityRequired)
+
+				affinity := r.InstanceGroups[0].Spec.Template.Spec.Template.Spec.Affinity
+				Expect(affinity.NodeAffinity).To(BeNil())
+				Expect(affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution).To(HaveLen(1))
+				Expect(affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution[0].TopologyKey).To(Equal(corev1.LabelHostname))
+			})
+
+			It("keeps the manifest's affinity by default", func() {
+				r, err := act(bpmConfigs[0], m.InstanceGroups[2])
+				Expect(err).ShouldNot(HaveOccurred())
+
+				spec := bdv1.BOSHDeploymentSpec{}
+				r.MergePodAntiAffinity(spec.InstanceGroupPodAntiAffinity(m.InstanceGroups[2].Name))
+
+				antiAffinity := r.InstanceGroups[0].Spec.Template.Spec.Template.Spec.Affinity.PodAntiAffinity
+				Expect(antiAffinity.PreferredDuringSchedulingIgnoredDuringExecution).To(HaveLen(1))
+			})
+
+			It("prefers the policy of the instance group override", func() {
+				spec := bdv1.BOSHDeploymentSpec{
+					PodAntiAffinity: bdv1.PodAntiAffinityPreferred,
+					InstanceGroups: []bdv1.InstanceGroupOverride{
+						{Name: "bpm3", PodAntiAffinity: bdv1.PodAntiAffinityRequired},
+					},
+				}
+				Expect(spec.InstanceGroupPodAntiAffinity("bpm3")).To(Equal(bdv1.PodAntiAffinityRequired))
+				Expect(spec.InstanceGroupPodAntiAffinity("bpm1")).To(Equal(bdv1.PodAntiAffinityPreferred))
+			})
+
+			It("prepends custom init containers, which mount the shared directories", func() {
+				r, err := act(bpmConfigs[0], m.InstanceGroups[0])
+				Expect(err).ShouldNot(HaveOccurred())
+
+				podSpec := &r.InstanceGroups[0].Spec.Template.Spec.Template.Spec
+				podSpec.Volumes = append(podSpec.Volumes,
+					corev1.Volume{Name: bpmconverter.VolumeDataDirName},
+					corev1.Volume{Name: bpmconverter.VolumeSysDirName},
+				)
+				podSpec.InitContainers = []corev1.Container{{Name: "spec-copier-fake"}}
+				generated := podSpec.InitContainers
+
+				custom := []corev1.Container{
+					{Name: "migrate", Image: "migrate:1"},
+					{
+						Name:         "certs",
+						Image:        "certs:1",
+						VolumeMounts: []corev1.VolumeMount{{Name: "certs", MountPath: bpmconverter.VolumeDataDirMountPath}},
+					},
+				}
+				r.PrependInitContainers(custom)
+
+				Expect(podSpec.InitContainers).To(HaveLen(len(generated) + 2))
+				Expect(podSpec.InitContainers[2:]).To(Equal(generated))
+				Expect(podSpec.InitContainers[0].Name).To(Equal("migrate"))
+				Expect(podSpec.InitContainers[0].VolumeMounts).To(Equal([]corev1.VolumeMount{
+					{Name: bpmconverter.VolumeDataDirName, MountPath: bpmconverter.VolumeDataDirMountPath},
+					{Name: bpmconverter.VolumeSysDirName, MountPath: bpmconverter.VolumeSysDirMountPath},
+				}))
+				Expect(podSpec.InitContainers[1].VolumeMounts).To(Equal([]corev1.VolumeMount{
+					{Name: "certs", MountPath: bpmconverter.VolumeDataDirMountPath},
+					{Name: bpmconverter.VolumeSysDirName, MountPath: bpmconverter.VolumeSysDirMountPath},
+				}))
+				Expect(custom[0].VolumeMounts).To(BeEmpty())
+			})
+
+			It("leaves the init containers unchanged without custom ones", func() {
+				r, err := act(bpmConfigs[0], m.InstanceGroups[0])
+				Expect(err).ShouldNot(HaveOccurred())
+				generated := r.InstanceGroups[0].Spec.Template.Spec.Template.Spec.InitContainers
+
+				spec := bdv1.BOSHDeploymentSpec{}
+				r.PrependInitContainers(spec.InitContainers[m.InstanceGroups[0].Name])
+
+				Expect(r.InstanceGroups[0].Spec.Template.Spec.Template.Spec.InitContainers).To(Equal(generated))
+			})
+
+			It("adds the BPM debug container to the instance groups", func() {
+				r, err := act(bpmConfigs[0], m.InstanceGroups[0])
+				Expect(err).ShouldNot(HaveOccurred())
+
+				podSpec := &r.InstanceGroups[0].Spec.Template.Spec.Template.Spec
+				podSpec.Containers = []corev1.Container{
+					{Name: "job-a", VolumeMounts: []corev1.VolumeMount{
+						{Name: bpmconverter.VolumeDataDirName, MountPath: bpmconverter.VolumeDataDirMountPath},
+						{Name: "store", MountPath: "/var/vcap/store/job-a"},
+					}},
+					{Name: "job-b", VolumeMounts: []corev1.VolumeMount{
+						{Name: bpmconverter.VolumeDataDirName, MountPath: bpmconverter.VolumeDataDirMountPath},
+					}},
+				}
+				r.Errands = []qjv1a1.QuarksJob{{}}
+				r.Errands[0].Spec.Template.Spec.Template.Spec.Containers = []corev1.Container{{Name: "errand"}}
+
+				r.AddBPMDebugContainer()
+				r.AddBPMDebugContainer()
+
+				Expect(podSpec.Containers).To(HaveLen(3))
+				debug := podSpec.Containers[2]
+				Expect(debug.Name).To(Equal(bpmconverter.BPMDebugContainerName))
+				Expect(debug.Image).To(Equal("ubuntu:22.04"))
+				Expect(debug.Command).To(Equal([]string{"sleep", "infinity"}))
+				Expect(debug.VolumeMounts).To(Equal([]corev1.VolumeMount{
+					{Name: bpmconverter.VolumeDataDirName, MountPath: bpmconverter.VolumeDataDirMountPath},
+					{Name: "store", MountPath: "/var/vcap/store/job-a"},
+				}))
+				Expect(debug.SecurityContext.Capabilities.Add).To(ConsistOf(corev1.Capability("SYS_PTRACE")))
+				Expect(*podSpec.ShareProcessNamespace).To(BeTrue())
+				Expect(r.Errands[0].Spec.Template.Spec.Template.Spec.Containers).To(HaveLen(1))
+				Expect(r.Errands[0].Spec.Template.Spec.Template.Spec.ShareProcessNamespace).To(BeNil())
+			})
 		})
 
 		Context("when tolerations are provided", func() {
@@ -707,4 +930,156 @@ var _ = Describe("BPM Converter", func() {
 			})
 		})
 	})
+
+	Context("GenerateServiceMonitor", func() {
+		It("scrapes the metrics port of the services matching the labels", func() {
+			c := bpmconverter.NewConverter("foo", volumeFactory, nil)
+			labels := map[string]string{
+				bdm.LabelDeploymentName:    "fake-deployment",
+				bdm.LabelInstanceGroupName: "diego-cell",
+			}
+
+			sm := c.GenerateServiceMonitor("diego-cell", "foo", 9090, labels)
+			Expect(sm.GroupVersionKind()).To(Equal(bpmconverter.ServiceMonitorGroupVersionKind))
+			Expect(sm.GetName()).To(Equal("fake-deployment-diego-cell"))
+			Expect(sm.GetNamespace()).To(Equal("foo"))
+			Expect(sm.GetLabels()).To(Equal(labels))
+
+			matchLabels, _, err := unstructured.NestedStringMap(sm.Object, "spec", "selector", "matchLabels")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(matchLabels).To(Equal(labels))
+
+			endpoints, _, err := unstructured.NestedSlice(sm.Object, "spec", "endpoints")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(endpoints).To(ConsistOf(map[string]interface{}{"targetPort": int64(9090)}))
+
+			namespaces, _, err := unstructured.NestedStringSlice(sm.Object, "spec", "namespaceSelector", "matchNames")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(namespaces).To(ConsistOf("foo"))
+		})
+	})
+
+	Context("GenerateServiceExport", func() {
+		It("exports the service under its name", func() {
+			c := bpmconverter.NewConverter("foo", volumeFactory, nil)
+			labels := map[string]string{
+				bdm.LabelDeploymentName:    "fake-deployment",
+				bdm.LabelInstanceGroupName: "diego-cell",
+			}
+			svc := corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "fake-deployment-diego-cell", Namespace: "foo", Labels: labels}}
+
+			se := c.GenerateServiceExport(svc)
+			Expect(se.GroupVersionKind()).To(Equal(bpmconverter.ServiceExportGroupVersionKind))
+			Expect(se.GetName()).To(Equal("fake-deployment-diego-cell"))
+			Expect(se.GetNamespace()).To(Equal("foo"))
+			Expect(se.GetLabels()).To(Equal(labels))
+		})
+	})
+
+	Context("GenerateServicePerIG", func() {
+		var (
+			c  *bpmconverter.BPMConverter
+			ig bdm.InstanceGroup
+		)
+
+		BeforeEach(func() {
+			c = bpmconverter.NewConverter("foo", volumeFactory, nil)
+			ig = bdm.InstanceGroup{Name: "diego_cell"}
+			ig.Env.AgentEnvBoshConfig.Agent.Settings.Set("fake-deployment", "diego_cell", "1")
+		})
+
+		It("creates a ClusterIP service for the BPM ports, selecting the instance group's pods", func() {
+			svc := c.GenerateServicePerIG(ig, "foo", []int32{8080, 9090})
+			Expect(svc).ToNot(BeNil())
+			Expect(svc.Name).To(Equal("fake-deployment-diego-cell-svc"))
+			Expect(svc.Namespace).To(Equal("foo"))
+			Expect(svc.Labels).To(HaveKeyWithValue(bdm.LabelInstanceGroupName, "diego_cell"))
+			Expect(svc.Spec.Type).To(Equal(corev1.ServiceTypeClusterIP))
+			Expect(svc.Spec.ClusterIP).To(BeEmpty())
+			Expect(svc.Spec.Selector).To(Equal(map[string]string{
+				bdm.LabelDeploymentName:    "fake-deployment",
+				bdm.LabelInstanceGroupName: "diego_cell",
+			}))
+			Expect(svc.Spec.Ports).To(ConsistOf(
+				corev1.ServicePort{Name: "port-8080", Protocol: corev1.ProtocolTCP, Port: 8080},
+				corev1.ServicePort{Name: "port-9090", Protocol: corev1.ProtocolTCP, Port: 9090},
+			))
+		})
+
+		It("returns nil without BPM ports", func() {
+			Expect(c.GenerateServicePerIG(ig, "foo", nil)).To(BeNil())
+		})
+
+		It("returns nil for errands", func() {
+			ig.LifeCycle = bdm.IGTypeErrand
+			Expect(c.GenerateServicePerIG(ig, "foo", []int32{8080})).To(BeNil())
+		})
+
+		It("collects the unique ports of all BPM configs", func() {
+			ports := bpmconverter.BPMPorts(bpm.Configs{
+				"a": bpm.Config{Ports: []bpm.Port{{Name: "http", Internal: 9090}, {Name: "https", Internal: 8443}}},
+				"b": bpm.Config{Ports: []bpm.Port{{Name: "http", Internal: 9090}}},
+			})
+			Expect(ports).To(Equal([]int32{8443, 9090}))
+		})
+	})
+
+	Context("GenerateVolumeClaimTemplates", func() {
+		var (
+			c         *bpmconverter.BPMConverter
+			ig        bdm.InstanceGroup
+			diskTypes map[string]bdm.DiskType
+		)
+
+		BeforeEach(func() {
+			c = bpmconverter.NewConverter("foo", volumeFactory, nil)
+			ig = bdm.InstanceGroup{Name: "diego_cell", PersistentDiskType: "10GB"}
+			diskTypes = map[string]bdm.DiskType{
+				"10GB": {Name: "10GB", DiskSize: 10240},
+				"fast": {Name: "fast", DiskSize: 512, CloudProperties: map[string]interface{}{"storage_class": "ssd"}},
+			}
+		})
+
+		It("requests the size of the persistent disk type with its name as storage class", func() {
+			pvcs, err := c.GenerateVolumeClaimTemplates(ig, diskTypes)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pvcs).To(HaveLen(1))
+			Expect(pvcs[0].Name).To(Equal("diego-cell-pvc"))
+			Expect(pvcs[0].Namespace).To(Equal("foo"))
+			Expect(pvcs[0].Spec.AccessModes).To(ConsistOf(corev1.ReadWriteOnce))
+			Expect(pvcs[0].Spec.Resources.Requests[corev1.ResourceStorage]).To(Equal(resource.MustParse("10240Mi")))
+			Expect(*pvcs[0].Spec.StorageClassName).To(Equal("10GB"))
+		})
+
+		It("uses the storage class of the disk type's cloud properties", func() {
+			ig.PersistentDiskType = "fast"
+
+			pvcs, err := c.GenerateVolumeClaimTemplates(ig, diskTypes)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pvcs[0].Spec.Resources.Requests[corev1.ResourceStorage]).To(Equal(resource.MustParse("512Mi")))
+			Expect(*pvcs[0].Spec.StorageClassName).To(Equal("ssd"))
+		})
+
+		It("generates no templates without a persistent disk type", func() {
+			ig.PersistentDiskType = ""
+
+			pvcs, err := c.GenerateVolumeClaimTemplates(ig, diskTypes)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pvcs).To(BeEmpty())
+		})
+
+		It("fails for unknown disk types", func() {
+			ig.PersistentDiskType = "unknown"
+
+			_, err := c.GenerateVolumeClaimTemplates(ig, diskTypes)
+			Expect(err).To(MatchError("instance group 'diego_cell' uses unknown persistent disk type 'unknown'"))
+		})
+
+		It("fails for disk types without a size", func() {
+			diskTypes["10GB"] = bdm.DiskType{Name: "10GB"}
+
+			_, err := c.GenerateVolumeClaimTemplates(ig, diskTypes)
+			Expect(err).To(MatchError("persistent disk type '10GB' of instance group 'diego_cell' has no disk size"))
+		})
+	})
 })
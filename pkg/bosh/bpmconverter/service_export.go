@@ -0,0 +1,28 @@
+package bpmconverter
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ServiceExportGroupVersionKind is the kind of the Multicluster Services API's
+// ServiceExport. The operator doesn't depend on the Multicluster Services API
+// types, so ServiceExports are unstructured objects.
+var ServiceExportGroupVersionKind = schema.GroupVersionKind{
+	Group:   "multicluster.x-k8s.io",
+	Version: "v1alpha1",
+	Kind:    "ServiceExport",
+}
+
+// GenerateServiceExport returns a ServiceExport, which propagates the service
+// to the other clusters of the cluster set. It is named like the service.
+func (kc *BPMConverter) GenerateServiceExport(service corev1.Service) *unstructured.Unstructured {
+	se := &unstructured.Unstructured{}
+	se.SetGroupVersionKind(ServiceExportGroupVersionKind)
+	se.SetName(service.Name)
+	se.SetNamespace(service.Namespace)
+	se.SetLabels(service.Labels)
+
+	return se
+}
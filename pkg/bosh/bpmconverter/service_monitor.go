@@ -0,0 +1,52 @@
+package bpmconverter
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util"
+)
+
+// MetricsPortName is the name of the instance group port, which is scraped by the generated ServiceMonitor
+const MetricsPortName = "metrics"
+
+// ServiceMonitorGroupVersionKind is the kind of the Prometheus Operator's ServiceMonitor.
+// The operator doesn't depend on the Prometheus Operator's API types, so
+// ServiceMonitors are unstructured objects.
+var ServiceMonitorGroupVersionKind = schema.GroupVersionKind{
+	Group:   "monitoring.coreos.com",
+	Version: "v1",
+	Kind:    "ServiceMonitor",
+}
+
+// GenerateServiceMonitor returns a ServiceMonitor for the instance group, which
+// scrapes metricsPort of the services matching labels, i.e. the instance group's
+// headless service. It is named like the headless service.
+func (kc *BPMConverter) GenerateServiceMonitor(igName, namespace string, metricsPort int, labels map[string]string) *unstructured.Unstructured {
+	matchLabels := map[string]interface{}{}
+	for k, v := range labels {
+		matchLabels[k] = v
+	}
+
+	sm := &unstructured.Unstructured{}
+	sm.SetGroupVersionKind(ServiceMonitorGroupVersionKind)
+	sm.SetName(util.ServiceName(igName, labels[bdm.LabelDeploymentName], 63))
+	sm.SetNamespace(namespace)
+	sm.SetLabels(labels)
+	sm.Object["spec"] = map[string]interface{}{
+		"endpoints": []interface{}{
+			map[string]interface{}{
+				"targetPort": int64(metricsPort),
+			},
+		},
+		"namespaceSelector": map[string]interface{}{
+			"matchNames": []interface{}{namespace},
+		},
+		"selector": map[string]interface{}{
+			"matchLabels": matchLabels,
+		},
+	}
+
+	return sm
+}
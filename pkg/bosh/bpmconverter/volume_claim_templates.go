@@ -0,0 +1,50 @@
+package bpmconverter
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+	"code.cloudfoundry.org/quarks-utils/pkg/names"
+)
+
+// GenerateVolumeClaimTemplates returns the volume claim templates for the
+// 'persistent_disk_type' of the instance group. The size and storage class of
+// the claim are looked up in diskTypes, the disk types of the cloud config.
+// Instance groups without a persistent disk type get no templates.
+func (kc *BPMConverter) GenerateVolumeClaimTemplates(ig bdm.InstanceGroup, diskTypes map[string]bdm.DiskType) ([]corev1.PersistentVolumeClaim, error) {
+	if ig.PersistentDiskType == "" {
+		return []corev1.PersistentVolumeClaim{}, nil
+	}
+
+	diskType, ok := diskTypes[ig.PersistentDiskType]
+	if !ok {
+		return nil, errors.Errorf("instance group '%s' uses unknown persistent disk type '%s'", ig.Name, ig.PersistentDiskType)
+	}
+	if diskType.DiskSize <= 0 {
+		return nil, errors.Errorf("persistent disk type '%s' of instance group '%s' has no disk size", ig.PersistentDiskType, ig.Name)
+	}
+
+	storageClass := diskType.StorageClass()
+	return []corev1.PersistentVolumeClaim{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      names.Sanitize(fmt.Sprintf("%s-%s", ig.Name, "pvc")),
+				Namespace: kc.namespace,
+			},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{
+						corev1.ResourceStorage: resource.MustParse(fmt.Sprintf("%d%s", diskType.DiskSize, "Mi")),
+					},
+				},
+				StorageClassName: &storageClass,
+			},
+		},
+	}, nil
+}
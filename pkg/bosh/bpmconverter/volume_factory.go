@@ -15,6 +15,7 @@ import (
 	"code.cloudfoundry.org/cf-operator/pkg/bosh/bpm"
 	"code.cloudfoundry.org/cf-operator/pkg/bosh/disk"
 	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+	bdnames "code.cloudfoundry.org/cf-operator/pkg/kube/util/names"
 	"code.cloudfoundry.org/quarks-utils/pkg/names"
 )
 
@@ -68,11 +69,12 @@ const (
 
 // VolumeFactoryImpl is a concrete implementation of VolumeFactoryImpl
 type VolumeFactoryImpl struct {
+	secretNamer bdnames.SecretNamer
 }
 
 // NewVolumeFactory returns a concrete implementation of VolumeFactory
-func NewVolumeFactory() *VolumeFactoryImpl {
-	return &VolumeFactoryImpl{}
+func NewVolumeFactory(secretNamer bdnames.SecretNamer) *VolumeFactoryImpl {
+	return &VolumeFactoryImpl{secretNamer: secretNamer}
 }
 
 // GenerateDefaultDisks defines default disks. This looks for:
@@ -83,7 +85,7 @@ func NewVolumeFactory() *VolumeFactoryImpl {
 // - the "not interpolated" manifest volume
 // - resolved properties data volume
 func (f *VolumeFactoryImpl) GenerateDefaultDisks(manifestName string, instanceGroupName string, igResolvedSecretVersion string, namespace string) disk.BPMResourceDisks {
-	resolvedPropertiesSecretName := names.InstanceGroupSecretName(
+	resolvedPropertiesSecretName := f.secretNamer.InstanceGroupSecretName(
 		names.DeploymentSecretTypeInstanceGroupResolvedProperties,
 		manifestName,
 		instanceGroupName,
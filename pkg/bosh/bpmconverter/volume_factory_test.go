@@ -13,6 +13,7 @@ import (
 	. "code.cloudfoundry.org/cf-operator/pkg/bosh/bpmconverter"
 	"code.cloudfoundry.org/cf-operator/pkg/bosh/disk"
 	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+	bdnames "code.cloudfoundry.org/cf-operator/pkg/kube/util/names"
 	"code.cloudfoundry.org/quarks-utils/pkg/names"
 	"code.cloudfoundry.org/quarks-utils/pkg/pointers"
 	corev1 "k8s.io/api/core/v1"
@@ -43,7 +44,7 @@ var _ = Describe("VolumeFactory", func() {
 		bpmConfigs = &bpm.Configs{
 			"fake-job": bpm.Config{},
 		}
-		factory = NewVolumeFactory()
+		factory = NewVolumeFactory(bdnames.DefaultSecretNamer{})
 	})
 
 	Describe("GenerateDefaultDisks", func() {
@@ -102,6 +103,22 @@ var _ = Describe("VolumeFactory", func() {
 				},
 			}))
 		})
+
+		It("names the resolved properties secret with the secret namer", func() {
+			factory = NewVolumeFactory(bdnames.PrefixSecretNamer{Prefix: "custom"})
+			disks := factory.GenerateDefaultDisks(manifestName, instanceGroup.Name, version, namespace)
+
+			Expect(disks).Should(ContainElement(disk.BPMResourceDisk{
+				Volume: &corev1.Volume{
+					Name: "ig-resolved",
+					VolumeSource: corev1.VolumeSource{
+						Secret: &corev1.SecretVolumeSource{
+							SecretName: fmt.Sprintf("custom-%s.ig-resolved.%s-v%s", manifestName, instanceGroup.Name, version),
+						},
+					},
+				},
+			}))
+		})
 	})
 
 	Describe("GenerateBPMDisks", func() {
@@ -8,18 +8,24 @@ import (
 
 	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
 	qsv1a1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/quarkssecret/v1alpha1"
+	bdnames "code.cloudfoundry.org/cf-operator/pkg/kube/util/names"
 	"code.cloudfoundry.org/quarks-utils/pkg/names"
 )
 
+// LabelVariableName is the label key for the BOSH variable name on QuarksSecrets
+const LabelVariableName = "variableName"
+
 // VariablesConverter represents a BOSH manifest into kubernetes resources
 type VariablesConverter struct {
-	namespace string
+	namespace   string
+	secretNamer bdnames.SecretNamer
 }
 
 // NewVariablesConverter converts a BOSH manifest into kubernetes resources
-func NewVariablesConverter(namespace string) *VariablesConverter {
+func NewVariablesConverter(namespace string, secretNamer bdnames.SecretNamer) *VariablesConverter {
 	return &VariablesConverter{
-		namespace: namespace,
+		namespace:   namespace,
+		secretNamer: secretNamer,
 	}
 }
 
@@ -27,14 +33,19 @@ func NewVariablesConverter(namespace string) *VariablesConverter {
 func (vc *VariablesConverter) Variables(manifestName string, variables []bdm.Variable) ([]qsv1a1.QuarksSecret, error) {
 	secrets := []qsv1a1.QuarksSecret{}
 
+	declared := map[string]bool{}
+	for _, v := range variables {
+		declared[v.Name] = true
+	}
+
 	for _, v := range variables {
-		secretName := names.DeploymentSecretName(names.DeploymentSecretTypeVariable, manifestName, v.Name)
+		secretName := vc.secretNamer.DeploymentSecretName(names.DeploymentSecretTypeVariable, manifestName, v.Name)
 		s := qsv1a1.QuarksSecret{
 			ObjectMeta: metav1.ObjectMeta{
 				Name:      secretName,
 				Namespace: vc.namespace,
 				Labels: map[string]string{
-					"variableName":          v.Name,
+					LabelVariableName:       v.Name,
 					bdm.LabelDeploymentName: manifestName,
 				},
 			},
@@ -43,6 +54,19 @@ func (vc *VariablesConverter) Variables(manifestName string, variables []bdm.Var
 				SecretName: secretName,
 			},
 		}
+		for _, name := range v.RegenerateOnChange {
+			if !declared[name] {
+				return secrets, fmt.Errorf("invalid QuarksSecret '%s': regenerateOnChange references unknown variable '%s'", v.Name, name)
+			}
+			s.Spec.RegenerateOnChange = append(s.Spec.RegenerateOnChange,
+				vc.secretNamer.DeploymentSecretName(names.DeploymentSecretTypeVariable, manifestName, name))
+		}
+		if v.Type == qsv1a1.CredHub {
+			if v.Options == nil || v.Options.CredHubPath == "" {
+				return secrets, fmt.Errorf("invalid credhub QuarksSecret: missing options.credhub_path key")
+			}
+			s.Annotations = map[string]string{qsv1a1.AnnotationCredHubPath: v.Options.CredHubPath}
+		}
 		if v.Type == qsv1a1.Certificate {
 			if v.Options == nil {
 				return secrets, fmt.Errorf("invalid certificate QuarksSecret: missing options key")
@@ -83,11 +107,11 @@ func (vc *VariablesConverter) Variables(manifestName string, variables []bdm.Var
 			}
 			if v.Options.CA != "" {
 				certRequest.CARef = qsv1a1.SecretReference{
-					Name: names.DeploymentSecretName(names.DeploymentSecretTypeVariable, manifestName, v.Options.CA),
+					Name: vc.secretNamer.DeploymentSecretName(names.DeploymentSecretTypeVariable, manifestName, v.Options.CA),
 					Key:  "certificate",
 				}
 				certRequest.CAKeyRef = qsv1a1.SecretReference{
-					Name: names.DeploymentSecretName(names.DeploymentSecretTypeVariable, manifestName, v.Options.CA),
+					Name: vc.secretNamer.DeploymentSecretName(names.DeploymentSecretTypeVariable, manifestName, v.Options.CA),
 					Key:  "private_key",
 				}
 			}
@@ -98,3 +122,44 @@ func (vc *VariablesConverter) Variables(manifestName string, variables []bdm.Var
 
 	return secrets, nil
 }
+
+// VariablesByType returns quarks secrets for a list of BOSH variables, grouped by the variable type
+func (vc *VariablesConverter) VariablesByType(manifestName string, variables []bdm.Variable) (map[string][]qsv1a1.QuarksSecret, error) {
+	secrets, err := vc.Variables(manifestName, variables)
+	if err != nil {
+		return nil, err
+	}
+
+	byType := map[string][]qsv1a1.QuarksSecret{}
+	for i, v := range variables {
+		byType[v.Type] = append(byType[v.Type], secrets[i])
+	}
+
+	return byType, nil
+}
+
+// VariableOrder returns the variables in creation order, so each variable
+// follows the CA in its `options.ca` and the variables referenced in its
+// `options.alternative_names`. Independent variables keep their order.
+func (vc *VariablesConverter) VariableOrder(variables []bdm.Variable) ([]bdm.Variable, error) {
+	m := &bdm.Manifest{Variables: variables}
+	graph, err := m.VariableGraph()
+	if err != nil {
+		return nil, err
+	}
+	order, err := graph.TopologicalOrder()
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]bdm.Variable, len(variables))
+	for _, v := range variables {
+		byName[v.Name] = v
+	}
+	ordered := make([]bdm.Variable, 0, len(order))
+	for _, name := range order {
+		ordered = append(ordered, byName[name])
+	}
+
+	return ordered, nil
+}
@@ -8,7 +8,9 @@ import (
 	"code.cloudfoundry.org/cf-operator/pkg/bosh/converter"
 	"code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
 	qsv1a1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/quarkssecret/v1alpha1"
+	bdnames "code.cloudfoundry.org/cf-operator/pkg/kube/util/names"
 	"code.cloudfoundry.org/cf-operator/testing"
+	"code.cloudfoundry.org/quarks-utils/pkg/names"
 )
 
 var _ = Describe("kube converter", func() {
@@ -17,8 +19,13 @@ var _ = Describe("kube converter", func() {
 		m              *manifest.Manifest
 		env            testing.Catalog
 		err            error
+		secretNamer    bdnames.SecretNamer
 	)
 
+	BeforeEach(func() {
+		secretNamer = bdnames.DefaultSecretNamer{}
+	})
+
 	Describe("Variables", func() {
 		BeforeEach(func() {
 			deploymentName = "foo-deployment"
@@ -28,7 +35,7 @@ var _ = Describe("kube converter", func() {
 		})
 
 		act := func() ([]qsv1a1.QuarksSecret, error) {
-			kubeConverter := converter.NewVariablesConverter("foo")
+			kubeConverter := converter.NewVariablesConverter("foo", secretNamer)
 			return kubeConverter.Variables(deploymentName, m.Variables)
 		}
 
@@ -51,6 +58,15 @@ var _ = Describe("kube converter", func() {
 				Expect(variables[0].Name).To(Equal("foo.var-this-is-waaaaaaaaaaaaaa5bffdb0302ac051d11f52d2606254a5f"))
 			})
 
+			It("names secrets with the secret namer", func() {
+				secretNamer = customSecretNamer{}
+
+				variables, err := act()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(variables[0].Name).To(Equal("custom-foo-deployment-adminpass"))
+				Expect(variables[0].Spec.SecretName).To(Equal("custom-foo-deployment-adminpass"))
+			})
+
 			It("converts password variables", func() {
 				variables, err := act()
 				Expect(err).NotTo(HaveOccurred())
@@ -94,6 +110,31 @@ var _ = Describe("kube converter", func() {
 				Expect(var1.Spec.SecretName).To(Equal("foo-deployment.var-adminkey"))
 			})
 
+			It("converts credhub variables", func() {
+				m.Variables[0] = manifest.Variable{
+					Name:    "uaa_admin",
+					Type:    "credhub",
+					Options: &manifest.VariableOptions{CredHubPath: "/cf/uaa_admin"},
+				}
+				variables, err := act()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(variables).To(HaveLen(1))
+
+				var1 := variables[0]
+				Expect(var1.Name).To(Equal("foo-deployment.var-uaa-admin"))
+				Expect(var1.Spec.Type).To(Equal(qsv1a1.CredHub))
+				Expect(var1.GetAnnotations()).To(HaveKeyWithValue(qsv1a1.AnnotationCredHubPath, "/cf/uaa_admin"))
+			})
+
+			It("raises an error when the credhub path is missing for a credhub variable", func() {
+				m.Variables[0] = manifest.Variable{
+					Name: "uaa_admin",
+					Type: "credhub",
+				}
+				_, err := act()
+				Expect(err).To(MatchError("invalid credhub QuarksSecret: missing options.credhub_path key"))
+			})
+
 			It("raises an error when the options are missing for a certificate variable", func() {
 				m.Variables[0] = manifest.Variable{
 					Name: "foo-cert",
@@ -131,7 +172,119 @@ var _ = Describe("kube converter", func() {
 				Expect(request.CARef.Name).To(Equal("foo-deployment.var-theca"))
 				Expect(request.CARef.Key).To(Equal("certificate"))
 			})
+
+			It("converts the variables, which regenerate a variable, to QuarksSecret names", func() {
+				m.Variables = append(m.Variables, manifest.Variable{
+					Name:               "adminkey",
+					Type:               "rsa",
+					RegenerateOnChange: []string{"adminpass"},
+				})
+				variables, err := act()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(variables).To(HaveLen(2))
+				Expect(variables[0].Spec.RegenerateOnChange).To(BeEmpty())
+				Expect(variables[1].Spec.RegenerateOnChange).To(Equal([]string{"foo-deployment.var-adminpass"}))
+			})
+
+			It("raises an error when a variable regenerates on change of an unknown variable", func() {
+				m.Variables[0].RegenerateOnChange = []string{"missing"}
+				_, err := act()
+				Expect(err).To(MatchError("invalid QuarksSecret 'adminpass': regenerateOnChange references unknown variable 'missing'"))
+			})
+		})
+
+	})
+
+	Describe("VariablesByType", func() {
+		BeforeEach(func() {
+			deploymentName = "foo-deployment"
+			m, err = env.DefaultBOSHManifest()
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		act := func() (map[string][]qsv1a1.QuarksSecret, error) {
+			kubeConverter := converter.NewVariablesConverter("foo", secretNamer)
+			return kubeConverter.VariablesByType(deploymentName, m.Variables)
+		}
+
+		It("groups quarks secrets by variable type", func() {
+			m.Variables = append(m.Variables,
+				manifest.Variable{
+					Name:    "foo-cert",
+					Type:    "certificate",
+					Options: &manifest.VariableOptions{CommonName: "example.com"},
+				},
+				manifest.Variable{Name: "otherpass", Type: "password"},
+			)
+
+			variables, err := act()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(variables).To(HaveLen(2))
+
+			Expect(variables["password"]).To(HaveLen(2))
+			Expect(variables["password"][0].Name).To(Equal("foo-deployment.var-adminpass"))
+			Expect(variables["password"][1].Name).To(Equal("foo-deployment.var-otherpass"))
+
+			Expect(variables["certificate"]).To(HaveLen(1))
+			Expect(variables["certificate"][0].Name).To(Equal("foo-deployment.var-foo-cert"))
+			Expect(variables["certificate"][0].Spec.Request.CertificateRequest.CommonName).To(Equal("example.com"))
+		})
+
+		It("returns the conversion error", func() {
+			m.Variables[0] = manifest.Variable{
+				Name: "foo-cert",
+				Type: "certificate",
+			}
+
+			_, err := act()
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("VariableOrder", func() {
+		act := func(variables []manifest.Variable) ([]manifest.Variable, error) {
+			kubeConverter := converter.NewVariablesConverter("foo", secretNamer)
+			return kubeConverter.VariableOrder(variables)
+		}
+
+		names := func(variables []manifest.Variable) []string {
+			result := []string{}
+			for _, v := range variables {
+				result = append(result, v.Name)
+			}
+			return result
+		}
+
+		It("orders CAs and referenced variables before their dependents", func() {
+			variables, err := act([]manifest.Variable{
+				{Name: "leaf-cert", Type: "certificate", Options: &manifest.VariableOptions{
+					CA:               "the-ca",
+					AlternativeNames: []string{"((router-ip))"},
+				}},
+				{Name: "adminpass", Type: "password"},
+				{Name: "router-ip", Type: "password"},
+				{Name: "the-ca", Type: "certificate", Options: &manifest.VariableOptions{IsCA: true}},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(names(variables)).To(Equal([]string{"adminpass", "router-ip", "the-ca", "leaf-cert"}))
+			Expect(variables[3].Options.CA).To(Equal("the-ca"))
 		})
 
+		It("fails for cyclic references", func() {
+			_, err := act([]manifest.Variable{
+				{Name: "a", Type: "certificate", Options: &manifest.VariableOptions{CA: "b"}},
+				{Name: "b", Type: "certificate", Options: &manifest.VariableOptions{CA: "a"}},
+			})
+			Expect(err).To(MatchError(ContainSubstring("cyclic CA reference")))
+		})
 	})
 })
+
+// customSecretNamer names secrets without the secret type
+type customSecretNamer struct {
+	bdnames.DefaultSecretNamer
+}
+
+func (customSecretNamer) DeploymentSecretName(_ names.DeploymentSecretType, deploymentName, name string) string {
+	return "custom-" + deploymentName + "-" + name
+}
@@ -0,0 +1,103 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// CloudConfig from BOSH, which provides the defaults for the vm types,
+// disk types and networks of a deployment manifest
+type CloudConfig struct {
+	VMTypes         []VMType               `json:"vm_types,omitempty"`
+	DiskTypes       []DiskType             `json:"disk_types,omitempty"`
+	Networks        []CloudConfigNetwork   `json:"networks,omitempty"`
+	CloudProperties map[string]interface{} `json:"cloud_properties,omitempty"`
+}
+
+// VMType from the vm types of a BOSH cloud config
+type VMType struct {
+	Name            string                 `json:"name"`
+	CloudProperties map[string]interface{} `json:"cloud_properties,omitempty"`
+}
+
+// CloudConfigNetwork from the networks of a BOSH cloud config
+type CloudConfigNetwork struct {
+	Name string `json:"name"`
+	Type string `json:"type,omitempty"`
+}
+
+// LoadCloudConfig returns a new BOSH cloud config from a yaml representation
+func LoadCloudConfig(data []byte) (*CloudConfig, error) {
+	c := &CloudConfig{}
+	err := yaml.Unmarshal(data, c, func(opt *json.Decoder) *json.Decoder {
+		opt.UseNumber()
+		return opt
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal BOSH cloud config")
+	}
+
+	return c, nil
+}
+
+// DefaultVMType returns the 'default_vm_type' cloud property of the cloud config
+func (c CloudConfig) DefaultVMType() string {
+	if vmType, ok := c.CloudProperties["default_vm_type"].(string); ok {
+		return vmType
+	}
+	return ""
+}
+
+// ApplyCloudConfig decorates the instance groups with the defaults of the
+// cloud config. Instance groups without a 'vm_type' get the cloud config's
+// 'default_vm_type' cloud property. The persistent disk of instance groups,
+// whose 'persistent_disk_type' is a disk type of the cloud config, gets the
+// disk type's size, unless it has one, and its storage class. Other disk types
+// are used as storage class names, as before. All networks referenced by the
+// instance groups have to exist in the cloud config.
+func (m *Manifest) ApplyCloudConfig(cloudConfig CloudConfig) error {
+	networks := map[string]bool{}
+	for _, network := range cloudConfig.Networks {
+		networks[network.Name] = true
+	}
+
+	diskTypes := map[string]DiskType{}
+	for _, diskType := range cloudConfig.DiskTypes {
+		diskTypes[diskType.Name] = diskType
+	}
+
+	unknown := []string{}
+	for _, ig := range m.InstanceGroups {
+		for _, network := range ig.Networks {
+			if !networks[network.Name] {
+				unknown = append(unknown, fmt.Sprintf("instance group '%s' references unknown network '%s'", ig.Name, network.Name))
+			}
+		}
+	}
+	if len(unknown) > 0 {
+		return errors.Errorf("invalid networks: %s", strings.Join(unknown, ", "))
+	}
+
+	defaultVMType := cloudConfig.DefaultVMType()
+	for _, ig := range m.InstanceGroups {
+		if ig.VMType == "" {
+			ig.VMType = defaultVMType
+		}
+
+		diskType, ok := diskTypes[ig.PersistentDiskType]
+		if !ok {
+			continue
+		}
+		if ig.PersistentDisk == nil && diskType.DiskSize > 0 {
+			diskSize := diskType.DiskSize
+			ig.PersistentDisk = &diskSize
+		}
+		ig.PersistentDiskType = diskType.StorageClass()
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package manifest_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+)
+
+var _ = Describe("ApplyCloudConfig", func() {
+	var (
+		m           *Manifest
+		cloudConfig *CloudConfig
+	)
+
+	BeforeEach(func() {
+		var err error
+		m, err = LoadYAML([]byte(`
+name: foo
+instance_groups:
+- name: api
+  instances: 1
+  networks:
+  - name: default
+  persistent_disk_type: fast
+- name: worker
+  instances: 1
+  vm_type: large
+  persistent_disk: 2048
+  persistent_disk_type: 10GB
+- name: db
+  instances: 1
+  persistent_disk: 1024
+  persistent_disk_type: standard
+`))
+		Expect(err).ToNot(HaveOccurred())
+
+		cloudConfig, err = LoadCloudConfig([]byte(`
+vm_types:
+- name: small
+- name: large
+disk_types:
+- name: fast
+  disk_size: 512
+  cloud_properties:
+    storage_class: ssd
+- name: 10GB
+  disk_size: 10240
+networks:
+- name: default
+  type: manual
+cloud_properties:
+  default_vm_type: small
+`))
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("fills in missing vm types from the default vm type", func() {
+		Expect(m.ApplyCloudConfig(*cloudConfig)).To(Succeed())
+
+		Expect(m.InstanceGroups[0].VMType).To(Equal("small"))
+		Expect(m.InstanceGroups[1].VMType).To(Equal("large"))
+	})
+
+	It("applies the disk types to the persistent disks", func() {
+		Expect(m.ApplyCloudConfig(*cloudConfig)).To(Succeed())
+
+		Expect(*m.InstanceGroups[0].PersistentDisk).To(Equal(512))
+		Expect(m.InstanceGroups[0].PersistentDiskType).To(Equal("ssd"))
+		Expect(*m.InstanceGroups[1].PersistentDisk).To(Equal(2048))
+		Expect(m.InstanceGroups[1].PersistentDiskType).To(Equal("10GB"))
+	})
+
+	It("keeps disk types, which are not in the cloud config", func() {
+		Expect(m.ApplyCloudConfig(*cloudConfig)).To(Succeed())
+
+		Expect(*m.InstanceGroups[2].PersistentDisk).To(Equal(1024))
+		Expect(m.InstanceGroups[2].PersistentDiskType).To(Equal("standard"))
+	})
+
+	It("fails for networks, which are not in the cloud config", func() {
+		m.InstanceGroups[1].Networks = []*Network{{Name: "private"}}
+
+		err := m.ApplyCloudConfig(*cloudConfig)
+		Expect(err).To(MatchError("invalid networks: instance group 'worker' references unknown network 'private'"))
+		Expect(m.InstanceGroups[0].VMType).To(BeEmpty())
+	})
+})
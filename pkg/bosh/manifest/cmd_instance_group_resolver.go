@@ -34,6 +34,7 @@ type InstanceGroupResolver struct {
 	jobProviderLinks jobProviderLinks
 	fs               afero.Fs
 	dns              DomainNameService
+	renderCache      *RenderCache
 }
 
 // NewInstanceGroupResolver returns a data gatherer with logging for a given input manifest and instance group
@@ -55,6 +56,12 @@ func NewInstanceGroupResolver(fs afero.Fs, basedir string, deploymentName string
 	}, nil
 }
 
+// UseRenderCache makes the resolver reuse rendered BPM templates from the cache,
+// as long as their inputs are unchanged
+func (igr *InstanceGroupResolver) UseRenderCache(cache *RenderCache) {
+	igr.renderCache = cache
+}
+
 // Resolve collects bpm and link information and enriches the manifest accordingly
 //
 // Data gathered:
@@ -383,39 +390,19 @@ func (igr *InstanceGroupResolver) renderJobBPM(currentJob *Job, jobSpecFile stri
 	jobIndexBPM := make([]bpm.Config, len(jobInstances))
 	for i, jobInstance := range jobInstances {
 		properties := currentJob.Properties.ToMap()
-
-		renderPointer := btg.NewERBRenderer(
-			&btg.EvaluationContext{
-				Properties: properties,
-			},
-			&btg.InstanceInfo{
-				Address:    jobInstance.Address,
-				AZ:         jobInstance.AZ,
-				Bootstrap:  jobInstance.Bootstrap,
-				ID:         jobInstance.ID,
-				Index:      jobInstance.Index,
-				Deployment: igr.deploymentName,
-				Name:       jobInstance.Name,
-			},
-			jobSpecFile,
-		)
-
-		// Write to a tmp, this is following the conventions on how the
-		// https://github.com/viovanov/bosh-template-go/ processes the params
-		// when we calling the *.Render().
-		tmpfile, err := ioutil.TempFile("", "rendered.*.yml")
-		if err != nil {
-			return errors.Wrapf(err, "Creation of tmp file %s failed", tmpfile.Name())
-		}
-		defer os.Remove(tmpfile.Name())
-
-		if err := renderPointer.Render(erbFilePath, tmpfile.Name()); err != nil {
-			return errors.Wrapf(err, "Rendering file %s failed", erbFilePath)
+		instanceInfo := &btg.InstanceInfo{
+			Address:    jobInstance.Address,
+			AZ:         jobInstance.AZ,
+			Bootstrap:  jobInstance.Bootstrap,
+			ID:         jobInstance.ID,
+			Index:      jobInstance.Index,
+			Deployment: igr.deploymentName,
+			Name:       jobInstance.Name,
 		}
 
-		bpmBytes, err := ioutil.ReadFile(tmpfile.Name())
+		bpmBytes, err := igr.renderTemplate(currentJob, jobSpecFile, erbFilePath, properties, instanceInfo)
 		if err != nil {
-			return errors.Wrapf(err, "Reading of tmp file %s failed", tmpfile.Name())
+			return err
 		}
 
 		// Parse a rendered bpm.yml into the bpm Config struct.
@@ -461,6 +448,60 @@ func (igr *InstanceGroupResolver) renderJobBPM(currentJob *Job, jobSpecFile stri
 	return nil
 }
 
+// renderTemplate renders the ERB template of the job for one instance. If a
+// render cache is used, the output of an earlier render with the same inputs
+// is reused.
+func (igr *InstanceGroupResolver) renderTemplate(currentJob *Job, jobSpecFile string, erbFilePath string, properties map[string]interface{}, instanceInfo *btg.InstanceInfo) ([]byte, error) {
+	var renderer TemplateRenderer = erbRenderer{}
+	if igr.renderCache != nil {
+		renderer = NewCachedRenderer(igr.renderCache, renderer)
+	}
+
+	return renderer.Render(TemplateRender{
+		Release:        currentJob.Release,
+		ReleaseVersion: releaseVersion(igr.manifest.Releases, currentJob.Release),
+		Job:            currentJob.Name,
+		JobSpecFile:    jobSpecFile,
+		TemplateFile:   erbFilePath,
+		Properties:     properties,
+		Instance:       instanceInfo,
+	})
+}
+
+// erbRenderer renders templates with the ERB renderer of bosh-template-go
+type erbRenderer struct{}
+
+// Render renders the template into a temporary file and returns its content
+func (erbRenderer) Render(render TemplateRender) ([]byte, error) {
+	renderPointer := btg.NewERBRenderer(
+		&btg.EvaluationContext{
+			Properties: render.Properties,
+		},
+		render.Instance,
+		render.JobSpecFile,
+	)
+
+	// Write to a tmp, this is following the conventions on how the
+	// https://github.com/viovanov/bosh-template-go/ processes the params
+	// when we calling the *.Render().
+	tmpfile, err := ioutil.TempFile("", "rendered.*.yml")
+	if err != nil {
+		return nil, errors.Wrapf(err, "Creation of tmp file %s failed", tmpfile.Name())
+	}
+	defer os.Remove(tmpfile.Name())
+
+	if err := renderPointer.Render(render.TemplateFile, tmpfile.Name()); err != nil {
+		return nil, errors.Wrapf(err, "Rendering file %s failed", render.TemplateFile)
+	}
+
+	rendered, err := ioutil.ReadFile(tmpfile.Name())
+	if err != nil {
+		return nil, errors.Wrapf(err, "Reading of tmp file %s failed", tmpfile.Name())
+	}
+
+	return rendered, nil
+}
+
 // generateJobConsumersData will populate a job with its corresponding provider links
 // under properties.quarks.consumes
 func generateJobConsumersData(currentJob *Job, jobReleaseSpecs map[string]map[string]JobSpec, jobProviderLinks jobProviderLinks) error {
@@ -559,6 +600,16 @@ func lookUpJobRelease(releases []*Release, jobRelease string) bool {
 	return false
 }
 
+// releaseVersion returns the version of the release from the manifest's releases block
+func releaseVersion(releases []*Release, name string) string {
+	for _, release := range releases {
+		if release.Name == name {
+			return release.Version
+		}
+	}
+	return ""
+}
+
 // mergeNestedExplicitProperty merges an explicitly set Job property into an existing
 // map of properties
 func mergeNestedExplicitProperty(properties map[string]interface{}, job Job, propertyName string) {
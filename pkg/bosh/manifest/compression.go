@@ -0,0 +1,74 @@
+package manifest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// Compress gzip compresses manifest bytes, so large manifests fit into a secret
+func Compress(manifestBytes []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(manifestBytes); err != nil {
+		return nil, errors.Wrap(err, "compressing manifest")
+	}
+	if err := w.Close(); err != nil {
+		return nil, errors.Wrap(err, "compressing manifest")
+	}
+	return buf.Bytes(), nil
+}
+
+// Decompress returns the manifest bytes of gzip compressed data
+func Decompress(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.Wrap(err, "decompressing manifest")
+	}
+	defer r.Close()
+
+	manifestBytes, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "decompressing manifest")
+	}
+	return manifestBytes, nil
+}
+
+// SecretData returns the manifest stored in the data of a secret, which is
+// either plain in the DesiredManifestKeyName key or compressed in the
+// CompressedManifestKeyName key. It returns nil, if data contains neither.
+func SecretData(data map[string][]byte) ([]byte, error) {
+	if compressed, ok := data[CompressedManifestKeyName]; ok {
+		if _, plain := data[DesiredManifestKeyName]; !plain {
+			return Decompress(compressed)
+		}
+	}
+	return data[DesiredManifestKeyName], nil
+}
+
+// ReadFile reads a manifest file. If it doesn't exist, it reads the gzip
+// compressed manifest next to it, which has the '.gz' extension.
+func ReadFile(path string) ([]byte, error) {
+	manifestBytes, err := ioutil.ReadFile(path)
+	if !os.IsNotExist(err) {
+		return manifestBytes, err
+	}
+
+	compressed, gzErr := ioutil.ReadFile(path + ".gz")
+	if gzErr != nil {
+		return nil, err
+	}
+	return Decompress(compressed)
+}
+
+// FileExists returns true, if the manifest file or its gzip compressed variant exists
+func FileExists(path string) bool {
+	if _, err := os.Stat(path); err == nil {
+		return true
+	}
+	_, err := os.Stat(path + ".gz")
+	return err == nil
+}
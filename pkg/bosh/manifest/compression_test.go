@@ -0,0 +1,105 @@
+package manifest_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+)
+
+var _ = Describe("Compression", func() {
+	manifestBytes := []byte("name: foo\ninstance_groups: []\n")
+
+	compress := func(data []byte) []byte {
+		compressed, err := Compress(data)
+		Expect(err).ToNot(HaveOccurred())
+		return compressed
+	}
+
+	It("decompresses compressed manifests", func() {
+		compressed := compress(manifestBytes)
+		Expect(compressed).ToNot(Equal(manifestBytes))
+
+		decompressed, err := Decompress(compressed)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decompressed).To(Equal(manifestBytes))
+	})
+
+	It("compresses deterministically", func() {
+		Expect(compress(manifestBytes)).To(Equal(compress(manifestBytes)))
+	})
+
+	Describe("SecretData", func() {
+		It("reads plain manifests", func() {
+			data, err := SecretData(map[string][]byte{DesiredManifestKeyName: manifestBytes})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal(manifestBytes))
+		})
+
+		It("decompresses compressed manifests", func() {
+			data, err := SecretData(map[string][]byte{CompressedManifestKeyName: compress(manifestBytes)})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal(manifestBytes))
+		})
+
+		It("prefers the plain manifest", func() {
+			data, err := SecretData(map[string][]byte{
+				DesiredManifestKeyName:    manifestBytes,
+				CompressedManifestKeyName: compress([]byte("name: old")),
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal(manifestBytes))
+		})
+
+		It("fails for invalid compressed data", func() {
+			_, err := SecretData(map[string][]byte{CompressedManifestKeyName: []byte("not gzip")})
+			Expect(err).To(MatchError(ContainSubstring("decompressing manifest")))
+		})
+	})
+
+	Describe("ReadFile", func() {
+		var (
+			dir  string
+			path string
+		)
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "manifest")
+			Expect(err).ToNot(HaveOccurred())
+			path = filepath.Join(dir, DesiredManifestKeyName)
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(dir)).To(Succeed())
+		})
+
+		It("reads the manifest file", func() {
+			Expect(ioutil.WriteFile(path, manifestBytes, 0644)).To(Succeed())
+
+			Expect(FileExists(path)).To(BeTrue())
+			data, err := ReadFile(path)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal(manifestBytes))
+		})
+
+		It("falls back to the compressed manifest file", func() {
+			Expect(ioutil.WriteFile(path+".gz", compress(manifestBytes), 0644)).To(Succeed())
+
+			Expect(FileExists(path)).To(BeTrue())
+			data, err := ReadFile(path)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal(manifestBytes))
+		})
+
+		It("fails if neither exists", func() {
+			Expect(FileExists(path)).To(BeFalse())
+			_, err := ReadFile(path)
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+	})
+})
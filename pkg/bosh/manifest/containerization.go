@@ -40,6 +40,41 @@ type JobInstance struct {
 	Bootstrap bool                   `json:"bootstrap"`
 	ID        string                 `json:"id"`
 	Network   map[string]interface{} `json:"networks"`
+	// FQDN is the DNS name of the instance's pod, e.g. via its headless service
+	FQDN string `json:"-"`
+	// ServiceDNS is the DNS name of the headless service, which selects the instance's pod
+	ServiceDNS string `json:"-"`
+	// Draining marks an instance, which is about to be removed, so consumers stop using it
+	Draining bool `json:"draining,omitempty"`
+}
+
+// AddressFormat selects the format of link instance addresses
+type AddressFormat string
+
+// Valid values for address formats
+const (
+	// AddressFormatIP is the raw IP of the instance's pod
+	AddressFormatIP AddressFormat = "IP"
+	// AddressFormatFQDN is the DNS name of the instance's pod
+	AddressFormatFQDN AddressFormat = "FQDN"
+	// AddressFormatServiceDNS is the DNS name of the headless service of the instance
+	AddressFormatServiceDNS AddressFormat = "ServiceDNS"
+)
+
+// ToAddressString returns the address of the instance in the given format.
+// It falls back to the raw address, if the instance has no address in that format.
+func (j JobInstance) ToAddressString(format AddressFormat) string {
+	switch format {
+	case AddressFormatFQDN:
+		if j.FQDN != "" {
+			return j.FQDN
+		}
+	case AddressFormatServiceDNS:
+		if j.ServiceDNS != "" {
+			return j.ServiceDNS
+		}
+	}
+	return j.Address
 }
 
 // JobLinkProperties are the properties from the provides section in a job spec manifest
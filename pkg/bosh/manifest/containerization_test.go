@@ -63,3 +63,34 @@ var _ = Describe("Quarks", func() {
 		}))
 	})
 })
+
+var _ = Describe("JobInstance", func() {
+	var instance JobInstance
+
+	BeforeEach(func() {
+		instance = JobInstance{
+			Address:    "10.0.0.1",
+			FQDN:       "baz-0.baz.default.svc.cluster.local",
+			ServiceDNS: "baz.default.svc.cluster.local",
+		}
+	})
+
+	Describe("ToAddressString", func() {
+		It("returns the address in the requested format", func() {
+			Expect(instance.ToAddressString(AddressFormatIP)).To(Equal("10.0.0.1"))
+			Expect(instance.ToAddressString(AddressFormatFQDN)).To(Equal("baz-0.baz.default.svc.cluster.local"))
+			Expect(instance.ToAddressString(AddressFormatServiceDNS)).To(Equal("baz.default.svc.cluster.local"))
+		})
+
+		It("returns the raw address by default", func() {
+			Expect(instance.ToAddressString("")).To(Equal("10.0.0.1"))
+		})
+
+		It("falls back to the raw address, if the format is not available", func() {
+			instance.FQDN = ""
+			instance.ServiceDNS = ""
+			Expect(instance.ToAddressString(AddressFormatFQDN)).To(Equal("10.0.0.1"))
+			Expect(instance.ToAddressString(AddressFormatServiceDNS)).To(Equal("10.0.0.1"))
+		})
+	})
+})
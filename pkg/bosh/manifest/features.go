@@ -0,0 +1,69 @@
+package manifest
+
+import (
+	"hash/fnv"
+	"math/rand"
+)
+
+// ApplyFeatureFlags applies the optional features of the manifest's features
+// block to the instance groups and links.
+//
+// With randomize_az_placement, the AZs of each instance group are shuffled, so
+// the first instances of all instance groups don't end up in the same AZ. The
+// order only depends on the instance group's name and AZs, so it is stable
+// across reconciles and doesn't cause rollouts.
+//
+// With use_dns_addresses, the instances of kube native links use the DNS
+// names of their pods instead of their IPs.
+func (m *Manifest) ApplyFeatureFlags() {
+	if m.Features == nil {
+		return
+	}
+
+	if enabled(m.Features.RandomizeAzPlacement) {
+		for _, ig := range m.InstanceGroups {
+			ig.shuffleAZs()
+		}
+	}
+
+	if enabled(m.Features.UseDNSAddresses) {
+		m.LinkAddressFormat = AddressFormatFQDN
+	}
+}
+
+// UnsupportedFeatures returns the names of the enabled features of the
+// manifest's features block, which have no effect in kube. BOSH's short DNS
+// addresses are resolved by the BOSH DNS server, which doesn't exist in kube.
+func (m *Manifest) UnsupportedFeatures() []string {
+	if m.Features == nil {
+		return nil
+	}
+
+	var unsupported []string
+	if enabled(m.Features.UseShortDNSAddresses) {
+		unsupported = append(unsupported, "use_short_dns_addresses")
+	}
+	return unsupported
+}
+
+// shuffleAZs shuffles the AZs of the instance group, seeded by its name and AZs
+func (ig *InstanceGroup) shuffleAZs() {
+	if len(ig.AZs) < 2 {
+		return
+	}
+
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(ig.Name))
+	for _, az := range ig.AZs {
+		_, _ = h.Write([]byte(az))
+	}
+
+	azs := append([]string{}, ig.AZs...)
+	r := rand.New(rand.NewSource(int64(h.Sum64())))
+	r.Shuffle(len(azs), func(i, j int) { azs[i], azs[j] = azs[j], azs[i] })
+	ig.AZs = azs
+}
+
+func enabled(flag *bool) bool {
+	return flag != nil && *flag
+}
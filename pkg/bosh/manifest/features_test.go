@@ -0,0 +1,104 @@
+package manifest_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+)
+
+var _ = Describe("ApplyFeatureFlags", func() {
+	var m *Manifest
+
+	load := func(features string) {
+		var err error
+		m, err = LoadYAML([]byte(`
+name: foo
+` + features + `
+instance_groups:
+- name: api
+  instances: 2
+  azs: [z1, z2, z3, z4, z5]
+- name: worker
+  instances: 1
+  azs: [z1]
+`))
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	Context("when the manifest has no features", func() {
+		BeforeEach(func() {
+			load("")
+		})
+
+		It("leaves the manifest unchanged", func() {
+			m.ApplyFeatureFlags()
+			Expect(m.InstanceGroups[0].AZs).To(Equal([]string{"z1", "z2", "z3", "z4", "z5"}))
+			Expect(m.LinkAddressFormat).To(BeEmpty())
+		})
+	})
+
+	Context("when randomize_az_placement is enabled", func() {
+		BeforeEach(func() {
+			load("features:\n  randomize_az_placement: true")
+		})
+
+		It("shuffles the AZs of each instance group", func() {
+			m.ApplyFeatureFlags()
+			Expect(m.InstanceGroups[0].AZs).To(ConsistOf("z1", "z2", "z3", "z4", "z5"))
+			Expect(m.InstanceGroups[0].AZs).NotTo(Equal([]string{"z1", "z2", "z3", "z4", "z5"}))
+			Expect(m.InstanceGroups[1].AZs).To(Equal([]string{"z1"}))
+		})
+
+		It("shuffles the AZs in the same order each time", func() {
+			m.ApplyFeatureFlags()
+			shuffled := m.InstanceGroups[0].AZs
+
+			load("features:\n  randomize_az_placement: true")
+			m.ApplyFeatureFlags()
+			Expect(m.InstanceGroups[0].AZs).To(Equal(shuffled))
+		})
+	})
+
+	Context("when randomize_az_placement is disabled", func() {
+		BeforeEach(func() {
+			load("features:\n  randomize_az_placement: false")
+		})
+
+		It("keeps the order of the AZs", func() {
+			m.ApplyFeatureFlags()
+			Expect(m.InstanceGroups[0].AZs).To(Equal([]string{"z1", "z2", "z3", "z4", "z5"}))
+		})
+	})
+
+	Context("when use_dns_addresses is enabled", func() {
+		BeforeEach(func() {
+			load("features:\n  use_dns_addresses: true")
+		})
+
+		It("selects DNS names for link addresses", func() {
+			m.ApplyFeatureFlags()
+			Expect(m.LinkAddressFormat).To(Equal(AddressFormatFQDN))
+		})
+	})
+})
+
+var _ = Describe("UnsupportedFeatures", func() {
+	It("returns nothing, if the manifest has no features", func() {
+		m, err := LoadYAML([]byte("name: foo"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.UnsupportedFeatures()).To(BeEmpty())
+	})
+
+	It("returns use_short_dns_addresses, if it is enabled", func() {
+		m, err := LoadYAML([]byte("name: foo\nfeatures:\n  use_short_dns_addresses: true"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.UnsupportedFeatures()).To(ConsistOf("use_short_dns_addresses"))
+	})
+
+	It("returns nothing, if use_short_dns_addresses is disabled", func() {
+		m, err := LoadYAML([]byte("name: foo\nfeatures:\n  use_short_dns_addresses: false"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.UnsupportedFeatures()).To(BeEmpty())
+	})
+})
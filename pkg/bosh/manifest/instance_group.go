@@ -187,6 +187,23 @@ type VMResource struct {
 	EphemeralDiskSize int `json:"ephemeral_disk_size"`
 }
 
+// DiskType from the disk types of a BOSH cloud config, which instance groups
+// refer to by their 'persistent_disk_type'. DiskSize is in MiB.
+type DiskType struct {
+	Name            string                 `json:"name"`
+	DiskSize        int                    `json:"disk_size"`
+	CloudProperties map[string]interface{} `json:"cloud_properties,omitempty"`
+}
+
+// StorageClass returns the 'storage_class' cloud property of the disk type,
+// or its name, if the property is not set
+func (dt DiskType) StorageClass() string {
+	if storageClass, ok := dt.CloudProperties["storage_class"].(string); ok && storageClass != "" {
+		return storageClass
+	}
+	return dt.Name
+}
+
 // Network from BOSH deployment manifest.
 type Network struct {
 	Name      string   `json:"name"`
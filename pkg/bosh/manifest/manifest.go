@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"reflect"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -24,6 +25,8 @@ import (
 const (
 	// DesiredManifestKeyName is the name of the key in desired manifest secret
 	DesiredManifestKeyName = "manifest.yaml"
+	// CompressedManifestKeyName is the name of the key of a gzip compressed manifest in a secret
+	CompressedManifestKeyName = DesiredManifestKeyName + ".gz"
 )
 
 // ReleaseImageProvider interface to provide the docker release image for a BOSH job
@@ -38,6 +41,7 @@ type Feature struct {
 	ConvergeVariables    bool  `json:"converge_variables"`
 	RandomizeAzPlacement *bool `json:"randomize_az_placement,omitempty"`
 	UseDNSAddresses      *bool `json:"use_dns_addresses,omitempty"`
+	UseShortDNSAddresses *bool `json:"use_short_dns_addresses,omitempty"`
 	UseTmpfsJobConfig    *bool `json:"use_tmpfs_job_config,omitempty"`
 }
 
@@ -71,6 +75,7 @@ type VariableOptions struct {
 	SignerType                  string                    `json:"signer_type,omitempty"`
 	ServiceRef                  []qsv1a1.ServiceReference `json:"serviceRef,omitempty"`
 	ActivateEKSWorkaroundForSAN bool                      `json:"activateEKSWorkaroundForSAN,omitempty"`
+	CredHubPath                 string                    `json:"credhub_path,omitempty"`
 }
 
 // Variable from BOSH deployment manifest
@@ -78,6 +83,9 @@ type Variable struct {
 	Name    string           `json:"name"`
 	Type    string           `json:"type"`
 	Options *VariableOptions `json:"options,omitempty"`
+	// RegenerateOnChange lists the variables, whose regeneration also
+	// regenerates this variable, e.g. the CA of a certificate
+	RegenerateOnChange []string `json:"regenerateOnChange,omitempty"`
 }
 
 // Stemcell from BOSH deployment manifest
@@ -153,6 +161,9 @@ type Manifest struct {
 	Variables      []Variable             `json:"variables,omitempty"`
 	Update         *Update                `json:"update,omitempty"`
 	AddOnsApplied  bool                   `json:"addons_applied,omitempty"`
+	// LinkAddressFormat is the address format of kube native link
+	// instances, which is selected by the manifest's features
+	LinkAddressFormat AddressFormat `json:"-"`
 }
 
 // duplicateYamlValue is a struct used for size compression
@@ -177,15 +188,18 @@ func LoadYAML(data []byte) (*Manifest, error) {
 	return m, nil
 }
 
-// Marshal serializes a BOSH manifest into yaml
+// Marshal serializes a BOSH manifest into yaml. The output is deterministic,
+// so it can be used for change detection: map keys are sorted and anchors
+// are placed on the first occurrence of a duplicated value in that order.
 func (m *Manifest) Marshal() ([]byte, error) {
 
+	// yaml.Marshal converts via JSON, which sorts the map keys recursively
 	marshalledManifest, err := yaml.Marshal(m)
 	if err != nil {
 		return nil, err
 	}
 
-	// UnMarshalling the manifest to interface{}interface{} so that it is easy to loop.
+	// UnMarshalling the manifest to a MapSlice keeps the sorted order of all nested maps, so it is easy to loop.
 	manifestInterfaceMap := goyaml.MapSlice{}
 	err = goyaml.Unmarshal(marshalledManifest, &manifestInterfaceMap)
 	if err != nil {
@@ -316,17 +330,16 @@ func (m *Manifest) SHA1() (string, error) {
 	return fmt.Sprintf("%x", sha1.Sum(manifestBytes)), nil
 }
 
-// GetReleaseImage returns the release image location for a given instance group/job
-func (m *Manifest) GetReleaseImage(instanceGroupName, jobName string) (string, error) {
-	var instanceGroup *InstanceGroup
-	for i := range m.InstanceGroups {
-		if m.InstanceGroups[i].Name == instanceGroupName {
-			instanceGroup = m.InstanceGroups[i]
-			break
-		}
-	}
-	if instanceGroup == nil {
-		return "", errors.Errorf("instance group '%s' not found.", instanceGroupName)
+// ErrStemcellNotFound is the cause of errors for instance groups, whose stemcell alias doesn't match a stemcell
+var ErrStemcellNotFound = errors.New("stemcell not found")
+
+// GetStemcellForIG returns the stemcell, whose alias is referenced by the
+// instance group. The cause of the error is ErrStemcellNotFound, if there is
+// no such stemcell.
+func (m *Manifest) GetStemcellForIG(igName string) (*Stemcell, error) {
+	instanceGroup, found := m.InstanceGroups.InstanceGroupByName(igName)
+	if !found {
+		return nil, errors.Errorf("instance group '%s' not found", igName)
 	}
 
 	var stemcell *Stemcell
@@ -335,6 +348,23 @@ func (m *Manifest) GetReleaseImage(instanceGroupName, jobName string) (string, e
 			stemcell = m.Stemcells[i]
 		}
 	}
+	if stemcell == nil {
+		return nil, errors.Wrapf(ErrStemcellNotFound, "no stemcell with alias '%s' for instance group '%s'", instanceGroup.Stemcell, igName)
+	}
+	return stemcell, nil
+}
+
+// GetReleaseImage returns the release image location for a given instance group/job
+func (m *Manifest) GetReleaseImage(instanceGroupName, jobName string) (string, error) {
+	instanceGroup, found := m.InstanceGroups.InstanceGroupByName(instanceGroupName)
+	if !found {
+		return "", errors.Errorf("instance group '%s' not found.", instanceGroupName)
+	}
+
+	stemcell, err := m.GetStemcellForIG(instanceGroupName)
+	if err != nil && errors.Cause(err) != ErrStemcellNotFound {
+		return "", err
+	}
 
 	var job *Job
 	for i := range instanceGroup.Jobs {
@@ -371,22 +401,14 @@ func (m *Manifest) GetReleaseImage(instanceGroupName, jobName string) (string, e
 // GetJobOS returns the stemcell layer OS used for a Job
 // This is used for matching addon placement rules
 func (m *Manifest) GetJobOS(instanceGroupName, jobName string) (string, error) {
-	var instanceGroup *InstanceGroup
-	for i := range m.InstanceGroups {
-		if m.InstanceGroups[i].Name == instanceGroupName {
-			instanceGroup = m.InstanceGroups[i]
-			break
-		}
-	}
-	if instanceGroup == nil {
+	instanceGroup, found := m.InstanceGroups.InstanceGroupByName(instanceGroupName)
+	if !found {
 		return "", fmt.Errorf("instance group '%s' not found", instanceGroupName)
 	}
 
-	var stemcell *Stemcell
-	for i := range m.Stemcells {
-		if m.Stemcells[i].Alias == instanceGroup.Stemcell {
-			stemcell = m.Stemcells[i]
-		}
+	stemcell, err := m.GetStemcellForIG(instanceGroupName)
+	if err != nil && errors.Cause(err) != ErrStemcellNotFound {
+		return "", err
 	}
 
 	var job *Job
@@ -459,6 +481,43 @@ func (m *Manifest) ImplicitVariables() ([]string, error) {
 	return names, nil
 }
 
+// ExplicitVariableFields returns the fields of explicit variables, which are
+// referenced in the manifest, e.g. '((ca.certificate))' -> ca: [certificate]
+func (m *Manifest) ExplicitVariableFields() (map[string][]string, error) {
+	manifestBytes, err := m.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	explicit := map[string]bool{}
+	for _, v := range m.Variables {
+		explicit[v.Name] = true
+	}
+
+	fieldMap := map[string]map[string]bool{}
+	varRegexp := regexp.MustCompile(`\(\((!?[-/\.\w\pL]+)\)\)`)
+	for _, match := range varRegexp.FindAllStringSubmatch(string(manifestBytes), -1) {
+		parts := strings.SplitN(match[1], ".", 2)
+		if len(parts) != 2 || !explicit[parts[0]] {
+			continue
+		}
+		if fieldMap[parts[0]] == nil {
+			fieldMap[parts[0]] = map[string]bool{}
+		}
+		fieldMap[parts[0]][parts[1]] = true
+	}
+
+	fields := map[string][]string{}
+	for name, fieldSet := range fieldMap {
+		for field := range fieldSet {
+			fields[name] = append(fields[name], field)
+		}
+		sort.Strings(fields[name])
+	}
+
+	return fields, nil
+}
+
 // ApplyAddons goes through all defined addons and adds jobs to matched instance groups
 func (m *Manifest) ApplyAddons() error {
 	if m.AddOnsApplied {
@@ -520,6 +579,24 @@ func (m *Manifest) PropagateGlobalUpdateBlockToIGs() {
 	}
 }
 
+// OverrideInstanceCount returns a copy of the manifest, in which the
+// instances of the instance groups in overrides are replaced by the given
+// counts. Instance groups, which are not in the manifest, are ignored. The
+// instance groups are copied, all other fields are shared with the manifest.
+func (m *Manifest) OverrideInstanceCount(overrides map[string]int) *Manifest {
+	scaled := *m
+	scaled.InstanceGroups = make(InstanceGroups, len(m.InstanceGroups))
+	for i, ig := range m.InstanceGroups {
+		copied := *ig
+		if instances, ok := overrides[ig.Name]; ok {
+			copied.Instances = instances
+		}
+		scaled.InstanceGroups[i] = &copied
+	}
+
+	return &scaled
+}
+
 // ListMissingProviders returns a list of missing providers from the manifest
 func (m *Manifest) ListMissingProviders() map[string]bool {
 	provideAsNames := map[string]bool{}
@@ -565,3 +642,111 @@ func listProviderNames(providerProperties map[string]interface{}, providerKey st
 
 	return providerNames
 }
+
+// LinkConsumer is a job, which consumes a link from a provider
+type LinkConsumer struct {
+	InstanceGroup string
+	Job           string
+	// Type is the link type declared in the job's consumes section, empty if none is declared
+	Type string
+}
+
+// ListLinkConsumers returns the jobs, which consume links from the named provider
+func (m *Manifest) ListLinkConsumers(providerName string) []LinkConsumer {
+	consumers := []LinkConsumer{}
+	for _, ig := range m.InstanceGroups {
+		for _, job := range ig.Jobs {
+			for _, property := range job.Consumes {
+				p, ok := property.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				if from, _ := p["from"].(string); from != providerName {
+					continue
+				}
+				linkType, _ := p["type"].(string)
+				consumers = append(consumers, LinkConsumer{
+					InstanceGroup: ig.Name,
+					Job:           job.Name,
+					Type:          linkType,
+				})
+			}
+		}
+	}
+	return consumers
+}
+
+// LinkPortConflict is a port, which is declared by more than one job
+// providing the same link
+type LinkPortConflict struct {
+	Link string
+	Port Port
+	Jobs []string
+}
+
+func (c LinkPortConflict) String() string {
+	return fmt.Sprintf("link '%s' port %d/%s is exposed by jobs: %s", c.Link, c.Port.Internal, c.Port.Protocol, strings.Join(c.Jobs, ", "))
+}
+
+// ListLinkPortConflicts returns the ports, which are declared by multiple jobs
+// providing the same link. Jobs are referenced as '<instance group>/<job>'.
+func (m *Manifest) ListLinkPortConflicts() []LinkPortConflict {
+	// link name -> port -> providing jobs
+	linkPorts := map[string]map[Port][]string{}
+
+	for _, ig := range m.InstanceGroups {
+		for _, job := range ig.Jobs {
+			for _, linkName := range listProvidedLinkNames(job.Provides) {
+				if _, ok := linkPorts[linkName]; !ok {
+					linkPorts[linkName] = map[Port][]string{}
+				}
+				for _, port := range job.Properties.Quarks.Ports {
+					// the port's name is not part of the exposed address
+					key := Port{Protocol: strings.ToUpper(port.Protocol), Internal: port.Internal}
+					linkPorts[linkName][key] = append(linkPorts[linkName][key], fmt.Sprintf("%s/%s", ig.Name, job.Name))
+				}
+			}
+		}
+	}
+
+	conflicts := []LinkPortConflict{}
+	for linkName, ports := range linkPorts {
+		for port, jobs := range ports {
+			if len(jobs) > 1 {
+				conflicts = append(conflicts, LinkPortConflict{Link: linkName, Port: port, Jobs: jobs})
+			}
+		}
+	}
+
+	sort.Slice(conflicts, func(i, j int) bool {
+		if conflicts[i].Link != conflicts[j].Link {
+			return conflicts[i].Link < conflicts[j].Link
+		}
+		if conflicts[i].Port.Internal != conflicts[j].Port.Internal {
+			return conflicts[i].Port.Internal < conflicts[j].Port.Internal
+		}
+		return conflicts[i].Port.Protocol < conflicts[j].Port.Protocol
+	})
+
+	return conflicts
+}
+
+// listProvidedLinkNames returns the names of the links a job provides,
+// respecting the "as" alias and skipping blocked links
+func listProvidedLinkNames(provides map[string]interface{}) []string {
+	linkNames := []string{}
+	for name, property := range provides {
+		switch p := property.(type) {
+		case map[string]interface{}:
+			if as, ok := p["as"].(string); ok && len(as) != 0 {
+				name = as
+			}
+		case string:
+			// "nil" blocks the link
+			continue
+		}
+		linkNames = append(linkNames, name)
+	}
+
+	return linkNames
+}
@@ -3,6 +3,9 @@ package manifest_test
 import (
 	"reflect"
 	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/utils/pointer"
@@ -1190,6 +1193,34 @@ var _ = Describe("Manifest", func() {
 					Expect(result1).To(Equal(result2))
 				})
 
+				It("should sort map keys and place anchors independent of the map iteration order", func() {
+					dup := strings.Repeat("duplicated value ", 5)
+					m := &Manifest{
+						InstanceGroups: []*InstanceGroup{
+							{
+								Name: "nats",
+								Properties: InstanceGroupProperties{
+									Properties: map[string]interface{}{
+										"d": dup,
+										"a": map[string]interface{}{"z": dup, "x": dup, "y": dup},
+										"c": dup,
+										"b": dup,
+									},
+								},
+							},
+						},
+					}
+
+					expected, err := m.Marshal()
+					Expect(err).NotTo(HaveOccurred())
+					Expect(string(expected)).To(MatchRegexp(`(?s)a:\n\s+x: &\w+ .*\n\s+"y": \*\w+\n\s+z: \*\w+\n\s+b: \*\w+\n\s+c: \*\w+\n\s+d: \*\w+`))
+					for i := 0; i < 20; i++ {
+						result, err := m.Marshal()
+						Expect(err).NotTo(HaveOccurred())
+						Expect(result).To(Equal(expected))
+					}
+				})
+
 				It("should marshal correctly and resolve anchors", func() {
 					marshalledLargeManifest, err := largeManifest.Marshal()
 					Expect(err).NotTo(HaveOccurred())
@@ -1336,6 +1367,33 @@ var _ = Describe("Manifest", func() {
 			})
 		})
 
+		Describe("GetStemcellForIG", func() {
+			BeforeEach(func() {
+				manifest, err = env.DefaultBOSHManifest()
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("returns the stemcell matching the alias of the instance group", func() {
+				stemcell, err := manifest.GetStemcellForIG("redis-slave")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(stemcell.Alias).To(Equal(manifest.InstanceGroups[0].Stemcell))
+				Expect(stemcell.OS).To(Equal("opensuse-42.3"))
+			})
+
+			It("reports an error if the instance group was not found", func() {
+				_, err := manifest.GetStemcellForIG("unknown-instancegroup")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("instance group 'unknown-instancegroup' not found"))
+				Expect(errors.Cause(err)).ToNot(Equal(ErrStemcellNotFound))
+			})
+
+			It("returns ErrStemcellNotFound if no stemcell matches the alias", func() {
+				manifest.Stemcells = []*Stemcell{}
+				_, err := manifest.GetStemcellForIG("redis-slave")
+				Expect(errors.Cause(err)).To(Equal(ErrStemcellNotFound))
+			})
+		})
+
 		Describe("GetReleaseImage", func() {
 			BeforeEach(func() {
 				manifest, err = env.DefaultBOSHManifest()
@@ -1473,5 +1531,154 @@ var _ = Describe("Manifest", func() {
 				}))
 			})
 		})
+
+		Describe("ExplicitVariableFields", func() {
+			It("returns the referenced fields of explicit variables", func() {
+				manifest := &Manifest{
+					InstanceGroups: []*InstanceGroup{
+						{
+							Name: "nats",
+							Properties: InstanceGroupProperties{
+								Properties: map[string]interface{}{
+									"tls": map[string]interface{}{
+										"cert": "((nats_cert.certificate))",
+										"key":  "((nats_cert.private_key))",
+										"ca":   "((nats_cert.ca))",
+									},
+									"password":  "((nats_password))",
+									"implicit":  "((system_domain.value))",
+									"duplicate": "((nats_cert.ca))",
+								},
+							},
+						},
+					},
+					Variables: []Variable{
+						{Name: "nats_cert", Type: "certificate"},
+						{Name: "nats_password", Type: "password"},
+					},
+				}
+
+				fields, err := manifest.ExplicitVariableFields()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fields).To(Equal(map[string][]string{
+					"nats_cert": {"ca", "certificate", "private_key"},
+				}))
+			})
+		})
+
+		Describe("ListLinkPortConflicts", func() {
+			const linkManifest = `---
+name: test
+instance_groups:
+- name: nats
+  jobs:
+  - name: nats
+    release: nats
+    provides:
+      nats: {as: shared}
+    properties:
+      quarks:
+        ports:
+        - name: nats
+          protocol: TCP
+          internal: 4222
+  - name: nats-tls
+    release: nats
+    provides:
+      nats-tls: {as: shared}
+    properties:
+      quarks:
+        ports:
+        - name: nats-tls
+          protocol: tcp
+          internal: 4222
+        - name: routes
+          protocol: TCP
+          internal: 4223
+- name: other
+  jobs:
+  - name: other
+    release: other
+    provides:
+      other: {}
+    properties:
+      quarks:
+        ports:
+        - name: other
+          protocol: TCP
+          internal: 4222
+`
+
+			It("returns the ports declared by multiple jobs of one link", func() {
+				manifest, err := LoadYAML([]byte(linkManifest))
+				Expect(err).NotTo(HaveOccurred())
+
+				conflicts := manifest.ListLinkPortConflicts()
+				Expect(conflicts).To(HaveLen(1))
+				Expect(conflicts[0].Link).To(Equal("shared"))
+				Expect(conflicts[0].Port).To(Equal(Port{Protocol: "TCP", Internal: 4222}))
+				Expect(conflicts[0].Jobs).To(ConsistOf("nats/nats", "nats/nats-tls"))
+				Expect(conflicts[0].String()).To(ContainSubstring("nats/nats, nats/nats-tls"))
+			})
+
+			It("returns no conflicts for links with distinct ports", func() {
+				manifest, err := LoadYAML([]byte(boshmanifest.Default))
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(manifest.ListLinkPortConflicts()).To(BeEmpty())
+			})
+		})
+
+		Describe("ListLinkConsumers", func() {
+			It("returns the jobs consuming links from the provider with their declared type", func() {
+				manifest, err := LoadYAML([]byte(`---
+name: test
+instance_groups:
+- name: api
+  jobs:
+  - name: cloud_controller
+    release: capi
+    consumes:
+      database: {from: db, type: database}
+  - name: worker
+    release: capi
+    consumes:
+      database: {from: db}
+      nats: {from: nats}
+`))
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(manifest.ListLinkConsumers("db")).To(ConsistOf(
+					LinkConsumer{InstanceGroup: "api", Job: "cloud_controller", Type: "database"},
+					LinkConsumer{InstanceGroup: "api", Job: "worker"},
+				))
+				Expect(manifest.ListLinkConsumers("other")).To(BeEmpty())
+			})
+		})
+
+		Describe("OverrideInstanceCount", func() {
+			It("returns a copy with the instances of the given instance groups replaced", func() {
+				manifest, err := LoadYAML([]byte(`---
+name: test
+instance_groups:
+- name: api
+  instances: 2
+- name: db
+  instances: 3
+- name: nats
+  instances: 2
+`))
+				Expect(err).NotTo(HaveOccurred())
+
+				scaled := manifest.OverrideInstanceCount(map[string]int{"api": 1, "db": 1, "missing": 5})
+				Expect(scaled.InstanceGroups).To(HaveLen(3))
+				Expect(scaled.InstanceGroups[0].Instances).To(Equal(1))
+				Expect(scaled.InstanceGroups[1].Instances).To(Equal(1))
+				Expect(scaled.InstanceGroups[2].Instances).To(Equal(2))
+
+				Expect(manifest.InstanceGroups[0].Instances).To(Equal(2))
+				Expect(manifest.InstanceGroups[1].Instances).To(Equal(3))
+			})
+		})
 	})
 })
@@ -0,0 +1,92 @@
+package manifest
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Normalization lists the top level sections of a manifest, whose entries
+// are compared regardless of their order
+type Normalization []string
+
+// DefaultNormalization ignores the order of all sections, in which the order
+// is semantically irrelevant
+var DefaultNormalization = Normalization{"addons", "releases", "stemcells", "variables"}
+
+// normalizationKeys maps the sections, which can be normalized, to the key identifying their entries.
+// The order of instance groups and their jobs is significant, so they can't be normalized.
+var normalizationKeys = map[string]string{
+	"addons":    "name",
+	"releases":  "name",
+	"stemcells": "alias",
+	"variables": "name",
+}
+
+// ParseNormalization returns the normalization for a comma separated list of sections
+func ParseNormalization(sections string) (Normalization, error) {
+	n := Normalization{}
+	for _, section := range strings.Split(sections, ",") {
+		section = strings.TrimSpace(section)
+		if section == "" {
+			continue
+		}
+		if _, ok := normalizationKeys[section]; !ok {
+			return nil, errors.Errorf("invalid manifest normalization section '%s'", section)
+		}
+		n = append(n, section)
+	}
+	return n, nil
+}
+
+// NormalizedSHA1 calculates the SHA1 of a canonical representation of the
+// manifest, in which map keys are sorted and the entries of the sections in
+// n are ordered by their name. It is meant for change detection only, the
+// manifest itself is not modified.
+func (m *Manifest) NormalizedSHA1(n Normalization) (string, error) {
+	manifestBytes, err := json.Marshal(m)
+	if err != nil {
+		return "", errors.Wrap(err, "JSON marshalling manifest failed")
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(manifestBytes))
+	decoder.UseNumber()
+	canonical := map[string]interface{}{}
+	if err := decoder.Decode(&canonical); err != nil {
+		return "", errors.Wrap(err, "JSON unmarshalling manifest failed")
+	}
+
+	for _, section := range n {
+		entries, ok := canonical[section].([]interface{})
+		if !ok {
+			continue
+		}
+		key := normalizationKeys[section]
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entryName(entries[i], key) < entryName(entries[j], key)
+		})
+	}
+
+	// encoding/json sorts map keys
+	canonicalBytes, err := json.Marshal(canonical)
+	if err != nil {
+		return "", errors.Wrap(err, "JSON marshalling normalized manifest failed")
+	}
+
+	return fmt.Sprintf("%x", sha1.Sum(canonicalBytes)), nil
+}
+
+// entryName returns the identifying key of a section entry
+func entryName(entry interface{}, key string) string {
+	if fields, ok := entry.(map[string]interface{}); ok {
+		if name, ok := fields[key].(string); ok {
+			return name
+		}
+	}
+	return ""
+}
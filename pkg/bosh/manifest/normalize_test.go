@@ -0,0 +1,149 @@
+package manifest_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+)
+
+var _ = Describe("Normalization", func() {
+	load := func(yaml string) *Manifest {
+		m, err := LoadYAML([]byte(yaml))
+		Expect(err).ToNot(HaveOccurred())
+		return m
+	}
+
+	sha1 := func(m *Manifest, n Normalization) string {
+		sum, err := m.NormalizedSHA1(n)
+		Expect(err).ToNot(HaveOccurred())
+		return sum
+	}
+
+	var m *Manifest
+
+	BeforeEach(func() {
+		m = load(`---
+name: foo
+releases:
+- name: bar
+  version: "1"
+- name: baz
+  version: "2"
+instance_groups:
+- name: first
+  instances: 1
+- name: second
+  instances: 1
+properties:
+  a: 1
+  b:
+    c: 2
+    d: 3
+variables:
+- name: password
+  type: password
+- name: ca
+  type: certificate
+`)
+	})
+
+	Describe("NormalizedSHA1", func() {
+		It("ignores the order of map keys", func() {
+			other := load(`---
+properties:
+  b:
+    d: 3
+    c: 2
+  a: 1
+variables:
+- type: password
+  name: password
+- type: certificate
+  name: ca
+instance_groups:
+- instances: 1
+  name: first
+- instances: 1
+  name: second
+releases:
+- version: "1"
+  name: bar
+- version: "2"
+  name: baz
+name: foo
+`)
+			Expect(sha1(m, Normalization{})).To(Equal(sha1(other, Normalization{})))
+		})
+
+		It("ignores the order of entries in normalized sections", func() {
+			m.Releases[0], m.Releases[1] = m.Releases[1], m.Releases[0]
+			m.Variables[0], m.Variables[1] = m.Variables[1], m.Variables[0]
+
+			Expect(sha1(m, DefaultNormalization)).To(Equal(sha1(load(`---
+name: foo
+releases:
+- name: baz
+  version: "2"
+- name: bar
+  version: "1"
+instance_groups:
+- name: first
+  instances: 1
+- name: second
+  instances: 1
+properties:
+  a: 1
+  b:
+    c: 2
+    d: 3
+variables:
+- name: ca
+  type: certificate
+- name: password
+  type: password
+`), DefaultNormalization)))
+		})
+
+		It("respects the order of entries in other sections", func() {
+			before := sha1(m, DefaultNormalization)
+			m.InstanceGroups[0], m.InstanceGroups[1] = m.InstanceGroups[1], m.InstanceGroups[0]
+			Expect(sha1(m, DefaultNormalization)).ToNot(Equal(before))
+
+			before = sha1(m, Normalization{"variables"})
+			m.Releases[0], m.Releases[1] = m.Releases[1], m.Releases[0]
+			Expect(sha1(m, Normalization{"variables"})).ToNot(Equal(before))
+		})
+
+		It("detects changed values", func() {
+			before := sha1(m, DefaultNormalization)
+			m.Releases[0].Version = "3"
+			Expect(sha1(m, DefaultNormalization)).ToNot(Equal(before))
+		})
+
+		It("doesn't modify the manifest", func() {
+			m.Releases[0], m.Releases[1] = m.Releases[1], m.Releases[0]
+			sha1(m, DefaultNormalization)
+			Expect(m.Releases[0].Name).To(Equal("baz"))
+		})
+	})
+
+	Describe("ParseNormalization", func() {
+		It("parses a comma separated list of sections", func() {
+			n, err := ParseNormalization("releases, variables")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(Normalization{"releases", "variables"}))
+		})
+
+		It("returns an empty normalization for an empty list", func() {
+			n, err := ParseNormalization("")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(BeEmpty())
+		})
+
+		It("rejects sections, whose order is significant", func() {
+			_, err := ParseNormalization("instance_groups")
+			Expect(err).To(MatchError(ContainSubstring("invalid manifest normalization section 'instance_groups'")))
+		})
+	})
+})
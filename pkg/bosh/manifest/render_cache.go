@@ -0,0 +1,169 @@
+package manifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+
+	btg "github.com/viovanov/bosh-template-go"
+)
+
+// RenderCache stores rendered templates in a directory, e.g. on a persistent
+// volume, so they are not rendered again while their inputs are unchanged.
+// Entries are keyed by a hash of all inputs of the render, so a cached
+// output is identical to a fresh render.
+type RenderCache struct {
+	fs  afero.Fs
+	dir string
+}
+
+// renderInputs are all inputs, which influence the output of a template render
+type renderInputs struct {
+	Release        string                 `json:"release"`
+	ReleaseVersion string                 `json:"release_version"`
+	Job            string                 `json:"job"`
+	Spec           []byte                 `json:"spec"`
+	Template       []byte                 `json:"template"`
+	Properties     map[string]interface{} `json:"properties"`
+	Instance       interface{}            `json:"instance"`
+}
+
+// TemplateRender describes the render of a job's ERB template for one instance
+type TemplateRender struct {
+	Release        string
+	ReleaseVersion string
+	Job            string
+	JobSpecFile    string
+	TemplateFile   string
+	Properties     map[string]interface{}
+	Instance       *btg.InstanceInfo
+}
+
+// TemplateRenderer renders the ERB template of a job for one instance
+type TemplateRenderer interface {
+	Render(render TemplateRender) ([]byte, error)
+}
+
+// CachedRenderer renders templates with another renderer and stores the
+// output in a render cache. The output of an earlier render with the same
+// inputs is reused.
+type CachedRenderer struct {
+	cache    *RenderCache
+	renderer TemplateRenderer
+}
+
+// NewCachedRenderer returns a renderer, which caches the renders of renderer
+func NewCachedRenderer(cache *RenderCache, renderer TemplateRenderer) *CachedRenderer {
+	return &CachedRenderer{cache: cache, renderer: renderer}
+}
+
+// Render returns the cached output for the inputs of the render, or renders
+// the template and caches the output
+func (r *CachedRenderer) Render(render TemplateRender) ([]byte, error) {
+	spec, err := ioutil.ReadFile(render.JobSpecFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Reading of job spec file %s failed", render.JobSpecFile)
+	}
+	template, err := ioutil.ReadFile(render.TemplateFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Reading of template file %s failed", render.TemplateFile)
+	}
+
+	key, err := renderCacheKey(renderInputs{
+		Release:        render.Release,
+		ReleaseVersion: render.ReleaseVersion,
+		Job:            render.Job,
+		Spec:           spec,
+		Template:       template,
+		Properties:     render.Properties,
+		Instance:       render.Instance,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rendered, found, err := r.cache.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		return rendered, nil
+	}
+
+	rendered, err = r.renderer.Render(render)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.cache.Put(key, rendered); err != nil {
+		return nil, err
+	}
+	return rendered, nil
+}
+
+// NewRenderCache returns a render cache, which stores its entries in dir
+func NewRenderCache(fs afero.Fs, dir string) *RenderCache {
+	return &RenderCache{fs: fs, dir: dir}
+}
+
+// renderCacheKey returns the cache key for the render inputs
+func renderCacheKey(inputs renderInputs) (string, error) {
+	// encoding/json sorts map keys, so equal inputs produce equal keys
+	data, err := json.Marshal(inputs)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to marshal render inputs of job '%s'", inputs.Job)
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Get returns the cached render output for the key. It returns false, if
+// there is no entry.
+func (c *RenderCache) Get(key string) ([]byte, bool, error) {
+	data, err := afero.ReadFile(c.fs, c.path(key))
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "failed to read render cache entry '%s'", key)
+	}
+	return data, true, nil
+}
+
+// Put stores the render output for the key. The entry is written to a
+// temporary file first, so concurrent readers never see partial entries.
+func (c *RenderCache) Put(key string, data []byte) error {
+	if err := c.fs.MkdirAll(c.dir, 0755); err != nil {
+		return errors.Wrapf(err, "failed to create render cache directory '%s'", c.dir)
+	}
+
+	tmp, err := afero.TempFile(c.fs, c.dir, key+".*.tmp")
+	if err != nil {
+		return errors.Wrapf(err, "failed to create render cache entry '%s'", key)
+	}
+	defer c.fs.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return errors.Wrapf(err, "failed to write render cache entry '%s'", key)
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrapf(err, "failed to write render cache entry '%s'", key)
+	}
+
+	if err := c.fs.Rename(tmp.Name(), c.path(key)); err != nil {
+		return errors.Wrapf(err, "failed to store render cache entry '%s'", key)
+	}
+	return nil
+}
+
+func (c *RenderCache) path(key string) string {
+	return filepath.Join(c.dir, key)
+}
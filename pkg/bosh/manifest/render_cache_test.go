@@ -0,0 +1,161 @@
+package manifest_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+
+	. "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+)
+
+// fakeRenderer counts its renders and returns a fixed output
+type fakeRenderer struct {
+	renders int
+	output  string
+	err     error
+}
+
+func (r *fakeRenderer) Render(render TemplateRender) ([]byte, error) {
+	r.renders++
+	if r.err != nil {
+		return nil, r.err
+	}
+	return []byte(r.output), nil
+}
+
+var _ = Describe("RenderCache", func() {
+	var (
+		fs    afero.Fs
+		cache *RenderCache
+	)
+
+	BeforeEach(func() {
+		fs = afero.NewMemMapFs()
+		cache = NewRenderCache(fs, "/cache")
+	})
+
+	It("reports missing entries", func() {
+		_, found, err := cache.Get("0123")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(found).To(BeFalse())
+	})
+
+	It("returns stored entries", func() {
+		Expect(cache.Put("0123", []byte("processes: []"))).To(Succeed())
+
+		data, found, err := cache.Get("0123")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(found).To(BeTrue())
+		Expect(string(data)).To(Equal("processes: []"))
+	})
+
+	It("doesn't leave temporary files behind", func() {
+		Expect(cache.Put("0123", []byte("processes: []"))).To(Succeed())
+		Expect(cache.Put("0123", []byte("processes: []"))).To(Succeed())
+
+		entries, err := afero.ReadDir(fs, "/cache")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(entries).To(HaveLen(1))
+		Expect(entries[0].Name()).To(Equal("0123"))
+	})
+
+	Describe("CachedRenderer", func() {
+		var (
+			dir      string
+			renderer *fakeRenderer
+			cached   *CachedRenderer
+			render   TemplateRender
+		)
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "render-cache")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ioutil.WriteFile(filepath.Join(dir, "job.MF"), []byte("name: foo"), 0644)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(dir, "bpm.yml.erb"), []byte("processes: []"), 0644)).To(Succeed())
+
+			renderer = &fakeRenderer{output: "processes:\n- name: foo\n"}
+			cached = NewCachedRenderer(cache, renderer)
+			render = TemplateRender{
+				Release:        "foo-release",
+				ReleaseVersion: "1.0",
+				Job:            "foo",
+				JobSpecFile:    filepath.Join(dir, "job.MF"),
+				TemplateFile:   filepath.Join(dir, "bpm.yml.erb"),
+				Properties:     map[string]interface{}{"port": 8080},
+			}
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(dir)).To(Succeed())
+		})
+
+		It("reuses the render for unchanged inputs", func() {
+			first, err := cached.Render(render)
+			Expect(err).ToNot(HaveOccurred())
+			second, err := cached.Render(render)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(string(first)).To(Equal("processes:\n- name: foo\n"))
+			Expect(second).To(Equal(first))
+			Expect(renderer.renders).To(Equal(1))
+		})
+
+		It("renders again, if the properties change", func() {
+			_, err := cached.Render(render)
+			Expect(err).ToNot(HaveOccurred())
+
+			render.Properties = map[string]interface{}{"port": 9090}
+			_, err = cached.Render(render)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(renderer.renders).To(Equal(2))
+		})
+
+		It("renders again, if the template changes", func() {
+			_, err := cached.Render(render)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(ioutil.WriteFile(render.TemplateFile, []byte("processes: [<%= p('port') %>]"), 0644)).To(Succeed())
+			_, err = cached.Render(render)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(renderer.renders).To(Equal(2))
+		})
+
+		It("renders again, if the release version changes", func() {
+			_, err := cached.Render(render)
+			Expect(err).ToNot(HaveOccurred())
+
+			render.ReleaseVersion = "1.1"
+			_, err = cached.Render(render)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(renderer.renders).To(Equal(2))
+		})
+
+		It("doesn't cache failed renders", func() {
+			renderer.err = errors.New("fake-error")
+			_, err := cached.Render(render)
+			Expect(err).To(MatchError("fake-error"))
+
+			renderer.err = nil
+			_, err = cached.Render(render)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(renderer.renders).To(Equal(2))
+
+			entries, err := afero.ReadDir(fs, "/cache")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(entries).To(HaveLen(1))
+		})
+
+		It("returns an error, if the template doesn't exist", func() {
+			render.TemplateFile = filepath.Join(dir, "missing.erb")
+			_, err := cached.Render(render)
+			Expect(err).To(HaveOccurred())
+			Expect(renderer.renders).To(BeZero())
+		})
+	})
+})
@@ -0,0 +1,116 @@
+package manifest
+
+import (
+	"regexp"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// variableRefRegexp matches variable references like '((name))' or '((name.key))'
+var variableRefRegexp = regexp.MustCompile(`\(\(!?([-/\w\pL]+)(\.[-/\.\w\pL]+)?\)\)`)
+
+// VariableDependencyGraph is a DAG of the manifest's variables, in which
+// each certificate depends on the CA referenced by its `options.ca` and
+// on the variables referenced in its `options.alternative_names`
+type VariableDependencyGraph struct {
+	// names of all variables in declaration order
+	names []string
+	// deps maps a variable to the variables it depends on
+	deps map[string][]variableDependency
+}
+
+type variableDependency struct {
+	name string
+	// kind of the reference, e.g. 'CA'
+	kind string
+}
+
+// VariableGraph builds the dependency graph of the manifest's variables.
+// References to variables, which are not declared in the manifest, are
+// not part of the graph.
+func (m *Manifest) VariableGraph() (*VariableDependencyGraph, error) {
+	g := &VariableDependencyGraph{
+		names: make([]string, 0, len(m.Variables)),
+		deps:  map[string][]variableDependency{},
+	}
+
+	declared := map[string]bool{}
+	for _, v := range m.Variables {
+		if declared[v.Name] {
+			return nil, errors.Errorf("duplicated variable '%s'", v.Name)
+		}
+		declared[v.Name] = true
+		g.names = append(g.names, v.Name)
+	}
+
+	for _, v := range m.Variables {
+		if v.Options == nil {
+			continue
+		}
+		if declared[v.Options.CA] {
+			g.deps[v.Name] = append(g.deps[v.Name], variableDependency{name: v.Options.CA, kind: "CA"})
+		}
+		for _, altName := range v.Options.AlternativeNames {
+			for _, match := range variableRefRegexp.FindAllStringSubmatch(altName, -1) {
+				if declared[match[1]] {
+					g.deps[v.Name] = append(g.deps[v.Name], variableDependency{name: match[1], kind: "alternative name"})
+				}
+			}
+		}
+	}
+
+	return g, nil
+}
+
+// Depth returns the length of the longest dependency chain above a
+// variable, i.e. 0 for a root CA or a variable without dependencies, 1 for
+// a certificate signed by a root CA and so on
+func (g *VariableDependencyGraph) Depth(name string) (int, error) {
+	return g.depth(name, map[string]bool{}, map[string]int{})
+}
+
+func (g *VariableDependencyGraph) depth(name string, visiting map[string]bool, depths map[string]int) (int, error) {
+	if depth, ok := depths[name]; ok {
+		return depth, nil
+	}
+
+	visiting[name] = true
+	depth := 0
+	for _, dep := range g.deps[name] {
+		if visiting[dep.name] {
+			return 0, errors.Errorf("cyclic %s reference of variable '%s' via '%s'", dep.kind, name, dep.name)
+		}
+		d, err := g.depth(dep.name, visiting, depths)
+		if err != nil {
+			return 0, err
+		}
+		if d+1 > depth {
+			depth = d + 1
+		}
+	}
+	delete(visiting, name)
+	depths[name] = depth
+
+	return depth, nil
+}
+
+// TopologicalOrder returns the variable names in creation order, so each
+// variable is created after the variables it depends on, e.g. CAs before
+// the certificates they sign. Variables of the same depth keep their
+// declaration order.
+func (g *VariableDependencyGraph) TopologicalOrder() ([]string, error) {
+	depths := make(map[string]int, len(g.names))
+	for _, name := range g.names {
+		if _, err := g.depth(name, map[string]bool{}, depths); err != nil {
+			return nil, err
+		}
+	}
+
+	order := append([]string{}, g.names...)
+	sort.SliceStable(order, func(i, j int) bool {
+		return depths[order[i]] < depths[order[j]]
+	})
+
+	return order, nil
+}
@@ -0,0 +1,100 @@
+package manifest_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+)
+
+var _ = Describe("VariableGraph", func() {
+	var m *Manifest
+
+	BeforeEach(func() {
+		m = &Manifest{
+			Variables: []Variable{
+				{Name: "leaf_cert", Type: "certificate", Options: &VariableOptions{CA: "intermediate_ca"}},
+				{Name: "password", Type: "password"},
+				{Name: "intermediate_ca", Type: "certificate", Options: &VariableOptions{IsCA: true, CA: "root_ca"}},
+				{Name: "root_ca", Type: "certificate", Options: &VariableOptions{IsCA: true}},
+				{Name: "other_cert", Type: "certificate", Options: &VariableOptions{CA: "root_ca"}},
+			},
+		}
+	})
+
+	It("returns the depth of the signing chain", func() {
+		g, err := m.VariableGraph()
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(g.Depth("root_ca")).To(Equal(0))
+		Expect(g.Depth("intermediate_ca")).To(Equal(1))
+		Expect(g.Depth("leaf_cert")).To(Equal(2))
+	})
+
+	It("orders CAs before the certificates they sign", func() {
+		g, err := m.VariableGraph()
+		Expect(err).ToNot(HaveOccurred())
+
+		order, err := g.TopologicalOrder()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(order).To(Equal([]string{"password", "root_ca", "intermediate_ca", "other_cert", "leaf_cert"}))
+	})
+
+	It("ignores CAs, which are not declared in the manifest", func() {
+		m.Variables[3].Options.CA = "external_ca"
+
+		g, err := m.VariableGraph()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(g.Depth("root_ca")).To(Equal(0))
+	})
+
+	It("fails for duplicated variables", func() {
+		m.Variables = append(m.Variables, Variable{Name: "password", Type: "password"})
+
+		_, err := m.VariableGraph()
+		Expect(err).To(MatchError(ContainSubstring("duplicated variable 'password'")))
+	})
+
+	It("orders variables after the variables referenced in their alternative names", func() {
+		m.Variables = append([]Variable{
+			{Name: "router_cert", Type: "certificate", Options: &VariableOptions{
+				CA:               "root_ca",
+				AlternativeNames: []string{"router.((system_domain))", "((router_ip.value))", "((external_name))"},
+			}},
+		}, m.Variables...)
+		m.Variables = append(m.Variables,
+			Variable{Name: "router_ip", Type: "password"},
+			Variable{Name: "system_domain", Type: "password", Options: &VariableOptions{AlternativeNames: []string{"((leaf_cert))"}}},
+		)
+
+		g, err := m.VariableGraph()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(g.Depth("system_domain")).To(Equal(3))
+		Expect(g.Depth("router_cert")).To(Equal(4))
+
+		order, err := g.TopologicalOrder()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(order).To(Equal([]string{"password", "root_ca", "router_ip", "intermediate_ca", "other_cert", "leaf_cert", "system_domain", "router_cert"}))
+	})
+
+	It("fails for cyclic alternative name references", func() {
+		m.Variables[1].Options = &VariableOptions{AlternativeNames: []string{"((other_cert))"}}
+		m.Variables[4].Options.AlternativeNames = []string{"((password))"}
+
+		g, err := m.VariableGraph()
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = g.TopologicalOrder()
+		Expect(err).To(MatchError(ContainSubstring("cyclic alternative name reference")))
+	})
+
+	It("fails for cyclic CA references", func() {
+		m.Variables[3].Options.CA = "leaf_cert"
+
+		g, err := m.VariableGraph()
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = g.TopologicalOrder()
+		Expect(err).To(MatchError(ContainSubstring("cyclic CA reference")))
+	})
+})
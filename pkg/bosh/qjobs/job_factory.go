@@ -15,6 +15,7 @@ import (
 	"code.cloudfoundry.org/cf-operator/pkg/bosh/converter"
 	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
 	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	bdnames "code.cloudfoundry.org/cf-operator/pkg/kube/util/names"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/util/operatorimage"
 	qjv1a1 "code.cloudfoundry.org/quarks-job/pkg/kube/apis/quarksjob/v1alpha1"
 	"code.cloudfoundry.org/quarks-utils/pkg/names"
@@ -45,28 +46,60 @@ const (
 	VarInterpolationContainerName = "desired-manifest"
 	// PodNameEnvVar is the environment variable containing metadata.name used to render BOSH spec.id. (CLI)
 	PodNameEnvVar = "POD_NAME"
+	// EnvLogLevel is a key for the container Env used to set the log level (CLI)
+	EnvLogLevel = "LOG_LEVEL"
+	// EnvRenderCacheDir is a key for the container Env used to lookup the render cache dir (CLI)
+	EnvRenderCacheDir = "RENDER_CACHE_DIR"
+	// EnvSSLCertFile is a key for the container Env used by the HTTP clients of the jobs to lookup additional trusted CAs
+	EnvSSLCertFile = "SSL_CERT_FILE"
 )
 
+// renderCacheClaim is the name of the persistent volume claim, on which the
+// instance group manifest job caches rendered templates. Empty disables caching.
+var renderCacheClaim string
+
+// SetRenderCacheClaim initializes the package scoped render cache claim name
+func SetRenderCacheClaim(claim string) {
+	renderCacheClaim = claim
+}
+
+// trustedCABundleSecret is the name of the secret in the watched namespace,
+// whose CA bundle is trusted by the jobs. Empty disables the CA bundle.
+var trustedCABundleSecret string
+
+// SetTrustedCABundle initializes the package scoped trusted CA bundle secret name
+func SetTrustedCABundle(secret string) {
+	trustedCABundleSecret = secret
+}
+
 // JobFactory is a concrete implementation of JobFactory
 type JobFactory struct {
-	Namespace string
+	Namespace   string
+	SecretNamer bdnames.SecretNamer
 }
 
 // NewJobFactory returns a concrete implementation of JobFactory
-func NewJobFactory(namespace string) *JobFactory {
+func NewJobFactory(namespace string, secretNamer bdnames.SecretNamer) *JobFactory {
 	return &JobFactory{
-		Namespace: namespace,
+		Namespace:   namespace,
+		SecretNamer: secretNamer,
 	}
 }
 
 // VariableInterpolationJob returns an quarks job to create the desired manifest
 // The desired manifest is a BOSH manifest with all variables interpolated.
 // It's sometimes referred to as the 'with-vars' manifest.
-func (f *JobFactory) VariableInterpolationJob(deploymentName string, manifest bdm.Manifest) (*qjv1a1.QuarksJob, error) {
+// If debug is set, the interpolation runs with verbose logging.
+// If triggerSecret is set, the manifest is read from that secret instead of
+// the with-ops manifest secret.
+func (f *JobFactory) VariableInterpolationJob(deploymentName string, manifest bdm.Manifest, debug bool, dns *bdv1.JobDNS, triggerSecret string) (*qjv1a1.QuarksJob, error) {
 	args := []string{"util", "variable-interpolation"}
 
 	// This is the source manifest, that still has the '((vars))'
-	manifestSecretName := names.DeploymentSecretName(names.DeploymentSecretTypeManifestWithOps, deploymentName, "")
+	manifestSecretName := f.SecretNamer.DeploymentSecretName(names.DeploymentSecretTypeManifestWithOps, deploymentName, "")
+	if triggerSecret != "" {
+		manifestSecretName = triggerSecret
+	}
 
 	// Prepare Volumes and Volume mounts
 
@@ -76,7 +109,7 @@ func (f *JobFactory) VariableInterpolationJob(deploymentName string, manifest bd
 	// We need a volume and a mount for each input variable
 	for _, variable := range manifest.Variables {
 		varName := variable.Name
-		varSecretName := names.DeploymentSecretName(names.DeploymentSecretTypeVariable, deploymentName, varName)
+		varSecretName := f.SecretNamer.DeploymentSecretName(names.DeploymentSecretTypeVariable, deploymentName, varName)
 
 		volumes = append(volumes, variableVolume(varSecretName))
 		volumeMounts = append(volumeMounts, variableVolumeMount(varSecretName, varName))
@@ -88,8 +121,27 @@ func (f *JobFactory) VariableInterpolationJob(deploymentName string, manifest bd
 		volumeMounts = append(volumeMounts, noVarsVolumeMount())
 	}
 
+	env := []corev1.EnvVar{
+		{
+			Name:  bpmconverter.EnvBOSHManifestPath,
+			Value: filepath.Join("/var/run/secrets/deployment/", bdm.DesiredManifestKeyName),
+		},
+		{
+			Name:  EnvVariablesDir,
+			Value: "/var/run/secrets/variables/",
+		},
+		{
+			Name:  EnvOutputFilePath,
+			Value: filepath.Join(EnvOutputFilePathValue, outputFilename),
+		},
+	}
+	if debug {
+		env = append(env, corev1.EnvVar{Name: EnvLogLevel, Value: "debug"})
+	}
+
 	qJobName := fmt.Sprintf("dm-%s", deploymentName)
-	secretName := names.DesiredManifestPrefix(deploymentName) + VarInterpolationContainerName
+	// QuarksJob appends the version to the unversioned desired manifest name
+	secretName := f.SecretNamer.DesiredManifestName(deploymentName, "")
 
 	// Construct the var interpolation auto-errand qJob
 	qJob := &qjv1a1.QuarksJob{
@@ -133,20 +185,7 @@ func (f *JobFactory) VariableInterpolationJob(deploymentName string, manifest bd
 									ImagePullPolicy: operatorimage.GetOperatorImagePullPolicy(),
 									Args:            args,
 									VolumeMounts:    volumeMounts,
-									Env: []corev1.EnvVar{
-										{
-											Name:  bpmconverter.EnvBOSHManifestPath,
-											Value: filepath.Join("/var/run/secrets/deployment/", bdm.DesiredManifestKeyName),
-										},
-										{
-											Name:  EnvVariablesDir,
-											Value: "/var/run/secrets/variables/",
-										},
-										{
-											Name:  EnvOutputFilePath,
-											Value: filepath.Join(EnvOutputFilePathValue, outputFilename),
-										},
-									},
+									Env:             env,
 								},
 							},
 							Volumes: volumes,
@@ -156,15 +195,17 @@ func (f *JobFactory) VariableInterpolationJob(deploymentName string, manifest bd
 			},
 		},
 	}
+	setPodDNS(qJob, dns)
+	setTrustedCABundle(qJob)
 	return qJob, nil
 }
 
 // InstanceGroupManifestJob generates the job to create an instance group manifest
-func (f *JobFactory) InstanceGroupManifestJob(deploymentName string, manifest bdm.Manifest, linkInfos converter.LinkInfos, initialRollout bool) (*qjv1a1.QuarksJob, error) {
+func (f *JobFactory) InstanceGroupManifestJob(deploymentName string, manifest bdm.Manifest, linkInfos converter.LinkInfos, initialRollout bool, dns *bdv1.JobDNS) (*qjv1a1.QuarksJob, error) {
 	containers := []corev1.Container{}
 	ct := containerTemplate{
 		deploymentName: deploymentName,
-		manifestName:   desiredManifestName(deploymentName),
+		manifestName:   f.desiredManifestName(deploymentName),
 		cmd:            "instance-group",
 		namespace:      f.Namespace,
 		initialRollout: initialRollout,
@@ -197,13 +238,79 @@ func (f *JobFactory) InstanceGroupManifestJob(deploymentName string, manifest bd
 		}
 	}
 
+	setPodDNS(qJob, dns)
+	setTrustedCABundle(qJob)
 	return qJob, nil
 }
 
+// setPodDNS applies the DNS settings of the deployment to the pods of the
+// job. Without settings, the pods use the cluster's default DNS policy.
+func setPodDNS(qJob *qjv1a1.QuarksJob, dns *bdv1.JobDNS) {
+	if dns == nil {
+		return
+	}
+	podSpec := &qJob.Spec.Template.Spec.Template.Spec
+	podSpec.DNSPolicy = dns.DNSPolicy
+	podSpec.DNSConfig = dns.DNSConfig.DeepCopy()
+}
+
+// setTrustedCABundle mounts the trusted CA bundle into the containers of the
+// job and points their HTTP clients at it. The volume, mounts and env are
+// only added once, so applying it again doesn't change the job.
+func setTrustedCABundle(qJob *qjv1a1.QuarksJob) {
+	if trustedCABundleSecret == "" {
+		return
+	}
+
+	podSpec := &qJob.Spec.Template.Spec.Template.Spec
+	volume := trustedCABundleVolume(trustedCABundleSecret)
+	if !hasVolume(podSpec.Volumes, volume.Name) {
+		podSpec.Volumes = append(podSpec.Volumes, volume)
+	}
+
+	for i := range podSpec.Containers {
+		container := &podSpec.Containers[i]
+		mount := trustedCABundleVolumeMount()
+		if !hasVolumeMount(container.VolumeMounts, mount.Name) {
+			container.VolumeMounts = append(container.VolumeMounts, mount)
+		}
+		if !hasEnv(container.Env, EnvSSLCertFile) {
+			container.Env = append(container.Env, corev1.EnvVar{Name: EnvSSLCertFile, Value: trustedCABundlePath})
+		}
+	}
+}
+
+func hasVolume(volumes []corev1.Volume, name string) bool {
+	for _, volume := range volumes {
+		if volume.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func hasVolumeMount(mounts []corev1.VolumeMount, name string) bool {
+	for _, mount := range mounts {
+		if mount.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func hasEnv(env []corev1.EnvVar, name string) bool {
+	for _, e := range env {
+		if e.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 // desiredManifestName returns the sanitized, versioned name of the manifest.
 // QuarksJob will always pick the latest version for versioned secrets
-func desiredManifestName(name string) string {
-	return names.DesiredManifestName(name, "1")
+func (f *JobFactory) desiredManifestName(name string) string {
+	return f.SecretNamer.DesiredManifestName(name, "1")
 }
 
 type containerTemplate struct {
@@ -215,7 +322,7 @@ type containerTemplate struct {
 }
 
 func (ct *containerTemplate) newUtilContainer(instanceGroupName string, linkVolumeMounts []corev1.VolumeMount) corev1.Container {
-	return corev1.Container{
+	container := corev1.Container{
 		Name:            names.Sanitize(instanceGroupName),
 		Image:           operatorimage.GetOperatorDockerImage(),
 		ImagePullPolicy: operatorimage.GetOperatorImagePullPolicy(),
@@ -255,6 +362,13 @@ func (ct *containerTemplate) newUtilContainer(instanceGroupName string, linkVolu
 			},
 		},
 	}
+
+	if renderCacheClaim != "" {
+		container.VolumeMounts = append(container.VolumeMounts, renderCacheVolumeMount())
+		container.Env = append(container.Env, corev1.EnvVar{Name: EnvRenderCacheDir, Value: renderCacheMountPath})
+	}
+
+	return container
 }
 
 // releaseImageQJob collects outputs, like bpm, links or ig manifests, from the BOSH release images
@@ -287,20 +401,18 @@ func (f *JobFactory) releaseImageQJob(name string, deploymentName string, manife
 	}
 
 	outputMap := qjv1a1.OutputMap{}
-	igPrefix := names.DeploymentSecretPrefix(names.DeploymentSecretTypeInstanceGroupResolvedProperties, deploymentName)
-	bpmPrefix := names.DeploymentSecretPrefix(names.DeploymentSecretBpmInformation, deploymentName)
 	for _, container := range containers {
 		outputMap[container.Name] = qjv1a1.FilesToSecrets{
 			InstanceGroupOutputFilename: qjv1a1.SecretOptions{
-				// the same as names.InstanceGroupSecretName(secretType, manifestName, container.Name, "")
-				Name: igPrefix + container.Name,
+				// QuarksJob appends the version to the unversioned names
+				Name: f.SecretNamer.InstanceGroupSecretName(names.DeploymentSecretTypeInstanceGroupResolvedProperties, deploymentName, container.Name, ""),
 				AdditionalSecretLabels: map[string]string{
 					bdv1.LabelDeploymentSecretType: names.DeploymentSecretTypeInstanceGroupResolvedProperties.String(),
 				},
 				Versioned: true,
 			},
 			BPMOutputFilename: qjv1a1.SecretOptions{
-				Name: bpmPrefix + container.Name,
+				Name: f.SecretNamer.InstanceGroupSecretName(names.DeploymentSecretBpmInformation, deploymentName, container.Name, ""),
 				AdditionalSecretLabels: map[string]string{
 					bdv1.LabelDeploymentSecretType: names.DeploymentSecretBpmInformation.String(),
 				},
@@ -309,6 +421,14 @@ func (f *JobFactory) releaseImageQJob(name string, deploymentName string, manife
 		}
 	}
 
+	volumes := append(linkVolumes, []corev1.Volume{
+		*withOpsVolume(f.desiredManifestName(deploymentName)),
+		releaseSourceVolume(),
+	}...)
+	if renderCacheClaim != "" {
+		volumes = append(volumes, renderCacheVolume(renderCacheClaim))
+	}
+
 	// Construct the "BPM configs" or "data gathering" auto-errand qJob
 	qJob := &qjv1a1.QuarksJob{
 		ObjectMeta: metav1.ObjectMeta{
@@ -345,10 +465,7 @@ func (f *JobFactory) releaseImageQJob(name string, deploymentName string, manife
 							// Container to run data gathering
 							Containers: containers,
 							// Volumes for secrets
-							Volumes: append(linkVolumes, []corev1.Volume{
-								*withOpsVolume(desiredManifestName(deploymentName)),
-								releaseSourceVolume(),
-							}...),
+							Volumes: volumes,
 						},
 					},
 				},
@@ -4,9 +4,13 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	corev1 "k8s.io/api/core/v1"
+
 	. "code.cloudfoundry.org/cf-operator/pkg/bosh/converter"
 	"code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
 	"code.cloudfoundry.org/cf-operator/pkg/bosh/qjobs"
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	bdnames "code.cloudfoundry.org/cf-operator/pkg/kube/util/names"
 	"code.cloudfoundry.org/cf-operator/testing"
 	qjv1a1 "code.cloudfoundry.org/quarks-job/pkg/kube/apis/quarksjob/v1alpha1"
 )
@@ -26,12 +30,12 @@ var _ = Describe("JobFactory", func() {
 		m, err = env.DefaultBOSHManifest()
 		linkInfos = LinkInfos{}
 		Expect(err).NotTo(HaveOccurred())
-		factory = qjobs.NewJobFactory("namespace")
+		factory = qjobs.NewJobFactory("namespace", bdnames.DefaultSecretNamer{})
 	})
 
 	Describe("InstanceGroupManifestJob", func() {
 		It("creates init containers", func() {
-			qJob, err := factory.InstanceGroupManifestJob(deploymentName, *m, linkInfos, true)
+			qJob, err := factory.InstanceGroupManifestJob(deploymentName, *m, linkInfos, true, nil)
 			Expect(err).ToNot(HaveOccurred())
 			jobIG := qJob.Spec.Template.Spec
 			// Test init containers in the ig manifest qJob
@@ -49,7 +53,7 @@ var _ = Describe("JobFactory", func() {
 				},
 			}
 
-			qJob, err := factory.InstanceGroupManifestJob(deploymentName, *m, linkInfos, true)
+			qJob, err := factory.InstanceGroupManifestJob(deploymentName, *m, linkInfos, true, nil)
 			Expect(err).ToNot(HaveOccurred())
 			jobIG := qJob.Spec.Template.Spec
 			// Test init containers in the ig manifest qJob
@@ -65,25 +69,43 @@ var _ = Describe("JobFactory", func() {
 
 		It("handles an error when getting release image", func() {
 			m.Stemcells = nil
-			_, err := factory.InstanceGroupManifestJob(deploymentName, *m, linkInfos, true)
+			_, err := factory.InstanceGroupManifestJob(deploymentName, *m, linkInfos, true, nil)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("Generation of gathering job failed for manifest"))
 		})
 
 		It("does not generate the instance group containers when its instances is zero", func() {
 			m.InstanceGroups[0].Instances = 0
-			qJob, err := factory.InstanceGroupManifestJob(deploymentName, *m, linkInfos, true)
+			qJob, err := factory.InstanceGroupManifestJob(deploymentName, *m, linkInfos, true, nil)
 			Expect(err).ToNot(HaveOccurred())
 			jobIG := qJob.Spec.Template.Spec
 			Expect(len(jobIG.Template.Spec.InitContainers)).To(BeNumerically("<", 2))
 			Expect(len(jobIG.Template.Spec.Containers)).To(BeNumerically("<", 2))
 		})
 
+		It("names the outputs and mounts the desired manifest with the secret namer", func() {
+			factory = qjobs.NewJobFactory("namespace", bdnames.PrefixSecretNamer{Prefix: "custom"})
+
+			qJob, err := factory.InstanceGroupManifestJob(deploymentName, *m, linkInfos, true, nil)
+			Expect(err).ToNot(HaveOccurred())
+			om := qJob.Spec.Output.OutputMap
+			Expect(om["redis-slave"]["ig.json"].Name).To(Equal("custom-foo-deployment.ig-resolved.redis-slave"))
+			Expect(om["redis-slave"]["bpm.json"].Name).To(Equal("custom-foo-deployment.bpm.redis-slave"))
+
+			secretNames := []string{}
+			for _, v := range qJob.Spec.Template.Spec.Template.Spec.Volumes {
+				if v.Secret != nil {
+					secretNames = append(secretNames, v.Secret.SecretName)
+				}
+			}
+			Expect(secretNames).To(ContainElement("custom-foo-deployment.desired-manifest-v1"))
+		})
+
 		Context("when manifest contains links", func() {
 			It("creates output entries for all provides", func() {
 				m, err = env.ElaboratedBOSHManifest()
 				Expect(err).NotTo(HaveOccurred())
-				qJob, err := factory.InstanceGroupManifestJob(deploymentName, *m, linkInfos, true)
+				qJob, err := factory.InstanceGroupManifestJob(deploymentName, *m, linkInfos, true, nil)
 				Expect(err).ToNot(HaveOccurred())
 				om := qJob.Spec.Output.OutputMap
 				Expect(om).To(Equal(
@@ -134,7 +156,7 @@ var _ = Describe("JobFactory", func() {
 		})
 
 		It("has one spec-copier init container per instance group", func() {
-			job, err := factory.InstanceGroupManifestJob(deploymentName, *m, linkInfos, true)
+			job, err := factory.InstanceGroupManifestJob(deploymentName, *m, linkInfos, true, nil)
 			Expect(err).ToNot(HaveOccurred())
 
 			spec := job.Spec.Template.Spec.Template.Spec
@@ -145,7 +167,7 @@ var _ = Describe("JobFactory", func() {
 		})
 
 		It("has one bpm-configs container per instance group", func() {
-			job, err := factory.InstanceGroupManifestJob(deploymentName, *m, linkInfos, true)
+			job, err := factory.InstanceGroupManifestJob(deploymentName, *m, linkInfos, true, nil)
 			Expect(err).ToNot(HaveOccurred())
 
 			spec := job.Spec.Template.Spec.Template.Spec
@@ -156,18 +178,58 @@ var _ = Describe("JobFactory", func() {
 
 		It("does not generate the instance group containers when its instances is zero", func() {
 			m.InstanceGroups[0].Instances = 0
-			job, err := factory.InstanceGroupManifestJob(deploymentName, *m, linkInfos, true)
+			job, err := factory.InstanceGroupManifestJob(deploymentName, *m, linkInfos, true, nil)
 			Expect(err).ToNot(HaveOccurred())
 
 			spec := job.Spec.Template.Spec.Template.Spec
 			Expect(len(spec.InitContainers)).To(BeNumerically("<", 2))
 			Expect(len(spec.Containers)).To(BeNumerically("<", 2))
 		})
+
+		It("doesn't mount a render cache by default", func() {
+			job, err := factory.InstanceGroupManifestJob(deploymentName, *m, linkInfos, true, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			spec := job.Spec.Template.Spec.Template.Spec
+			for _, volume := range spec.Volumes {
+				Expect(volume.PersistentVolumeClaim).To(BeNil())
+			}
+			for _, env := range spec.Containers[0].Env {
+				Expect(env.Name).ToNot(Equal(qjobs.EnvRenderCacheDir))
+			}
+		})
+
+		Context("when a render cache claim is configured", func() {
+			BeforeEach(func() {
+				qjobs.SetRenderCacheClaim("render-cache")
+			})
+
+			AfterEach(func() {
+				qjobs.SetRenderCacheClaim("")
+			})
+
+			It("mounts the claim into each instance group container", func() {
+				job, err := factory.InstanceGroupManifestJob(deploymentName, *m, linkInfos, true, nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				spec := job.Spec.Template.Spec.Template.Spec
+				Expect(spec.Volumes).To(ContainElement(corev1.Volume{
+					Name: "render-cache",
+					VolumeSource: corev1.VolumeSource{
+						PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "render-cache"},
+					},
+				}))
+				for _, container := range spec.Containers {
+					Expect(container.VolumeMounts).To(ContainElement(corev1.VolumeMount{Name: "render-cache", MountPath: "/var/cache/quarks/render"}))
+					Expect(container.Env).To(ContainElement(corev1.EnvVar{Name: qjobs.EnvRenderCacheDir, Value: "/var/cache/quarks/render"}))
+				}
+			})
+		})
 	})
 
 	Describe("VariableInterpolationJob", func() {
 		It("mounts variable secrets in the variable interpolation container", func() {
-			job, err := factory.VariableInterpolationJob(deploymentName, *m)
+			job, err := factory.VariableInterpolationJob(deploymentName, *m, false, nil, "")
 			Expect(err).ToNot(HaveOccurred())
 			Expect(job.GetLabels()).To(HaveKeyWithValue(manifest.LabelDeploymentName, deploymentName))
 
@@ -187,6 +249,146 @@ var _ = Describe("JobFactory", func() {
 				"/var/run/secrets/deployment/",
 				"/var/run/secrets/variables/adminpass",
 			))
+			Expect(podSpec.Containers[0].Env).ToNot(ContainElement(corev1.EnvVar{Name: "LOG_LEVEL", Value: "debug"}))
+		})
+
+		It("enables verbose logging in debug mode", func() {
+			job, err := factory.VariableInterpolationJob(deploymentName, *m, true, nil, "")
+			Expect(err).ToNot(HaveOccurred())
+
+			podSpec := job.Spec.Template.Spec.Template.Spec
+			Expect(podSpec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "LOG_LEVEL", Value: "debug"}))
+		})
+
+		It("mounts the secrets named by the secret namer", func() {
+			factory = qjobs.NewJobFactory("namespace", bdnames.PrefixSecretNamer{Prefix: "custom"})
+
+			job, err := factory.VariableInterpolationJob(deploymentName, *m, false, nil, "")
+			Expect(err).ToNot(HaveOccurred())
+
+			secretNames := []string{}
+			for _, v := range job.Spec.Template.Spec.Template.Spec.Volumes {
+				secretNames = append(secretNames, v.Secret.SecretName)
+			}
+			Expect(secretNames).To(ConsistOf("custom-foo-deployment.with-ops", "custom-foo-deployment.var-adminpass"))
+			Expect(job.Spec.Output.OutputMap[qjobs.VarInterpolationContainerName]["output.json"].Name).To(Equal("custom-foo-deployment.desired-manifest"))
+		})
+
+		It("mounts the trigger secret instead of the with-ops secret", func() {
+			job, err := factory.VariableInterpolationJob(deploymentName, *m, false, nil, "first-pass")
+			Expect(err).ToNot(HaveOccurred())
+
+			podSpec := job.Spec.Template.Spec.Template.Spec
+			secretNames := []string{}
+			for _, v := range podSpec.Volumes {
+				secretNames = append(secretNames, v.Secret.SecretName)
+			}
+			Expect(secretNames).To(ConsistOf("first-pass", "foo-deployment.var-adminpass"))
+			Expect(podSpec.Containers[0].VolumeMounts).To(ContainElement(corev1.VolumeMount{Name: "first-pass", MountPath: "/var/run/secrets/deployment/", ReadOnly: true}))
+		})
+	})
+
+	Describe("trusted CA bundle", func() {
+		It("doesn't mount a CA bundle by default", func() {
+			job, err := factory.VariableInterpolationJob(deploymentName, *m, false, nil, "")
+			Expect(err).ToNot(HaveOccurred())
+
+			spec := job.Spec.Template.Spec.Template.Spec
+			for _, volume := range spec.Volumes {
+				Expect(volume.Name).ToNot(Equal("trusted-ca-bundle"))
+			}
+			for _, env := range spec.Containers[0].Env {
+				Expect(env.Name).ToNot(Equal(qjobs.EnvSSLCertFile))
+			}
+		})
+
+		Context("when a trusted CA bundle secret is configured", func() {
+			BeforeEach(func() {
+				qjobs.SetTrustedCABundle("internal-ca")
+			})
+
+			AfterEach(func() {
+				qjobs.SetTrustedCABundle("")
+			})
+
+			expectCABundle := func(job *qjv1a1.QuarksJob) {
+				spec := job.Spec.Template.Spec.Template.Spec
+				Expect(spec.Volumes).To(ContainElement(corev1.Volume{
+					Name: "trusted-ca-bundle",
+					VolumeSource: corev1.VolumeSource{
+						Secret: &corev1.SecretVolumeSource{
+							SecretName: "internal-ca",
+							Items:      []corev1.KeyToPath{{Key: "ca.crt", Path: "ca.crt"}},
+						},
+					},
+				}))
+				for _, container := range spec.Containers {
+					Expect(container.VolumeMounts).To(ContainElement(corev1.VolumeMount{Name: "trusted-ca-bundle", MountPath: "/etc/ssl/quarks", ReadOnly: true}))
+					Expect(container.Env).To(ContainElement(corev1.EnvVar{Name: qjobs.EnvSSLCertFile, Value: "/etc/ssl/quarks/ca.crt"}))
+				}
+			}
+
+			It("mounts the CA bundle into the variable interpolation job", func() {
+				job, err := factory.VariableInterpolationJob(deploymentName, *m, false, nil, "")
+				Expect(err).ToNot(HaveOccurred())
+				expectCABundle(job)
+			})
+
+			It("mounts the CA bundle into the instance group manifest job", func() {
+				job, err := factory.InstanceGroupManifestJob(deploymentName, *m, linkInfos, true, nil)
+				Expect(err).ToNot(HaveOccurred())
+				expectCABundle(job)
+			})
+
+			It("generates the same job on every reconcile", func() {
+				job, err := factory.InstanceGroupManifestJob(deploymentName, *m, linkInfos, true, nil)
+				Expect(err).ToNot(HaveOccurred())
+				again, err := factory.InstanceGroupManifestJob(deploymentName, *m, linkInfos, true, nil)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(again.Spec.Template).To(Equal(job.Spec.Template))
+			})
+		})
+	})
+
+	Describe("job DNS", func() {
+		var dns *bdv1.JobDNS
+
+		BeforeEach(func() {
+			dns = &bdv1.JobDNS{
+				DNSPolicy: corev1.DNSNone,
+				DNSConfig: &corev1.PodDNSConfig{
+					Nameservers: []string{"10.0.0.53"},
+					Searches:    []string{"corp.internal"},
+				},
+			}
+		})
+
+		It("keeps the default DNS settings without job DNS", func() {
+			job, err := factory.VariableInterpolationJob(deploymentName, *m, false, nil, "")
+			Expect(err).ToNot(HaveOccurred())
+
+			podSpec := job.Spec.Template.Spec.Template.Spec
+			Expect(podSpec.DNSPolicy).To(BeEmpty())
+			Expect(podSpec.DNSConfig).To(BeNil())
+		})
+
+		It("sets the DNS settings of the variable interpolation job", func() {
+			job, err := factory.VariableInterpolationJob(deploymentName, *m, false, dns, "")
+			Expect(err).ToNot(HaveOccurred())
+
+			podSpec := job.Spec.Template.Spec.Template.Spec
+			Expect(podSpec.DNSPolicy).To(Equal(corev1.DNSNone))
+			Expect(podSpec.DNSConfig).To(Equal(dns.DNSConfig))
+		})
+
+		It("sets the DNS settings of the instance group manifest job", func() {
+			job, err := factory.InstanceGroupManifestJob(deploymentName, *m, linkInfos, true, dns)
+			Expect(err).ToNot(HaveOccurred())
+
+			podSpec := job.Spec.Template.Spec.Template.Spec
+			Expect(podSpec.DNSPolicy).To(Equal(corev1.DNSNone))
+			Expect(podSpec.DNSConfig.Nameservers).To(ConsistOf("10.0.0.53"))
+			Expect(podSpec.DNSConfig.Searches).To(ConsistOf("corp.internal"))
 		})
 	})
 })
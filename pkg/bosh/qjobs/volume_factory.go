@@ -13,6 +13,19 @@ const (
 
 	// releaseSourceName is the folder for release sources
 	releaseSourceName = "instance-group"
+
+	// renderCacheName is the name of the render cache volume
+	renderCacheName = "render-cache"
+	// renderCacheMountPath is the directory, in which rendered templates are cached
+	renderCacheMountPath = "/var/cache/quarks/render"
+	// trustedCABundleName is the name of the trusted CA bundle volume
+	trustedCABundleName = "trusted-ca-bundle"
+	// trustedCABundleMountPath is the directory, in which the trusted CA bundle is mounted
+	trustedCABundleMountPath = "/etc/ssl/quarks"
+	// trustedCABundleKey is the key of the CA bundle in the trusted CA bundle secret
+	trustedCABundleKey = "ca.crt"
+	// trustedCABundlePath is the path of the trusted CA bundle in the job containers
+	trustedCABundlePath = trustedCABundleMountPath + "/" + trustedCABundleKey
 )
 
 // withOpsVolume is a volume for the "not interpolated" manifest,
@@ -92,3 +105,47 @@ func releaseSourceVolumeMount() corev1.VolumeMount {
 		MountPath: bpmconverter.VolumeRenderingDataMountPath,
 	}
 }
+
+// renderCacheVolume is the persistent volume, which caches rendered templates
+func renderCacheVolume(claim string) corev1.Volume {
+	return corev1.Volume{
+		Name: names.VolumeName(renderCacheName),
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: claim,
+			},
+		},
+	}
+}
+
+// renderCacheVolumeMount mounts the render cache volume
+func renderCacheVolumeMount() corev1.VolumeMount {
+	return corev1.VolumeMount{
+		Name:      names.VolumeName(renderCacheName),
+		MountPath: renderCacheMountPath,
+	}
+}
+
+// trustedCABundleVolume is the volume of the secret, which contains the CA bundle trusted by the jobs
+func trustedCABundleVolume(secret string) corev1.Volume {
+	return corev1.Volume{
+		Name: trustedCABundleName,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: secret,
+				Items: []corev1.KeyToPath{
+					{Key: trustedCABundleKey, Path: trustedCABundleKey},
+				},
+			},
+		},
+	}
+}
+
+// trustedCABundleVolumeMount mounts the trusted CA bundle volume read only
+func trustedCABundleVolumeMount() corev1.VolumeMount {
+	return corev1.VolumeMount{
+		Name:      trustedCABundleName,
+		MountPath: trustedCABundleMountPath,
+		ReadOnly:  true,
+	}
+}
@@ -0,0 +1,80 @@
+// Package configserver writes variable values to a BOSH Config Server
+package configserver
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Client writes variable values to a BOSH Config Server
+type Client interface {
+	Put(ctx context.Context, name string, value interface{}) error
+}
+
+// NewClient returns a client for the Config Server at url
+func NewClient(url string, tlsConfig *tls.Config) Client {
+	return &client{
+		url: strings.TrimSuffix(url, "/"),
+		http: &http.Client{
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		},
+	}
+}
+
+type client struct {
+	url  string
+	http *http.Client
+}
+
+// Put sets the value of the variable name, which creates a new version
+func (c *client) Put(ctx context.Context, name string, value interface{}) error {
+	body, err := json.Marshal(struct {
+		Name  string      `json:"name"`
+		Value interface{} `json:"value"`
+	}{name, value})
+	if err != nil {
+		return errors.Wrapf(err, "marshalling value of variable '%s'", name)
+	}
+
+	req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("%s/v1/data", c.url), bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrapf(err, "building request for variable '%s'", name)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req.WithContext(ctx))
+	if err != nil {
+		return errors.Wrapf(err, "putting variable '%s'", name)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		respBody, _ := ioutil.ReadAll(resp.Body)
+		return errors.Errorf("putting variable '%s' failed with status %d: %s", name, resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	return nil
+}
+
+// Value converts the data of a variable secret into a Config Server value.
+// Passwords, which only have the 'password' key, are plain strings. Other
+// variables, e.g. certificates, are objects with a field per key.
+func Value(data map[string][]byte) interface{} {
+	if password, ok := data["password"]; ok && len(data) == 1 {
+		return string(password)
+	}
+
+	value := map[string]string{}
+	for k, v := range data {
+		value[k] = string(v)
+	}
+	return value
+}
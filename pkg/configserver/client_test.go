@@ -0,0 +1,71 @@
+package configserver_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/cf-operator/pkg/configserver"
+)
+
+var _ = Describe("Client", func() {
+	var (
+		server *httptest.Server
+		status int
+		method string
+		body   string
+	)
+
+	BeforeEach(func() {
+		status = http.StatusOK
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.URL.Path).To(Equal("/v1/data"))
+			method = r.Method
+			b, err := ioutil.ReadAll(r.Body)
+			Expect(err).ToNot(HaveOccurred())
+			body = string(b)
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(`{"error":"fake-error"}`))
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("puts the value of the variable", func() {
+		err := configserver.NewClient(server.URL+"/", nil).Put(context.Background(), "/foo/adminpass", "secret")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(method).To(Equal(http.MethodPut))
+		Expect(body).To(MatchJSON(`{"name":"/foo/adminpass","value":"secret"}`))
+	})
+
+	It("fails on error responses", func() {
+		status = http.StatusUnauthorized
+
+		err := configserver.NewClient(server.URL, nil).Put(context.Background(), "/foo/adminpass", "secret")
+		Expect(err).To(MatchError(ContainSubstring("failed with status 401: {\"error\":\"fake-error\"}")))
+	})
+})
+
+var _ = Describe("Value", func() {
+	It("returns passwords as strings", func() {
+		Expect(configserver.Value(map[string][]byte{"password": []byte("secret")})).To(Equal("secret"))
+	})
+
+	It("returns other variables as objects", func() {
+		Expect(configserver.Value(map[string][]byte{
+			"ca":          []byte("ca"),
+			"certificate": []byte("cert"),
+			"private_key": []byte("key"),
+		})).To(Equal(map[string]string{
+			"ca":          "ca",
+			"certificate": "cert",
+			"private_key": "key",
+		}))
+	})
+})
@@ -0,0 +1,13 @@
+package configserver_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestConfigServer(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Config Server Suite")
+}
@@ -0,0 +1,165 @@
+// Package credhub reads credentials from a CredHub server
+package credhub
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// CredHub credential types
+const (
+	TypeValue       = "value"
+	TypePassword    = "password"
+	TypeCertificate = "certificate"
+	TypeJSON        = "json"
+)
+
+// Keys of the secret containing the mTLS client certificate for CredHub
+const (
+	ClientCertKey = "tls.crt"
+	ClientKeyKey  = "tls.key"
+	CAKey         = "ca.crt"
+)
+
+// Credential is the current version of a CredHub credential
+type Credential struct {
+	Name  string          `json:"name"`
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Client reads credentials from CredHub
+type Client interface {
+	Get(ctx context.Context, path string) (Credential, error)
+}
+
+// NewClient returns a client for the CredHub server at url
+func NewClient(url string, tlsConfig *tls.Config) Client {
+	return &client{
+		url: strings.TrimSuffix(url, "/"),
+		http: &http.Client{
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		},
+	}
+}
+
+type client struct {
+	url  string
+	http *http.Client
+}
+
+// Get returns the current version of the credential at path
+func (c *client) Get(ctx context.Context, path string) (Credential, error) {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/api/v1/data?current=true&name=%s", c.url, url.QueryEscape(path)), nil)
+	if err != nil {
+		return Credential{}, errors.Wrapf(err, "building request for credential '%s'", path)
+	}
+
+	resp, err := c.http.Do(req.WithContext(ctx))
+	if err != nil {
+		return Credential{}, errors.Wrapf(err, "requesting credential '%s'", path)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return Credential{}, errors.Wrapf(err, "reading credential '%s'", path)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Credential{}, errors.Errorf("requesting credential '%s' failed with status %d: %s", path, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	result := struct {
+		Data []Credential `json:"data"`
+	}{}
+	err = json.Unmarshal(body, &result)
+	if err != nil {
+		return Credential{}, errors.Wrapf(err, "parsing credential '%s'", path)
+	}
+	if len(result.Data) == 0 {
+		return Credential{}, errors.Errorf("credential '%s' has no current version", path)
+	}
+
+	return result.Data[0], nil
+}
+
+// TLSConfig returns the mTLS configuration for the client certificate in secret
+func TLSConfig(secret *corev1.Secret) (*tls.Config, error) {
+	cert, err := tls.X509KeyPair(secret.Data[ClientCertKey], secret.Data[ClientKeyKey])
+	if err != nil {
+		return nil, errors.Wrapf(err, "loading client certificate from secret '%s'", secret.Name)
+	}
+
+	config := &tls.Config{Certificates: []tls.Certificate{cert}}
+	if ca, ok := secret.Data[CAKey]; ok {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, errors.Errorf("invalid CA certificate in secret '%s'", secret.Name)
+		}
+		config.RootCAs = pool
+	}
+
+	return config, nil
+}
+
+// SecretData converts the credential into the data of a variable secret.
+// Values and passwords are stored in the 'password' key, which the variable
+// interpolation uses as the value of the variable. Certificates are stored in
+// the 'ca', 'certificate' and 'private_key' keys and JSON credentials in a key
+// per top level field.
+func SecretData(cred Credential) (map[string]string, error) {
+	switch cred.Type {
+	case TypeValue, TypePassword:
+		var value interface{}
+		if err := json.Unmarshal(cred.Value, &value); err != nil {
+			return nil, errors.Wrapf(err, "parsing %s credential '%s'", cred.Type, cred.Name)
+		}
+		return map[string]string{"password": stringify(value)}, nil
+	case TypeCertificate:
+		value := struct {
+			CA          string `json:"ca"`
+			Certificate string `json:"certificate"`
+			PrivateKey  string `json:"private_key"`
+		}{}
+		if err := json.Unmarshal(cred.Value, &value); err != nil {
+			return nil, errors.Wrapf(err, "parsing certificate credential '%s'", cred.Name)
+		}
+		return map[string]string{
+			"ca":          value.CA,
+			"certificate": value.Certificate,
+			"private_key": value.PrivateKey,
+		}, nil
+	case TypeJSON:
+		value := map[string]interface{}{}
+		if err := json.Unmarshal(cred.Value, &value); err != nil {
+			return nil, errors.Wrapf(err, "parsing json credential '%s'", cred.Name)
+		}
+		data := map[string]string{}
+		for k, v := range value {
+			data[k] = stringify(v)
+		}
+		return data, nil
+	}
+
+	return nil, errors.Errorf("unsupported type '%s' of credential '%s'", cred.Type, cred.Name)
+}
+
+// stringify returns strings as they are and other values JSON encoded
+func stringify(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	b, _ := json.Marshal(value)
+	return string(b)
+}
@@ -0,0 +1,100 @@
+package credhub_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"code.cloudfoundry.org/cf-operator/pkg/credhub"
+)
+
+var _ = Describe("Client", func() {
+	var (
+		server *httptest.Server
+		status int
+		body   string
+		query  string
+	)
+
+	BeforeEach(func() {
+		status = http.StatusOK
+		body = `{"data":[{"name":"/cf/admin","type":"password","value":"secret"}]}`
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.URL.Path).To(Equal("/api/v1/data"))
+			query = r.URL.RawQuery
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(body))
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("returns the current version of the credential", func() {
+		cred, err := credhub.NewClient(server.URL+"/", nil).Get(context.Background(), "/cf/admin")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(query).To(Equal("current=true&name=%2Fcf%2Fadmin"))
+		Expect(cred.Type).To(Equal(credhub.TypePassword))
+		Expect(string(cred.Value)).To(Equal(`"secret"`))
+	})
+
+	It("fails on error responses", func() {
+		status = http.StatusNotFound
+		body = `{"error":"The request could not be completed because the credential does not exist"}`
+
+		_, err := credhub.NewClient(server.URL, nil).Get(context.Background(), "/cf/admin")
+		Expect(err).To(MatchError(ContainSubstring("failed with status 404: {\"error\"")))
+	})
+
+	It("fails, if there is no current version", func() {
+		body = `{"data":[]}`
+
+		_, err := credhub.NewClient(server.URL, nil).Get(context.Background(), "/cf/admin")
+		Expect(err).To(MatchError(ContainSubstring("has no current version")))
+	})
+})
+
+var _ = Describe("SecretData", func() {
+	credential := func(t string, value string) credhub.Credential {
+		return credhub.Credential{Name: "/cf/x", Type: t, Value: json.RawMessage(value)}
+	}
+
+	It("stores values and passwords in the password key", func() {
+		Expect(credhub.SecretData(credential(credhub.TypePassword, `"secret"`))).To(Equal(map[string]string{"password": "secret"}))
+		Expect(credhub.SecretData(credential(credhub.TypeValue, `42`))).To(Equal(map[string]string{"password": "42"}))
+	})
+
+	It("stores certificates in the keys of certificate variables", func() {
+		Expect(credhub.SecretData(credential(credhub.TypeCertificate, `{"ca":"ca","certificate":"cert","private_key":"key"}`))).To(Equal(map[string]string{
+			"ca":          "ca",
+			"certificate": "cert",
+			"private_key": "key",
+		}))
+	})
+
+	It("stores each field of json credentials in its own key", func() {
+		Expect(credhub.SecretData(credential(credhub.TypeJSON, `{"user":"admin","ports":[80,443]}`))).To(Equal(map[string]string{
+			"user":  "admin",
+			"ports": "[80,443]",
+		}))
+	})
+
+	It("rejects unsupported types", func() {
+		_, err := credhub.SecretData(credential("ssh", `{}`))
+		Expect(err).To(MatchError("unsupported type 'ssh' of credential '/cf/x'"))
+	})
+})
+
+var _ = Describe("TLSConfig", func() {
+	It("fails for secrets without a client certificate", func() {
+		_, err := credhub.TLSConfig(&corev1.Secret{Data: map[string][]byte{}})
+		Expect(err).To(MatchError(ContainSubstring("loading client certificate")))
+	})
+})
@@ -0,0 +1,52 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SetStatusCondition sets the condition in conditions, like
+// apimeta.SetStatusCondition of newer Kubernetes versions. The last
+// transition time is only changed, if the status changes. If it isn't set,
+// it defaults to now.
+func SetStatusCondition(conditions *[]Condition, newCondition Condition) {
+	if conditions == nil {
+		return
+	}
+
+	existing := FindStatusCondition(*conditions, newCondition.Type)
+	if existing == nil {
+		if newCondition.LastTransitionTime.IsZero() {
+			newCondition.LastTransitionTime = metav1.Now()
+		}
+		*conditions = append(*conditions, newCondition)
+		return
+	}
+
+	if existing.Status != newCondition.Status {
+		existing.Status = newCondition.Status
+		if !newCondition.LastTransitionTime.IsZero() {
+			existing.LastTransitionTime = newCondition.LastTransitionTime
+		} else {
+			existing.LastTransitionTime = metav1.Now()
+		}
+	}
+	existing.Reason = newCondition.Reason
+	existing.Message = newCondition.Message
+	existing.ObservedGeneration = newCondition.ObservedGeneration
+}
+
+// FindStatusCondition returns the condition of the type, or nil if it isn't set
+func FindStatusCondition(conditions []Condition, conditionType string) *Condition {
+	for i := range conditions {
+		if conditions[i].Type == conditionType {
+			return &conditions[i]
+		}
+	}
+	return nil
+}
+
+// IsStatusConditionTrue returns true, if the condition of the type is 'True'
+func IsStatusConditionTrue(conditions []Condition, conditionType string) bool {
+	c := FindStatusCondition(conditions, conditionType)
+	return c != nil && c.Status == metav1.ConditionTrue
+}
@@ -20,6 +20,11 @@ const (
 	BOSHDeploymentResourceKind = "BOSHDeployment"
 	// BOSHDeploymentResourcePlural is the plural name of BOSHDeployment
 	BOSHDeploymentResourcePlural = "boshdeployments"
+
+	// BOSHDeploymentTemplateResourceKind is the kind name of BOSHDeploymentTemplate
+	BOSHDeploymentTemplateResourceKind = "BOSHDeploymentTemplate"
+	// BOSHDeploymentTemplateResourcePlural is the plural name of BOSHDeploymentTemplate
+	BOSHDeploymentTemplateResourcePlural = "boshdeploymenttemplates"
 )
 
 var (
@@ -40,6 +45,236 @@ var (
 				"spec": {
 					Type: "object",
 					Properties: map[string]extv1.JSONSchemaProps{
+						"cloudConfig": {
+							Type: "object",
+							Properties: map[string]extv1.JSONSchemaProps{
+								"name": {
+									Type:      "string",
+									MinLength: pointers.Int64(1),
+								},
+								"type": {
+									Type: "string",
+									Enum: []extv1.JSON{
+										{
+											Raw: []byte(`"configmap"`),
+										},
+										{
+											Raw: []byte(`"secret"`),
+										},
+										{
+											Raw: []byte(`"url"`),
+										},
+									},
+								},
+							},
+							Required: []string{
+								"type",
+								"name",
+							},
+						},
+						"compressManifest": {
+							Type: "boolean",
+						},
+						"deploymentStrategy": {
+							Type: "object",
+							Properties: map[string]extv1.JSONSchemaProps{
+								"terminationPolicy": {
+									Type: "string",
+									Enum: []extv1.JSON{
+										{
+											Raw: []byte(`"DeleteAfterReady"`),
+										},
+										{
+											Raw: []byte(`"DeleteBeforeCreate"`),
+										},
+										{
+											Raw: []byte(`"Retain"`),
+										},
+									},
+								},
+							},
+						},
+						"deploymentTags": {
+							Type: "object",
+							AdditionalProperties: &extv1.JSONSchemaPropsOrBool{
+								Schema: &extv1.JSONSchemaProps{
+									Type: "string",
+								},
+							},
+						},
+						"externalSecretSelector": {
+							Type: "object",
+						},
+						"failurePolicy": {
+							Type: "string",
+							Enum: []extv1.JSON{
+								{
+									Raw: []byte(`"Retry"`),
+								},
+								{
+									Raw: []byte(`"Halt"`),
+								},
+								{
+									Raw: []byte(`"Ignore"`),
+								},
+							},
+						},
+						"features": {
+							Type: "object",
+							Properties: map[string]extv1.JSONSchemaProps{
+								"enableBPMDebug": {
+									Type: "boolean",
+								},
+								"observabilityBundle": {
+									Type: "boolean",
+								},
+								"reconcileServiceExports": {
+									Type: "boolean",
+								},
+							},
+						},
+						"generateServiceMonitors": {
+							Type: "boolean",
+						},
+						"gracefulUpgradeTimeout": {
+							Type: "string",
+						},
+						"ignoredInstanceGroups": {
+							Type: "array",
+							Items: &extv1.JSONSchemaPropsOrArray{
+								Schema: &extv1.JSONSchemaProps{
+									Type: "string",
+								},
+							},
+						},
+						"initContainers": {
+							Type: "object",
+							AdditionalProperties: &extv1.JSONSchemaPropsOrBool{
+								Schema: &extv1.JSONSchemaProps{
+									Type: "array",
+									Items: &extv1.JSONSchemaPropsOrArray{
+										Schema: &extv1.JSONSchemaProps{
+											Type: "object",
+										},
+									},
+								},
+							},
+						},
+						"instanceGroups": {
+							Type: "array",
+							Items: &extv1.JSONSchemaPropsOrArray{
+								Schema: &extv1.JSONSchemaProps{
+									Type: "object",
+									Properties: map[string]extv1.JSONSchemaProps{
+										"name": {
+											Type:      "string",
+											MinLength: pointers.Int64(1),
+										},
+										"podAnnotations": {
+											Type: "object",
+											AdditionalProperties: &extv1.JSONSchemaPropsOrBool{
+												Schema: &extv1.JSONSchemaProps{
+													Type: "string",
+												},
+											},
+										},
+										"podAntiAffinity": {
+											Type: "string",
+											Enum: []extv1.JSON{
+												{
+													Raw: []byte(`"None"`),
+												},
+												{
+													Raw: []byte(`"Preferred"`),
+												},
+												{
+													Raw: []byte(`"Required"`),
+												},
+											},
+										},
+										"podSecurityContext": {
+											Type: "object",
+										},
+									},
+									Required: []string{
+										"name",
+									},
+								},
+							},
+						},
+						"jobDNS": {
+							Type: "object",
+							Properties: map[string]extv1.JSONSchemaProps{
+								"dnsConfig": {
+									Type: "object",
+									Properties: map[string]extv1.JSONSchemaProps{
+										"nameservers": {
+											Type: "array",
+											Items: &extv1.JSONSchemaPropsOrArray{
+												Schema: &extv1.JSONSchemaProps{
+													Type: "string",
+												},
+											},
+										},
+										"options": {
+											Type: "array",
+											Items: &extv1.JSONSchemaPropsOrArray{
+												Schema: &extv1.JSONSchemaProps{
+													Type: "object",
+													Properties: map[string]extv1.JSONSchemaProps{
+														"name": {
+															Type: "string",
+														},
+														"value": {
+															Type: "string",
+														},
+													},
+												},
+											},
+										},
+										"searches": {
+											Type: "array",
+											Items: &extv1.JSONSchemaPropsOrArray{
+												Schema: &extv1.JSONSchemaProps{
+													Type: "string",
+												},
+											},
+										},
+									},
+								},
+								"dnsPolicy": {
+									Type: "string",
+									Enum: []extv1.JSON{
+										{
+											Raw: []byte(`"ClusterFirst"`),
+										},
+										{
+											Raw: []byte(`"ClusterFirstWithHostNet"`),
+										},
+										{
+											Raw: []byte(`"Default"`),
+										},
+										{
+											Raw: []byte(`"None"`),
+										},
+									},
+								},
+							},
+						},
+						"linkAddressFormat": {
+							Type: "string",
+							Enum: []extv1.JSON{
+								{
+									Raw: []byte(`"IP"`),
+								},
+								{
+									Raw: []byte(`"FQDN"`),
+								},
+								{
+									Raw: []byte(`"ServiceDNS"`),
+								},
+							},
+						},
 						"manifest": {
 							Type: "object",
 							Properties: map[string]extv1.JSONSchemaProps{
@@ -47,6 +282,42 @@ var (
 									Type:      "string",
 									MinLength: pointers.Int64(1),
 								},
+								"git": {
+									Type: "object",
+									Properties: map[string]extv1.JSONSchemaProps{
+										"authSecretName": {
+											Type: "string",
+										},
+										"commit": {
+											Type:    "string",
+											Pattern: "^[0-9a-f]{40}$",
+										},
+										"path": {
+											Type:      "string",
+											MinLength: pointers.Int64(1),
+										},
+										"ref": {
+											Type: "string",
+										},
+									},
+									Required: []string{
+										"path",
+									},
+								},
+								"oauthTokenSecretRef": {
+									Type: "object",
+									Properties: map[string]extv1.JSONSchemaProps{
+										"key": {
+											Type: "string",
+										},
+										"name": {
+											Type: "string",
+										},
+									},
+									Required: []string{
+										"key",
+									},
+								},
 								"type": {
 									Type: "string",
 									Enum: []extv1.JSON{
@@ -59,6 +330,9 @@ var (
 										{
 											Raw: []byte(`"url"`),
 										},
+										{
+											Raw: []byte(`"git"`),
+										},
 									},
 								},
 							},
@@ -67,6 +341,20 @@ var (
 								"name",
 							},
 						},
+						"manifestDebugMode": {
+							Type: "boolean",
+						},
+						"manifestRetentionPolicy": {
+							Type: "string",
+							Enum: []extv1.JSON{
+								{
+									Raw: []byte(`"Delete"`),
+								},
+								{
+									Raw: []byte(`"Retain"`),
+								},
+							},
+						},
 						"ops": {
 							Type: "array",
 							Items: &extv1.JSONSchemaPropsOrArray{
@@ -77,6 +365,42 @@ var (
 											Type:      "string",
 											MinLength: pointers.Int64(1),
 										},
+										"git": {
+											Type: "object",
+											Properties: map[string]extv1.JSONSchemaProps{
+												"authSecretName": {
+													Type: "string",
+												},
+												"commit": {
+													Type:    "string",
+													Pattern: "^[0-9a-f]{40}$",
+												},
+												"path": {
+													Type:      "string",
+													MinLength: pointers.Int64(1),
+												},
+												"ref": {
+													Type: "string",
+												},
+											},
+											Required: []string{
+												"path",
+											},
+										},
+										"oauthTokenSecretRef": {
+											Type: "object",
+											Properties: map[string]extv1.JSONSchemaProps{
+												"key": {
+													Type: "string",
+												},
+												"name": {
+													Type: "string",
+												},
+											},
+											Required: []string{
+												"key",
+											},
+										},
 										"type": {
 											Type: "string",
 											Enum: []extv1.JSON{
@@ -89,6 +413,9 @@ var (
 												{
 													Raw: []byte(`"url"`),
 												},
+												{
+													Raw: []byte(`"git"`),
+												},
 											},
 										},
 									},
@@ -99,6 +426,40 @@ var (
 								},
 							},
 						},
+						"podAntiAffinity": {
+							Type: "string",
+							Enum: []extv1.JSON{
+								{
+									Raw: []byte(`"None"`),
+								},
+								{
+									Raw: []byte(`"Preferred"`),
+								},
+								{
+									Raw: []byte(`"Required"`),
+								},
+							},
+						},
+						"podSecurityContext": {
+							Type: "object",
+						},
+						"updateConfig": {
+							Type: "object",
+							Properties: map[string]extv1.JSONSchemaProps{
+								"canaryWatchTime": {
+									Type: "string",
+								},
+								"updateWatchTime": {
+									Type: "string",
+								},
+							},
+						},
+						"validateOnStaging": {
+							Type: "boolean",
+						},
+						"variableInterpolationTriggerSecret": {
+							Type: "string",
+						},
 					},
 					Required: []string{
 						"manifest",
@@ -107,9 +468,104 @@ var (
 				"status": {
 					Type: "object",
 					Properties: map[string]extv1.JSONSchemaProps{
+						"conditions": {
+							Type: "array",
+							Items: &extv1.JSONSchemaPropsOrArray{
+								Schema: &extv1.JSONSchemaProps{
+									Type: "object",
+									Properties: map[string]extv1.JSONSchemaProps{
+										"lastTransitionTime": {
+											Type: "string",
+										},
+										"message": {
+											Type: "string",
+										},
+										"observedGeneration": {
+											Type: "integer",
+										},
+										"reason": {
+											Type: "string",
+										},
+										"status": {
+											Type: "string",
+										},
+										"type": {
+											Type: "string",
+										},
+									},
+									Required: []string{
+										"type",
+										"status",
+									},
+								},
+							},
+						},
+						"correlationID": {
+							Type: "string",
+						},
+						"lastError": {
+							Type: "string",
+						},
 						"lastReconcile": {
 							Type: "string",
 						},
+						"pendingChanges": {
+							Type: "array",
+							Items: &extv1.JSONSchemaPropsOrArray{
+								Schema: &extv1.JSONSchemaProps{
+									Type: "string",
+								},
+							},
+						},
+						"phase": {
+							Type: "string",
+						},
+						"restoredState": {
+							Type: "object",
+							Properties: map[string]extv1.JSONSchemaProps{
+								"desiredManifestVersion": {
+									Type: "integer",
+								},
+								"exportedAt": {
+									Type: "string",
+								},
+								"inputHash": {
+									Type: "string",
+								},
+								"observedGeneration": {
+									Type: "integer",
+								},
+								"phase": {
+									Type: "string",
+								},
+							},
+						},
+						"stagingError": {
+							Type: "string",
+						},
+						"suspendedInstanceGroups": {
+							Type: "array",
+							Items: &extv1.JSONSchemaPropsOrArray{
+								Schema: &extv1.JSONSchemaProps{
+									Type: "string",
+								},
+							},
+						},
+						"updateStartedAt": {
+							Type: "string",
+						},
+						"updateWaitUntil": {
+							Type: "string",
+						},
+						"variableGenerationComplete": {
+							Type: "integer",
+						},
+						"variableGenerationTotal": {
+							Type: "integer",
+						},
+						"waitingForSecret": {
+							Type: "string",
+						},
 					},
 				},
 			},
@@ -119,6 +575,72 @@ var (
 	// BOSHDeploymentResourceName is the resource name of BOSHDeployment
 	BOSHDeploymentResourceName = fmt.Sprintf("%s.%s", BOSHDeploymentResourcePlural, apis.GroupName)
 
+	// BOSHDeploymentTemplateResourceShortNames is the short names of BOSHDeploymentTemplate
+	BOSHDeploymentTemplateResourceShortNames = []string{"bdtpl", "bdtpls"}
+
+	// BOSHDeploymentTemplateValidation is the validation method for BOSHDeploymentTemplate.
+	// Its template is validated like the spec of a BOSHDeployment.
+	BOSHDeploymentTemplateValidation = extv1.CustomResourceValidation{
+		OpenAPIV3Schema: &extv1.JSONSchemaProps{
+			Type: "object",
+			Properties: map[string]extv1.JSONSchemaProps{
+				"spec": {
+					Type: "object",
+					Properties: map[string]extv1.JSONSchemaProps{
+						"instances": {
+							Type: "array",
+							Items: &extv1.JSONSchemaPropsOrArray{
+								Schema: &extv1.JSONSchemaProps{
+									Type: "object",
+									Properties: map[string]extv1.JSONSchemaProps{
+										"name": {
+											Type:    "string",
+											Pattern: "^[a-z0-9]([-a-z0-9]*[a-z0-9])?$",
+										},
+										"values": {
+											Type: "object",
+											AdditionalProperties: &extv1.JSONSchemaPropsOrBool{
+												Schema: &extv1.JSONSchemaProps{
+													Type: "string",
+												},
+											},
+										},
+									},
+									Required: []string{
+										"name",
+									},
+								},
+							},
+						},
+						"template": BOSHDeploymentValidation.OpenAPIV3Schema.Properties["spec"],
+					},
+					Required: []string{
+						"template",
+					},
+				},
+				"status": {
+					Type: "object",
+					Properties: map[string]extv1.JSONSchemaProps{
+						"deployments": {
+							Type: "array",
+							Items: &extv1.JSONSchemaPropsOrArray{
+								Schema: &extv1.JSONSchemaProps{
+									Type: "string",
+								},
+							},
+						},
+						"lastError": {
+							Type: "string",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	// BOSHDeploymentTemplateResourceName is the resource name of BOSHDeploymentTemplate
+	BOSHDeploymentTemplateResourceName = fmt.Sprintf("%s.%s", BOSHDeploymentTemplateResourcePlural, apis.GroupName)
+
 	// SchemeGroupVersion is group version used to register these objects
 	SchemeGroupVersion = schema.GroupVersion{Group: apis.GroupName, Version: "v1alpha1"}
 )
@@ -138,6 +660,8 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
 		&BOSHDeployment{},
 		&BOSHDeploymentList{},
+		&BOSHDeploymentTemplate{},
+		&BOSHDeploymentTemplateList{},
 	)
 
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
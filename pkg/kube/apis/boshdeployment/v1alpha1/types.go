@@ -2,7 +2,10 @@ package v1alpha1
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"code.cloudfoundry.org/cf-operator/pkg/kube/apis"
@@ -23,10 +26,16 @@ const (
 	SecretReference ReferenceType = "secret"
 	// URLReference represents URL reference
 	URLReference ReferenceType = "url"
+	// GitReference represents a file in a Git repository, whose URL is the reference's name
+	GitReference ReferenceType = "git"
 
 	ManifestSpecName        string = "manifest"
 	OpsSpecName             string = "ops"
+	CloudConfigSpecName     string = "cloud-config"
 	ImplicitVariableKeyName string = "value"
+
+	// DefaultPriority is the reconcile priority of deployments without a valid priority annotation
+	DefaultPriority = 0
 )
 
 var (
@@ -34,28 +43,462 @@ var (
 	LabelDeploymentName = fmt.Sprintf("%s/deployment-name", apis.GroupName)
 	// LabelDeploymentSecretType is the label key for secret type
 	LabelDeploymentSecretType = fmt.Sprintf("%s/secret-type", apis.GroupName)
+	// LabelDeploymentTemplate is the label key on BOSHDeployments, which contains the name of the
+	// BOSHDeploymentTemplate, that generated them
+	LabelDeploymentTemplate = fmt.Sprintf("%s/deployment-template", apis.GroupName)
 	// AnnotationLinkProvidesKey is the key for the quarks links 'provides' JSON
 	AnnotationLinkProvidesKey = fmt.Sprintf("%s/provides", apis.GroupName)
 	// AnnotationLinkProviderService is the annotation key used on services to identify the link provider
 	AnnotationLinkProviderService = fmt.Sprintf("%s/link-provider-name", apis.GroupName)
+	// AnnotationLinkProviderZones is the annotation key used on link provider services to add the
+	// topology zone of each pod's node to the link instances, if set to "true"
+	AnnotationLinkProviderZones = fmt.Sprintf("%s/link-provider-zones", apis.GroupName)
+	// AnnotationSuspendedInstanceGroups is the annotation key on a BOSHDeployment listing the comma separated
+	// names of instance groups, whose manifests and BPM configs are not regenerated
+	AnnotationSuspendedInstanceGroups = fmt.Sprintf("%s/suspended-instance-groups", apis.GroupName)
+	// AnnotationEnvironment is the annotation key on a BOSHDeployment naming its environment, e.g. 'prod',
+	// which selects the operator's policy profile for the deployment
+	AnnotationEnvironment = fmt.Sprintf("%s/environment", apis.GroupName)
+	// AnnotationDeploymentGeneration is the annotation key on QuarksJobs and their versioned output secrets,
+	// which contains the generation of the BOSHDeployment, whose reconcile produced them
+	AnnotationDeploymentGeneration = fmt.Sprintf("%s/deployment-generation", apis.GroupName)
+	// AnnotationTriggerManifestSHA1 is the annotation key on the pod template of the variable interpolation
+	// QuarksJob, which contains the SHA1 of the normalized manifest in the variable interpolation trigger secret
+	AnnotationTriggerManifestSHA1 = fmt.Sprintf("%s/trigger-manifest-sha1", apis.GroupName)
+	// LabelManifestRetained is the label key on with-ops manifest secrets, which were retained after
+	// their BOSHDeployment was deleted
+	LabelManifestRetained = fmt.Sprintf("%s/manifest-retained", apis.GroupName)
+	// FinalizerManifestRetention is the finalizer on BOSHDeployments, whose with-ops manifest secret is
+	// retained on deletion
+	FinalizerManifestRetention = fmt.Sprintf("%s/manifest-retention", apis.GroupName)
+	// FinalizerLinkDrain is the finalizer on pods of kube native link providers, whose removal waits
+	// until their consumers were drained
+	FinalizerLinkDrain = fmt.Sprintf("%s/link-drain", apis.GroupName)
+	// AnnotationLinkConsumers is the annotation key on pods of kube native link providers listing the
+	// comma separated 'namespace/name' of the BOSHDeployments consuming them
+	AnnotationLinkConsumers = fmt.Sprintf("%s/link-consumers", apis.GroupName)
+	// AnnotationResume is the annotation key on a BOSHDeployment, which resumes the reconciliation of a
+	// deployment halted by its failure policy, if set to "true"
+	AnnotationResume = fmt.Sprintf("%s/resume", apis.GroupName)
+	// AnnotationLogLevel is the annotation key on a BOSHDeployment, which sets the level of the
+	// operator's log messages about the deployment, e.g. 'debug', independent of the operator's log level
+	AnnotationLogLevel = fmt.Sprintf("%s/log-level", apis.GroupName)
+	// AnnotationMinOperatorVersion is the annotation key on a BOSHDeployment naming the minimum version of
+	// the operator, e.g. '4.5.0', which may reconcile the deployment
+	AnnotationMinOperatorVersion = fmt.Sprintf("%s/min-operator-version", apis.GroupName)
+	// AnnotationPriority is the annotation key on a BOSHDeployment setting the priority of its reconciles,
+	// e.g. '10'. Under load, the reconciles of deployments with a higher priority are started first
+	AnnotationPriority = fmt.Sprintf("%s/priority", apis.GroupName)
+	// AnnotationOpsSignature is the annotation key on ops file configmaps and secrets, which contains
+	// the base64 encoded ed25519 signature of the ops file by a trusted signer
+	AnnotationOpsSignature = fmt.Sprintf("%s/ops-signature", apis.GroupName)
+	// LabelTenant is the label key on namespaces naming the tenant, which owns the namespace and whose
+	// quota limits the BOSHDeployments in it
+	LabelTenant = fmt.Sprintf("%s/tenant", apis.GroupName)
 )
 
 // BOSHDeploymentSpec defines the desired state of BOSHDeployment
 type BOSHDeploymentSpec struct {
 	Manifest ResourceReference   `json:"manifest"`
 	Ops      []ResourceReference `json:"ops,omitempty"`
+	// CloudConfig references a BOSH cloud config in its 'cloud-config' key,
+	// whose defaults for vm types, disk types and networks are applied to
+	// the manifest
+	CloudConfig *ResourceReference `json:"cloudConfig,omitempty"`
+	// ManifestDebugMode runs the variable interpolation with verbose logging and
+	// captures its output in the '<deployment>-interpolation-debug' config map
+	ManifestDebugMode bool `json:"manifestDebugMode,omitempty"`
+	// ExternalSecretSelector selects secrets managed outside of the operator,
+	// whose changes trigger a reconcile of the deployment
+	ExternalSecretSelector *metav1.LabelSelector `json:"externalSecretSelector,omitempty"`
+	// ValidateOnStaging dry-runs all changes against the operator's staging
+	// cluster and only applies them, if the dry-run succeeded
+	ValidateOnStaging bool `json:"validateOnStaging,omitempty"`
+	// InstanceGroups overrides settings of the deployment's instance groups
+	InstanceGroups []InstanceGroupOverride `json:"instanceGroups,omitempty"`
+	// CompressManifest stores the with-ops manifest gzip compressed, so
+	// large manifests don't exceed the size limit of secrets
+	CompressManifest bool `json:"compressManifest,omitempty"`
+	// GenerateServiceMonitors creates a Prometheus Operator ServiceMonitor for
+	// each instance group, which exposes a port named 'metrics'
+	GenerateServiceMonitors bool `json:"generateServiceMonitors,omitempty"`
+	// IgnoredInstanceGroups lists instance groups, which are left out of the
+	// reconciliation, e.g. a broken errand during an upgrade
+	IgnoredInstanceGroups []string `json:"ignoredInstanceGroups,omitempty"`
+	// DeploymentStrategy controls how manifest changes are rolled out
+	DeploymentStrategy DeploymentStrategy `json:"deploymentStrategy,omitempty"`
+	// UpdateConfig gates the rollout of each instance group on the
+	// stability of the instance groups before it in the manifest
+	UpdateConfig *UpdateConfig `json:"updateConfig,omitempty"`
+	// GracefulUpgradeTimeout is the time a rollout gated by the update config
+	// may wait for instance groups to become stable, before it is stopped
+	GracefulUpgradeTimeout *metav1.Duration `json:"gracefulUpgradeTimeout,omitempty"`
+	// LinkAddressFormat selects the address format of the link instances of
+	// kube native link providers: 'IP' (default), 'FQDN' or 'ServiceDNS'
+	LinkAddressFormat string `json:"linkAddressFormat,omitempty"`
+	// JobDNS overrides the DNS settings of the pods of the generated
+	// QuarksJobs, e.g. to resolve internal hosts of remote ops files
+	JobDNS *JobDNS `json:"jobDNS,omitempty"`
+	// FailurePolicy controls how failed reconciles are handled: 'Retry'
+	// (default), 'Halt' or 'Ignore'
+	FailurePolicy FailurePolicy `json:"failurePolicy,omitempty"`
+	// PodSecurityContext is the default pod security context of all instance
+	// group pods. The settings are merged field by field, in increasing order
+	// of precedence:
+	//   1. the operator's defaults, i.e. fsGroup 1000 ('adm')
+	//   2. this deployment wide security context
+	//   3. the 'podSecurityContext' of the instance group's override
+	// BPM-derived per-process settings are container security contexts, which
+	// take precedence over all pod-level settings for their container.
+	PodSecurityContext *corev1.PodSecurityContext `json:"podSecurityContext,omitempty"`
+	// PodAntiAffinity injects a pod anti-affinity into the pods of all
+	// instance groups, which spreads them across nodes: 'None' (default),
+	// 'Preferred' or 'Required'. It is merged with the manifest's affinity.
+	PodAntiAffinity PodAntiAffinityPolicy `json:"podAntiAffinity,omitempty"`
+	// Features enables optional resources, which are created alongside the
+	// deployment
+	Features DeploymentFeatures `json:"features,omitempty"`
+	// InitContainers are run before the generated init containers of the
+	// pods of an instance group, keyed by instance group name. They mount
+	// the data and sys directories below /var/vcap, which are shared with
+	// the BOSH job processes.
+	InitContainers map[string][]corev1.Container `json:"initContainers,omitempty"`
+	// ManifestRetentionPolicy controls what happens to the with-ops manifest
+	// secret, when the deployment is deleted: 'Delete' (default) or 'Retain'
+	ManifestRetentionPolicy ManifestRetentionPolicy `json:"manifestRetentionPolicy,omitempty"`
+	// DeploymentTags are added as labels to the generated QuarksStatefulSets,
+	// StatefulSets, QuarksJobs, services and persistent volume claims, e.g.
+	// for cost allocation. Tags listed in the operator's cloud tag annotations
+	// are also added as the cloud provider specific annotations.
+	DeploymentTags map[string]string `json:"deploymentTags,omitempty"`
+	// VariableInterpolationTriggerSecret names a secret in the deployment's
+	// namespace, which the variable interpolation job reads its manifest from
+	// instead of the with-ops manifest secret, e.g. the output of a previous
+	// interpolation pass. Changes of the secret trigger the job again.
+	VariableInterpolationTriggerSecret string `json:"variableInterpolationTriggerSecret,omitempty"`
+}
+
+// DeploymentFeatures enables optional resources of a BOSHDeployment
+type DeploymentFeatures struct {
+	// EnableBPMDebug adds the 'bpm-debug' container to the instance group
+	// pods, which mounts the volumes of the job containers and is allowed to
+	// trace their processes
+	EnableBPMDebug bool `json:"enableBPMDebug,omitempty"`
+	// ObservabilityBundle creates the '<deployment>-grafana-dashboard' config
+	// map, which the Grafana operator discovers by its 'grafana_dashboard' label
+	ObservabilityBundle bool `json:"observabilityBundle,omitempty"`
+	// ReconcileServiceExports creates a Multicluster Services API
+	// ServiceExport for each headless service of the instance groups, if the
+	// ServiceExport CRD is installed
+	ReconcileServiceExports bool `json:"reconcileServiceExports,omitempty"`
+}
+
+// PodAntiAffinityPolicy controls the pod anti-affinity, which is injected
+// into the pods of instance groups
+type PodAntiAffinityPolicy string
+
+// Valid values for pod anti-affinity policies
+const (
+	// PodAntiAffinityNone doesn't inject an anti-affinity
+	PodAntiAffinityNone PodAntiAffinityPolicy = "None"
+	// PodAntiAffinityPreferred prefers nodes without a pod of the same
+	// instance group
+	PodAntiAffinityPreferred PodAntiAffinityPolicy = "Preferred"
+	// PodAntiAffinityRequired doesn't schedule two pods of the same instance
+	// group on a node
+	PodAntiAffinityRequired PodAntiAffinityPolicy = "Required"
+)
+
+// ManifestRetentionPolicy controls, whether the with-ops manifest secret of a
+// BOSHDeployment is kept after the deployment is deleted
+type ManifestRetentionPolicy string
+
+// Valid values for manifest retention policies
+const (
+	// ManifestRetentionDelete garbage collects the manifest secret with the
+	// deployment
+	ManifestRetentionDelete ManifestRetentionPolicy = "Delete"
+	// ManifestRetentionRetain keeps the manifest secret, e.g. for audits. It
+	// loses its owner reference and is labeled as retained.
+	ManifestRetentionRetain ManifestRetentionPolicy = "Retain"
+)
+
+// FailurePolicy controls how failed reconciles of a BOSHDeployment are handled
+type FailurePolicy string
+
+// Valid values for failure policies
+const (
+	// FailurePolicyRetry requeues failed reconciles with backoff
+	FailurePolicyRetry FailurePolicy = "Retry"
+	// FailurePolicyHalt marks the deployment as failed and stops reconciling
+	// it, until it is resumed by the resume annotation
+	FailurePolicyHalt FailurePolicy = "Halt"
+	// FailurePolicyIgnore marks the deployment as degraded and requeues it
+	// with backoff, without reporting the error
+	FailurePolicyIgnore FailurePolicy = "Ignore"
+)
+
+// JobDNS defines the DNS settings of the pods of the generated QuarksJobs
+type JobDNS struct {
+	// DNSPolicy of the pods, 'None' requires a dnsConfig with nameservers
+	DNSPolicy corev1.DNSPolicy `json:"dnsPolicy,omitempty"`
+	// DNSConfig is merged with the DNS settings generated from the DNSPolicy
+	DNSConfig *corev1.PodDNSConfig `json:"dnsConfig,omitempty"`
+}
+
+// TerminationPolicy controls when the QuarksStatefulSets of instance groups,
+// which were removed from or renamed in the manifest, are deleted
+type TerminationPolicy string
+
+// Valid values for termination policies
+const (
+	// TerminationPolicyDeleteAfterReady deletes them once the StatefulSets of
+	// all instance groups of the manifest are ready
+	TerminationPolicyDeleteAfterReady TerminationPolicy = "DeleteAfterReady"
+	// TerminationPolicyDeleteBeforeCreate deletes them before the resources of
+	// an instance group are applied, which minimizes resource consumption
+	TerminationPolicyDeleteBeforeCreate TerminationPolicy = "DeleteBeforeCreate"
+	// TerminationPolicyRetain leaves them for manual cleanup
+	TerminationPolicyRetain TerminationPolicy = "Retain"
+)
+
+// DeploymentStrategy defines how manifest changes are rolled out
+type DeploymentStrategy struct {
+	// TerminationPolicy controls when the QuarksStatefulSets of removed
+	// instance groups are deleted. Defaults to 'Retain'.
+	TerminationPolicy TerminationPolicy `json:"terminationPolicy,omitempty"`
+}
+
+// UpdateConfig controls how long instance groups have to be ready, before
+// the next instance group of the manifest is rolled out, like the BOSH
+// 'canary_watch_time' and 'update_watch_time'
+type UpdateConfig struct {
+	// CanaryWatchTime is the time the first instance group has to be ready
+	CanaryWatchTime *metav1.Duration `json:"canaryWatchTime,omitempty"`
+	// UpdateWatchTime is the time each following instance group has to be ready
+	UpdateWatchTime *metav1.Duration `json:"updateWatchTime,omitempty"`
+}
+
+// InstanceGroupOverride defines settings of a single instance group, which are not part of the BOSH manifest
+type InstanceGroupOverride struct {
+	// Name of the instance group
+	Name string `json:"name"`
+	// PodAnnotations are added to the instance group's pods. They take precedence over
+	// the annotations from the manifest's agent settings.
+	PodAnnotations map[string]string `json:"podAnnotations,omitempty"`
+	// PodSecurityContext of the instance group's pods. Its fields take
+	// precedence over the deployment's podSecurityContext.
+	PodSecurityContext *corev1.PodSecurityContext `json:"podSecurityContext,omitempty"`
+	// PodAntiAffinity of the instance group's pods, which takes precedence
+	// over the deployment's podAntiAffinity
+	PodAntiAffinity PodAntiAffinityPolicy `json:"podAntiAffinity,omitempty"`
+}
+
+// InstanceGroupOverride returns the override for the named instance group
+func (spec *BOSHDeploymentSpec) InstanceGroupOverride(name string) (InstanceGroupOverride, bool) {
+	for _, ig := range spec.InstanceGroups {
+		if ig.Name == name {
+			return ig, true
+		}
+	}
+	return InstanceGroupOverride{}, false
+}
+
+// InstanceGroupPodSecurityContext returns the deployment's pod security
+// context merged with the one of the named instance group's override. It
+// returns nil if neither is set.
+func (spec *BOSHDeploymentSpec) InstanceGroupPodSecurityContext(name string) *corev1.PodSecurityContext {
+	override, _ := spec.InstanceGroupOverride(name)
+	if spec.PodSecurityContext == nil && override.PodSecurityContext == nil {
+		return nil
+	}
+
+	psc := &corev1.PodSecurityContext{}
+	MergePodSecurityContext(psc, spec.PodSecurityContext)
+	MergePodSecurityContext(psc, override.PodSecurityContext)
+	return psc
+}
+
+// InstanceGroupPodAntiAffinity returns the pod anti-affinity policy of the
+// named instance group
+func (spec *BOSHDeploymentSpec) InstanceGroupPodAntiAffinity(name string) PodAntiAffinityPolicy {
+	if override, ok := spec.InstanceGroupOverride(name); ok && override.PodAntiAffinity != "" {
+		return override.PodAntiAffinity
+	}
+	if spec.PodAntiAffinity != "" {
+		return spec.PodAntiAffinity
+	}
+	return PodAntiAffinityNone
+}
+
+// MergePodSecurityContext copies all fields, which are set in override, to psc
+func MergePodSecurityContext(psc *corev1.PodSecurityContext, override *corev1.PodSecurityContext) {
+	if override == nil {
+		return
+	}
+	o := override.DeepCopy()
+	if o.SELinuxOptions != nil {
+		psc.SELinuxOptions = o.SELinuxOptions
+	}
+	if o.WindowsOptions != nil {
+		psc.WindowsOptions = o.WindowsOptions
+	}
+	if o.RunAsUser != nil {
+		psc.RunAsUser = o.RunAsUser
+	}
+	if o.RunAsGroup != nil {
+		psc.RunAsGroup = o.RunAsGroup
+	}
+	if o.RunAsNonRoot != nil {
+		psc.RunAsNonRoot = o.RunAsNonRoot
+	}
+	if o.SupplementalGroups != nil {
+		psc.SupplementalGroups = o.SupplementalGroups
+	}
+	if o.FSGroup != nil {
+		psc.FSGroup = o.FSGroup
+	}
+	if o.Sysctls != nil {
+		psc.Sysctls = o.Sysctls
+	}
 }
 
 // ResourceReference defines the resource reference type and location
 type ResourceReference struct {
 	Name string        `json:"name"`
 	Type ReferenceType `json:"type"`
+	// OAuthTokenSecretRef selects an OAuth2 bearer token (JWT) for url references.
+	// An expired token is refreshed via the 'refresh_token' and 'token_url' keys of the same secret.
+	OAuthTokenSecretRef *corev1.SecretKeySelector `json:"oauthTokenSecretRef,omitempty"`
+	// Git selects the file and revision for git references
+	Git *GitSource `json:"git,omitempty"`
+}
+
+// GitSource selects a file in the repository of a git reference
+type GitSource struct {
+	// Path of the file in the repository
+	Path string `json:"path"`
+	// Ref is the branch or tag, which is fetched. Defaults to the remote's HEAD.
+	Ref string `json:"ref,omitempty"`
+	// Commit pins the reference to a full commit SHA, Ref is ignored then
+	Commit string `json:"commit,omitempty"`
+	// AuthSecretName names a secret in the deployment's namespace with the
+	// repository credentials: 'username' and 'password' for https or
+	// 'ssh-privatekey' and 'known_hosts' for ssh
+	AuthSecretName string `json:"authSecretName,omitempty"`
+}
+
+// Pinned returns true, if the git reference is pinned to a commit
+func (g *GitSource) Pinned() bool {
+	return g.Commit != ""
 }
 
+// Phase is the state of a BOSHDeployment
+type Phase = string
+
+// Valid values for phases
+const (
+	// PhaseApplied means the desired objects have been written
+	PhaseApplied Phase = "Applied"
+	// PhasePendingWindow means the desired objects are staged until the change window opens
+	PhasePendingWindow Phase = "PendingWindow"
+	// PhaseDegraded means the desired objects failed the staging validation,
+	// or the reconcile failed with the 'Ignore' failure policy
+	PhaseDegraded Phase = "Degraded"
+	// PhaseFailed means the reconcile failed with the 'Halt' failure policy
+	PhaseFailed Phase = "Failed"
+	// PhaseWaitingForSecret means the reconcile waits for a referenced secret to be created
+	PhaseWaitingForSecret Phase = "WaitingForSecret"
+	// PhaseGeneratingVariables means the desired objects have been written, but not all
+	// QuarksSecrets of the explicit variables are generated yet
+	PhaseGeneratingVariables Phase = "GeneratingVariables"
+	// PhaseUpdateFailed means the rollout was stopped, because it exceeded the graceful upgrade timeout
+	PhaseUpdateFailed Phase = "UpdateFailed"
+)
+
 // BOSHDeploymentStatus defines the observed state of BOSHDeployment
 type BOSHDeploymentStatus struct {
 	// Timestamp for the last reconcile
 	LastReconcile *metav1.Time `json:"lastReconcile"`
+	// Phase of the deployment
+	Phase Phase `json:"phase,omitempty"`
+	// PendingChanges lists the objects, which will be written once the change window opens
+	PendingChanges []string `json:"pendingChanges,omitempty"`
+	// StagingError is the error of the last failed staging validation
+	StagingError string `json:"stagingError,omitempty"`
+	// LastError is the error of the last failed reconcile, which was halted or ignored by the failure policy
+	LastError string `json:"lastError,omitempty"`
+	// SuspendedInstanceGroups lists the instance groups, which are suspended by annotation
+	SuspendedInstanceGroups []string `json:"suspendedInstanceGroups,omitempty"`
+	// UpdateStartedAt is the time, when the rollout of the current desired
+	// manifest started
+	UpdateStartedAt *metav1.Time `json:"updateStartedAt,omitempty"`
+	// UpdateWaitUntil is the time, until which the rollout of the next
+	// instance group waits for the update watch time
+	UpdateWaitUntil *metav1.Time `json:"updateWaitUntil,omitempty"`
+	// WaitingForSecret is the name of the missing secret, the reconcile waits for
+	WaitingForSecret string `json:"waitingForSecret,omitempty"`
+	// CorrelationID identifies the reconcile pass, which updated the status last
+	CorrelationID string `json:"correlationID,omitempty"`
+	// VariableGenerationTotal is the number of QuarksSecrets of the explicit variables
+	VariableGenerationTotal int `json:"variableGenerationTotal,omitempty"`
+	// VariableGenerationComplete is the number of those QuarksSecrets, which are generated
+	VariableGenerationComplete int `json:"variableGenerationComplete,omitempty"`
+	// RestoredState is the reconcile state imported from an export of another cluster
+	RestoredState *RestoredState `json:"restoredState,omitempty"`
+	// Conditions reflect the phases of the last reconcile
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
+// Condition types of a BOSHDeployment, one for each phase of the reconcile
+const (
+	// ConditionManifestResolved is true, if the manifest with ops files,
+	// cloud config and links was resolved
+	ConditionManifestResolved = "ManifestResolved"
+	// ConditionVariablesGenerated is true, if the QuarksSecrets of all
+	// explicit variables are generated
+	ConditionVariablesGenerated = "VariablesGenerated"
+	// ConditionVariableInterpolationJobReady is true, if the variable
+	// interpolation QuarksJob was created or updated
+	ConditionVariableInterpolationJobReady = "VariableInterpolationJobReady"
+	// ConditionInstanceGroupManifestJobReady is true, if the instance group
+	// manifest QuarksJob was created or updated
+	ConditionInstanceGroupManifestJobReady = "InstanceGroupManifestJobReady"
+)
+
+// Condition is the state of a phase of the reconcile. It has the fields of
+// the metav1.Condition of newer Kubernetes versions, so tools like
+// 'kubectl wait --for=condition=VariablesGenerated' work with it.
+type Condition struct {
+	// Type of the condition, e.g. 'ManifestResolved'
+	Type string `json:"type"`
+	// Status of the condition: 'True', 'False' or 'Unknown'
+	Status metav1.ConditionStatus `json:"status"`
+	// ObservedGeneration is the generation of the BOSHDeployment, which the
+	// condition was set for
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// LastTransitionTime is the time, when the status changed last
+	LastTransitionTime metav1.Time `json:"lastTransitionTime"`
+	// Reason is the reason of the event, which set the condition
+	Reason string `json:"reason"`
+	// Message is the message of that event
+	Message string `json:"message"`
+}
+
+// RestoredState is the reconcile state of a BOSHDeployment on the cluster,
+// from which it was exported for disaster recovery
+type RestoredState struct {
+	// ExportedAt is the time of the export
+	ExportedAt metav1.Time `json:"exportedAt"`
+	// ObservedGeneration is the generation of the BOSHDeployment, which
+	// produced the last desired manifest
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// InputHash is the SHA256 hash of the with-ops manifest
+	InputHash string `json:"inputHash,omitempty"`
+	// DesiredManifestVersion is the version of the last desired manifest
+	DesiredManifestVersion int `json:"desiredManifestVersion,omitempty"`
+	// Phase is the phase of the BOSHDeployment
+	Phase Phase `json:"phase,omitempty"`
 }
 
 // +genclient
@@ -71,6 +514,48 @@ type BOSHDeployment struct {
 	Status BOSHDeploymentStatus `json:"status,omitempty"`
 }
 
+// SuspendedInstanceGroups returns the instance groups listed in the suspend annotation
+func (bdpl *BOSHDeployment) SuspendedInstanceGroups() []string {
+	suspended := []string{}
+	for _, name := range strings.Split(bdpl.GetAnnotations()[AnnotationSuspendedInstanceGroups], ",") {
+		name = strings.TrimSpace(name)
+		if name != "" {
+			suspended = append(suspended, name)
+		}
+	}
+	return suspended
+}
+
+// ResumeRequested returns true, if the resume annotation is set to "true"
+func (bdpl *BOSHDeployment) ResumeRequested() bool {
+	return bdpl.GetAnnotations()[AnnotationResume] == "true"
+}
+
+// Environment returns the environment named in the environment annotation
+func (bdpl *BOSHDeployment) Environment() string {
+	return strings.TrimSpace(bdpl.GetAnnotations()[AnnotationEnvironment])
+}
+
+// LogLevel returns the log level named in the log level annotation
+func (bdpl *BOSHDeployment) LogLevel() string {
+	return strings.TrimSpace(bdpl.GetAnnotations()[AnnotationLogLevel])
+}
+
+// MinOperatorVersion returns the version named in the min operator version annotation
+func (bdpl *BOSHDeployment) MinOperatorVersion() string {
+	return strings.TrimSpace(bdpl.GetAnnotations()[AnnotationMinOperatorVersion])
+}
+
+// Priority returns the priority set in the priority annotation, or
+// DefaultPriority, if it is unset or not an integer
+func (bdpl *BOSHDeployment) Priority() int {
+	priority, err := strconv.Atoi(strings.TrimSpace(bdpl.GetAnnotations()[AnnotationPriority]))
+	if err != nil {
+		return DefaultPriority
+	}
+	return priority
+}
+
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 // BOSHDeploymentList contains a list of BOSHDeployment
@@ -79,3 +564,50 @@ type BOSHDeploymentList struct {
 	metav1.ListMeta `json:"metadata,omitempty"`
 	Items           []BOSHDeployment `json:"items"`
 }
+
+// TemplateInstance is a BOSHDeployment generated from a BOSHDeploymentTemplate
+type TemplateInstance struct {
+	// Name of the generated BOSHDeployment
+	Name string `json:"name"`
+	// Values replace the '$(key)' placeholders of the template
+	Values map[string]string `json:"values,omitempty"`
+}
+
+// BOSHDeploymentTemplateSpec defines the desired state of BOSHDeploymentTemplate
+type BOSHDeploymentTemplateSpec struct {
+	// Template is the spec of the generated BOSHDeployments. '$(key)'
+	// placeholders in its strings are replaced with the values of each instance
+	Template BOSHDeploymentSpec `json:"template"`
+	// Instances lists the BOSHDeployments to generate
+	Instances []TemplateInstance `json:"instances,omitempty"`
+}
+
+// BOSHDeploymentTemplateStatus defines the observed state of BOSHDeploymentTemplate
+type BOSHDeploymentTemplateStatus struct {
+	// Deployments lists the generated BOSHDeployments
+	Deployments []string `json:"deployments,omitempty"`
+	// LastError is the error of the last failed reconcile
+	LastError string `json:"lastError,omitempty"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// BOSHDeploymentTemplate is the Schema for the boshdeploymenttemplates API
+// +k8s:openapi-gen=true
+type BOSHDeploymentTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   BOSHDeploymentTemplateSpec   `json:"spec,omitempty"`
+	Status BOSHDeploymentTemplateStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// BOSHDeploymentTemplateList contains a list of BOSHDeploymentTemplate
+type BOSHDeploymentTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []BOSHDeploymentTemplate `json:"items"`
+}
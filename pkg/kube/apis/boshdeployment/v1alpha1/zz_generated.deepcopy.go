@@ -10,6 +10,8 @@ Don't alter this file, it was generated.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -77,12 +79,80 @@ func (in *BOSHDeploymentList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BOSHDeploymentSpec) DeepCopyInto(out *BOSHDeploymentSpec) {
 	*out = *in
-	out.Manifest = in.Manifest
+	in.Manifest.DeepCopyInto(&out.Manifest)
 	if in.Ops != nil {
 		in, out := &in.Ops, &out.Ops
 		*out = make([]ResourceReference, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.CloudConfig != nil {
+		in, out := &in.CloudConfig, &out.CloudConfig
+		*out = new(ResourceReference)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ExternalSecretSelector != nil {
+		in, out := &in.ExternalSecretSelector, &out.ExternalSecretSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.InstanceGroups != nil {
+		in, out := &in.InstanceGroups, &out.InstanceGroups
+		*out = make([]InstanceGroupOverride, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.IgnoredInstanceGroups != nil {
+		in, out := &in.IgnoredInstanceGroups, &out.IgnoredInstanceGroups
+		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.UpdateConfig != nil {
+		in, out := &in.UpdateConfig, &out.UpdateConfig
+		*out = new(UpdateConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.GracefulUpgradeTimeout != nil {
+		in, out := &in.GracefulUpgradeTimeout, &out.GracefulUpgradeTimeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.JobDNS != nil {
+		in, out := &in.JobDNS, &out.JobDNS
+		*out = new(JobDNS)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PodSecurityContext != nil {
+		in, out := &in.PodSecurityContext, &out.PodSecurityContext
+		*out = new(corev1.PodSecurityContext)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.InitContainers != nil {
+		in, out := &in.InitContainers, &out.InitContainers
+		*out = make(map[string][]corev1.Container, len(*in))
+		for key, val := range *in {
+			var outVal []corev1.Container
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				in, out := &val, &outVal
+				*out = make([]corev1.Container, len(*in))
+				for i := range *in {
+					(*in)[i].DeepCopyInto(&(*out)[i])
+				}
+			}
+			(*out)[key] = outVal
+		}
+	}
+	if in.DeploymentTags != nil {
+		in, out := &in.DeploymentTags, &out.DeploymentTags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	return
 }
 
@@ -103,6 +173,36 @@ func (in *BOSHDeploymentStatus) DeepCopyInto(out *BOSHDeploymentStatus) {
 		in, out := &in.LastReconcile, &out.LastReconcile
 		*out = (*in).DeepCopy()
 	}
+	if in.PendingChanges != nil {
+		in, out := &in.PendingChanges, &out.PendingChanges
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SuspendedInstanceGroups != nil {
+		in, out := &in.SuspendedInstanceGroups, &out.SuspendedInstanceGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.UpdateStartedAt != nil {
+		in, out := &in.UpdateStartedAt, &out.UpdateStartedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.UpdateWaitUntil != nil {
+		in, out := &in.UpdateWaitUntil, &out.UpdateWaitUntil
+		*out = (*in).DeepCopy()
+	}
+	if in.RestoredState != nil {
+		in, out := &in.RestoredState, &out.RestoredState
+		*out = new(RestoredState)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -116,9 +216,239 @@ func (in *BOSHDeploymentStatus) DeepCopy() *BOSHDeploymentStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BOSHDeploymentTemplate) DeepCopyInto(out *BOSHDeploymentTemplate) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BOSHDeploymentTemplate.
+func (in *BOSHDeploymentTemplate) DeepCopy() *BOSHDeploymentTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(BOSHDeploymentTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BOSHDeploymentTemplate) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BOSHDeploymentTemplateList) DeepCopyInto(out *BOSHDeploymentTemplateList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]BOSHDeploymentTemplate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BOSHDeploymentTemplateList.
+func (in *BOSHDeploymentTemplateList) DeepCopy() *BOSHDeploymentTemplateList {
+	if in == nil {
+		return nil
+	}
+	out := new(BOSHDeploymentTemplateList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BOSHDeploymentTemplateList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BOSHDeploymentTemplateSpec) DeepCopyInto(out *BOSHDeploymentTemplateSpec) {
+	*out = *in
+	in.Template.DeepCopyInto(&out.Template)
+	if in.Instances != nil {
+		in, out := &in.Instances, &out.Instances
+		*out = make([]TemplateInstance, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BOSHDeploymentTemplateSpec.
+func (in *BOSHDeploymentTemplateSpec) DeepCopy() *BOSHDeploymentTemplateSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BOSHDeploymentTemplateSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BOSHDeploymentTemplateStatus) DeepCopyInto(out *BOSHDeploymentTemplateStatus) {
+	*out = *in
+	if in.Deployments != nil {
+		in, out := &in.Deployments, &out.Deployments
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BOSHDeploymentTemplateStatus.
+func (in *BOSHDeploymentTemplateStatus) DeepCopy() *BOSHDeploymentTemplateStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BOSHDeploymentTemplateStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Condition) DeepCopyInto(out *Condition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Condition.
+func (in *Condition) DeepCopy() *Condition {
+	if in == nil {
+		return nil
+	}
+	out := new(Condition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeploymentFeatures) DeepCopyInto(out *DeploymentFeatures) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeploymentFeatures.
+func (in *DeploymentFeatures) DeepCopy() *DeploymentFeatures {
+	if in == nil {
+		return nil
+	}
+	out := new(DeploymentFeatures)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeploymentStrategy) DeepCopyInto(out *DeploymentStrategy) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeploymentStrategy.
+func (in *DeploymentStrategy) DeepCopy() *DeploymentStrategy {
+	if in == nil {
+		return nil
+	}
+	out := new(DeploymentStrategy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitSource) DeepCopyInto(out *GitSource) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitSource.
+func (in *GitSource) DeepCopy() *GitSource {
+	if in == nil {
+		return nil
+	}
+	out := new(GitSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceGroupOverride) DeepCopyInto(out *InstanceGroupOverride) {
+	*out = *in
+	if in.PodAnnotations != nil {
+		in, out := &in.PodAnnotations, &out.PodAnnotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.PodSecurityContext != nil {
+		in, out := &in.PodSecurityContext, &out.PodSecurityContext
+		*out = new(corev1.PodSecurityContext)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceGroupOverride.
+func (in *InstanceGroupOverride) DeepCopy() *InstanceGroupOverride {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceGroupOverride)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JobDNS) DeepCopyInto(out *JobDNS) {
+	*out = *in
+	if in.DNSConfig != nil {
+		in, out := &in.DNSConfig, &out.DNSConfig
+		*out = new(corev1.PodDNSConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JobDNS.
+func (in *JobDNS) DeepCopy() *JobDNS {
+	if in == nil {
+		return nil
+	}
+	out := new(JobDNS)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ResourceReference) DeepCopyInto(out *ResourceReference) {
 	*out = *in
+	if in.OAuthTokenSecretRef != nil {
+		in, out := &in.OAuthTokenSecretRef, &out.OAuthTokenSecretRef
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Git != nil {
+		in, out := &in.Git, &out.Git
+		*out = new(GitSource)
+		**out = **in
+	}
 	return
 }
 
@@ -131,3 +461,69 @@ func (in *ResourceReference) DeepCopy() *ResourceReference {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RestoredState) DeepCopyInto(out *RestoredState) {
+	*out = *in
+	in.ExportedAt.DeepCopyInto(&out.ExportedAt)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RestoredState.
+func (in *RestoredState) DeepCopy() *RestoredState {
+	if in == nil {
+		return nil
+	}
+	out := new(RestoredState)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TemplateInstance) DeepCopyInto(out *TemplateInstance) {
+	*out = *in
+	if in.Values != nil {
+		in, out := &in.Values, &out.Values
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TemplateInstance.
+func (in *TemplateInstance) DeepCopy() *TemplateInstance {
+	if in == nil {
+		return nil
+	}
+	out := new(TemplateInstance)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpdateConfig) DeepCopyInto(out *UpdateConfig) {
+	*out = *in
+	if in.CanaryWatchTime != nil {
+		in, out := &in.CanaryWatchTime, &out.CanaryWatchTime
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.UpdateWatchTime != nil {
+		in, out := &in.UpdateWatchTime, &out.UpdateWatchTime
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpdateConfig.
+func (in *UpdateConfig) DeepCopy() *UpdateConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(UpdateConfig)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -40,6 +40,15 @@ var (
 				"spec": {
 					Type: "object",
 					Properties: map[string]extv1.JSONSchemaProps{
+						"regenerateOnChange": {
+							Type:        "array",
+							Description: "Names of QuarksSecrets, whose regeneration also regenerates this QuarksSecret",
+							Items: &extv1.JSONSchemaPropsOrArray{
+								Schema: &extv1.JSONSchemaProps{
+									Type: "string",
+								},
+							},
+						},
 						"secretName": {
 							Type:        "string",
 							MinLength:   pointers.Int64(1),
@@ -48,7 +57,7 @@ var (
 						"type": {
 							Type:        "string",
 							MinLength:   pointers.Int64(1),
-							Description: "What kind of secret to generate: password, certificate, ssh, rsa",
+							Description: "What kind of secret to generate: password, certificate, ssh, rsa, credhub",
 						},
 						"request": {
 							Type:                   "object",
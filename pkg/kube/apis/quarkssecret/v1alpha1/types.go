@@ -22,6 +22,8 @@ const (
 	Certificate SecretType = "certificate"
 	SSHKey      SecretType = "ssh"
 	RSAKey      SecretType = "rsa"
+	// CredHub secrets are not generated, but synced from a CredHub server
+	CredHub SecretType = "credhub"
 )
 
 // SignerType defines the type of the certificate signer
@@ -42,6 +44,8 @@ var (
 	AnnotationCertSecretName = fmt.Sprintf("%s/cert-secret-name", apis.GroupName)
 	// AnnotationQSecNamespace is the annotation key for quarks secret namespace
 	AnnotationQSecNamespace = fmt.Sprintf("%s/quarks-secret-namespace", apis.GroupName)
+	// AnnotationCredHubPath is the annotation key for the CredHub path of a 'credhub' quarks secret
+	AnnotationCredHubPath = fmt.Sprintf("%s/credhub-path", apis.GroupName)
 	// LabelSecretRotationTrigger is set on a config map to trigger secret
 	// rotation. If set, then creating the config map will trigger secret
 	// rotation.
@@ -49,6 +53,10 @@ var (
 	// RotateQSecretListName is the name of the config map entry, which
 	// contains a JSON array of quarks secret names to rotate
 	RotateQSecretListName = "secrets"
+	// RotateDeploymentCertificatesListName is the name of the config map
+	// entry, which contains a JSON array of BOSHDeployment names, whose
+	// certificate variables are rotated
+	RotateDeploymentCertificatesListName = "deployment-certificates"
 )
 
 const (
@@ -90,6 +98,9 @@ type QuarksSecretSpec struct {
 	Type       SecretType `json:"type"`
 	Request    Request    `json:"request"`
 	SecretName string     `json:"secretName"`
+	// RegenerateOnChange lists the names of QuarksSecrets in the same
+	// namespace, whose regeneration also regenerates this QuarksSecret
+	RegenerateOnChange []string `json:"regenerateOnChange,omitempty"`
 }
 
 // QuarksSecretStatus defines the observed state of QuarksSecret
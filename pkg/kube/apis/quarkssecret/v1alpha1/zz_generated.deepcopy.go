@@ -112,6 +112,11 @@ func (in *QuarksSecretList) DeepCopyObject() runtime.Object {
 func (in *QuarksSecretSpec) DeepCopyInto(out *QuarksSecretSpec) {
 	*out = *in
 	in.Request.DeepCopyInto(&out.Request)
+	if in.RegenerateOnChange != nil {
+		in, out := &in.RegenerateOnChange, &out.RegenerateOnChange
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
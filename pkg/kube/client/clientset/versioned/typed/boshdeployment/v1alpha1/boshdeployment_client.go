@@ -16,6 +16,7 @@ import (
 type BoshdeploymentV1alpha1Interface interface {
 	RESTClient() rest.Interface
 	BOSHDeploymentsGetter
+	BOSHDeploymentTemplatesGetter
 }
 
 // BoshdeploymentV1alpha1Client is used to interact with features provided by the boshdeployment group.
@@ -27,6 +28,10 @@ func (c *BoshdeploymentV1alpha1Client) BOSHDeployments(namespace string) BOSHDep
 	return newBOSHDeployments(c, namespace)
 }
 
+func (c *BoshdeploymentV1alpha1Client) BOSHDeploymentTemplates(namespace string) BOSHDeploymentTemplateInterface {
+	return newBOSHDeploymentTemplates(c, namespace)
+}
+
 // NewForConfig creates a new BoshdeploymentV1alpha1Client for the given config.
 func NewForConfig(c *rest.Config) (*BoshdeploymentV1alpha1Client, error) {
 	config := *c
@@ -21,6 +21,10 @@ func (c *FakeBoshdeploymentV1alpha1) BOSHDeployments(namespace string) v1alpha1.
 	return &FakeBOSHDeployments{c, namespace}
 }
 
+func (c *FakeBoshdeploymentV1alpha1) BOSHDeploymentTemplates(namespace string) v1alpha1.BOSHDeploymentTemplateInterface {
+	return &FakeBOSHDeploymentTemplates{c, namespace}
+}
+
 // RESTClient returns a RESTClient that is used to communicate
 // with API server by this client implementation.
 func (c *FakeBoshdeploymentV1alpha1) RESTClient() rest.Interface {
@@ -8,3 +8,5 @@ Don't alter this file, it was generated.
 package v1alpha1
 
 type BOSHDeploymentExpansion interface{}
+
+type BOSHDeploymentTemplateExpansion interface{}
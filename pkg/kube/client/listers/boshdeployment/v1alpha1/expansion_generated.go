@@ -14,3 +14,11 @@ type BOSHDeploymentListerExpansion interface{}
 // BOSHDeploymentNamespaceListerExpansion allows custom methods to be added to
 // BOSHDeploymentNamespaceLister.
 type BOSHDeploymentNamespaceListerExpansion interface{}
+
+// BOSHDeploymentTemplateListerExpansion allows custom methods to be added to
+// BOSHDeploymentTemplateLister.
+type BOSHDeploymentTemplateListerExpansion interface{}
+
+// BOSHDeploymentTemplateNamespaceListerExpansion allows custom methods to be added to
+// BOSHDeploymentTemplateNamespaceLister.
+type BOSHDeploymentTemplateNamespaceListerExpansion interface{}
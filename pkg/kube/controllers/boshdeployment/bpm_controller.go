@@ -19,8 +19,11 @@ import (
 	"code.cloudfoundry.org/cf-operator/pkg/bosh/bpmconverter"
 	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
 	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/backpressure"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/util/boshdns"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/util/desiredmanifest"
+	bdnames "code.cloudfoundry.org/cf-operator/pkg/kube/util/names"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/watchdog"
 	"code.cloudfoundry.org/quarks-utils/pkg/config"
 	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
 	"code.cloudfoundry.org/quarks-utils/pkg/meltdown"
@@ -36,22 +39,23 @@ func AddBPM(ctx context.Context, config *config.Config, mgr manager.Manager) err
 	ctx = ctxlog.NewContextWithRecorder(ctx, "bpm-reconciler", mgr.GetEventRecorderFor("bpm-recorder"))
 	r := NewBPMReconciler(
 		ctx, config, mgr,
-		desiredmanifest.NewDesiredManifest(mgr.GetClient()),
+		desiredmanifest.NewDesiredManifest(mgr.GetClient(), secretNamer),
 		controllerutil.SetControllerReference,
 		bpmconverter.NewConverter(
 			config.Namespace,
-			bpmconverter.NewVolumeFactory(),
+			bpmconverter.NewVolumeFactory(secretNamer),
 			func(deploymentName string, instanceGroupName string, version string, disableLogSidecar bool, releaseImageProvider bdm.ReleaseImageProvider, bpmConfigs bpm.Configs) bpmconverter.ContainerFactory {
-				return bpmconverter.NewContainerFactory(deploymentName, instanceGroupName, version, disableLogSidecar, releaseImageProvider, bpmConfigs)
+				return bpmconverter.NewContainerFactory(deploymentName, instanceGroupName, version, disableLogSidecar, releaseImageProvider, bpmConfigs, secretNamer)
 			}),
 		func(deploymentName string, m bdm.Manifest) (boshdns.DomainNameService, error) {
 			return boshdns.NewDNS(deploymentName, m)
 		},
+		secretNamer,
 	)
 
 	// Create a new controller
 	c, err := controller.New("bpm-controller", mgr, controller.Options{
-		Reconciler:              r,
+		Reconciler:              watchdog.NewReconciler(ctx, backpressure.NewReconciler(ctx, r), &corev1.Secret{}),
 		MaxConcurrentReconciles: config.MaxBoshDeploymentWorkers,
 	})
 	if err != nil {
@@ -62,7 +66,7 @@ func AddBPM(ctx context.Context, config *config.Config, mgr manager.Manager) err
 	p := predicate.Funcs{
 		CreateFunc: func(e event.CreateEvent) bool {
 			o := e.Object.(*corev1.Secret)
-			shouldProcessEvent := isBPMInfoSecret(o)
+			shouldProcessEvent := isBPMInfoSecret(secretNamer, o)
 
 			if shouldProcessEvent {
 				if metav1.HasAnnotation(o.ObjectMeta, meltdown.AnnotationLastReconcile) {
@@ -94,20 +98,25 @@ func AddBPM(ctx context.Context, config *config.Config, mgr manager.Manager) err
 	return nil
 }
 
-func isBPMInfoSecret(secret *corev1.Secret) bool {
-	ok := vss.IsVersionedSecret(*secret)
-	if !ok {
+func isBPMInfoSecret(secretNamer bdnames.SecretNamer, secret *corev1.Secret) bool {
+	if !isVersionedSecret(secretNamer, secret) {
 		return false
 	}
 
-	secretLabels := secret.GetLabels()
-	deploymentSecretType, ok := secretLabels[bdv1.LabelDeploymentSecretType]
-	if !ok {
+	_, secretType, _, err := secretNamer.ParseDeploymentSecretName(secret.Name)
+	if err != nil {
 		return false
 	}
-	if deploymentSecretType != names.DeploymentSecretBpmInformation.String() {
+
+	return secretType == names.DeploymentSecretBpmInformation
+}
+
+// isVersionedSecret returns true if the secret is a versioned secret and its name carries a version
+func isVersionedSecret(secretNamer bdnames.SecretNamer, secret *corev1.Secret) bool {
+	if !vss.IsVersionedSecret(*secret) {
 		return false
 	}
 
-	return true
+	_, _, version, err := secretNamer.ParseDeploymentSecretName(secret.Name)
+	return err == nil && version > 0
 }
@@ -0,0 +1,113 @@
+package boshdeployment
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	qjv1a1 "code.cloudfoundry.org/quarks-job/pkg/kube/apis/quarksjob/v1alpha1"
+	"code.cloudfoundry.org/quarks-utils/pkg/names"
+)
+
+// bpmOnlyChange returns true, if only inputs of the instance group manifest
+// job changed, e.g. the link providers or the ignored instance groups, while
+// all inputs of the variable interpolation, i.e. the with-ops manifest and
+// the spec of the finished variable interpolation job, are unchanged. The
+// spec contains the SHA1 of the trigger manifest, if a trigger secret is set.
+// Then the existing desired manifest secret is still valid and only the
+// instance group manifests and BPM configs have to be rendered again. If the
+// classification is uncertain, e.g. a job is still running, it returns false,
+// so the full pipeline runs.
+func (r *ReconcileBOSHDeployment) bpmOnlyChange(ctx context.Context, instance *bdv1.BOSHDeployment, manifestSecret *corev1.Secret, dmQJob *qjv1a1.QuarksJob, igQJob *qjv1a1.QuarksJob) (bool, error) {
+	existing := &corev1.Secret{}
+	err := r.client.Get(ctx, crc.ObjectKey{Name: manifestSecret.Name, Namespace: manifestSecret.Namespace}, existing)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, errors.Wrapf(err, "getting Secret '%s'", manifestSecret.Name)
+	}
+	changed, err := r.manifestWithOpsChanged(ctx, manifestSecret)
+	if err != nil || changed {
+		return false, err
+	}
+
+	dm, err := r.finishedQuarksJob(ctx, dmQJob)
+	if err != nil || dm == nil || !quarksJobSpecEqual(dm, dmQJob) {
+		return false, err
+	}
+
+	ig, err := r.finishedQuarksJob(ctx, igQJob)
+	if err != nil || ig == nil || quarksJobSpecEqual(ig, igQJob) {
+		return false, err
+	}
+
+	return r.desiredManifestExists(ctx, instance)
+}
+
+// finishedQuarksJob returns the existing QuarksJob, if it isn't deleted,
+// triggered or running, otherwise nil
+func (r *ReconcileBOSHDeployment) finishedQuarksJob(ctx context.Context, qJob *qjv1a1.QuarksJob) (*qjv1a1.QuarksJob, error) {
+	existing := &qjv1a1.QuarksJob{}
+	err := r.client.Get(ctx, crc.ObjectKey{Name: qJob.Name, Namespace: qJob.Namespace}, existing)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "getting QuarksJob '%s'", qJob.Name)
+	}
+
+	if existing.DeletionTimestamp != nil || existing.Spec.Trigger.Strategy != qjv1a1.TriggerDone {
+		return nil, nil
+	}
+
+	running, err := r.isQuarksJobRunning(ctx, existing)
+	if err != nil || running {
+		return nil, err
+	}
+
+	return existing, nil
+}
+
+// quarksJobSpecEqual compares the parts of the QuarksJob specs, which are
+// updated by the reconciler
+func quarksJobSpecEqual(existing *qjv1a1.QuarksJob, desired *qjv1a1.QuarksJob) bool {
+	return equality.Semantic.DeepEqual(existing.Spec.Template, desired.Spec.Template) &&
+		equality.Semantic.DeepEqual(existing.Spec.Output, desired.Spec.Output) &&
+		existing.Spec.UpdateOnConfigChange == desired.Spec.UpdateOnConfigChange
+}
+
+// desiredManifestExists returns true, if the variable interpolation created
+// a desired manifest secret for the deployment
+func (r *ReconcileBOSHDeployment) desiredManifestExists(ctx context.Context, instance *bdv1.BOSHDeployment) (bool, error) {
+	secrets := &corev1.SecretList{}
+	err := r.client.List(ctx, secrets,
+		crc.InNamespace(instance.Namespace),
+		crc.MatchingLabels{
+			bdv1.LabelDeploymentName:       instance.Name,
+			bdv1.LabelDeploymentSecretType: names.DeploymentSecretTypeDesiredManifest.String(),
+		},
+	)
+	if err != nil {
+		return false, errors.Wrapf(err, "listing desired manifest secrets of BOSHDeployment '%s/%s'", instance.Namespace, instance.Name)
+	}
+
+	return len(secrets.Items) > 0, nil
+}
+
+// triggerQuarksJob runs the QuarksJob again, which renders the instance
+// group manifests and BPM configs from the existing desired manifest
+func (r *ReconcileBOSHDeployment) triggerQuarksJob(ctx context.Context, qJob *qjv1a1.QuarksJob) error {
+	qJob.Spec.Trigger.Strategy = qjv1a1.TriggerNow
+	err := r.client.Update(ctx, qJob)
+	if err != nil {
+		return errors.Wrapf(err, "triggering QuarksJob '%s'", qJob.Name)
+	}
+	return nil
+}
@@ -2,13 +2,17 @@ package boshdeployment
 
 import (
 	"context"
+	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -23,8 +27,11 @@ import (
 	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
 	qstsv1a1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/quarksstatefulset/v1alpha1"
 	qstscontroller "code.cloudfoundry.org/cf-operator/pkg/kube/controllers/quarksstatefulset"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/audit"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/util/boshdns"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/correlation"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/util/mutate"
+	bdnames "code.cloudfoundry.org/cf-operator/pkg/kube/util/names"
 	qjv1a1 "code.cloudfoundry.org/quarks-job/pkg/kube/apis/quarksjob/v1alpha1"
 	"code.cloudfoundry.org/quarks-utils/pkg/config"
 	log "code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
@@ -36,6 +43,9 @@ import (
 // BPMConverter converts k8s resources from single BOSH manifest
 type BPMConverter interface {
 	Resources(manifestName string, dns bpmconverter.DomainNameService, qStsVersion string, instanceGroup *bdm.InstanceGroup, releaseImageProvider bdm.ReleaseImageProvider, bpmConfigs bpm.Configs, igResolvedSecretVersion string) (*bpmconverter.Resources, error)
+	GenerateServiceMonitor(igName, namespace string, metricsPort int, labels map[string]string) *unstructured.Unstructured
+	GenerateServiceExport(service corev1.Service) *unstructured.Unstructured
+	GenerateServicePerIG(ig bdm.InstanceGroup, namespace string, bpmPorts []int32) *corev1.Service
 }
 
 // DesiredManifest unmarshals desired manifest from the manifest secret
@@ -46,17 +56,18 @@ type DesiredManifest interface {
 var _ reconcile.Reconciler = &ReconcileBOSHDeployment{}
 
 // NewBPMReconciler returns a new reconcile.Reconciler
-func NewBPMReconciler(ctx context.Context, config *config.Config, mgr manager.Manager, resolver DesiredManifest, srf setReferenceFunc, converter BPMConverter, dns boshdns.NewDNSFunc) reconcile.Reconciler {
+func NewBPMReconciler(ctx context.Context, config *config.Config, mgr manager.Manager, resolver DesiredManifest, srf setReferenceFunc, converter BPMConverter, dns boshdns.NewDNSFunc, secretNamer bdnames.SecretNamer) reconcile.Reconciler {
 	return &ReconcileBPM{
 		ctx:                  ctx,
 		config:               config,
-		client:               mgr.GetClient(),
+		client:               audit.NewClient(mgr.GetClient()),
 		scheme:               mgr.GetScheme(),
 		resolver:             resolver,
 		setReference:         srf,
 		converter:            converter,
 		versionedSecretStore: versionedsecretstore.NewVersionedSecretStore(mgr.GetClient()),
 		newDNSFunc:           dns,
+		secretNamer:          secretNamer,
 	}
 }
 
@@ -71,6 +82,7 @@ type ReconcileBPM struct {
 	converter            BPMConverter
 	versionedSecretStore versionedsecretstore.VersionedSecretStore
 	newDNSFunc           boshdns.NewDNSFunc
+	secretNamer          bdnames.SecretNamer
 }
 
 // Reconcile reconciles an Instance Group BPM versioned secret read the corresponding
@@ -79,6 +91,7 @@ func (r *ReconcileBPM) Reconcile(request reconcile.Request) (reconcile.Result, e
 	// Set the ctx to be Background, as the top-level context for incoming requests.
 	ctx, cancel := context.WithTimeout(r.ctx, r.config.CtxTimeOut)
 	defer cancel()
+	ctx = correlation.NewContext(ctx)
 
 	log.Infof(ctx, "Reconciling Instance Group BPM versioned secret '%s'", request.NamespacedName)
 	bpmSecret := &corev1.Secret{}
@@ -135,12 +148,60 @@ func (r *ReconcileBPM) Reconcile(request reconcile.Request) (reconcile.Result, e
 			log.WithEvent(bpmSecret, "LabelMissingError").Errorf(ctx, "Missing deployment mame label for bpm information bpmSecret '%s'", request.NamespacedName)
 	}
 
+	ctx = audit.NewContext(ctx, types.NamespacedName{Namespace: request.Namespace, Name: instanceName})
+
 	bdpl := &bdv1.BOSHDeployment{}
 	err = r.client.Get(ctx, types.NamespacedName{Namespace: request.Namespace, Name: instanceName}, bdpl)
 	if err != nil {
 		return reconcile.Result{},
 			log.WithEvent(bpmSecret, "GetBOSHDeployment").Errorf(ctx, "Failed to get BoshDeployment instance '%s': %v", instanceName, err)
 	}
+	ctx = withLogLevel(ctx, bdpl)
+
+	if containsInstanceGroup(bdpl.SuspendedInstanceGroups(), instanceGroupName) {
+		log.WithEvent(bpmSecret, "SkipReconcile").Infof(ctx, "Skip reconcile: instance group '%s' of BOSHDeployment '%s' is suspended", instanceGroupName, bdpl.Name)
+		return reconcile.Result{}, nil
+	}
+
+	if containsInstanceGroup(bdpl.Spec.IgnoredInstanceGroups, instanceGroupName) {
+		log.WithEvent(bdpl, "InstanceGroupIgnored").Infof(ctx, "Skip reconcile: instance group '%s' of BOSHDeployment '%s' is ignored", instanceGroupName, bdpl.Name)
+		return reconcile.Result{}, nil
+	}
+
+	// Outside of the change window the instance groups are left unchanged
+	if result, pending := pendingWindow(ctx, bdpl, fmt.Sprintf("instance group '%s'", instanceGroupName)); pending {
+		return result, nil
+	}
+
+	// Instance groups are rolled out in the order of the manifest, once the preceding ones are stable
+	now := time.Now()
+	watch, err := checkUpdateWatch(ctx, r.client, bdpl, manifest, instanceGroupName, now)
+	if err != nil {
+		return reconcile.Result{},
+			log.WithEvent(bpmSecret, "UpdateWatchError").Errorf(ctx, "Failed to check update watch of instance group '%s': %v", instanceGroupName, err)
+	}
+	if watch.waits() && upgradeTimedOut(bdpl, now) {
+		// The partial rollout is left in place for investigation
+		reason := fmt.Sprintf("upgrade timeout: instance group '%s' was not stable within %s", watch.instanceGroup, bdpl.Spec.GracefulUpgradeTimeout.Duration)
+		failed, err := updateFailed(ctx, r.client, bdpl, reason)
+		if err != nil {
+			log.WithEvent(bdpl, "UpdateError").Errorf(ctx, "Failed to update update failure on bdpl '%s' (%v): %s", bdpl.Name, bdpl.ResourceVersion, err)
+			return reconcile.Result{Requeue: true}, nil
+		}
+		if failed {
+			log.WithEvent(bdpl, "UpgradeTimeout").Errorf(ctx, "Stopped rollout of BOSHDeployment '%s/%s': %s", bdpl.Namespace, bdpl.Name, reason)
+		}
+		return reconcile.Result{}, nil
+	}
+	err = updateWaitUntil(ctx, r.client, bdpl, watch.until, now)
+	if err != nil {
+		log.WithEvent(bdpl, "UpdateError").Errorf(ctx, "Failed to update update wait on bdpl '%s' (%v): %s", bdpl.Name, bdpl.ResourceVersion, err)
+	}
+	if watch.waits() {
+		requeueAfter := watch.requeueAfter(now)
+		log.Infof(ctx, "Instance group '%s' of BOSHDeployment '%s' waits for instance group '%s' to be stable, requeue reconcile after %s", instanceGroupName, bdpl.Name, watch.instanceGroup, requeueAfter)
+		return reconcile.Result{RequeueAfter: requeueAfter}, nil
+	}
 
 	err = dns.Reconcile(ctx, request.Namespace, r.client, func(object metav1.Object) error {
 		return r.setReference(bdpl, object, r.scheme)
@@ -162,6 +223,25 @@ func (r *ReconcileBPM) Reconcile(request reconcile.Request) (reconcile.Result, e
 		return reconcile.Result{}, nil
 	}
 
+	if override, ok := bdpl.Spec.InstanceGroupOverride(instanceGroupName); ok {
+		resources.MergePodAnnotations(override.PodAnnotations)
+	}
+	resources.MergePodSecurityContext(bdpl.Spec.InstanceGroupPodSecurityContext(instanceGroupName))
+	resources.MergePodAntiAffinity(bdpl.Spec.InstanceGroupPodAntiAffinity(instanceGroupName))
+	resources.PrependInitContainers(bdpl.Spec.InitContainers[instanceGroupName])
+	resources.MergeDeploymentTags(bdpl.Spec.DeploymentTags, deploymentTagAnnotations(bdpl.Spec.DeploymentTags))
+	if bdpl.Spec.Features.EnableBPMDebug {
+		resources.AddBPMDebugContainer()
+	}
+
+	if bdpl.Spec.DeploymentStrategy.TerminationPolicy == bdv1.TerminationPolicyDeleteBeforeCreate {
+		err = terminateObsoleteInstanceGroups(ctx, r.client, bdpl, manifest)
+		if err != nil {
+			return reconcile.Result{},
+				log.WithEvent(bpmSecret, "InstanceGroupTerminationError").Errorf(ctx, "Failed to terminate obsolete instance groups: %v", err)
+		}
+	}
+
 	// Deploy instance groups
 	err = r.deployInstanceGroups(ctx, bdpl, instanceGroupName, resources)
 	if err != nil {
@@ -169,6 +249,13 @@ func (r *ReconcileBPM) Reconcile(request reconcile.Request) (reconcile.Result, e
 			log.WithEvent(bpmSecret, "InstanceGroupStartError").Errorf(ctx, "Failed to start: %v", err)
 	}
 
+	// Volume claim templates are immutable, the claims are tagged once they exist
+	requeueAfter, err := tagPersistentVolumeClaims(ctx, r.client, bdpl, instanceGroupName, resources.InstanceGroups)
+	if err != nil {
+		return reconcile.Result{},
+			log.WithEvent(bpmSecret, "PersistentVolumeClaimTagError").Errorf(ctx, "Failed to tag persistent volume claims: %v", err)
+	}
+
 	meltdown.SetLastReconcile(&bpmSecret.ObjectMeta, time.Now())
 	err = r.client.Update(ctx, bpmSecret)
 	if err != nil {
@@ -176,7 +263,7 @@ func (r *ReconcileBPM) Reconcile(request reconcile.Request) (reconcile.Result, e
 		return reconcile.Result{Requeue: false}, nil
 	}
 
-	return reconcile.Result{}, nil
+	return reconcile.Result{RequeueAfter: requeueAfter}, nil
 }
 
 func (r *ReconcileBPM) applyBPMResources(bdplName string, bpmSecret *corev1.Secret, manifest *bdm.Manifest, dns boshdns.DomainNameService) (*bpmconverter.Resources, error) {
@@ -230,11 +317,17 @@ func (r *ReconcileBPM) applyBPMResources(bdplName string, bpmSecret *corev1.Secr
 		return resources, err
 	}
 
+	// The ClusterIP service gives link providers a stable address for the instance group
+	svc := r.converter.GenerateServicePerIG(*instanceGroup, bpmSecret.Namespace, bpmconverter.BPMPorts(bpmInfo.Configs))
+	if svc != nil {
+		resources.Services = append(resources.Services, *svc)
+	}
+
 	return resources, nil
 }
 
 func (r *ReconcileBPM) fetchIGresolvedVersion(manifestName, instanceGroupName string) (string, error) {
-	igResolvedSecretName := names.InstanceGroupSecretName(
+	igResolvedSecretName := r.secretNamer.InstanceGroupSecretName(
 		names.DeploymentSecretTypeInstanceGroupResolvedProperties,
 		manifestName,
 		instanceGroupName,
@@ -251,6 +344,22 @@ func (r *ReconcileBPM) fetchIGresolvedVersion(manifestName, instanceGroupName st
 func (r *ReconcileBPM) deployInstanceGroups(ctx context.Context, bdpl *bdv1.BOSHDeployment, instanceGroupName string, resources *bpmconverter.Resources) error {
 	log.Debugf(ctx, "Creating quarksJobs and quarksStatefulSets for instance group '%s'", instanceGroupName)
 
+	errands := []*qjv1a1.QuarksJob{}
+	for i := range resources.Errands {
+		if resources.Errands[i].Labels[bdm.LabelInstanceGroupName] == instanceGroupName {
+			errands = append(errands, &resources.Errands[i])
+		}
+	}
+	if len(errands) > 0 {
+		violations, err := podSecurityViolations(ctx, r.client, bdpl.Namespace, errands...)
+		if err != nil {
+			return log.WithEvent(bdpl, "PodSecurityViolation").Errorf(ctx, "Failed to check pod security of QuarksJobs for instance group '%s' : %v", instanceGroupName, err)
+		}
+		if len(violations) > 0 {
+			return log.WithEvent(bdpl, "PodSecurityViolation").Errorf(ctx, "QuarksJobs for instance group '%s' violate the pod security standard of namespace '%s': %s", instanceGroupName, bdpl.Namespace, strings.Join(violations, "; "))
+		}
+	}
+
 	for _, qJob := range resources.Errands {
 		if qJob.Labels[bdm.LabelInstanceGroupName] != instanceGroupName {
 			log.Debugf(ctx, "Skipping apply QuarksJob '%s' for instance group '%s' because of mismatching '%s' label", qJob.Name, bdpl.Name, bdm.LabelInstanceGroupName)
@@ -261,7 +370,7 @@ func (r *ReconcileBPM) deployInstanceGroups(ctx context.Context, bdpl *bdv1.BOSH
 			return log.WithEvent(bdpl, "QuarksJobForDeploymentError").Errorf(ctx, "Failed to set reference for QuarksJob instance group '%s' : %v", instanceGroupName, err)
 		}
 
-		op, err := controllerutil.CreateOrUpdate(ctx, r.client, &qJob, mutate.QuarksJobMutateFn(&qJob))
+		op, err := controllerutil.CreateOrUpdate(ctx, r.client, &qJob, withOwnerReference(ctx, bdpl, &qJob, r.setReference, r.scheme, mutate.QuarksJobMutateFn(&qJob)))
 		if err != nil {
 			return log.WithEvent(bdpl, "ApplyQuarksJobError").Errorf(ctx, "Failed to apply QuarksJob for instance group '%s' : %v", instanceGroupName, err)
 		}
@@ -279,12 +388,26 @@ func (r *ReconcileBPM) deployInstanceGroups(ctx context.Context, bdpl *bdv1.BOSH
 			return log.WithEvent(bdpl, "ServiceForDeploymentError").Errorf(ctx, "Failed to set reference for Service instance group '%s' : %v", instanceGroupName, err)
 		}
 
-		op, err := controllerutil.CreateOrUpdate(ctx, r.client, &svc, mutate.ServiceMutateFn(&svc))
+		op, err := controllerutil.CreateOrUpdate(ctx, r.client, &svc, withOwnerReference(ctx, bdpl, &svc, r.setReference, r.scheme, mutate.ServiceMutateFn(&svc)))
 		if err != nil {
 			return log.WithEvent(bdpl, "ApplyServiceError").Errorf(ctx, "Failed to apply Service for instance group '%s' : %v", instanceGroupName, err)
 		}
 
 		log.Debugf(ctx, "Service '%s' has been %s", svc.Name, op)
+
+		if bdpl.Spec.GenerateServiceMonitors && svc.Spec.ClusterIP == corev1.ClusterIPNone {
+			err := r.deployServiceMonitor(ctx, bdpl, instanceGroupName, svc)
+			if err != nil {
+				return err
+			}
+		}
+
+		if bdpl.Spec.Features.ReconcileServiceExports && svc.Spec.ClusterIP == corev1.ClusterIPNone {
+			err := r.deployServiceExport(ctx, bdpl, instanceGroupName, svc)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
 	for _, qSts := range resources.InstanceGroups {
@@ -297,7 +420,7 @@ func (r *ReconcileBPM) deployInstanceGroups(ctx context.Context, bdpl *bdv1.BOSH
 			return log.WithEvent(bdpl, "QuarksStatefulSetForDeploymentError").Errorf(ctx, "Failed to set reference for QuarksStatefulSet instance group '%s' : %v", instanceGroupName, err)
 		}
 
-		op, err := controllerutil.CreateOrUpdate(ctx, r.client, &qSts, mutate.QuarksStatefulSetMutateFn(&qSts))
+		op, err := controllerutil.CreateOrUpdate(ctx, r.client, &qSts, withOwnerReference(ctx, bdpl, &qSts, r.setReference, r.scheme, mutate.QuarksStatefulSetMutateFn(&qSts)))
 		if err != nil {
 			return log.WithEvent(bdpl, "ApplyQuarksStatefulSetError").Errorf(ctx, "Failed to apply QuarksStatefulSet for instance group '%s' : %v", instanceGroupName, err)
 		}
@@ -307,3 +430,54 @@ func (r *ReconcileBPM) deployInstanceGroups(ctx context.Context, bdpl *bdv1.BOSH
 
 	return nil
 }
+
+// deployServiceMonitor creates or updates the ServiceMonitor, which scrapes the
+// metrics port of the instance group's headless service
+func (r *ReconcileBPM) deployServiceMonitor(ctx context.Context, bdpl *bdv1.BOSHDeployment, instanceGroupName string, headlessService corev1.Service) error {
+	var metricsPort *corev1.ServicePort
+	for i, port := range headlessService.Spec.Ports {
+		if port.Name == bpmconverter.MetricsPortName {
+			metricsPort = &headlessService.Spec.Ports[i]
+			break
+		}
+	}
+	if metricsPort == nil {
+		log.Debugf(ctx, "Skipping ServiceMonitor for instance group '%s', which has no '%s' port", instanceGroupName, bpmconverter.MetricsPortName)
+		return nil
+	}
+
+	sm := r.converter.GenerateServiceMonitor(instanceGroupName, headlessService.Namespace, int(metricsPort.Port), headlessService.Labels)
+	if err := r.setReference(bdpl, sm, r.scheme); err != nil {
+		return log.WithEvent(bdpl, "ServiceMonitorForDeploymentError").Errorf(ctx, "Failed to set reference for ServiceMonitor instance group '%s' : %v", instanceGroupName, err)
+	}
+
+	op, err := controllerutil.CreateOrUpdate(ctx, r.client, sm, withOwnerReference(ctx, bdpl, sm, r.setReference, r.scheme, mutate.ServiceMonitorMutateFn(sm)))
+	if err != nil {
+		return log.WithEvent(bdpl, "ApplyServiceMonitorError").Errorf(ctx, "Failed to apply ServiceMonitor for instance group '%s' : %v", instanceGroupName, err)
+	}
+
+	log.Debugf(ctx, "ServiceMonitor '%s' has been %s", sm.GetName(), op)
+	return nil
+}
+
+// deployServiceExport creates or updates the ServiceExport of the instance
+// group's headless service. It is skipped, if the ServiceExport CRD of the
+// Multicluster Services API is not installed.
+func (r *ReconcileBPM) deployServiceExport(ctx context.Context, bdpl *bdv1.BOSHDeployment, instanceGroupName string, headlessService corev1.Service) error {
+	se := r.converter.GenerateServiceExport(headlessService)
+	if err := r.setReference(bdpl, se, r.scheme); err != nil {
+		return log.WithEvent(bdpl, "ServiceExportForDeploymentError").Errorf(ctx, "Failed to set reference for ServiceExport instance group '%s' : %v", instanceGroupName, err)
+	}
+
+	op, err := controllerutil.CreateOrUpdate(ctx, r.client, se, withOwnerReference(ctx, bdpl, se, r.setReference, r.scheme, mutate.ServiceExportMutateFn(se)))
+	if meta.IsNoMatchError(err) {
+		log.Infof(ctx, "Skipping ServiceExport for instance group '%s', the ServiceExport CRD is not installed", instanceGroupName)
+		return nil
+	}
+	if err != nil {
+		return log.WithEvent(bdpl, "ApplyServiceExportError").Errorf(ctx, "Failed to apply ServiceExport for instance group '%s' : %v", instanceGroupName, err)
+	}
+
+	log.Debugf(ctx, "ServiceExport '%s' has been %s", se.GetName(), op)
+	return nil
+}
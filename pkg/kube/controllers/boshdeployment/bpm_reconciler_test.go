@@ -3,6 +3,7 @@ package boshdeployment_test
 import (
 	"context"
 	"errors"
+	"os"
 	"time"
 
 	. "github.com/onsi/ginkgo"
@@ -10,9 +11,12 @@ import (
 	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest/observer"
 
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
@@ -25,10 +29,12 @@ import (
 	"code.cloudfoundry.org/cf-operator/pkg/bosh/bpmconverter"
 	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
 	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	qstsv1a1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/quarksstatefulset/v1alpha1"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/controllers"
 	cfd "code.cloudfoundry.org/cf-operator/pkg/kube/controllers/boshdeployment"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/controllers/fakes"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/util/boshdns"
+	bdnames "code.cloudfoundry.org/cf-operator/pkg/kube/util/names"
 	qjv1a1 "code.cloudfoundry.org/quarks-job/pkg/kube/apis/quarksjob/v1alpha1"
 	cfcfg "code.cloudfoundry.org/quarks-utils/pkg/config"
 	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
@@ -238,6 +244,7 @@ variables: []
 			func(name string, m bdm.Manifest) (boshdns.DomainNameService, error) {
 				return boshdns.NewSimpleDomainNameService("fake-manifest"), nil
 			},
+			bdnames.DefaultSecretNamer{},
 		)
 	})
 
@@ -316,6 +323,754 @@ variables: []
 				Expect(err.Error()).To(ContainSubstring("failed to start: failed to apply Service for instance group 'fakepod'"))
 			})
 
+			It("adds the pod annotations of the instance group override", func() {
+				kubeConverter.ResourcesReturns(&bpmconverter.Resources{
+					Errands: []qjv1a1.QuarksJob{
+						{
+							ObjectMeta: metav1.ObjectMeta{
+								Name: "fake-errand",
+								Labels: map[string]string{
+									bdm.LabelInstanceGroupName: "fakepod",
+								},
+							},
+						},
+					},
+				}, nil)
+
+				client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+					switch object := object.(type) {
+					case *corev1.Secret:
+						if nn.Name == manifestWithVars.Name {
+							manifestWithVars.DeepCopyInto(object)
+						}
+						if nn.Name == bpmInformation.Name {
+							bpmInformation.DeepCopyInto(object)
+						}
+					case *bdv1.BOSHDeployment:
+						object.Name = "foo"
+						object.Spec.InstanceGroups = []bdv1.InstanceGroupOverride{
+							{Name: "other", PodAnnotations: map[string]string{"other": "true"}},
+							{Name: "fakepod", PodAnnotations: map[string]string{"sidecar.istio.io/inject": "true"}},
+						}
+					case *qjv1a1.QuarksJob:
+						return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+					}
+
+					return nil
+				})
+
+				_, err := reconciler.Reconcile(request)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(client.CreateCallCount()).To(Equal(1))
+				_, object, _ := client.CreateArgsForCall(0)
+				annotations := object.(*qjv1a1.QuarksJob).Spec.Template.Spec.Template.Annotations
+				Expect(annotations).To(Equal(map[string]string{"sidecar.istio.io/inject": "true"}))
+			})
+
+			It("adds the deployment tags and their cloud tag annotations", func() {
+				file := writeTempFile("cost-center:\n- example.com/cost-center\n")
+				defer os.Remove(file)
+				Expect(cfd.SetCloudTagAnnotations(file)).To(Succeed())
+				defer cfd.SetCloudTagAnnotations("")
+
+				kubeConverter.ResourcesReturns(&bpmconverter.Resources{
+					Errands: []qjv1a1.QuarksJob{
+						{
+							ObjectMeta: metav1.ObjectMeta{
+								Name: "fake-errand",
+								Labels: map[string]string{
+									bdm.LabelInstanceGroupName: "fakepod",
+								},
+							},
+						},
+					},
+				}, nil)
+
+				client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+					switch object := object.(type) {
+					case *corev1.Secret:
+						if nn.Name == manifestWithVars.Name {
+							manifestWithVars.DeepCopyInto(object)
+						}
+						if nn.Name == bpmInformation.Name {
+							bpmInformation.DeepCopyInto(object)
+						}
+					case *bdv1.BOSHDeployment:
+						object.Name = "foo"
+						object.Spec.DeploymentTags = map[string]string{"cost-center": "4711", "project": "cf"}
+					case *qjv1a1.QuarksJob:
+						return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+					}
+
+					return nil
+				})
+
+				_, err := reconciler.Reconcile(request)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(client.CreateCallCount()).To(Equal(1))
+				_, object, _ := client.CreateArgsForCall(0)
+				qJob := object.(*qjv1a1.QuarksJob)
+				Expect(qJob.Labels).To(HaveKeyWithValue("cost-center", "4711"))
+				Expect(qJob.Labels).To(HaveKeyWithValue("project", "cf"))
+				Expect(qJob.Labels).To(HaveKeyWithValue(bdm.LabelInstanceGroupName, "fakepod"))
+				Expect(qJob.Annotations).To(Equal(map[string]string{"example.com/cost-center": "4711"}))
+			})
+
+			Context("when the instance group has volume claim templates", func() {
+				var claims []corev1.PersistentVolumeClaim
+
+				claim := func(name string, labels map[string]string) corev1.PersistentVolumeClaim {
+					return corev1.PersistentVolumeClaim{
+						ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: labels},
+					}
+				}
+
+				claimUpdates := func() []*corev1.PersistentVolumeClaim {
+					updates := []*corev1.PersistentVolumeClaim{}
+					for i := 0; i < client.UpdateCallCount(); i++ {
+						_, object, _ := client.UpdateArgsForCall(i)
+						if claim, ok := object.(*corev1.PersistentVolumeClaim); ok {
+							updates = append(updates, claim)
+						}
+					}
+					return updates
+				}
+
+				BeforeEach(func() {
+					replicas := int32(2)
+					qsts := qstsv1a1.QuarksStatefulSet{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      "foo-fakepod",
+							Namespace: "default",
+							Labels:    map[string]string{bdm.LabelInstanceGroupName: "fakepod"},
+						},
+					}
+					qsts.Spec.Template.Spec.Replicas = &replicas
+					qsts.Spec.Template.Spec.VolumeClaimTemplates = []corev1.PersistentVolumeClaim{
+						{ObjectMeta: metav1.ObjectMeta{Name: "fakepod-pvc"}},
+					}
+					kubeConverter.ResourcesReturns(&bpmconverter.Resources{
+						InstanceGroups: []qstsv1a1.QuarksStatefulSet{qsts},
+					}, nil)
+
+					claims = []corev1.PersistentVolumeClaim{
+						claim("fakepod-pvc-fakepod-0", map[string]string{
+							qstsv1a1.LabelQStsName: "foo-fakepod",
+							qstsv1a1.LabelAZIndex:  "0",
+							"cost-center":          "0815",
+						}),
+					}
+
+					client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+						switch object := object.(type) {
+						case *corev1.Secret:
+							if nn.Name == manifestWithVars.Name {
+								manifestWithVars.DeepCopyInto(object)
+							}
+							if nn.Name == bpmInformation.Name {
+								bpmInformation.DeepCopyInto(object)
+							}
+						case *bdv1.BOSHDeployment:
+							object.Name = "foo"
+							object.Namespace = "default"
+							object.Spec.DeploymentTags = map[string]string{"cost-center": "4711", qstsv1a1.LabelAZIndex: "9"}
+						}
+
+						return nil
+					})
+					client.ListCalls(func(context context.Context, object runtime.Object, opts ...crc.ListOption) error {
+						switch object := object.(type) {
+						case *corev1.SecretList:
+							object.Items = []corev1.Secret{*manifestWithVars, *bpmInformation}
+						case *corev1.PersistentVolumeClaimList:
+							listOpts := &crc.ListOptions{}
+							for _, opt := range opts {
+								opt.ApplyToList(listOpts)
+							}
+							Expect(listOpts.Namespace).To(Equal("default"))
+							Expect(listOpts.LabelSelector.String()).To(Equal(qstsv1a1.LabelQStsName + "=foo-fakepod"))
+							object.Items = claims
+						}
+
+						return nil
+					})
+				})
+
+				It("updates the tags of the existing claims, but not the generated labels, and requeues until all claims exist", func() {
+					result, err := reconciler.Reconcile(request)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result).To(Equal(reconcile.Result{RequeueAfter: 30 * time.Second}))
+
+					updates := claimUpdates()
+					Expect(updates).To(HaveLen(1))
+					Expect(updates[0].Name).To(Equal("fakepod-pvc-fakepod-0"))
+					Expect(updates[0].Labels).To(HaveKeyWithValue("cost-center", "4711"))
+					Expect(updates[0].Labels).To(HaveKeyWithValue(qstsv1a1.LabelAZIndex, "0"))
+				})
+
+				It("doesn't update tagged claims and doesn't requeue, once all claims exist", func() {
+					claims = []corev1.PersistentVolumeClaim{
+						claim("fakepod-pvc-fakepod-0", map[string]string{qstsv1a1.LabelQStsName: "foo-fakepod", qstsv1a1.LabelAZIndex: "0", "cost-center": "4711"}),
+						claim("fakepod-pvc-fakepod-1", map[string]string{qstsv1a1.LabelQStsName: "foo-fakepod", qstsv1a1.LabelAZIndex: "0", "cost-center": "4711"}),
+					}
+
+					result, err := reconciler.Reconcile(request)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result).To(Equal(reconcile.Result{}))
+					Expect(claimUpdates()).To(BeEmpty())
+				})
+			})
+
+			It("adds the BPM debug container to the instance groups, if enabled", func() {
+				qsts := qstsv1a1.QuarksStatefulSet{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "foo-fakepod",
+						Labels: map[string]string{
+							bdm.LabelInstanceGroupName: "fakepod",
+						},
+					},
+				}
+				qsts.Spec.Template.Spec.Template.Spec.Containers = []corev1.Container{{Name: "fake-job"}}
+				kubeConverter.ResourcesReturns(&bpmconverter.Resources{
+					InstanceGroups: []qstsv1a1.QuarksStatefulSet{qsts},
+				}, nil)
+
+				client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+					switch object := object.(type) {
+					case *corev1.Secret:
+						if nn.Name == manifestWithVars.Name {
+							manifestWithVars.DeepCopyInto(object)
+						}
+						if nn.Name == bpmInformation.Name {
+							bpmInformation.DeepCopyInto(object)
+						}
+					case *bdv1.BOSHDeployment:
+						object.Name = "foo"
+						object.Spec.Features.EnableBPMDebug = true
+					case *qstsv1a1.QuarksStatefulSet:
+						return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+					}
+
+					return nil
+				})
+
+				_, err := reconciler.Reconcile(request)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(client.CreateCallCount()).To(Equal(1))
+				_, object, _ := client.CreateArgsForCall(0)
+				containers := object.(*qstsv1a1.QuarksStatefulSet).Spec.Template.Spec.Template.Spec.Containers
+				Expect(containers).To(HaveLen(2))
+				Expect(containers[1].Name).To(Equal(bpmconverter.BPMDebugContainerName))
+			})
+
+			It("rejects invalid cloud tag annotation keys", func() {
+				file := writeTempFile("cost-center:\n- example.com/cost center\n")
+				defer os.Remove(file)
+				Expect(cfd.SetCloudTagAnnotations(file)).To(MatchError(ContainSubstring("invalid annotation key 'example.com/cost center' for tag 'cost-center'")))
+			})
+
+			It("generates a ServiceMonitor for the headless service, if enabled", func() {
+				headlessService := corev1.Service{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "foo-fakepod",
+						Namespace: "default",
+						Labels: map[string]string{
+							bdm.LabelInstanceGroupName: "fakepod",
+						},
+					},
+					Spec: corev1.ServiceSpec{
+						ClusterIP: corev1.ClusterIPNone,
+						Ports: []corev1.ServicePort{
+							{Name: "http", Port: 8080},
+							{Name: bpmconverter.MetricsPortName, Port: 9090},
+						},
+					},
+				}
+				kubeConverter.ResourcesReturns(&bpmconverter.Resources{Services: []corev1.Service{headlessService}}, nil)
+				sm := &unstructured.Unstructured{}
+				sm.SetGroupVersionKind(bpmconverter.ServiceMonitorGroupVersionKind)
+				sm.SetName("foo-fakepod")
+				kubeConverter.GenerateServiceMonitorReturns(sm)
+
+				client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+					switch object := object.(type) {
+					case *corev1.Secret:
+						if nn.Name == manifestWithVars.Name {
+							manifestWithVars.DeepCopyInto(object)
+						}
+						if nn.Name == bpmInformation.Name {
+							bpmInformation.DeepCopyInto(object)
+						}
+					case *bdv1.BOSHDeployment:
+						object.Name = "foo"
+						object.Spec.GenerateServiceMonitors = true
+					case *corev1.Service, *unstructured.Unstructured:
+						return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+					}
+
+					return nil
+				})
+
+				_, err := reconciler.Reconcile(request)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(kubeConverter.GenerateServiceMonitorCallCount()).To(Equal(1))
+				igName, namespace, port, labels := kubeConverter.GenerateServiceMonitorArgsForCall(0)
+				Expect(igName).To(Equal("fakepod"))
+				Expect(namespace).To(Equal("default"))
+				Expect(port).To(Equal(9090))
+				Expect(labels).To(Equal(headlessService.Labels))
+
+				Expect(client.CreateCallCount()).To(Equal(2))
+				_, object, _ := client.CreateArgsForCall(1)
+				Expect(object).To(Equal(sm))
+			})
+
+			Context("when service exports are enabled", func() {
+				var (
+					headlessService corev1.Service
+					se              *unstructured.Unstructured
+					exportErr       error
+				)
+
+				BeforeEach(func() {
+					headlessService = corev1.Service{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      "foo-fakepod",
+							Namespace: "default",
+							Labels: map[string]string{
+								bdm.LabelInstanceGroupName: "fakepod",
+							},
+						},
+						Spec: corev1.ServiceSpec{ClusterIP: corev1.ClusterIPNone},
+					}
+					kubeConverter.ResourcesReturns(&bpmconverter.Resources{Services: []corev1.Service{headlessService}}, nil)
+					se = &unstructured.Unstructured{}
+					se.SetGroupVersionKind(bpmconverter.ServiceExportGroupVersionKind)
+					se.SetName("foo-fakepod")
+					kubeConverter.GenerateServiceExportReturns(se)
+					exportErr = nil
+
+					client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+						switch object := object.(type) {
+						case *corev1.Secret:
+							if nn.Name == manifestWithVars.Name {
+								manifestWithVars.DeepCopyInto(object)
+							}
+							if nn.Name == bpmInformation.Name {
+								bpmInformation.DeepCopyInto(object)
+							}
+						case *bdv1.BOSHDeployment:
+							object.Name = "foo"
+							object.Spec.Features.ReconcileServiceExports = true
+						case *unstructured.Unstructured:
+							if exportErr != nil {
+								return exportErr
+							}
+							return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+						case *corev1.Service:
+							return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+						}
+
+						return nil
+					})
+				})
+
+				It("creates a ServiceExport for the headless service", func() {
+					_, err := reconciler.Reconcile(request)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(kubeConverter.GenerateServiceExportCallCount()).To(Equal(1))
+					Expect(kubeConverter.GenerateServiceExportArgsForCall(0).Name).To(Equal("foo-fakepod"))
+					Expect(client.CreateCallCount()).To(Equal(2))
+					_, object, _ := client.CreateArgsForCall(1)
+					Expect(object).To(Equal(se))
+				})
+
+				It("skips the ServiceExport, if its CRD is not installed", func() {
+					exportErr = &meta.NoKindMatchError{GroupKind: bpmconverter.ServiceExportGroupVersionKind.GroupKind()}
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(client.CreateCallCount()).To(Equal(1))
+				})
+
+				It("doesn't export services with a cluster IP", func() {
+					headlessService.Spec.ClusterIP = ""
+					kubeConverter.ResourcesReturns(&bpmconverter.Resources{Services: []corev1.Service{headlessService}}, nil)
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(kubeConverter.GenerateServiceExportCallCount()).To(Equal(0))
+				})
+			})
+
+			It("creates the ClusterIP service of the instance group", func() {
+				kubeConverter.ResourcesReturns(&bpmconverter.Resources{}, nil)
+				svc := &corev1.Service{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "foo-fakepod-svc",
+						Namespace: "default",
+						Labels:    map[string]string{bdm.LabelInstanceGroupName: "fakepod"},
+					},
+					Spec: corev1.ServiceSpec{
+						Type:  corev1.ServiceTypeClusterIP,
+						Ports: []corev1.ServicePort{{Name: "port-8080", Port: 8080}},
+					},
+				}
+				kubeConverter.GenerateServicePerIGReturns(svc)
+
+				client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+					switch object := object.(type) {
+					case *corev1.Secret:
+						if nn.Name == manifestWithVars.Name {
+							manifestWithVars.DeepCopyInto(object)
+						}
+						if nn.Name == bpmInformation.Name {
+							bpmInformation.DeepCopyInto(object)
+						}
+					case *bdv1.BOSHDeployment:
+						object.Name = "foo"
+					case *corev1.Service:
+						return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+					}
+
+					return nil
+				})
+
+				_, err := reconciler.Reconcile(request)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(kubeConverter.GenerateServicePerIGCallCount()).To(Equal(1))
+				ig, namespace, _ := kubeConverter.GenerateServicePerIGArgsForCall(0)
+				Expect(ig.Name).To(Equal("fakepod"))
+				Expect(namespace).To(Equal("default"))
+
+				Expect(client.CreateCallCount()).To(Equal(1))
+				_, object, _ := client.CreateArgsForCall(0)
+				Expect(object.(*corev1.Service).Name).To(Equal("foo-fakepod-svc"))
+			})
+
+			It("doesn't generate ServiceMonitors, if disabled", func() {
+				kubeConverter.ResourcesReturns(&bpmconverter.Resources{
+					Services: []corev1.Service{
+						{
+							ObjectMeta: metav1.ObjectMeta{
+								Name:   "foo-fakepod",
+								Labels: map[string]string{bdm.LabelInstanceGroupName: "fakepod"},
+							},
+							Spec: corev1.ServiceSpec{
+								ClusterIP: corev1.ClusterIPNone,
+								Ports:     []corev1.ServicePort{{Name: bpmconverter.MetricsPortName, Port: 9090}},
+							},
+						},
+					},
+				}, nil)
+
+				_, err := reconciler.Reconcile(request)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(kubeConverter.GenerateServiceMonitorCallCount()).To(Equal(0))
+			})
+
+			It("skips suspended instance groups", func() {
+				client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+					switch object := object.(type) {
+					case *corev1.Secret:
+						if nn.Name == manifestWithVars.Name {
+							manifestWithVars.DeepCopyInto(object)
+						}
+						if nn.Name == bpmInformation.Name {
+							bpmInformation.DeepCopyInto(object)
+						}
+					case *bdv1.BOSHDeployment:
+						object.Name = "foo"
+						object.Annotations = map[string]string{
+							bdv1.AnnotationSuspendedInstanceGroups: "fakepod",
+						}
+					}
+
+					return nil
+				})
+
+				result, err := reconciler.Reconcile(request)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result).To(Equal(reconcile.Result{}))
+				Expect(kubeConverter.ResourcesCallCount()).To(Equal(0))
+				Expect(client.CreateCallCount()).To(Equal(0))
+			})
+
+			It("skips ignored instance groups", func() {
+				client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+					switch object := object.(type) {
+					case *corev1.Secret:
+						if nn.Name == manifestWithVars.Name {
+							manifestWithVars.DeepCopyInto(object)
+						}
+						if nn.Name == bpmInformation.Name {
+							bpmInformation.DeepCopyInto(object)
+						}
+					case *bdv1.BOSHDeployment:
+						object.Name = "foo"
+						object.Spec.IgnoredInstanceGroups = []string{"fakepod"}
+					}
+
+					return nil
+				})
+
+				result, err := reconciler.Reconcile(request)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result).To(Equal(reconcile.Result{}))
+				Expect(kubeConverter.ResourcesCallCount()).To(Equal(0))
+				Expect(client.CreateCallCount()).To(Equal(0))
+				Expect(<-recorder.Events).To(ContainSubstring("InstanceGroupIgnored"))
+			})
+
+			Context("when the operator has a change window", func() {
+				clock := func(t time.Time) string {
+					return t.UTC().Format("15:04")
+				}
+
+				AfterEach(func() {
+					Expect(cfd.SetChangeWindow("")).To(Succeed())
+				})
+
+				It("defers the instance group outside the window", func() {
+					now := time.Now()
+					Expect(cfd.SetChangeWindow(clock(now.Add(2*time.Hour)) + "-" + clock(now.Add(3*time.Hour)))).To(Succeed())
+
+					result, err := reconciler.Reconcile(request)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result.RequeueAfter).To(BeNumerically(">", time.Hour))
+					Expect(result.RequeueAfter).To(BeNumerically("<=", 2*time.Hour))
+					Expect(kubeConverter.ResourcesCallCount()).To(Equal(0))
+					Expect(client.CreateCallCount()).To(Equal(0))
+					Expect(client.UpdateCallCount()).To(Equal(0))
+					Expect(<-recorder.Events).To(ContainSubstring("PendingWindow"))
+				})
+
+				It("deploys the instance group inside the window", func() {
+					now := time.Now()
+					Expect(cfd.SetChangeWindow(clock(now.Add(-time.Hour)) + "-" + clock(now.Add(time.Hour)))).To(Succeed())
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(kubeConverter.ResourcesCallCount()).To(Equal(1))
+				})
+			})
+
+			Context("when the deployment has an update config", func() {
+				var (
+					statusWriter    *fakes.FakeStatusWriter
+					readyReplicas   int32
+					readySince      time.Time
+					upgradeTimeout  *metav1.Duration
+					updateStartedAt *metav1.Time
+					phase           bdv1.Phase
+				)
+
+				BeforeEach(func() {
+					manifest.InstanceGroups = append([]*bdm.InstanceGroup{{Name: "canary", Instances: 1}}, manifest.InstanceGroups...)
+					readyReplicas = 1
+					upgradeTimeout = nil
+					updateStartedAt = nil
+					phase = bdv1.PhaseApplied
+
+					statusWriter = &fakes.FakeStatusWriter{}
+					client.StatusCalls(func() crc.StatusWriter { return statusWriter })
+					client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+						switch object := object.(type) {
+						case *corev1.Secret:
+							if nn.Name == manifestWithVars.Name {
+								manifestWithVars.DeepCopyInto(object)
+							}
+							if nn.Name == bpmInformation.Name {
+								bpmInformation.DeepCopyInto(object)
+							}
+						case *bdv1.BOSHDeployment:
+							object.Name = "foo"
+							object.Namespace = "default"
+							object.Spec.UpdateConfig = &bdv1.UpdateConfig{
+								CanaryWatchTime: &metav1.Duration{Duration: time.Minute},
+							}
+							object.Spec.GracefulUpgradeTimeout = upgradeTimeout
+							object.Status.UpdateStartedAt = updateStartedAt
+							object.Status.Phase = phase
+						}
+
+						return nil
+					})
+					client.ListCalls(func(context context.Context, object runtime.Object, _ ...crc.ListOption) error {
+						switch object := object.(type) {
+						case *corev1.SecretList:
+							object.Items = []corev1.Secret{*manifestWithVars, *bpmInformation}
+						case *appsv1.StatefulSetList:
+							replicas := int32(1)
+							object.Items = []appsv1.StatefulSet{{
+								ObjectMeta: metav1.ObjectMeta{Name: "foo-canary", Namespace: "default"},
+								Spec:       appsv1.StatefulSetSpec{Replicas: &replicas},
+								Status:     appsv1.StatefulSetStatus{ReadyReplicas: readyReplicas},
+							}}
+						case *corev1.PodList:
+							object.Items = []corev1.Pod{{
+								ObjectMeta: metav1.ObjectMeta{Name: "foo-canary-0", Namespace: "default"},
+								Status: corev1.PodStatus{
+									Conditions: []corev1.PodCondition{{
+										Type:               corev1.PodReady,
+										Status:             corev1.ConditionTrue,
+										LastTransitionTime: metav1.NewTime(readySince),
+									}},
+								},
+							}}
+						}
+
+						return nil
+					})
+				})
+
+				It("waits for the preceding instance group to be ready", func() {
+					readyReplicas = 0
+
+					result, err := reconciler.Reconcile(request)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result.RequeueAfter).To(Equal(10 * time.Second))
+					Expect(kubeConverter.ResourcesCallCount()).To(Equal(0))
+					Expect(statusWriter.UpdateCallCount()).To(Equal(0))
+				})
+
+				It("waits for the canary watch time and records the end of the wait", func() {
+					readySince = time.Now().Add(-10 * time.Second)
+
+					result, err := reconciler.Reconcile(request)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result.RequeueAfter).To(BeNumerically("~", 50*time.Second, time.Second))
+					Expect(kubeConverter.ResourcesCallCount()).To(Equal(0))
+
+					Expect(statusWriter.UpdateCallCount()).To(Equal(1))
+					_, object, _ := statusWriter.UpdateArgsForCall(0)
+					Expect(object.(*bdv1.BOSHDeployment).Status.UpdateWaitUntil.Time).To(BeTemporally("~", readySince.Add(time.Minute), time.Second))
+				})
+
+				It("deploys the instance group, once the preceding instance group is stable", func() {
+					readySince = time.Now().Add(-2 * time.Minute)
+
+					result, err := reconciler.Reconcile(request)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result).To(Equal(reconcile.Result{}))
+					Expect(kubeConverter.ResourcesCallCount()).To(Equal(1))
+				})
+
+				Context("when the rollout exceeds the graceful upgrade timeout", func() {
+					BeforeEach(func() {
+						readyReplicas = 0
+						upgradeTimeout = &metav1.Duration{Duration: 10 * time.Minute}
+						startedAt := metav1.NewTime(time.Now().Add(-11 * time.Minute))
+						updateStartedAt = &startedAt
+					})
+
+					It("stops the rollout with the UpdateFailed phase", func() {
+						result, err := reconciler.Reconcile(request)
+						Expect(err).NotTo(HaveOccurred())
+						Expect(result).To(Equal(reconcile.Result{}))
+						Expect(kubeConverter.ResourcesCallCount()).To(Equal(0))
+						Expect(<-recorder.Events).To(ContainSubstring("UpgradeTimeout"))
+
+						Expect(statusWriter.UpdateCallCount()).To(Equal(1))
+						_, object, _ := statusWriter.UpdateArgsForCall(0)
+						status := object.(*bdv1.BOSHDeployment).Status
+						Expect(status.Phase).To(Equal(bdv1.PhaseUpdateFailed))
+						Expect(status.LastError).To(ContainSubstring("instance group 'canary' was not stable within 10m0s"))
+					})
+
+					It("doesn't update the status again, once the rollout is stopped", func() {
+						phase = bdv1.PhaseUpdateFailed
+
+						result, err := reconciler.Reconcile(request)
+						Expect(err).NotTo(HaveOccurred())
+						Expect(result).To(Equal(reconcile.Result{}))
+						Expect(kubeConverter.ResourcesCallCount()).To(Equal(0))
+						Expect(statusWriter.UpdateCallCount()).To(Equal(0))
+						Expect(recorder.Events).To(BeEmpty())
+					})
+
+					It("keeps waiting within the timeout", func() {
+						startedAt := metav1.NewTime(time.Now().Add(-time.Minute))
+						updateStartedAt = &startedAt
+
+						result, err := reconciler.Reconcile(request)
+						Expect(err).NotTo(HaveOccurred())
+						Expect(result.RequeueAfter).To(Equal(10 * time.Second))
+						Expect(statusWriter.UpdateCallCount()).To(Equal(0))
+					})
+				})
+			})
+
+			Context("when instance groups were removed from the manifest", func() {
+				var terminationPolicy bdv1.TerminationPolicy
+
+				BeforeEach(func() {
+					client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+						switch object := object.(type) {
+						case *corev1.Secret:
+							if nn.Name == manifestWithVars.Name {
+								manifestWithVars.DeepCopyInto(object)
+							}
+							if nn.Name == bpmInformation.Name {
+								bpmInformation.DeepCopyInto(object)
+							}
+						case *bdv1.BOSHDeployment:
+							object.Name = "foo"
+							object.Namespace = "default"
+							object.Spec.DeploymentStrategy.TerminationPolicy = terminationPolicy
+						}
+
+						return nil
+					})
+					client.ListCalls(func(context context.Context, object runtime.Object, _ ...crc.ListOption) error {
+						switch object := object.(type) {
+						case *corev1.SecretList:
+							object.Items = []corev1.Secret{*manifestWithVars, *bpmInformation}
+						case *qstsv1a1.QuarksStatefulSetList:
+							for _, ig := range []string{"fakepod", "oldpod"} {
+								object.Items = append(object.Items, qstsv1a1.QuarksStatefulSet{
+									ObjectMeta: metav1.ObjectMeta{
+										Name:      "foo-" + ig,
+										Namespace: "default",
+										Labels: map[string]string{
+											bdm.LabelDeploymentName:    "foo",
+											bdm.LabelInstanceGroupName: ig,
+										},
+									},
+								})
+							}
+						}
+
+						return nil
+					})
+				})
+
+				It("deletes their QuarksStatefulSets before deploying, if the policy is DeleteBeforeCreate", func() {
+					terminationPolicy = bdv1.TerminationPolicyDeleteBeforeCreate
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(client.DeleteCallCount()).To(Equal(1))
+					_, object, _ := client.DeleteArgsForCall(0)
+					Expect(object.(*qstsv1a1.QuarksStatefulSet).Name).To(Equal("foo-oldpod"))
+					Expect(<-recorder.Events).To(ContainSubstring("InstanceGroupTerminated"))
+				})
+
+				It("retains their QuarksStatefulSets by default", func() {
+					terminationPolicy = ""
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(client.DeleteCallCount()).To(Equal(0))
+				})
+			})
+
 			It("creates instance groups and updates bpm configs created state to deploying state successfully", func() {
 				client.UpdateCalls(func(context context.Context, object runtime.Object, _ ...crc.UpdateOption) error {
 					switch object.(type) {
@@ -0,0 +1,72 @@
+package boshdeployment
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+
+	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+)
+
+// manifestNormalization lists the manifest sections, whose order is ignored when detecting changes
+var manifestNormalization = bdm.DefaultNormalization
+
+// SetManifestNormalization initializes the package scoped manifestNormalization variable
+// from a comma separated list of manifest sections.
+func SetManifestNormalization(sections string) error {
+	n, err := bdm.ParseNormalization(sections)
+	if err != nil {
+		return err
+	}
+	manifestNormalization = n
+	return nil
+}
+
+// manifestWithOpsChanged returns true, if the existing with-ops manifest differs from
+// the one in manifestSecret. Both are normalized, so insignificant reordering is no change.
+func (r *ReconcileBOSHDeployment) manifestWithOpsChanged(ctx context.Context, manifestSecret *corev1.Secret) (bool, error) {
+	existing := &corev1.Secret{}
+	err := r.client.Get(ctx, crc.ObjectKey{Name: manifestSecret.Name, Namespace: manifestSecret.Namespace}, existing)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, errors.Wrapf(err, "getting Secret '%s'", manifestSecret.Name)
+	}
+
+	oldManifest, err := bdm.SecretData(existing.Data)
+	if err != nil {
+		return false, errors.Wrapf(err, "reading manifest of Secret '%s'", manifestSecret.Name)
+	}
+	oldSHA1, err := normalizedManifestSHA1(oldManifest)
+	if err != nil {
+		return false, errors.Wrapf(err, "loading manifest of Secret '%s'", manifestSecret.Name)
+	}
+
+	newManifest := []byte(manifestSecret.StringData[bdm.DesiredManifestKeyName])
+	if manifestSecret.StringData == nil {
+		newManifest, err = bdm.SecretData(manifestSecret.Data)
+		if err != nil {
+			return false, errors.Wrap(err, "reading with-ops manifest")
+		}
+	}
+	newSHA1, err := normalizedManifestSHA1(newManifest)
+	if err != nil {
+		return false, errors.Wrap(err, "loading with-ops manifest")
+	}
+
+	return oldSHA1 != newSHA1, nil
+}
+
+// normalizedManifestSHA1 returns the SHA1 of the normalized manifest
+func normalizedManifestSHA1(data []byte) (string, error) {
+	m, err := bdm.LoadYAML(data)
+	if err != nil {
+		return "", err
+	}
+	return m.NormalizedSHA1(manifestNormalization)
+}
@@ -0,0 +1,64 @@
+package boshdeployment
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	qsv1a1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/quarkssecret/v1alpha1"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/changewindow"
+	qjv1a1 "code.cloudfoundry.org/quarks-job/pkg/kube/apis/quarksjob/v1alpha1"
+	log "code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+	"code.cloudfoundry.org/quarks-utils/pkg/names"
+)
+
+// SetChangeWindow initializes the operator wide change window from a schedule
+// like '22:00-06:00' (UTC). Outside of it the BOSHDeployment, BPM, termination
+// and QuarksStatefulSet reconcilers don't write the objects of BOSHDeployments.
+// An empty schedule disables the window.
+func SetChangeWindow(schedule string) error {
+	return changewindow.Set(schedule)
+}
+
+// pendingWindow returns a result, which requeues the reconcile for when the
+// change window opens, if the objects of the BOSHDeployment must not be
+// written now
+func pendingWindow(ctx context.Context, bdpl *bdv1.BOSHDeployment, what string) (reconcile.Result, bool) {
+	requeueAfter := changewindow.Deferral(time.Now())
+	if requeueAfter == 0 {
+		return reconcile.Result{}, false
+	}
+
+	log.WithEvent(bdpl, "PendingWindow").Infof(ctx, "Deferring %s of BOSHDeployment '%s/%s' until change window '%s' opens in %s", what, bdpl.Namespace, bdpl.Name, changewindow.Current(), requeueAfter)
+	return reconcile.Result{RequeueAfter: requeueAfter}, true
+}
+
+// stageChanges records the objects, which would be written, in the status of
+// the BOSHDeployment and requeues the reconcile for when the window opens
+func (r *ReconcileBOSHDeployment) stageChanges(ctx context.Context, instance *bdv1.BOSHDeployment, secrets []qsv1a1.QuarksSecret, qJobs []*qjv1a1.QuarksJob, requeueAfter time.Duration) (reconcile.Result, error) {
+	pending := []string{
+		fmt.Sprintf("Secret/%s", r.secretNamer.DeploymentSecretName(names.DeploymentSecretTypeManifestWithOps, instance.Name, "")),
+	}
+	for _, s := range secrets {
+		pending = append(pending, fmt.Sprintf("QuarksSecret/%s", s.Name))
+	}
+	pending = append(pending, fmt.Sprintf("ConfigMap/%s", VariablesMappingConfigMapName(instance.Name)))
+	for _, qJob := range qJobs {
+		pending = append(pending, fmt.Sprintf("QuarksJob/%s", qJob.Name))
+	}
+
+	log.WithEvent(instance, "PendingWindow").Infof(ctx, "Staged %d changes for BOSHDeployment '%s/%s' until change window '%s' opens in %s", len(pending), instance.Namespace, instance.Name, changewindow.Current(), requeueAfter)
+
+	err := r.updateStatus(ctx, instance, func(bdpl *bdv1.BOSHDeployment) {
+		bdpl.Status.Phase = bdv1.PhasePendingWindow
+		bdpl.Status.PendingChanges = pending
+	})
+	if err != nil {
+		log.WithEvent(instance, "UpdateError").Errorf(ctx, "failed to update pending changes on bdpl '%s' (%v): %s", instance.Name, instance.ResourceVersion, err)
+	}
+
+	return reconcile.Result{RequeueAfter: requeueAfter}, nil
+}
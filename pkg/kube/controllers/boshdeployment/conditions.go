@@ -0,0 +1,178 @@
+package boshdeployment
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	log "code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+// Reasons of conditions, which are not set by an event
+const (
+	conditionReasonReconciling         = "Reconciling"
+	conditionReasonResolved            = "Resolved"
+	conditionReasonGeneratingVariables = "GeneratingVariables"
+	conditionReasonVariablesGenerated  = "VariablesGenerated"
+	conditionReasonJobApplied          = "Applied"
+	conditionReasonJobSkipped          = "SkipVariableInterpolation"
+)
+
+type conditionsKey struct{}
+
+// reconcileConditions are the conditions of a reconcile pass. They are
+// written with every status update of the pass, once a condition was set.
+type reconcileConditions struct {
+	mu         sync.Mutex
+	generation int64
+	conditions []bdv1.Condition
+	set        bool
+}
+
+// newConditionsContext returns a context, which tracks the conditions of a
+// reconcile pass of the BOSHDeployment. Conditions, which were true after
+// the previous pass, are reset to unknown, so they don't stay true, if this
+// pass fails before reaching them. Passes, which skip the deployment, e.g.
+// during a meltdown, don't set conditions and keep the previous ones.
+func newConditionsContext(ctx context.Context, instance *bdv1.BOSHDeployment) context.Context {
+	c := &reconcileConditions{generation: instance.Generation}
+	for _, condition := range instance.Status.Conditions {
+		if condition.Status == metav1.ConditionTrue {
+			condition.Status = metav1.ConditionUnknown
+			condition.Reason = conditionReasonReconciling
+			condition.Message = fmt.Sprintf("reconciling generation %d", instance.Generation)
+			condition.ObservedGeneration = instance.Generation
+		}
+		c.conditions = append(c.conditions, condition)
+	}
+	return context.WithValue(ctx, conditionsKey{}, c)
+}
+
+// setCondition sets the condition of the context's reconcile pass, it does
+// nothing, if the context doesn't track conditions
+func setCondition(ctx context.Context, conditionType string, status metav1.ConditionStatus, reason, message string) {
+	c, ok := ctx.Value(conditionsKey{}).(*reconcileConditions)
+	if !ok {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set = true
+	bdv1.SetStatusCondition(&c.conditions, bdv1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		ObservedGeneration: c.generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+// applyConditions sets the conditions of the context's reconcile pass in the
+// status of the BOSHDeployment. It returns false, if that didn't change them
+// or no condition was set yet.
+func applyConditions(ctx context.Context, bdpl *bdv1.BOSHDeployment) bool {
+	c, ok := ctx.Value(conditionsKey{}).(*reconcileConditions)
+	if !ok {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.set {
+		return false
+	}
+
+	changed := false
+	for _, condition := range c.conditions {
+		existing := bdv1.FindStatusCondition(bdpl.Status.Conditions, condition.Type)
+		if existing == nil ||
+			existing.Status != condition.Status ||
+			existing.Reason != condition.Reason ||
+			existing.Message != condition.Message ||
+			existing.ObservedGeneration != condition.ObservedGeneration {
+			changed = true
+		}
+		bdv1.SetStatusCondition(&bdpl.Status.Conditions, condition)
+	}
+	return changed
+}
+
+// persistConditions writes the conditions of the context's reconcile pass,
+// unless a status update of the pass already wrote them
+func (r *ReconcileBOSHDeployment) persistConditions(ctx context.Context, instance *bdv1.BOSHDeployment) {
+	if !applyConditions(ctx, instance.DeepCopy()) {
+		return
+	}
+
+	err := r.updateStatus(ctx, instance, func(*bdv1.BOSHDeployment) {})
+	if err != nil {
+		log.WithEvent(instance, "UpdateError").Errorf(ctx, "failed to update conditions of bdpl '%s' (%v): %s", instance.Name, instance.ResourceVersion, err)
+	}
+}
+
+// conditionEvent is a log event, whose errors also set a condition to false
+// with the event's reason and message
+type conditionEvent struct {
+	log.Event
+	conditionType string
+	reason        string
+}
+
+// withConditionEvent returns a log event for the object, like log.WithEvent,
+// which sets the condition, when it reports an error
+func withConditionEvent(object *bdv1.BOSHDeployment, conditionType, reason string) conditionEvent {
+	return conditionEvent{
+		Event:         log.WithEvent(object, reason),
+		conditionType: conditionType,
+		reason:        reason,
+	}
+}
+
+// Errorf logs and records the error, like log.Event.Errorf, and sets the condition to false
+func (ev conditionEvent) Errorf(ctx context.Context, format string, v ...interface{}) error {
+	err := ev.Event.Errorf(ctx, format, v...)
+	setCondition(ctx, ev.conditionType, metav1.ConditionFalse, ev.reason, err.Error())
+	return err
+}
+
+// Error logs and records the error, like log.Event.Error, and sets the condition to false
+func (ev conditionEvent) Error(ctx context.Context, parts ...interface{}) error {
+	err := ev.Event.Error(ctx, parts...)
+	setCondition(ctx, ev.conditionType, metav1.ConditionFalse, ev.reason, err.Error())
+	return err
+}
+
+// variablesGeneratedCondition returns the VariablesGenerated condition for
+// the variable generation progress in the status
+func variablesGeneratedCondition(status bdv1.BOSHDeploymentStatus, generation int64) bdv1.Condition {
+	condition := bdv1.Condition{
+		Type:               bdv1.ConditionVariablesGenerated,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             conditionReasonVariablesGenerated,
+		Message:            fmt.Sprintf("%d of %d variables generated", status.VariableGenerationComplete, status.VariableGenerationTotal),
+	}
+	if status.VariableGenerationComplete < status.VariableGenerationTotal {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = conditionReasonGeneratingVariables
+	}
+	return condition
+}
+
+// updateVariablesGeneratedCondition updates the VariablesGenerated condition
+// to the variable generation progress in the status. Conditions, which don't
+// report the progress, e.g. after a failed reconcile, are kept.
+func updateVariablesGeneratedCondition(bdpl *bdv1.BOSHDeployment) {
+	existing := bdv1.FindStatusCondition(bdpl.Status.Conditions, bdv1.ConditionVariablesGenerated)
+	if existing == nil {
+		return
+	}
+	if existing.Reason != conditionReasonGeneratingVariables && existing.Reason != conditionReasonVariablesGenerated {
+		return
+	}
+	bdv1.SetStatusCondition(&bdpl.Status.Conditions, variablesGeneratedCondition(bdpl.Status, existing.ObservedGeneration))
+}
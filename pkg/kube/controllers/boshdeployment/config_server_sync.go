@@ -0,0 +1,181 @@
+package boshdeployment
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"code.cloudfoundry.org/cf-operator/pkg/configserver"
+	"code.cloudfoundry.org/cf-operator/pkg/credhub"
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	qsv1a1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/quarkssecret/v1alpha1"
+	log "code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+// configServerSyncRequeueAfter is the delay before the sync is retried,
+// while variables are not generated yet or the Config Server failed
+const configServerSyncRequeueAfter = 10 * time.Second
+
+var (
+	// configServerEndpoint is the URL of the BOSH Config Server, empty disables the sync
+	configServerEndpoint string
+	// configServerClientSecret is the name of the secret containing the mTLS client certificate for the Config Server
+	configServerClientSecret string
+)
+
+// SetConfigServer initializes the package scoped Config Server variables. An
+// empty endpoint disables the sync of variable values to the Config Server.
+func SetConfigServer(endpoint string, clientSecret string) error {
+	if endpoint != "" && clientSecret == "" {
+		return errors.New("the Config Server client secret is required, if a Config Server endpoint is set")
+	}
+
+	configServerEndpoint = endpoint
+	configServerClientSecret = clientSecret
+	return nil
+}
+
+// NewConfigServerClientFunc returns a Config Server client, which authenticates with the client certificate in namespace
+type NewConfigServerClientFunc func(ctx context.Context, c crc.Client, namespace string) (configserver.Client, error)
+
+// NewConfigServerClient returns a client for the configured Config Server.
+// The client secret has the same keys as the one of CredHub.
+func NewConfigServerClient(ctx context.Context, c crc.Client, namespace string) (configserver.Client, error) {
+	secret := &corev1.Secret{}
+	err := c.Get(ctx, crc.ObjectKey{Name: configServerClientSecret, Namespace: namespace}, secret)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting Config Server client secret '%s'", configServerClientSecret)
+	}
+
+	tlsConfig, err := credhub.TLSConfig(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return configserver.NewClient(configServerEndpoint, tlsConfig), nil
+}
+
+// ConfigServerVariableName returns the Config Server name of a deployment's
+// variable. Absolute variable names are used as they are.
+func ConfigServerVariableName(deploymentName string, variableName string) string {
+	if strings.HasPrefix(variableName, "/") {
+		return variableName
+	}
+	return fmt.Sprintf("/%s/%s", deploymentName, variableName)
+}
+
+// configServerChecksums remembers the checksums of the values, which were put
+// to the Config Server, so unchanged values don't create new versions. They
+// are kept in memory, so the values are put again after an operator restart.
+type configServerChecksums struct {
+	sync.Mutex
+	checksums map[string]string
+}
+
+func newConfigServerChecksums() *configServerChecksums {
+	return &configServerChecksums{checksums: map[string]string{}}
+}
+
+func (c *configServerChecksums) synced(name string, checksum string) bool {
+	c.Lock()
+	defer c.Unlock()
+	return c.checksums[name] == checksum
+}
+
+func (c *configServerChecksums) set(name string, checksum string) {
+	c.Lock()
+	defer c.Unlock()
+	c.checksums[name] = checksum
+}
+
+// reconcileConfigServerSync puts the values of the deployment's variables to
+// the Config Server, once all of them are generated or provided by the user.
+// Variables synced from CredHub are left out. It returns a requeue delay, if
+// the sync has to be retried. Failures are reported as warning events, they
+// don't fail the reconcile.
+func (r *ReconcileBOSHDeployment) reconcileConfigServerSync(ctx context.Context, instance *bdv1.BOSHDeployment, secrets []qsv1a1.QuarksSecret) time.Duration {
+	if configServerEndpoint == "" || len(secrets) == 0 {
+		return 0
+	}
+
+	inventory := &CredentialInventory{client: r.client, namespace: instance.Namespace}
+	credentials, err := inventory.credentials(ctx, secrets)
+	if err != nil {
+		r.configServerSyncFailed(ctx, instance, err)
+		return configServerSyncRequeueAfter
+	}
+
+	for _, credential := range credentials {
+		if !credential.Generated && credential.Source != CredentialSourceExternal {
+			log.Debugf(ctx, "Waiting for variable '%s' of BOSHDeployment '%s/%s' to be generated before syncing to the Config Server", credential.Name, instance.Namespace, instance.Name)
+			return configServerSyncRequeueAfter
+		}
+	}
+
+	c, err := r.newConfigServerClient(ctx, r.client, instance.Namespace)
+	if err != nil {
+		r.configServerSyncFailed(ctx, instance, err)
+		return configServerSyncRequeueAfter
+	}
+
+	for _, credential := range credentials {
+		if credential.Source == CredentialSourceCredHub {
+			continue
+		}
+
+		secret := &corev1.Secret{}
+		err := r.client.Get(ctx, crc.ObjectKey{Name: credential.Secret, Namespace: instance.Namespace}, secret)
+		if err != nil {
+			r.configServerSyncFailed(ctx, instance, errors.Wrapf(err, "getting secret '%s'", credential.Secret))
+			return configServerSyncRequeueAfter
+		}
+
+		name := ConfigServerVariableName(instance.Name, credential.Name)
+		key := instance.Namespace + name
+		checksum := secretDataChecksum(secret.Data)
+		if r.configServerChecksums.synced(key, checksum) {
+			continue
+		}
+
+		err = c.Put(ctx, name, configserver.Value(secret.Data))
+		if err != nil {
+			r.configServerSyncFailed(ctx, instance, err)
+			return configServerSyncRequeueAfter
+		}
+		r.configServerChecksums.set(key, checksum)
+		log.Debugf(ctx, "Synced variable '%s' of BOSHDeployment '%s/%s' to the Config Server", name, instance.Namespace, instance.Name)
+	}
+
+	return 0
+}
+
+func (r *ReconcileBOSHDeployment) configServerSyncFailed(ctx context.Context, instance *bdv1.BOSHDeployment, err error) {
+	msg := fmt.Sprintf("failed to sync variables of BOSHDeployment '%s/%s' to the Config Server: %v", instance.Namespace, instance.Name, err)
+	log.WarningEvent(ctx, instance, "ConfigServerSyncError", msg)
+}
+
+// secretDataChecksum returns a checksum of the keys and values of a secret
+func secretDataChecksum(data map[string][]byte) string {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, k := range keys {
+		fmt.Fprintf(h, "%d:%s%d:", len(k), k, len(data[k]))
+		h.Write(data[k])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,157 @@
+package boshdeployment
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"code.cloudfoundry.org/cf-operator/pkg/bosh/converter"
+	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+	qsv1a1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/quarkssecret/v1alpha1"
+	"code.cloudfoundry.org/quarks-utils/pkg/names"
+)
+
+// CredentialSource tells where the value of a BOSH variable comes from
+type CredentialSource string
+
+// Valid values for credential sources
+const (
+	// CredentialSourceGenerated values are generated by the QuarksSecret controller
+	CredentialSourceGenerated CredentialSource = "generated"
+	// CredentialSourceExternal values are provided by a user created secret
+	CredentialSourceExternal CredentialSource = "external"
+	// CredentialSourceCredHub values are synced from a CredHub server
+	CredentialSourceCredHub CredentialSource = "credhub"
+)
+
+// Credential describes a BOSH variable of a deployment. It contains
+// metadata only, never the value.
+type Credential struct {
+	Name         string           `json:"name"`
+	Type         string           `json:"type"`
+	QuarksSecret string           `json:"quarksSecret"`
+	Secret       string           `json:"secret"`
+	Source       CredentialSource `json:"source"`
+	// Generated is false until the QuarksSecret controller generated the
+	// value, or after a rotation was triggered
+	Generated     bool         `json:"generated"`
+	LastGenerated *metav1.Time `json:"lastGenerated,omitempty"`
+}
+
+// CredentialInventory lists the credentials of BOSHDeployments, for audits
+// of the credentials a deployment uses
+type CredentialInventory struct {
+	client    crc.Client
+	converter VariablesConverter
+	namespace string
+}
+
+// NewCredentialInventory returns an inventory for the deployments of a namespace
+func NewCredentialInventory(client crc.Client, namespace string) *CredentialInventory {
+	return &CredentialInventory{
+		client:    client,
+		converter: converter.NewVariablesConverter(namespace, secretNamer),
+		namespace: namespace,
+	}
+}
+
+// List returns the credentials of the explicit variables of a deployment's
+// with-ops manifest, sorted like the manifest. Their status is read from the
+// live QuarksSecrets and the labels of their secrets.
+func (ci *CredentialInventory) List(ctx context.Context, deploymentName string) ([]Credential, error) {
+	manifest, err := ci.manifestWithOps(ctx, deploymentName)
+	if err != nil {
+		return nil, err
+	}
+
+	secrets, err := ci.converter.Variables(deploymentName, manifest.Variables)
+	if err != nil {
+		return nil, errors.Wrapf(err, "converting variables of deployment '%s'", deploymentName)
+	}
+
+	return ci.credentials(ctx, secrets)
+}
+
+// credentials returns the credentials of the variables' QuarksSecrets
+func (ci *CredentialInventory) credentials(ctx context.Context, secrets []qsv1a1.QuarksSecret) ([]Credential, error) {
+	credentials := make([]Credential, 0, len(secrets))
+	for _, qsec := range secrets {
+		credential := Credential{
+			Name:         qsec.GetLabels()[converter.LabelVariableName],
+			Type:         qsec.Spec.Type,
+			QuarksSecret: qsec.Name,
+			Secret:       qsec.Spec.SecretName,
+			Source:       CredentialSourceGenerated,
+		}
+
+		live := &qsv1a1.QuarksSecret{}
+		err := ci.client.Get(ctx, crc.ObjectKey{Name: qsec.Name, Namespace: ci.namespace}, live)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return nil, errors.Wrapf(err, "getting QuarksSecret '%s'", qsec.Name)
+		}
+		if err == nil && live.Status.Generated {
+			credential.Generated = true
+			credential.LastGenerated = live.Status.LastReconcile
+		}
+
+		if qsec.Spec.Type == qsv1a1.CredHub {
+			credential.Source = CredentialSourceCredHub
+		} else {
+			external, err := ci.userProvided(ctx, qsec.Spec.SecretName)
+			if err != nil {
+				return nil, err
+			}
+			if external {
+				credential.Source = CredentialSourceExternal
+			}
+		}
+
+		credentials = append(credentials, credential)
+	}
+
+	return credentials, nil
+}
+
+// userProvided returns true if the secret exists, but wasn't generated by
+// the QuarksSecret controller, which leaves such secrets untouched. Only the
+// labels of the secret are inspected.
+func (ci *CredentialInventory) userProvided(ctx context.Context, secretName string) (bool, error) {
+	secret := &corev1.Secret{}
+	err := ci.client.Get(ctx, crc.ObjectKey{Name: secretName, Namespace: ci.namespace}, secret)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, errors.Wrapf(err, "getting secret '%s'", secretName)
+	}
+
+	return secret.GetLabels()[qsv1a1.LabelKind] != qsv1a1.GeneratedSecretKind, nil
+}
+
+// manifestWithOps loads the with-ops manifest of a deployment, which
+// declares its variables, but contains none of their values
+func (ci *CredentialInventory) manifestWithOps(ctx context.Context, deploymentName string) (*bdm.Manifest, error) {
+	secretName := secretNamer.DeploymentSecretName(names.DeploymentSecretTypeManifestWithOps, deploymentName, "")
+	secret := &corev1.Secret{}
+	err := ci.client.Get(ctx, crc.ObjectKey{Name: secretName, Namespace: ci.namespace}, secret)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting with-ops manifest secret '%s'", secretName)
+	}
+
+	data, err := bdm.SecretData(secret.Data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading with-ops manifest secret '%s'", secretName)
+	}
+
+	manifest, err := bdm.LoadYAML(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "loading with-ops manifest of deployment '%s'", deploymentName)
+	}
+
+	return manifest, nil
+}
@@ -0,0 +1,150 @@
+package boshdeployment_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+
+	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+	qsv1a1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/quarkssecret/v1alpha1"
+	cfd "code.cloudfoundry.org/cf-operator/pkg/kube/controllers/boshdeployment"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/controllers/fakes"
+)
+
+var _ = Describe("CredentialInventory", func() {
+	var (
+		client        *fakes.FakeClient
+		manifest      string
+		quarksSecrets map[string]qsv1a1.QuarksSecret
+		secrets       map[string]corev1.Secret
+		generatedAt   metav1.Time
+	)
+
+	BeforeEach(func() {
+		manifest = `---
+name: foo
+variables:
+- name: adminpass
+  type: password
+- name: user_key
+  type: rsa
+- name: vault_token
+  type: credhub
+  options:
+    credhub_path: /vault/token
+`
+		generatedAt = metav1.NewTime(time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC))
+		quarksSecrets = map[string]qsv1a1.QuarksSecret{
+			"foo.var-adminpass": {Status: qsv1a1.QuarksSecretStatus{Generated: true, LastReconcile: &generatedAt}},
+		}
+		secrets = map[string]corev1.Secret{
+			"foo.var-adminpass": {
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{qsv1a1.LabelKind: qsv1a1.GeneratedSecretKind}},
+				Data:       map[string][]byte{"password": []byte("secret-value")},
+			},
+			"foo.var-user-key": {
+				Data: map[string][]byte{"private_key": []byte("secret-value")},
+			},
+		}
+
+		client = &fakes.FakeClient{}
+		client.GetCalls(func(_ context.Context, nn types.NamespacedName, object runtime.Object) error {
+			switch object := object.(type) {
+			case *qsv1a1.QuarksSecret:
+				qsec, ok := quarksSecrets[nn.Name]
+				if !ok {
+					return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+				}
+				qsec.DeepCopyInto(object)
+			case *corev1.Secret:
+				if nn.Name == "foo.with-ops" {
+					object.Data = map[string][]byte{bdm.DesiredManifestKeyName: []byte(manifest)}
+					return nil
+				}
+				secret, ok := secrets[nn.Name]
+				if !ok {
+					return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+				}
+				secret.DeepCopyInto(object)
+			}
+			return nil
+		})
+	})
+
+	It("lists the metadata of the deployment's variables", func() {
+		credentials, err := cfd.NewCredentialInventory(client, "default").List(context.Background(), "foo")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(credentials).To(Equal([]cfd.Credential{
+			{
+				Name:          "adminpass",
+				Type:          "password",
+				QuarksSecret:  "foo.var-adminpass",
+				Secret:        "foo.var-adminpass",
+				Source:        cfd.CredentialSourceGenerated,
+				Generated:     true,
+				LastGenerated: &generatedAt,
+			},
+			{
+				Name:         "user_key",
+				Type:         "rsa",
+				QuarksSecret: "foo.var-user-key",
+				Secret:       "foo.var-user-key",
+				Source:       cfd.CredentialSourceExternal,
+			},
+			{
+				Name:         "vault_token",
+				Type:         "credhub",
+				QuarksSecret: "foo.var-vault-token",
+				Secret:       "foo.var-vault-token",
+				Source:       cfd.CredentialSourceCredHub,
+			},
+		}))
+	})
+
+	It("reads a compressed with-ops manifest", func() {
+		compressed, err := bdm.Compress([]byte(manifest))
+		Expect(err).ToNot(HaveOccurred())
+		get := client.GetStub
+		client.GetCalls(func(ctx context.Context, nn types.NamespacedName, object runtime.Object) error {
+			if secret, ok := object.(*corev1.Secret); ok && nn.Name == "foo.with-ops" {
+				secret.Data = map[string][]byte{bdm.CompressedManifestKeyName: compressed}
+				return nil
+			}
+			return get(ctx, nn, object)
+		})
+
+		credentials, err := cfd.NewCredentialInventory(client, "default").List(context.Background(), "foo")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(credentials).To(HaveLen(3))
+	})
+
+	It("fails if the with-ops manifest doesn't exist", func() {
+		client.GetReturns(apierrors.NewNotFound(schema.GroupResource{}, "foo.with-ops"))
+
+		_, err := cfd.NewCredentialInventory(client, "default").List(context.Background(), "foo")
+		Expect(err).To(MatchError(ContainSubstring("getting with-ops manifest secret 'foo.with-ops'")))
+	})
+
+	It("fails if a QuarksSecret can't be read", func() {
+		get := client.GetStub
+		client.GetCalls(func(ctx context.Context, nn types.NamespacedName, object runtime.Object) error {
+			if _, ok := object.(*qsv1a1.QuarksSecret); ok {
+				return errors.New("fake-error")
+			}
+			return get(ctx, nn, object)
+		})
+
+		_, err := cfd.NewCredentialInventory(client, "default").List(context.Background(), "foo")
+		Expect(err).To(MatchError(ContainSubstring("getting QuarksSecret 'foo.var-adminpass'")))
+	})
+})
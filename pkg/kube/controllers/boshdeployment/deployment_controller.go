@@ -9,7 +9,7 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
-	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"k8s.io/client-go/kubernetes"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -22,8 +22,11 @@ import (
 	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
 	"code.cloudfoundry.org/cf-operator/pkg/bosh/qjobs"
 	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/backpressure"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/util/boshdns"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/priorityqueue"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/util/reference"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/watchdog"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/util/withops"
 	"code.cloudfoundry.org/quarks-utils/pkg/config"
 	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
@@ -34,6 +37,17 @@ import (
 // finally produce the "desired manifest", the instance group manifests and the BPM configs.
 func AddDeployment(ctx context.Context, config *config.Config, mgr manager.Manager) error {
 	ctx = ctxlog.NewContextWithRecorder(ctx, "boshdeployment-reconciler", mgr.GetEventRecorderFor("boshdeployment-recorder"))
+
+	kclient, err := kubernetes.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		return errors.Wrap(err, "failed retrieving kubernetes client configuration")
+	}
+
+	staging, err := NewStagingValidator(mgr)
+	if err != nil {
+		return errors.Wrap(err, "failed to configure the staging cluster")
+	}
+
 	r := NewDeploymentReconciler(
 		ctx, config, mgr,
 		withops.NewResolver(
@@ -42,16 +56,31 @@ func AddDeployment(ctx context.Context, config *config.Config, mgr manager.Manag
 			func(deploymentName string, m bdm.Manifest) (withops.DomainNameService, error) {
 				return boshdns.NewDNS(deploymentName, m)
 			},
+			secretNamer,
 		),
-		qjobs.NewJobFactory(config.Namespace),
-		converter.NewVariablesConverter(config.Namespace),
+		qjobs.NewJobFactory(config.Namespace, secretNamer),
+		converter.NewVariablesConverter(config.Namespace, secretNamer),
+		NewPodLogs(kclient),
+		staging,
+		secretNamer,
+		NewConfigServerClient,
+		NewExternalLinkPublisher,
 		controllerutil.SetControllerReference,
 	)
+	watchedSecrets := r.(*ReconcileBOSHDeployment).watchedSecretsIndex
 
 	// Create a new controller
-	c, err := controller.New("boshdeployment-controller", mgr, controller.Options{
-		Reconciler:              r,
+	// Reconcile deployments with a higher priority annotation first
+	c, err := priorityqueue.NewController(ctx, "boshdeployment-controller", mgr, priorityqueue.Options{
+		Reconciler:              watchdog.NewReconciler(ctx, backpressure.NewReconciler(ctx, r), &bdv1.BOSHDeployment{}),
 		MaxConcurrentReconciles: config.MaxBoshDeploymentWorkers,
+		Priority: func(request reconcile.Request) int {
+			bdpl := &bdv1.BOSHDeployment{}
+			if err := mgr.GetClient().Get(ctx, request.NamespacedName, bdpl); err != nil {
+				return bdv1.DefaultPriority
+			}
+			return bdpl.Priority()
+		},
 	})
 	if err != nil {
 		return errors.Wrap(err, "Adding Bosh deployment controller to manager failed.")
@@ -71,7 +100,11 @@ func AddDeployment(ctx context.Context, config *config.Config, mgr manager.Manag
 		UpdateFunc: func(e event.UpdateEvent) bool {
 			o := e.ObjectOld.(*bdv1.BOSHDeployment)
 			n := e.ObjectNew.(*bdv1.BOSHDeployment)
-			if !reflect.DeepEqual(o.Spec, n.Spec) {
+			suspendedChanged := o.GetAnnotations()[bdv1.AnnotationSuspendedInstanceGroups] != n.GetAnnotations()[bdv1.AnnotationSuspendedInstanceGroups]
+			resumed := !o.ResumeRequested() && n.ResumeRequested()
+			deleting := o.DeletionTimestamp == nil && n.DeletionTimestamp != nil
+			minVersionChanged := o.MinOperatorVersion() != n.MinOperatorVersion()
+			if !reflect.DeepEqual(o.Spec, n.Spec) || suspendedChanged || resumed || deleting || minVersionChanged {
 				ctxlog.NewPredicateEvent(e.ObjectNew).Debug(
 					ctx, e.MetaNew, "bdv1.BOSHDeployment",
 					fmt.Sprintf("Update predicate passed for '%s'", e.MetaNew.GetName()),
@@ -122,17 +155,15 @@ func AddDeployment(ctx context.Context, config *config.Config, mgr manager.Manag
 		return errors.Wrapf(err, "Watching configmaps failed in bosh deployment controller.")
 	}
 
-	// Watch Secrets referenced by the BOSHDeployment
+	// Watch Secrets referenced by the BOSHDeployment. The index of watched
+	// secrets is populated by the reconciler, so no deployments have to be
+	// listed and resolved for each event.
 	secretPredicates := predicate.Funcs{
 		CreateFunc: func(e event.CreateEvent) bool {
 			secret := e.Object.(*corev1.Secret)
-			reconciles, err := reference.GetReconciles(ctx, mgr.GetClient(), reference.ReconcileForBOSHDeployment, secret, false)
-			if err != nil {
-				ctxlog.Errorf(ctx, "Failed to calculate reconciles for secret '%s': %v", secret.Name, err)
-			}
 
 			// The Secret should reference at least one BOSHDeployment in order for us to consider it
-			return len(reconciles) > 1
+			return len(watchedSecrets.reconciles(secret)) > 0
 		},
 		DeleteFunc:  func(e event.DeleteEvent) bool { return false },
 		GenericFunc: func(e event.GenericEvent) bool { return false },
@@ -147,25 +178,65 @@ func AddDeployment(ctx context.Context, config *config.Config, mgr manager.Manag
 		ToRequests: handler.ToRequestsFunc(func(a handler.MapObject) []reconcile.Request {
 			secret := a.Object.(*corev1.Secret)
 
-			if reference.SkipReconciles(ctx, mgr.GetClient(), secret) {
-				return []reconcile.Request{}
+			reconciles := watchedSecrets.reconciles(secret)
+			for _, reconciliation := range reconciles {
+				ctxlog.NewMappingEvent(a.Object).Debug(ctx, reconciliation, "BOSHDeployment", a.Meta.GetName(), bdv1.SecretReference)
+			}
+
+			return reconciles
+		}),
+	}, secretPredicates)
+	if err != nil {
+		return errors.Wrapf(err, "Watching secrets failed in bosh deployment controller.")
+
+	}
+
+	// Watch external Secrets selected by the BOSHDeployment's external secret selector
+	externalSecretPredicates := predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			secret := e.Object.(*corev1.Secret)
+			reconciles, err := reference.GetExternalSecretReconciles(ctx, mgr.GetClient(), secret)
+			if err != nil {
+				ctxlog.Errorf(ctx, "Failed to calculate external secret reconciles for secret '%s': %v", secret.Name, err)
+			}
+
+			return len(reconciles) > 0
+		},
+		DeleteFunc:  func(e event.DeleteEvent) bool { return false },
+		GenericFunc: func(e event.GenericEvent) bool { return false },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldSecret := e.ObjectOld.(*corev1.Secret)
+			newSecret := e.ObjectNew.(*corev1.Secret)
+			if reflect.DeepEqual(oldSecret.Data, newSecret.Data) {
+				return false
 			}
 
-			reconciles, err := reference.GetReconciles(ctx, mgr.GetClient(), reference.ReconcileForBOSHDeployment, secret, false)
+			reconciles, err := reference.GetExternalSecretReconciles(ctx, mgr.GetClient(), newSecret)
 			if err != nil {
-				ctxlog.Errorf(ctx, "Failed to calculate reconciles for secret '%s': %v", secret.Name, err)
+				ctxlog.Errorf(ctx, "Failed to calculate external secret reconciles for secret '%s': %v", newSecret.Name, err)
+			}
+
+			return len(reconciles) > 0
+		},
+	}
+	err = c.Watch(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(a handler.MapObject) []reconcile.Request {
+			secret := a.Object.(*corev1.Secret)
+
+			reconciles, err := reference.GetExternalSecretReconciles(ctx, mgr.GetClient(), secret)
+			if err != nil {
+				ctxlog.Errorf(ctx, "Failed to calculate external secret reconciles for secret '%s': %v", secret.Name, err)
 			}
 
 			for _, reconciliation := range reconciles {
-				ctxlog.NewMappingEvent(a.Object).Debug(ctx, reconciliation, "BOSHDeployment", a.Meta.GetName(), bdv1.SecretReference)
+				ctxlog.NewMappingEvent(a.Object).Debug(ctx, reconciliation, "BOSHDeployment", a.Meta.GetName(), "ExternalSecret")
 			}
 
 			return reconciles
 		}),
-	}, secretPredicates)
+	}, externalSecretPredicates)
 	if err != nil {
-		return errors.Wrapf(err, "Watching secrets failed in bosh deployment controller.")
-
+		return errors.Wrapf(err, "Watching external secrets failed in bosh deployment controller.")
 	}
 
 	// Watch Services that route (select) pods that are external link providers
@@ -207,5 +278,38 @@ func AddDeployment(ctx context.Context, config *config.Config, mgr manager.Manag
 
 	}
 
+	// Watch pods of link providers, whose removal starts the drain of their consumers
+	linkPodPredicates := predicate.Funcs{
+		CreateFunc:  func(e event.CreateEvent) bool { return false },
+		DeleteFunc:  func(e event.DeleteEvent) bool { return false },
+		GenericFunc: func(e event.GenericEvent) bool { return false },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			o := e.ObjectOld.(*corev1.Pod)
+			n := e.ObjectNew.(*corev1.Pod)
+
+			return !linkDrainStarted(o) && linkDrainStarted(n)
+		},
+	}
+	err = c.Watch(&source.Kind{Type: &corev1.Pod{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(a handler.MapObject) []reconcile.Request {
+			pod := a.Object.(*corev1.Pod)
+			reconciles := linkConsumerRequests(pod)
+			for _, reconciliation := range reconciles {
+				ctxlog.NewMappingEvent(a.Object).Debug(ctx, reconciliation, "BOSHDeployment", a.Meta.GetName(), "PodOfLinkProvider")
+			}
+
+			return reconciles
+		}),
+	}, linkPodPredicates)
+	if err != nil {
+		return errors.Wrapf(err, "watching link provider pods failed in bosh deployment controller.")
+	}
+
+	// Watch ServiceImports of link providers, which are resolved via their cluster set DNS name
+	err = watchServiceImports(ctx, mgr, c)
+	if err != nil {
+		return errors.Wrapf(err, "watching service imports failed in bosh deployment controller.")
+	}
+
 	return nil
 }
@@ -4,14 +4,17 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	crc "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -22,8 +25,14 @@ import (
 	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
 	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
 	qsv1a1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/quarkssecret/v1alpha1"
+	qstsv1a1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/quarksstatefulset/v1alpha1"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/audit"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/util/boshdns"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/changewindow"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/correlation"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/util/mutate"
+	bdnames "code.cloudfoundry.org/cf-operator/pkg/kube/util/names"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/withops"
 	qjv1a1 "code.cloudfoundry.org/quarks-job/pkg/kube/apis/quarksjob/v1alpha1"
 	"code.cloudfoundry.org/quarks-utils/pkg/config"
 	log "code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
@@ -33,18 +42,21 @@ import (
 
 // JobFactory creates Jobs for a given manifest
 type JobFactory interface {
-	VariableInterpolationJob(deploymentName string, manifest bdm.Manifest) (*qjv1a1.QuarksJob, error)
-	InstanceGroupManifestJob(deploymentName string, manifest bdm.Manifest, linkInfos converter.LinkInfos, initialRollout bool) (*qjv1a1.QuarksJob, error)
+	VariableInterpolationJob(deploymentName string, manifest bdm.Manifest, debug bool, dns *bdv1.JobDNS, triggerSecret string) (*qjv1a1.QuarksJob, error)
+	InstanceGroupManifestJob(deploymentName string, manifest bdm.Manifest, linkInfos converter.LinkInfos, initialRollout bool, dns *bdv1.JobDNS) (*qjv1a1.QuarksJob, error)
 }
 
 // VariablesConverter converts BOSH variables into QuarksSecrets
 type VariablesConverter interface {
 	Variables(manifestName string, variables []bdm.Variable) ([]qsv1a1.QuarksSecret, error)
+	VariableOrder(variables []bdm.Variable) ([]bdm.Variable, error)
 }
 
 // WithOps interpolates BOSH manifests and operations files to create the WithOps manifest
 type WithOps interface {
 	Manifest(instance *bdv1.BOSHDeployment, namespace string) (*bdm.Manifest, []string, error)
+	NoOpOps(instance *bdv1.BOSHDeployment, namespace string) ([]string, error)
+	CloudConfig(instance *bdv1.BOSHDeployment, namespace string) (*bdm.CloudConfig, error)
 }
 
 // Check that ReconcileBOSHDeployment implements the reconcile.Reconciler interface
@@ -53,17 +65,27 @@ var _ reconcile.Reconciler = &ReconcileBOSHDeployment{}
 type setReferenceFunc func(owner, object metav1.Object, scheme *runtime.Scheme) error
 
 // NewDeploymentReconciler returns a new reconcile.Reconciler
-func NewDeploymentReconciler(ctx context.Context, config *config.Config, mgr manager.Manager, withops WithOps, jobFactory JobFactory, converter VariablesConverter, srf setReferenceFunc) reconcile.Reconciler {
+func NewDeploymentReconciler(ctx context.Context, config *config.Config, mgr manager.Manager, withops WithOps, jobFactory JobFactory, converter VariablesConverter, podLogs PodLogs, staging StagingValidator, secretNamer bdnames.SecretNamer, newConfigServerClient NewConfigServerClientFunc, newExternalLinkPublisher NewExternalLinkPublisherFunc, srf setReferenceFunc) reconcile.Reconciler {
 
 	return &ReconcileBOSHDeployment{
 		ctx:          ctx,
 		config:       config,
-		client:       mgr.GetClient(),
+		client:       audit.NewClient(mgr.GetClient()),
 		scheme:       mgr.GetScheme(),
 		withops:      withops,
 		setReference: srf,
 		jobFactory:   jobFactory,
 		converter:    converter,
+		podLogs:      podLogs,
+		staging:      staging,
+		secretNamer:  secretNamer,
+
+		newConfigServerClient:    newConfigServerClient,
+		newExternalLinkPublisher: newExternalLinkPublisher,
+		configServerChecksums:    newConfigServerChecksums(),
+		qJobConflicts:            newQJobConflicts(),
+		ignoredFailures:          newIgnoredFailures(),
+		watchedSecretsIndex:      newWatchedSecretsIndex(),
 	}
 }
 
@@ -77,6 +99,19 @@ type ReconcileBOSHDeployment struct {
 	setReference setReferenceFunc
 	jobFactory   JobFactory
 	converter    VariablesConverter
+	podLogs      PodLogs
+	staging      StagingValidator
+	secretNamer  bdnames.SecretNamer
+
+	newConfigServerClient    NewConfigServerClientFunc
+	newExternalLinkPublisher NewExternalLinkPublisherFunc
+	configServerChecksums    *configServerChecksums
+	qJobConflicts            *qJobConflicts
+	ignoredFailures          *ignoredFailures
+
+	// watchedSecretsIndex maps secrets to the deployments, which are
+	// reconciled when they change
+	watchedSecretsIndex *watchedSecretsIndex
 }
 
 // Reconcile starts the deployment process for a BOSHDeployment and deploys QuarksJobs to generate required properties for instance groups and rendered BPM
@@ -87,6 +122,8 @@ func (r *ReconcileBOSHDeployment) Reconcile(request reconcile.Request) (reconcil
 	// Set the ctx to be Background, as the top-level context for incoming requests.
 	ctx, cancel := context.WithTimeout(r.ctx, r.config.CtxTimeOut)
 	defer cancel()
+	ctx = correlation.NewContext(ctx)
+	ctx = audit.NewContext(ctx, request.NamespacedName)
 
 	log.Infof(ctx, "Reconciling BOSHDeployment %s", request.NamespacedName)
 	err := r.client.Get(ctx, request.NamespacedName, instance)
@@ -96,113 +133,435 @@ func (r *ReconcileBOSHDeployment) Reconcile(request reconcile.Request) (reconcil
 			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
 			// Return and don't requeue
 			log.Debug(ctx, "Skip reconcile: BOSHDeployment not found")
+			r.watchedSecretsIndex.remove(request.NamespacedName)
 			return reconcile.Result{}, nil
 		}
 
 		return reconcile.Result{},
 			log.WithEvent(instance, "GetBOSHDeploymentError").Errorf(ctx, "failed to get BOSHDeployment '%s': %v", request.NamespacedName, err)
 	}
+	ctx = withLogLevel(ctx, instance)
+	r.reportDeprecatedAPIVersions(ctx, instance)
 
-	if meltdown.NewWindow(r.config.MeltdownDuration, instance.Status.LastReconcile).Contains(time.Now()) {
-		log.WithEvent(instance, "Meltdown").Debugf(ctx, "Resource '%s' is in meltdown, requeue reconcile after %s", instance.Name, r.config.MeltdownRequeueAfter)
-		return reconcile.Result{RequeueAfter: r.config.MeltdownRequeueAfter}, nil
+	// Deleted deployments are only finalized
+	deleted, err := r.reconcileManifestRetention(ctx, instance)
+	if err != nil {
+		return reconcile.Result{},
+			log.WithEvent(instance, "ManifestRetentionError").Errorf(ctx, "failed to reconcile manifest retention of BOSHDeployment '%s': %v", request.NamespacedName, err)
+	}
+	if deleted {
+		log.Debugf(ctx, "Skip reconcile: BOSHDeployment '%s' is being deleted", request.NamespacedName)
+		return reconcile.Result{}, nil
+	}
+
+	// Deployments halted by their failure policy wait for the resume annotation
+	halted, err := r.halted(ctx, instance)
+	if err != nil {
+		return reconcile.Result{},
+			log.WithEvent(instance, "ResumeError").Errorf(ctx, "failed to resume BOSHDeployment '%s': %v", request.NamespacedName, err)
+	}
+	if halted {
+		log.Debugf(ctx, "Skip reconcile: BOSHDeployment '%s' is halted by its failure policy", request.NamespacedName)
+		return reconcile.Result{}, nil
+	}
+
+	// Deployments requiring a newer operator are left unchanged
+	if !r.verifyManifestCompatibility(ctx, instance) {
+		return reconcile.Result{Requeue: false}, nil
+	}
+
+	// Track the conditions of this pass, they are written with each status update
+	ctx = newConditionsContext(ctx, instance)
+	defer r.persistConditions(ctx, instance)
+
+	result, err := r.reconcileDeployment(ctx, request, instance)
+	return r.applyFailurePolicy(ctx, instance, result, err)
+}
+
+// reconcileDeployment renders the BOSHDeployment
+func (r *ReconcileBOSHDeployment) reconcileDeployment(ctx context.Context, request reconcile.Request, instance *bdv1.BOSHDeployment) (reconcile.Result, error) {
+	profile := environmentProfile(instance)
+	meltdownDuration, meltdownRequeueAfter := profile.meltdown(r.config)
+	if meltdown.NewWindow(meltdownDuration, instance.Status.LastReconcile).Contains(time.Now()) {
+		log.WithEvent(instance, "Meltdown").Debugf(ctx, "Resource '%s' is in meltdown, requeue reconcile after %s", instance.Name, meltdownRequeueAfter)
+		return reconcile.Result{RequeueAfter: meltdownRequeueAfter}, nil
 	}
 
 	// Resolve the manifest with ops
-	manifest, err := r.resolveManifest(ctx, instance)
+	manifest, implicitVars, err := r.resolveManifest(ctx, instance)
+	r.watchSecrets(request.NamespacedName, instance, implicitVars, err)
+	if secretName, ok := withops.MissingSecret(err); ok {
+		return r.waitForSecret(ctx, instance, secretName, err)
+	}
 	if err != nil {
 		return reconcile.Result{},
 			log.WithEvent(instance, "WithOpsManifestError").Errorf(ctx, "failed to get with-ops manifest for BOSHDeployment '%s': %v", request.NamespacedName, err)
 	}
+	r.reportNoOpOpsFiles(ctx, instance)
 
 	// Get link infos containing provider name and its secret name
-	linkInfos, err := r.listLinkInfos(instance, manifest)
+	linkInfos, linkDrainRequeue, err := r.listLinkInfos(ctx, instance, manifest)
+	if isPodIPPending(err) {
+		log.WithEvent(instance, "LinkPodIPPending").Infof(ctx, "Requeue reconcile of BOSHDeployment '%s' after %s: %v", request.NamespacedName, podIPRequeueAfter, err)
+		return reconcile.Result{RequeueAfter: podIPRequeueAfter}, nil
+	}
+	if isLinkPortConflict(err) {
+		return reconcile.Result{},
+			withConditionEvent(instance, bdv1.ConditionManifestResolved, "LinkPortConflict").Errorf(ctx, "failed to resolve links for BOSHDeployment '%s': %v", request.NamespacedName, err)
+	}
+	if isLinkTypeMismatch(err) {
+		return reconcile.Result{},
+			withConditionEvent(instance, bdv1.ConditionManifestResolved, "LinkTypeMismatch").Errorf(ctx, "failed to resolve links for BOSHDeployment '%s': %v", request.NamespacedName, err)
+	}
 	if err != nil {
 		return reconcile.Result{},
-			log.WithEvent(instance, "InstanceGroupManifestError").Errorf(ctx, "failed to list quarks-link secrets for BOSHDeployment '%s': %v", request.NamespacedName, err)
+			withConditionEvent(instance, bdv1.ConditionManifestResolved, "InstanceGroupManifestError").Errorf(ctx, "failed to list quarks-link secrets for BOSHDeployment '%s': %v", request.NamespacedName, err)
 	}
 
-	// Apply the "with-ops" manifest secret
-	log.Debug(ctx, "Creating with-ops manifest secret")
-	manifestSecret, err := r.createManifestWithOps(ctx, instance, *manifest)
+	// Verify the storage of the instance groups before anything is rolled out
+	err = r.validateStorageClasses(ctx, manifest)
+	if isMissingStorageClass(err) {
+		return reconcile.Result{},
+			withConditionEvent(instance, bdv1.ConditionManifestResolved, "MissingStorageClass").Errorf(ctx, "failed to verify storage classes of BOSHDeployment '%s': %v", request.NamespacedName, err)
+	}
+	if err != nil {
+		return reconcile.Result{},
+			withConditionEvent(instance, bdv1.ConditionManifestResolved, "StorageClassError").Errorf(ctx, "failed to verify storage classes of BOSHDeployment '%s': %v", request.NamespacedName, err)
+	}
+
+	// The variable interpolation reads the manifest of the trigger secret instead of the with-ops manifest, if one is set
+	interpolationManifest, triggerSHA1, err := triggerManifest(ctx, r.client, instance)
+	if secretName, ok := withops.MissingSecret(err); ok {
+		return r.waitForSecret(ctx, instance, secretName, err)
+	}
 	if err != nil {
 		return reconcile.Result{},
-			log.WithEvent(instance, "WithOpsManifestError").Errorf(ctx, "failed to create with-ops manifest secret for BOSHDeployment '%s': %v", request.NamespacedName, err)
+			withConditionEvent(instance, bdv1.ConditionManifestResolved, "TriggerManifestError").Errorf(ctx, "failed to get variable interpolation trigger manifest for BOSHDeployment '%s': %v", request.NamespacedName, err)
 	}
+	if interpolationManifest == nil {
+		interpolationManifest = manifest
+	}
+
+	setCondition(ctx, bdv1.ConditionManifestResolved, metav1.ConditionTrue, conditionReasonResolved, "the manifest with ops files, cloud config and links is resolved")
 
-	// Create all QuarksSecret variables
+	// Build all QuarksSecret variables
 	log.Debug(ctx, "Converting BOSH manifest variables to QuarksSecret resources")
-	secrets, err := r.converter.Variables(instance.Name, manifest.Variables)
+	secrets, err := r.converter.Variables(instance.Name, interpolationManifest.Variables)
 	if err != nil {
 		return reconcile.Result{},
-			log.WithEvent(instance, "BadManifestError").Error(ctx, errors.Wrap(err, "failed to generate quarks secrets from manifest"))
+			withConditionEvent(instance, bdv1.ConditionVariablesGenerated, "BadManifestError").Error(ctx, errors.Wrap(err, "failed to generate quarks secrets from manifest"))
+
+	}
+
+	// Order the variables, so CAs are created before the certificates they sign
+	variableOrder, err := r.converter.VariableOrder(interpolationManifest.Variables)
+	if err != nil {
+		return reconcile.Result{},
+			withConditionEvent(instance, bdv1.ConditionVariablesGenerated, "BadManifestError").Error(ctx, errors.Wrap(err, "failed to order the manifest variables"))
+	}
+
+	// Verify the variable references before the interpolation replaces missing keys with empty values.
+	// Secrets managed outside of the operator may not be complete yet.
+	if instance.Spec.ExternalSecretSelector == nil {
+		missing, err := r.missingSecretReferences(ctx, instance, interpolationManifest)
+		if err != nil {
+			return reconcile.Result{},
+				withConditionEvent(instance, bdv1.ConditionVariablesGenerated, "MissingSecretReference").Errorf(ctx, "failed to verify secret references of BOSHDeployment '%s': %v", request.NamespacedName, err)
+		}
+		if len(missing) > 0 && profile.LenientValidation {
+			log.WithEvent(instance, "MissingSecretReference").Infof(ctx, "BOSHDeployment '%s' references missing secret keys: %s", request.NamespacedName, strings.Join(missing, ", "))
+		} else if len(missing) > 0 {
+			return reconcile.Result{},
+				withConditionEvent(instance, bdv1.ConditionVariablesGenerated, "MissingSecretReference").Errorf(ctx, "BOSHDeployment '%s' references missing secret keys: %s", request.NamespacedName, strings.Join(missing, ", "))
+		}
+	}
+
+	// Build the "Variable Interpolation" QuarksJob, which creates the desired manifest secret
+	dmQJob, err := r.jobFactory.VariableInterpolationJob(instance.Name, *interpolationManifest, instance.Spec.ManifestDebugMode, instance.Spec.JobDNS, instance.Spec.VariableInterpolationTriggerSecret)
+	if err != nil {
+		return reconcile.Result{}, withConditionEvent(instance, bdv1.ConditionVariableInterpolationJobReady, "DesiredManifestError").Errorf(ctx, "failed to build the desired manifest qJob: %v", err)
+	}
+	setTriggerManifestSHA1(dmQJob, triggerSHA1)
+
+	// Suspended instance groups keep their current instance group manifests and BPM configs
+	igManifest, suspended := withoutInstanceGroups(*manifest, instance.SuspendedInstanceGroups())
+	if len(suspended) > 0 {
+		log.Infof(ctx, "Skipping suspended instance groups of BOSHDeployment '%s': %s", request.NamespacedName, strings.Join(suspended, ", "))
+	}
+
+	// Ignored instance groups are left out of the reconciliation, so no QuarksJobs are created for them
+	igManifest, ignored := withoutInstanceGroups(igManifest, instance.Spec.IgnoredInstanceGroups)
+	for _, name := range ignored {
+		log.WithEvent(instance, "InstanceGroupIgnored").Infof(ctx, "Ignoring instance group '%s' of BOSHDeployment '%s'", name, request.NamespacedName)
+	}
+
+	// Build the "Instance group manifest" QuarksJob, which creates instance group manifests (ig-resolved) secrets and BPM config secrets
+	// once the "Variable Interpolation" job created the desired manifest.
+	igQJob, err := r.jobFactory.InstanceGroupManifestJob(instance.Name, igManifest, linkInfos, instance.ObjectMeta.Generation == 1, instance.Spec.JobDNS)
+	if err != nil {
+		return reconcile.Result{},
+			withConditionEvent(instance, bdv1.ConditionInstanceGroupManifestJobReady, "InstanceGroupManifestError").Errorf(ctx, "failed to build instance group manifest qJob: %v", err)
+	}
 
+	// Outside of the change window only record the intended changes
+	now := time.Now()
+	if requeueAfter := changewindow.Deferral(now); requeueAfter > 0 {
+		return r.stageChanges(ctx, instance, secrets, []*qjv1a1.QuarksJob{dmQJob, igQJob}, requeueAfter)
+	}
+
+	// Build the "with-ops" manifest secret
+	manifestSecret, err := r.manifestWithOpsSecret(ctx, instance, *manifest)
+	if err != nil {
+		return reconcile.Result{},
+			withConditionEvent(instance, bdv1.ConditionManifestResolved, "WithOpsManifestError").Errorf(ctx, "failed to create with-ops manifest secret for BOSHDeployment '%s': %v", request.NamespacedName, err)
+	}
+
+	// Jobs violating the namespace's Pod Security Standard would be rejected at admission
+	violations, err := podSecurityViolations(ctx, r.client, instance.Namespace, dmQJob, igQJob)
+	if err != nil {
+		return reconcile.Result{},
+			withConditionEvent(instance, bdv1.ConditionVariableInterpolationJobReady, "PodSecurityViolation").Errorf(ctx, "failed to check pod security of BOSHDeployment '%s': %v", request.NamespacedName, err)
+	}
+	if len(violations) > 0 {
+		return reconcile.Result{},
+			withConditionEvent(instance, bdv1.ConditionVariableInterpolationJobReady, "PodSecurityViolation").Errorf(ctx, "QuarksJobs of BOSHDeployment '%s' violate the pod security standard of namespace '%s': %s", request.NamespacedName, instance.Namespace, strings.Join(violations, "; "))
+	}
+
+	// Dry-run all changes on the staging cluster first
+	if instance.Spec.ValidateOnStaging {
+		if r.staging == nil {
+			return r.markDegraded(ctx, instance, errors.New("no staging cluster configured"))
+		}
+
+		objects := []runtime.Object{manifestSecret}
+		for i := range secrets {
+			objects = append(objects, &secrets[i])
+		}
+		objects = append(objects, dmQJob, igQJob)
+
+		log.Debugf(ctx, "Validating %d objects of BOSHDeployment '%s' on the staging cluster", len(objects), request.NamespacedName)
+		if err := r.staging.DryRun(ctx, objects); err != nil {
+			return r.markDegraded(ctx, instance, err)
+		}
+	}
+
+	// Stop the variable interpolation of a superseded manifest
+	wait, err := r.cleanupStaleVariableInterpolationJob(ctx, instance, dmQJob, manifestSecret)
+	if err != nil {
+		return reconcile.Result{},
+			withConditionEvent(instance, bdv1.ConditionVariableInterpolationJobReady, "DesiredManifestError").Errorf(ctx, "failed to clean up stale desired manifest qJob for BOSHDeployment '%s': %v", request.NamespacedName, err)
+	}
+	if wait {
+		return reconcile.Result{RequeueAfter: staleJobRequeueAfter}, nil
+	}
+
+	// Recover from a variable interpolation job, which doesn't finish
+	stop, result, err := r.checkInterpolationTimeout(ctx, instance, dmQJob)
+	if err != nil {
+		return result, err
+	}
+	if stop {
+		return result, nil
+	}
+
+	// Changes, which only affect the BPM rendering, reuse the existing desired manifest
+	bpmOnly, err := r.bpmOnlyChange(ctx, instance, manifestSecret, dmQJob, igQJob)
+	if err != nil {
+		return reconcile.Result{},
+			withConditionEvent(instance, bdv1.ConditionVariableInterpolationJobReady, "DesiredManifestError").Errorf(ctx, "failed to detect changes of BOSHDeployment '%s': %v", request.NamespacedName, err)
+	}
+
+	// Apply the "with-ops" manifest secret
+	log.Debug(ctx, "Creating with-ops manifest secret")
+	err = r.createManifestWithOps(ctx, instance, manifestSecret)
+	if err != nil {
+		return reconcile.Result{},
+			withConditionEvent(instance, bdv1.ConditionManifestResolved, "WithOpsManifestError").Errorf(ctx, "failed to create with-ops manifest secret for BOSHDeployment '%s': %v", request.NamespacedName, err)
 	}
 
 	// Create/update all explicit BOSH Variables
 	if len(secrets) > 0 {
-		err = r.createQuarksSecrets(ctx, manifestSecret, secrets)
+		err = r.createQuarksSecrets(ctx, manifestSecret, secrets, variableOrder)
 		if err != nil {
 			return reconcile.Result{},
-				log.WithEvent(instance, "VariableGenerationError").Errorf(ctx, "failed to create quarks secrets for BOSH manifest '%s': %v", instance.Name, err)
+				withConditionEvent(instance, bdv1.ConditionVariablesGenerated, "VariableGenerationError").Errorf(ctx, "failed to create quarks secrets for BOSH manifest '%s': %v", instance.Name, err)
 		}
 	}
 
-	// Apply the "Variable Interpolation" QuarksJob, which creates the desired manifest secret
-	qJob, err := r.jobFactory.VariableInterpolationJob(instance.Name, *manifest)
+	// Publish which secret holds which BOSH variable
+	err = r.createVariablesMapping(ctx, instance, secrets)
 	if err != nil {
-		return reconcile.Result{}, log.WithEvent(instance, "DesiredManifestError").Errorf(ctx, "failed to build the desired manifest qJob: %v", err)
+		return reconcile.Result{},
+			withConditionEvent(instance, bdv1.ConditionVariablesGenerated, "VariablesMappingError").Errorf(ctx, "failed to create variables mapping for BOSHDeployment '%s': %v", request.NamespacedName, err)
 	}
 
-	log.Debug(ctx, "Creating desired manifest QuarksJob")
-	err = r.createQuarksJob(ctx, instance, qJob)
+	err = r.reconcileGrafanaDashboard(ctx, instance)
 	if err != nil {
 		return reconcile.Result{},
-			log.WithEvent(instance, "DesiredManifestError").Errorf(ctx, "failed to create desired manifest qJob for BOSHDeployment '%s': %v", request.NamespacedName, err)
+			log.WithEvent(instance, "GrafanaDashboardError").Errorf(ctx, "failed to reconcile Grafana dashboard for BOSHDeployment '%s': %v", request.NamespacedName, err)
 	}
 
-	// Apply the "Instance group manifest" QuarksJob, which creates instance group manifests (ig-resolved) secrets and BPM config secrets
-	// once the "Variable Interpolation" job created the desired manifest.
-	qJob, err = r.jobFactory.InstanceGroupManifestJob(instance.Name, *manifest, linkInfos, instance.ObjectMeta.Generation == 1)
+	dmQJobOp := controllerutil.OperationResultNone
+	if bpmOnly {
+		log.WithEvent(instance, "SkipVariableInterpolation").Infof(ctx, "Only BPM inputs of BOSHDeployment '%s' changed, reusing the desired manifest", request.NamespacedName)
+		setCondition(ctx, bdv1.ConditionVariableInterpolationJobReady, metav1.ConditionTrue, conditionReasonJobSkipped, "the existing desired manifest is reused")
+	} else {
+		log.Debug(ctx, "Creating desired manifest QuarksJob")
+		dmQJobOp, err = r.createQuarksJob(ctx, instance, dmQJob)
+		if err != nil {
+			if result, ok := r.requeueOnQJobConflict(ctx, instance, err); ok {
+				return result, nil
+			}
+			return reconcile.Result{},
+				withConditionEvent(instance, bdv1.ConditionVariableInterpolationJobReady, "DesiredManifestError").Errorf(ctx, "failed to create desired manifest qJob for BOSHDeployment '%s': %v", request.NamespacedName, err)
+		}
+		setCondition(ctx, bdv1.ConditionVariableInterpolationJobReady, metav1.ConditionTrue, conditionReasonJobApplied, fmt.Sprintf("QuarksJob '%s' is %s", dmQJob.Name, dmQJobOp))
+	}
+
+	log.Debug(ctx, "Creating instance group manifest QuarksJob")
+	_, err = r.createQuarksJob(ctx, instance, igQJob)
 	if err != nil {
+		if result, ok := r.requeueOnQJobConflict(ctx, instance, err); ok {
+			return result, nil
+		}
 		return reconcile.Result{},
-			log.WithEvent(instance, "InstanceGroupManifestError").Errorf(ctx, "failed to build instance group manifest qJob: %v", err)
+			withConditionEvent(instance, bdv1.ConditionInstanceGroupManifestJobReady, "InstanceGroupManifestError").Errorf(ctx, "failed to create instance group manifest qJob for BOSHDeployment '%s': %v", request.NamespacedName, err)
+	}
+	if bpmOnly {
+		err = r.triggerQuarksJob(ctx, igQJob)
+		if err != nil {
+			if result, ok := r.requeueOnQJobConflict(ctx, instance, err); ok {
+				return result, nil
+			}
+			return reconcile.Result{},
+				withConditionEvent(instance, bdv1.ConditionInstanceGroupManifestJobReady, "InstanceGroupManifestError").Errorf(ctx, "failed to trigger instance group manifest qJob for BOSHDeployment '%s': %v", request.NamespacedName, err)
+		}
 	}
+	setCondition(ctx, bdv1.ConditionInstanceGroupManifestJobReady, metav1.ConditionTrue, conditionReasonJobApplied, fmt.Sprintf("QuarksJob '%s' is applied", igQJob.Name))
+	r.qJobConflicts.reset(request.NamespacedName)
 
-	log.Debug(ctx, "Creating instance group manifest QuarksJob")
-	err = r.createQuarksJob(ctx, instance, qJob)
+	// A new desired manifest starts a new rollout, a rollout stopped by the
+	// graceful upgrade timeout stays failed until then
+	rollout := bpmOnly || dmQJobOp != controllerutil.OperationResultNone
+
+	// Count the generated variables, the variable generation controller
+	// keeps counting, while the QuarksSecrets are generated
+	generated, err := countGeneratedVariables(ctx, r.client, instance)
 	if err != nil {
 		return reconcile.Result{},
-			log.WithEvent(instance, "InstanceGroupManifestError").Errorf(ctx, "failed to create instance group manifest qJob for BOSHDeployment '%s': %v", request.NamespacedName, err)
+			withConditionEvent(instance, bdv1.ConditionVariablesGenerated, "VariableGenerationError").Errorf(ctx, "failed to count generated variables of BOSHDeployment '%s': %v", request.NamespacedName, err)
 	}
 
-	// Update status of bdpl with the timestamp of the last reconcile
-	now := metav1.Now()
-	instance.Status.LastReconcile = &now
+	status := bdv1.BOSHDeploymentStatus{VariableGenerationTotal: len(secrets), VariableGenerationComplete: generated}
+	condition := variablesGeneratedCondition(status, instance.Generation)
+	setCondition(ctx, condition.Type, condition.Status, condition.Reason, condition.Message)
 
-	err = r.client.Status().Update(ctx, instance)
+	// Update status of bdpl with the timestamp of the last reconcile
+	lastReconcile := metav1.NewTime(now)
+	err = r.updateStatus(ctx, instance, func(bdpl *bdv1.BOSHDeployment) {
+		bdpl.Status.LastReconcile = &lastReconcile
+		if rollout || bdpl.Status.UpdateStartedAt == nil {
+			bdpl.Status.UpdateStartedAt = &lastReconcile
+		}
+		bdpl.Status.VariableGenerationTotal = len(secrets)
+		bdpl.Status.VariableGenerationComplete = generated
+		if rollout || bdpl.Status.Phase != bdv1.PhaseUpdateFailed {
+			bdpl.Status.Phase = bdv1.PhaseApplied
+			bdpl.Status.LastError = ""
+		}
+		bdpl.Status.Phase = variableGenerationPhase(bdpl.Status)
+		bdpl.Status.PendingChanges = nil
+		bdpl.Status.StagingError = ""
+		bdpl.Status.WaitingForSecret = ""
+		bdpl.Status.SuspendedInstanceGroups = suspended
+	})
 	if err != nil {
 		log.WithEvent(instance, "UpdateError").Errorf(ctx, "failed to update reconcile timestamp on bdpl '%s' (%v): %s", instance.Name, instance.ResourceVersion, err)
-		return reconcile.Result{Requeue: false}, nil
+		return reconcile.Result{Requeue: true}, nil
+	}
+
+	// Check the variable interpolation job for the interpolation timeout again
+	requeueAfter, err := r.interpolationTimeoutRequeue(ctx, dmQJob, dmQJobOp)
+	if err != nil {
+		return reconcile.Result{},
+			withConditionEvent(instance, bdv1.ConditionVariableInterpolationJobReady, "DesiredManifestError").Errorf(ctx, "failed to check desired manifest qJob for BOSHDeployment '%s': %v", request.NamespacedName, err)
 	}
 
-	return reconcile.Result{}, nil
+	// Poll git references for upstream changes
+	requeueAfter = earliestRequeue(requeueAfter, gitPollRequeue(instance.Spec))
+
+	// Push the variable values to the BOSH Config Server, once they are generated
+	requeueAfter = earliestRequeue(requeueAfter, r.reconcileConfigServerSync(ctx, instance, secrets))
+
+	// Drain the removed link provider pods, once their drain period has passed
+	requeueAfter = earliestRequeue(requeueAfter, linkDrainRequeue)
+
+	// Follow the output of the variable interpolation job in debug mode, until it finished
+	if instance.Spec.ManifestDebugMode {
+		debugRequeue, err := r.captureInterpolationDebug(ctx, instance, dmQJobOp != controllerutil.OperationResultNone)
+		if err != nil {
+			return reconcile.Result{},
+				log.WithEvent(instance, "InterpolationDebugError").Errorf(ctx, "failed to capture variable interpolation output for BOSHDeployment '%s': %v", request.NamespacedName, err)
+		}
+		requeueAfter = earliestRequeue(requeueAfter, debugRequeue)
+	}
+
+	return reconcile.Result{RequeueAfter: requeueAfter}, nil
 }
 
 // resolveManifest resolves manifest with ops manifest
-func (r *ReconcileBOSHDeployment) resolveManifest(ctx context.Context, instance *bdv1.BOSHDeployment) (*bdm.Manifest, error) {
+func (r *ReconcileBOSHDeployment) resolveManifest(ctx context.Context, instance *bdv1.BOSHDeployment) (*bdm.Manifest, []string, error) {
 	log.Debug(ctx, "Resolving manifest")
-	manifest, _, err := r.withops.Manifest(instance, instance.GetNamespace())
+	manifest, implicitVars, err := r.withops.Manifest(instance, instance.GetNamespace())
+	if _, ok := withops.MissingSecret(err); ok {
+		return nil, nil, err
+	}
 	if err != nil {
-		return nil, log.WithEvent(instance, "WithOpsManifestError").Errorf(ctx, "Error resolving the manifest %s: %s", instance.GetName(), err)
+		reason := "WithOpsManifestError"
+		if withops.IsManifestTooDeep(err) {
+			reason = "ManifestTooDeep"
+		}
+		if withops.IsUnsignedOps(err) {
+			reason = "UnsignedOps"
+		}
+		return nil, nil, withConditionEvent(instance, bdv1.ConditionManifestResolved, reason).Errorf(ctx, "Error resolving the manifest %s: %s", instance.GetName(), err)
+	}
+
+	manifest.ApplyFeatureFlags()
+	for _, feature := range manifest.UnsupportedFeatures() {
+		msg := fmt.Sprintf("Manifest feature '%s' of BOSHDeployment '%s/%s' is not supported and ignored", feature, instance.Namespace, instance.Name)
+		log.Info(ctx, msg)
+		log.WarningEvent(ctx, instance, "UnsupportedManifestFeature", msg)
+	}
+
+	err = applyCloudConfig(r.withops, instance, manifest)
+	if _, ok := withops.MissingSecret(err); ok {
+		return nil, nil, err
+	}
+	if err != nil {
+		return nil, nil, withConditionEvent(instance, bdv1.ConditionManifestResolved, "CloudConfigError").Errorf(ctx, "Error applying the cloud config to the manifest %s: %s", instance.GetName(), err)
 	}
 
-	return manifest, nil
+	return manifest, implicitVars, nil
 }
 
-// createManifestWithOps creates a secret containing the deployment manifest with ops files applied
-func (r *ReconcileBOSHDeployment) createManifestWithOps(ctx context.Context, instance *bdv1.BOSHDeployment, manifest bdm.Manifest) (*corev1.Secret, error) {
-	log.Debug(ctx, "Creating manifest secret with ops")
+// applyCloudConfig resolves the cloud config, which the BOSHDeployment
+// references, and applies it to the manifest
+func applyCloudConfig(resolver WithOps, instance *bdv1.BOSHDeployment, manifest *bdm.Manifest) error {
+	if instance.Spec.CloudConfig == nil {
+		return nil
+	}
+
+	cloudConfig, err := resolver.CloudConfig(instance, instance.GetNamespace())
+	if err != nil {
+		return errors.Wrap(err, "resolving the cloud config")
+	}
+
+	return manifest.ApplyCloudConfig(*cloudConfig)
+}
+
+// manifestWithOpsSecret builds a secret containing the deployment manifest with ops files applied
+func (r *ReconcileBOSHDeployment) manifestWithOpsSecret(ctx context.Context, instance *bdv1.BOSHDeployment, manifest bdm.Manifest) (*corev1.Secret, error) {
 
 	// Create manifest with ops, which will be used as a base for variable interpolation in desired manifest job input.
 	manifestBytes, err := manifest.Marshal()
@@ -210,7 +569,7 @@ func (r *ReconcileBOSHDeployment) createManifestWithOps(ctx context.Context, ins
 		return nil, log.WithEvent(instance, "ManifestWithOpsMarshalError").Errorf(ctx, "Error marshaling the manifest %s: %s", instance.GetName(), err)
 	}
 
-	manifestSecretName := names.DeploymentSecretName(names.DeploymentSecretTypeManifestWithOps, instance.Name, "")
+	manifestSecretName := r.secretNamer.DeploymentSecretName(names.DeploymentSecretTypeManifestWithOps, instance.Name, "")
 
 	// Create a secret object for the manifest
 	manifestSecret := &corev1.Secret{
@@ -222,9 +581,15 @@ func (r *ReconcileBOSHDeployment) createManifestWithOps(ctx context.Context, ins
 				bdv1.LabelDeploymentSecretType: names.DeploymentSecretTypeManifestWithOps.String(),
 			},
 		},
-		StringData: map[string]string{
-			"manifest.yaml": string(manifestBytes),
-		},
+	}
+	if instance.Spec.CompressManifest {
+		compressed, err := bdm.Compress(manifestBytes)
+		if err != nil {
+			return nil, log.WithEvent(instance, "ManifestWithOpsMarshalError").Errorf(ctx, "Error compressing the manifest %s: %s", instance.GetName(), err)
+		}
+		manifestSecret.Data = map[string][]byte{bdm.CompressedManifestKeyName: compressed}
+	} else {
+		manifestSecret.StringData = map[string]string{bdm.DesiredManifestKeyName: string(manifestBytes)}
 	}
 
 	// Set ownership reference
@@ -232,43 +597,77 @@ func (r *ReconcileBOSHDeployment) createManifestWithOps(ctx context.Context, ins
 		return nil, log.WithEvent(instance, "ManifestWithOpsRefError").Errorf(ctx, "failed to set ownerReference for Secret '%s': %v", manifestSecretName, err)
 	}
 
-	// Apply the secret
-	op, err := controllerutil.CreateOrUpdate(ctx, r.client, manifestSecret, mutate.SecretMutateFn(manifestSecret))
+	return manifestSecret, nil
+}
+
+// createManifestWithOps applies the "with-ops" manifest secret
+func (r *ReconcileBOSHDeployment) createManifestWithOps(ctx context.Context, instance *bdv1.BOSHDeployment, manifestSecret *corev1.Secret) error {
+	log.Debug(ctx, "Creating manifest secret with ops")
+
+	op, err := controllerutil.CreateOrUpdate(ctx, r.client, manifestSecret, withOwnerReference(ctx, instance, manifestSecret, r.setReference, r.scheme, mutate.SecretMutateFn(manifestSecret)))
 	if err != nil {
-		return nil, log.WithEvent(instance, "ManifestWithOpsApplyError").Errorf(ctx, "failed to apply Secret '%s': %v", manifestSecretName, err)
+		return log.WithEvent(instance, "ManifestWithOpsApplyError").Errorf(ctx, "failed to apply Secret '%s': %v", manifestSecret.Name, err)
 	}
 
 	log.Debugf(ctx, "ResourceReference secret '%s' has been %s", manifestSecret.Name, op)
 
-	return manifestSecret, nil
+	return nil
 }
 
 // createQuarksJob creates a QuarksJob and sets its ownership
-func (r *ReconcileBOSHDeployment) createQuarksJob(ctx context.Context, instance *bdv1.BOSHDeployment, qJob *qjv1a1.QuarksJob) error {
+func (r *ReconcileBOSHDeployment) createQuarksJob(ctx context.Context, instance *bdv1.BOSHDeployment, qJob *qjv1a1.QuarksJob) (controllerutil.OperationResult, error) {
 	if err := r.setReference(instance, qJob, r.scheme); err != nil {
-		return errors.Errorf("failed to set ownerReference for QuarksJob '%s': %v", qJob.GetName(), err)
+		return controllerutil.OperationResultNone, errors.Errorf("failed to set ownerReference for QuarksJob '%s': %v", qJob.GetName(), err)
 	}
 
-	op, err := controllerutil.CreateOrUpdate(ctx, r.client, qJob, mutate.QuarksJobMutateFn(qJob))
+	mutateFn := withOwnerReference(ctx, instance, qJob, r.setReference, r.scheme, mutate.QuarksJobMutateFn(qJob))
+	op, err := controllerutil.CreateOrUpdate(ctx, r.client, qJob, func() error {
+		existing := qJob.DeepCopy()
+		if err := mutateFn(); err != nil {
+			return err
+		}
+
+		// Record which reconcile changed the job, the provenance controller copies this onto its versioned output secrets
+		if qJob.ResourceVersion == "" || !equality.Semantic.DeepEqual(existing, qJob) {
+			annotateProvenance(ctx, instance, &qJob.ObjectMeta)
+		}
+		return nil
+	})
 	if err != nil {
-		return errors.Wrapf(err, "creating or updating QuarksJob '%s'", qJob.Name)
+		return op, errors.Wrapf(err, "creating or updating QuarksJob '%s'", qJob.Name)
 	}
 
 	log.Debugf(ctx, "QuarksJob '%s' has been %s", qJob.Name, op)
 
-	return err
+	return op, err
 }
 
 // listLinkInfos returns a LinkInfos containing link providers if needed
-// and updates `quarks_links` properties
-func (r *ReconcileBOSHDeployment) listLinkInfos(instance *bdv1.BOSHDeployment, manifest *bdm.Manifest) (converter.LinkInfos, error) {
+// and updates `quarks_links` properties. While link provider pods are
+// drained, it returns the delay until the next of them is drained.
+func (r *ReconcileBOSHDeployment) listLinkInfos(ctx context.Context, instance *bdv1.BOSHDeployment, manifest *bdm.Manifest) (converter.LinkInfos, time.Duration, error) {
 	linkInfos := converter.LinkInfos{}
 
+	if conflicts := manifest.ListLinkPortConflicts(); len(conflicts) != 0 {
+		return linkInfos, 0, &linkPortConflictError{conflicts: conflicts}
+	}
+
 	// find all missing providers in the manifest, so we can look for secrets
 	missingProviders := manifest.ListMissingProviders()
 
 	// quarksLinks store for missing provider names with types read from secrets
 	quarksLinks := map[string]bdm.QuarksLink{}
+	// providerNames maps the link secrets to the names of their providers
+	providerNames := map[string]string{}
+	// properties maps the link secrets to their link properties
+	properties := map[string]map[string]interface{}{}
+	// drainRequeue is the delay until the next link provider pod is drained
+	var drainRequeue time.Duration
+	// addressFormat of the link instances, the spec overrides the manifest's features
+	addressFormat := bdm.AddressFormat(instance.Spec.LinkAddressFormat)
+	if addressFormat == "" {
+		addressFormat = manifest.LinkAddressFormat
+	}
 	if len(missingProviders) != 0 {
 		// list secrets and services from target deployment
 		secrets := &corev1.SecretList{}
@@ -276,7 +675,7 @@ func (r *ReconcileBOSHDeployment) listLinkInfos(instance *bdv1.BOSHDeployment, m
 			crc.InNamespace(instance.Namespace),
 		)
 		if err != nil {
-			return linkInfos, errors.Wrapf(err, "listing secrets for link in deployment '%s':", instance.Name)
+			return linkInfos, 0, errors.Wrapf(err, "listing secrets for link in deployment '%s':", instance.Name)
 		}
 
 		services := &corev1.ServiceList{}
@@ -284,18 +683,23 @@ func (r *ReconcileBOSHDeployment) listLinkInfos(instance *bdv1.BOSHDeployment, m
 			crc.InNamespace(instance.Namespace),
 		)
 		if err != nil {
-			return linkInfos, errors.Wrapf(err, "listing services for link in deployment '%s':", instance.Name)
+			return linkInfos, 0, errors.Wrapf(err, "listing services for link in deployment '%s':", instance.Name)
 		}
 
 		for _, s := range secrets.Items {
 			if deploymentName, ok := s.GetAnnotations()[bdv1.LabelDeploymentName]; ok && deploymentName == instance.Name {
 				linkProvider, err := newLinkProvider(s.GetAnnotations())
 				if err != nil {
-					return linkInfos, errors.Wrapf(err, "failed to parse link JSON for  '%s'", instance.Name)
+					return linkInfos, 0, errors.Wrapf(err, "failed to parse link JSON for  '%s'", instance.Name)
 				}
 				if dup, ok := missingProviders[linkProvider.Name]; ok {
 					if dup {
-						return linkInfos, errors.New(fmt.Sprintf("duplicated secrets of provider: %s", linkProvider.Name))
+						return linkInfos, 0, errors.New(fmt.Sprintf("duplicated secrets of provider: %s", linkProvider.Name))
+					}
+
+					err = r.validateCrossDeploymentLinks(manifest, linkProvider)
+					if err != nil {
+						return linkInfos, 0, err
 					}
 
 					linkInfos = append(linkInfos, converter.LinkInfo{
@@ -308,36 +712,93 @@ func (r *ReconcileBOSHDeployment) listLinkInfos(instance *bdv1.BOSHDeployment, m
 						quarksLinks[s.Name] = bdm.QuarksLink{
 							Type: linkProvider.ProviderType,
 						}
+						providerNames[s.Name] = linkProvider.Name
+						properties[s.Name] = linkProperties(s.Data)
 					}
 					missingProviders[linkProvider.Name] = true
 				}
 			}
 		}
 
-		serviceRecords, err := r.getServiceRecords(instance.Namespace, instance.Name, services.Items)
+		imported := map[string]bool{}
+		if instance.Spec.Features.ReconcileServiceExports {
+			imported, err = r.listServiceImports(ctx, instance.Namespace)
+			if err != nil {
+				return linkInfos, 0, err
+			}
+		}
+
+		serviceRecords, err := r.getServiceRecords(instance.Namespace, instance.Name, services.Items, imported)
 		if err != nil {
-			return linkInfos, errors.Wrapf(err, "failed to get link services for '%s'", instance.Name)
+			return linkInfos, 0, errors.Wrapf(err, "failed to get link services for '%s'", instance.Name)
 		}
 
+		pendingPods := []string{}
+		nodeZones := map[string]string{}
 		for qName := range quarksLinks {
 			if svcRecord, ok := serviceRecords[qName]; ok {
 				pods, err := r.listPodsFromSelector(instance.Namespace, svcRecord.selector)
 				if err != nil {
-					return linkInfos, errors.Wrapf(err, "Failed to get link pods for '%s'", instance.Name)
+					return linkInfos, 0, errors.Wrapf(err, "Failed to get link pods for '%s'", instance.Name)
 				}
+				r.checkLinkNetworkPolicies(ctx, instance, manifest, providerNames[qName], svcRecord.ports, pods)
 
 				var jobsInstances []bdm.JobInstance
-				for i, p := range pods {
+				for _, p := range pods {
+					// Removed pods are kept as draining instances for the link drain period
+					draining := false
+					pod := p
+					if linkDrainStarted(&pod) {
+						remaining, err := r.drainLinkPod(ctx, &pod, time.Now())
+						if err != nil {
+							return linkInfos, 0, err
+						}
+						if remaining <= 0 {
+							continue
+						}
+						draining = true
+						drainRequeue = earliestRequeue(drainRequeue, remaining)
+					} else if p.DeletionTimestamp == nil && linkDrainPeriod > 0 {
+						if err := r.protectLinkPod(ctx, instance, &pod); err != nil {
+							return linkInfos, 0, err
+						}
+					}
+
 					if len(p.Status.PodIP) == 0 {
-						return linkInfos, fmt.Errorf("empty ip of kube native component: '%s/%s'", p.Namespace, p.Name)
+						switch emptyPodIPPolicy {
+						case EmptyPodIPSkip:
+							log.Infof(ctx, "Skipping kube native component '%s/%s' for link '%s': empty ip", p.Namespace, p.Name, qName)
+							continue
+						case EmptyPodIPWait:
+							log.Infof(ctx, "Waiting for ip of kube native component '%s/%s' for link '%s'", p.Namespace, p.Name, qName)
+							pendingPods = append(pendingPods, fmt.Sprintf("%s/%s", p.Namespace, p.Name))
+							continue
+						default:
+							return linkInfos, 0, fmt.Errorf("empty ip of kube native component: '%s/%s'", p.Namespace, p.Name)
+						}
 					}
-					jobsInstances = append(jobsInstances, bdm.JobInstance{
-						Name:      qName,
-						ID:        string(p.GetUID()),
-						Index:     i,
-						Address:   p.Status.PodIP,
-						Bootstrap: i == 0,
-					})
+					az := ""
+					if svcRecord.zones {
+						az, err = r.nodeZone(ctx, p.Spec.NodeName, nodeZones)
+						if err != nil {
+							return linkInfos, 0, errors.Wrapf(err, "Failed to get zone of link pod '%s/%s'", p.Namespace, p.Name)
+						}
+					}
+					i := len(jobsInstances)
+					fqdn, serviceDNS := podDNSNames(p, svcRecord.dnsRecord)
+					jobInstance := bdm.JobInstance{
+						Name:       qName,
+						ID:         string(p.GetUID()),
+						Index:      i,
+						Address:    p.Status.PodIP,
+						AZ:         az,
+						Bootstrap:  i == 0,
+						FQDN:       fqdn,
+						ServiceDNS: serviceDNS,
+						Draining:   draining,
+					}
+					jobInstance.Address = jobInstance.ToAddressString(addressFormat)
+					jobsInstances = append(jobsInstances, jobInstance)
 				}
 
 				quarksLinks[qName] = bdm.QuarksLink{
@@ -348,6 +809,10 @@ func (r *ReconcileBOSHDeployment) listLinkInfos(instance *bdv1.BOSHDeployment, m
 			}
 
 		}
+
+		if len(pendingPods) != 0 {
+			return linkInfos, 0, &podIPPendingError{pods: pendingPods}
+		}
 	}
 
 	missingPs := make([]string, 0, len(missingProviders))
@@ -358,9 +823,11 @@ func (r *ReconcileBOSHDeployment) listLinkInfos(instance *bdv1.BOSHDeployment, m
 	}
 
 	if len(missingPs) != 0 {
-		return linkInfos, errors.New(fmt.Sprintf("missing link secrets for providers: %s", strings.Join(missingPs, ", ")))
+		return linkInfos, 0, errors.New(fmt.Sprintf("missing link secrets for providers: %s", strings.Join(missingPs, ", ")))
 	}
 
+	r.publishExternalLinks(ctx, instance, quarksLinks, providerNames, properties)
+
 	if len(quarksLinks) != 0 {
 		if manifest.Properties == nil {
 			manifest.Properties = map[string]interface{}{}
@@ -368,11 +835,33 @@ func (r *ReconcileBOSHDeployment) listLinkInfos(instance *bdv1.BOSHDeployment, m
 		manifest.Properties["quarks_links"] = quarksLinks
 	}
 
-	return linkInfos, nil
+	return linkInfos, drainRequeue, nil
 }
 
-// getServiceRecords gets service records from Kube Services
-func (r *ReconcileBOSHDeployment) getServiceRecords(namespace string, name string, svcs []corev1.Service) (map[string]serviceRecord, error) {
+// validateCrossDeploymentLinks checks that the jobs consuming the link of
+// another deployment expect the type, which its provider declares. Types,
+// which are not declared on either side, match any type.
+func (r *ReconcileBOSHDeployment) validateCrossDeploymentLinks(manifest *bdm.Manifest, provider linkProvider) error {
+	if provider.ProviderType == "" {
+		return nil
+	}
+
+	for _, consumer := range manifest.ListLinkConsumers(provider.Name) {
+		if consumer.Type != "" && consumer.Type != provider.ProviderType {
+			return &linkTypeMismatchError{
+				provider:     provider.Name,
+				providerType: provider.ProviderType,
+				consumer:     consumer,
+			}
+		}
+	}
+	return nil
+}
+
+// getServiceRecords gets service records from Kube Services. Services, which
+// are imported into the deployment's namespace, use the DNS name of the
+// cluster set instead.
+func (r *ReconcileBOSHDeployment) getServiceRecords(namespace string, name string, svcs []corev1.Service, imported map[string]bool) (map[string]serviceRecord, error) {
 	svcRecords := map[string]serviceRecord{}
 	for _, svc := range svcs {
 		if deploymentName, ok := svc.GetAnnotations()[bdv1.LabelDeploymentName]; ok && deploymentName == name {
@@ -382,9 +871,15 @@ func (r *ReconcileBOSHDeployment) getServiceRecords(namespace string, name strin
 					return svcRecords, errors.New(fmt.Sprintf("duplicated services of provider: %s", providerName))
 				}
 
+				dnsRecord := fmt.Sprintf("%s.%s.svc.%s", svc.Name, namespace, boshdns.GetClusterDomain())
+				if imported[svc.Name] {
+					dnsRecord = clusterSetDNSName(svc.Name, namespace)
+				}
 				svcRecords[providerName] = serviceRecord{
 					selector:  svc.Spec.Selector,
-					dnsRecord: fmt.Sprintf("%s.%s.svc.%s", svc.Name, namespace, boshdns.GetClusterDomain()),
+					dnsRecord: dnsRecord,
+					zones:     svc.GetAnnotations()[bdv1.AnnotationLinkProviderZones] == "true",
+					ports:     svc.Spec.Ports,
 				}
 			}
 		}
@@ -393,6 +888,27 @@ func (r *ReconcileBOSHDeployment) getServiceRecords(namespace string, name strin
 	return svcRecords, nil
 }
 
+// nodeZone returns the topology zone label of a node. Zones are cached in
+// nodeZones, as the pods of a link usually share nodes.
+func (r *ReconcileBOSHDeployment) nodeZone(ctx context.Context, nodeName string, nodeZones map[string]string) (string, error) {
+	if nodeName == "" {
+		return "", nil
+	}
+	if zone, ok := nodeZones[nodeName]; ok {
+		return zone, nil
+	}
+
+	node := &corev1.Node{}
+	err := r.client.Get(ctx, crc.ObjectKey{Name: nodeName}, node)
+	if err != nil {
+		return "", errors.Wrapf(err, "getting node '%s'", nodeName)
+	}
+
+	zone := node.GetLabels()[qstsv1a1.DefaultZoneNodeLabel]
+	nodeZones[nodeName] = zone
+	return zone, nil
+}
+
 // listPodsFromSelector lists pods from the selector
 func (r *ReconcileBOSHDeployment) listPodsFromSelector(namespace string, selector map[string]string) ([]corev1.Pod, error) {
 	podList := &corev1.PodList{}
@@ -411,41 +927,120 @@ func (r *ReconcileBOSHDeployment) listPodsFromSelector(namespace string, selecto
 	return podList.Items, nil
 }
 
-// createQuarksSecrets create variables quarksSecrets
-func (r *ReconcileBOSHDeployment) createQuarksSecrets(ctx context.Context, manifestSecret *corev1.Secret, variables []qsv1a1.QuarksSecret) error {
-	for _, variable := range variables {
-		log.Debugf(ctx, "CreateOrUpdate QuarksSecrets for explicit variable '%s'", variable.Name)
+// createQuarksSecrets create variables quarksSecrets level by level of the
+// variable dependency graph, so CAs exist before the certificates they sign.
+// Each level is finished before the next one starts. With more than one
+// variable worker the variables of a level are created concurrently.
+// After the first error no further variables are started, the errors of
+// the ones in flight are aggregated.
+func (r *ReconcileBOSHDeployment) createQuarksSecrets(ctx context.Context, manifestSecret *corev1.Secret, variables []qsv1a1.QuarksSecret, order []bdm.Variable) error {
+	m := &bdm.Manifest{Variables: order}
+	graph, err := m.VariableGraph()
+	if err != nil {
+		return err
+	}
 
-		// Set the "manifest with ops" secret as the owner for the QuarksSecrets
-		// The "manifest with ops" secret is owned by the actual BOSHDeployment, so everything
-		// should be garbage collected properly.
-		if err := r.setReference(manifestSecret, &variable, r.scheme); err != nil {
-			err = log.WithEvent(manifestSecret, "OwnershipError").Errorf(ctx, "failed to set ownership for %s: %v", variable.Name, err)
+	levels := [][]*qsv1a1.QuarksSecret{}
+	for i := range variables {
+		depth, err := graph.Depth(variables[i].Labels[converter.LabelVariableName])
+		if err != nil {
 			return err
 		}
+		for len(levels) <= depth {
+			levels = append(levels, nil)
+		}
+		levels[depth] = append(levels[depth], &variables[i])
+	}
 
-		op, err := controllerutil.CreateOrUpdate(ctx, r.client, &variable, mutate.QuarksSecretMutateFn(&variable))
+	for _, level := range levels {
+		err := r.createQuarksSecretLevel(ctx, manifestSecret, level)
 		if err != nil {
-			return errors.Wrapf(err, "creating or updating QuarksSecret '%s'", variable.Name)
+			return err
 		}
+	}
+
+	return nil
+}
+
+// createQuarksSecretLevel creates the independent variables of one level of
+// the variable dependency graph with up to variableWorkers workers
+func (r *ReconcileBOSHDeployment) createQuarksSecretLevel(ctx context.Context, manifestSecret *corev1.Secret, variables []*qsv1a1.QuarksSecret) error {
+	var (
+		mu   sync.Mutex
+		errs []error
+		wg   sync.WaitGroup
+	)
+	failed := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(errs) > 0
+	}
 
-		// Update does not update status. We only trigger quarks secret
-		// reconciler again if variable was updated by previous CreateOrUpdate
-		if op == controllerutil.OperationResultUpdated {
-			variable.Status.Generated = false
-			if err := r.client.Status().Update(ctx, &variable); err != nil {
-				log.WithEvent(&variable, "UpdateError").Errorf(ctx, "failed to update generated status on quarks secret '%s' (%v): %s", variable.Name, variable.ResourceVersion, err)
-				return err
+	queue := make(chan *qsv1a1.QuarksSecret)
+	for i := 0; i < variableWorkers && i < len(variables); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for variable := range queue {
+				if failed() {
+					continue
+				}
+				if err := r.createQuarksSecret(ctx, manifestSecret, variable); err != nil {
+					mu.Lock()
+					errs = append(errs, err)
+					mu.Unlock()
+				}
 			}
+		}()
+	}
+
+	for _, variable := range variables {
+		if failed() {
+			break
 		}
+		queue <- variable
+	}
+	close(queue)
+	wg.Wait()
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// createQuarksSecret creates or updates a single variable quarksSecret
+func (r *ReconcileBOSHDeployment) createQuarksSecret(ctx context.Context, manifestSecret *corev1.Secret, variable *qsv1a1.QuarksSecret) error {
+	log.Debugf(ctx, "CreateOrUpdate QuarksSecrets for explicit variable '%s'", variable.Name)
+
+	// Set the "manifest with ops" secret as the owner for the QuarksSecrets
+	// The "manifest with ops" secret is owned by the actual BOSHDeployment, so everything
+	// should be garbage collected properly.
+	if err := r.setReference(manifestSecret, variable, r.scheme); err != nil {
+		err = log.WithEvent(manifestSecret, "OwnershipError").Errorf(ctx, "failed to set ownership for %s: %v", variable.Name, err)
+		return err
+	}
 
-		log.Debugf(ctx, "QuarksSecret '%s' has been %s", variable.Name, op)
+	op, err := controllerutil.CreateOrUpdate(ctx, r.client, variable, withOwnerReference(ctx, manifestSecret, variable, r.setReference, r.scheme, mutate.QuarksSecretMutateFn(variable)))
+	if err != nil {
+		return errors.Wrapf(err, "creating or updating QuarksSecret '%s'", variable.Name)
 	}
 
+	// Update does not update status. We only trigger quarks secret
+	// reconciler again if variable was updated by previous CreateOrUpdate
+	if op == controllerutil.OperationResultUpdated {
+		variable.Status.Generated = false
+		if err := r.client.Status().Update(ctx, variable); err != nil {
+			log.WithEvent(variable, "UpdateError").Errorf(ctx, "failed to update generated status on quarks secret '%s' (%v): %s", variable.Name, variable.ResourceVersion, err)
+			return err
+		}
+	}
+
+	log.Debugf(ctx, "QuarksSecret '%s' has been %s", variable.Name, op)
+
 	return nil
 }
 
 type serviceRecord struct {
 	selector  map[string]string
 	dnsRecord string
+	zones     bool
+	ports     []corev1.ServicePort
 }
@@ -2,37 +2,58 @@ package boshdeployment_test
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"strings"
+	"sync"
 	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/record"
 	crc "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"code.cloudfoundry.org/cf-operator/pkg/bosh/converter"
 	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+	"code.cloudfoundry.org/cf-operator/pkg/configserver"
+	csfakes "code.cloudfoundry.org/cf-operator/pkg/configserver/fakes"
 	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
 	qsv1a1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/quarkssecret/v1alpha1"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/controllers"
 	cfd "code.cloudfoundry.org/cf-operator/pkg/kube/controllers/boshdeployment"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/controllers/fakes"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/correlation"
+	bdnames "code.cloudfoundry.org/cf-operator/pkg/kube/util/names"
+	withopsutil "code.cloudfoundry.org/cf-operator/pkg/kube/util/withops"
+	"code.cloudfoundry.org/cf-operator/version"
 	qjv1a1 "code.cloudfoundry.org/quarks-job/pkg/kube/apis/quarksjob/v1alpha1"
 	cfcfg "code.cloudfoundry.org/quarks-utils/pkg/config"
 	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+	"code.cloudfoundry.org/quarks-utils/pkg/pointers"
 	helper "code.cloudfoundry.org/quarks-utils/testing/testhelper"
 )
 
@@ -46,8 +67,13 @@ var _ = Describe("ReconcileBoshDeployment", func() {
 		withops        fakes.FakeWithOps
 		jobFactory     fakes.FakeJobFactory
 		kubeConverter  fakes.FakeVariablesConverter
+		podLogs        fakes.FakePodLogs
+		staging        cfd.StagingValidator
+		configServer   *csfakes.FakeClient
+		linkPublisher  *fakes.FakeExternalLinkPublisher
 		manifest       *bdm.Manifest
 		log            *zap.SugaredLogger
+		logs           *observer.ObservedLogs
 		config         *cfcfg.Config
 		client         *fakes.FakeClient
 		instance       *bdv1.BOSHDeployment
@@ -66,6 +92,11 @@ var _ = Describe("ReconcileBoshDeployment", func() {
 		jobFactory = fakes.FakeJobFactory{}
 		kubeConverter = fakes.FakeVariablesConverter{}
 		kubeConverter.VariablesReturns([]qsv1a1.QuarksSecret{}, nil)
+		kubeConverter.VariableOrderCalls(converter.NewVariablesConverter("default", bdnames.DefaultSecretNamer{}).VariableOrder)
+		podLogs = fakes.FakePodLogs{}
+		staging = nil
+		configServer = &csfakes.FakeClient{}
+		linkPublisher = &fakes.FakeExternalLinkPublisher{}
 
 		deploymentName = "foo"
 
@@ -128,7 +159,7 @@ var _ = Describe("ReconcileBoshDeployment", func() {
 			},
 		}
 		config = &cfcfg.Config{CtxTimeOut: 10 * time.Second}
-		_, log = helper.NewTestLogger()
+		logs, log = helper.NewTestLogger()
 		ctx = ctxlog.NewParentContext(log)
 		ctx = ctxlog.NewContextWithRecorder(ctx, "TestRecorder", recorder)
 
@@ -177,7 +208,11 @@ var _ = Describe("ReconcileBoshDeployment", func() {
 		withops.ManifestReturns(manifest, []string{}, nil)
 		reconciler = cfd.NewDeploymentReconciler(
 			ctx, config, manager,
-			&withops, &jobFactory, &kubeConverter,
+			&withops, &jobFactory, &kubeConverter, &podLogs, staging, bdnames.DefaultSecretNamer{},
+			func(context.Context, crc.Client, string) (configserver.Client, error) { return configServer, nil },
+			func(context.Context, crc.Client, string) (cfd.ExternalLinkPublisher, error) {
+				return linkPublisher, nil
+			},
 			controllerutil.SetControllerReference,
 		)
 	})
@@ -214,187 +249,2545 @@ var _ = Describe("ReconcileBoshDeployment", func() {
 				// check for events
 				Expect(<-recorder.Events).To(ContainSubstring("WithOpsManifestError"))
 			})
-		})
 
-		Context("when the manifest can be resolved", func() {
-			It("handles an error when resolving manifest", func() {
-				manifest = &bdm.Manifest{}
-				withops.ManifestReturns(manifest, []string{}, errors.New("fake-error"))
+			It("reports manifests exceeding the maximum depth", func() {
+				withops.ManifestReturns(nil, []string{}, errors.Wrap(&withopsutil.ManifestTooDeepError{MaxDepth: 100}, "Interpolation failed"))
 
 				_, err := reconciler.Reconcile(request)
 				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(ContainSubstring("error resolving the manifest foo: fake-error"))
+				Expect(err.Error()).To(ContainSubstring("nested deeper than the maximum depth of 100"))
+				Expect(<-recorder.Events).To(ContainSubstring("ManifestTooDeep"))
 			})
 
-			It("handles an error when setting the owner reference on the object", func() {
-				reconciler = cfd.NewDeploymentReconciler(ctx, config, manager, &withops, &jobFactory, &kubeConverter,
-					func(owner, object metav1.Object, scheme *runtime.Scheme) error {
-						return fmt.Errorf("some error")
-					},
-				)
+			It("reports ops files without a trusted signature", func() {
+				withops.ManifestReturns(nil, []string{}, errors.Wrap(&withopsutil.UnsignedOpsError{Name: "ops", Reason: "missing signature"}, "Interpolation failed"))
 
 				_, err := reconciler.Reconcile(request)
 				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(ContainSubstring("failed to set ownerReference for Secret 'foo.with-ops': some error"))
+				Expect(err.Error()).To(ContainSubstring("ops file 'ops' is not signed by a trusted signer"))
+				Expect(<-recorder.Events).To(ContainSubstring("UnsignedOps"))
 			})
 
-			It("handles an error when creating manifest secret with ops", func() {
-				client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
-					switch object := object.(type) {
-					case *bdv1.BOSHDeployment:
-						instance.DeepCopyInto(object)
-					case *qjv1a1.QuarksJob:
-						return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
-					case *corev1.Secret:
-						if nn.Name == "foo.with-ops" {
-							return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+			Context("when the deployment has a log level annotation", func() {
+				JustBeforeEach(func() {
+					withops.ManifestReturns(nil, []string{}, fmt.Errorf("resolver error"))
+				})
+
+				It("logs the messages about the deployment from that level onward", func() {
+					instance.Annotations = map[string]string{bdv1.AnnotationLogLevel: "error"}
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).To(HaveOccurred())
+					Expect(logs.FilterMessage("Resolving manifest").All()).To(BeEmpty())
+					Expect(logs.FilterMessageSnippet("resolver error").All()).ToNot(BeEmpty())
+				})
+
+				It("ignores invalid levels with a warning", func() {
+					instance.Annotations = map[string]string{bdv1.AnnotationLogLevel: "verbose"}
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).To(HaveOccurred())
+					Expect(<-recorder.Events).To(ContainSubstring("InvalidLogLevel"))
+					Expect(logs.FilterMessage("Resolving manifest").All()).To(HaveLen(1))
+				})
+			})
+
+			Context("when the deployment has a min operator version annotation", func() {
+				var operatorVersion string
+
+				BeforeEach(func() {
+					operatorVersion = version.Version
+					version.Version = "v1.2.0-15.g1a2b3c4"
+				})
+
+				AfterEach(func() {
+					version.Version = operatorVersion
+				})
+
+				JustBeforeEach(func() {
+					withops.ManifestReturns(nil, []string{}, fmt.Errorf("resolver error"))
+				})
+
+				It("doesn't apply changes, if the operator is too old", func() {
+					instance.Annotations = map[string]string{bdv1.AnnotationMinOperatorVersion: "1.3.0"}
+
+					result, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(result).To(Equal(reconcile.Result{Requeue: false}))
+					Expect(<-recorder.Events).To(ContainSubstring("OperatorVersionTooOld"))
+					Expect(withops.ManifestCallCount()).To(Equal(0))
+				})
+
+				It("reconciles the deployment, if the operator is new enough", func() {
+					instance.Annotations = map[string]string{bdv1.AnnotationMinOperatorVersion: "v1.2.0"}
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("resolver error"))
+				})
+
+				It("doesn't apply changes, if the annotation is invalid", func() {
+					instance.Annotations = map[string]string{bdv1.AnnotationMinOperatorVersion: "latest"}
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(<-recorder.Events).To(ContainSubstring("InvalidMinOperatorVersion"))
+					Expect(withops.ManifestCallCount()).To(Equal(0))
+				})
+
+				It("reconciles all deployments, if the operator has no semantic version", func() {
+					version.Version = "dev"
+					instance.Annotations = map[string]string{bdv1.AnnotationMinOperatorVersion: "1.3.0"}
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("resolver error"))
+				})
+			})
+
+			Context("when API versions are deprecated", func() {
+				deprecatedCount := func() float64 {
+					families, err := crmetrics.Registry.Gather()
+					Expect(err).NotTo(HaveOccurred())
+					for _, family := range families {
+						if family.GetName() != "quarks_boshdeployment_deprecated_api_version_total" {
+							continue
+						}
+						for _, metric := range family.GetMetric() {
+							labels := map[string]string{}
+							for _, label := range metric.GetLabel() {
+								labels[label.GetName()] = label.GetValue()
+							}
+							if labels["namespace"] == "default" && labels["api_version"] == "quarks.cloudfoundry.org/v1alpha1" {
+								return metric.GetCounter().GetValue()
+							}
 						}
 					}
+					return 0
+				}
 
-					return nil
+				BeforeEach(func() {
+					Expect(cfd.SetDeprecatedAPIVersions("quarks.cloudfoundry.org/v1alpha1, quarks.cloudfoundry.org/v1alpha0")).To(Succeed())
 				})
-				client.UpdateCalls(func(context context.Context, object runtime.Object, _ ...crc.UpdateOption) error {
-					switch object := object.(type) {
-					case *bdv1.BOSHDeployment:
-						object.DeepCopyInto(instance)
+
+				AfterEach(func() {
+					Expect(cfd.SetDeprecatedAPIVersions("")).To(Succeed())
+				})
+
+				JustBeforeEach(func() {
+					withops.ManifestReturns(nil, []string{}, fmt.Errorf("resolver error"))
+				})
+
+				It("reports deployments submitted via a deprecated version and reconciles them", func() {
+					instance.ManagedFields = []metav1.ManagedFieldsEntry{
+						{Manager: "kubectl", Operation: metav1.ManagedFieldsOperationUpdate, APIVersion: "quarks.cloudfoundry.org/v1alpha1"},
 					}
-					return nil
+					count := deprecatedCount()
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("resolver error"))
+					Expect(<-recorder.Events).To(ContainSubstring("DeprecatedAPIVersion"))
+					Expect(deprecatedCount()).To(Equal(count + 1))
 				})
-				client.CreateCalls(func(context context.Context, object runtime.Object, _ ...crc.CreateOption) error {
-					switch object.(type) {
-					case *corev1.Secret:
-						return errors.New("fake-error")
+
+				It("reports the version of kubectl's last applied configuration", func() {
+					instance.Annotations = map[string]string{
+						corev1.LastAppliedConfigAnnotation: `{"apiVersion":"quarks.cloudfoundry.org/v1alpha1","kind":"BOSHDeployment"}`,
 					}
-					return nil
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).To(HaveOccurred())
+					Expect(<-recorder.Events).To(ContainSubstring("DeprecatedAPIVersion"))
 				})
 
-				By("From created state to ops applied state")
-				_, err := reconciler.Reconcile(request)
-				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(ContainSubstring("failed to create with-ops manifest secret for BOSHDeployment 'default/foo': failed to apply Secret 'foo.with-ops': fake-error"))
+				It("ignores the operator's own writes", func() {
+					instance.ManagedFields = []metav1.ManagedFieldsEntry{
+						{
+							Manager:    strings.Split(rest.DefaultKubernetesUserAgent(), "/")[0],
+							Operation:  metav1.ManagedFieldsOperationUpdate,
+							APIVersion: "quarks.cloudfoundry.org/v1alpha1",
+						},
+					}
+					count := deprecatedCount()
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).To(HaveOccurred())
+					Expect(<-recorder.Events).NotTo(ContainSubstring("DeprecatedAPIVersion"))
+					Expect(deprecatedCount()).To(Equal(count))
+				})
+
+				It("rejects versions of other API groups", func() {
+					Expect(cfd.SetDeprecatedAPIVersions("apps/v1")).To(MatchError(ContainSubstring("the group must be 'quarks.cloudfoundry.org'")))
+					Expect(cfd.SetDeprecatedAPIVersions("a/b/c")).To(MatchError(ContainSubstring("invalid deprecated API version 'a/b/c'")))
+				})
 			})
 
-			It("handles an error when building desired manifest qJob", func() {
-				jobFactory.VariableInterpolationJobReturns(dmQJob, errors.New("fake-error"))
+			Context("when a referenced secret doesn't exist yet", func() {
+				var statusWriter *fakes.FakeStatusWriter
+
+				BeforeEach(func() {
+					instance.Spec.FailurePolicy = bdv1.FailurePolicyHalt
+					statusWriter = &fakes.FakeStatusWriter{}
+					client.StatusCalls(func() crc.StatusWriter { return statusWriter })
+				})
+
+				JustBeforeEach(func() {
+					withops.ManifestReturns(nil, []string{}, errors.Wrap(&withopsutil.SecretNotFoundError{Namespace: "default", Name: "baz"}, "Interpolation failed"))
+				})
+
+				It("waits for the secret without failing the reconcile", func() {
+					result, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+
+					Expect(statusWriter.UpdateCallCount()).To(Equal(1))
+					_, object, _ := statusWriter.UpdateArgsForCall(0)
+					status := object.(*bdv1.BOSHDeployment).Status
+					Expect(status.Phase).To(Equal(bdv1.PhaseWaitingForSecret))
+					Expect(status.WaitingForSecret).To(Equal("baz"))
+					Expect(status.LastReconcile).To(BeNil())
+					Expect(status.LastError).To(BeEmpty())
+
+					Expect(<-recorder.Events).To(ContainSubstring("WaitingForSecret"))
+				})
+
+				It("sets the ManifestResolved condition to false", func() {
+					_, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(statusWriter.UpdateCallCount()).To(Equal(1))
+					_, object, _ := statusWriter.UpdateArgsForCall(0)
+					condition := bdv1.FindStatusCondition(object.(*bdv1.BOSHDeployment).Status.Conditions, bdv1.ConditionManifestResolved)
+					Expect(condition).NotTo(BeNil())
+					Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+					Expect(condition.Reason).To(Equal("WaitingForSecret"))
+					Expect(condition.Message).To(Equal("waiting for secret 'baz'"))
+				})
+
+				It("doesn't update the status again while waiting for the same secret", func() {
+					instance.Status.Phase = bdv1.PhaseWaitingForSecret
+					instance.Status.WaitingForSecret = "baz"
+					instance.Status.Conditions = []bdv1.Condition{{
+						Type:               bdv1.ConditionManifestResolved,
+						Status:             metav1.ConditionFalse,
+						ObservedGeneration: instance.Generation,
+						Reason:             "WaitingForSecret",
+						Message:            "waiting for secret 'baz'",
+					}}
+
+					result, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+					Expect(statusWriter.UpdateCallCount()).To(Equal(0))
+				})
+
+				It("clears the waiting status once the secret exists", func() {
+					instance.Status.Phase = bdv1.PhaseWaitingForSecret
+					instance.Status.WaitingForSecret = "baz"
+					withops.ManifestReturns(manifest, []string{}, nil)
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(statusWriter.UpdateCallCount()).To(Equal(1))
+					_, object, _ := statusWriter.UpdateArgsForCall(0)
+					status := object.(*bdv1.BOSHDeployment).Status
+					Expect(status.Phase).To(Equal(bdv1.PhaseApplied))
+					Expect(status.WaitingForSecret).To(BeEmpty())
+				})
+			})
+		})
+
+		Context("when a failure policy is set", func() {
+			var statusWriter *fakes.FakeStatusWriter
+
+			BeforeEach(func() {
+				statusWriter = &fakes.FakeStatusWriter{}
+				client.StatusCalls(func() crc.StatusWriter { return statusWriter })
+			})
+
+			JustBeforeEach(func() {
+				withops.ManifestReturns(nil, []string{}, fmt.Errorf("resolver error"))
+			})
+
+			It("requeues failed reconciles with the 'Retry' policy", func() {
+				instance.Spec.FailurePolicy = bdv1.FailurePolicyRetry
 
 				_, err := reconciler.Reconcile(request)
 				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(ContainSubstring("failed to build the desired manifest qJob"))
+
+				Expect(statusWriter.UpdateCallCount()).To(Equal(1))
+				_, object, _ := statusWriter.UpdateArgsForCall(0)
+				status := object.(*bdv1.BOSHDeployment).Status
+				Expect(status.Phase).NotTo(Equal(bdv1.PhaseFailed))
+				condition := bdv1.FindStatusCondition(status.Conditions, bdv1.ConditionManifestResolved)
+				Expect(condition).NotTo(BeNil())
+				Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+				Expect(condition.Reason).To(Equal("WithOpsManifestError"))
+				Expect(condition.Message).To(ContainSubstring("resolver error"))
 			})
 
-			It("handles an error generating the new variable secrets", func() {
-				kubeConverter.VariablesReturns(nil, errors.New("fake-error"))
+			Context("with the 'Halt' policy", func() {
+				BeforeEach(func() {
+					instance.Spec.FailurePolicy = bdv1.FailurePolicyHalt
+				})
+
+				It("marks the deployment as failed and doesn't requeue", func() {
+					result, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(result).To(Equal(reconcile.Result{}))
+
+					Expect(statusWriter.UpdateCallCount()).To(Equal(1))
+					_, object, _ := statusWriter.UpdateArgsForCall(0)
+					Expect(object.(*bdv1.BOSHDeployment).Status.Phase).To(Equal(bdv1.PhaseFailed))
+					Expect(object.(*bdv1.BOSHDeployment).Status.LastError).To(ContainSubstring("resolver error"))
+
+					Expect(<-recorder.Events).To(ContainSubstring("WithOpsManifestError"))
+					Expect(<-recorder.Events).To(ContainSubstring("WithOpsManifestError"))
+					Expect(<-recorder.Events).To(ContainSubstring("ReconcileHalted"))
+				})
+
+				It("requeues, if the failure can't be recorded", func() {
+					statusWriter.UpdateReturns(fmt.Errorf("status error"))
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("resolver error"))
+				})
+
+				Context("when the deployment failed before", func() {
+					BeforeEach(func() {
+						instance.Status.Phase = bdv1.PhaseFailed
+					})
+
+					It("skips the reconcile", func() {
+						result, err := reconciler.Reconcile(request)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(result).To(Equal(reconcile.Result{}))
+						Expect(withops.ManifestCallCount()).To(Equal(0))
+					})
+
+					It("resumes the reconcile, if the resume annotation is set", func() {
+						instance.Annotations = map[string]string{bdv1.AnnotationResume: "true"}
+
+						_, err := reconciler.Reconcile(request)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(withops.ManifestCallCount()).To(Equal(1))
 
+						Expect(client.UpdateCallCount()).To(Equal(1))
+						_, object, _ := client.UpdateArgsForCall(0)
+						Expect(object.(*bdv1.BOSHDeployment).Annotations).ToNot(HaveKey(bdv1.AnnotationResume))
+						Expect(<-recorder.Events).To(ContainSubstring("ReconcileResumed"))
+					})
+
+					It("resumes the reconcile, if the failure policy changed", func() {
+						instance.Spec.FailurePolicy = bdv1.FailurePolicyRetry
+
+						_, err := reconciler.Reconcile(request)
+						Expect(err).To(HaveOccurred())
+						Expect(withops.ManifestCallCount()).To(Equal(1))
+					})
+				})
+			})
+
+			It("marks the deployment as degraded with the 'Ignore' policy", func() {
+				instance.Spec.FailurePolicy = bdv1.FailurePolicyIgnore
+
+				result, err := reconciler.Reconcile(request)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result).To(Equal(reconcile.Result{RequeueAfter: 5 * time.Second}))
+
+				_, object, _ := statusWriter.UpdateArgsForCall(0)
+				Expect(object.(*bdv1.BOSHDeployment).Status.Phase).To(Equal(bdv1.PhaseDegraded))
+				Expect(object.(*bdv1.BOSHDeployment).Status.LastError).To(ContainSubstring("resolver error"))
+				Expect(<-recorder.Events).To(ContainSubstring("WithOpsManifestError"))
+				Expect(<-recorder.Events).To(ContainSubstring("WithOpsManifestError"))
+				Expect(<-recorder.Events).To(ContainSubstring("ReconcileFailureIgnored"))
+			})
+
+			It("requeues with backoff, while the reconcile keeps failing with the 'Ignore' policy", func() {
+				instance.Spec.FailurePolicy = bdv1.FailurePolicyIgnore
+
+				for _, delay := range []time.Duration{5 * time.Second, 10 * time.Second, 20 * time.Second} {
+					result, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(result).To(Equal(reconcile.Result{RequeueAfter: delay}))
+				}
+
+				withops.ManifestReturns(manifest, []string{}, nil)
 				_, err := reconciler.Reconcile(request)
-				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(ContainSubstring("failed to generate quarks secrets from manifest"))
+				Expect(err).ToNot(HaveOccurred())
+
+				withops.ManifestReturns(nil, []string{}, fmt.Errorf("resolver error"))
+				result, err := reconciler.Reconcile(request)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result).To(Equal(reconcile.Result{RequeueAfter: 5 * time.Second}))
 			})
+		})
 
-			It("handles an error when creating the new quarks secrets", func() {
-				kubeConverter.VariablesReturns([]qsv1a1.QuarksSecret{
-					{
-						ObjectMeta: metav1.ObjectMeta{
-							Name: "fake-variable",
+		Context("when a manifest retention policy is set", func() {
+			var manifestSecret *corev1.Secret
+
+			BeforeEach(func() {
+				instance.UID = "bdpl-uid"
+				manifestSecret = &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "foo.with-ops",
+						Namespace: "default",
+						OwnerReferences: []metav1.OwnerReference{
+							{Name: deploymentName, UID: "bdpl-uid"},
+							{Name: "other", UID: "other-uid"},
 						},
 					},
-				}, nil)
+				}
 				client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
 					switch object := object.(type) {
 					case *bdv1.BOSHDeployment:
 						instance.DeepCopyInto(object)
-					case *qsv1a1.QuarksSecret:
-						return apierrors.NewNotFound(schema.GroupResource{}, "fake-variable")
-					}
-					return nil
-				})
-				client.CreateCalls(func(context context.Context, object runtime.Object, _ ...crc.CreateOption) error {
-					switch object.(type) {
-					case *qsv1a1.QuarksSecret:
-						return errors.New("fake-error")
+					case *corev1.Secret:
+						if nn.Name == manifestSecret.Name {
+							manifestSecret.DeepCopyInto(object)
+							return nil
+						}
+						return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+					case *qjv1a1.QuarksJob:
+						return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
 					}
+
 					return nil
 				})
+			})
+
+			It("adds the finalizer for the 'Retain' policy", func() {
+				instance.Spec.ManifestRetentionPolicy = bdv1.ManifestRetentionRetain
 
 				_, err := reconciler.Reconcile(request)
-				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(ContainSubstring("failed to create quarks secrets for BOSH manifest 'foo'"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(withops.ManifestCallCount()).To(Equal(1))
+
+				_, object, _ := client.UpdateArgsForCall(0)
+				Expect(object.(*bdv1.BOSHDeployment).Finalizers).To(ConsistOf(bdv1.FinalizerManifestRetention))
 			})
 
-			It("handles an error when building desired manifest qJob", func() {
-				jobFactory.VariableInterpolationJobReturns(dmQJob, errors.New("fake-error"))
+			It("removes the finalizer, if the policy changed to 'Delete'", func() {
+				instance.Spec.ManifestRetentionPolicy = bdv1.ManifestRetentionDelete
+				instance.Finalizers = []string{bdv1.FinalizerManifestRetention}
 
 				_, err := reconciler.Reconcile(request)
-				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(ContainSubstring("failed to build the desired manifest qJob"))
+				Expect(err).ToNot(HaveOccurred())
+
+				_, object, _ := client.UpdateArgsForCall(0)
+				Expect(object.(*bdv1.BOSHDeployment).Finalizers).To(BeEmpty())
 			})
 
-			It("handles an error when creating desired manifest qJob", func() {
-				client.CreateCalls(func(context context.Context, object runtime.Object, _ ...crc.CreateOption) error {
-					switch object := object.(type) {
-					case *qjv1a1.QuarksJob:
-						qJob := object
-						if strings.HasPrefix(qJob.Name, "dm-") {
-							return errors.New("fake-error")
-						}
-					}
-					return nil
+			Context("when the deployment is deleted", func() {
+				BeforeEach(func() {
+					now := metav1.Now()
+					instance.DeletionTimestamp = &now
+					instance.Spec.ManifestRetentionPolicy = bdv1.ManifestRetentionRetain
+					instance.Finalizers = []string{bdv1.FinalizerManifestRetention}
+				})
+
+				It("retains the manifest secret and removes the finalizer", func() {
+					_, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(withops.ManifestCallCount()).To(Equal(0))
+
+					Expect(client.UpdateCallCount()).To(Equal(2))
+					_, object, _ := client.UpdateArgsForCall(0)
+					secret := object.(*corev1.Secret)
+					Expect(secret.OwnerReferences).To(ConsistOf(metav1.OwnerReference{Name: "other", UID: "other-uid"}))
+					Expect(secret.Labels).To(HaveKeyWithValue(bdv1.LabelManifestRetained, "true"))
+
+					_, object, _ = client.UpdateArgsForCall(1)
+					Expect(object.(*bdv1.BOSHDeployment).Finalizers).To(BeEmpty())
+					Expect(<-recorder.Events).To(ContainSubstring("ManifestRetained"))
+				})
+
+				It("only removes the finalizer, if the manifest secret doesn't exist", func() {
+					manifestSecret.Name = "other"
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(client.UpdateCallCount()).To(Equal(1))
+					_, object, _ := client.UpdateArgsForCall(0)
+					Expect(object.(*bdv1.BOSHDeployment).Finalizers).To(BeEmpty())
+				})
+
+				It("keeps the finalizer, if the manifest secret can't be retained", func() {
+					client.UpdateReturns(errors.New("fake-error"))
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("retaining Secret 'foo.with-ops'"))
+					Expect(client.UpdateCallCount()).To(Equal(1))
 				})
 
-				_, err := reconciler.Reconcile(request)
-				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(ContainSubstring("failed to create desired manifest qJob for BOSHDeployment 'default/foo': creating or updating QuarksJob 'dm-foo': fake-error"))
-			})
+				It("skips the reconcile without the finalizer", func() {
+					instance.Finalizers = nil
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(client.UpdateCallCount()).To(Equal(0))
+					Expect(withops.ManifestCallCount()).To(Equal(0))
+				})
+			})
+		})
+
+		Context("when ops files without effect are reported", func() {
+			BeforeEach(func() {
+				cfd.SetReportNoOpOps(true)
+				withops.NoOpOpsReturns([]string{"baz"}, nil)
+			})
+
+			AfterEach(func() {
+				cfd.SetReportNoOpOps(false)
+			})
+
+			It("records a warning event for each of them", func() {
+				_, _ = reconciler.Reconcile(request)
+
+				Expect(withops.NoOpOpsCallCount()).To(Equal(1))
+				Expect(<-recorder.Events).To(And(
+					ContainSubstring("Warning NoOpOpsFile"),
+					ContainSubstring("Ops file 'baz' of BOSHDeployment 'default/foo' doesn't change the manifest"),
+				))
+			})
+
+			It("doesn't fail the reconcile, if the check fails", func() {
+				withops.NoOpOpsReturns(nil, fmt.Errorf("check error"))
+
+				_, err := reconciler.Reconcile(request)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(recorder.Events).ToNot(Receive(ContainSubstring("NoOpOpsFile")))
+			})
+		})
+
+		Context("when the deployment references a cloud config", func() {
+			BeforeEach(func() {
+				instance.Spec.CloudConfig = &bdv1.ResourceReference{Name: "cloud-config", Type: bdv1.ConfigMapReference}
+				withops.CloudConfigReturns(&bdm.CloudConfig{
+					CloudProperties: map[string]interface{}{"default_vm_type": "small"},
+				}, nil)
+			})
+
+			It("applies the cloud config to the manifest", func() {
+				_, err := reconciler.Reconcile(request)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(withops.CloudConfigCallCount()).To(Equal(1))
+				_, m, _, _, _ := jobFactory.VariableInterpolationJobArgsForCall(0)
+				Expect(m.InstanceGroups[0].VMType).To(Equal("small"))
+			})
+
+			It("fails for networks, which are not in the cloud config", func() {
+				manifest.InstanceGroups[0].Networks = []*bdm.Network{{Name: "private"}}
+
+				_, err := reconciler.Reconcile(request)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("references unknown network 'private'"))
+				Expect(<-recorder.Events).To(ContainSubstring("CloudConfigError"))
+			})
+
+			It("waits for a missing cloud config secret", func() {
+				withops.CloudConfigReturns(nil, errors.Wrap(&withopsutil.SecretNotFoundError{Namespace: "default", Name: "cloud-config"}, "Failed to get cloud config"))
+
+				result, err := reconciler.Reconcile(request)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+				Expect(<-recorder.Events).To(ContainSubstring("WaitingForSecret"))
+			})
+		})
+
+		Context("when the manifest can be resolved", func() {
+			It("handles an error when resolving manifest", func() {
+				manifest = &bdm.Manifest{}
+				withops.ManifestReturns(manifest, []string{}, errors.New("fake-error"))
+
+				_, err := reconciler.Reconcile(request)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("error resolving the manifest foo: fake-error"))
+			})
+
+			It("handles an error when setting the owner reference on the object", func() {
+				reconciler = cfd.NewDeploymentReconciler(ctx, config, manager, &withops, &jobFactory, &kubeConverter, &podLogs, staging, bdnames.DefaultSecretNamer{}, nil, nil,
+					func(owner, object metav1.Object, scheme *runtime.Scheme) error {
+						return fmt.Errorf("some error")
+					},
+				)
+
+				_, err := reconciler.Reconcile(request)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("failed to set ownerReference for Secret 'foo.with-ops': some error"))
+			})
+
+			It("handles an error when creating manifest secret with ops", func() {
+				client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+					switch object := object.(type) {
+					case *bdv1.BOSHDeployment:
+						instance.DeepCopyInto(object)
+					case *qjv1a1.QuarksJob:
+						return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+					case *corev1.Secret:
+						if nn.Name == "foo.with-ops" {
+							return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+						}
+					}
+
+					return nil
+				})
+				client.UpdateCalls(func(context context.Context, object runtime.Object, _ ...crc.UpdateOption) error {
+					switch object := object.(type) {
+					case *bdv1.BOSHDeployment:
+						object.DeepCopyInto(instance)
+					}
+					return nil
+				})
+				client.CreateCalls(func(context context.Context, object runtime.Object, _ ...crc.CreateOption) error {
+					switch object.(type) {
+					case *corev1.Secret:
+						return errors.New("fake-error")
+					}
+					return nil
+				})
+
+				By("From created state to ops applied state")
+				_, err := reconciler.Reconcile(request)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("failed to create with-ops manifest secret for BOSHDeployment 'default/foo': failed to apply Secret 'foo.with-ops': fake-error"))
+			})
+
+			It("handles an error when building desired manifest qJob", func() {
+				jobFactory.VariableInterpolationJobReturns(dmQJob, errors.New("fake-error"))
+
+				_, err := reconciler.Reconcile(request)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("failed to build the desired manifest qJob"))
+			})
+
+			It("handles an error generating the new variable secrets", func() {
+				kubeConverter.VariablesReturns(nil, errors.New("fake-error"))
+
+				_, err := reconciler.Reconcile(request)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("failed to generate quarks secrets from manifest"))
+			})
+
+			It("handles an error when creating the new quarks secrets", func() {
+				kubeConverter.VariablesReturns([]qsv1a1.QuarksSecret{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "fake-variable",
+						},
+					},
+				}, nil)
+				client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+					switch object := object.(type) {
+					case *bdv1.BOSHDeployment:
+						instance.DeepCopyInto(object)
+					case *qsv1a1.QuarksSecret:
+						return apierrors.NewNotFound(schema.GroupResource{}, "fake-variable")
+					}
+					return nil
+				})
+				client.CreateCalls(func(context context.Context, object runtime.Object, _ ...crc.CreateOption) error {
+					switch object.(type) {
+					case *qsv1a1.QuarksSecret:
+						return errors.New("fake-error")
+					}
+					return nil
+				})
+
+				_, err := reconciler.Reconcile(request)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("failed to create quarks secrets for BOSH manifest 'foo'"))
+			})
+
+			It("handles an error when building desired manifest qJob", func() {
+				jobFactory.VariableInterpolationJobReturns(dmQJob, errors.New("fake-error"))
+
+				_, err := reconciler.Reconcile(request)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("failed to build the desired manifest qJob"))
+			})
+
+			It("handles an error when creating desired manifest qJob", func() {
+				client.CreateCalls(func(context context.Context, object runtime.Object, _ ...crc.CreateOption) error {
+					switch object := object.(type) {
+					case *qjv1a1.QuarksJob:
+						qJob := object
+						if strings.HasPrefix(qJob.Name, "dm-") {
+							return errors.New("fake-error")
+						}
+					}
+					return nil
+				})
+
+				_, err := reconciler.Reconcile(request)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("failed to create desired manifest qJob for BOSHDeployment 'default/foo': creating or updating QuarksJob 'dm-foo': fake-error"))
+			})
+
+			It("handles an error when building instance group manifest qJob", func() {
+				jobFactory.InstanceGroupManifestJobReturns(dmQJob, errors.New("fake-error"))
+
+				_, err := reconciler.Reconcile(request)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("failed to build instance group manifest qJob"))
+			})
+
+			It("handles an error when creating instance group manifest qJob", func() {
+				client.CreateCalls(func(context context.Context, object runtime.Object, _ ...crc.CreateOption) error {
+					switch object := object.(type) {
+					case *qjv1a1.QuarksJob:
+						qJob := object
+						if strings.HasPrefix(qJob.Name, "ig-") {
+							return errors.New("fake-error")
+						}
+					}
+					return nil
+				})
+
+				_, err := reconciler.Reconcile(request)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("failed to create instance group manifest qJob for BOSHDeployment 'default/foo': creating or updating QuarksJob 'ig-foo': fake-error"))
+			})
+
+			Context("when a change window is configured", func() {
+				var statusWriter *fakes.FakeStatusWriter
+
+				clock := func(t time.Time) string {
+					return t.UTC().Format("15:04")
+				}
+
+				BeforeEach(func() {
+					statusWriter = &fakes.FakeStatusWriter{}
+					client.StatusCalls(func() crc.StatusWriter { return statusWriter })
+					kubeConverter.VariablesReturns([]qsv1a1.QuarksSecret{
+						{ObjectMeta: metav1.ObjectMeta{Name: "fake-variable", Namespace: "default"}},
+					}, nil)
+				})
+
+				AfterEach(func() {
+					Expect(cfd.SetChangeWindow("")).To(Succeed())
+				})
+
+				It("applies the changes inside the window", func() {
+					now := time.Now()
+					Expect(cfd.SetChangeWindow(clock(now.Add(-time.Hour)) + "-" + clock(now.Add(time.Hour)))).To(Succeed())
+
+					result, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(result).To(Equal(reconcile.Result{}))
+					Expect(client.CreateCallCount()).To(Equal(2))
+
+					_, object, _ := statusWriter.UpdateArgsForCall(0)
+					Expect(object.(*bdv1.BOSHDeployment).Status.Phase).To(Equal(bdv1.PhaseGeneratingVariables))
+				})
+
+				It("stages the changes outside the window", func() {
+					now := time.Now()
+					Expect(cfd.SetChangeWindow(clock(now.Add(2*time.Hour)) + "-" + clock(now.Add(3*time.Hour)))).To(Succeed())
+
+					result, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(result.RequeueAfter).To(BeNumerically(">", time.Hour))
+					Expect(result.RequeueAfter).To(BeNumerically("<=", 2*time.Hour))
+					Expect(client.CreateCallCount()).To(Equal(0))
+					Expect(client.UpdateCallCount()).To(Equal(0))
+
+					Expect(statusWriter.UpdateCallCount()).To(Equal(1))
+					_, object, _ := statusWriter.UpdateArgsForCall(0)
+					status := object.(*bdv1.BOSHDeployment).Status
+					Expect(status.Phase).To(Equal(bdv1.PhasePendingWindow))
+					Expect(status.LastReconcile).To(BeNil())
+					Expect(status.PendingChanges).To(Equal([]string{
+						"Secret/foo.with-ops",
+						"QuarksSecret/fake-variable",
+						"ConfigMap/foo-variables",
+						"QuarksJob/dm-foo",
+						"QuarksJob/ig-foo",
+					}))
+				})
+
+				It("rejects an invalid schedule", func() {
+					Expect(cfd.SetChangeWindow("22:00")).To(MatchError(ContainSubstring("invalid change window")))
+				})
+			})
+
+			Context("when the deployment has variables", func() {
+				var (
+					statusWriter *fakes.FakeStatusWriter
+					generated    bool
+				)
+
+				BeforeEach(func() {
+					generated = false
+					statusWriter = &fakes.FakeStatusWriter{}
+					client.StatusCalls(func() crc.StatusWriter { return statusWriter })
+					kubeConverter.VariablesReturns([]qsv1a1.QuarksSecret{
+						{ObjectMeta: metav1.ObjectMeta{Name: "foo.var-a", Namespace: "default"}},
+						{ObjectMeta: metav1.ObjectMeta{Name: "foo.var-b", Namespace: "default"}},
+					}, nil)
+					client.ListCalls(func(context context.Context, object runtime.Object, _ ...crc.ListOption) error {
+						switch object := object.(type) {
+						case *qsv1a1.QuarksSecretList:
+							labels := map[string]string{bdv1.LabelDeploymentName: "foo", converter.LabelVariableName: "a"}
+							object.Items = []qsv1a1.QuarksSecret{
+								{
+									ObjectMeta: metav1.ObjectMeta{Name: "foo.var-a", Labels: labels},
+									Status:     qsv1a1.QuarksSecretStatus{Generated: true},
+								},
+								{
+									ObjectMeta: metav1.ObjectMeta{Name: "foo.var-b", Labels: labels},
+									Status:     qsv1a1.QuarksSecretStatus{Generated: generated},
+								},
+							}
+						}
+						return nil
+					})
+				})
+
+				It("tracks the variable generation in the status, until all variables are generated", func() {
+					_, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+
+					_, object, _ := statusWriter.UpdateArgsForCall(0)
+					status := object.(*bdv1.BOSHDeployment).Status
+					Expect(status.VariableGenerationTotal).To(Equal(2))
+					Expect(status.VariableGenerationComplete).To(Equal(1))
+					Expect(status.Phase).To(Equal(bdv1.PhaseGeneratingVariables))
+				})
+
+				It("sets the phase to applied, once all variables are generated", func() {
+					generated = true
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+
+					_, object, _ := statusWriter.UpdateArgsForCall(0)
+					status := object.(*bdv1.BOSHDeployment).Status
+					Expect(status.VariableGenerationTotal).To(Equal(2))
+					Expect(status.VariableGenerationComplete).To(Equal(2))
+					Expect(status.Phase).To(Equal(bdv1.PhaseApplied))
+				})
+
+				It("sets a condition for each reconcile phase", func() {
+					_, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(statusWriter.UpdateCallCount()).To(Equal(1))
+					_, object, _ := statusWriter.UpdateArgsForCall(0)
+					conditions := object.(*bdv1.BOSHDeployment).Status.Conditions
+					Expect(bdv1.IsStatusConditionTrue(conditions, bdv1.ConditionManifestResolved)).To(BeTrue())
+					Expect(bdv1.IsStatusConditionTrue(conditions, bdv1.ConditionVariableInterpolationJobReady)).To(BeTrue())
+					Expect(bdv1.IsStatusConditionTrue(conditions, bdv1.ConditionInstanceGroupManifestJobReady)).To(BeTrue())
+
+					condition := bdv1.FindStatusCondition(conditions, bdv1.ConditionVariablesGenerated)
+					Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+					Expect(condition.Reason).To(Equal("GeneratingVariables"))
+					Expect(condition.Message).To(Equal("1 of 2 variables generated"))
+				})
+
+				It("keeps the transition time of unchanged conditions", func() {
+					transition := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+					instance.Status.Conditions = []bdv1.Condition{{
+						Type:               bdv1.ConditionManifestResolved,
+						Status:             metav1.ConditionTrue,
+						LastTransitionTime: transition,
+						Reason:             "Resolved",
+					}}
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+
+					_, object, _ := statusWriter.UpdateArgsForCall(0)
+					condition := bdv1.FindStatusCondition(object.(*bdv1.BOSHDeployment).Status.Conditions, bdv1.ConditionManifestResolved)
+					Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+					Expect(condition.LastTransitionTime).To(Equal(transition))
+				})
+
+				It("sets the condition of the failed phase to false and resets later ones to unknown", func() {
+					instance.Status.Conditions = []bdv1.Condition{
+						{Type: bdv1.ConditionManifestResolved, Status: metav1.ConditionTrue, Reason: "Resolved"},
+						{Type: bdv1.ConditionInstanceGroupManifestJobReady, Status: metav1.ConditionTrue, Reason: "Applied"},
+					}
+					jobFactory.InstanceGroupManifestJobReturns(dmQJob, errors.New("fake-error"))
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).To(HaveOccurred())
+
+					Expect(statusWriter.UpdateCallCount()).To(Equal(1))
+					_, object, _ := statusWriter.UpdateArgsForCall(0)
+					conditions := object.(*bdv1.BOSHDeployment).Status.Conditions
+					Expect(bdv1.IsStatusConditionTrue(conditions, bdv1.ConditionManifestResolved)).To(BeTrue())
+					Expect(bdv1.FindStatusCondition(conditions, bdv1.ConditionVariablesGenerated)).To(BeNil())
+
+					condition := bdv1.FindStatusCondition(conditions, bdv1.ConditionInstanceGroupManifestJobReady)
+					Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+					Expect(condition.Reason).To(Equal("InstanceGroupManifestError"))
+					Expect(condition.Message).To(ContainSubstring("fake-error"))
+				})
+
+				It("resets true conditions to unknown, if the reconcile fails before reaching them", func() {
+					instance.Status.Conditions = []bdv1.Condition{
+						{Type: bdv1.ConditionVariableInterpolationJobReady, Status: metav1.ConditionTrue, Reason: "Applied"},
+					}
+					kubeConverter.VariablesReturns(nil, errors.New("fake-error"))
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).To(HaveOccurred())
+
+					_, object, _ := statusWriter.UpdateArgsForCall(0)
+					conditions := object.(*bdv1.BOSHDeployment).Status.Conditions
+					condition := bdv1.FindStatusCondition(conditions, bdv1.ConditionVariableInterpolationJobReady)
+					Expect(condition.Status).To(Equal(metav1.ConditionUnknown))
+					Expect(condition.Reason).To(Equal("Reconciling"))
+					Expect(bdv1.FindStatusCondition(conditions, bdv1.ConditionVariablesGenerated).Reason).To(Equal("BadManifestError"))
+				})
+			})
+
+			Context("when a rollout was stopped by the graceful upgrade timeout", func() {
+				var statusWriter *fakes.FakeStatusWriter
+
+				BeforeEach(func() {
+					statusWriter = &fakes.FakeStatusWriter{}
+					client.StatusCalls(func() crc.StatusWriter { return statusWriter })
+					startedAt := metav1.NewTime(time.Now().Add(-time.Hour))
+					instance.Status.UpdateStartedAt = &startedAt
+					instance.Status.Phase = bdv1.PhaseUpdateFailed
+					instance.Status.LastError = "upgrade timeout"
+				})
+
+				It("starts a new rollout with a new desired manifest", func() {
+					_, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(statusWriter.UpdateCallCount()).To(Equal(1))
+					_, object, _ := statusWriter.UpdateArgsForCall(0)
+					status := object.(*bdv1.BOSHDeployment).Status
+					Expect(status.Phase).To(Equal(bdv1.PhaseApplied))
+					Expect(status.LastError).To(BeEmpty())
+					Expect(status.UpdateStartedAt.Time).To(BeTemporally("~", time.Now(), time.Second))
+				})
+			})
+
+			Context("when the status update conflicts with a concurrent change", func() {
+				var statusWriter *fakes.FakeStatusWriter
+
+				BeforeEach(func() {
+					statusWriter = &fakes.FakeStatusWriter{}
+					client.StatusCalls(func() crc.StatusWriter { return statusWriter })
+					statusWriter.UpdateReturns(apierrors.NewConflict(schema.GroupResource{}, "foo", errors.New("fake-conflict")))
+				})
+
+				AfterEach(func() {
+					Expect(cfd.SetStatusUpdateAttempts(4)).To(Succeed())
+				})
+
+				It("retries the update on the latest version", func() {
+					statusWriter.UpdateReturnsOnCall(1, nil)
+
+					result, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(result).To(Equal(reconcile.Result{}))
+					Expect(statusWriter.UpdateCallCount()).To(Equal(2))
+
+					_, object, _ := statusWriter.UpdateArgsForCall(1)
+					Expect(object.(*bdv1.BOSHDeployment).Status.LastReconcile).ToNot(BeNil())
+				})
+
+				It("requeues after the last attempt failed", func() {
+					Expect(cfd.SetStatusUpdateAttempts(2)).To(Succeed())
+
+					result, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(result.Requeue).To(BeTrue())
+					Expect(statusWriter.UpdateCallCount()).To(Equal(2))
+				})
+
+				It("rejects less than one attempt", func() {
+					Expect(cfd.SetStatusUpdateAttempts(0)).To(MatchError(ContainSubstring("invalid number of status update attempts")))
+				})
+			})
+
+			Context("when the changes are validated on a staging cluster", func() {
+				var (
+					statusWriter     *fakes.FakeStatusWriter
+					stagingValidator *fakes.FakeStagingValidator
+				)
+
+				BeforeEach(func() {
+					instance.Spec.ValidateOnStaging = true
+					statusWriter = &fakes.FakeStatusWriter{}
+					client.StatusCalls(func() crc.StatusWriter { return statusWriter })
+					stagingValidator = &fakes.FakeStagingValidator{}
+					staging = stagingValidator
+				})
+
+				It("applies the changes after a successful dry-run", func() {
+					result, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(result).To(Equal(reconcile.Result{}))
+					Expect(client.CreateCallCount()).To(Equal(2))
+
+					Expect(stagingValidator.DryRunCallCount()).To(Equal(1))
+					_, objects := stagingValidator.DryRunArgsForCall(0)
+					Expect(objects).To(HaveLen(3))
+					Expect(objects[0].(*corev1.Secret).Name).To(Equal("foo.with-ops"))
+					Expect(objects[1]).To(Equal(dmQJob))
+					Expect(objects[2]).To(Equal(igQJob))
+
+					_, object, _ := statusWriter.UpdateArgsForCall(0)
+					Expect(object.(*bdv1.BOSHDeployment).Status.StagingError).To(BeEmpty())
+				})
+
+				It("marks the deployment degraded when the dry-run fails", func() {
+					stagingValidator.DryRunReturns(errors.New("fake-admission-error"))
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("staging validation failed for BOSHDeployment 'default/foo': fake-admission-error"))
+					Expect(client.CreateCallCount()).To(Equal(0))
+					Expect(client.UpdateCallCount()).To(Equal(0))
+
+					Expect(statusWriter.UpdateCallCount()).To(Equal(1))
+					_, object, _ := statusWriter.UpdateArgsForCall(0)
+					status := object.(*bdv1.BOSHDeployment).Status
+					Expect(status.Phase).To(Equal(bdv1.PhaseDegraded))
+					Expect(status.StagingError).To(Equal("fake-admission-error"))
+					Expect(<-recorder.Events).To(ContainSubstring("StagingValidationError"))
+				})
+
+				Context("when no staging cluster is configured", func() {
+					BeforeEach(func() {
+						staging = nil
+					})
+
+					It("marks the deployment degraded", func() {
+						_, err := reconciler.Reconcile(request)
+						Expect(err).To(HaveOccurred())
+						Expect(err.Error()).To(ContainSubstring("no staging cluster configured"))
+						Expect(client.CreateCallCount()).To(Equal(0))
+
+						_, object, _ := statusWriter.UpdateArgsForCall(0)
+						Expect(object.(*bdv1.BOSHDeployment).Status.Phase).To(Equal(bdv1.PhaseDegraded))
+					})
+				})
+			})
+
+			Context("when a variable interpolation job exists", func() {
+				var (
+					qJob         *qjv1a1.QuarksJob
+					manifestYAML string
+					activePods   int32
+				)
+
+				BeforeEach(func() {
+					qJob = dmQJob.DeepCopy()
+					activePods = 1
+
+					manifestYAML = "name: superseded"
+
+					client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+						switch object := object.(type) {
+						case *bdv1.BOSHDeployment:
+							instance.DeepCopyInto(object)
+						case *qjv1a1.QuarksJob:
+							if nn.Name == qJob.Name {
+								qJob.DeepCopyInto(object)
+								return nil
+							}
+							return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+						case *corev1.Secret:
+							if nn.Name == "foo.with-ops" {
+								object.Data = map[string][]byte{"manifest.yaml": []byte(manifestYAML)}
+							}
+						}
+
+						return nil
+					})
+					client.ListCalls(func(context context.Context, object runtime.Object, _ ...crc.ListOption) error {
+						switch object := object.(type) {
+						case *batchv1.JobList:
+							object.Items = []batchv1.Job{
+								{Status: batchv1.JobStatus{Active: activePods}},
+							}
+						}
+						return nil
+					})
+				})
+
+				It("deletes it and requeues, if it is running on a superseded manifest", func() {
+					result, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(result).To(Equal(reconcile.Result{RequeueAfter: 5 * time.Second}))
+
+					Expect(client.DeleteCallCount()).To(Equal(1))
+					_, object, _ := client.DeleteArgsForCall(0)
+					Expect(object.(*qjv1a1.QuarksJob).Name).To(Equal(dmQJob.Name))
+					Expect(client.CreateCallCount()).To(Equal(0))
+					Expect(client.UpdateCallCount()).To(Equal(0))
+				})
+
+				It("waits while it is being deleted", func() {
+					now := metav1.Now()
+					qJob.DeletionTimestamp = &now
+
+					result, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(result).To(Equal(reconcile.Result{RequeueAfter: 5 * time.Second}))
+					Expect(client.DeleteCallCount()).To(Equal(0))
+				})
+
+				It("keeps it, if it isn't running", func() {
+					activePods = 0
+
+					result, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(result).To(Equal(reconcile.Result{}))
+					Expect(client.DeleteCallCount()).To(Equal(0))
+				})
+
+				It("keeps it, if the manifest didn't change", func() {
+					m, err := manifest.Marshal()
+					Expect(err).ToNot(HaveOccurred())
+					manifestYAML = string(m)
+
+					result, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(result).To(Equal(reconcile.Result{}))
+					Expect(client.DeleteCallCount()).To(Equal(0))
+				})
+
+				It("keeps it, if the manifest only differs in the order of its variables", func() {
+					manifest.Variables = append(manifest.Variables, bdm.Variable{Name: "bar_password", Type: "password"})
+					reordered := *manifest
+					reordered.Variables = []bdm.Variable{manifest.Variables[1], manifest.Variables[0]}
+					m, err := reordered.Marshal()
+					Expect(err).ToNot(HaveOccurred())
+					manifestYAML = string(m)
+
+					result, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(result).To(Equal(reconcile.Result{}))
+					Expect(client.DeleteCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("when only inputs of the BPM rendering changed", func() {
+				var (
+					existingDM     *qjv1a1.QuarksJob
+					existingIG     *qjv1a1.QuarksJob
+					manifestYAML   string
+					desiredSecrets []corev1.Secret
+				)
+
+				triggeredJobs := func() []string {
+					triggered := []string{}
+					for i := 0; i < client.UpdateCallCount(); i++ {
+						_, object, _ := client.UpdateArgsForCall(i)
+						if qJob, ok := object.(*qjv1a1.QuarksJob); ok && qJob.Spec.Trigger.Strategy == qjv1a1.TriggerNow {
+							triggered = append(triggered, qJob.Name)
+						}
+					}
+					return triggered
+				}
+
+				BeforeEach(func() {
+					existingDM = dmQJob.DeepCopy()
+					existingDM.Spec.Trigger.Strategy = qjv1a1.TriggerDone
+					existingIG = igQJob.DeepCopy()
+					existingIG.Spec.Trigger.Strategy = qjv1a1.TriggerDone
+					existingIG.Spec.Template.Spec.Template.Spec.Volumes = []corev1.Volume{{Name: "superseded-link"}}
+
+					m, err := manifest.Marshal()
+					Expect(err).ToNot(HaveOccurred())
+					manifestYAML = string(m)
+					desiredSecrets = []corev1.Secret{{ObjectMeta: metav1.ObjectMeta{Name: "foo.desired-manifest-v1"}}}
+
+					client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+						switch object := object.(type) {
+						case *bdv1.BOSHDeployment:
+							instance.DeepCopyInto(object)
+						case *qjv1a1.QuarksJob:
+							switch nn.Name {
+							case existingDM.Name:
+								existingDM.DeepCopyInto(object)
+							case existingIG.Name:
+								existingIG.DeepCopyInto(object)
+							}
+						case *corev1.Secret:
+							if nn.Name == "foo.with-ops" {
+								object.Data = map[string][]byte{"manifest.yaml": []byte(manifestYAML)}
+							}
+						}
+						return nil
+					})
+					client.ListCalls(func(context context.Context, object runtime.Object, _ ...crc.ListOption) error {
+						switch object := object.(type) {
+						case *corev1.SecretList:
+							object.Items = desiredSecrets
+						}
+						return nil
+					})
+				})
+
+				It("skips the variable interpolation and renders the BPM configs again", func() {
+					_, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+
+					for i := 0; i < client.UpdateCallCount(); i++ {
+						_, object, _ := client.UpdateArgsForCall(i)
+						if qJob, ok := object.(*qjv1a1.QuarksJob); ok {
+							Expect(qJob.Name).ToNot(Equal(dmQJob.Name))
+						}
+					}
+					Expect(triggeredJobs()).To(ContainElement(igQJob.Name))
+					Expect(triggeredJobs()).ToNot(ContainElement(dmQJob.Name))
+					Expect(<-recorder.Events).To(ContainSubstring("SkipVariableInterpolation"))
+				})
+
+				It("runs the full pipeline, if the with-ops manifest changed", func() {
+					manifestYAML = "name: superseded"
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(triggeredJobs()).To(BeEmpty())
+				})
+
+				It("runs the full pipeline, if the variable interpolation job changed", func() {
+					existingDM.Spec.Template.Spec.Template.Spec.Volumes = []corev1.Volume{{Name: "superseded-variable"}}
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(triggeredJobs()).To(BeEmpty())
+				})
+
+				It("runs the full pipeline, if there is no desired manifest", func() {
+					desiredSecrets = nil
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(triggeredJobs()).To(BeEmpty())
+				})
+
+				It("leaves the instance group job alone, if it didn't change", func() {
+					existingIG = igQJob.DeepCopy()
+					existingIG.Spec.Trigger.Strategy = qjv1a1.TriggerDone
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(triggeredJobs()).To(BeEmpty())
+				})
+			})
+
+			Context("when the interpolation timeout is configured", func() {
+				var (
+					statusWriter *fakes.FakeStatusWriter
+					startTime    metav1.Time
+				)
+
+				BeforeEach(func() {
+					Expect(cfd.SetInterpolationTimeout(time.Hour, cfd.InterpolationTimeoutRecreate)).To(Succeed())
+					startTime = metav1.NewTime(time.Now().Add(-2 * time.Hour))
+
+					statusWriter = &fakes.FakeStatusWriter{}
+					client.StatusCalls(func() crc.StatusWriter { return statusWriter })
+					client.ListCalls(func(context context.Context, object runtime.Object, _ ...crc.ListOption) error {
+						switch object := object.(type) {
+						case *batchv1.JobList:
+							object.Items = []batchv1.Job{
+								{Status: batchv1.JobStatus{Active: 1, StartTime: &startTime}},
+							}
+						}
+						return nil
+					})
+				})
+
+				AfterEach(func() {
+					Expect(cfd.SetInterpolationTimeout(0, cfd.InterpolationTimeoutRecreate)).To(Succeed())
+				})
+
+				It("deletes a job exceeding the timeout and requeues", func() {
+					result, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(result).To(Equal(reconcile.Result{RequeueAfter: 5 * time.Second}))
+
+					Expect(client.DeleteCallCount()).To(Equal(1))
+					_, object, _ := client.DeleteArgsForCall(0)
+					Expect(object.(*qjv1a1.QuarksJob).Name).To(Equal(dmQJob.Name))
+					Expect(client.CreateCallCount()).To(Equal(0))
+					Expect(<-recorder.Events).To(ContainSubstring("InterpolationTimeout"))
+				})
+
+				It("marks the deployment degraded, if configured", func() {
+					Expect(cfd.SetInterpolationTimeout(time.Hour, cfd.InterpolationTimeoutDegrade)).To(Succeed())
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("longer than 1h0m0s"))
+
+					Expect(client.DeleteCallCount()).To(Equal(0))
+					_, object, _ := statusWriter.UpdateArgsForCall(0)
+					Expect(object.(*bdv1.BOSHDeployment).Status.Phase).To(Equal(bdv1.PhaseDegraded))
+				})
+
+				It("requeues to check a new job again after the timeout", func() {
+					startTime = metav1.NewTime(time.Now().Add(-10 * time.Minute))
+
+					result, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(result).To(Equal(reconcile.Result{RequeueAfter: time.Hour}))
+					Expect(client.DeleteCallCount()).To(Equal(0))
+				})
+
+				It("rejects unknown recovery actions", func() {
+					Expect(cfd.SetInterpolationTimeout(time.Hour, "restart")).To(MatchError(ContainSubstring("invalid interpolation timeout action")))
+				})
+			})
+
+			Context("when the manifest is a git reference", func() {
+				BeforeEach(func() {
+					instance.Spec.Manifest = bdv1.ResourceReference{
+						Type: bdv1.GitReference,
+						Name: "https://example.com/deployments.git",
+						Git:  &bdv1.GitSource{Path: "manifest.yml"},
+					}
+				})
+
+				AfterEach(func() {
+					Expect(cfd.SetGitPollInterval(5 * time.Minute)).To(Succeed())
+				})
+
+				It("requeues after the git poll interval", func() {
+					result, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(result).To(Equal(reconcile.Result{RequeueAfter: 5 * time.Minute}))
+				})
+
+				It("doesn't poll references pinned to a commit", func() {
+					instance.Spec.Manifest.Git.Commit = "0123456789abcdef0123456789abcdef01234567"
+
+					result, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(result).To(Equal(reconcile.Result{}))
+				})
+
+				It("doesn't poll, if polling is disabled", func() {
+					Expect(cfd.SetGitPollInterval(0)).To(Succeed())
+
+					result, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(result).To(Equal(reconcile.Result{}))
+				})
+
+				It("rejects negative poll intervals", func() {
+					Expect(cfd.SetGitPollInterval(-time.Minute)).To(MatchError(ContainSubstring("invalid git poll interval")))
+				})
+			})
+
+			Context("when the manifest is compressed", func() {
+				BeforeEach(func() {
+					instance.Spec.CompressManifest = true
+
+					client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+						switch object := object.(type) {
+						case *bdv1.BOSHDeployment:
+							instance.DeepCopyInto(object)
+						case *qjv1a1.QuarksJob, *corev1.Secret:
+							return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+						}
+						return nil
+					})
+				})
+
+				It("stores the with-ops manifest gzip compressed", func() {
+					_, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+
+					var manifestSecret *corev1.Secret
+					for i := 0; i < client.CreateCallCount(); i++ {
+						_, object, _ := client.CreateArgsForCall(i)
+						if secret, ok := object.(*corev1.Secret); ok && secret.Name == "foo.with-ops" {
+							manifestSecret = secret
+						}
+					}
+					Expect(manifestSecret).ToNot(BeNil())
+					Expect(manifestSecret.StringData).To(BeEmpty())
+					Expect(manifestSecret.Data).To(HaveKey(bdm.CompressedManifestKeyName))
+					Expect(manifestSecret.Data).ToNot(HaveKey(bdm.DesiredManifestKeyName))
+
+					manifestBytes, err := bdm.SecretData(manifestSecret.Data)
+					Expect(err).ToNot(HaveOccurred())
+					expected, err := manifest.Marshal()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(manifestBytes).To(Equal(expected))
+				})
+			})
+
+			Context("when instance groups are suspended", func() {
+				var statusWriter *fakes.FakeStatusWriter
+
+				BeforeEach(func() {
+					manifest.InstanceGroups = append(manifest.InstanceGroups, &bdm.InstanceGroup{Name: "other"})
+					instance.Annotations = map[string]string{
+						bdv1.AnnotationSuspendedInstanceGroups: "fakepod, unknown",
+					}
+					statusWriter = &fakes.FakeStatusWriter{}
+					client.StatusCalls(func() crc.StatusWriter { return statusWriter })
+				})
+
+				It("builds the instance group manifest qJob without them", func() {
+					_, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+
+					_, igManifest, _, _, _ := jobFactory.InstanceGroupManifestJobArgsForCall(0)
+					Expect(igManifest.InstanceGroups).To(HaveLen(1))
+					Expect(igManifest.InstanceGroups[0].Name).To(Equal("other"))
+
+					_, dmManifest, _, _, _ := jobFactory.VariableInterpolationJobArgsForCall(0)
+					Expect(dmManifest.InstanceGroups).To(HaveLen(2))
+				})
+
+				It("reports them in the status", func() {
+					_, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+
+					_, object, _ := statusWriter.UpdateArgsForCall(0)
+					Expect(object.(*bdv1.BOSHDeployment).Status.SuspendedInstanceGroups).To(Equal([]string{"fakepod"}))
+				})
+			})
+
+			Context("when instance groups are ignored", func() {
+				BeforeEach(func() {
+					manifest.InstanceGroups = append(manifest.InstanceGroups, &bdm.InstanceGroup{Name: "other"})
+					instance.Spec.IgnoredInstanceGroups = []string{"fakepod", "unknown"}
+				})
+
+				It("builds the instance group manifest qJob without them", func() {
+					_, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+
+					_, igManifest, _, _, _ := jobFactory.InstanceGroupManifestJobArgsForCall(0)
+					Expect(igManifest.InstanceGroups).To(HaveLen(1))
+					Expect(igManifest.InstanceGroups[0].Name).To(Equal("other"))
+
+					_, dmManifest, _, _, _ := jobFactory.VariableInterpolationJobArgsForCall(0)
+					Expect(dmManifest.InstanceGroups).To(HaveLen(2))
+				})
+
+				It("emits an event for each ignored instance group of the manifest", func() {
+					_, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+
+					event := <-recorder.Events
+					Expect(event).To(ContainSubstring("InstanceGroupIgnored"))
+					Expect(event).To(ContainSubstring("fakepod"))
+					Expect(recorder.Events).ToNot(Receive(ContainSubstring("InstanceGroupIgnored")))
+				})
+			})
+
+			Context("when job DNS settings are configured", func() {
+				BeforeEach(func() {
+					instance.Spec.JobDNS = &bdv1.JobDNS{
+						DNSPolicy: corev1.DNSNone,
+						DNSConfig: &corev1.PodDNSConfig{Nameservers: []string{"10.0.0.53"}},
+					}
+				})
+
+				It("builds both qJobs with them", func() {
+					_, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+
+					_, _, _, dns, _ := jobFactory.VariableInterpolationJobArgsForCall(0)
+					Expect(dns).To(Equal(instance.Spec.JobDNS))
+
+					_, _, _, _, dns = jobFactory.InstanceGroupManifestJobArgsForCall(0)
+					Expect(dns).To(Equal(instance.Spec.JobDNS))
+				})
+			})
+
+			Context("when a variable interpolation trigger secret is set", func() {
+				var triggerSecretFound bool
+
+				BeforeEach(func() {
+					instance.Spec.VariableInterpolationTriggerSecret = "first-pass"
+					triggerSecretFound = true
+
+					client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+						switch object := object.(type) {
+						case *bdv1.BOSHDeployment:
+							instance.DeepCopyInto(object)
+						case *qjv1a1.QuarksJob:
+							return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+						case *corev1.Secret:
+							if nn.Name == "first-pass" {
+								if !triggerSecretFound {
+									return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+								}
+								object.Data = map[string][]byte{
+									bdm.DesiredManifestKeyName: []byte("name: foo\nvariables:\n- name: second_pass_password\n  type: password\n"),
+								}
+							}
+						}
+						return nil
+					})
+				})
+
+				It("builds the variable interpolation qJob with the trigger manifest", func() {
+					_, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+
+					_, m, _, _, triggerSecret := jobFactory.VariableInterpolationJobArgsForCall(0)
+					Expect(triggerSecret).To(Equal("first-pass"))
+					Expect(m.Variables).To(HaveLen(1))
+					Expect(m.Variables[0].Name).To(Equal("second_pass_password"))
+
+					_, variables := kubeConverter.VariablesArgsForCall(0)
+					Expect(variables).To(Equal(m.Variables))
+				})
+
+				It("records the trigger manifest on the variable interpolation qJob", func() {
+					_, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(dmQJob.Spec.Template.Spec.Template.Annotations).To(HaveKey(bdv1.AnnotationTriggerManifestSHA1))
+				})
+
+				It("waits for a missing trigger secret", func() {
+					triggerSecretFound = false
+
+					result, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+					Expect(jobFactory.VariableInterpolationJobCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("when the manifest references keys of variable secrets", func() {
+				BeforeEach(func() {
+					manifest.Variables = append(manifest.Variables, bdm.Variable{Name: "foo_cert", Type: "certificate"})
+					manifest.InstanceGroups[0].Jobs[0].Properties.Properties["cert"] = "((foo_cert.certificate))"
+					manifest.InstanceGroups[0].Jobs[0].Properties.Properties["key"] = "((foo_cert.private_key))"
+
+					client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+						switch object := object.(type) {
+						case *bdv1.BOSHDeployment:
+							instance.DeepCopyInto(object)
+						case *qjv1a1.QuarksJob:
+							return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+						case *corev1.Secret:
+							if nn.Name == "foo.var-foo-cert" {
+								object.Data = map[string][]byte{"certificate": []byte("cert")}
+							}
+						}
+						return nil
+					})
+				})
+
+				It("fails with an event naming the missing key", func() {
+					_, err := reconciler.Reconcile(request)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("secret 'foo.var-foo-cert' has no key 'private_key'"))
+					Expect(err.Error()).ToNot(ContainSubstring("'certificate'"))
+					Expect(<-recorder.Events).To(ContainSubstring("MissingSecretReference"))
+					Expect(jobFactory.VariableInterpolationJobCallCount()).To(Equal(0))
+				})
+
+				It("skips the check for deployments with external secrets", func() {
+					instance.Spec.ExternalSecretSelector = &metav1.LabelSelector{}
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(jobFactory.VariableInterpolationJobCallCount()).To(Equal(1))
+				})
+
+				It("only warns for environments with lenient validation", func() {
+					defer cfd.SetEnvironmentProfiles("")
+					setEnvironmentProfiles("dev:\n  lenientValidation: true\n")
+					instance.Annotations = map[string]string{bdv1.AnnotationEnvironment: "dev"}
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(<-recorder.Events).To(ContainSubstring("MissingSecretReference"))
+					Expect(jobFactory.VariableInterpolationJobCallCount()).To(Equal(1))
+				})
+			})
+
+			Context("when the namespace enforces a pod security standard", func() {
+				BeforeEach(func() {
+					dmQJob.Spec.Template.Spec.Template.Spec.Containers = []corev1.Container{{Name: "interpolation"}}
+
+					client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+						switch object := object.(type) {
+						case *bdv1.BOSHDeployment:
+							instance.DeepCopyInto(object)
+						case *qjv1a1.QuarksJob:
+							return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+						case *unstructured.Unstructured:
+							object.SetName(nn.Name)
+							object.SetLabels(map[string]string{"pod-security.kubernetes.io/enforce": "restricted"})
+						}
+						return nil
+					})
+				})
+
+				It("fails with an event describing the violations, before creating anything", func() {
+					_, err := reconciler.Reconcile(request)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("violate the pod security standard of namespace 'default'"))
+					Expect(err.Error()).To(ContainSubstring("QuarksJob 'dm-foo': container 'interpolation' must set securityContext.allowPrivilegeEscalation=false"))
+					Expect(<-recorder.Events).To(ContainSubstring("PodSecurityViolation"))
+					Expect(client.CreateCallCount()).To(Equal(0))
+				})
+
+				It("creates the jobs, if they comply", func() {
+					dmQJob.Spec.Template.Spec.Template.Spec.SecurityContext = &corev1.PodSecurityContext{RunAsNonRoot: pointers.Bool(true)}
+					dmQJob.Spec.Template.Spec.Template.Spec.Containers[0].SecurityContext = &corev1.SecurityContext{
+						AllowPrivilegeEscalation: pointers.Bool(false),
+						Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+					}
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+				})
+			})
+
+			Context("when environment profiles are configured", func() {
+				BeforeEach(func() {
+					config.MeltdownDuration = 0
+					config.MeltdownRequeueAfter = time.Minute
+					instance.Status.LastReconcile = &metav1.Time{Time: time.Now()}
+					setEnvironmentProfiles("default:\n  meltdownRequeueAfter: 10s\nprod:\n  meltdownDuration: 1h\n  meltdownRequeueAfter: 30s\n")
+				})
+
+				AfterEach(func() {
+					Expect(cfd.SetEnvironmentProfiles("")).To(Succeed())
+				})
+
+				It("applies the meltdown tuning of the annotated environment", func() {
+					instance.Annotations = map[string]string{bdv1.AnnotationEnvironment: "prod"}
+
+					result, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(result.RequeueAfter).To(Equal(30 * time.Second))
+					Expect(jobFactory.VariableInterpolationJobCallCount()).To(Equal(0))
+				})
+
+				It("uses the default profile for unknown environments", func() {
+					instance.Annotations = map[string]string{bdv1.AnnotationEnvironment: "qa"}
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(jobFactory.VariableInterpolationJobCallCount()).To(Equal(1))
+				})
+
+				It("rejects invalid profiles", func() {
+					file := writeTempFile("prod:\n  meltdownDuration: -1m\n")
+					defer os.Remove(file)
+					Expect(cfd.SetEnvironmentProfiles(file)).To(MatchError(ContainSubstring("invalid meltdown duration")))
+
+					file = writeTempFile("prod:\n  strict: true\n")
+					defer os.Remove(file)
+					Expect(cfd.SetEnvironmentProfiles(file)).To(MatchError(ContainSubstring("parsing environment profiles")))
+				})
+			})
+
+			Context("when a storage class mapping is configured", func() {
+				BeforeEach(func() {
+					manifest.InstanceGroups[0].PersistentDiskType = "fast"
+					file := writeTempFile("fast: ssd\n")
+					defer os.Remove(file)
+					Expect(cfd.SetStorageClassMapping(file)).To(Succeed())
+
+					client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+						switch object := object.(type) {
+						case *bdv1.BOSHDeployment:
+							instance.DeepCopyInto(object)
+						case *qjv1a1.QuarksJob:
+							return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+						case *unstructured.Unstructured:
+							if object.GetKind() == "StorageClass" && nn.Name != "ssd" {
+								return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+							}
+						}
+						return nil
+					})
+				})
+
+				AfterEach(func() {
+					Expect(cfd.SetStorageClassMapping("")).To(Succeed())
+				})
+
+				It("rolls out instance groups, whose storage class exists", func() {
+					_, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(jobFactory.VariableInterpolationJobCallCount()).To(Equal(1))
+				})
+
+				It("fails with an event, if the storage class doesn't exist", func() {
+					file := writeTempFile("fast: premium\n")
+					defer os.Remove(file)
+					Expect(cfd.SetStorageClassMapping(file)).To(Succeed())
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("instance group 'fakepod' uses persistent disk type 'fast', whose storage class 'premium' doesn't exist"))
+					Expect(<-recorder.Events).To(ContainSubstring("MissingStorageClass"))
+					Expect(client.CreateCallCount()).To(Equal(0))
+				})
+
+				It("fails with an event, if the disk type isn't mapped", func() {
+					manifest.InstanceGroups[0].PersistentDiskType = "slow"
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("instance group 'fakepod' uses unmapped persistent disk type 'slow'"))
+					Expect(<-recorder.Events).To(ContainSubstring("MissingStorageClass"))
+				})
+
+				It("skips instance groups without persistent disk type", func() {
+					manifest.InstanceGroups[0].PersistentDiskType = ""
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+				})
+
+				It("rejects invalid mappings", func() {
+					file := writeTempFile("fast: ''\n")
+					defer os.Remove(file)
+					Expect(cfd.SetStorageClassMapping(file)).To(MatchError(ContainSubstring("empty storage class")))
+				})
+			})
+
+			Context("when the status is updated", func() {
+				var statusWriter *fakes.FakeStatusWriter
+
+				BeforeEach(func() {
+					statusWriter = &fakes.FakeStatusWriter{}
+					client.StatusCalls(func() crc.StatusWriter { return statusWriter })
+				})
+
+				It("records a new correlation ID for each reconcile", func() {
+					_, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					_, err = reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(statusWriter.UpdateCallCount()).To(Equal(2))
+					_, first, _ := statusWriter.UpdateArgsForCall(0)
+					_, second, _ := statusWriter.UpdateArgsForCall(1)
+					firstID := first.(*bdv1.BOSHDeployment).Status.CorrelationID
+					Expect(firstID).To(MatchRegexp("^[0-9a-f]{8}$"))
+					Expect(second.(*bdv1.BOSHDeployment).Status.CorrelationID).ToNot(Equal(firstID))
+				})
+			})
+
+			Context("when QuarksJobs are created or updated", func() {
+				var statusWriter *fakes.FakeStatusWriter
+
+				BeforeEach(func() {
+					instance.Generation = 3
+					statusWriter = &fakes.FakeStatusWriter{}
+					client.StatusCalls(func() crc.StatusWriter { return statusWriter })
+				})
+
+				It("annotates new jobs with the correlation ID and the deployment generation", func() {
+					_, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+
+					_, object, _ := statusWriter.UpdateArgsForCall(0)
+					correlationID := object.(*bdv1.BOSHDeployment).Status.CorrelationID
+
+					jobs := 0
+					for i := 0; i < client.CreateCallCount(); i++ {
+						_, object, _ := client.CreateArgsForCall(i)
+						if qJob, ok := object.(*qjv1a1.QuarksJob); ok {
+							Expect(qJob.Annotations).To(HaveKeyWithValue(correlation.AnnotationCorrelationID, correlationID))
+							Expect(qJob.Annotations).To(HaveKeyWithValue(bdv1.AnnotationDeploymentGeneration, "3"))
+							jobs++
+						}
+					}
+					Expect(jobs).To(Equal(2))
+				})
+
+				It("doesn't update unchanged jobs", func() {
+					existing := map[string]*qjv1a1.QuarksJob{}
+					for _, qJob := range []*qjv1a1.QuarksJob{dmQJob, igQJob} {
+						existing[qJob.Name] = qJob.DeepCopy()
+						existing[qJob.Name].ResourceVersion = "1"
+						Expect(controllerutil.SetControllerReference(instance, existing[qJob.Name], scheme.Scheme)).To(Succeed())
+					}
+					client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+						switch object := object.(type) {
+						case *bdv1.BOSHDeployment:
+							instance.DeepCopyInto(object)
+						case *qjv1a1.QuarksJob:
+							existing[nn.Name].DeepCopyInto(object)
+						}
+						return nil
+					})
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					for i := 0; i < client.UpdateCallCount(); i++ {
+						_, object, _ := client.UpdateArgsForCall(i)
+						Expect(object).ToNot(BeAssignableToTypeOf(&qjv1a1.QuarksJob{}))
+					}
+				})
+
+				It("repairs missing owner references of existing jobs", func() {
+					existing := map[string]*qjv1a1.QuarksJob{}
+					for _, qJob := range []*qjv1a1.QuarksJob{dmQJob, igQJob} {
+						existing[qJob.Name] = qJob.DeepCopy()
+						existing[qJob.Name].ResourceVersion = "1"
+					}
+					client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+						switch object := object.(type) {
+						case *bdv1.BOSHDeployment:
+							instance.DeepCopyInto(object)
+						case *qjv1a1.QuarksJob:
+							existing[nn.Name].DeepCopyInto(object)
+						}
+						return nil
+					})
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+
+					jobs := 0
+					for i := 0; i < client.UpdateCallCount(); i++ {
+						_, object, _ := client.UpdateArgsForCall(i)
+						if qJob, ok := object.(*qjv1a1.QuarksJob); ok {
+							owner := metav1.GetControllerOf(qJob)
+							Expect(owner).ToNot(BeNil())
+							Expect(owner.Name).To(Equal(instance.Name))
+							jobs++
+						}
+					}
+					Expect(jobs).To(Equal(2))
+
+					reasons := []string{}
+					for len(recorder.Events) > 0 {
+						reasons = append(reasons, <-recorder.Events)
+					}
+					Expect(reasons).To(ContainElement(ContainSubstring("OwnerReferenceRepaired")))
+				})
+			})
+
+			Context("when updating a QuarksJob conflicts with a concurrent change", func() {
+				BeforeEach(func() {
+					conflict := func(object runtime.Object) error {
+						if qJob, ok := object.(*qjv1a1.QuarksJob); ok {
+							return apierrors.NewConflict(schema.GroupResource{}, qJob.Name, errors.New("fake-error"))
+						}
+						return nil
+					}
+					client.CreateCalls(func(_ context.Context, object runtime.Object, _ ...crc.CreateOption) error {
+						return conflict(object)
+					})
+					client.UpdateCalls(func(_ context.Context, object runtime.Object, _ ...crc.UpdateOption) error {
+						return conflict(object)
+					})
+				})
+
+				AfterEach(func() {
+					Expect(cfd.SetQJobConflictRequeue(time.Second, 5)).To(Succeed())
+				})
+
+				It("requeues the reconcile without an error", func() {
+					Expect(cfd.SetQJobConflictRequeue(2*time.Second, 5)).To(Succeed())
+
+					result, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(result.RequeueAfter).To(Equal(2 * time.Second))
+					Expect(recorder.Events).To(BeEmpty())
+				})
+
+				It("reports an error once the conflicts persist", func() {
+					Expect(cfd.SetQJobConflictRequeue(time.Second, 2)).To(Succeed())
+
+					result, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(result.RequeueAfter).To(Equal(time.Second))
+
+					_, err = reconciler.Reconcile(request)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("failed to create desired manifest qJob"))
+					Expect(<-recorder.Events).To(ContainSubstring("DesiredManifestError"))
+				})
+
+				It("rejects invalid settings", func() {
+					Expect(cfd.SetQJobConflictRequeue(0, 5)).ToNot(Succeed())
+					Expect(cfd.SetQJobConflictRequeue(time.Second, 0)).ToNot(Succeed())
+				})
+			})
+
+			Context("when manifest debug mode is enabled", func() {
+				var (
+					debugConfigMap *corev1.ConfigMap
+					pods           []corev1.Pod
+					existingQJob   *qjv1a1.QuarksJob
+					createdQJob    *qjv1a1.QuarksJob
+				)
+
+				// reconcileUnchangedQJob reconciles twice, so the second
+				// reconcile finds the variable interpolation job unchanged
+				reconcileUnchangedQJob := func() (reconcile.Result, error) {
+					_, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					existingQJob = createdQJob.DeepCopy()
+					existingQJob.ResourceVersion = "1"
+					return reconciler.Reconcile(request)
+				}
+
+				BeforeEach(func() {
+					instance.Spec.ManifestDebugMode = true
+					debugConfigMap = nil
+					pods = []corev1.Pod{}
+					existingQJob = nil
+					createdQJob = nil
+
+					client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+						switch object := object.(type) {
+						case *bdv1.BOSHDeployment:
+							instance.DeepCopyInto(object)
+						case *qjv1a1.QuarksJob:
+							if existingQJob != nil && nn.Name == existingQJob.Name {
+								existingQJob.DeepCopyInto(object)
+								return nil
+							}
+							return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+						case *corev1.ConfigMap:
+							if debugConfigMap == nil || nn.Name != "foo-interpolation-debug" {
+								return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+							}
+							debugConfigMap.DeepCopyInto(object)
+						}
+						return nil
+					})
+					client.ListCalls(func(context context.Context, object runtime.Object, _ ...crc.ListOption) error {
+						switch object := object.(type) {
+						case *corev1.PodList:
+							podList := corev1.PodList{Items: pods}
+							podList.DeepCopyInto(object)
+						}
+						return nil
+					})
+					client.CreateCalls(func(context context.Context, object runtime.Object, _ ...crc.CreateOption) error {
+						switch object := object.(type) {
+						case *corev1.ConfigMap:
+							if object.Name == "foo-interpolation-debug" {
+								debugConfigMap = object.DeepCopy()
+							}
+						case *qjv1a1.QuarksJob:
+							if object.Name == dmQJob.Name {
+								createdQJob = object.DeepCopy()
+							}
+						}
+						return nil
+					})
+					client.UpdateCalls(func(context context.Context, object runtime.Object, _ ...crc.UpdateOption) error {
+						switch object := object.(type) {
+						case *corev1.ConfigMap:
+							if object.Name == "foo-interpolation-debug" {
+								debugConfigMap = object.DeepCopy()
+							}
+						}
+						return nil
+					})
+				})
+
+				It("builds the desired manifest qJob in debug mode", func() {
+					_, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+
+					_, _, debug, _, _ := jobFactory.VariableInterpolationJobArgsForCall(0)
+					Expect(debug).To(BeTrue())
+				})
+
+				Context("when the interpolation pod did not succeed", func() {
+					BeforeEach(func() {
+						pods = []corev1.Pod{
+							{
+								ObjectMeta: metav1.ObjectMeta{Name: "dm-foo-old", Namespace: "default", CreationTimestamp: metav1.NewTime(time.Now().Add(-time.Hour))},
+							},
+							{
+								ObjectMeta: metav1.ObjectMeta{Name: "dm-foo-new", Namespace: "default", CreationTimestamp: metav1.Now()},
+								Status: corev1.PodStatus{
+									Phase: corev1.PodRunning,
+									ContainerStatuses: []corev1.ContainerStatus{
+										{
+											Name:                 "desired-manifest",
+											LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 1}},
+										},
+									},
+								},
+							},
+						}
+						podLogs.GetLogsReturns([]byte("interpolation failed"), nil)
+					})
+
+					It("captures the output of the latest pod in a config map", func() {
+						result, err := reconciler.Reconcile(request)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(result.RequeueAfter).To(Equal(10 * time.Second))
+
+						_, namespace, podName, opts := podLogs.GetLogsArgsForCall(0)
+						Expect(namespace).To(Equal("default"))
+						Expect(podName).To(Equal("dm-foo-new"))
+						Expect(opts.Container).To(Equal("desired-manifest"))
+						Expect(opts.Previous).To(BeTrue())
+
+						Expect(debugConfigMap).ToNot(BeNil())
+						Expect(debugConfigMap.Name).To(Equal("foo-interpolation-debug"))
+						Expect(debugConfigMap.Data).To(Equal(map[string]string{
+							"pod":    "dm-foo-new",
+							"output": "interpolation failed",
+						}))
+					})
+
+					It("keeps only the last 64KB of output", func() {
+						output := strings.Repeat("a", 1024) + strings.Repeat("b", 64*1024)
+						podLogs.GetLogsReturns([]byte(output), nil)
+
+						_, err := reconciler.Reconcile(request)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(debugConfigMap.Data["output"]).To(Equal(strings.Repeat("b", 64*1024)))
+					})
+
+					It("handles an error when reading the logs", func() {
+						podLogs.GetLogsReturns(nil, errors.New("fake-error"))
+
+						_, err := reconciler.Reconcile(request)
+						Expect(err).To(HaveOccurred())
+						Expect(err.Error()).To(ContainSubstring("failed to capture variable interpolation output for BOSHDeployment 'default/foo'"))
+					})
+
+					It("stops following the job, once the pod failed", func() {
+						pods[1].Status.Phase = corev1.PodFailed
+
+						result, err := reconcileUnchangedQJob()
+						Expect(err).ToNot(HaveOccurred())
+						Expect(result.RequeueAfter).To(BeZero())
+						Expect(debugConfigMap.Data["output"]).To(Equal("interpolation failed"))
+					})
+
+					It("keeps the earlier requeues of the reconcile", func() {
+						instance.Spec.Manifest = bdv1.ResourceReference{
+							Type: bdv1.GitReference,
+							Name: "https://example.com/deployments.git",
+							Git:  &bdv1.GitSource{Path: "manifest.yml"},
+						}
+						Expect(cfd.SetGitPollInterval(5 * time.Second)).To(Succeed())
+						defer func() { Expect(cfd.SetGitPollInterval(5 * time.Minute)).To(Succeed()) }()
+
+						result, err := reconciler.Reconcile(request)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(result.RequeueAfter).To(Equal(5 * time.Second))
+						Expect(debugConfigMap.Data["output"]).To(Equal("interpolation failed"))
+					})
+				})
+
+				Context("when the interpolation pod succeeded", func() {
+					BeforeEach(func() {
+						debugConfigMap = &corev1.ConfigMap{
+							ObjectMeta: metav1.ObjectMeta{Name: "foo-interpolation-debug", Namespace: "default"},
+							Data:       map[string]string{"output": "interpolation failed"},
+						}
+					})
+
+					It("clears the config map", func() {
+						_, err := reconciler.Reconcile(request)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(podLogs.GetLogsCallCount()).To(Equal(0))
+						Expect(debugConfigMap.Data).To(BeEmpty())
+					})
+
+					It("follows a job, which was just started, until its pod exists", func() {
+						result, err := reconciler.Reconcile(request)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(result.RequeueAfter).To(Equal(10 * time.Second))
+					})
+
+					It("stops following an unchanged job", func() {
+						result, err := reconcileUnchangedQJob()
+						Expect(err).ToNot(HaveOccurred())
+						Expect(result.RequeueAfter).To(BeZero())
+					})
+				})
+			})
+
+			Context("when the observability bundle is enabled", func() {
+				var dashboard *corev1.ConfigMap
+
+				BeforeEach(func() {
+					instance.Spec.Features.ObservabilityBundle = true
+					dashboard = nil
+
+					client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+						switch object := object.(type) {
+						case *bdv1.BOSHDeployment:
+							instance.DeepCopyInto(object)
+						case *qjv1a1.QuarksJob, *corev1.ConfigMap:
+							return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+						}
+						return nil
+					})
+					client.CreateCalls(func(context context.Context, object runtime.Object, _ ...crc.CreateOption) error {
+						if cm, ok := object.(*corev1.ConfigMap); ok && cm.Name == "foo-grafana-dashboard" {
+							dashboard = cm.DeepCopy()
+						}
+						return nil
+					})
+				})
+
+				It("creates a Grafana dashboard config map for the deployment", func() {
+					_, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(dashboard).ToNot(BeNil())
+					Expect(dashboard.Labels).To(HaveKeyWithValue("grafana_dashboard", "1"))
+					Expect(dashboard.Labels).To(HaveKeyWithValue(bdv1.LabelDeploymentName, "foo"))
+					Expect(dashboard.OwnerReferences).To(HaveLen(1))
+
+					var content map[string]interface{}
+					Expect(json.Unmarshal([]byte(dashboard.Data[cfd.GrafanaDashboardKey]), &content)).To(Succeed())
+					Expect(content).To(HaveKeyWithValue("title", "BOSHDeployment default/foo"))
+					Expect(dashboard.Data[cfd.GrafanaDashboardKey]).To(ContainSubstring(`namespace=\"default\", pod=~\"foo-.*\"`))
+					Expect(dashboard.Data[cfd.GrafanaDashboardKey]).To(ContainSubstring(`"legendFormat": "{{pod}}"`))
+				})
+
+				It("handles an error when creating the dashboard", func() {
+					client.CreateCalls(func(context context.Context, object runtime.Object, _ ...crc.CreateOption) error {
+						if cm, ok := object.(*corev1.ConfigMap); ok && cm.Name == "foo-grafana-dashboard" {
+							return errors.New("fake-error")
+						}
+						return nil
+					})
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("failed to reconcile Grafana dashboard for BOSHDeployment 'default/foo'"))
+				})
+			})
+
+			Context("when the observability bundle is disabled", func() {
+				var dashboard *corev1.ConfigMap
+
+				BeforeEach(func() {
+					instance.UID = "bdpl-uid"
+					dashboard = &corev1.ConfigMap{
+						ObjectMeta: metav1.ObjectMeta{Name: "foo-grafana-dashboard", Namespace: "default"},
+					}
+
+					client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+						switch object := object.(type) {
+						case *bdv1.BOSHDeployment:
+							instance.DeepCopyInto(object)
+						case *qjv1a1.QuarksJob:
+							return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+						case *corev1.ConfigMap:
+							if nn.Name != "foo-grafana-dashboard" {
+								return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+							}
+							dashboard.DeepCopyInto(object)
+						}
+						return nil
+					})
+				})
+
+				It("deletes the dashboard config map created for the deployment", func() {
+					Expect(controllerutil.SetControllerReference(instance, dashboard, scheme.Scheme)).To(Succeed())
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(client.DeleteCallCount()).To(Equal(1))
+					_, object, _ := client.DeleteArgsForCall(0)
+					Expect(object.(*corev1.ConfigMap).Name).To(Equal("foo-grafana-dashboard"))
+				})
+
+				It("keeps a config map, which isn't controlled by the deployment", func() {
+					_, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(client.DeleteCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("when a phase notification webhook is configured", func() {
+				var (
+					server        *httptest.Server
+					notifications chan string
+				)
+
+				BeforeEach(func() {
+					notifications = make(chan string, 10)
+					server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+						b, _ := ioutil.ReadAll(r.Body)
+						notifications <- string(b)
+					}))
+					Expect(cfd.SetPhaseNotification(server.URL, time.Second, 0)).To(Succeed())
+				})
+
+				AfterEach(func() {
+					Expect(cfd.SetPhaseNotification("", 0, 0)).To(Succeed())
+					server.Close()
+				})
+
+				It("posts the phase transition", func() {
+					_, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+
+					var notification map[string]interface{}
+					Eventually(notifications).Should(Receive(WithTransform(func(body string) error {
+						return json.Unmarshal([]byte(body), &notification)
+					}, Succeed())))
+					Expect(notification).To(HaveKeyWithValue("deployment", "foo"))
+					Expect(notification).To(HaveKeyWithValue("namespace", "default"))
+					Expect(notification).To(HaveKeyWithValue("phase", bdv1.PhaseApplied))
+					Expect(notification).To(HaveKeyWithValue("previousPhase", ""))
+				})
+
+				It("doesn't post, if the phase didn't change", func() {
+					instance.Status.Phase = bdv1.PhaseApplied
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Consistently(notifications, "200ms").ShouldNot(Receive())
+				})
+
+				It("rejects invalid settings", func() {
+					Expect(cfd.SetPhaseNotification("ftp://example.com", time.Second, 0)).ToNot(Succeed())
+					Expect(cfd.SetPhaseNotification(server.URL, 0, 0)).ToNot(Succeed())
+					Expect(cfd.SetPhaseNotification(server.URL, time.Second, -1)).ToNot(Succeed())
+				})
+			})
+
+			Context("when a Config Server is configured", func() {
+				var (
+					generated bool
+					password  string
+				)
+
+				BeforeEach(func() {
+					Expect(cfd.SetConfigServer("https://config-server.example.com", "config-server-client")).To(Succeed())
+					generated = true
+					password = "secret"
+
+					kubeConverter.VariablesReturns([]qsv1a1.QuarksSecret{
+						{
+							ObjectMeta: metav1.ObjectMeta{Name: "foo.var-adminpass", Namespace: "default", Labels: map[string]string{converter.LabelVariableName: "adminpass"}},
+							Spec:       qsv1a1.QuarksSecretSpec{Type: qsv1a1.Password, SecretName: "foo.var-adminpass"},
+						},
+						{
+							ObjectMeta: metav1.ObjectMeta{Name: "foo.var-token", Namespace: "default", Labels: map[string]string{converter.LabelVariableName: "token"}},
+							Spec:       qsv1a1.QuarksSecretSpec{Type: qsv1a1.CredHub, SecretName: "foo.var-token"},
+						},
+					}, nil)
+					client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+						switch object := object.(type) {
+						case *bdv1.BOSHDeployment:
+							instance.DeepCopyInto(object)
+						case *qjv1a1.QuarksJob, *corev1.ConfigMap:
+							return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+						case *qsv1a1.QuarksSecret:
+							object.Name = nn.Name
+							object.Namespace = nn.Namespace
+							object.Status.Generated = generated
+						case *corev1.Secret:
+							object.Name = nn.Name
+							object.Labels = map[string]string{qsv1a1.LabelKind: qsv1a1.GeneratedSecretKind}
+							object.Data = map[string][]byte{"password": []byte(password)}
+						}
+						return nil
+					})
+				})
+
+				AfterEach(func() {
+					Expect(cfd.SetConfigServer("", "")).To(Succeed())
+				})
+
+				It("puts the generated values to the Config Server", func() {
+					result, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(result.RequeueAfter).To(BeZero())
+					Expect(configServer.PutCallCount()).To(Equal(1))
+					_, name, value := configServer.PutArgsForCall(0)
+					Expect(name).To(Equal("/foo/adminpass"))
+					Expect(value).To(Equal("secret"))
+				})
+
+				It("puts changed values only", func() {
+					_, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					_, err = reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(configServer.PutCallCount()).To(Equal(1))
+
+					password = "rotated"
+					_, err = reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(configServer.PutCallCount()).To(Equal(2))
+					_, _, value := configServer.PutArgsForCall(1)
+					Expect(value).To(Equal("rotated"))
+				})
+
+				It("waits until all variables are generated", func() {
+					generated = false
+
+					result, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(result.RequeueAfter).To(Equal(10 * time.Second))
+					Expect(configServer.PutCallCount()).To(Equal(0))
+				})
+
+				It("reports failures as warning events and retries", func() {
+					configServer.PutReturns(errors.New("fake-error"))
+
+					result, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(result.RequeueAfter).To(Equal(10 * time.Second))
+					Expect(recorder.Events).To(Receive(ContainSubstring("ConfigServerSyncError")))
+				})
+			})
+
+			Context("when the manifest contains variables", func() {
+				BeforeEach(func() {
+					kubeConverter.VariablesReturns([]qsv1a1.QuarksSecret{
+						{ObjectMeta: metav1.ObjectMeta{Name: "fake-variable", Namespace: "default"}},
+						{ObjectMeta: metav1.ObjectMeta{Name: "other-variable", Namespace: "default"}},
+						{ObjectMeta: metav1.ObjectMeta{Name: "last-variable", Namespace: "default"}},
+					}, nil)
+					client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+						switch object := object.(type) {
+						case *bdv1.BOSHDeployment:
+							instance.DeepCopyInto(object)
+						case *qjv1a1.QuarksJob:
+							return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+						case *qsv1a1.QuarksSecret:
+							return apierrors.NewNotFound(schema.GroupResource{}, "")
+						case *corev1.ConfigMap:
+							return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+						}
+						return nil
+					})
+				})
+
+				It("creates the variable secrets", func() {
+					result, err := reconciler.Reconcile(request)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result).To(Equal(reconcile.Result{}))
+					Expect(client.CreateCallCount()).To(Equal(6))
+				})
+
+				It("creates CAs before the certificates they sign", func() {
+					manifest.Variables = []bdm.Variable{
+						{Name: "leaf_cert", Type: "certificate", Options: &bdm.VariableOptions{CA: "root_ca"}},
+						{Name: "root_ca", Type: "certificate", Options: &bdm.VariableOptions{IsCA: true}},
+					}
+					kubeConverter.VariablesReturns([]qsv1a1.QuarksSecret{
+						{ObjectMeta: metav1.ObjectMeta{Name: "foo.var-leaf-cert", Namespace: "default", Labels: map[string]string{converter.LabelVariableName: "leaf_cert"}}},
+						{ObjectMeta: metav1.ObjectMeta{Name: "foo.var-root-ca", Namespace: "default", Labels: map[string]string{converter.LabelVariableName: "root_ca"}}},
+					}, nil)
+					created := []string{}
+					client.CreateCalls(func(context context.Context, object runtime.Object, _ ...crc.CreateOption) error {
+						if qsec, ok := object.(*qsv1a1.QuarksSecret); ok {
+							created = append(created, qsec.Name)
+						}
+						return nil
+					})
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(created).To(Equal([]string{"foo.var-root-ca", "foo.var-leaf-cert"}))
+				})
+
+				It("handles cyclic CA references", func() {
+					manifest.Variables = []bdm.Variable{
+						{Name: "a_ca", Type: "certificate", Options: &bdm.VariableOptions{CA: "b_ca"}},
+						{Name: "b_ca", Type: "certificate", Options: &bdm.VariableOptions{CA: "a_ca"}},
+					}
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("failed to order the manifest variables"))
+				})
+
+				It("maps the variables to their secrets in a config map", func() {
+					kubeConverter.VariablesReturns([]qsv1a1.QuarksSecret{
+						{
+							ObjectMeta: metav1.ObjectMeta{Name: "foo.var-adminpass", Namespace: "default", Labels: map[string]string{converter.LabelVariableName: "adminpass"}},
+							Spec:       qsv1a1.QuarksSecretSpec{SecretName: "foo.var-adminpass"},
+						},
+					}, nil)
+					var mapping *corev1.ConfigMap
+					client.CreateCalls(func(context context.Context, object runtime.Object, _ ...crc.CreateOption) error {
+						if cm, ok := object.(*corev1.ConfigMap); ok {
+							mapping = cm.DeepCopy()
+						}
+						return nil
+					})
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(mapping).ToNot(BeNil())
+					Expect(mapping.Name).To(Equal("foo-variables"))
+					Expect(mapping.Data).To(HaveKeyWithValue("variables.json", `{"adminpass":{"quarksSecret":"foo.var-adminpass","secret":"foo.var-adminpass"}}`))
+				})
+
+				It("handles an error when creating the variables mapping", func() {
+					client.CreateCalls(func(context context.Context, object runtime.Object, _ ...crc.CreateOption) error {
+						if _, ok := object.(*corev1.ConfigMap); ok {
+							return errors.New("fake-error")
+						}
+						return nil
+					})
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("failed to create variables mapping for BOSHDeployment 'default/foo'"))
+				})
+
+				Context("when variables are created concurrently", func() {
+					var (
+						variables    []qsv1a1.QuarksSecret
+						statusWriter *fakes.FakeStatusWriter
+					)
+
+					BeforeEach(func() {
+						Expect(cfd.SetVariableWorkers(4)).To(Succeed())
+
+						variables = []qsv1a1.QuarksSecret{}
+						for i := 0; i < 20; i++ {
+							variables = append(variables, qsv1a1.QuarksSecret{
+								ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("foo.var-%d", i), Namespace: "default"},
+							})
+						}
+						kubeConverter.VariablesReturns(variables, nil)
+
+						statusWriter = &fakes.FakeStatusWriter{}
+						client.StatusCalls(func() crc.StatusWriter { return statusWriter })
+					})
+
+					AfterEach(func() {
+						Expect(cfd.SetVariableWorkers(1)).To(Succeed())
+					})
+
+					It("creates each variable once and owned by the manifest secret", func() {
+						_, err := reconciler.Reconcile(request)
+						Expect(err).NotTo(HaveOccurred())
+
+						created := map[string]int{}
+						for i := 0; i < client.CreateCallCount(); i++ {
+							_, object, _ := client.CreateArgsForCall(i)
+							if qsec, ok := object.(*qsv1a1.QuarksSecret); ok {
+								created[qsec.Name]++
+								owner := metav1.GetControllerOf(qsec)
+								Expect(owner).ToNot(BeNil())
+								Expect(owner.Name).To(Equal("foo.with-ops"))
+							}
+						}
+						Expect(created).To(HaveLen(len(variables)))
+						for _, variable := range variables {
+							Expect(created).To(HaveKeyWithValue(variable.Name, 1))
+						}
+					})
 
-			It("handles an error when building instance group manifest qJob", func() {
-				jobFactory.InstanceGroupManifestJobReturns(dmQJob, errors.New("fake-error"))
+					It("resets the generated status of each updated variable", func() {
+						client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+							switch object := object.(type) {
+							case *bdv1.BOSHDeployment:
+								instance.DeepCopyInto(object)
+							case *qjv1a1.QuarksJob:
+								return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+							case *qsv1a1.QuarksSecret:
+								object.Name = nn.Name
+								object.Namespace = nn.Namespace
+								object.ResourceVersion = "1"
+								object.Spec.SecretName = "outdated"
+								object.Status.Generated = true
+							case *corev1.ConfigMap:
+								return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+							}
+							return nil
+						})
 
-				_, err := reconciler.Reconcile(request)
-				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(ContainSubstring("failed to build instance group manifest qJob"))
-			})
+						_, err := reconciler.Reconcile(request)
+						Expect(err).NotTo(HaveOccurred())
 
-			It("handles an error when creating instance group manifest qJob", func() {
-				client.CreateCalls(func(context context.Context, object runtime.Object, _ ...crc.CreateOption) error {
-					switch object := object.(type) {
-					case *qjv1a1.QuarksJob:
-						qJob := object
-						if strings.HasPrefix(qJob.Name, "ig-") {
-							return errors.New("fake-error")
+						updated := map[string]bool{}
+						for i := 0; i < statusWriter.UpdateCallCount(); i++ {
+							_, object, _ := statusWriter.UpdateArgsForCall(i)
+							if qsec, ok := object.(*qsv1a1.QuarksSecret); ok {
+								Expect(qsec.Status.Generated).To(BeFalse())
+								updated[qsec.Name] = true
+							}
 						}
-					}
-					return nil
-				})
+						Expect(updated).To(HaveLen(len(variables)))
+					})
 
-				_, err := reconciler.Reconcile(request)
-				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(ContainSubstring("failed to create instance group manifest qJob for BOSHDeployment 'default/foo': creating or updating QuarksJob 'ig-foo': fake-error"))
-			})
+					It("aggregates the errors of the variables in flight", func() {
+						var barrier sync.WaitGroup
+						barrier.Add(2)
+						client.CreateCalls(func(context context.Context, object runtime.Object, _ ...crc.CreateOption) error {
+							if qsec, ok := object.(*qsv1a1.QuarksSecret); ok {
+								if qsec.Name == "foo.var-0" || qsec.Name == "foo.var-1" {
+									barrier.Done()
+									barrier.Wait()
+									return errors.New("fake-error")
+								}
+							}
+							return nil
+						})
 
-			Context("when the manifest contains variables", func() {
-				BeforeEach(func() {
-					kubeConverter.VariablesReturns([]qsv1a1.QuarksSecret{
-						{ObjectMeta: metav1.ObjectMeta{Name: "fake-variable", Namespace: "default"}},
-						{ObjectMeta: metav1.ObjectMeta{Name: "other-variable", Namespace: "default"}},
-						{ObjectMeta: metav1.ObjectMeta{Name: "last-variable", Namespace: "default"}},
-					}, nil)
-					client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
-						switch object := object.(type) {
-						case *bdv1.BOSHDeployment:
-							instance.DeepCopyInto(object)
-						case *qjv1a1.QuarksJob:
-							return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
-						case *qsv1a1.QuarksSecret:
-							return apierrors.NewNotFound(schema.GroupResource{}, "")
+						_, err := reconciler.Reconcile(request)
+						Expect(err).To(HaveOccurred())
+						Expect(err.Error()).To(ContainSubstring("creating or updating QuarksSecret 'foo.var-0'"))
+						Expect(err.Error()).To(ContainSubstring("creating or updating QuarksSecret 'foo.var-1'"))
+					})
+
+					It("creates CAs before the certificates they sign", func() {
+						manifest.Variables = []bdm.Variable{
+							{Name: "leaf_cert_1", Type: "certificate", Options: &bdm.VariableOptions{CA: "root_ca"}},
+							{Name: "root_ca", Type: "certificate", Options: &bdm.VariableOptions{IsCA: true}},
+							{Name: "leaf_cert_2", Type: "certificate", Options: &bdm.VariableOptions{CA: "root_ca"}},
+							{Name: "leaf_cert_3", Type: "certificate", Options: &bdm.VariableOptions{CA: "root_ca"}},
 						}
-						return nil
+						variables = []qsv1a1.QuarksSecret{}
+						for _, v := range manifest.Variables {
+							variables = append(variables, qsv1a1.QuarksSecret{
+								ObjectMeta: metav1.ObjectMeta{Name: "foo.var-" + v.Name, Namespace: "default", Labels: map[string]string{converter.LabelVariableName: v.Name}},
+							})
+						}
+						kubeConverter.VariablesReturns(variables, nil)
+
+						var mu sync.Mutex
+						created := []string{}
+						client.CreateCalls(func(context context.Context, object runtime.Object, _ ...crc.CreateOption) error {
+							if qsec, ok := object.(*qsv1a1.QuarksSecret); ok {
+								// A slow CA gives the other workers a chance to overtake it
+								if qsec.Name == "foo.var-root_ca" {
+									time.Sleep(100 * time.Millisecond)
+								}
+								mu.Lock()
+								created = append(created, qsec.Name)
+								mu.Unlock()
+							}
+							return nil
+						})
+
+						_, err := reconciler.Reconcile(request)
+						Expect(err).NotTo(HaveOccurred())
+						Expect(created).To(HaveLen(4))
+						Expect(created[0]).To(Equal("foo.var-root_ca"))
+						Expect(created[1:]).To(ConsistOf("foo.var-leaf_cert_1", "foo.var-leaf_cert_2", "foo.var-leaf_cert_3"))
 					})
-				})
 
-				It("creates the variable secrets", func() {
-					result, err := reconciler.Reconcile(request)
-					Expect(err).NotTo(HaveOccurred())
-					Expect(result).To(Equal(reconcile.Result{}))
-					Expect(client.CreateCallCount()).To(Equal(5))
+					It("rejects less than one worker", func() {
+						Expect(cfd.SetVariableWorkers(0)).To(MatchError(ContainSubstring("invalid number of variable workers")))
+					})
 				})
 			})
 
@@ -480,14 +2873,14 @@ var _ = Describe("ReconcileBoshDeployment", func() {
 				It("passes link secrets to QJobs", func() {
 					_, err := reconciler.Reconcile(request)
 					Expect(err).ToNot(HaveOccurred())
-					_, _, linksSecrets, _ := jobFactory.InstanceGroupManifestJobArgsForCall(0)
+					_, _, linksSecrets, _, _ := jobFactory.InstanceGroupManifestJobArgsForCall(0)
 					Expect(linksSecrets).To(Equal(converter.LinkInfos{
 						{
 							SecretName:   "baz-sec",
 							ProviderName: "baz",
 						},
 					}))
-					_, _, linksSecrets, _ = jobFactory.InstanceGroupManifestJobArgsForCall(0)
+					_, _, linksSecrets, _, _ = jobFactory.InstanceGroupManifestJobArgsForCall(0)
 					Expect(linksSecrets).To(Equal(converter.LinkInfos{
 						{
 							SecretName:   "baz-sec",
@@ -532,7 +2925,646 @@ var _ = Describe("ReconcileBoshDeployment", func() {
 					_, err := reconciler.Reconcile(request)
 					Expect(err.Error()).To(ContainSubstring("duplicated secrets of provider"))
 				})
+
+				It("handles an error when the consumer expects another link type than the provider declares", func() {
+					bazSecret.Annotations[bdv1.AnnotationLinkProvidesKey] = `{"name":"baz","type":"bar"}`
+					manifest.InstanceGroups[0].Jobs[0].Consumes["baz"] = map[string]interface{}{"from": "baz", "type": "qux"}
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("job 'fakepod/foo' consumes link of type 'qux' from provider 'baz' of type 'bar'"))
+					Expect(<-recorder.Events).To(ContainSubstring("LinkTypeMismatch"))
+				})
+
+				It("accepts consumers, which declare the provider's link type", func() {
+					bazSecret.Annotations[bdv1.AnnotationLinkProvidesKey] = `{"name":"baz","type":"bar"}`
+					manifest.InstanceGroups[0].Jobs[0].Consumes["baz"] = map[string]interface{}{"from": "baz", "type": "bar"}
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+				})
+
+				Context("when an external link publisher is configured", func() {
+					BeforeEach(func() {
+						Expect(cfd.SetExternalLinkPublisher("https://config-server.example.com", "config-server-client")).To(Succeed())
+						bazSecret.Annotations[bdv1.AnnotationLinkProvidesKey] = `{"name":"baz","type":"bar"}`
+						bazSecret.Data = map[string][]byte{"port": []byte("8080")}
+					})
+
+					AfterEach(func() {
+						Expect(cfd.SetExternalLinkPublisher("", "")).To(Succeed())
+					})
+
+					It("publishes the links of typed providers", func() {
+						_, err := reconciler.Reconcile(request)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(linkPublisher.PublishCallCount()).To(Equal(1))
+						_, providerName, linkType, _, properties := linkPublisher.PublishArgsForCall(0)
+						Expect(providerName).To(Equal("baz"))
+						Expect(linkType).To(Equal("bar"))
+						Expect(properties).To(Equal(map[string]interface{}{"port": "8080"}))
+					})
+
+					It("doesn't publish links of untyped providers", func() {
+						bazSecret.Annotations[bdv1.AnnotationLinkProvidesKey] = `{"name":"baz"}`
+
+						_, err := reconciler.Reconcile(request)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(linkPublisher.PublishCallCount()).To(Equal(0))
+					})
+
+					It("emits a warning event, but continues, when publishing fails", func() {
+						linkPublisher.PublishReturns(errors.New("fake-error"))
+
+						_, err := reconciler.Reconcile(request)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(<-recorder.Events).To(ContainSubstring("ExternalLinkPublishError"))
+						Expect(jobFactory.InstanceGroupManifestJobCallCount()).To(Equal(1))
+					})
+
+					It("rejects an endpoint without client secret", func() {
+						Expect(cfd.SetExternalLinkPublisher("https://config-server.example.com", "")).ToNot(Succeed())
+					})
+				})
+
+				Context("when the link provider service selects pods without an IP", func() {
+					BeforeEach(func() {
+						bazSecret.Annotations[bdv1.AnnotationLinkProvidesKey] = `{"name":"baz","type":"bar"}`
+						bazService := corev1.Service{
+							ObjectMeta: metav1.ObjectMeta{
+								Name:      "baz-svc",
+								Namespace: "default",
+								Annotations: map[string]string{
+									bdv1.LabelDeploymentName:           deploymentName,
+									bdv1.AnnotationLinkProviderService: "baz-sec",
+								},
+							},
+							Spec: corev1.ServiceSpec{
+								Selector: map[string]string{"app": "baz"},
+							},
+						}
+						pods := []corev1.Pod{
+							{
+								ObjectMeta: metav1.ObjectMeta{Name: "baz-0", Namespace: "default", UID: "uid-0"},
+							},
+							{
+								ObjectMeta: metav1.ObjectMeta{Name: "baz-1", Namespace: "default", UID: "uid-1"},
+								Status:     corev1.PodStatus{PodIP: "10.0.0.1"},
+							},
+						}
+
+						client.ListCalls(func(context context.Context, object runtime.Object, _ ...crc.ListOption) error {
+							switch object := object.(type) {
+							case *corev1.SecretList:
+								secretList := corev1.SecretList{
+									Items: []corev1.Secret{*bazSecret},
+								}
+								secretList.DeepCopyInto(object)
+							case *corev1.ServiceList:
+								serviceList := corev1.ServiceList{
+									Items: []corev1.Service{bazService},
+								}
+								serviceList.DeepCopyInto(object)
+							case *corev1.PodList:
+								podList := corev1.PodList{Items: pods}
+								podList.DeepCopyInto(object)
+							}
+
+							return nil
+						})
+					})
+
+					AfterEach(func() {
+						Expect(cfd.SetEmptyPodIPPolicy("error")).To(Succeed())
+					})
+
+					It("returns an error by default", func() {
+						_, err := reconciler.Reconcile(request)
+						Expect(err).To(HaveOccurred())
+						Expect(err.Error()).To(ContainSubstring("empty ip of kube native component: 'default/baz-0'"))
+					})
+
+					It("skips the pods when the policy is skip", func() {
+						Expect(cfd.SetEmptyPodIPPolicy("skip")).To(Succeed())
+
+						_, err := reconciler.Reconcile(request)
+						Expect(err).ToNot(HaveOccurred())
+
+						quarksLinks := manifest.Properties["quarks_links"].(map[string]bdm.QuarksLink)
+						Expect(quarksLinks["baz-sec"].Instances).To(Equal([]bdm.JobInstance{
+							{
+								Name:       "baz-sec",
+								ID:         "uid-1",
+								Index:      0,
+								Address:    "10.0.0.1",
+								Bootstrap:  true,
+								FQDN:       "10-0-0-1.default.pod.",
+								ServiceDNS: "baz-svc.default.svc.",
+							},
+						}))
+					})
+
+					It("requeues the reconcile when the policy is wait", func() {
+						Expect(cfd.SetEmptyPodIPPolicy("wait")).To(Succeed())
+
+						result, err := reconciler.Reconcile(request)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(result.RequeueAfter).To(Equal(5 * time.Second))
+						Expect(jobFactory.InstanceGroupManifestJobCallCount()).To(Equal(0))
+					})
+
+					It("rejects an unknown policy", func() {
+						Expect(cfd.SetEmptyPodIPPolicy("ignore")).To(MatchError(ContainSubstring("invalid empty pod IP policy")))
+					})
+				})
+
+				Context("when the link provider pods are drained", func() {
+					var (
+						pods      []corev1.Pod
+						deletedAt metav1.Time
+					)
+
+					updatedPods := func() []*corev1.Pod {
+						updated := []*corev1.Pod{}
+						for i := 0; i < client.UpdateCallCount(); i++ {
+							_, object, _ := client.UpdateArgsForCall(i)
+							if pod, ok := object.(*corev1.Pod); ok {
+								updated = append(updated, pod)
+							}
+						}
+						return updated
+					}
+
+					BeforeEach(func() {
+						Expect(cfd.SetLinkDrainPeriod(30 * time.Second)).To(Succeed())
+
+						bazSecret.Annotations[bdv1.AnnotationLinkProvidesKey] = `{"name":"baz","type":"bar"}`
+						bazService := corev1.Service{
+							ObjectMeta: metav1.ObjectMeta{
+								Name:      "baz-svc",
+								Namespace: "default",
+								Annotations: map[string]string{
+									bdv1.LabelDeploymentName:           deploymentName,
+									bdv1.AnnotationLinkProviderService: "baz-sec",
+								},
+							},
+							Spec: corev1.ServiceSpec{
+								Selector: map[string]string{"app": "baz"},
+							},
+						}
+						deletedAt = metav1.NewTime(time.Now().Add(-10 * time.Second))
+						pods = []corev1.Pod{
+							{
+								ObjectMeta: metav1.ObjectMeta{Name: "baz-0", Namespace: "default", UID: "uid-0"},
+								Status:     corev1.PodStatus{PodIP: "10.0.0.1"},
+							},
+							{
+								ObjectMeta: metav1.ObjectMeta{
+									Name:              "baz-1",
+									Namespace:         "default",
+									UID:               "uid-1",
+									DeletionTimestamp: &deletedAt,
+									Finalizers:        []string{bdv1.FinalizerLinkDrain},
+									Annotations:       map[string]string{bdv1.AnnotationLinkConsumers: "default/foo"},
+								},
+								Status: corev1.PodStatus{PodIP: "10.0.0.2"},
+							},
+						}
+
+						client.ListCalls(func(context context.Context, object runtime.Object, _ ...crc.ListOption) error {
+							switch object := object.(type) {
+							case *corev1.SecretList:
+								secretList := corev1.SecretList{
+									Items: []corev1.Secret{*bazSecret},
+								}
+								secretList.DeepCopyInto(object)
+							case *corev1.ServiceList:
+								serviceList := corev1.ServiceList{
+									Items: []corev1.Service{bazService},
+								}
+								serviceList.DeepCopyInto(object)
+							case *corev1.PodList:
+								podList := corev1.PodList{Items: pods}
+								podList.DeepCopyInto(object)
+							}
+
+							return nil
+						})
+					})
+
+					AfterEach(func() {
+						Expect(cfd.SetLinkDrainPeriod(0)).To(Succeed())
+					})
+
+					It("protects the provider pods with the link drain finalizer", func() {
+						_, err := reconciler.Reconcile(request)
+						Expect(err).ToNot(HaveOccurred())
+
+						updated := updatedPods()
+						Expect(updated).To(HaveLen(1))
+						Expect(updated[0].Name).To(Equal("baz-0"))
+						Expect(updated[0].Finalizers).To(ContainElement(bdv1.FinalizerLinkDrain))
+						Expect(updated[0].Annotations[bdv1.AnnotationLinkConsumers]).To(Equal("default/foo"))
+					})
+
+					It("marks removed pods as draining until the drain period has passed", func() {
+						result, err := reconciler.Reconcile(request)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(result.RequeueAfter).To(BeNumerically("~", 20*time.Second, time.Second))
+
+						quarksLinks := manifest.Properties["quarks_links"].(map[string]bdm.QuarksLink)
+						Expect(quarksLinks["baz-sec"].Instances).To(HaveLen(2))
+						Expect(quarksLinks["baz-sec"].Instances[0].Draining).To(BeFalse())
+						Expect(quarksLinks["baz-sec"].Instances[1].Draining).To(BeTrue())
+					})
+
+					It("removes the finalizer of drained pods and leaves them out", func() {
+						deletedAt.Time = time.Now().Add(-time.Minute)
+
+						_, err := reconciler.Reconcile(request)
+						Expect(err).ToNot(HaveOccurred())
+
+						updated := updatedPods()
+						Expect(updated).To(HaveLen(2))
+						Expect(updated[1].Name).To(Equal("baz-1"))
+						Expect(updated[1].Finalizers).To(BeEmpty())
+
+						quarksLinks := manifest.Properties["quarks_links"].(map[string]bdm.QuarksLink)
+						Expect(quarksLinks["baz-sec"].Instances).To(HaveLen(1))
+						Expect(quarksLinks["baz-sec"].Instances[0].Name).To(Equal("baz-sec"))
+						Expect(quarksLinks["baz-sec"].Instances[0].ID).To(Equal("uid-0"))
+					})
+
+					It("releases pods immediately, when draining is disabled", func() {
+						Expect(cfd.SetLinkDrainPeriod(0)).To(Succeed())
+
+						_, err := reconciler.Reconcile(request)
+						Expect(err).ToNot(HaveOccurred())
+
+						updated := updatedPods()
+						Expect(updated).To(HaveLen(1))
+						Expect(updated[0].Name).To(Equal("baz-1"))
+						Expect(updated[0].Finalizers).To(BeEmpty())
+					})
+
+					It("rejects a negative drain period", func() {
+						Expect(cfd.SetLinkDrainPeriod(-time.Second)).To(MatchError(ContainSubstring("invalid link drain period")))
+					})
+				})
+
+				Context("when the link provider service selects pods on zoned nodes", func() {
+					var (
+						bazService     corev1.Service
+						serviceImports []unstructured.Unstructured
+					)
+
+					BeforeEach(func() {
+						serviceImports = nil
+						bazSecret.Annotations[bdv1.AnnotationLinkProvidesKey] = `{"name":"baz","type":"bar"}`
+						bazService = corev1.Service{
+							ObjectMeta: metav1.ObjectMeta{
+								Name:      "baz-svc",
+								Namespace: "default",
+								Annotations: map[string]string{
+									bdv1.LabelDeploymentName:           deploymentName,
+									bdv1.AnnotationLinkProviderService: "baz-sec",
+								},
+							},
+							Spec: corev1.ServiceSpec{
+								Selector: map[string]string{"app": "baz"},
+							},
+						}
+						pods := []corev1.Pod{
+							{
+								ObjectMeta: metav1.ObjectMeta{Name: "baz-0", Namespace: "default", UID: "uid-0"},
+								Spec:       corev1.PodSpec{NodeName: "node-a"},
+								Status:     corev1.PodStatus{PodIP: "10.0.0.1"},
+							},
+							{
+								ObjectMeta: metav1.ObjectMeta{Name: "baz-1", Namespace: "default", UID: "uid-1"},
+								Spec:       corev1.PodSpec{NodeName: "node-b"},
+								Status:     corev1.PodStatus{PodIP: "10.0.0.2"},
+							},
+						}
+
+						client.ListCalls(func(context context.Context, object runtime.Object, _ ...crc.ListOption) error {
+							switch object := object.(type) {
+							case *corev1.SecretList:
+								secretList := corev1.SecretList{
+									Items: []corev1.Secret{*bazSecret},
+								}
+								secretList.DeepCopyInto(object)
+							case *corev1.ServiceList:
+								serviceList := corev1.ServiceList{
+									Items: []corev1.Service{bazService},
+								}
+								serviceList.DeepCopyInto(object)
+							case *corev1.PodList:
+								podList := corev1.PodList{Items: pods}
+								podList.DeepCopyInto(object)
+							case *unstructured.UnstructuredList:
+								object.Items = serviceImports
+							}
+
+							return nil
+						})
+						client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+							switch object := object.(type) {
+							case *bdv1.BOSHDeployment:
+								instance.DeepCopyInto(object)
+							case *qjv1a1.QuarksJob:
+								return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+							case *corev1.Node:
+								object.Name = nn.Name
+								object.Labels = map[string]string{"failure-domain.beta.kubernetes.io/zone": "zone-" + nn.Name}
+							}
+
+							return nil
+						})
+					})
+
+					It("doesn't add zones by default", func() {
+						_, err := reconciler.Reconcile(request)
+						Expect(err).ToNot(HaveOccurred())
+
+						quarksLinks := manifest.Properties["quarks_links"].(map[string]bdm.QuarksLink)
+						Expect(quarksLinks["baz-sec"].Instances[0].AZ).To(BeEmpty())
+						Expect(quarksLinks["baz-sec"].Instances[1].AZ).To(BeEmpty())
+					})
+
+					It("adds the node zones when the service opts in", func() {
+						bazService.Annotations[bdv1.AnnotationLinkProviderZones] = "true"
+
+						_, err := reconciler.Reconcile(request)
+						Expect(err).ToNot(HaveOccurred())
+
+						quarksLinks := manifest.Properties["quarks_links"].(map[string]bdm.QuarksLink)
+						Expect(quarksLinks["baz-sec"].Instances[0].AZ).To(Equal("zone-node-a"))
+						Expect(quarksLinks["baz-sec"].Instances[1].AZ).To(Equal("zone-node-b"))
+					})
+
+					It("uses the pod IPs as addresses by default", func() {
+						_, err := reconciler.Reconcile(request)
+						Expect(err).ToNot(HaveOccurred())
+
+						quarksLinks := manifest.Properties["quarks_links"].(map[string]bdm.QuarksLink)
+						Expect(quarksLinks["baz-sec"].Instances[0].Address).To(Equal("10.0.0.1"))
+						Expect(quarksLinks["baz-sec"].Instances[1].Address).To(Equal("10.0.0.2"))
+					})
+
+					It("uses the pod DNS names as addresses, if the link address format is FQDN", func() {
+						instance.Spec.LinkAddressFormat = "FQDN"
+
+						_, err := reconciler.Reconcile(request)
+						Expect(err).ToNot(HaveOccurred())
+
+						quarksLinks := manifest.Properties["quarks_links"].(map[string]bdm.QuarksLink)
+						Expect(quarksLinks["baz-sec"].Instances[0].Address).To(HavePrefix("10-0-0-1.default.pod."))
+						Expect(quarksLinks["baz-sec"].Instances[1].Address).To(HavePrefix("10-0-0-2.default.pod."))
+					})
+
+					It("uses the service DNS name as addresses, if the link address format is ServiceDNS", func() {
+						instance.Spec.LinkAddressFormat = "ServiceDNS"
+
+						_, err := reconciler.Reconcile(request)
+						Expect(err).ToNot(HaveOccurred())
+
+						quarksLinks := manifest.Properties["quarks_links"].(map[string]bdm.QuarksLink)
+						Expect(quarksLinks["baz-sec"].Instances[0].Address).To(HavePrefix("baz-svc.default.svc."))
+					})
+
+					Context("when the service is imported into the cluster set", func() {
+						BeforeEach(func() {
+							serviceImport := unstructured.Unstructured{}
+							serviceImport.SetGroupVersionKind(cfd.ServiceImportGroupVersionKind)
+							serviceImport.SetName("baz-svc")
+							serviceImport.SetNamespace("default")
+							serviceImports = []unstructured.Unstructured{serviceImport}
+						})
+
+						It("uses the cluster set DNS name, if service exports are enabled", func() {
+							instance.Spec.Features.ReconcileServiceExports = true
+
+							_, err := reconciler.Reconcile(request)
+							Expect(err).ToNot(HaveOccurred())
+
+							quarksLinks := manifest.Properties["quarks_links"].(map[string]bdm.QuarksLink)
+							Expect(quarksLinks["baz-sec"].Address).To(Equal("baz-svc.default.svc.clusterset.local"))
+							Expect(quarksLinks["baz-sec"].Instances[0].ServiceDNS).To(Equal("baz-svc.default.svc.clusterset.local"))
+						})
+
+						It("uses the cluster DNS name by default", func() {
+							_, err := reconciler.Reconcile(request)
+							Expect(err).ToNot(HaveOccurred())
+
+							quarksLinks := manifest.Properties["quarks_links"].(map[string]bdm.QuarksLink)
+							Expect(quarksLinks["baz-sec"].Address).To(Equal("baz-svc.default.svc."))
+						})
+
+						It("ignores a missing ServiceImport CRD", func() {
+							instance.Spec.Features.ReconcileServiceExports = true
+							client.ListCalls(func(context context.Context, object runtime.Object, _ ...crc.ListOption) error {
+								switch object := object.(type) {
+								case *corev1.SecretList:
+									object.Items = []corev1.Secret{*bazSecret}
+								case *corev1.ServiceList:
+									object.Items = []corev1.Service{bazService}
+								case *corev1.PodList:
+									object.Items = []corev1.Pod{{
+										ObjectMeta: metav1.ObjectMeta{Name: "baz-0", Namespace: "default"},
+										Status:     corev1.PodStatus{PodIP: "10.0.0.1"},
+									}}
+								case *unstructured.UnstructuredList:
+									return &meta.NoKindMatchError{GroupKind: cfd.ServiceImportGroupVersionKind.GroupKind()}
+								}
+
+								return nil
+							})
+
+							_, err := reconciler.Reconcile(request)
+							Expect(err).ToNot(HaveOccurred())
+
+							quarksLinks := manifest.Properties["quarks_links"].(map[string]bdm.QuarksLink)
+							Expect(quarksLinks["baz-sec"].Address).To(Equal("baz-svc.default.svc."))
+						})
+					})
+				})
+
+				Context("when network policies isolate the link provider pods", func() {
+					var (
+						policy       networkingv1.NetworkPolicy
+						linkWarnings func() []string
+					)
+
+					BeforeEach(func() {
+						bazSecret.Annotations[bdv1.AnnotationLinkProvidesKey] = `{"name":"baz","type":"bar"}`
+						bazService := corev1.Service{
+							ObjectMeta: metav1.ObjectMeta{
+								Name:      "baz-svc",
+								Namespace: "default",
+								Annotations: map[string]string{
+									bdv1.LabelDeploymentName:           deploymentName,
+									bdv1.AnnotationLinkProviderService: "baz-sec",
+								},
+							},
+							Spec: corev1.ServiceSpec{
+								Selector: map[string]string{"app": "baz"},
+								Ports:    []corev1.ServicePort{{Name: "baz", Port: 8080}},
+							},
+						}
+						pods := []corev1.Pod{
+							{
+								ObjectMeta: metav1.ObjectMeta{Name: "baz-0", Namespace: "default", UID: "uid-0", Labels: map[string]string{"app": "baz"}},
+								Status:     corev1.PodStatus{PodIP: "10.0.0.1"},
+							},
+						}
+						policy = networkingv1.NetworkPolicy{
+							ObjectMeta: metav1.ObjectMeta{Name: "baz-ingress", Namespace: "default"},
+							Spec: networkingv1.NetworkPolicySpec{
+								PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "baz"}},
+								Ingress: []networkingv1.NetworkPolicyIngressRule{
+									{
+										From: []networkingv1.NetworkPolicyPeer{
+											{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "monitoring"}}},
+										},
+									},
+								},
+							},
+						}
+
+						client.ListCalls(func(context context.Context, object runtime.Object, _ ...crc.ListOption) error {
+							switch object := object.(type) {
+							case *corev1.SecretList:
+								secretList := corev1.SecretList{
+									Items: []corev1.Secret{*bazSecret},
+								}
+								secretList.DeepCopyInto(object)
+							case *corev1.ServiceList:
+								serviceList := corev1.ServiceList{
+									Items: []corev1.Service{bazService},
+								}
+								serviceList.DeepCopyInto(object)
+							case *corev1.PodList:
+								podList := corev1.PodList{Items: pods}
+								podList.DeepCopyInto(object)
+							case *networkingv1.NetworkPolicyList:
+								policyList := networkingv1.NetworkPolicyList{Items: []networkingv1.NetworkPolicy{policy}}
+								policyList.DeepCopyInto(object)
+							}
+
+							return nil
+						})
+
+						linkWarnings = func() []string {
+							warnings := []string{}
+							for len(recorder.Events) > 0 {
+								if event := <-recorder.Events; strings.Contains(event, "LinkNetworkBlocked") {
+									warnings = append(warnings, event)
+								}
+							}
+							return warnings
+						}
+					})
+
+					AfterEach(func() {
+						cfd.SetLinkNetworkPolicyCheck(false)
+					})
+
+					It("doesn't check the network policies by default", func() {
+						_, err := reconciler.Reconcile(request)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(linkWarnings()).To(BeEmpty())
+					})
+
+					It("warns about consumers, which the policies don't permit to reach the provider", func() {
+						cfd.SetLinkNetworkPolicyCheck(true)
+
+						_, err := reconciler.Reconcile(request)
+						Expect(err).ToNot(HaveOccurred())
+						warnings := linkWarnings()
+						Expect(warnings).To(HaveLen(1))
+						Expect(warnings[0]).To(ContainSubstring("instance group 'fakepod'"))
+						Expect(warnings[0]).To(ContainSubstring("link provider 'baz' (pod 'baz-0') on ports 8080/TCP"))
+						Expect(warnings[0]).To(ContainSubstring("provider ingress is isolated by baz-ingress"))
+					})
+
+					It("doesn't warn, if a policy permits the consumer on the link port", func() {
+						cfd.SetLinkNetworkPolicyCheck(true)
+						policy.Spec.Ingress = append(policy.Spec.Ingress, networkingv1.NetworkPolicyIngressRule{
+							From: []networkingv1.NetworkPolicyPeer{
+								{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{bdm.LabelInstanceGroupName: "fakepod"}}},
+							},
+							Ports: []networkingv1.NetworkPolicyPort{{Port: &intstr.IntOrString{IntVal: 8080}}},
+						})
+
+						_, err := reconciler.Reconcile(request)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(linkWarnings()).To(BeEmpty())
+					})
+
+					It("warns, if the permitted ports don't include the link port", func() {
+						cfd.SetLinkNetworkPolicyCheck(true)
+						policy.Spec.Ingress = append(policy.Spec.Ingress, networkingv1.NetworkPolicyIngressRule{
+							Ports: []networkingv1.NetworkPolicyPort{{Port: &intstr.IntOrString{IntVal: 9090}}},
+						})
+
+						_, err := reconciler.Reconcile(request)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(linkWarnings()).To(HaveLen(1))
+					})
+				})
+
+				Context("when jobs providing the same link expose the same port", func() {
+					BeforeEach(func() {
+						manifest.InstanceGroups[0].Jobs[0].Provides = map[string]interface{}{
+							"foo": map[string]interface{}{"as": "shared"},
+						}
+						manifest.InstanceGroups[0].Jobs = append(manifest.InstanceGroups[0].Jobs, bdm.Job{
+							Name:    "foo-tls",
+							Release: "bar",
+							Provides: map[string]interface{}{
+								"foo-tls": map[string]interface{}{"as": "shared"},
+							},
+							Properties: bdm.JobProperties{
+								Quarks: bdm.Quarks{
+									Ports: []bdm.Port{{Name: "foo-tls", Protocol: "TCP", Internal: 8080}},
+								},
+							},
+						})
+					})
+
+					It("returns an error naming the conflicting jobs", func() {
+						_, err := reconciler.Reconcile(request)
+						Expect(err).To(HaveOccurred())
+						Expect(err.Error()).To(ContainSubstring("link 'shared' port 8080/TCP is exposed by jobs: fakepod/foo, fakepod/foo-tls"))
+						Expect(jobFactory.InstanceGroupManifestJobCallCount()).To(Equal(0))
+					})
+
+					It("proceeds when the ports differ", func() {
+						manifest.InstanceGroups[0].Jobs[1].Properties.Quarks.Ports[0].Internal = 8443
+
+						_, err := reconciler.Reconcile(request)
+						Expect(err).ToNot(HaveOccurred())
+					})
+				})
 			})
 		})
 	})
 })
+
+// writeTempFile writes content to a temporary file and returns its path
+func writeTempFile(content string) string {
+	file, err := ioutil.TempFile("", "cf-operator-test")
+	Expect(err).ToNot(HaveOccurred())
+	defer file.Close()
+	_, err = file.WriteString(content)
+	Expect(err).ToNot(HaveOccurred())
+	return file.Name()
+}
+
+// setEnvironmentProfiles configures the environment profiles for the current test
+func setEnvironmentProfiles(content string) {
+	file := writeTempFile(content)
+	Expect(cfd.SetEnvironmentProfiles(file)).To(Succeed())
+	Expect(os.Remove(file)).To(Succeed())
+}
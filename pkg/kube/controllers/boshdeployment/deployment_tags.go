@@ -0,0 +1,190 @@
+package boshdeployment
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	qstsv1a1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/quarksstatefulset/v1alpha1"
+	log "code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+// claimTagRequeueAfter is the delay before the persistent volume claims of an
+// instance group are tagged again, while its StatefulSets haven't created all
+// of them yet
+var claimTagRequeueAfter = 30 * time.Second
+
+// cloudTagAnnotations maps deployment tag keys to the annotation keys, which
+// cloud providers translate to tags of their resources, it is empty if no
+// mapping is configured
+var cloudTagAnnotations = map[string][]string{}
+
+// SetCloudTagAnnotations initializes the package scoped cloud tag annotations
+// from a YAML file, which maps deployment tag keys to lists of cloud specific
+// annotation keys. An empty path disables the mapping.
+func SetCloudTagAnnotations(path string) error {
+	mapping := map[string][]string{}
+	if path == "" {
+		cloudTagAnnotations = mapping
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return errors.Wrapf(err, "reading cloud tag annotations '%s'", path)
+	}
+	err = yaml.UnmarshalStrict(data, &mapping)
+	if err != nil {
+		return errors.Wrapf(err, "parsing cloud tag annotations '%s'", path)
+	}
+	for tag, keys := range mapping {
+		for _, key := range keys {
+			if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+				return errors.Errorf("invalid annotation key '%s' for tag '%s': %s", key, tag, strings.Join(errs, ", "))
+			}
+		}
+	}
+
+	cloudTagAnnotations = mapping
+	return nil
+}
+
+// deploymentTagAnnotations returns the cloud specific annotations for the
+// deployment tags, which have an entry in the cloud tag annotations
+func deploymentTagAnnotations(tags map[string]string) map[string]string {
+	annotations := map[string]string{}
+	for tag, value := range tags {
+		for _, key := range cloudTagAnnotations[tag] {
+			annotations[key] = value
+		}
+	}
+	return annotations
+}
+
+// validateDeploymentTags checks that the deployment tags are valid labels
+func validateDeploymentTags(tags map[string]string) error {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("tag key '%s' is invalid: %s", key, strings.Join(errs, ", "))
+		}
+		if errs := validation.IsValidLabelValue(tags[key]); len(errs) > 0 {
+			return fmt.Errorf("value of tag '%s' is invalid: %s", key, strings.Join(errs, ", "))
+		}
+	}
+	return nil
+}
+
+// tagPersistentVolumeClaims adds the deployment tags to the persistent volume
+// claims, which the StatefulSets of the instance group created from their
+// volume claim templates. The templates of existing StatefulSets are
+// immutable, so the claims are tagged once they exist. Changed tags update
+// the claims, but the labels and annotations of the templates and the labels
+// set by the QuarksStatefulSet controller take precedence over the tags. If
+// claims are still missing, the delay after which they are tagged is returned.
+func tagPersistentVolumeClaims(ctx context.Context, c client.Client, bdpl *bdv1.BOSHDeployment, instanceGroupName string, instanceGroups []qstsv1a1.QuarksStatefulSet) (time.Duration, error) {
+	labels := bdpl.Spec.DeploymentTags
+	annotations := deploymentTagAnnotations(labels)
+	if len(labels) == 0 && len(annotations) == 0 {
+		return 0, nil
+	}
+
+	var requeueAfter time.Duration
+	for _, qSts := range instanceGroups {
+		templates := qSts.Spec.Template.Spec.VolumeClaimTemplates
+		if qSts.Labels[bdm.LabelInstanceGroupName] != instanceGroupName || len(templates) == 0 {
+			continue
+		}
+
+		generatedLabels := map[string]bool{
+			qstsv1a1.LabelAZIndex:  true,
+			qstsv1a1.LabelAZName:   true,
+			qstsv1a1.LabelQStsName: true,
+		}
+		generatedAnnotations := map[string]bool{}
+		for _, template := range templates {
+			for key := range template.Labels {
+				generatedLabels[key] = true
+			}
+			for key := range template.Annotations {
+				generatedAnnotations[key] = true
+			}
+		}
+
+		claims := &corev1.PersistentVolumeClaimList{}
+		err := c.List(ctx, claims, client.InNamespace(bdpl.Namespace), client.MatchingLabels{qstsv1a1.LabelQStsName: qSts.Name})
+		if err != nil {
+			return 0, errors.Wrapf(err, "listing persistent volume claims of QuarksStatefulSet '%s'", qSts.Name)
+		}
+
+		for i := range claims.Items {
+			claim := &claims.Items[i]
+			var labelsChanged, annotationsChanged bool
+			claim.Labels, labelsChanged = setTags(claim.Labels, labels, generatedLabels)
+			claim.Annotations, annotationsChanged = setTags(claim.Annotations, annotations, generatedAnnotations)
+			if !labelsChanged && !annotationsChanged {
+				continue
+			}
+			if err := c.Update(ctx, claim); err != nil {
+				return 0, errors.Wrapf(err, "tagging persistent volume claim '%s'", claim.Name)
+			}
+			log.Debugf(ctx, "Tagged persistent volume claim '%s' of instance group '%s'", claim.Name, instanceGroupName)
+		}
+
+		if len(claims.Items) < expectedClaims(qSts) {
+			requeueAfter = claimTagRequeueAfter
+		}
+	}
+
+	return requeueAfter, nil
+}
+
+// expectedClaims returns the number of persistent volume claims, which the
+// StatefulSets of the QuarksStatefulSet create from their templates
+func expectedClaims(qSts qstsv1a1.QuarksStatefulSet) int {
+	replicas := 1
+	if qSts.Spec.Template.Spec.Replicas != nil {
+		replicas = int(*qSts.Spec.Template.Spec.Replicas)
+	}
+	zones := 1
+	if len(qSts.Spec.Zones) > 0 {
+		zones = len(qSts.Spec.Zones)
+	}
+	return len(qSts.Spec.Template.Spec.VolumeClaimTemplates) * replicas * zones
+}
+
+// setTags sets the tags in m, except for the generated keys. It returns true,
+// if m changed.
+func setTags(m map[string]string, tags map[string]string, generated map[string]bool) (map[string]string, bool) {
+	changed := false
+	for key, value := range tags {
+		if generated[key] {
+			continue
+		}
+		if current, ok := m[key]; ok && current == value {
+			continue
+		}
+		if m == nil {
+			m = map[string]string{}
+		}
+		m[key] = value
+		changed = true
+	}
+	return m, changed
+}
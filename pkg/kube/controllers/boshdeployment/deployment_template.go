@@ -0,0 +1,72 @@
+package boshdeployment
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+)
+
+// templatePlaceholder matches the '$(key)' placeholders of BOSHDeploymentTemplates.
+// Unlike '((var))' they don't collide with BOSH variables.
+var templatePlaceholder = regexp.MustCompile(`\$\(([A-Za-z0-9_.-]+)\)`)
+
+// renderDeploymentTemplate returns the spec of a BOSHDeployment generated
+// from template, in which the placeholders of all strings are replaced with
+// values. Placeholders without value are an error.
+func renderDeploymentTemplate(template bdv1.BOSHDeploymentSpec, values map[string]string) (bdv1.BOSHDeploymentSpec, error) {
+	spec := bdv1.BOSHDeploymentSpec{}
+
+	raw, err := json.Marshal(template)
+	if err != nil {
+		return spec, errors.Wrap(err, "marshalling template")
+	}
+	var doc interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return spec, errors.Wrap(err, "unmarshalling template")
+	}
+
+	missing := sets.NewString()
+	doc = replacePlaceholders(doc, values, missing)
+	if missing.Len() > 0 {
+		return spec, errors.Errorf("no values for placeholders: %s", strings.Join(missing.List(), ", "))
+	}
+
+	raw, err = json.Marshal(doc)
+	if err != nil {
+		return spec, errors.Wrap(err, "marshalling rendered template")
+	}
+	if err := json.Unmarshal(raw, &spec); err != nil {
+		return spec, errors.Wrap(err, "unmarshalling rendered template")
+	}
+	return spec, nil
+}
+
+// replacePlaceholders replaces the placeholders in all strings of doc and
+// records the keys without value in missing
+func replacePlaceholders(doc interface{}, values map[string]string, missing sets.String) interface{} {
+	switch doc := doc.(type) {
+	case string:
+		return templatePlaceholder.ReplaceAllStringFunc(doc, func(placeholder string) string {
+			key := templatePlaceholder.FindStringSubmatch(placeholder)[1]
+			value, ok := values[key]
+			if !ok {
+				missing.Insert(key)
+			}
+			return value
+		})
+	case map[string]interface{}:
+		for k, v := range doc {
+			doc[k] = replacePlaceholders(v, values, missing)
+		}
+	case []interface{}:
+		for i, v := range doc {
+			doc[i] = replacePlaceholders(v, values, missing)
+		}
+	}
+	return doc
+}
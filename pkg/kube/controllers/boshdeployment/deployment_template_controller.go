@@ -0,0 +1,105 @@
+package boshdeployment
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/backpressure"
+	"code.cloudfoundry.org/quarks-utils/pkg/config"
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+// AddDeploymentTemplate creates a new controller, which generates
+// BOSHDeployments from BOSHDeploymentTemplates and keeps them in sync with
+// their template.
+func AddDeploymentTemplate(ctx context.Context, config *config.Config, mgr manager.Manager) error {
+	ctx = ctxlog.NewContextWithRecorder(ctx, "deployment-template-reconciler", mgr.GetEventRecorderFor("deployment-template-recorder"))
+	r := NewDeploymentTemplateReconciler(ctx, config, mgr, controllerutil.SetControllerReference)
+
+	// Create a new controller
+	c, err := controller.New("deployment-template-controller", mgr, controller.Options{
+		Reconciler:              backpressure.NewReconciler(ctx, r),
+		MaxConcurrentReconciles: config.MaxBoshDeploymentWorkers,
+	})
+	if err != nil {
+		return errors.Wrap(err, "Adding deployment template controller to manager failed.")
+	}
+
+	// Watch for changes to BOSHDeploymentTemplates
+	p := predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			ctxlog.NewPredicateEvent(e.Object).Debug(
+				ctx, e.Meta, bdv1.BOSHDeploymentTemplateResourceName,
+				fmt.Sprintf("Create predicate passed for '%s'", e.Meta.GetName()),
+			)
+			return true
+		},
+		DeleteFunc:  func(e event.DeleteEvent) bool { return false },
+		GenericFunc: func(e event.GenericEvent) bool { return false },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldTemplate := e.ObjectOld.(*bdv1.BOSHDeploymentTemplate)
+			newTemplate := e.ObjectNew.(*bdv1.BOSHDeploymentTemplate)
+			if reflect.DeepEqual(oldTemplate.Spec, newTemplate.Spec) {
+				return false
+			}
+
+			ctxlog.NewPredicateEvent(e.ObjectNew).Debug(
+				ctx, e.MetaNew, bdv1.BOSHDeploymentTemplateResourceName,
+				fmt.Sprintf("Update predicate passed for '%s'", e.MetaNew.GetName()),
+			)
+			return true
+		},
+	}
+	err = c.Watch(&source.Kind{Type: &bdv1.BOSHDeploymentTemplate{}}, &handler.EnqueueRequestForObject{}, p)
+	if err != nil {
+		return errors.Wrapf(err, "Watching bosh deployment templates failed in deployment template controller.")
+	}
+
+	// Watch for deleted or changed BOSHDeployments, which were generated by a
+	// template, to revert the drift
+	p = predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool { return false },
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			ctxlog.NewPredicateEvent(e.Object).Debug(
+				ctx, e.Meta, bdv1.BOSHDeploymentResourceName,
+				fmt.Sprintf("Delete predicate passed for '%s'", e.Meta.GetName()),
+			)
+			return true
+		},
+		GenericFunc: func(e event.GenericEvent) bool { return false },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldBdpl := e.ObjectOld.(*bdv1.BOSHDeployment)
+			newBdpl := e.ObjectNew.(*bdv1.BOSHDeployment)
+			if reflect.DeepEqual(oldBdpl.Spec, newBdpl.Spec) &&
+				oldBdpl.Labels[bdv1.LabelDeploymentTemplate] == newBdpl.Labels[bdv1.LabelDeploymentTemplate] {
+				return false
+			}
+
+			ctxlog.NewPredicateEvent(e.ObjectNew).Debug(
+				ctx, e.MetaNew, bdv1.BOSHDeploymentResourceName,
+				fmt.Sprintf("Update predicate passed for '%s'", e.MetaNew.GetName()),
+			)
+			return true
+		},
+	}
+	err = c.Watch(&source.Kind{Type: &bdv1.BOSHDeployment{}}, &handler.EnqueueRequestForOwner{
+		OwnerType:    &bdv1.BOSHDeploymentTemplate{},
+		IsController: true,
+	}, p)
+	if err != nil {
+		return errors.Wrapf(err, "Watching bosh deployments failed in deployment template controller.")
+	}
+
+	return nil
+}
@@ -0,0 +1,164 @@
+package boshdeployment
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/audit"
+	"code.cloudfoundry.org/quarks-utils/pkg/config"
+	log "code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+// NewDeploymentTemplateReconciler returns a new reconcile.Reconciler
+func NewDeploymentTemplateReconciler(ctx context.Context, config *config.Config, mgr manager.Manager, srf setReferenceFunc) reconcile.Reconciler {
+	return &ReconcileDeploymentTemplate{
+		ctx:          ctx,
+		config:       config,
+		client:       audit.NewClient(mgr.GetClient()),
+		scheme:       mgr.GetScheme(),
+		setReference: srf,
+	}
+}
+
+// ReconcileDeploymentTemplate generates the BOSHDeployments of a BOSHDeploymentTemplate
+type ReconcileDeploymentTemplate struct {
+	ctx          context.Context
+	config       *config.Config
+	client       crc.Client
+	scheme       *runtime.Scheme
+	setReference setReferenceFunc
+}
+
+// Reconcile creates or updates a BOSHDeployment for each instance of the
+// template and deletes the generated BOSHDeployments of removed instances.
+// Changes to generated BOSHDeployments are reverted. Instances, which can't
+// be rendered, are reported with a 'TemplateRenderError' event and in
+// 'status.lastError', their BOSHDeployments are left unchanged.
+func (r *ReconcileDeploymentTemplate) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	ctx, cancel := context.WithTimeout(r.ctx, r.config.CtxTimeOut)
+	defer cancel()
+
+	log.Infof(ctx, "Reconciling BOSHDeploymentTemplate %s", request.NamespacedName)
+	template := &bdv1.BOSHDeploymentTemplate{}
+	err := r.client.Get(ctx, request.NamespacedName, template)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			// Generated deployments are garbage collected by their owner reference
+			log.Debug(ctx, "Skip reconcile: BOSHDeploymentTemplate not found")
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{},
+			log.WithEvent(template, "GetBOSHDeploymentTemplateError").Errorf(ctx, "failed to get BOSHDeploymentTemplate '%s': %v", request.NamespacedName, err)
+	}
+
+	instances := sets.NewString()
+	deployments := []string{}
+	renderErrors := []string{}
+	for _, instance := range template.Spec.Instances {
+		instances.Insert(instance.Name)
+
+		spec, err := renderDeploymentTemplate(template.Spec.Template, instance.Values)
+		if err != nil {
+			renderErrors = append(renderErrors, fmt.Sprintf("instance '%s': %v", instance.Name, err))
+			log.WithEvent(template, "TemplateRenderError").Errorf(ctx, "failed to render instance '%s' of BOSHDeploymentTemplate '%s': %v", instance.Name, request.NamespacedName, err)
+			continue
+		}
+
+		err = r.applyDeployment(ctx, template, instance.Name, spec)
+		if err != nil {
+			return reconcile.Result{},
+				log.WithEvent(template, "DeploymentTemplateError").Errorf(ctx, "failed to apply BOSHDeployment '%s' of BOSHDeploymentTemplate '%s': %v", instance.Name, request.NamespacedName, err)
+		}
+		deployments = append(deployments, instance.Name)
+	}
+
+	err = r.pruneDeployments(ctx, template, instances)
+	if err != nil {
+		return reconcile.Result{},
+			log.WithEvent(template, "DeploymentTemplateError").Errorf(ctx, "failed to delete removed BOSHDeployments of BOSHDeploymentTemplate '%s': %v", request.NamespacedName, err)
+	}
+
+	sort.Strings(deployments)
+	template.Status.Deployments = deployments
+	template.Status.LastError = strings.Join(renderErrors, "; ")
+	err = r.client.Status().Update(ctx, template)
+	if err != nil {
+		return reconcile.Result{},
+			log.WithEvent(template, "UpdateError").Errorf(ctx, "failed to update status of BOSHDeploymentTemplate '%s': %v", request.NamespacedName, err)
+	}
+
+	return reconcile.Result{}, nil
+}
+
+// applyDeployment creates or updates a generated BOSHDeployment. Existing
+// BOSHDeployments, which were not generated by the template, are not changed.
+func (r *ReconcileDeploymentTemplate) applyDeployment(ctx context.Context, template *bdv1.BOSHDeploymentTemplate, name string, spec bdv1.BOSHDeploymentSpec) error {
+	bdpl := &bdv1.BOSHDeployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: template.Namespace,
+		},
+	}
+
+	op, err := controllerutil.CreateOrUpdate(ctx, r.client, bdpl, func() error {
+		if bdpl.GetResourceVersion() != "" && !metav1.IsControlledBy(bdpl, template) {
+			return errors.Errorf("BOSHDeployment '%s' already exists and isn't generated by the template", name)
+		}
+
+		labels := bdpl.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[bdv1.LabelDeploymentTemplate] = template.Name
+		bdpl.SetLabels(labels)
+		bdpl.Spec = spec
+
+		return r.setReference(template, bdpl, r.scheme)
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Debugf(ctx, "BOSHDeployment '%s/%s' has been %s", template.Namespace, name, op)
+	return nil
+}
+
+// pruneDeployments deletes the BOSHDeployments generated by the template,
+// whose instances were removed from it
+func (r *ReconcileDeploymentTemplate) pruneDeployments(ctx context.Context, template *bdv1.BOSHDeploymentTemplate, instances sets.String) error {
+	generated := &bdv1.BOSHDeploymentList{}
+	err := r.client.List(ctx, generated,
+		crc.InNamespace(template.Namespace),
+		crc.MatchingLabels{bdv1.LabelDeploymentTemplate: template.Name},
+	)
+	if err != nil {
+		return errors.Wrap(err, "listing generated BOSHDeployments")
+	}
+
+	for i := range generated.Items {
+		bdpl := &generated.Items[i]
+		if instances.Has(bdpl.Name) || !metav1.IsControlledBy(bdpl, template) {
+			continue
+		}
+
+		err := r.client.Delete(ctx, bdpl)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "deleting BOSHDeployment '%s'", bdpl.Name)
+		}
+		log.WithEvent(template, "DeploymentRemoved").Infof(ctx, "Deleted BOSHDeployment '%s/%s' of removed instance", bdpl.Namespace, bdpl.Name)
+	}
+	return nil
+}
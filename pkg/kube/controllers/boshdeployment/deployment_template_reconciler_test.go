@@ -0,0 +1,185 @@
+package boshdeployment_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	cfd "code.cloudfoundry.org/cf-operator/pkg/kube/controllers/boshdeployment"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/controllers/fakes"
+	cfcfg "code.cloudfoundry.org/quarks-utils/pkg/config"
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+	helper "code.cloudfoundry.org/quarks-utils/testing/testhelper"
+)
+
+var _ = Describe("ReconcileDeploymentTemplate", func() {
+	var (
+		manager    *fakes.FakeManager
+		client     crc.Client
+		scheme     *runtime.Scheme
+		recorder   *record.FakeRecorder
+		reconciler reconcile.Reconciler
+		request    reconcile.Request
+		template   *bdv1.BOSHDeploymentTemplate
+		objects    []runtime.Object
+	)
+
+	getDeployment := func(name string) (*bdv1.BOSHDeployment, error) {
+		bdpl := &bdv1.BOSHDeployment{}
+		err := client.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: name}, bdpl)
+		return bdpl, err
+	}
+
+	getTemplate := func() *bdv1.BOSHDeploymentTemplate {
+		tpl := &bdv1.BOSHDeploymentTemplate{}
+		Expect(client.Get(context.Background(), request.NamespacedName, tpl)).To(Succeed())
+		return tpl
+	}
+
+	BeforeEach(func() {
+		scheme = runtime.NewScheme()
+		Expect(bdv1.AddToScheme(scheme)).To(Succeed())
+
+		manager = &fakes.FakeManager{}
+		manager.GetSchemeReturns(scheme)
+		recorder = record.NewFakeRecorder(20)
+		request = reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "cf"}}
+
+		template = &bdv1.BOSHDeploymentTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: "cf", Namespace: "default"},
+			Spec: bdv1.BOSHDeploymentTemplateSpec{
+				Template: bdv1.BOSHDeploymentSpec{
+					Manifest: bdv1.ResourceReference{Name: "cf-manifest", Type: bdv1.ConfigMapReference},
+					Ops: []bdv1.ResourceReference{
+						{Name: "$(env)-ops", Type: bdv1.ConfigMapReference},
+					},
+					IgnoredInstanceGroups: []string{"$(ignored)"},
+				},
+				Instances: []bdv1.TemplateInstance{
+					{Name: "cf-dev", Values: map[string]string{"env": "dev", "ignored": "smoke-tests"}},
+					{Name: "cf-prod", Values: map[string]string{"env": "prod", "ignored": "acceptance-tests"}},
+				},
+			},
+		}
+		objects = []runtime.Object{template}
+	})
+
+	JustBeforeEach(func() {
+		client = fake.NewFakeClientWithScheme(scheme, objects...)
+		manager.GetClientReturns(client)
+
+		_, log := helper.NewTestLogger()
+		ctx := ctxlog.NewParentContext(log)
+		ctx = ctxlog.NewContextWithRecorder(ctx, "TestRecorder", recorder)
+		config := &cfcfg.Config{CtxTimeOut: 10 * time.Second}
+		reconciler = cfd.NewDeploymentTemplateReconciler(ctx, config, manager, controllerutil.SetControllerReference)
+	})
+
+	It("generates a BOSHDeployment for each instance", func() {
+		_, err := reconciler.Reconcile(request)
+		Expect(err).ToNot(HaveOccurred())
+
+		bdpl, err := getDeployment("cf-dev")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(bdpl.Spec.Manifest.Name).To(Equal("cf-manifest"))
+		Expect(bdpl.Spec.Ops[0].Name).To(Equal("dev-ops"))
+		Expect(bdpl.Spec.IgnoredInstanceGroups).To(Equal([]string{"smoke-tests"}))
+		Expect(bdpl.Labels).To(HaveKeyWithValue(bdv1.LabelDeploymentTemplate, "cf"))
+		Expect(bdpl.OwnerReferences).To(HaveLen(1))
+		Expect(bdpl.OwnerReferences[0].Name).To(Equal("cf"))
+
+		bdpl, err = getDeployment("cf-prod")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(bdpl.Spec.Ops[0].Name).To(Equal("prod-ops"))
+
+		Expect(getTemplate().Status.Deployments).To(Equal([]string{"cf-dev", "cf-prod"}))
+	})
+
+	It("reverts changes to generated BOSHDeployments", func() {
+		_, err := reconciler.Reconcile(request)
+		Expect(err).ToNot(HaveOccurred())
+
+		bdpl, err := getDeployment("cf-dev")
+		Expect(err).ToNot(HaveOccurred())
+		bdpl.Spec.Ops = nil
+		Expect(client.Update(context.Background(), bdpl)).To(Succeed())
+
+		_, err = reconciler.Reconcile(request)
+		Expect(err).ToNot(HaveOccurred())
+
+		bdpl, err = getDeployment("cf-dev")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(bdpl.Spec.Ops).To(HaveLen(1))
+	})
+
+	It("deletes the BOSHDeployments of removed instances", func() {
+		_, err := reconciler.Reconcile(request)
+		Expect(err).ToNot(HaveOccurred())
+
+		tpl := getTemplate()
+		tpl.Spec.Instances = tpl.Spec.Instances[:1]
+		Expect(client.Update(context.Background(), tpl)).To(Succeed())
+
+		_, err = reconciler.Reconcile(request)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = getDeployment("cf-dev")
+		Expect(err).ToNot(HaveOccurred())
+		_, err = getDeployment("cf-prod")
+		Expect(err).To(HaveOccurred())
+		Expect(<-recorder.Events).To(ContainSubstring("DeploymentRemoved"))
+		Expect(getTemplate().Status.Deployments).To(Equal([]string{"cf-dev"}))
+	})
+
+	Context("when an instance has no value for a placeholder", func() {
+		BeforeEach(func() {
+			delete(template.Spec.Instances[1].Values, "ignored")
+		})
+
+		It("reports the instance and generates the others", func() {
+			_, err := reconciler.Reconcile(request)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(<-recorder.Events).To(And(ContainSubstring("TemplateRenderError"), ContainSubstring("no values for placeholders: ignored")))
+			_, err = getDeployment("cf-dev")
+			Expect(err).ToNot(HaveOccurred())
+			_, err = getDeployment("cf-prod")
+			Expect(err).To(HaveOccurred())
+
+			tpl := getTemplate()
+			Expect(tpl.Status.Deployments).To(Equal([]string{"cf-dev"}))
+			Expect(tpl.Status.LastError).To(ContainSubstring("instance 'cf-prod'"))
+		})
+	})
+
+	Context("when a BOSHDeployment with the name of an instance exists", func() {
+		BeforeEach(func() {
+			objects = append(objects, &bdv1.BOSHDeployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "cf-dev", Namespace: "default", ResourceVersion: "1"},
+				Spec:       bdv1.BOSHDeploymentSpec{Manifest: bdv1.ResourceReference{Name: "other", Type: bdv1.ConfigMapReference}},
+			})
+		})
+
+		It("doesn't take it over", func() {
+			_, err := reconciler.Reconcile(request)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("isn't generated by the template"))
+
+			bdpl, err := getDeployment("cf-dev")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(bdpl.Spec.Manifest.Name).To(Equal("other"))
+		})
+	})
+})
@@ -0,0 +1,112 @@
+package boshdeployment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	log "code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+// deprecatedAPIVersions are the API versions of BOSHDeployments, whose use is
+// reported. Empty disables the report.
+var deprecatedAPIVersions = map[string]bool{}
+
+// operatorFieldManager is the field manager of the operator's own writes,
+// which are not reported
+var operatorFieldManager = strings.Split(rest.DefaultKubernetesUserAgent(), "/")[0]
+
+var deprecatedAPIVersionTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "quarks_boshdeployment_deprecated_api_version_total",
+		Help: "Number of reconciles of BOSHDeployments, which were submitted via a deprecated API version",
+	},
+	[]string{"namespace", "api_version"},
+)
+
+func init() {
+	crmetrics.Registry.MustRegister(deprecatedAPIVersionTotal)
+}
+
+// SetDeprecatedAPIVersions initializes the package scoped deprecated API
+// versions from a comma separated list like 'quarks.cloudfoundry.org/v1alpha1'
+func SetDeprecatedAPIVersions(versions string) error {
+	deprecated := map[string]bool{}
+	for _, v := range strings.Split(versions, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		gv, err := schema.ParseGroupVersion(v)
+		if err != nil {
+			return errors.Wrapf(err, "invalid deprecated API version '%s'", v)
+		}
+		if gv.Group != bdv1.SchemeGroupVersion.Group {
+			return errors.Errorf("invalid deprecated API version '%s', the group must be '%s'", v, bdv1.SchemeGroupVersion.Group)
+		}
+		deprecated[gv.String()] = true
+	}
+	deprecatedAPIVersions = deprecated
+	return nil
+}
+
+// reportDeprecatedAPIVersions emits a 'DeprecatedAPIVersion' warning event and
+// counts the reconcile, for each deprecated API version, via which the
+// deployment was submitted. The versions are read from the managed fields of
+// all field managers except the operator and from kubectl's last applied
+// configuration. The report never changes the reconcile.
+func (r *ReconcileBOSHDeployment) reportDeprecatedAPIVersions(ctx context.Context, instance *bdv1.BOSHDeployment) {
+	if len(deprecatedAPIVersions) == 0 {
+		return
+	}
+
+	for _, apiVersion := range submittedAPIVersions(instance) {
+		if !deprecatedAPIVersions[apiVersion] {
+			continue
+		}
+		msg := fmt.Sprintf("BOSHDeployment '%s/%s' was submitted via the deprecated API version '%s'", instance.Namespace, instance.Name, apiVersion)
+		log.WarningEvent(ctx, instance, "DeprecatedAPIVersion", msg)
+		deprecatedAPIVersionTotal.WithLabelValues(instance.Namespace, apiVersion).Inc()
+	}
+}
+
+// submittedAPIVersions returns the distinct API versions, via which the
+// deployment was written by other clients than the operator
+func submittedAPIVersions(instance *bdv1.BOSHDeployment) []string {
+	versions := []string{}
+	seen := map[string]bool{}
+	add := func(apiVersion string) {
+		if apiVersion != "" && !seen[apiVersion] {
+			seen[apiVersion] = true
+			versions = append(versions, apiVersion)
+		}
+	}
+
+	for _, entry := range instance.GetManagedFields() {
+		if entry.Manager == operatorFieldManager {
+			continue
+		}
+		add(entry.APIVersion)
+	}
+
+	if applied, ok := instance.GetAnnotations()[corev1.LastAppliedConfigAnnotation]; ok {
+		config := struct {
+			APIVersion string `json:"apiVersion"`
+		}{}
+		if err := json.Unmarshal([]byte(applied), &config); err == nil {
+			add(config.APIVersion)
+		}
+	}
+
+	return versions
+}
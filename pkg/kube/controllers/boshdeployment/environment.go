@@ -0,0 +1,85 @@
+package boshdeployment
+
+import (
+	"io/ioutil"
+	"time"
+
+	"github.com/pkg/errors"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-utils/pkg/config"
+)
+
+// DefaultEnvironment is the name of the profile used for BOSHDeployments
+// without environment annotation or with an unknown environment
+const DefaultEnvironment = "default"
+
+// EnvironmentProfile is the policy applied to the BOSHDeployments of an environment
+type EnvironmentProfile struct {
+	// MeltdownDuration overrides the operator's meltdown duration
+	MeltdownDuration *metav1.Duration `json:"meltdownDuration,omitempty"`
+	// MeltdownRequeueAfter overrides the operator's meltdown requeue delay
+	MeltdownRequeueAfter *metav1.Duration `json:"meltdownRequeueAfter,omitempty"`
+	// LenientValidation reports sensitive ConfigMap content and missing secret
+	// references as warnings instead of rejecting the deployment
+	LenientValidation bool `json:"lenientValidation,omitempty"`
+}
+
+// environmentProfiles maps environment names to their profile, it is empty if no profiles are configured
+var environmentProfiles = map[string]EnvironmentProfile{}
+
+// SetEnvironmentProfiles initializes the package scoped environment profiles
+// from a YAML file, which maps environment names to profiles. An empty path
+// disables the profiles.
+func SetEnvironmentProfiles(path string) error {
+	profiles := map[string]EnvironmentProfile{}
+	if path == "" {
+		environmentProfiles = profiles
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return errors.Wrapf(err, "reading environment profiles '%s'", path)
+	}
+	err = yaml.UnmarshalStrict(data, &profiles)
+	if err != nil {
+		return errors.Wrapf(err, "parsing environment profiles '%s'", path)
+	}
+	for name, p := range profiles {
+		if p.MeltdownDuration != nil && p.MeltdownDuration.Duration < 0 {
+			return errors.Errorf("invalid meltdown duration '%s' in environment profile '%s'", p.MeltdownDuration.Duration, name)
+		}
+		if p.MeltdownRequeueAfter != nil && p.MeltdownRequeueAfter.Duration < 0 {
+			return errors.Errorf("invalid meltdown requeue delay '%s' in environment profile '%s'", p.MeltdownRequeueAfter.Duration, name)
+		}
+	}
+
+	environmentProfiles = profiles
+	return nil
+}
+
+// environmentProfile returns the profile for the environment of the
+// BOSHDeployment, falling back to the default profile
+func environmentProfile(bdpl *bdv1.BOSHDeployment) EnvironmentProfile {
+	if p, ok := environmentProfiles[bdpl.Environment()]; ok {
+		return p
+	}
+	return environmentProfiles[DefaultEnvironment]
+}
+
+// meltdown returns the meltdown duration and requeue delay of the profile,
+// falling back to the operator's configuration
+func (p EnvironmentProfile) meltdown(c *config.Config) (time.Duration, time.Duration) {
+	duration, requeueAfter := c.MeltdownDuration, c.MeltdownRequeueAfter
+	if p.MeltdownDuration != nil {
+		duration = p.MeltdownDuration.Duration
+	}
+	if p.MeltdownRequeueAfter != nil {
+		requeueAfter = p.MeltdownRequeueAfter.Duration
+	}
+	return duration, requeueAfter
+}
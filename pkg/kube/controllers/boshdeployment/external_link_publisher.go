@@ -0,0 +1,138 @@
+package boshdeployment
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+
+	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+	"code.cloudfoundry.org/cf-operator/pkg/configserver"
+	"code.cloudfoundry.org/cf-operator/pkg/credhub"
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	log "code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+var (
+	// externalLinkPublisherEndpoint is the URL of the BOSH Config Server, to which links are published, empty disables publishing
+	externalLinkPublisherEndpoint string
+	// externalLinkPublisherClientSecret is the name of the secret containing the mTLS client certificate for the Config Server
+	externalLinkPublisherClientSecret string
+)
+
+// SetExternalLinkPublisher initializes the package scoped external link
+// publisher variables. An empty endpoint disables publishing links.
+func SetExternalLinkPublisher(endpoint string, clientSecret string) error {
+	if endpoint != "" && clientSecret == "" {
+		return errors.New("the external link publisher client secret is required, if an external link publisher endpoint is set")
+	}
+
+	externalLinkPublisherEndpoint = endpoint
+	externalLinkPublisherClientSecret = clientSecret
+	return nil
+}
+
+// ExternalLinkPublisher makes the links of Kubernetes native providers
+// available to consumers outside of Kubernetes, e.g. VM based BOSH deployments
+type ExternalLinkPublisher interface {
+	Publish(ctx context.Context, providerName string, linkType string, instances []bdm.JobInstance, properties map[string]interface{}) error
+}
+
+// NewExternalLinkPublisherFunc returns an external link publisher, which authenticates with the client certificate in namespace
+type NewExternalLinkPublisherFunc func(ctx context.Context, c crc.Client, namespace string) (ExternalLinkPublisher, error)
+
+// NewExternalLinkPublisher returns a publisher for the configured Config
+// Server. The client secret has the same keys as the one of CredHub.
+func NewExternalLinkPublisher(ctx context.Context, c crc.Client, namespace string) (ExternalLinkPublisher, error) {
+	secret := &corev1.Secret{}
+	err := c.Get(ctx, crc.ObjectKey{Name: externalLinkPublisherClientSecret, Namespace: namespace}, secret)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting external link publisher client secret '%s'", externalLinkPublisherClientSecret)
+	}
+
+	tlsConfig, err := credhub.TLSConfig(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &configServerLinkPublisher{client: configserver.NewClient(externalLinkPublisherEndpoint, tlsConfig)}, nil
+}
+
+// ExternalLinkName returns the Config Server name of the link of a provider
+func ExternalLinkName(providerName string) string {
+	return fmt.Sprintf("/links/%s", providerName)
+}
+
+// ExternalLink is the Config Server value of a published link
+type ExternalLink struct {
+	Type       string                 `json:"type,omitempty"`
+	Instances  []bdm.JobInstance      `json:"instances"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// configServerLinkPublisher puts links to a BOSH Config Server
+type configServerLinkPublisher struct {
+	client configserver.Client
+}
+
+// Publish puts the link to the Config Server, which creates a new version
+func (p *configServerLinkPublisher) Publish(ctx context.Context, providerName string, linkType string, instances []bdm.JobInstance, properties map[string]interface{}) error {
+	if instances == nil {
+		instances = []bdm.JobInstance{}
+	}
+	return p.client.Put(ctx, ExternalLinkName(providerName), ExternalLink{
+		Type:       linkType,
+		Instances:  instances,
+		Properties: properties,
+	})
+}
+
+// publishExternalLinks publishes the links of the Kubernetes native providers
+// consumed by the deployment. The link secrets are the link properties.
+// Failures are reported as warning events, they don't fail the reconcile.
+func (r *ReconcileBOSHDeployment) publishExternalLinks(ctx context.Context, instance *bdv1.BOSHDeployment, quarksLinks map[string]bdm.QuarksLink, providerNames map[string]string, properties map[string]map[string]interface{}) {
+	if externalLinkPublisherEndpoint == "" || len(quarksLinks) == 0 {
+		return
+	}
+
+	publisher, err := r.newExternalLinkPublisher(ctx, r.client, instance.Namespace)
+	if err != nil {
+		r.externalLinkPublishFailed(ctx, instance, err)
+		return
+	}
+
+	secretNames := make([]string, 0, len(quarksLinks))
+	for name := range quarksLinks {
+		secretNames = append(secretNames, name)
+	}
+	sort.Strings(secretNames)
+
+	for _, name := range secretNames {
+		link := quarksLinks[name]
+		err := publisher.Publish(ctx, providerNames[name], link.Type, link.Instances, properties[name])
+		if err != nil {
+			r.externalLinkPublishFailed(ctx, instance, errors.Wrapf(err, "publishing link '%s'", providerNames[name]))
+			continue
+		}
+		log.Debugf(ctx, "Published link '%s' of BOSHDeployment '%s/%s'", providerNames[name], instance.Namespace, instance.Name)
+	}
+}
+
+// externalLinkPublishFailed reports a failed publishing as warning event
+func (r *ReconcileBOSHDeployment) externalLinkPublishFailed(ctx context.Context, instance *bdv1.BOSHDeployment, err error) {
+	msg := fmt.Sprintf("failed to publish links of BOSHDeployment '%s/%s': %v", instance.Namespace, instance.Name, err)
+	log.WarningEvent(ctx, instance, "ExternalLinkPublishError", msg)
+}
+
+// linkProperties returns the data of a link secret as link properties
+func linkProperties(data map[string][]byte) map[string]interface{} {
+	properties := map[string]interface{}{}
+	for k, v := range data {
+		properties[k] = string(v)
+	}
+	return properties
+}
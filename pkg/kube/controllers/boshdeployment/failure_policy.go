@@ -0,0 +1,124 @@
+package boshdeployment
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	log "code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+var (
+	// ignoredFailureRequeueAfter is the delay before the first retry of a
+	// reconcile, which failed with the 'Ignore' failure policy. It doubles
+	// with each consecutive failure.
+	ignoredFailureRequeueAfter = 5 * time.Second
+	// ignoredFailureMaxRequeueAfter caps the delay of the retries
+	ignoredFailureMaxRequeueAfter = 5 * time.Minute
+)
+
+// ignoredFailures counts the consecutive failed reconciles of each
+// BOSHDeployment with the 'Ignore' failure policy
+type ignoredFailures struct {
+	sync.Mutex
+	counts map[types.NamespacedName]int
+}
+
+func newIgnoredFailures() *ignoredFailures {
+	return &ignoredFailures{counts: map[types.NamespacedName]int{}}
+}
+
+func (f *ignoredFailures) add(nn types.NamespacedName) int {
+	f.Lock()
+	defer f.Unlock()
+	f.counts[nn]++
+	return f.counts[nn]
+}
+
+func (f *ignoredFailures) reset(nn types.NamespacedName) {
+	f.Lock()
+	defer f.Unlock()
+	delete(f.counts, nn)
+}
+
+// ignoredFailureBackoff returns the requeue delay after attempt consecutive
+// failures
+func ignoredFailureBackoff(attempt int) time.Duration {
+	delay := ignoredFailureRequeueAfter
+	for i := 1; i < attempt && delay < ignoredFailureMaxRequeueAfter; i++ {
+		delay *= 2
+	}
+	if delay > ignoredFailureMaxRequeueAfter {
+		delay = ignoredFailureMaxRequeueAfter
+	}
+	return delay
+}
+
+// halted returns true, if the deployment failed with the 'Halt' failure
+// policy and was not resumed yet. A requested resume is acknowledged by
+// removing the resume annotation.
+func (r *ReconcileBOSHDeployment) halted(ctx context.Context, instance *bdv1.BOSHDeployment) (bool, error) {
+	if instance.Spec.FailurePolicy != bdv1.FailurePolicyHalt || instance.Status.Phase != bdv1.PhaseFailed {
+		return false, nil
+	}
+	if !instance.ResumeRequested() {
+		return true, nil
+	}
+
+	annotations := instance.GetAnnotations()
+	delete(annotations, bdv1.AnnotationResume)
+	instance.SetAnnotations(annotations)
+	if err := r.client.Update(ctx, instance); err != nil {
+		return false, err
+	}
+
+	log.WithEvent(instance, "ReconcileResumed").Infof(ctx, "Resumed reconciliation of BOSHDeployment '%s/%s'", instance.Namespace, instance.Name)
+	return false, nil
+}
+
+// applyFailurePolicy decides how a failed reconcile is reported. 'Retry'
+// returns the error, so the reconcile is requeued with backoff. 'Halt'
+// records the error in the status and doesn't requeue. 'Ignore' records the
+// error in the status and requeues with its own backoff, without reporting
+// the error to the controller.
+// If the status can't be updated, the error is returned to retry the reconcile.
+func (r *ReconcileBOSHDeployment) applyFailurePolicy(ctx context.Context, instance *bdv1.BOSHDeployment, result reconcile.Result, reconcileErr error) (reconcile.Result, error) {
+	nn := types.NamespacedName{Namespace: instance.Namespace, Name: instance.Name}
+	if reconcileErr == nil {
+		r.ignoredFailures.reset(nn)
+		return result, nil
+	}
+
+	var phase bdv1.Phase
+	switch instance.Spec.FailurePolicy {
+	case bdv1.FailurePolicyHalt:
+		phase = bdv1.PhaseFailed
+	case bdv1.FailurePolicyIgnore:
+		phase = bdv1.PhaseDegraded
+	default:
+		return result, reconcileErr
+	}
+
+	err := r.updateStatus(ctx, instance, func(bdpl *bdv1.BOSHDeployment) {
+		bdpl.Status.Phase = phase
+		bdpl.Status.LastError = reconcileErr.Error()
+	})
+	if err != nil {
+		log.WithEvent(instance, "UpdateError").Errorf(ctx, "failed to update failure phase on bdpl '%s' (%v): %s", instance.Name, instance.ResourceVersion, err)
+		return result, reconcileErr
+	}
+
+	if phase == bdv1.PhaseFailed {
+		log.WithEvent(instance, "ReconcileHalted").Infof(ctx, "Halted reconciliation of BOSHDeployment '%s/%s', set the '%s' annotation to \"true\" to resume: %v",
+			instance.Namespace, instance.Name, bdv1.AnnotationResume, reconcileErr)
+		return reconcile.Result{}, nil
+	}
+
+	delay := ignoredFailureBackoff(r.ignoredFailures.add(nn))
+	log.WithEvent(instance, "ReconcileFailureIgnored").Infof(ctx, "Ignoring failed reconcile of BOSHDeployment '%s/%s', retrying after %s: %v", instance.Namespace, instance.Name, delay, reconcileErr)
+	return reconcile.Result{RequeueAfter: delay}, nil
+}
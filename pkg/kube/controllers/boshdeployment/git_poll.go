@@ -0,0 +1,43 @@
+package boshdeployment
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+)
+
+// gitPollInterval is the interval in which git references, which are not pinned to a commit, are fetched again.
+// Zero disables polling.
+var gitPollInterval = 5 * time.Minute
+
+// SetGitPollInterval initializes the package scoped git poll interval
+func SetGitPollInterval(interval time.Duration) error {
+	if interval < 0 {
+		return errors.Errorf("invalid git poll interval '%s', must not be negative", interval)
+	}
+
+	gitPollInterval = interval
+	return nil
+}
+
+// gitPollRequeue returns the delay after which the deployment is reconciled
+// again, to detect upstream changes of its git references. It returns zero,
+// if the deployment has no unpinned git references.
+func gitPollRequeue(spec bdv1.BOSHDeploymentSpec) time.Duration {
+	for _, ref := range append([]bdv1.ResourceReference{spec.Manifest}, spec.Ops...) {
+		if ref.Type == bdv1.GitReference && ref.Git != nil && !ref.Git.Pinned() {
+			return gitPollInterval
+		}
+	}
+	return 0
+}
+
+// earliestRequeue returns the shorter of two requeue delays, ignoring zero delays
+func earliestRequeue(a, b time.Duration) time.Duration {
+	if a == 0 || (b != 0 && b < a) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,149 @@
+package boshdeployment
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"text/template"
+
+	"github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/mutate"
+	log "code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+const (
+	// GrafanaDashboardKey is the config map key holding the dashboard JSON
+	GrafanaDashboardKey = "dashboard.json"
+	// LabelGrafanaDashboard is the label the Grafana operator discovers dashboard config maps by
+	LabelGrafanaDashboard = "grafana_dashboard"
+)
+
+// grafanaDashboardTemplate is the Grafana dashboard of a deployment's pods.
+// Deployment name and namespace are DNS labels, so they need no JSON escaping.
+var grafanaDashboardTemplate = template.Must(template.New("grafana-dashboard").Parse(`{
+  "title": "BOSHDeployment {{ .Namespace }}/{{ .Name }}",
+  "uid": "bdpl-{{ .Namespace }}-{{ .Name }}",
+  "tags": ["cf-operator", "boshdeployment"],
+  "timezone": "browser",
+  "schemaVersion": 22,
+  "refresh": "30s",
+  "time": {"from": "now-1h", "to": "now"},
+  "panels": [
+    {
+      "id": 1,
+      "title": "Ready pods",
+      "type": "stat",
+      "gridPos": {"h": 6, "w": 6, "x": 0, "y": 0},
+      "targets": [
+        {"expr": "sum(kube_pod_status_ready{namespace=\"{{ .Namespace }}\", pod=~\"{{ .Name }}-.*\", condition=\"true\"})", "refId": "A"}
+      ]
+    },
+    {
+      "id": 2,
+      "title": "Container restarts",
+      "type": "graph",
+      "gridPos": {"h": 6, "w": 18, "x": 6, "y": 0},
+      "targets": [
+        {"expr": "sum by (pod, container) (increase(kube_pod_container_status_restarts_total{namespace=\"{{ .Namespace }}\", pod=~\"{{ .Name }}-.*\"}[5m]))", "legendFormat": "{{"{{"}}pod{{"}}"}}/{{"{{"}}container{{"}}"}}", "refId": "A"}
+      ]
+    },
+    {
+      "id": 3,
+      "title": "CPU usage",
+      "type": "graph",
+      "gridPos": {"h": 8, "w": 12, "x": 0, "y": 6},
+      "targets": [
+        {"expr": "sum by (pod) (rate(container_cpu_usage_seconds_total{namespace=\"{{ .Namespace }}\", pod=~\"{{ .Name }}-.*\", container!=\"\"}[5m]))", "legendFormat": "{{"{{"}}pod{{"}}"}}", "refId": "A"}
+      ]
+    },
+    {
+      "id": 4,
+      "title": "Memory usage",
+      "type": "graph",
+      "gridPos": {"h": 8, "w": 12, "x": 12, "y": 6},
+      "targets": [
+        {"expr": "sum by (pod) (container_memory_working_set_bytes{namespace=\"{{ .Namespace }}\", pod=~\"{{ .Name }}-.*\", container!=\"\"})", "legendFormat": "{{"{{"}}pod{{"}}"}}", "refId": "A"}
+      ]
+    }
+  ]
+}
+`))
+
+// GrafanaDashboardConfigMapName returns the name of the config map, which
+// holds the Grafana dashboard of the deployment
+func GrafanaDashboardConfigMapName(deploymentName string) string {
+	return fmt.Sprintf("%s-grafana-dashboard", deploymentName)
+}
+
+// reconcileGrafanaDashboard creates or updates the Grafana dashboard config
+// map, if the observability bundle is enabled. Otherwise it deletes the
+// config map, if it is controlled by the deployment.
+func (r *ReconcileBOSHDeployment) reconcileGrafanaDashboard(ctx context.Context, instance *bdv1.BOSHDeployment) error {
+	if !instance.Spec.Features.ObservabilityBundle {
+		return r.deleteGrafanaDashboard(ctx, instance)
+	}
+
+	var dashboard bytes.Buffer
+	err := grafanaDashboardTemplate.Execute(&dashboard, struct{ Name, Namespace string }{instance.Name, instance.Namespace})
+	if err != nil {
+		return errors.Wrapf(err, "rendering Grafana dashboard for '%s'", instance.Name)
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      GrafanaDashboardConfigMapName(instance.Name),
+			Namespace: instance.Namespace,
+			Labels: map[string]string{
+				bdv1.LabelDeploymentName: instance.Name,
+				LabelGrafanaDashboard:    "1",
+			},
+		},
+		Data: map[string]string{
+			GrafanaDashboardKey: dashboard.String(),
+		},
+	}
+	if err := r.setReference(instance, cm, r.scheme); err != nil {
+		return errors.Wrapf(err, "failed to set ownerReference for ConfigMap '%s'", cm.Name)
+	}
+
+	op, err := controllerutil.CreateOrUpdate(ctx, r.client, cm, withOwnerReference(ctx, instance, cm, r.setReference, r.scheme, mutate.ConfigMapMutateFn(cm)))
+	if err != nil {
+		return errors.Wrapf(err, "creating or updating ConfigMap '%s'", cm.Name)
+	}
+	log.Debugf(ctx, "Grafana dashboard ConfigMap '%s' has been %s", cm.Name, op)
+
+	return nil
+}
+
+// deleteGrafanaDashboard deletes the Grafana dashboard config map, after the
+// observability bundle was disabled
+func (r *ReconcileBOSHDeployment) deleteGrafanaDashboard(ctx context.Context, instance *bdv1.BOSHDeployment) error {
+	cm := &corev1.ConfigMap{}
+	err := r.client.Get(ctx, crc.ObjectKey{Name: GrafanaDashboardConfigMapName(instance.Name), Namespace: instance.Namespace}, cm)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return errors.Wrapf(err, "getting ConfigMap '%s'", GrafanaDashboardConfigMapName(instance.Name))
+	}
+
+	if !metav1.IsControlledBy(cm, instance) {
+		return nil
+	}
+
+	err = r.client.Delete(ctx, cm)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "deleting ConfigMap '%s'", cm.Name)
+	}
+	log.Debugf(ctx, "Grafana dashboard ConfigMap '%s' has been deleted", cm.Name)
+
+	return nil
+}
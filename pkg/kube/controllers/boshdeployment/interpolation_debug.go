@@ -0,0 +1,164 @@
+package boshdeployment
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"code.cloudfoundry.org/cf-operator/pkg/bosh/qjobs"
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/mutate"
+	qjv1a1 "code.cloudfoundry.org/quarks-job/pkg/kube/apis/quarksjob/v1alpha1"
+	log "code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+const (
+	// InterpolationDebugOutputKey is the config map key holding the captured output
+	InterpolationDebugOutputKey = "output"
+	// InterpolationDebugPodKey is the config map key holding the name of the captured pod
+	InterpolationDebugPodKey = "pod"
+
+	// interpolationDebugMaxBytes limits the captured output to its last 64KB
+	interpolationDebugMaxBytes = 64 * 1024
+	// interpolationDebugRequeueAfter is the delay before capturing the output
+	// of a pending or running variable interpolation pod again
+	interpolationDebugRequeueAfter = 10 * time.Second
+)
+
+// InterpolationDebugConfigMapName returns the name of the config map,
+// which holds the output of the variable interpolation job in debug mode
+func InterpolationDebugConfigMapName(deploymentName string) string {
+	return fmt.Sprintf("%s-interpolation-debug", deploymentName)
+}
+
+// PodLogs reads the logs of a pod's container
+type PodLogs interface {
+	GetLogs(ctx context.Context, namespace string, podName string, opts *corev1.PodLogOptions) ([]byte, error)
+}
+
+// captureInterpolationDebug stores the output of the latest variable interpolation
+// pod in the debug config map. The config map is cleared, once the pod succeeded.
+// Succeeded pods are deleted by quarks-job, so a missing pod clears it, too.
+// It returns the delay, after which the output is captured again. It is zero,
+// once the pod finished, unless the job was just started and its pod may not
+// exist yet.
+func (r *ReconcileBOSHDeployment) captureInterpolationDebug(ctx context.Context, instance *bdv1.BOSHDeployment, started bool) (time.Duration, error) {
+	requeueAfter := time.Duration(0)
+	if started {
+		requeueAfter = interpolationDebugRequeueAfter
+	}
+
+	podList := &corev1.PodList{}
+	err := r.client.List(ctx, podList,
+		crc.InNamespace(instance.Namespace),
+		crc.MatchingLabels{qjv1a1.LabelQJobName: fmt.Sprintf("dm-%s", instance.Name)},
+	)
+	if err != nil {
+		return 0, errors.Wrapf(err, "listing variable interpolation pods of '%s'", instance.Name)
+	}
+
+	var pod *corev1.Pod
+	for i := range podList.Items {
+		if pod == nil || podList.Items[i].CreationTimestamp.After(pod.CreationTimestamp.Time) {
+			pod = &podList.Items[i]
+		}
+	}
+
+	if pod == nil || pod.Status.Phase == corev1.PodSucceeded {
+		log.Debugf(ctx, "No pending variable interpolation pod found for '%s', clearing debug output", instance.Name)
+		return requeueAfter, r.clearInterpolationDebug(ctx, instance)
+	}
+	if pod.Status.Phase != corev1.PodFailed {
+		requeueAfter = interpolationDebugRequeueAfter
+	}
+
+	opts := &corev1.PodLogOptions{
+		Container: qjobs.VarInterpolationContainerName,
+		Previous:  restartedSinceTermination(pod),
+	}
+	output, err := r.podLogs.GetLogs(ctx, pod.Namespace, pod.Name, opts)
+	if err != nil {
+		return 0, errors.Wrapf(err, "reading logs of variable interpolation pod '%s'", pod.Name)
+	}
+	if len(output) > interpolationDebugMaxBytes {
+		output = output[len(output)-interpolationDebugMaxBytes:]
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      InterpolationDebugConfigMapName(instance.Name),
+			Namespace: instance.Namespace,
+			Labels: map[string]string{
+				bdv1.LabelDeploymentName: instance.Name,
+			},
+		},
+		Data: map[string]string{
+			InterpolationDebugPodKey:    pod.Name,
+			InterpolationDebugOutputKey: string(output),
+		},
+	}
+	if err := r.setReference(instance, cm, r.scheme); err != nil {
+		return 0, errors.Wrapf(err, "failed to set ownerReference for ConfigMap '%s'", cm.Name)
+	}
+
+	op, err := controllerutil.CreateOrUpdate(ctx, r.client, cm, withOwnerReference(ctx, instance, cm, r.setReference, r.scheme, mutate.ConfigMapMutateFn(cm)))
+	if err != nil {
+		return 0, errors.Wrapf(err, "creating or updating ConfigMap '%s'", cm.Name)
+	}
+	log.Debugf(ctx, "Interpolation debug ConfigMap '%s' has been %s", cm.Name, op)
+
+	return requeueAfter, nil
+}
+
+// clearInterpolationDebug empties the debug config map, if it exists
+func (r *ReconcileBOSHDeployment) clearInterpolationDebug(ctx context.Context, instance *bdv1.BOSHDeployment) error {
+	cm := &corev1.ConfigMap{}
+	err := r.client.Get(ctx, crc.ObjectKey{Name: InterpolationDebugConfigMapName(instance.Name), Namespace: instance.Namespace}, cm)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return errors.Wrapf(err, "getting ConfigMap '%s'", InterpolationDebugConfigMapName(instance.Name))
+	}
+
+	if len(cm.Data) == 0 {
+		return nil
+	}
+
+	cm.Data = nil
+	return errors.Wrapf(r.client.Update(ctx, cm), "clearing ConfigMap '%s'", cm.Name)
+}
+
+// restartedSinceTermination returns true if the interpolation container is
+// not terminated, but a previous run was, so its logs are the interesting ones
+func restartedSinceTermination(pod *corev1.Pod) bool {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name == qjobs.VarInterpolationContainerName {
+			return status.State.Terminated == nil && status.LastTerminationState.Terminated != nil
+		}
+	}
+	return false
+}
+
+// NewPodLogs returns a PodLogs, which uses the kubernetes core API
+func NewPodLogs(client kubernetes.Interface) PodLogs {
+	return kubePodLogs{client: client}
+}
+
+type kubePodLogs struct {
+	client kubernetes.Interface
+}
+
+// GetLogs returns the logs of a pod's container
+func (k kubePodLogs) GetLogs(ctx context.Context, namespace string, podName string, opts *corev1.PodLogOptions) ([]byte, error) {
+	return k.client.CoreV1().Pods(namespace).GetLogs(podName, opts).Do().Raw()
+}
@@ -0,0 +1,183 @@
+package boshdeployment
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	qjv1a1 "code.cloudfoundry.org/quarks-job/pkg/kube/apis/quarksjob/v1alpha1"
+	log "code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+// Recovery actions for variable interpolation jobs, which exceed the interpolation timeout
+const (
+	// InterpolationTimeoutRecreate deletes the job, so the next reconcile creates it again
+	InterpolationTimeoutRecreate = "recreate"
+	// InterpolationTimeoutDegrade marks the BOSHDeployment as degraded
+	InterpolationTimeoutDegrade = "degrade"
+)
+
+var (
+	// interpolationTimeout is the time a variable interpolation job may be active, zero disables the timeout
+	interpolationTimeout time.Duration
+	// interpolationTimeoutAction is the recovery action for jobs exceeding interpolationTimeout
+	interpolationTimeoutAction = InterpolationTimeoutRecreate
+)
+
+// SetInterpolationTimeout initializes the package scoped interpolation timeout variables
+func SetInterpolationTimeout(timeout time.Duration, action string) error {
+	if timeout < 0 {
+		return errors.Errorf("invalid interpolation timeout '%s', must not be negative", timeout)
+	}
+	switch action {
+	case InterpolationTimeoutRecreate, InterpolationTimeoutDegrade:
+	default:
+		return errors.Errorf("invalid interpolation timeout action '%s', must be '%s' or '%s'", action, InterpolationTimeoutRecreate, InterpolationTimeoutDegrade)
+	}
+
+	interpolationTimeout = timeout
+	interpolationTimeoutAction = action
+	return nil
+}
+
+// staleJobRequeueAfter is the delay, which lets the deletion of a superseded
+// variable interpolation job propagate before it is created again
+const staleJobRequeueAfter = 5 * time.Second
+
+// cleanupStaleVariableInterpolationJob deletes the variable interpolation job,
+// if it is still running on a with-ops manifest, which is superseded by manifestSecret,
+// or on a trigger manifest, which is superseded by the one of dmQJob.
+// It returns true, if the reconcile has to wait for the deletion.
+func (r *ReconcileBOSHDeployment) cleanupStaleVariableInterpolationJob(ctx context.Context, instance *bdv1.BOSHDeployment, dmQJob *qjv1a1.QuarksJob, manifestSecret *corev1.Secret) (bool, error) {
+	qJob := &qjv1a1.QuarksJob{}
+	err := r.client.Get(ctx, crc.ObjectKey{Name: dmQJob.Name, Namespace: dmQJob.Namespace}, qJob)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, errors.Wrapf(err, "getting QuarksJob '%s'", dmQJob.Name)
+	}
+
+	if qJob.DeletionTimestamp != nil {
+		log.Debugf(ctx, "Waiting for the deletion of QuarksJob '%s'", qJob.Name)
+		return true, nil
+	}
+
+	superseded, err := r.interpolationInputChanged(ctx, instance, qJob, dmQJob, manifestSecret)
+	if err != nil || !superseded {
+		return false, err
+	}
+
+	running, err := r.isQuarksJobRunning(ctx, qJob)
+	if err != nil || !running {
+		return false, err
+	}
+
+	log.WithEvent(instance, "StaleVariableInterpolationJob").Infof(ctx, "Deleting QuarksJob '%s', which is running on a superseded manifest", qJob.Name)
+	err = r.client.Delete(ctx, qJob, crc.PropagationPolicy(metav1.DeletePropagationForeground))
+	if err != nil && !apierrors.IsNotFound(err) {
+		return false, errors.Wrapf(err, "deleting QuarksJob '%s'", qJob.Name)
+	}
+
+	return true, nil
+}
+
+// isQuarksJobRunning returns true, if one of the jobs created for the QuarksJob has active pods
+func (r *ReconcileBOSHDeployment) isQuarksJobRunning(ctx context.Context, qJob *qjv1a1.QuarksJob) (bool, error) {
+	since, err := r.quarksJobActiveSince(ctx, qJob)
+	return since != nil, err
+}
+
+// quarksJobActiveSince returns the start time of the oldest job created for the
+// QuarksJob, which still has active pods, or nil if none is active
+func (r *ReconcileBOSHDeployment) quarksJobActiveSince(ctx context.Context, qJob *qjv1a1.QuarksJob) (*metav1.Time, error) {
+	jobList := &batchv1.JobList{}
+	err := r.client.List(ctx, jobList,
+		crc.InNamespace(qJob.Namespace),
+		crc.MatchingLabels{qjv1a1.LabelQJobName: qJob.Name},
+	)
+	if err != nil {
+		return nil, errors.Wrapf(err, "listing jobs of QuarksJob '%s'", qJob.Name)
+	}
+
+	var since *metav1.Time
+	for _, job := range jobList.Items {
+		if job.Status.Active == 0 {
+			continue
+		}
+		start := job.CreationTimestamp
+		if job.Status.StartTime != nil {
+			start = *job.Status.StartTime
+		}
+		if since == nil || start.Before(since) {
+			since = start.DeepCopy()
+		}
+	}
+
+	return since, nil
+}
+
+// checkInterpolationTimeout recovers from a variable interpolation job, which
+// has been active for longer than the interpolation timeout. It returns true,
+// if the reconcile has to stop with the returned result.
+func (r *ReconcileBOSHDeployment) checkInterpolationTimeout(ctx context.Context, instance *bdv1.BOSHDeployment, dmQJob *qjv1a1.QuarksJob) (bool, reconcile.Result, error) {
+	if interpolationTimeout == 0 {
+		return false, reconcile.Result{}, nil
+	}
+
+	since, err := r.quarksJobActiveSince(ctx, dmQJob)
+	if err != nil || since == nil || time.Since(since.Time) < interpolationTimeout {
+		return false, reconcile.Result{}, err
+	}
+
+	if interpolationTimeoutAction == InterpolationTimeoutDegrade {
+		err := r.updateStatus(ctx, instance, func(bdpl *bdv1.BOSHDeployment) {
+			bdpl.Status.Phase = bdv1.PhaseDegraded
+		})
+		if err != nil {
+			log.WithEvent(instance, "UpdateError").Errorf(ctx, "failed to update phase on bdpl '%s' (%v): %s", instance.Name, instance.ResourceVersion, err)
+		}
+		return true, reconcile.Result{},
+			log.WithEvent(instance, "InterpolationTimeout").Errorf(ctx, "QuarksJob '%s' has been active since %s, longer than %s", dmQJob.Name, since.Format(time.RFC3339), interpolationTimeout)
+	}
+
+	log.WithEvent(instance, "InterpolationTimeout").Infof(ctx, "Recreating QuarksJob '%s', which has been active since %s, longer than %s", dmQJob.Name, since.Format(time.RFC3339), interpolationTimeout)
+	err = r.client.Delete(ctx, dmQJob.DeepCopy(), crc.PropagationPolicy(metav1.DeletePropagationForeground))
+	if err != nil && !apierrors.IsNotFound(err) {
+		return true, reconcile.Result{}, errors.Wrapf(err, "deleting QuarksJob '%s'", dmQJob.Name)
+	}
+
+	return true, reconcile.Result{RequeueAfter: staleJobRequeueAfter}, nil
+}
+
+// interpolationTimeoutRequeue returns the delay, after which the variable
+// interpolation job has to be checked for the interpolation timeout again
+func (r *ReconcileBOSHDeployment) interpolationTimeoutRequeue(ctx context.Context, dmQJob *qjv1a1.QuarksJob, op controllerutil.OperationResult) (time.Duration, error) {
+	if interpolationTimeout == 0 {
+		return 0, nil
+	}
+	if op != controllerutil.OperationResultNone {
+		return interpolationTimeout, nil
+	}
+
+	since, err := r.quarksJobActiveSince(ctx, dmQJob)
+	if err != nil || since == nil {
+		return 0, err
+	}
+
+	remaining := interpolationTimeout - time.Since(since.Time)
+	if remaining < time.Second {
+		remaining = time.Second
+	}
+	return remaining, nil
+}
@@ -0,0 +1,74 @@
+package boshdeployment
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+)
+
+// Limits of the pod DNS config, as enforced by the Kubernetes API server
+const (
+	maxDNSNameservers     = 3
+	maxDNSSearchPaths     = 6
+	maxDNSSearchListChars = 256
+)
+
+// validateJobDNS rejects DNS settings for the generated job pods, which the
+// API server would reject when the QuarksJob creates the pods
+func validateJobDNS(dns *bdv1.JobDNS) error {
+	if dns == nil {
+		return nil
+	}
+
+	switch dns.DNSPolicy {
+	case "", corev1.DNSClusterFirst, corev1.DNSClusterFirstWithHostNet, corev1.DNSDefault, corev1.DNSNone:
+	default:
+		return fmt.Errorf("unsupported dnsPolicy '%s'", dns.DNSPolicy)
+	}
+
+	config := dns.DNSConfig
+	if config == nil {
+		if dns.DNSPolicy == corev1.DNSNone {
+			return errors.New("dnsConfig must be set for dnsPolicy 'None'")
+		}
+		return nil
+	}
+
+	if dns.DNSPolicy == corev1.DNSNone && len(config.Nameservers) == 0 {
+		return errors.New("dnsConfig must contain at least one nameserver for dnsPolicy 'None'")
+	}
+	if len(config.Nameservers) > maxDNSNameservers {
+		return fmt.Errorf("dnsConfig must not contain more than %d nameservers", maxDNSNameservers)
+	}
+	for _, ns := range config.Nameservers {
+		if net.ParseIP(ns) == nil {
+			return fmt.Errorf("dnsConfig nameserver '%s' is not a valid IP address", ns)
+		}
+	}
+
+	if len(config.Searches) > maxDNSSearchPaths {
+		return fmt.Errorf("dnsConfig must not contain more than %d search paths", maxDNSSearchPaths)
+	}
+	if chars := len(strings.Join(config.Searches, " ")); chars > maxDNSSearchListChars {
+		return fmt.Errorf("dnsConfig search paths must not be longer than %d characters in total", maxDNSSearchListChars)
+	}
+	for _, search := range config.Searches {
+		if msgs := validation.IsDNS1123Subdomain(strings.TrimSuffix(search, ".")); len(msgs) > 0 {
+			return fmt.Errorf("dnsConfig search path '%s' is invalid: %s", search, strings.Join(msgs, ", "))
+		}
+	}
+
+	for _, option := range config.Options {
+		if option.Name == "" {
+			return errors.New("dnsConfig options must have a name")
+		}
+	}
+
+	return nil
+}
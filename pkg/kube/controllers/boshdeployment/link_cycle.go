@@ -0,0 +1,148 @@
+package boshdeployment
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/controllers/quarkslink"
+)
+
+// linkCycleCheckInterval is the interval in which the links between the
+// BOSHDeployments of a namespace are checked for cycles. Zero disables the check.
+var linkCycleCheckInterval time.Duration
+
+// SetLinkCycleCheckInterval initializes the package scoped link cycle check interval
+func SetLinkCycleCheckInterval(interval time.Duration) error {
+	if interval < 0 {
+		return errors.Errorf("invalid link cycle check interval '%s', must not be negative", interval)
+	}
+
+	linkCycleCheckInterval = interval
+	return nil
+}
+
+// linkGraph maps the name of a link providing deployment to the names of the
+// deployments consuming its links
+type linkGraph map[string]sets.String
+
+// addEdge records that consumer consumes a link of provider. Self links and
+// deployments, which are not in the deployments set, are ignored.
+func (g linkGraph) addEdge(deployments sets.String, provider, consumer string) {
+	if provider == consumer || !deployments.Has(provider) || !deployments.Has(consumer) {
+		return
+	}
+	if _, ok := g[provider]; !ok {
+		g[provider] = sets.NewString()
+	}
+	g[provider].Insert(consumer)
+}
+
+// newLinkGraph builds the link graph between deployments from the link
+// annotations of the objects in their namespace:
+// * link provider services, whose selected pods belong to a provider deployment
+// * link provider secrets, which are labeled with a provider deployment
+// * pods of a consumer deployment, which are entangled with a provider deployment
+func newLinkGraph(deployments sets.String, services []corev1.Service, secrets []corev1.Secret, pods []corev1.Pod) linkGraph {
+	graph := linkGraph{}
+
+	for _, svc := range services {
+		consumer, ok := svc.GetAnnotations()[bdv1.LabelDeploymentName]
+		if !ok || !isLinkProviderService(&svc) || len(svc.Spec.Selector) == 0 {
+			continue
+		}
+		selector := labels.SelectorFromSet(svc.Spec.Selector)
+		for _, pod := range pods {
+			if pod.Namespace == svc.Namespace && selector.Matches(labels.Set(pod.GetLabels())) {
+				graph.addEdge(deployments, pod.GetLabels()[bdv1.LabelDeploymentName], consumer)
+			}
+		}
+	}
+
+	for _, s := range secrets {
+		consumer, ok := s.GetAnnotations()[bdv1.LabelDeploymentName]
+		if _, provides := s.GetAnnotations()[bdv1.AnnotationLinkProvidesKey]; !ok || !provides {
+			continue
+		}
+		graph.addEdge(deployments, s.GetLabels()[bdv1.LabelDeploymentName], consumer)
+	}
+
+	for _, pod := range pods {
+		annotations := pod.GetAnnotations()
+		if annotations[quarkslink.ConsumesKey] == "" {
+			continue
+		}
+		graph.addEdge(deployments, annotations[quarkslink.DeploymentKey], pod.GetLabels()[bdv1.LabelDeploymentName])
+	}
+
+	return graph
+}
+
+// cycles returns the groups of deployments, which consume each others links
+// directly or transitively. These are the strongly connected components of
+// the graph with more than one deployment, found with Tarjan's algorithm.
+// Names are sorted within and across groups.
+func (g linkGraph) cycles() [][]string {
+	nodes := sets.NewString()
+	for provider, consumers := range g {
+		nodes.Insert(provider)
+		nodes.Insert(consumers.UnsortedList()...)
+	}
+
+	index := map[string]int{}
+	lowlink := map[string]int{}
+	onStack := sets.NewString()
+	stack := []string{}
+	cycles := [][]string{}
+
+	var connect func(node string)
+	connect = func(node string) {
+		index[node] = len(index)
+		lowlink[node] = index[node]
+		stack = append(stack, node)
+		onStack.Insert(node)
+
+		for _, consumer := range g[node].List() {
+			if _, visited := index[consumer]; !visited {
+				connect(consumer)
+				if lowlink[consumer] < lowlink[node] {
+					lowlink[node] = lowlink[consumer]
+				}
+			} else if onStack.Has(consumer) && index[consumer] < lowlink[node] {
+				lowlink[node] = index[consumer]
+			}
+		}
+
+		if lowlink[node] != index[node] {
+			return
+		}
+		component := []string{}
+		for {
+			member := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack.Delete(member)
+			component = append(component, member)
+			if member == node {
+				break
+			}
+		}
+		if len(component) > 1 {
+			sort.Strings(component)
+			cycles = append(cycles, component)
+		}
+	}
+
+	for _, node := range nodes.List() {
+		if _, visited := index[node]; !visited {
+			connect(node)
+		}
+	}
+
+	sort.Slice(cycles, func(i, j int) bool { return cycles[i][0] < cycles[j][0] })
+	return cycles
+}
@@ -0,0 +1,73 @@
+package boshdeployment
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/backpressure"
+	"code.cloudfoundry.org/quarks-utils/pkg/config"
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+// AddLinkCycleCheck creates a new controller, which periodically checks the
+// links between the BOSHDeployments of a namespace for cycles. The check is
+// diagnostic only and doesn't block reconciles. It is not added, if the link
+// cycle check interval is zero.
+func AddLinkCycleCheck(ctx context.Context, config *config.Config, mgr manager.Manager) error {
+	if linkCycleCheckInterval == 0 {
+		return nil
+	}
+
+	ctx = ctxlog.NewContextWithRecorder(ctx, "link-cycle-reconciler", mgr.GetEventRecorderFor("link-cycle-recorder"))
+	r := NewLinkCycleReconciler(ctx, config, mgr)
+
+	// Create a new controller
+	c, err := controller.New("link-cycle-controller", mgr, controller.Options{
+		Reconciler:              backpressure.NewReconciler(ctx, r),
+		MaxConcurrentReconciles: 1,
+	})
+	if err != nil {
+		return errors.Wrap(err, "Adding link cycle controller to manager failed.")
+	}
+
+	// Start checking a namespace, once a BOSHDeployment is created in it.
+	// Deployments existing on startup trigger create events, too. All
+	// deployments of a namespace map to the same request, so the namespace
+	// is only queued once and then requeued by the reconciler.
+	p := predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			ctxlog.NewPredicateEvent(e.Object).Debug(
+				ctx, e.Meta, bdv1.BOSHDeploymentResourceName,
+				fmt.Sprintf("Create predicate passed for '%s'", e.Meta.GetName()),
+			)
+			return true
+		},
+		DeleteFunc:  func(e event.DeleteEvent) bool { return false },
+		GenericFunc: func(e event.GenericEvent) bool { return false },
+		UpdateFunc:  func(e event.UpdateEvent) bool { return false },
+	}
+
+	err = c.Watch(&source.Kind{Type: &bdv1.BOSHDeployment{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(a handler.MapObject) []reconcile.Request {
+			request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: a.Meta.GetNamespace()}}
+			ctxlog.NewMappingEvent(a.Object).Debug(ctx, request, "Namespace", a.Meta.GetName(), bdv1.BOSHDeploymentResourceName)
+			return []reconcile.Request{request}
+		}),
+	}, p)
+	if err != nil {
+		return errors.Wrapf(err, "Watching bosh deployment failed in link cycle controller.")
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package boshdeployment
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-utils/pkg/config"
+	log "code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+// NewLinkCycleReconciler returns a new reconcile.Reconciler
+func NewLinkCycleReconciler(ctx context.Context, config *config.Config, mgr manager.Manager) reconcile.Reconciler {
+	return &ReconcileLinkCycle{
+		ctx:    ctx,
+		config: config,
+		client: mgr.GetClient(),
+	}
+}
+
+// ReconcileLinkCycle checks the links between the BOSHDeployments of a namespace for cycles
+type ReconcileLinkCycle struct {
+	ctx    context.Context
+	config *config.Config
+	client crc.Client
+}
+
+// Reconcile builds the link graph of the BOSHDeployments in the request's
+// namespace and records a 'LinkCycleDetected' event on every deployment,
+// which is part of a cycle. The namespace is checked again after the link
+// cycle check interval, as long as it contains BOSHDeployments.
+func (r *ReconcileLinkCycle) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	ctx, cancel := context.WithTimeout(r.ctx, r.config.CtxTimeOut)
+	defer cancel()
+
+	namespace := request.Namespace
+	log.Debugf(ctx, "Checking links of BOSHDeployments in namespace '%s' for cycles", namespace)
+
+	deployments := &bdv1.BOSHDeploymentList{}
+	err := r.client.List(ctx, deployments, crc.InNamespace(namespace))
+	if err != nil {
+		return reconcile.Result{}, errors.Wrapf(err, "listing BOSHDeployments in namespace '%s' for link cycle check", namespace)
+	}
+	if len(deployments.Items) == 0 {
+		log.Debugf(ctx, "Stop link cycle check: no BOSHDeployments in namespace '%s'", namespace)
+		return reconcile.Result{}, nil
+	}
+
+	names := sets.NewString()
+	for _, bdpl := range deployments.Items {
+		names.Insert(bdpl.Name)
+	}
+
+	graph, err := r.linkGraph(ctx, namespace, names)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	for _, cycle := range graph.cycles() {
+		members := sets.NewString(cycle...)
+		for i := range deployments.Items {
+			bdpl := &deployments.Items[i]
+			if !members.Has(bdpl.Name) {
+				continue
+			}
+			log.WithEvent(bdpl, "LinkCycleDetected").Infof(ctx, "BOSHDeployment '%s/%s' is part of a link cycle between deployments: %s",
+				namespace, bdpl.Name, strings.Join(cycle, ", "))
+		}
+	}
+
+	return reconcile.Result{RequeueAfter: linkCycleCheckInterval}, nil
+}
+
+// linkGraph lists the link services, secrets and pods of the namespace and builds the link graph between the deployments
+func (r *ReconcileLinkCycle) linkGraph(ctx context.Context, namespace string, deployments sets.String) (linkGraph, error) {
+	services := &corev1.ServiceList{}
+	err := r.client.List(ctx, services, crc.InNamespace(namespace))
+	if err != nil {
+		return nil, errors.Wrapf(err, "listing services in namespace '%s' for link cycle check", namespace)
+	}
+
+	secrets := &corev1.SecretList{}
+	err = r.client.List(ctx, secrets, crc.InNamespace(namespace))
+	if err != nil {
+		return nil, errors.Wrapf(err, "listing secrets in namespace '%s' for link cycle check", namespace)
+	}
+
+	pods := &corev1.PodList{}
+	err = r.client.List(ctx, pods, crc.InNamespace(namespace))
+	if err != nil {
+		return nil, errors.Wrapf(err, "listing pods in namespace '%s' for link cycle check", namespace)
+	}
+
+	return newLinkGraph(deployments, services.Items, secrets.Items, pods.Items), nil
+}
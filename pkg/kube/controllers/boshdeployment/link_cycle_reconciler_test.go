@@ -0,0 +1,139 @@
+package boshdeployment_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	cfd "code.cloudfoundry.org/cf-operator/pkg/kube/controllers/boshdeployment"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/controllers/fakes"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/controllers/quarkslink"
+	cfcfg "code.cloudfoundry.org/quarks-utils/pkg/config"
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+	helper "code.cloudfoundry.org/quarks-utils/testing/testhelper"
+)
+
+var _ = Describe("ReconcileLinkCycle", func() {
+	var (
+		manager     *fakes.FakeManager
+		client      *fakes.FakeClient
+		recorder    *record.FakeRecorder
+		reconciler  reconcile.Reconciler
+		request     reconcile.Request
+		deployments []bdv1.BOSHDeployment
+		secrets     []corev1.Secret
+		pods        []corev1.Pod
+	)
+
+	deployment := func(name string) bdv1.BOSHDeployment {
+		return bdv1.BOSHDeployment{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+	}
+
+	// linkSecret provides a link of provider to consumer
+	linkSecret := func(provider, consumer string) corev1.Secret {
+		return corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Name:      provider + "-link",
+			Namespace: "default",
+			Labels:    map[string]string{bdv1.LabelDeploymentName: provider},
+			Annotations: map[string]string{
+				bdv1.LabelDeploymentName:       consumer,
+				bdv1.AnnotationLinkProvidesKey: `{"name":"db","type":"database"}`,
+			},
+		}}
+	}
+
+	// entangledPod consumes a link of provider in a pod of consumer
+	entangledPod := func(provider, consumer string) corev1.Pod {
+		return corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:      consumer + "-pod",
+			Namespace: "default",
+			Labels:    map[string]string{bdv1.LabelDeploymentName: consumer},
+			Annotations: map[string]string{
+				quarkslink.DeploymentKey: provider,
+				quarkslink.ConsumesKey:   `[{"name":"nats","type":"nats"}]`,
+			},
+		}}
+	}
+
+	BeforeEach(func() {
+		Expect(cfd.SetLinkCycleCheckInterval(10 * time.Minute)).To(Succeed())
+
+		manager = &fakes.FakeManager{}
+		recorder = record.NewFakeRecorder(20)
+		request = reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default"}}
+		deployments = []bdv1.BOSHDeployment{deployment("foo"), deployment("bar"), deployment("baz")}
+		secrets = []corev1.Secret{linkSecret("foo", "bar")}
+		pods = []corev1.Pod{entangledPod("bar", "foo")}
+
+		client = &fakes.FakeClient{}
+		client.ListCalls(func(context context.Context, object runtime.Object, _ ...crc.ListOption) error {
+			switch object := object.(type) {
+			case *bdv1.BOSHDeploymentList:
+				object.Items = deployments
+			case *corev1.SecretList:
+				object.Items = secrets
+			case *corev1.PodList:
+				object.Items = pods
+			}
+			return nil
+		})
+		manager.GetClientReturns(client)
+	})
+
+	AfterEach(func() {
+		Expect(cfd.SetLinkCycleCheckInterval(0)).To(Succeed())
+	})
+
+	JustBeforeEach(func() {
+		_, log := helper.NewTestLogger()
+		ctx := ctxlog.NewParentContext(log)
+		ctx = ctxlog.NewContextWithRecorder(ctx, "TestRecorder", recorder)
+		config := &cfcfg.Config{CtxTimeOut: 10 * time.Second}
+		reconciler = cfd.NewLinkCycleReconciler(ctx, config, manager)
+	})
+
+	It("records an event on each deployment in a link cycle and checks again after the interval", func() {
+		result, err := reconciler.Reconcile(request)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(10 * time.Minute))
+
+		Expect(<-recorder.Events).To(And(ContainSubstring("LinkCycleDetected"), ContainSubstring("'default/foo'"), ContainSubstring("bar, foo")))
+		Expect(<-recorder.Events).To(And(ContainSubstring("LinkCycleDetected"), ContainSubstring("'default/bar'")))
+		Expect(recorder.Events).ToNot(Receive())
+	})
+
+	It("doesn't record events for acyclic links", func() {
+		pods = []corev1.Pod{entangledPod("bar", "baz")}
+
+		_, err := reconciler.Reconcile(request)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(recorder.Events).ToNot(Receive())
+	})
+
+	It("ignores links of objects, which don't belong to a BOSHDeployment", func() {
+		deployments = []bdv1.BOSHDeployment{deployment("foo")}
+
+		_, err := reconciler.Reconcile(request)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(recorder.Events).ToNot(Receive())
+	})
+
+	It("stops checking namespaces without BOSHDeployments", func() {
+		deployments = nil
+
+		result, err := reconciler.Reconcile(request)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(Equal(reconcile.Result{}))
+	})
+})
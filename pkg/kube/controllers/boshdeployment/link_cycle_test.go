@@ -0,0 +1,76 @@
+package boshdeployment
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+)
+
+var _ = Describe("linkGraph", func() {
+	var (
+		deployments sets.String
+		graph       linkGraph
+	)
+
+	BeforeEach(func() {
+		deployments = sets.NewString("a", "b", "c", "d", "e")
+		graph = linkGraph{}
+	})
+
+	Describe("cycles", func() {
+		It("returns no cycles for acyclic links", func() {
+			graph.addEdge(deployments, "a", "b")
+			graph.addEdge(deployments, "b", "c")
+			graph.addEdge(deployments, "a", "c")
+
+			Expect(graph.cycles()).To(BeEmpty())
+		})
+
+		It("ignores self links and unknown deployments", func() {
+			graph.addEdge(deployments, "a", "a")
+			graph.addEdge(deployments, "a", "x")
+			graph.addEdge(deployments, "x", "a")
+
+			Expect(graph).To(BeEmpty())
+			Expect(graph.cycles()).To(BeEmpty())
+		})
+
+		It("returns each group of deployments linked in a cycle", func() {
+			graph.addEdge(deployments, "c", "b")
+			graph.addEdge(deployments, "b", "a")
+			graph.addEdge(deployments, "a", "c")
+			graph.addEdge(deployments, "a", "d")
+			graph.addEdge(deployments, "d", "e")
+			graph.addEdge(deployments, "e", "d")
+
+			Expect(graph.cycles()).To(Equal([][]string{{"a", "b", "c"}, {"d", "e"}}))
+		})
+	})
+
+	Describe("newLinkGraph", func() {
+		It("links the deployment of the pods selected by a link provider service to its consumer", func() {
+			services := []corev1.Service{{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "db",
+					Namespace: "default",
+					Annotations: map[string]string{
+						bdv1.LabelDeploymentName:           "b",
+						bdv1.AnnotationLinkProviderService: "db",
+					},
+				},
+				Spec: corev1.ServiceSpec{Selector: map[string]string{"app": "db"}},
+			}}
+			pods := []corev1.Pod{
+				{ObjectMeta: metav1.ObjectMeta{Name: "db-0", Namespace: "default", Labels: map[string]string{"app": "db", bdv1.LabelDeploymentName: "a"}}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "web-0", Namespace: "default", Labels: map[string]string{"app": "web", bdv1.LabelDeploymentName: "c"}}},
+			}
+
+			Expect(newLinkGraph(deployments, services, nil, pods)).To(Equal(linkGraph{"a": sets.NewString("b")}))
+		})
+	})
+})
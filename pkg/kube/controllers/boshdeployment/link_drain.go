@@ -0,0 +1,105 @@
+package boshdeployment
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	log "code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+// linkDrainPeriod is the time consumers get to stop using a removed link provider pod, zero disables draining
+var linkDrainPeriod time.Duration
+
+// SetLinkDrainPeriod initializes the package scoped link drain period
+func SetLinkDrainPeriod(period time.Duration) error {
+	if period < 0 {
+		return errors.Errorf("invalid link drain period '%s', must not be negative", period)
+	}
+
+	linkDrainPeriod = period
+	return nil
+}
+
+// protectLinkPod adds the link drain finalizer to a pod of a kube native
+// link provider and lists the deployment as its consumer, so the removal of
+// the pod waits until the consumers were drained
+func (r *ReconcileBOSHDeployment) protectLinkPod(ctx context.Context, instance *bdv1.BOSHDeployment, pod *corev1.Pod) error {
+	consumer := instance.Namespace + "/" + instance.Name
+	consumers := linkConsumers(pod)
+	if containsString(pod.GetFinalizers(), bdv1.FinalizerLinkDrain) && containsString(consumers, consumer) {
+		return nil
+	}
+
+	if !containsString(pod.GetFinalizers(), bdv1.FinalizerLinkDrain) {
+		pod.SetFinalizers(append(pod.GetFinalizers(), bdv1.FinalizerLinkDrain))
+	}
+	if !containsString(consumers, consumer) {
+		annotations := pod.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[bdv1.AnnotationLinkConsumers] = strings.Join(append(consumers, consumer), ",")
+		pod.SetAnnotations(annotations)
+	}
+
+	if err := r.client.Update(ctx, pod); err != nil {
+		return errors.Wrapf(err, "adding finalizer '%s' to link pod '%s/%s'", bdv1.FinalizerLinkDrain, pod.Namespace, pod.Name)
+	}
+	return nil
+}
+
+// drainLinkPod returns the remaining drain period of a link provider pod,
+// which is being removed. Once the drain period has passed, the link drain
+// finalizer is removed, so the removal of the pod completes.
+func (r *ReconcileBOSHDeployment) drainLinkPod(ctx context.Context, pod *corev1.Pod, now time.Time) (time.Duration, error) {
+	remaining := pod.DeletionTimestamp.Add(linkDrainPeriod).Sub(now)
+	if remaining > 0 {
+		return remaining, nil
+	}
+
+	pod.SetFinalizers(removeString(pod.GetFinalizers(), bdv1.FinalizerLinkDrain))
+	if err := r.client.Update(ctx, pod); err != nil {
+		return 0, errors.Wrapf(err, "removing finalizer '%s' from link pod '%s/%s'", bdv1.FinalizerLinkDrain, pod.Namespace, pod.Name)
+	}
+
+	log.Infof(ctx, "Drained link pod '%s/%s'", pod.Namespace, pod.Name)
+	return 0, nil
+}
+
+// linkDrainStarted returns true, if the removal of a drained link provider pod started
+func linkDrainStarted(pod *corev1.Pod) bool {
+	return pod.DeletionTimestamp != nil && containsString(pod.GetFinalizers(), bdv1.FinalizerLinkDrain)
+}
+
+// linkConsumers returns the consumers listed in the link consumers annotation of a pod
+func linkConsumers(pod *corev1.Pod) []string {
+	consumers := []string{}
+	for _, consumer := range strings.Split(pod.GetAnnotations()[bdv1.AnnotationLinkConsumers], ",") {
+		if consumer != "" {
+			consumers = append(consumers, consumer)
+		}
+	}
+	return consumers
+}
+
+// linkConsumerRequests returns the reconcile requests of the consumers of a link provider pod
+func linkConsumerRequests(pod *corev1.Pod) []reconcile.Request {
+	reconciles := []reconcile.Request{}
+	for _, consumer := range linkConsumers(pod) {
+		parts := strings.SplitN(consumer, "/", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		reconciles = append(reconciles, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: parts[0], Name: parts[1]},
+		})
+	}
+	return reconciles
+}
@@ -0,0 +1,252 @@
+package boshdeployment
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+
+	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	log "code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+// linkNetworkPolicyCheck enables warnings about network policies, which
+// likely block the traffic of links
+var linkNetworkPolicyCheck = false
+
+// SetLinkNetworkPolicyCheck initializes the package scoped linkNetworkPolicyCheck variable
+func SetLinkNetworkPolicyCheck(enabled bool) {
+	linkNetworkPolicyCheck = enabled
+}
+
+// linkTraffic is the traffic from the pods of a consuming instance group to
+// a pod of a link provider
+type linkTraffic struct {
+	namespace       labels.Set
+	consumer        labels.Set
+	provider        labels.Set
+	ports           []corev1.ServicePort
+	ingressPolicies []string
+	egressPolicies  []string
+}
+
+// checkLinkNetworkPolicies records a 'LinkNetworkBlocked' warning event for
+// every instance group consuming the link, whose pods the network policies
+// of the namespace likely don't permit to reach the provider pods on the
+// ports of the link provider service. The consumer pods are matched by their
+// deployment and instance group labels, peers with an IP block are assumed
+// to permit the traffic. The check is diagnostic only, so errors are logged
+// and don't fail the reconcile.
+func (r *ReconcileBOSHDeployment) checkLinkNetworkPolicies(ctx context.Context, instance *bdv1.BOSHDeployment, manifest *bdm.Manifest, providerName string, ports []corev1.ServicePort, pods []corev1.Pod) {
+	if !linkNetworkPolicyCheck || len(pods) == 0 {
+		return
+	}
+
+	policies := &networkingv1.NetworkPolicyList{}
+	err := r.client.List(ctx, policies, crc.InNamespace(instance.Namespace))
+	if err != nil {
+		log.Infof(ctx, "Skipping network policy check of link '%s' of BOSHDeployment '%s/%s': %v", providerName, instance.Namespace, instance.Name, err)
+		return
+	}
+	if len(policies.Items) == 0 {
+		return
+	}
+
+	// without the namespace labels, namespace selectors are assumed to match
+	var namespaceLabels labels.Set
+	namespace := &corev1.Namespace{}
+	err = r.client.Get(ctx, crc.ObjectKey{Name: instance.Namespace}, namespace)
+	if err == nil {
+		namespaceLabels = labels.Set(namespace.Labels)
+	}
+
+	checked := map[string]bool{}
+	for _, consumer := range manifest.ListLinkConsumers(providerName) {
+		if checked[consumer.InstanceGroup] {
+			continue
+		}
+		checked[consumer.InstanceGroup] = true
+
+		for _, pod := range pods {
+			t := &linkTraffic{
+				namespace: namespaceLabels,
+				consumer: labels.Set{
+					bdm.LabelDeploymentName:    instance.Name,
+					bdm.LabelInstanceGroupName: consumer.InstanceGroup,
+				},
+				provider: labels.Set(pod.Labels),
+				ports:    ports,
+			}
+			blocked := t.blockedPorts(policies.Items)
+			if len(blocked) == 0 {
+				continue
+			}
+
+			msg := fmt.Sprintf("Network policies likely block instance group '%s' of BOSHDeployment '%s/%s' from reaching link provider '%s' (pod '%s') on ports %s: %s",
+				consumer.InstanceGroup, instance.Namespace, instance.Name, providerName, pod.Name, strings.Join(blocked, ", "), t.isolation())
+			log.WarningEvent(ctx, instance, "LinkNetworkBlocked", msg)
+			break
+		}
+	}
+}
+
+// blockedPorts returns the link ports, which neither an ingress rule of the
+// policies isolating the provider nor an egress rule of the policies
+// isolating the consumer permits
+func (t *linkTraffic) blockedPorts(policies []networkingv1.NetworkPolicy) []string {
+	blocked := []string{}
+	for _, port := range t.ports {
+		ingressAllowed, ingressIsolated := true, false
+		egressAllowed, egressIsolated := true, false
+		for _, policy := range policies {
+			ingress, egress := policyTypes(policy)
+			if ingress && selects(&policy.Spec.PodSelector, t.provider) {
+				if !ingressIsolated {
+					ingressIsolated, ingressAllowed = true, false
+				}
+				t.ingressPolicies = appendUnique(t.ingressPolicies, policy.Name)
+				for _, rule := range policy.Spec.Ingress {
+					if t.permits(rule.From, t.consumer, rule.Ports, port) {
+						ingressAllowed = true
+					}
+				}
+			}
+			if egress && selects(&policy.Spec.PodSelector, t.consumer) {
+				if !egressIsolated {
+					egressIsolated, egressAllowed = true, false
+				}
+				t.egressPolicies = appendUnique(t.egressPolicies, policy.Name)
+				for _, rule := range policy.Spec.Egress {
+					if t.permits(rule.To, t.provider, rule.Ports, port) {
+						egressAllowed = true
+					}
+				}
+			}
+		}
+		if !ingressAllowed || !egressAllowed {
+			blocked = append(blocked, portString(port))
+		}
+	}
+	return blocked
+}
+
+// isolation describes the policies, which isolate the consumer and provider pods
+func (t *linkTraffic) isolation() string {
+	msgs := []string{}
+	if len(t.ingressPolicies) > 0 {
+		msgs = append(msgs, fmt.Sprintf("provider ingress is isolated by %s", strings.Join(t.ingressPolicies, ", ")))
+	}
+	if len(t.egressPolicies) > 0 {
+		msgs = append(msgs, fmt.Sprintf("consumer egress is isolated by %s", strings.Join(t.egressPolicies, ", ")))
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// permits returns true, if a rule with the peers and ports permits the
+// traffic to or from the pod with the peer labels on the service port
+func (t *linkTraffic) permits(peers []networkingv1.NetworkPolicyPeer, peer labels.Set, ports []networkingv1.NetworkPolicyPort, port corev1.ServicePort) bool {
+	return t.permitsPeer(peers, peer) && permitsPort(ports, port)
+}
+
+func (t *linkTraffic) permitsPeer(peers []networkingv1.NetworkPolicyPeer, peer labels.Set) bool {
+	if len(peers) == 0 {
+		return true
+	}
+	for _, p := range peers {
+		if p.IPBlock != nil {
+			return true
+		}
+		if p.NamespaceSelector != nil && t.namespace != nil && !selects(p.NamespaceSelector, t.namespace) {
+			continue
+		}
+		if p.PodSelector == nil || selects(p.PodSelector, peer) {
+			return true
+		}
+	}
+	return false
+}
+
+// permitsPort matches the rule ports against the target port of the
+// service port. Named ports can only be matched against named ports,
+// otherwise they are assumed to match.
+func permitsPort(ports []networkingv1.NetworkPolicyPort, port corev1.ServicePort) bool {
+	if len(ports) == 0 {
+		return true
+	}
+	protocol := port.Protocol
+	if protocol == "" {
+		protocol = corev1.ProtocolTCP
+	}
+	target := port.TargetPort
+	if target.Type == intstr.Int && target.IntVal == 0 {
+		target = intstr.FromInt(int(port.Port))
+	}
+
+	for _, p := range ports {
+		ruleProtocol := corev1.ProtocolTCP
+		if p.Protocol != nil {
+			ruleProtocol = *p.Protocol
+		}
+		if ruleProtocol != protocol {
+			continue
+		}
+		if p.Port == nil || p.Port.Type != target.Type {
+			return true
+		}
+		if p.Port.String() == target.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// policyTypes returns whether the policy isolates the ingress and the egress
+// of the pods it selects. Policies without types always isolate ingress and
+// isolate egress, if they have egress rules.
+func policyTypes(policy networkingv1.NetworkPolicy) (bool, bool) {
+	if len(policy.Spec.PolicyTypes) == 0 {
+		return true, len(policy.Spec.Egress) > 0
+	}
+	ingress, egress := false, false
+	for _, t := range policy.Spec.PolicyTypes {
+		switch t {
+		case networkingv1.PolicyTypeIngress:
+			ingress = true
+		case networkingv1.PolicyTypeEgress:
+			egress = true
+		}
+	}
+	return ingress, egress
+}
+
+func selects(selector *metav1.LabelSelector, set labels.Set) bool {
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return false
+	}
+	return s.Matches(set)
+}
+
+func portString(port corev1.ServicePort) string {
+	protocol := port.Protocol
+	if protocol == "" {
+		protocol = corev1.ProtocolTCP
+	}
+	return fmt.Sprintf("%d/%s", port.Port, protocol)
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, e := range list {
+		if e == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
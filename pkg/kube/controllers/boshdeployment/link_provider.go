@@ -3,12 +3,96 @@ package boshdeployment
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 
+	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
 	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/boshdns"
 )
 
+// EmptyPodIPPolicy decides how link resolution treats pods of a link
+// provider service, which don't have an IP yet
+type EmptyPodIPPolicy string
+
+const (
+	// EmptyPodIPError fails the reconcile
+	EmptyPodIPError EmptyPodIPPolicy = "error"
+	// EmptyPodIPSkip leaves the pod out of the link instances
+	EmptyPodIPSkip EmptyPodIPPolicy = "skip"
+	// EmptyPodIPWait requeues the reconcile until the pod has an IP
+	EmptyPodIPWait EmptyPodIPPolicy = "wait"
+
+	// podIPRequeueAfter is the delay before retrying a reconcile, which waits for pod IPs
+	podIPRequeueAfter = 5 * time.Second
+)
+
+var emptyPodIPPolicy = EmptyPodIPError
+
+// SetEmptyPodIPPolicy initializes the package scoped emptyPodIPPolicy variable.
+func SetEmptyPodIPPolicy(policy string) error {
+	switch p := EmptyPodIPPolicy(policy); p {
+	case EmptyPodIPError, EmptyPodIPSkip, EmptyPodIPWait:
+		emptyPodIPPolicy = p
+		return nil
+	}
+	return errors.Errorf("invalid empty pod IP policy '%s', must be one of: %s, %s, %s", policy, EmptyPodIPError, EmptyPodIPSkip, EmptyPodIPWait)
+}
+
+// podIPPendingError is returned by link resolution, when pods are waited on for their IP
+type podIPPendingError struct {
+	pods []string
+}
+
+func (e *podIPPendingError) Error() string {
+	return fmt.Sprintf("waiting for ip of kube native components: %s", strings.Join(e.pods, ", "))
+}
+
+func isPodIPPending(err error) bool {
+	_, ok := errors.Cause(err).(*podIPPendingError)
+	return ok
+}
+
+// linkPortConflictError is returned by link resolution, when jobs providing
+// the same link expose the same port
+type linkPortConflictError struct {
+	conflicts []bdm.LinkPortConflict
+}
+
+func (e *linkPortConflictError) Error() string {
+	msgs := make([]string, len(e.conflicts))
+	for i, c := range e.conflicts {
+		msgs[i] = c.String()
+	}
+	return fmt.Sprintf("conflicting link ports: %s", strings.Join(msgs, "; "))
+}
+
+func isLinkPortConflict(err error) bool {
+	_, ok := errors.Cause(err).(*linkPortConflictError)
+	return ok
+}
+
+// linkTypeMismatchError is returned by link resolution, when a job consumes
+// a link of another deployment, whose provider declares a different type
+type linkTypeMismatchError struct {
+	provider     string
+	providerType string
+	consumer     bdm.LinkConsumer
+}
+
+func (e *linkTypeMismatchError) Error() string {
+	return fmt.Sprintf("job '%s/%s' consumes link of type '%s' from provider '%s' of type '%s'",
+		e.consumer.InstanceGroup, e.consumer.Job, e.consumer.Type, e.provider, e.providerType)
+}
+
+func isLinkTypeMismatch(err error) bool {
+	_, ok := errors.Cause(err).(*linkTypeMismatchError)
+	return ok
+}
+
 func isLinkProviderService(svc *corev1.Service) bool {
 	if _, ok := svc.GetAnnotations()[bdv1.AnnotationLinkProviderService]; ok {
 		return true
@@ -30,3 +114,23 @@ func newLinkProvider(annotations map[string]string) (linkProvider, error) {
 	}
 	return *lp, fmt.Errorf("missing link secrets for providers")
 }
+
+// podDNSNames returns the DNS name of the link provider pod and of its
+// headless service. Pods without a subdomain get their IP based pod DNS name
+// and the DNS name of the link provider service.
+func podDNSNames(p corev1.Pod, serviceDNS string) (string, string) {
+	domain := boshdns.GetClusterDomain()
+	if p.Spec.Subdomain == "" {
+		if p.Status.PodIP == "" {
+			return "", serviceDNS
+		}
+		return fmt.Sprintf("%s.%s.pod.%s", strings.Replace(p.Status.PodIP, ".", "-", -1), p.Namespace, domain), serviceDNS
+	}
+
+	hostname := p.Spec.Hostname
+	if hostname == "" {
+		hostname = p.Name
+	}
+	headlessServiceDNS := fmt.Sprintf("%s.%s.svc.%s", p.Spec.Subdomain, p.Namespace, domain)
+	return fmt.Sprintf("%s.%s", hostname, headlessServiceDNS), headlessServiceDNS
+}
@@ -0,0 +1,31 @@
+package boshdeployment
+
+import (
+	"context"
+	"fmt"
+
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/loglevel"
+	log "code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+// withLogLevel returns a context, whose logger uses the level of the
+// deployment's log level annotation, so a single deployment can be debugged
+// without raising the operator's log level. Invalid levels are ignored with
+// a warning.
+func withLogLevel(ctx context.Context, instance *bdv1.BOSHDeployment) context.Context {
+	level := instance.LogLevel()
+	if level == "" {
+		return ctx
+	}
+
+	levelCtx, err := loglevel.NewContext(ctx, level)
+	if err != nil {
+		msg := fmt.Sprintf("Ignoring log level annotation of BOSHDeployment '%s/%s': %v", instance.Namespace, instance.Name, err)
+		log.ExtractLogger(ctx).Warn(msg)
+		log.WarningEvent(ctx, instance, "InvalidLogLevel", msg)
+		return ctx
+	}
+
+	return levelCtx
+}
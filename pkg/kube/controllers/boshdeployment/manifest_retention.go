@@ -0,0 +1,112 @@
+package boshdeployment
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	log "code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+	"code.cloudfoundry.org/quarks-utils/pkg/names"
+)
+
+// reconcileManifestRetention keeps the manifest retention finalizer in sync
+// with the deployment's retention policy. For deleted deployments it runs the
+// finalizer and returns true, they are not reconciled any further.
+func (r *ReconcileBOSHDeployment) reconcileManifestRetention(ctx context.Context, instance *bdv1.BOSHDeployment) (bool, error) {
+	finalizers := instance.GetFinalizers()
+	hasFinalizer := containsString(finalizers, bdv1.FinalizerManifestRetention)
+
+	if instance.DeletionTimestamp != nil {
+		if !hasFinalizer {
+			return true, nil
+		}
+
+		if err := r.retainManifestSecret(ctx, instance); err != nil {
+			return true, err
+		}
+
+		instance.SetFinalizers(removeString(finalizers, bdv1.FinalizerManifestRetention))
+		if err := r.client.Update(ctx, instance); err != nil {
+			return true, errors.Wrapf(err, "removing finalizer '%s'", bdv1.FinalizerManifestRetention)
+		}
+		return true, nil
+	}
+
+	retain := instance.Spec.ManifestRetentionPolicy == bdv1.ManifestRetentionRetain
+	switch {
+	case retain && !hasFinalizer:
+		instance.SetFinalizers(append(finalizers, bdv1.FinalizerManifestRetention))
+	case !retain && hasFinalizer:
+		instance.SetFinalizers(removeString(finalizers, bdv1.FinalizerManifestRetention))
+	default:
+		return false, nil
+	}
+
+	if err := r.client.Update(ctx, instance); err != nil {
+		return false, errors.Wrapf(err, "updating finalizer '%s'", bdv1.FinalizerManifestRetention)
+	}
+	return false, nil
+}
+
+// retainManifestSecret removes the deployment's owner reference from the
+// with-ops manifest secret, so it isn't garbage collected, and labels it as
+// retained
+func (r *ReconcileBOSHDeployment) retainManifestSecret(ctx context.Context, instance *bdv1.BOSHDeployment) error {
+	secretName := r.secretNamer.DeploymentSecretName(names.DeploymentSecretTypeManifestWithOps, instance.Name, "")
+	secret := &corev1.Secret{}
+	err := r.client.Get(ctx, crc.ObjectKey{Name: secretName, Namespace: instance.Namespace}, secret)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return errors.Wrapf(err, "getting Secret '%s'", secretName)
+	}
+
+	ownerReferences := []metav1.OwnerReference{}
+	for _, ref := range secret.GetOwnerReferences() {
+		if ref.UID != instance.UID {
+			ownerReferences = append(ownerReferences, ref)
+		}
+	}
+	secret.SetOwnerReferences(ownerReferences)
+
+	labels := secret.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[bdv1.LabelManifestRetained] = "true"
+	secret.SetLabels(labels)
+
+	err = r.client.Update(ctx, secret)
+	if err != nil {
+		return errors.Wrapf(err, "retaining Secret '%s'", secretName)
+	}
+
+	log.WithEvent(instance, "ManifestRetained").Infof(ctx, "Retained with-ops manifest secret '%s' of deleted BOSHDeployment '%s/%s'", secretName, instance.Namespace, instance.Name)
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+func removeString(list []string, s string) []string {
+	result := []string{}
+	for _, e := range list {
+		if e != s {
+			result = append(result, e)
+		}
+	}
+	return result
+}
@@ -0,0 +1,38 @@
+package boshdeployment
+
+import (
+	"context"
+	"fmt"
+
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	log "code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+// reportNoOpOps enables warnings about ops files, which don't change the manifest
+var reportNoOpOps = false
+
+// SetReportNoOpOps initializes the package scoped reportNoOpOps variable
+func SetReportNoOpOps(enabled bool) {
+	reportNoOpOps = enabled
+}
+
+// reportNoOpOpsFiles records a 'NoOpOpsFile' warning event for every ops file
+// of the deployment, which leaves the manifest unchanged. The check is
+// diagnostic only, so errors are logged and don't fail the reconcile.
+func (r *ReconcileBOSHDeployment) reportNoOpOpsFiles(ctx context.Context, instance *bdv1.BOSHDeployment) {
+	if !reportNoOpOps || len(instance.Spec.Ops) == 0 {
+		return
+	}
+
+	noOps, err := r.withops.NoOpOps(instance, instance.GetNamespace())
+	if err != nil {
+		log.Infof(ctx, "Skipping check for ops files without effect of BOSHDeployment '%s/%s': %v", instance.Namespace, instance.Name, err)
+		return
+	}
+
+	for _, name := range noOps {
+		msg := fmt.Sprintf("Ops file '%s' of BOSHDeployment '%s/%s' doesn't change the manifest", name, instance.Namespace, instance.Name)
+		log.Info(ctx, msg)
+		log.WarningEvent(ctx, instance, "NoOpOpsFile", msg)
+	}
+}
@@ -0,0 +1,41 @@
+package boshdeployment
+
+import (
+	"context"
+
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/cf-operator/version"
+	log "code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+// verifyManifestCompatibility returns false, if the deployment's min operator
+// version annotation requires a newer operator than the running one. Such
+// deployments, and those with an invalid annotation, are not reconciled until
+// the operator is upgraded or the annotation is changed. Operators without a
+// semantic version, e.g. development builds, reconcile all deployments.
+func (r *ReconcileBOSHDeployment) verifyManifestCompatibility(ctx context.Context, instance *bdv1.BOSHDeployment) bool {
+	minVersion := instance.MinOperatorVersion()
+	if minVersion == "" {
+		return true
+	}
+
+	required, err := utilversion.ParseGeneric(minVersion)
+	if err != nil {
+		log.WithEvent(instance, "InvalidMinOperatorVersion").Errorf(ctx, "Skip reconcile: invalid annotation '%s' of BOSHDeployment '%s/%s': %v", bdv1.AnnotationMinOperatorVersion, instance.Namespace, instance.Name, err)
+		return false
+	}
+
+	running, err := utilversion.ParseGeneric(version.Version)
+	if err != nil {
+		log.Debugf(ctx, "Skipping operator version check of BOSHDeployment '%s/%s', the operator version '%s' is invalid: %v", instance.Namespace, instance.Name, version.Version, err)
+		return true
+	}
+
+	if !running.AtLeast(required) {
+		log.WithEvent(instance, "OperatorVersionTooOld").Errorf(ctx, "Skip reconcile: BOSHDeployment '%s/%s' requires operator version %s, running %s", instance.Namespace, instance.Name, minVersion, version.Version)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,54 @@
+package boshdeployment
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	log "code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+// ownerObject is an owner, which events can be recorded on
+type ownerObject interface {
+	metav1.Object
+	runtime.Object
+}
+
+// withOwnerReference returns a MutateFn, which applies mutateFn and repairs
+// the controller reference of the owner on an existing object.
+// CreateOrUpdate reads the existing object before mutating it, so a
+// reference, which was removed after creation, e.g. by a manual edit, would
+// otherwise never be restored and the object would leak once the owner is
+// deleted. Objects controlled by another owner are left alone.
+func withOwnerReference(ctx context.Context, owner ownerObject, object metav1.Object, srf setReferenceFunc, scheme *runtime.Scheme, mutateFn controllerutil.MutateFn) controllerutil.MutateFn {
+	return func() error {
+		if err := mutateFn(); err != nil {
+			return err
+		}
+
+		if !ownerReferenceMissing(owner, object) {
+			return nil
+		}
+
+		if err := srf(owner, object, scheme); err != nil {
+			return log.WithEvent(owner, "OwnerReferenceError").Errorf(ctx, "failed to repair ownerReference of '%s': %v", object.GetName(), err)
+		}
+
+		if object.GetResourceVersion() != "" {
+			log.WithEvent(owner, "OwnerReferenceRepaired").Infof(ctx, "Repaired missing ownerReference of '%s' to '%s'", object.GetName(), owner.GetName())
+		}
+		return nil
+	}
+}
+
+// ownerReferenceMissing returns true, if the object has no controller
+// reference, or a stale one to a previous owner with the same name
+func ownerReferenceMissing(owner, object metav1.Object) bool {
+	ref := metav1.GetControllerOf(object)
+	if ref == nil {
+		return true
+	}
+	return ref.Name == owner.GetName() && ref.UID != owner.GetUID()
+}
@@ -0,0 +1,61 @@
+package boshdeployment
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/cf-operator/pkg/notification"
+)
+
+// phaseNotifier notifies external systems about phase transitions, nil disables the notifications
+var phaseNotifier notification.Notifier
+
+// SetPhaseNotification initializes the package scoped phaseNotifier variable.
+// An empty webhook URL disables the notifications.
+func SetPhaseNotification(webhookURL string, timeout time.Duration, retries int) error {
+	if webhookURL == "" {
+		phaseNotifier = nil
+		return nil
+	}
+
+	u, err := url.Parse(webhookURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.Errorf("invalid phase notification URL '%s', must be an http or https URL", webhookURL)
+	}
+	if timeout <= 0 {
+		return errors.Errorf("invalid phase notification timeout '%s', must be positive", timeout)
+	}
+	if retries < 0 {
+		return errors.Errorf("invalid number of phase notification retries '%d', must not be negative", retries)
+	}
+
+	phaseNotifier = notification.NewWebhookNotifier(webhookURL, timeout, retries)
+	return nil
+}
+
+// notifyPhaseTransition notifies about the transition of the BOSHDeployment
+// from the previous phase to its current one. The reason is the error, which
+// caused the transition, if any.
+func notifyPhaseTransition(ctx context.Context, instance *bdv1.BOSHDeployment, previous bdv1.Phase) {
+	if phaseNotifier == nil || instance.Status.Phase == previous {
+		return
+	}
+
+	reason := instance.Status.LastError
+	if reason == "" {
+		reason = instance.Status.StagingError
+	}
+
+	phaseNotifier.Notify(ctx, notification.PhaseTransition{
+		Deployment:    instance.Name,
+		Namespace:     instance.Namespace,
+		Phase:         instance.Status.Phase,
+		PreviousPhase: previous,
+		Reason:        reason,
+		Time:          time.Now().UTC(),
+	})
+}
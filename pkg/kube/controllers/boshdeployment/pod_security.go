@@ -0,0 +1,37 @@
+package boshdeployment
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/podsecurity"
+	qjv1a1 "code.cloudfoundry.org/quarks-job/pkg/kube/apis/quarksjob/v1alpha1"
+)
+
+// podSecurityViolations checks the pod templates of the QuarksJobs against the
+// Pod Security Standard level enforced on the namespace
+func podSecurityViolations(ctx context.Context, client crc.Client, namespace string, qJobs ...*qjv1a1.QuarksJob) ([]string, error) {
+	// Namespaces are not cached, so they are read as unstructured objects
+	ns := &unstructured.Unstructured{}
+	ns.SetGroupVersionKind(schema.GroupVersionKind{Version: "v1", Kind: "Namespace"})
+	err := client.Get(ctx, crc.ObjectKey{Name: namespace}, ns)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting namespace '%s'", namespace)
+	}
+
+	level := ns.GetLabels()[podsecurity.LabelEnforce]
+	violations := []string{}
+	for _, qJob := range qJobs {
+		for _, v := range podsecurity.Check(level, qJob.Spec.Template.Spec.Template.Spec) {
+			violations = append(violations, fmt.Sprintf("QuarksJob '%s': %s", qJob.Name, v))
+		}
+	}
+
+	return violations, nil
+}
@@ -0,0 +1,64 @@
+package boshdeployment
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/backpressure"
+	"code.cloudfoundry.org/quarks-utils/pkg/config"
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+	"code.cloudfoundry.org/quarks-utils/pkg/names"
+	vss "code.cloudfoundry.org/quarks-utils/pkg/versionedsecretstore"
+)
+
+// AddProvenance creates a new controller, which annotates the versioned
+// secrets created by QuarksJobs with the correlation ID and the BOSHDeployment
+// generation of the reconcile, which produced them.
+func AddProvenance(ctx context.Context, config *config.Config, mgr manager.Manager) error {
+	ctx = ctxlog.NewContextWithRecorder(ctx, "provenance-reconciler", mgr.GetEventRecorderFor("provenance-recorder"))
+	r := NewProvenanceReconciler(ctx, config, mgr)
+
+	// Create a new controller
+	c, err := controller.New("provenance-controller", mgr, controller.Options{
+		Reconciler:              backpressure.NewReconciler(ctx, r),
+		MaxConcurrentReconciles: config.MaxBoshDeploymentWorkers,
+	})
+	if err != nil {
+		return errors.Wrap(err, "Adding provenance controller to manager failed.")
+	}
+
+	// Watch for new versioned secrets owned by QuarksJobs
+	p := predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			o := e.Object.(*corev1.Secret)
+			if !vss.IsVersionedSecret(*o) || quarksJobOwner(o) == nil {
+				return false
+			}
+
+			ctxlog.NewPredicateEvent(o).Debug(
+				ctx, e.Meta, names.Secret,
+				fmt.Sprintf("Create predicate passed for versioned secret '%s'", e.Meta.GetName()),
+			)
+			return true
+		},
+		DeleteFunc:  func(e event.DeleteEvent) bool { return false },
+		GenericFunc: func(e event.GenericEvent) bool { return false },
+		UpdateFunc:  func(e event.UpdateEvent) bool { return false },
+	}
+
+	err = c.Watch(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestForObject{}, p)
+	if err != nil {
+		return errors.Wrapf(err, "Watching secrets failed in provenance controller.")
+	}
+
+	return nil
+}
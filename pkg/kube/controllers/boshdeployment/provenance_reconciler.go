@@ -0,0 +1,127 @@
+package boshdeployment
+
+import (
+	"context"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/audit"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/correlation"
+	qjv1a1 "code.cloudfoundry.org/quarks-job/pkg/kube/apis/quarksjob/v1alpha1"
+	"code.cloudfoundry.org/quarks-utils/pkg/config"
+	log "code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+// provenanceAnnotations are copied from QuarksJobs onto their versioned output secrets
+var provenanceAnnotations = []string{correlation.AnnotationCorrelationID, bdv1.AnnotationDeploymentGeneration}
+
+// annotateProvenance records the correlation ID of the reconcile pass and the
+// generation of the BOSHDeployment on the object
+func annotateProvenance(ctx context.Context, instance *bdv1.BOSHDeployment, object *metav1.ObjectMeta) {
+	if object.Annotations == nil {
+		object.Annotations = map[string]string{}
+	}
+	if id := correlation.ID(ctx); id != "" {
+		object.Annotations[correlation.AnnotationCorrelationID] = id
+	}
+	object.Annotations[bdv1.AnnotationDeploymentGeneration] = strconv.FormatInt(instance.Generation, 10)
+}
+
+// NewProvenanceReconciler returns a new reconcile.Reconciler
+func NewProvenanceReconciler(ctx context.Context, config *config.Config, mgr manager.Manager) reconcile.Reconciler {
+	return &ReconcileProvenance{
+		ctx:    ctx,
+		config: config,
+		client: audit.NewClient(mgr.GetClient()),
+	}
+}
+
+// ReconcileProvenance reconciles the provenance annotations of versioned secrets
+type ReconcileProvenance struct {
+	ctx    context.Context
+	config *config.Config
+	client client.Client
+}
+
+// Reconcile copies the correlation ID and deployment generation from the
+// QuarksJob, which created a versioned secret, onto the secret
+func (r *ReconcileProvenance) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	ctx, cancel := context.WithTimeout(r.ctx, r.config.CtxTimeOut)
+	defer cancel()
+
+	log.Debugf(ctx, "Reconciling provenance of versioned secret '%s'", request.NamespacedName)
+	secret := &corev1.Secret{}
+	err := r.client.Get(ctx, request.NamespacedName, secret)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			log.Debug(ctx, "Skip reconcile: versioned secret not found")
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, log.WithEvent(secret, "GetVersionedSecretError").Errorf(ctx, "failed to get versioned secret '%s': %v", request.NamespacedName, err)
+	}
+
+	if deployment, ok := secret.GetLabels()[bdv1.LabelDeploymentName]; ok {
+		ctx = audit.NewContext(ctx, types.NamespacedName{Namespace: secret.Namespace, Name: deployment})
+	}
+
+	owner := quarksJobOwner(secret)
+	if owner == nil {
+		log.Debugf(ctx, "Skip reconcile: versioned secret '%s' is not owned by a QuarksJob", request.NamespacedName)
+		return reconcile.Result{}, nil
+	}
+
+	qJob := &qjv1a1.QuarksJob{}
+	err = r.client.Get(ctx, types.NamespacedName{Namespace: secret.Namespace, Name: owner.Name}, qJob)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			log.Debugf(ctx, "Skip reconcile: QuarksJob '%s' of versioned secret '%s' not found", owner.Name, request.NamespacedName)
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, log.WithEvent(secret, "GetQuarksJobError").Errorf(ctx, "failed to get QuarksJob '%s' of versioned secret '%s': %v", owner.Name, request.NamespacedName, err)
+	}
+
+	changed := false
+	for _, key := range provenanceAnnotations {
+		value, ok := qJob.GetAnnotations()[key]
+		if !ok {
+			continue
+		}
+		// Keep annotations, which were set when the secret was created
+		if _, ok := secret.GetAnnotations()[key]; ok {
+			continue
+		}
+		if secret.Annotations == nil {
+			secret.Annotations = map[string]string{}
+		}
+		secret.Annotations[key] = value
+		changed = true
+	}
+	if !changed {
+		return reconcile.Result{}, nil
+	}
+
+	err = r.client.Update(ctx, secret)
+	if err != nil {
+		return reconcile.Result{}, log.WithEvent(secret, "UpdateVersionedSecretError").Errorf(ctx, "failed to annotate versioned secret '%s': %v", request.NamespacedName, err)
+	}
+
+	log.Debugf(ctx, "Annotated versioned secret '%s' with the provenance of QuarksJob '%s'", request.NamespacedName, qJob.Name)
+	return reconcile.Result{}, nil
+}
+
+// quarksJobOwner returns the QuarksJob controller reference of the secret, or nil
+func quarksJobOwner(secret *corev1.Secret) *metav1.OwnerReference {
+	owner := metav1.GetControllerOf(secret)
+	if owner == nil || owner.Kind != "QuarksJob" {
+		return nil
+	}
+	return owner
+}
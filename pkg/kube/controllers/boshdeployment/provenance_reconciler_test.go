@@ -0,0 +1,130 @@
+package boshdeployment_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	cfd "code.cloudfoundry.org/cf-operator/pkg/kube/controllers/boshdeployment"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/controllers/fakes"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/correlation"
+	qjv1a1 "code.cloudfoundry.org/quarks-job/pkg/kube/apis/quarksjob/v1alpha1"
+	cfcfg "code.cloudfoundry.org/quarks-utils/pkg/config"
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+	"code.cloudfoundry.org/quarks-utils/pkg/pointers"
+	"code.cloudfoundry.org/quarks-utils/pkg/versionedsecretstore"
+	helper "code.cloudfoundry.org/quarks-utils/testing/testhelper"
+)
+
+var _ = Describe("ReconcileProvenance", func() {
+	var (
+		manager    *fakes.FakeManager
+		client     *fakes.FakeClient
+		reconciler reconcile.Reconciler
+		request    reconcile.Request
+		secret     *corev1.Secret
+		qJob       *qjv1a1.QuarksJob
+	)
+
+	BeforeEach(func() {
+		manager = &fakes.FakeManager{}
+		request = reconcile.Request{NamespacedName: types.NamespacedName{Name: "desired-manifest-v1", Namespace: "default"}}
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "desired-manifest-v1",
+				Namespace: "default",
+				Labels: map[string]string{
+					versionedsecretstore.LabelSecretKind: versionedsecretstore.VersionSecretKind,
+				},
+				Annotations: map[string]string{
+					versionedsecretstore.AnnotationSourceDescription: "created by quarksJob",
+				},
+				OwnerReferences: []metav1.OwnerReference{
+					{Kind: "QuarksJob", Name: "dm-foo", Controller: pointers.Bool(true)},
+				},
+			},
+		}
+		qJob = &qjv1a1.QuarksJob{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "dm-foo",
+				Namespace: "default",
+				Annotations: map[string]string{
+					correlation.AnnotationCorrelationID: "0a1b2c3d",
+					bdv1.AnnotationDeploymentGeneration: "3",
+				},
+			},
+		}
+
+		client = &fakes.FakeClient{}
+		client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+			switch object := object.(type) {
+			case *corev1.Secret:
+				secret.DeepCopyInto(object)
+			case *qjv1a1.QuarksJob:
+				if qJob == nil {
+					return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+				}
+				qJob.DeepCopyInto(object)
+			}
+			return nil
+		})
+		manager.GetClientReturns(client)
+	})
+
+	JustBeforeEach(func() {
+		_, log := helper.NewTestLogger()
+		ctx := ctxlog.NewParentContext(log)
+		ctx = ctxlog.NewContextWithRecorder(ctx, "TestRecorder", record.NewFakeRecorder(20))
+		config := &cfcfg.Config{CtxTimeOut: 10 * time.Second}
+		reconciler = cfd.NewProvenanceReconciler(ctx, config, manager)
+	})
+
+	It("copies the provenance annotations of the QuarksJob onto the secret", func() {
+		_, err := reconciler.Reconcile(request)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(client.UpdateCallCount()).To(Equal(1))
+		_, object, _ := client.UpdateArgsForCall(0)
+		annotations := object.(*corev1.Secret).Annotations
+		Expect(annotations).To(HaveKeyWithValue(correlation.AnnotationCorrelationID, "0a1b2c3d"))
+		Expect(annotations).To(HaveKeyWithValue(bdv1.AnnotationDeploymentGeneration, "3"))
+		Expect(annotations).To(HaveKeyWithValue(versionedsecretstore.AnnotationSourceDescription, "created by quarksJob"))
+	})
+
+	It("keeps existing provenance annotations of the secret", func() {
+		secret.Annotations[correlation.AnnotationCorrelationID] = "ffffffff"
+		secret.Annotations[bdv1.AnnotationDeploymentGeneration] = "2"
+
+		_, err := reconciler.Reconcile(request)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(client.UpdateCallCount()).To(Equal(0))
+	})
+
+	It("skips secrets, which are not owned by a QuarksJob", func() {
+		secret.OwnerReferences = nil
+
+		_, err := reconciler.Reconcile(request)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(client.UpdateCallCount()).To(Equal(0))
+	})
+
+	It("skips secrets, whose QuarksJob was deleted", func() {
+		qJob = nil
+
+		_, err := reconciler.Reconcile(request)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(client.UpdateCallCount()).To(Equal(0))
+	})
+})
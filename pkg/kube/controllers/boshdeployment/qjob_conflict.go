@@ -0,0 +1,84 @@
+package boshdeployment
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	log "code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+var (
+	// qJobConflictRequeueAfter is the delay before a reconcile is retried,
+	// whose QuarksJob update conflicted with a concurrent change
+	qJobConflictRequeueAfter = time.Second
+	// qJobConflictAttempts is the number of consecutive conflicts, which
+	// are retried fast, before they are reported as errors
+	qJobConflictAttempts = 5
+)
+
+// SetQJobConflictRequeue initializes the package scoped variables, which
+// control how QuarksJob update conflicts are retried
+func SetQJobConflictRequeue(requeueAfter time.Duration, attempts int) error {
+	if requeueAfter <= 0 {
+		return errors.Errorf("invalid QuarksJob conflict requeue delay '%s', must be positive", requeueAfter)
+	}
+	if attempts < 1 {
+		return errors.Errorf("invalid number of QuarksJob conflict attempts '%d', must be at least 1", attempts)
+	}
+
+	qJobConflictRequeueAfter = requeueAfter
+	qJobConflictAttempts = attempts
+	return nil
+}
+
+// qJobConflicts counts the consecutive QuarksJob update conflicts of each
+// BOSHDeployment
+type qJobConflicts struct {
+	sync.Mutex
+	counts map[types.NamespacedName]int
+}
+
+func newQJobConflicts() *qJobConflicts {
+	return &qJobConflicts{counts: map[types.NamespacedName]int{}}
+}
+
+func (c *qJobConflicts) add(nn types.NamespacedName) int {
+	c.Lock()
+	defer c.Unlock()
+	c.counts[nn]++
+	return c.counts[nn]
+}
+
+func (c *qJobConflicts) reset(nn types.NamespacedName) {
+	c.Lock()
+	defer c.Unlock()
+	delete(c.counts, nn)
+}
+
+// requeueOnQJobConflict returns true, if err is a conflict of a QuarksJob
+// update, which should be retried fast. Conflicts with a concurrent change
+// are transient, so they don't go through the backoff and failure policy of
+// errors, until qJobConflictAttempts consecutive conflicts occurred.
+func (r *ReconcileBOSHDeployment) requeueOnQJobConflict(ctx context.Context, instance *bdv1.BOSHDeployment, err error) (reconcile.Result, bool) {
+	if !apierrors.IsConflict(errors.Cause(err)) {
+		return reconcile.Result{}, false
+	}
+
+	nn := types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}
+	attempt := r.qJobConflicts.add(nn)
+	if attempt >= qJobConflictAttempts {
+		r.qJobConflicts.reset(nn)
+		return reconcile.Result{}, false
+	}
+
+	log.Infof(ctx, "Requeue reconcile of BOSHDeployment '%s' after %s, conflict %d of %d: %v", nn, qJobConflictRequeueAfter, attempt, qJobConflictAttempts, err)
+	return reconcile.Result{RequeueAfter: qJobConflictRequeueAfter}, true
+}
@@ -0,0 +1,347 @@
+package boshdeployment
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"code.cloudfoundry.org/cf-operator/pkg/bosh/converter"
+	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+	"code.cloudfoundry.org/cf-operator/pkg/bosh/qjobs"
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/boshdns"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/withops"
+	"code.cloudfoundry.org/quarks-utils/pkg/config"
+)
+
+// PlanOperation is the write operation of a planned change
+type PlanOperation string
+
+// Valid values for plan operations
+const (
+	PlanOperationCreate PlanOperation = "create"
+	PlanOperationUpdate PlanOperation = "update"
+	PlanOperationDelete PlanOperation = "delete"
+)
+
+// PlannedChange is an object, which the reconcile of a BOSHDeployment would write
+type PlannedChange struct {
+	Kind      string        `json:"kind"`
+	Namespace string        `json:"namespace"`
+	Name      string        `json:"name"`
+	Operation PlanOperation `json:"operation"`
+}
+
+// ReconcilePlan lists the changes, which the reconcile of a proposed
+// BOSHDeployment would apply to the cluster
+type ReconcilePlan struct {
+	Deployment string `json:"deployment"`
+	// Phase is the phase of the BOSHDeployment after the reconcile, e.g.
+	// WaitingForSecret, if the plan is incomplete since a secret is missing
+	Phase bdv1.Phase `json:"phase"`
+	// Changes are listed in the order, in which the reconciler applies them
+	Changes []PlannedChange `json:"changes"`
+}
+
+// ReconcilePlanner computes reconcile plans by running the reconcile of the
+// BOSHDeployment controller against a client, which reads from the cluster,
+// but only records the writes
+type ReconcilePlanner struct {
+	client    crc.Client
+	scheme    *runtime.Scheme
+	podLogs   PodLogs
+	namespace string
+}
+
+// NewReconcilePlanner returns a planner for the deployments of a namespace
+func NewReconcilePlanner(client crc.Client, scheme *runtime.Scheme, podLogs PodLogs, namespace string) *ReconcilePlanner {
+	return &ReconcilePlanner{
+		client:    client,
+		scheme:    scheme,
+		podLogs:   podLogs,
+		namespace: namespace,
+	}
+}
+
+// Plan returns the objects, which the BOSHDeployment reconciler would create,
+// update or delete, if the proposed BOSHDeployment was applied. Nothing is
+// written to the cluster. The plan is computed as if the reconcile ran now,
+// so meltdown is ignored. Objects created later by the QuarksJobs and the
+// controllers watching their output are not part of the plan.
+func (p *ReconcilePlanner) Plan(ctx context.Context, proposed *bdv1.BOSHDeployment) (*ReconcilePlan, error) {
+	instance, err := p.proposedInstance(ctx, proposed)
+	if err != nil {
+		return nil, err
+	}
+
+	client := newPlanClient(p.client, p.scheme)
+	r := &ReconcileBOSHDeployment{
+		ctx:    ctx,
+		config: &config.Config{Namespace: p.namespace},
+		client: client,
+		scheme: p.scheme,
+		withops: withops.NewResolver(
+			client,
+			func() withops.Interpolator { return withops.NewInterpolator() },
+			func(deploymentName string, m bdm.Manifest) (withops.DomainNameService, error) {
+				return boshdns.NewDNS(deploymentName, m)
+			},
+			secretNamer,
+		),
+		setReference: controllerutil.SetControllerReference,
+		jobFactory:   qjobs.NewJobFactory(p.namespace, secretNamer),
+		converter:    converter.NewVariablesConverter(p.namespace, secretNamer),
+		podLogs:      p.podLogs,
+		staging:      planStagingValidator{},
+		secretNamer:  secretNamer,
+
+		newConfigServerClient:    NewConfigServerClient,
+		newExternalLinkPublisher: newPlanLinkPublisher,
+		configServerChecksums:    newConfigServerChecksums(),
+		qJobConflicts:            newQJobConflicts(),
+		ignoredFailures:          newIgnoredFailures(),
+		watchedSecretsIndex:      newWatchedSecretsIndex(),
+	}
+
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: instance.Namespace, Name: instance.Name}}
+	_, err = r.reconcileDeployment(ctx, request, instance)
+	if err != nil {
+		return nil, errors.Wrapf(err, "planning reconcile of BOSHDeployment '%s'", request.NamespacedName)
+	}
+
+	return &ReconcilePlan{
+		Deployment: instance.Name,
+		Phase:      instance.Status.Phase,
+		Changes:    client.changes,
+	}, nil
+}
+
+// proposedInstance returns the proposed BOSHDeployment with the metadata and
+// status of the live one, so owner references and the initial rollout are
+// detected like in the reconcile of the applied change
+func (p *ReconcilePlanner) proposedInstance(ctx context.Context, proposed *bdv1.BOSHDeployment) (*bdv1.BOSHDeployment, error) {
+	instance := proposed.DeepCopy()
+	instance.Namespace = p.namespace
+
+	live := &bdv1.BOSHDeployment{}
+	err := p.client.Get(ctx, crc.ObjectKey{Name: instance.Name, Namespace: instance.Namespace}, live)
+	if apierrors.IsNotFound(err) {
+		instance.Generation = 1
+		instance.Status = bdv1.BOSHDeploymentStatus{}
+		return instance, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting BOSHDeployment '%s/%s'", instance.Namespace, instance.Name)
+	}
+
+	instance.UID = live.UID
+	instance.ResourceVersion = live.ResourceVersion
+	instance.CreationTimestamp = live.CreationTimestamp
+	instance.Generation = live.Generation
+	if !reflect.DeepEqual(live.Spec, instance.Spec) {
+		instance.Generation++
+	}
+	instance.Status = *live.Status.DeepCopy()
+	instance.Status.LastReconcile = nil
+
+	return instance, nil
+}
+
+// planStagingValidator accepts all objects, the staging cluster isn't
+// involved in plans
+type planStagingValidator struct{}
+
+// DryRun does nothing
+func (planStagingValidator) DryRun(ctx context.Context, objects []runtime.Object) error {
+	return nil
+}
+
+// planLinkPublisher drops the published links, plans don't write to external endpoints
+type planLinkPublisher struct{}
+
+func newPlanLinkPublisher(context.Context, crc.Client, string) (ExternalLinkPublisher, error) {
+	return planLinkPublisher{}, nil
+}
+
+// Publish does nothing
+func (planLinkPublisher) Publish(ctx context.Context, providerName string, linkType string, instances []bdm.JobInstance, properties map[string]interface{}) error {
+	return nil
+}
+
+// planClient reads from the cluster and records the writes as planned
+// changes instead of applying them. Status writes are dropped.
+type planClient struct {
+	crc.Client
+	scheme  *runtime.Scheme
+	changes []PlannedChange
+}
+
+func newPlanClient(c crc.Client, scheme *runtime.Scheme) *planClient {
+	return &planClient{Client: c, scheme: scheme, changes: []PlannedChange{}}
+}
+
+// Create records the creation of obj, it fails like the API server if obj exists
+func (c *planClient) Create(ctx context.Context, obj runtime.Object, opts ...crc.CreateOption) error {
+	change, err := c.change(obj, PlanOperationCreate)
+	if err != nil {
+		return err
+	}
+
+	existing := obj.DeepCopyObject()
+	err = c.Client.Get(ctx, crc.ObjectKey{Name: change.Name, Namespace: change.Namespace}, existing)
+	if err == nil {
+		return apierrors.NewAlreadyExists(schema.GroupResource{Resource: change.Kind}, change.Name)
+	}
+	if !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	c.record(change)
+	return nil
+}
+
+// Update records the update of obj, unless it doesn't change the live object
+func (c *planClient) Update(ctx context.Context, obj runtime.Object, opts ...crc.UpdateOption) error {
+	change, err := c.change(obj, PlanOperationUpdate)
+	if err != nil {
+		return err
+	}
+
+	existing := obj.DeepCopyObject()
+	err = c.Client.Get(ctx, crc.ObjectKey{Name: change.Name, Namespace: change.Namespace}, existing)
+	if err == nil && equality.Semantic.DeepEqual(existing, obj) {
+		return nil
+	}
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	c.record(change)
+	return nil
+}
+
+// Patch records the patch of obj as update
+func (c *planClient) Patch(ctx context.Context, obj runtime.Object, patch crc.Patch, opts ...crc.PatchOption) error {
+	change, err := c.change(obj, PlanOperationUpdate)
+	if err != nil {
+		return err
+	}
+
+	c.record(change)
+	return nil
+}
+
+// Delete records the deletion of obj
+func (c *planClient) Delete(ctx context.Context, obj runtime.Object, opts ...crc.DeleteOption) error {
+	change, err := c.change(obj, PlanOperationDelete)
+	if err != nil {
+		return err
+	}
+
+	c.record(change)
+	return nil
+}
+
+// DeleteAllOf records the deletion of the live objects matching the options
+func (c *planClient) DeleteAllOf(ctx context.Context, obj runtime.Object, opts ...crc.DeleteAllOfOption) error {
+	deleteOpts := &crc.DeleteAllOfOptions{}
+	deleteOpts.ApplyOptions(opts)
+
+	gvk, err := apiutil.GVKForObject(obj, c.scheme)
+	if err != nil {
+		return errors.Wrap(err, "looking up object kind")
+	}
+	gvk.Kind += "List"
+	list, err := c.scheme.New(gvk)
+	if err != nil {
+		return errors.Wrapf(err, "creating list of kind '%s'", gvk.Kind)
+	}
+
+	err = c.Client.List(ctx, list, &deleteOpts.ListOptions)
+	if err != nil {
+		return err
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return errors.Wrap(err, "extracting list items")
+	}
+
+	for _, item := range items {
+		change, err := c.change(item, PlanOperationDelete)
+		if err != nil {
+			return err
+		}
+		c.record(change)
+	}
+	return nil
+}
+
+// Status returns a status writer, which drops all status writes
+func (c *planClient) Status() crc.StatusWriter {
+	return planStatusWriter{}
+}
+
+// change returns the planned change of writing obj
+func (c *planClient) change(obj runtime.Object, operation PlanOperation) (PlannedChange, error) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return PlannedChange{}, errors.Wrap(err, "accessing object metadata")
+	}
+	gvk, err := apiutil.GVKForObject(obj, c.scheme)
+	if err != nil {
+		return PlannedChange{}, errors.Wrap(err, "looking up object kind")
+	}
+
+	return PlannedChange{
+		Kind:      gvk.Kind,
+		Namespace: accessor.GetNamespace(),
+		Name:      accessor.GetName(),
+		Operation: operation,
+	}, nil
+}
+
+// record adds the change to the plan. Each object is listed once: a planned
+// creation isn't turned into an update and recreating a deleted object updates it.
+func (c *planClient) record(change PlannedChange) {
+	for i, planned := range c.changes {
+		if planned.Kind != change.Kind || planned.Namespace != change.Namespace || planned.Name != change.Name {
+			continue
+		}
+
+		switch {
+		case planned.Operation == PlanOperationCreate && change.Operation == PlanOperationDelete:
+			c.changes = append(c.changes[:i], c.changes[i+1:]...)
+		case planned.Operation == PlanOperationDelete && change.Operation == PlanOperationCreate:
+			c.changes[i].Operation = PlanOperationUpdate
+		case planned.Operation != PlanOperationCreate:
+			c.changes[i].Operation = change.Operation
+		}
+		return
+	}
+
+	c.changes = append(c.changes, change)
+}
+
+type planStatusWriter struct{}
+
+// Update does nothing
+func (planStatusWriter) Update(ctx context.Context, obj runtime.Object, opts ...crc.UpdateOption) error {
+	return nil
+}
+
+// Patch does nothing
+func (planStatusWriter) Patch(ctx context.Context, obj runtime.Object, patch crc.Patch, opts ...crc.PatchOption) error {
+	return nil
+}
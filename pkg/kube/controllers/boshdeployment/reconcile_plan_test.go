@@ -0,0 +1,138 @@
+package boshdeployment_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/controllers"
+	cfd "code.cloudfoundry.org/cf-operator/pkg/kube/controllers/boshdeployment"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/controllers/fakes"
+)
+
+var _ = Describe("ReconcilePlanner", func() {
+	var (
+		client     *fakes.FakeClient
+		proposed   *bdv1.BOSHDeployment
+		live       *bdv1.BOSHDeployment
+		configMaps map[string]corev1.ConfigMap
+		secrets    map[string]corev1.Secret
+	)
+
+	BeforeEach(func() {
+		controllers.AddToScheme(scheme.Scheme)
+
+		proposed = &bdv1.BOSHDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+			Spec: bdv1.BOSHDeploymentSpec{
+				Manifest: bdv1.ResourceReference{Name: "manifest", Type: bdv1.ConfigMapReference},
+			},
+		}
+		live = nil
+		configMaps = map[string]corev1.ConfigMap{
+			"manifest": {Data: map[string]string{bdv1.ManifestSpecName: `---
+name: foo
+instance_groups:
+- name: api
+  instances: 1
+variables:
+- name: adminpass
+  type: password
+`}},
+		}
+		secrets = map[string]corev1.Secret{}
+
+		client = &fakes.FakeClient{}
+		client.GetCalls(func(_ context.Context, nn types.NamespacedName, object runtime.Object) error {
+			switch object := object.(type) {
+			case *unstructured.Unstructured:
+				object.SetName(nn.Name)
+			case *bdv1.BOSHDeployment:
+				if live == nil {
+					return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+				}
+				live.DeepCopyInto(object)
+			case *corev1.ConfigMap:
+				configMap, ok := configMaps[nn.Name]
+				if !ok {
+					return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+				}
+				configMap.DeepCopyInto(object)
+			case *corev1.Secret:
+				secret, ok := secrets[nn.Name]
+				if !ok {
+					return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+				}
+				secret.DeepCopyInto(object)
+			default:
+				return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+			}
+			return nil
+		})
+	})
+
+	plan := func() (*cfd.ReconcilePlan, error) {
+		return cfd.NewReconcilePlanner(client, scheme.Scheme, &fakes.FakePodLogs{}, "default").Plan(context.Background(), proposed)
+	}
+
+	It("lists the objects of a new deployment without writing them", func() {
+		p, err := plan()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(p.Deployment).To(Equal("foo"))
+		Expect(p.Phase).To(Equal(bdv1.PhaseGeneratingVariables))
+		Expect(p.Changes).To(Equal([]cfd.PlannedChange{
+			{Kind: "Secret", Namespace: "default", Name: "foo.with-ops", Operation: cfd.PlanOperationCreate},
+			{Kind: "QuarksSecret", Namespace: "default", Name: "foo.var-adminpass", Operation: cfd.PlanOperationCreate},
+			{Kind: "ConfigMap", Namespace: "default", Name: "foo-variables", Operation: cfd.PlanOperationCreate},
+			{Kind: "QuarksJob", Namespace: "default", Name: "dm-foo", Operation: cfd.PlanOperationCreate},
+			{Kind: "QuarksJob", Namespace: "default", Name: "ig-foo", Operation: cfd.PlanOperationCreate},
+		}))
+
+		Expect(client.CreateCallCount()).To(Equal(0))
+		Expect(client.UpdateCallCount()).To(Equal(0))
+		Expect(client.DeleteCallCount()).To(Equal(0))
+		Expect(client.StatusCallCount()).To(Equal(0))
+	})
+
+	It("lists changed objects of an existing deployment as updates", func() {
+		live = proposed.DeepCopy()
+		live.Namespace = "default"
+		live.UID = "uid"
+		live.Generation = 2
+		secrets["foo.with-ops"] = corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo.with-ops", Namespace: "default", ResourceVersion: "1"},
+			Data:       map[string][]byte{"manifest.yaml": []byte("name: foo")},
+		}
+
+		p, err := plan()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(p.Changes).To(ContainElement(cfd.PlannedChange{Kind: "Secret", Namespace: "default", Name: "foo.with-ops", Operation: cfd.PlanOperationUpdate}))
+	})
+
+	It("reports a deployment waiting for a missing secret", func() {
+		proposed.Spec.Manifest = bdv1.ResourceReference{Name: "manifest", Type: bdv1.SecretReference}
+
+		p, err := plan()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(p.Phase).To(Equal(bdv1.PhaseWaitingForSecret))
+		Expect(p.Changes).To(BeEmpty())
+	})
+
+	It("fails if the manifest is invalid", func() {
+		configMaps["manifest"] = corev1.ConfigMap{Data: map[string]string{bdv1.ManifestSpecName: "instance_groups: {"}}
+
+		_, err := plan()
+		Expect(err).To(MatchError(ContainSubstring("planning reconcile of BOSHDeployment 'default/foo'")))
+	})
+})
@@ -0,0 +1,87 @@
+package boshdeployment
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+)
+
+// reconcilePreviewTimeout limits the reconcile preview of the validating
+// webhook, zero disables the preview
+var reconcilePreviewTimeout time.Duration
+
+// SetReconcilePreviewTimeout initializes the package scoped timeout of the
+// reconcile preview, which the validating webhook runs before admitting a
+// BOSHDeployment. Zero disables the preview.
+func SetReconcilePreviewTimeout(timeout time.Duration) error {
+	if timeout < 0 {
+		return errors.Errorf("invalid reconcile preview timeout '%s', must not be negative", timeout)
+	}
+
+	reconcilePreviewTimeout = timeout
+	return nil
+}
+
+// reconcilePreview runs the steps of the BOSHDeployment reconcile, which
+// don't change the cluster, on the manifest with ops, which the validating
+// webhook resolved: like the reconciler it applies the feature flags and the
+// cloud config, reads the manifest of the variable interpolation trigger
+// secret, converts and orders the variables and builds the QuarksJobs. Link
+// infos are not listed, since they don't affect whether the QuarksJobs can be
+// built. The validating webhook bounds the resolution of the manifest and the
+// preview with the reconcile preview timeout.
+type reconcilePreview struct {
+	client     client.Client
+	withops    WithOps
+	jobFactory JobFactory
+	converter  VariablesConverter
+}
+
+// Run returns the error of the first failing step. Missing secrets are
+// returned as withops.SecretNotFoundError, the reconciler waits for them.
+func (p reconcilePreview) Run(ctx context.Context, instance *bdv1.BOSHDeployment, manifest *bdm.Manifest) error {
+	manifest.ApplyFeatureFlags()
+
+	err := applyCloudConfig(p.withops, instance, manifest)
+	if err != nil {
+		return errors.Wrap(err, "failed to apply the cloud config")
+	}
+
+	interpolationManifest, _, err := triggerManifest(ctx, p.client, instance)
+	if err != nil {
+		return errors.Wrap(err, "failed to get variable interpolation trigger manifest")
+	}
+	if interpolationManifest == nil {
+		interpolationManifest = manifest
+	}
+
+	_, err = p.converter.Variables(instance.Name, interpolationManifest.Variables)
+	if err != nil {
+		return errors.Wrap(err, "failed to generate quarks secrets from manifest")
+	}
+
+	_, err = p.converter.VariableOrder(interpolationManifest.Variables)
+	if err != nil {
+		return errors.Wrap(err, "failed to order the manifest variables")
+	}
+
+	_, err = p.jobFactory.VariableInterpolationJob(instance.Name, *interpolationManifest, instance.Spec.ManifestDebugMode, instance.Spec.JobDNS, instance.Spec.VariableInterpolationTriggerSecret)
+	if err != nil {
+		return errors.Wrap(err, "failed to build the desired manifest qJob")
+	}
+
+	igManifest, _ := withoutInstanceGroups(*manifest, instance.SuspendedInstanceGroups())
+	igManifest, _ = withoutInstanceGroups(igManifest, instance.Spec.IgnoredInstanceGroups)
+	_, err = p.jobFactory.InstanceGroupManifestJob(instance.Name, igManifest, nil, instance.ObjectMeta.Generation == 1, instance.Spec.JobDNS)
+	if err != nil {
+		return errors.Wrap(err, "failed to build instance group manifest qJob")
+	}
+
+	return nil
+}
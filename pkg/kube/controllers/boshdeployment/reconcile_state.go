@@ -0,0 +1,202 @@
+package boshdeployment
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+
+	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-utils/pkg/names"
+	vss "code.cloudfoundry.org/quarks-utils/pkg/versionedsecretstore"
+)
+
+// ReconcileStateVersion is the version of the reconcile state format. It is
+// increased for incompatible changes, new fields are added without.
+const ReconcileStateVersion = 1
+
+// ReconcileState is the reconcile state of the BOSHDeployments of a
+// namespace, which is exported for disaster recovery. It complements the
+// backup of the BOSHDeployments themselves.
+type ReconcileState struct {
+	Version     int                        `json:"version"`
+	Namespace   string                     `json:"namespace"`
+	ExportedAt  metav1.Time                `json:"exportedAt"`
+	Deployments []DeploymentReconcileState `json:"deployments"`
+}
+
+// DeploymentReconcileState is the reconcile state of a single BOSHDeployment
+type DeploymentReconcileState struct {
+	Name string `json:"name"`
+	// Generation is the generation of the BOSHDeployment at the time of the export
+	Generation int64 `json:"generation"`
+	// ObservedGeneration is the generation, which produced the last desired manifest
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// InputHash is the SHA256 hash of the with-ops manifest
+	InputHash string `json:"inputHash,omitempty"`
+	// DesiredManifestVersion is the version of the last desired manifest
+	DesiredManifestVersion int        `json:"desiredManifestVersion,omitempty"`
+	Phase                  bdv1.Phase `json:"phase,omitempty"`
+}
+
+// ReconcileStateStore exports and imports the reconcile state of the
+// BOSHDeployments of a namespace
+type ReconcileStateStore struct {
+	client    crc.Client
+	namespace string
+}
+
+// NewReconcileStateStore returns a store for the deployments of a namespace
+func NewReconcileStateStore(client crc.Client, namespace string) *ReconcileStateStore {
+	return &ReconcileStateStore{client: client, namespace: namespace}
+}
+
+// Export returns the reconcile state of all BOSHDeployments in the namespace
+func (s *ReconcileStateStore) Export(ctx context.Context) (*ReconcileState, error) {
+	list := &bdv1.BOSHDeploymentList{}
+	err := s.client.List(ctx, list, crc.InNamespace(s.namespace))
+	if err != nil {
+		return nil, errors.Wrapf(err, "listing BOSHDeployments in namespace '%s'", s.namespace)
+	}
+
+	state := &ReconcileState{
+		Version:     ReconcileStateVersion,
+		Namespace:   s.namespace,
+		ExportedAt:  metav1.Now(),
+		Deployments: []DeploymentReconcileState{},
+	}
+	for _, bdpl := range list.Items {
+		deployment := DeploymentReconcileState{
+			Name:       bdpl.Name,
+			Generation: bdpl.Generation,
+			Phase:      bdpl.Status.Phase,
+		}
+
+		deployment.InputHash, err = s.inputHash(ctx, bdpl.Name)
+		if err != nil {
+			return nil, err
+		}
+		deployment.DesiredManifestVersion, deployment.ObservedGeneration, err = s.lastDesiredManifest(ctx, bdpl.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		state.Deployments = append(state.Deployments, deployment)
+	}
+
+	return state, nil
+}
+
+// Import records the exported reconcile state of each BOSHDeployment in its
+// status.restoredState. It returns the names of the deployments, which don't
+// exist in the namespace and were skipped.
+func (s *ReconcileStateStore) Import(ctx context.Context, state *ReconcileState) ([]string, error) {
+	if state.Version != ReconcileStateVersion {
+		return nil, errors.Errorf("unsupported reconcile state version '%d', expected '%d'", state.Version, ReconcileStateVersion)
+	}
+	if state.Namespace != s.namespace {
+		return nil, errors.Errorf("reconcile state of namespace '%s' doesn't match namespace '%s'", state.Namespace, s.namespace)
+	}
+
+	skipped := []string{}
+	for _, deployment := range state.Deployments {
+		restored := &bdv1.RestoredState{
+			ExportedAt:             state.ExportedAt,
+			ObservedGeneration:     deployment.ObservedGeneration,
+			InputHash:              deployment.InputHash,
+			DesiredManifestVersion: deployment.DesiredManifestVersion,
+			Phase:                  deployment.Phase,
+		}
+
+		found := true
+		err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+			bdpl := &bdv1.BOSHDeployment{}
+			err := s.client.Get(ctx, crc.ObjectKey{Name: deployment.Name, Namespace: s.namespace}, bdpl)
+			if apierrors.IsNotFound(err) {
+				found = false
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			bdpl.Status.RestoredState = restored
+			return s.client.Status().Update(ctx, bdpl)
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "restoring reconcile state of BOSHDeployment '%s/%s'", s.namespace, deployment.Name)
+		}
+		if !found {
+			skipped = append(skipped, deployment.Name)
+		}
+	}
+
+	return skipped, nil
+}
+
+// inputHash returns the SHA256 hash of the deployment's with-ops manifest,
+// or an empty string, if it doesn't exist
+func (s *ReconcileStateStore) inputHash(ctx context.Context, deploymentName string) (string, error) {
+	secretName := secretNamer.DeploymentSecretName(names.DeploymentSecretTypeManifestWithOps, deploymentName, "")
+	secret := &corev1.Secret{}
+	err := s.client.Get(ctx, crc.ObjectKey{Name: secretName, Namespace: s.namespace}, secret)
+	if apierrors.IsNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.Wrapf(err, "getting with-ops manifest secret '%s'", secretName)
+	}
+
+	data, err := bdm.SecretData(secret.Data)
+	if err != nil {
+		return "", errors.Wrapf(err, "reading with-ops manifest secret '%s'", secretName)
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// lastDesiredManifest returns the version of the deployment's latest desired
+// manifest and the deployment generation, which produced it
+func (s *ReconcileStateStore) lastDesiredManifest(ctx context.Context, deploymentName string) (int, int64, error) {
+	secrets := &corev1.SecretList{}
+	err := s.client.List(ctx, secrets,
+		crc.InNamespace(s.namespace),
+		crc.MatchingLabels{
+			bdv1.LabelDeploymentName:       deploymentName,
+			bdv1.LabelDeploymentSecretType: names.DeploymentSecretTypeDesiredManifest.String(),
+		},
+	)
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "listing desired manifest secrets of BOSHDeployment '%s/%s'", s.namespace, deploymentName)
+	}
+
+	var latest *corev1.Secret
+	version := 0
+	for i := range secrets.Items {
+		v, err := vss.Version(secrets.Items[i])
+		if err != nil {
+			continue
+		}
+		if v > version {
+			version = v
+			latest = &secrets.Items[i]
+		}
+	}
+	if latest == nil {
+		return 0, 0, nil
+	}
+
+	// Secrets created before the provenance controller have no generation
+	generation, _ := strconv.ParseInt(latest.GetAnnotations()[bdv1.AnnotationDeploymentGeneration], 10, 64)
+	return version, generation, nil
+}
@@ -0,0 +1,170 @@
+package boshdeployment_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+
+	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	cfd "code.cloudfoundry.org/cf-operator/pkg/kube/controllers/boshdeployment"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/controllers/fakes"
+	vss "code.cloudfoundry.org/quarks-utils/pkg/versionedsecretstore"
+)
+
+var _ = Describe("ReconcileStateStore", func() {
+	var (
+		client       *fakes.FakeClient
+		statusWriter *fakes.FakeStatusWriter
+		deployments  map[string]bdv1.BOSHDeployment
+		manifest     string
+		store        *cfd.ReconcileStateStore
+	)
+
+	desiredManifest := func(version, generation string) corev1.Secret {
+		return corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "foo.desired-manifest-v" + version,
+				Labels:      map[string]string{vss.LabelVersion: version},
+				Annotations: map[string]string{bdv1.AnnotationDeploymentGeneration: generation},
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		manifest = "name: foo\n"
+		deployments = map[string]bdv1.BOSHDeployment{
+			"foo": {
+				ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", Generation: 5},
+				Status:     bdv1.BOSHDeploymentStatus{Phase: bdv1.PhaseApplied},
+			},
+		}
+
+		statusWriter = &fakes.FakeStatusWriter{}
+		client = &fakes.FakeClient{}
+		client.StatusCalls(func() crc.StatusWriter { return statusWriter })
+		client.GetCalls(func(_ context.Context, nn types.NamespacedName, object runtime.Object) error {
+			switch object := object.(type) {
+			case *bdv1.BOSHDeployment:
+				bdpl, ok := deployments[nn.Name]
+				if !ok {
+					return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+				}
+				bdpl.DeepCopyInto(object)
+			case *corev1.Secret:
+				if nn.Name != "foo.with-ops" {
+					return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+				}
+				object.Data = map[string][]byte{bdm.DesiredManifestKeyName: []byte(manifest)}
+			}
+			return nil
+		})
+		client.ListCalls(func(_ context.Context, object runtime.Object, _ ...crc.ListOption) error {
+			switch object := object.(type) {
+			case *bdv1.BOSHDeploymentList:
+				for _, bdpl := range deployments {
+					object.Items = append(object.Items, bdpl)
+				}
+			case *corev1.SecretList:
+				object.Items = []corev1.Secret{desiredManifest("1", "3"), desiredManifest("2", "4")}
+			}
+			return nil
+		})
+
+		store = cfd.NewReconcileStateStore(client, "default")
+	})
+
+	Describe("Export", func() {
+		It("exports the reconcile state of each deployment", func() {
+			state, err := store.Export(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+
+			sum := sha256.Sum256([]byte(manifest))
+			Expect(state.Version).To(Equal(cfd.ReconcileStateVersion))
+			Expect(state.Namespace).To(Equal("default"))
+			Expect(state.Deployments).To(Equal([]cfd.DeploymentReconcileState{
+				{
+					Name:                   "foo",
+					Generation:             5,
+					ObservedGeneration:     4,
+					InputHash:              hex.EncodeToString(sum[:]),
+					DesiredManifestVersion: 2,
+					Phase:                  bdv1.PhaseApplied,
+				},
+			}))
+		})
+
+		It("exports a stable format", func() {
+			state, err := store.Export(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+
+			data, err := json.Marshal(state.Deployments[0])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(data)).To(MatchJSON(`{
+				"name": "foo",
+				"generation": 5,
+				"observedGeneration": 4,
+				"inputHash": "` + state.Deployments[0].InputHash + `",
+				"desiredManifestVersion": 2,
+				"phase": "Applied"
+			}`))
+		})
+	})
+
+	Describe("Import", func() {
+		var state *cfd.ReconcileState
+
+		BeforeEach(func() {
+			state = &cfd.ReconcileState{
+				Version:   cfd.ReconcileStateVersion,
+				Namespace: "default",
+				Deployments: []cfd.DeploymentReconcileState{
+					{Name: "foo", Generation: 5, ObservedGeneration: 4, InputHash: "abc", DesiredManifestVersion: 2, Phase: bdv1.PhaseApplied},
+					{Name: "bar", Generation: 1},
+				},
+			}
+		})
+
+		It("restores the state into the status and skips missing deployments", func() {
+			skipped, err := store.Import(context.Background(), state)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(skipped).To(Equal([]string{"bar"}))
+
+			Expect(statusWriter.UpdateCallCount()).To(Equal(1))
+			_, object, _ := statusWriter.UpdateArgsForCall(0)
+			restored := object.(*bdv1.BOSHDeployment).Status.RestoredState
+			Expect(restored).ToNot(BeNil())
+			Expect(restored.ObservedGeneration).To(Equal(int64(4)))
+			Expect(restored.InputHash).To(Equal("abc"))
+			Expect(restored.DesiredManifestVersion).To(Equal(2))
+			Expect(restored.Phase).To(Equal(bdv1.PhaseApplied))
+		})
+
+		It("rejects unsupported versions", func() {
+			state.Version = 2
+
+			_, err := store.Import(context.Background(), state)
+			Expect(err).To(MatchError(ContainSubstring("unsupported reconcile state version '2'")))
+			Expect(statusWriter.UpdateCallCount()).To(Equal(0))
+		})
+
+		It("rejects the state of other namespaces", func() {
+			state.Namespace = "other"
+
+			_, err := store.Import(context.Background(), state)
+			Expect(err).To(MatchError(ContainSubstring("doesn't match namespace 'default'")))
+		})
+	})
+})
@@ -0,0 +1,28 @@
+package boshdeployment
+
+import (
+	"github.com/pkg/errors"
+
+	"code.cloudfoundry.org/cf-operator/pkg/kube/controllers/quarkssecret"
+	bdnames "code.cloudfoundry.org/cf-operator/pkg/kube/util/names"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/reference"
+)
+
+// secretNamer names the secrets, which are generated for BOSHDeployments
+var secretNamer bdnames.SecretNamer = bdnames.DefaultSecretNamer{}
+
+// SetSecretNamer initializes the package scoped secret namer, which is
+// injected into the BOSHDeployment, BPM and termination reconcilers, their job
+// factory, converters and manifest resolvers, into the validating webhook,
+// into the secret reference lookups and into the secret rotation reconciler. It has to be called before the
+// controllers and webhooks are added to the manager.
+func SetSecretNamer(namer bdnames.SecretNamer) error {
+	if namer == nil {
+		return errors.New("invalid secret namer, must not be nil")
+	}
+
+	secretNamer = namer
+	reference.SetSecretNamer(namer)
+	quarkssecret.SetSecretNamer(namer)
+	return nil
+}
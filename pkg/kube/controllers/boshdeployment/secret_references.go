@@ -0,0 +1,52 @@
+package boshdeployment
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+
+	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-utils/pkg/names"
+)
+
+// missingSecretReferences returns the references to fields of explicit
+// variables, e.g. '((ca.certificate))', whose variable secret exists but
+// lacks the key. The variable interpolation would replace them with empty
+// values. Variable secrets, which are not generated yet, are skipped.
+func (r *ReconcileBOSHDeployment) missingSecretReferences(ctx context.Context, instance *bdv1.BOSHDeployment, manifest *bdm.Manifest) ([]string, error) {
+	fields, err := manifest.ExplicitVariableFields()
+	if err != nil {
+		return nil, errors.Wrap(err, "listing explicit variable references")
+	}
+
+	missing := []string{}
+	for _, variable := range manifest.Variables {
+		if len(fields[variable.Name]) == 0 {
+			continue
+		}
+
+		secretName := r.secretNamer.DeploymentSecretName(names.DeploymentSecretTypeVariable, instance.Name, variable.Name)
+		secret := &corev1.Secret{}
+		err := r.client.Get(ctx, crc.ObjectKey{Name: secretName, Namespace: instance.Namespace}, secret)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, errors.Wrapf(err, "getting variable secret '%s'", secretName)
+		}
+
+		for _, field := range fields[variable.Name] {
+			if _, ok := secret.Data[field]; !ok {
+				missing = append(missing, fmt.Sprintf("'%s.%s' (secret '%s' has no key '%s')", variable.Name, field, secretName, field))
+			}
+		}
+	}
+
+	return missing, nil
+}
@@ -0,0 +1,100 @@
+package boshdeployment
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+)
+
+// sensitiveKeyPattern matches keys, whose values must not be stored in a ConfigMap
+var sensitiveKeyPattern = regexp.MustCompile(`(?i)(password|secret|private_key|token|certificate)$`)
+
+// configMapReferences returns the manifest and ops references of the deployment, which are backed by a ConfigMap
+func configMapReferences(spec bdv1.BOSHDeploymentSpec) []bdv1.ResourceReference {
+	refs := []bdv1.ResourceReference{}
+	for _, ref := range append([]bdv1.ResourceReference{spec.Manifest}, spec.Ops...) {
+		if ref.Type == bdv1.ConfigMapReference {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// validateConfigMapContent rejects ConfigMaps referenced by the deployment,
+// which contain literal values for secret like keys. Those values have to be
+// provided as variables, e.g. 'password: ((admin_password))', or in a Secret.
+func (v *Validator) validateConfigMapContent(ctx context.Context, spec bdv1.BOSHDeploymentSpec, namespace string) error {
+	for _, ref := range configMapReferences(spec) {
+		configMap := &corev1.ConfigMap{}
+		err := v.client.Get(ctx, client.ObjectKey{Name: ref.Name, Namespace: namespace}, configMap)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				// missing references are reported by the resolver
+				continue
+			}
+			return errors.Wrapf(err, "getting configmap '%s'", ref.Name)
+		}
+
+		keys := []string{}
+		for key, content := range configMap.Data {
+			if sensitiveKeyPattern.MatchString(key) {
+				keys = append(keys, key)
+				continue
+			}
+			keys = append(keys, sensitiveKeys(key, content)...)
+		}
+		if len(keys) > 0 {
+			sort.Strings(keys)
+			return errors.Errorf("configmap '%s' contains sensitive content in '%s', use a secret or variables instead", ref.Name, strings.Join(keys, "', '"))
+		}
+	}
+	return nil
+}
+
+// sensitiveKeys returns the paths of all secret like keys in the YAML content,
+// which have a literal value instead of a variable placeholder
+func sensitiveKeys(path string, content string) []string {
+	var doc interface{}
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		// not YAML, the resolver reports invalid manifests
+		return nil
+	}
+	return findSensitiveKeys(path, doc)
+}
+
+func findSensitiveKeys(path string, node interface{}) []string {
+	keys := []string{}
+	switch n := node.(type) {
+	case map[string]interface{}:
+		for key, value := range n {
+			keyPath := fmt.Sprintf("%s/%s", path, key)
+			if s, ok := value.(string); ok && sensitiveKeyPattern.MatchString(key) && !isVariable(s) {
+				keys = append(keys, keyPath)
+				continue
+			}
+			keys = append(keys, findSensitiveKeys(keyPath, value)...)
+		}
+	case []interface{}:
+		for i, value := range n {
+			keys = append(keys, findSensitiveKeys(fmt.Sprintf("%s/%d", path, i), value)...)
+		}
+	}
+	return keys
+}
+
+// isVariable returns true, if the value is empty or only refers to a variable
+func isVariable(value string) bool {
+	value = strings.TrimSpace(value)
+	return value == "" || (strings.HasPrefix(value, "((") && strings.HasSuffix(value, "))"))
+}
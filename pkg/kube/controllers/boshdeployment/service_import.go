@@ -0,0 +1,97 @@
+package boshdeployment
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+// ServiceImportGroupVersionKind is the kind of the Multicluster Services API's
+// ServiceImport, which makes an exported service available in a cluster
+var ServiceImportGroupVersionKind = schema.GroupVersionKind{
+	Group:   "multicluster.x-k8s.io",
+	Version: "v1alpha1",
+	Kind:    "ServiceImport",
+}
+
+// clusterSetDomain is the DNS zone of imported services
+const clusterSetDomain = "clusterset.local"
+
+// watchServiceImports reconciles the consumers of link provider services,
+// whose ServiceImport changes. It is skipped, if the ServiceImport CRD is
+// not installed.
+func watchServiceImports(ctx context.Context, mgr manager.Manager, c controller.Controller) error {
+	_, err := mgr.GetRESTMapper().RESTMapping(ServiceImportGroupVersionKind.GroupKind(), ServiceImportGroupVersionKind.Version)
+	if meta.IsNoMatchError(err) {
+		ctxlog.Debugf(ctx, "Not watching ServiceImports, the ServiceImport CRD is not installed")
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "looking up the ServiceImport kind")
+	}
+
+	serviceImport := &unstructured.Unstructured{}
+	serviceImport.SetGroupVersionKind(ServiceImportGroupVersionKind)
+	return c.Watch(&source.Kind{Type: serviceImport}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(a handler.MapObject) []reconcile.Request {
+			svc := &corev1.Service{}
+			err := mgr.GetClient().Get(ctx, types.NamespacedName{Namespace: a.Meta.GetNamespace(), Name: a.Meta.GetName()}, svc)
+			if err != nil || !isLinkProviderService(svc) {
+				return []reconcile.Request{}
+			}
+
+			deploymentName, ok := svc.GetAnnotations()[bdv1.LabelDeploymentName]
+			if !ok {
+				return []reconcile.Request{}
+			}
+			request := reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: svc.Namespace, Name: deploymentName},
+			}
+			ctxlog.NewMappingEvent(a.Object).Debug(ctx, request, "BOSHDeployment", a.Meta.GetName(), "ServiceImportOfLinkProvider")
+
+			return []reconcile.Request{request}
+		}),
+	})
+}
+
+// listServiceImports returns the names of the services imported into the
+// namespace. It returns none, if the ServiceImport CRD is not installed.
+func (r *ReconcileBOSHDeployment) listServiceImports(ctx context.Context, namespace string) (map[string]bool, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(ServiceImportGroupVersionKind.GroupVersion().WithKind(ServiceImportGroupVersionKind.Kind + "List"))
+	err := r.client.List(ctx, list, crc.InNamespace(namespace))
+	if meta.IsNoMatchError(err) {
+		return map[string]bool{}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "listing service imports in namespace '%s'", namespace)
+	}
+
+	imported := make(map[string]bool, len(list.Items))
+	for _, item := range list.Items {
+		imported[item.GetName()] = true
+	}
+	return imported, nil
+}
+
+// clusterSetDNSName returns the DNS name of an imported service, which
+// resolves to its endpoints in all clusters of the cluster set
+func clusterSetDNSName(name, namespace string) string {
+	return fmt.Sprintf("%s.%s.svc.%s", name, namespace, clusterSetDomain)
+}
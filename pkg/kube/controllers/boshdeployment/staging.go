@@ -0,0 +1,126 @@
+package boshdeployment
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/clientcmd"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	log "code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+var (
+	// stagingKubeconfig is the path to the kubeconfig of the staging cluster
+	stagingKubeconfig string
+	// stagingContext is the kube context in stagingKubeconfig, empty means its current context
+	stagingContext string
+)
+
+// SetStagingCluster initializes the package scoped staging cluster variables.
+// An empty kubeconfig disables the staging validation.
+func SetStagingCluster(kubeconfig string, kubeContext string) {
+	stagingKubeconfig = kubeconfig
+	stagingContext = kubeContext
+}
+
+// StagingValidator validates objects against a staging cluster before they are applied
+type StagingValidator interface {
+	DryRun(ctx context.Context, objects []runtime.Object) error
+}
+
+// NewStagingValidator returns a StagingValidator for the configured staging cluster,
+// or nil if no staging cluster is configured
+func NewStagingValidator(mgr manager.Manager) (StagingValidator, error) {
+	if stagingKubeconfig == "" {
+		return nil, nil
+	}
+
+	restConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{ExplicitPath: stagingKubeconfig},
+		&clientcmd.ConfigOverrides{CurrentContext: stagingContext},
+	).ClientConfig()
+	if err != nil {
+		return nil, errors.Wrapf(err, "loading staging kubeconfig '%s'", stagingKubeconfig)
+	}
+
+	client, err := crc.New(restConfig, crc.Options{Scheme: mgr.GetScheme()})
+	if err != nil {
+		return nil, errors.Wrap(err, "creating staging cluster client")
+	}
+
+	return stagingValidator{client: client, scheme: mgr.GetScheme()}, nil
+}
+
+type stagingValidator struct {
+	client crc.Client
+	scheme *runtime.Scheme
+}
+
+// DryRun creates the objects in dry-run mode. Objects, which already exist
+// on the staging cluster, are updated in dry-run mode instead.
+func (v stagingValidator) DryRun(ctx context.Context, objects []runtime.Object) error {
+	for _, o := range objects {
+		obj := o.DeepCopyObject()
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			return errors.Wrap(err, "accessing object metadata")
+		}
+		gvk, err := apiutil.GVKForObject(obj, v.scheme)
+		if err != nil {
+			return errors.Wrap(err, "looking up object kind")
+		}
+		id := fmt.Sprintf("%s '%s/%s'", gvk.Kind, accessor.GetNamespace(), accessor.GetName())
+
+		err = v.client.Create(ctx, obj, crc.DryRunAll)
+		if apierrors.IsAlreadyExists(err) {
+			err = v.dryRunUpdate(ctx, obj, accessor)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "dry-run of %s on staging cluster", id)
+		}
+	}
+
+	return nil
+}
+
+// dryRunUpdate updates an object, which exists on the staging cluster, in dry-run mode
+func (v stagingValidator) dryRunUpdate(ctx context.Context, obj runtime.Object, accessor metav1.Object) error {
+	existing := obj.DeepCopyObject()
+	err := v.client.Get(ctx, crc.ObjectKey{Name: accessor.GetName(), Namespace: accessor.GetNamespace()}, existing)
+	if err != nil {
+		return errors.Wrap(err, "getting existing object")
+	}
+
+	existingAccessor, err := meta.Accessor(existing)
+	if err != nil {
+		return errors.Wrap(err, "accessing object metadata")
+	}
+	accessor.SetResourceVersion(existingAccessor.GetResourceVersion())
+
+	return v.client.Update(ctx, obj, crc.DryRunAll)
+}
+
+// markDegraded records the failed staging validation in the status of the BOSHDeployment
+func (r *ReconcileBOSHDeployment) markDegraded(ctx context.Context, instance *bdv1.BOSHDeployment, stagingErr error) (reconcile.Result, error) {
+	err := r.updateStatus(ctx, instance, func(bdpl *bdv1.BOSHDeployment) {
+		bdpl.Status.Phase = bdv1.PhaseDegraded
+		bdpl.Status.StagingError = stagingErr.Error()
+	})
+	if err != nil {
+		log.WithEvent(instance, "UpdateError").Errorf(ctx, "failed to update staging error on bdpl '%s' (%v): %s", instance.Name, instance.ResourceVersion, err)
+	}
+
+	return reconcile.Result{},
+		log.WithEvent(instance, "StagingValidationError").Errorf(ctx, "staging validation failed for BOSHDeployment '%s/%s': %v", instance.Namespace, instance.Name, stagingErr)
+}
@@ -0,0 +1,65 @@
+package boshdeployment
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"k8s.io/client-go/util/retry"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/correlation"
+)
+
+// statusUpdateAttempts is the number of attempts to update the status of a
+// BOSHDeployment, when the update conflicts with a concurrent change
+var statusUpdateAttempts = retry.DefaultBackoff.Steps
+
+// SetStatusUpdateAttempts initializes the package scoped statusUpdateAttempts variable.
+func SetStatusUpdateAttempts(attempts int) error {
+	if attempts < 1 {
+		return errors.Errorf("invalid number of status update attempts '%d', must be at least 1", attempts)
+	}
+	statusUpdateAttempts = attempts
+	return nil
+}
+
+// updateStatus applies mutateFn to the status of the BOSHDeployment and updates it.
+// The status records the correlation ID and the conditions of the reconcile pass.
+// On conflicts the latest version is fetched and mutateFn is applied again,
+// until statusUpdateAttempts is exhausted. Phase transitions are reported to
+// the phase notifier.
+func (r *ReconcileBOSHDeployment) updateStatus(ctx context.Context, instance *bdv1.BOSHDeployment, mutateFn func(*bdv1.BOSHDeployment)) error {
+	return updateDeploymentStatus(ctx, r.client, instance, mutateFn)
+}
+
+// updateDeploymentStatus implements updateStatus for reconcilers of other
+// controllers, which write the status of BOSHDeployments
+func updateDeploymentStatus(ctx context.Context, client crc.Client, instance *bdv1.BOSHDeployment, mutateFn func(*bdv1.BOSHDeployment)) error {
+	backoff := retry.DefaultBackoff
+	backoff.Steps = statusUpdateAttempts
+
+	attempt := 0
+	var previous bdv1.Phase
+	err := retry.RetryOnConflict(backoff, func() error {
+		if attempt > 0 {
+			key := crc.ObjectKey{Name: instance.Name, Namespace: instance.Namespace}
+			if err := client.Get(ctx, key, instance); err != nil {
+				return errors.Wrapf(err, "getting latest BOSHDeployment '%s'", key)
+			}
+		}
+		attempt++
+
+		previous = instance.Status.Phase
+		instance.Status.CorrelationID = correlation.ID(ctx)
+		mutateFn(instance)
+		applyConditions(ctx, instance)
+		return client.Status().Update(ctx, instance)
+	})
+	if err != nil {
+		return err
+	}
+
+	notifyPhaseTransition(ctx, instance, previous)
+	return nil
+}
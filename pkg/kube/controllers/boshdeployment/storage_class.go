@@ -0,0 +1,123 @@
+package boshdeployment
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+)
+
+// storageClassMapping maps persistent disk types to the names of their
+// StorageClasses, it is empty if no mapping is configured
+var storageClassMapping = map[string]string{}
+
+// SetStorageClassMapping initializes the package scoped storage class mapping
+// from a YAML file, which maps persistent disk types to StorageClass names.
+// An empty path disables the storage class check.
+func SetStorageClassMapping(path string) error {
+	mapping := map[string]string{}
+	if path == "" {
+		storageClassMapping = mapping
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return errors.Wrapf(err, "reading storage class mapping '%s'", path)
+	}
+	err = yaml.UnmarshalStrict(data, &mapping)
+	if err != nil {
+		return errors.Wrapf(err, "parsing storage class mapping '%s'", path)
+	}
+	for diskType, storageClass := range mapping {
+		if storageClass == "" {
+			return errors.Errorf("empty storage class for persistent disk type '%s'", diskType)
+		}
+	}
+
+	storageClassMapping = mapping
+	return nil
+}
+
+// missingStorageClassError is returned, if persistent disk types of instance
+// groups don't map to an existing StorageClass
+type missingStorageClassError struct {
+	problems []string
+}
+
+func (e *missingStorageClassError) Error() string {
+	return fmt.Sprintf("persistent disk types don't map to an existing storage class: %s", strings.Join(e.problems, ", "))
+}
+
+func isMissingStorageClass(err error) bool {
+	_, ok := errors.Cause(err).(*missingStorageClassError)
+	return ok
+}
+
+// validateStorageClasses checks, that the persistent disk type of each
+// instance group maps to an existing StorageClass, so pods don't get stuck
+// on unbound volume claims. It is skipped, if no mapping is configured.
+func (r *ReconcileBOSHDeployment) validateStorageClasses(ctx context.Context, manifest *bdm.Manifest) error {
+	if len(storageClassMapping) == 0 {
+		return nil
+	}
+
+	problems := []string{}
+	found := map[string]bool{}
+	for _, ig := range manifest.InstanceGroups {
+		if ig.PersistentDiskType == "" {
+			continue
+		}
+
+		storageClass, ok := storageClassMapping[ig.PersistentDiskType]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("instance group '%s' uses unmapped persistent disk type '%s'", ig.Name, ig.PersistentDiskType))
+			continue
+		}
+
+		exists, checked := found[storageClass]
+		if !checked {
+			var err error
+			exists, err = r.storageClassExists(ctx, storageClass)
+			if err != nil {
+				return err
+			}
+			found[storageClass] = exists
+		}
+		if !exists {
+			problems = append(problems, fmt.Sprintf("instance group '%s' uses persistent disk type '%s', whose storage class '%s' doesn't exist", ig.Name, ig.PersistentDiskType, storageClass))
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return &missingStorageClassError{problems: problems}
+	}
+	return nil
+}
+
+// storageClassExists returns true, if the StorageClass exists. StorageClasses
+// are not cached, so they are read as unstructured objects.
+func (r *ReconcileBOSHDeployment) storageClassExists(ctx context.Context, name string) (bool, error) {
+	sc := &unstructured.Unstructured{}
+	sc.SetGroupVersionKind(schema.GroupVersionKind{Group: "storage.k8s.io", Version: "v1", Kind: "StorageClass"})
+	err := r.client.Get(ctx, crc.ObjectKey{Name: name}, sc)
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrapf(err, "getting storage class '%s'", name)
+	}
+	return true, nil
+}
@@ -0,0 +1,43 @@
+package boshdeployment
+
+import (
+	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+)
+
+// withoutInstanceGroups returns a copy of the manifest without the excluded
+// instance groups, e.g. suspended or ignored ones, so their manifests and BPM
+// configs are not regenerated. It also returns the names of the excluded
+// instance groups, which are part of the manifest.
+func withoutInstanceGroups(manifest bdm.Manifest, excluded []string) (bdm.Manifest, []string) {
+	if len(excluded) == 0 {
+		return manifest, nil
+	}
+
+	isExcluded := map[string]bool{}
+	for _, name := range excluded {
+		isExcluded[name] = true
+	}
+
+	var names []string
+	instanceGroups := make(bdm.InstanceGroups, 0, len(manifest.InstanceGroups))
+	for _, ig := range manifest.InstanceGroups {
+		if isExcluded[ig.Name] {
+			names = append(names, ig.Name)
+			continue
+		}
+		instanceGroups = append(instanceGroups, ig)
+	}
+	manifest.InstanceGroups = instanceGroups
+
+	return manifest, names
+}
+
+// containsInstanceGroup returns true, if the instance group is in the list of instance group names, e.g. the suspended ones
+func containsInstanceGroup(names []string, instanceGroupName string) bool {
+	for _, name := range names {
+		if name == instanceGroupName {
+			return true
+		}
+	}
+	return false
+}
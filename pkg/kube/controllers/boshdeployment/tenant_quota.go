@@ -0,0 +1,100 @@
+package boshdeployment
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+)
+
+// TenantQuota limits the resolved manifest of each BOSHDeployment of a tenant
+type TenantQuota struct {
+	// MaxInstanceGroups is the maximum number of instance groups of a deployment
+	MaxInstanceGroups *int `json:"maxInstanceGroups,omitempty"`
+	// MaxInstances is the maximum number of instances of all instance groups of a deployment
+	MaxInstances *int `json:"maxInstances,omitempty"`
+}
+
+// tenantQuotas maps tenant names to their quota, it is empty if no quotas are configured
+var tenantQuotas = map[string]TenantQuota{}
+
+// SetTenantQuotas initializes the package scoped tenant quotas from a YAML
+// file, which maps tenant names to quotas. An empty path disables the quotas.
+func SetTenantQuotas(path string) error {
+	quotas := map[string]TenantQuota{}
+	if path == "" {
+		tenantQuotas = quotas
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return errors.Wrapf(err, "reading tenant quotas '%s'", path)
+	}
+	err = yaml.UnmarshalStrict(data, &quotas)
+	if err != nil {
+		return errors.Wrapf(err, "parsing tenant quotas '%s'", path)
+	}
+	for name, q := range quotas {
+		if q.MaxInstanceGroups != nil && *q.MaxInstanceGroups < 0 {
+			return errors.Errorf("invalid max instance groups '%d' in quota of tenant '%s'", *q.MaxInstanceGroups, name)
+		}
+		if q.MaxInstances != nil && *q.MaxInstances < 0 {
+			return errors.Errorf("invalid max instances '%d' in quota of tenant '%s'", *q.MaxInstances, name)
+		}
+	}
+
+	tenantQuotas = quotas
+	return nil
+}
+
+// validateTenantQuota checks the instance groups and instances of the
+// resolved manifest against the quota of the tenant, which the tenant label
+// of the namespace names. Namespaces without tenant and tenants without quota
+// are not limited.
+func (v *Validator) validateTenantQuota(ctx context.Context, namespace string, manifest *bdm.Manifest) error {
+	if len(tenantQuotas) == 0 {
+		return nil
+	}
+
+	// Namespaces are not cached, so they are read as unstructured objects
+	ns := &unstructured.Unstructured{}
+	ns.SetGroupVersionKind(schema.GroupVersionKind{Version: "v1", Kind: "Namespace"})
+	err := v.client.Get(ctx, crc.ObjectKey{Name: namespace}, ns)
+	if err != nil {
+		return errors.Wrapf(err, "getting namespace '%s'", namespace)
+	}
+
+	tenant, ok := ns.GetLabels()[bdv1.LabelTenant]
+	if !ok {
+		return nil
+	}
+	quota, ok := tenantQuotas[tenant]
+	if !ok {
+		return nil
+	}
+
+	instanceGroups := len(manifest.InstanceGroups)
+	if quota.MaxInstanceGroups != nil && instanceGroups > *quota.MaxInstanceGroups {
+		return fmt.Errorf("tenant '%s' may deploy at most %d instance groups, the deployment has %d", tenant, *quota.MaxInstanceGroups, instanceGroups)
+	}
+
+	instances := 0
+	for _, ig := range manifest.InstanceGroups {
+		instances += ig.Instances
+	}
+	if quota.MaxInstances != nil && instances > *quota.MaxInstances {
+		return fmt.Errorf("tenant '%s' may deploy at most %d instances, the deployment has %d", tenant, *quota.MaxInstances, instances)
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package boshdeployment
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/backpressure"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/desiredmanifest"
+	"code.cloudfoundry.org/quarks-utils/pkg/config"
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+// AddTermination creates a new controller, which deletes the instance groups
+// of BOSHDeployments with the 'DeleteAfterReady' termination policy, once
+// their remaining instance groups are ready.
+func AddTermination(ctx context.Context, config *config.Config, mgr manager.Manager) error {
+	ctx = ctxlog.NewContextWithRecorder(ctx, "termination-reconciler", mgr.GetEventRecorderFor("termination-recorder"))
+	r := NewTerminationReconciler(ctx, config, mgr, desiredmanifest.NewDesiredManifest(mgr.GetClient(), secretNamer))
+
+	// Create a new controller
+	c, err := controller.New("termination-controller", mgr, controller.Options{
+		Reconciler:              backpressure.NewReconciler(ctx, r),
+		MaxConcurrentReconciles: config.MaxBoshDeploymentWorkers,
+	})
+	if err != nil {
+		return errors.Wrap(err, "Adding termination controller to manager failed.")
+	}
+
+	// Watch for ready StatefulSets of BOSHDeployments. Resyncs trigger
+	// update events, too, so instance groups are also terminated when no
+	// other instance group changed.
+	p := predicate.Funcs{
+		CreateFunc:  func(e event.CreateEvent) bool { return false },
+		DeleteFunc:  func(e event.DeleteEvent) bool { return false },
+		GenericFunc: func(e event.GenericEvent) bool { return false },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			o := e.ObjectNew.(*appsv1.StatefulSet)
+			if _, ok := o.Labels[bdm.LabelDeploymentName]; !ok || !statefulSetReady(o) {
+				return false
+			}
+
+			ctxlog.NewPredicateEvent(o).Debug(
+				ctx, e.MetaNew, "StatefulSet",
+				fmt.Sprintf("Update predicate passed for ready StatefulSet '%s'", e.MetaNew.GetName()),
+			)
+			return true
+		},
+	}
+
+	err = c.Watch(&source.Kind{Type: &appsv1.StatefulSet{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(a handler.MapObject) []reconcile.Request {
+			request := reconcile.Request{NamespacedName: types.NamespacedName{
+				Namespace: a.Meta.GetNamespace(),
+				Name:      a.Meta.GetLabels()[bdm.LabelDeploymentName],
+			}}
+			ctxlog.NewMappingEvent(a.Object).Debug(ctx, request, "BOSHDeployment", a.Meta.GetName(), "StatefulSet")
+			return []reconcile.Request{request}
+		}),
+	}, p)
+	if err != nil {
+		return errors.Wrapf(err, "Watching statefulsets failed in termination controller.")
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package boshdeployment
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+
+	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	qstsv1a1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/quarksstatefulset/v1alpha1"
+	log "code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+// obsoleteQuarksStatefulSets returns the QuarksStatefulSets of the deployment,
+// whose instance groups are no longer part of the manifest, e.g. because they
+// were removed or renamed. Suspended and ignored instance groups are kept.
+func obsoleteQuarksStatefulSets(ctx context.Context, client crc.Client, bdpl *bdv1.BOSHDeployment, manifest *bdm.Manifest) ([]qstsv1a1.QuarksStatefulSet, error) {
+	qStsList := &qstsv1a1.QuarksStatefulSetList{}
+	err := client.List(ctx, qStsList,
+		crc.InNamespace(bdpl.Namespace),
+		crc.MatchingLabels{bdm.LabelDeploymentName: bdpl.Name},
+	)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list QuarksStatefulSets of BOSHDeployment '%s'", bdpl.Name)
+	}
+
+	obsolete := []qstsv1a1.QuarksStatefulSet{}
+	for _, qSts := range qStsList.Items {
+		igName, ok := qSts.Labels[bdm.LabelInstanceGroupName]
+		if !ok {
+			continue
+		}
+		if _, found := manifest.InstanceGroups.InstanceGroupByName(igName); found {
+			continue
+		}
+		if containsInstanceGroup(bdpl.SuspendedInstanceGroups(), igName) ||
+			containsInstanceGroup(bdpl.Spec.IgnoredInstanceGroups, igName) {
+			continue
+		}
+		obsolete = append(obsolete, qSts)
+	}
+
+	return obsolete, nil
+}
+
+// terminateObsoleteInstanceGroups deletes the QuarksStatefulSets of instance
+// groups, which are no longer part of the manifest. Their StatefulSets are
+// garbage collected.
+func terminateObsoleteInstanceGroups(ctx context.Context, client crc.Client, bdpl *bdv1.BOSHDeployment, manifest *bdm.Manifest) error {
+	obsolete, err := obsoleteQuarksStatefulSets(ctx, client, bdpl, manifest)
+	if err != nil {
+		return err
+	}
+
+	for i := range obsolete {
+		qSts := &obsolete[i]
+		err := client.Delete(ctx, qSts)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to delete QuarksStatefulSet '%s'", qSts.Name)
+		}
+
+		log.WithEvent(bdpl, "InstanceGroupTerminated").Infof(ctx, "Deleted QuarksStatefulSet '%s' of instance group '%s', which is no longer part of the manifest (termination policy '%s')",
+			qSts.Name, qSts.Labels[bdm.LabelInstanceGroupName], bdpl.Spec.DeploymentStrategy.TerminationPolicy)
+	}
+
+	return nil
+}
+
+// instanceGroupsReady returns true, if the StatefulSets of all service
+// instance groups of the manifest have their desired number of ready replicas
+func instanceGroupsReady(ctx context.Context, client crc.Client, bdpl *bdv1.BOSHDeployment, manifest *bdm.Manifest) (bool, error) {
+	for _, ig := range manifest.InstanceGroups {
+		if ig.LifeCycle != bdm.IGTypeService && ig.LifeCycle != bdm.IGTypeDefault {
+			continue
+		}
+		if ig.Instances == 0 ||
+			containsInstanceGroup(bdpl.SuspendedInstanceGroups(), ig.Name) ||
+			containsInstanceGroup(bdpl.Spec.IgnoredInstanceGroups, ig.Name) {
+			continue
+		}
+
+		stsList := &appsv1.StatefulSetList{}
+		err := client.List(ctx, stsList,
+			crc.InNamespace(bdpl.Namespace),
+			crc.MatchingLabels{
+				bdm.LabelDeploymentName:    bdpl.Name,
+				bdm.LabelInstanceGroupName: ig.Name,
+			},
+		)
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to list StatefulSets of instance group '%s'", ig.Name)
+		}
+
+		if len(stsList.Items) == 0 {
+			log.Debugf(ctx, "Instance group '%s' has no StatefulSets yet", ig.Name)
+			return false, nil
+		}
+
+		for _, sts := range stsList.Items {
+			if !statefulSetReady(&sts) {
+				log.Debugf(ctx, "StatefulSet '%s' of instance group '%s' is not ready: %d ready replicas", sts.Name, ig.Name, sts.Status.ReadyReplicas)
+				return false, nil
+			}
+		}
+	}
+
+	return true, nil
+}
+
+// statefulSetReady returns true, if the StatefulSet observed its latest spec
+// and all of its replicas are ready
+func statefulSetReady(sts *appsv1.StatefulSet) bool {
+	if sts.Status.ObservedGeneration < sts.Generation {
+		return false
+	}
+	return sts.Spec.Replicas == nil || sts.Status.ReadyReplicas >= *sts.Spec.Replicas
+}
@@ -0,0 +1,83 @@
+package boshdeployment
+
+import (
+	"context"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/audit"
+	"code.cloudfoundry.org/quarks-utils/pkg/config"
+	log "code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+// NewTerminationReconciler returns a new reconcile.Reconciler
+func NewTerminationReconciler(ctx context.Context, config *config.Config, mgr manager.Manager, resolver DesiredManifest) reconcile.Reconciler {
+	return &ReconcileTermination{
+		ctx:      ctx,
+		config:   config,
+		client:   audit.NewClient(mgr.GetClient()),
+		resolver: resolver,
+	}
+}
+
+// ReconcileTermination deletes the instance groups of a BOSHDeployment, which
+// are no longer part of its manifest, according to the 'DeleteAfterReady'
+// termination policy
+type ReconcileTermination struct {
+	ctx      context.Context
+	config   *config.Config
+	client   client.Client
+	resolver DesiredManifest
+}
+
+// Reconcile deletes the QuarksStatefulSets of obsolete instance groups, once
+// the StatefulSets of all instance groups of the desired manifest are ready
+func (r *ReconcileTermination) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	ctx, cancel := context.WithTimeout(r.ctx, r.config.CtxTimeOut)
+	defer cancel()
+	ctx = audit.NewContext(ctx, request.NamespacedName)
+
+	log.Debugf(ctx, "Reconciling termination of obsolete instance groups of BOSHDeployment '%s'", request.NamespacedName)
+	bdpl := &bdv1.BOSHDeployment{}
+	err := r.client.Get(ctx, request.NamespacedName, bdpl)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			log.Debug(ctx, "Skip reconcile: BOSHDeployment not found")
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, log.WithEvent(bdpl, "GetBOSHDeploymentError").Errorf(ctx, "failed to get BOSHDeployment '%s': %v", request.NamespacedName, err)
+	}
+
+	if bdpl.Spec.DeploymentStrategy.TerminationPolicy != bdv1.TerminationPolicyDeleteAfterReady {
+		return reconcile.Result{}, nil
+	}
+
+	manifest, err := r.resolver.DesiredManifest(ctx, bdpl.Name, bdpl.Namespace)
+	if err != nil {
+		return reconcile.Result{}, log.WithEvent(bdpl, "DesiredManifestReadError").Errorf(ctx, "failed to read desired manifest of BOSHDeployment '%s': %v", request.NamespacedName, err)
+	}
+
+	ready, err := instanceGroupsReady(ctx, r.client, bdpl, manifest)
+	if err != nil {
+		return reconcile.Result{}, log.WithEvent(bdpl, "InstanceGroupTerminationError").Errorf(ctx, "failed to check readiness of instance groups: %v", err)
+	}
+	if !ready {
+		log.Debugf(ctx, "Skip reconcile: instance groups of BOSHDeployment '%s' are not ready", request.NamespacedName)
+		return reconcile.Result{}, nil
+	}
+
+	if result, pending := pendingWindow(ctx, bdpl, "termination of obsolete instance groups"); pending {
+		return result, nil
+	}
+
+	err = terminateObsoleteInstanceGroups(ctx, r.client, bdpl, manifest)
+	if err != nil {
+		return reconcile.Result{}, log.WithEvent(bdpl, "InstanceGroupTerminationError").Errorf(ctx, "failed to terminate obsolete instance groups: %v", err)
+	}
+
+	return reconcile.Result{}, nil
+}
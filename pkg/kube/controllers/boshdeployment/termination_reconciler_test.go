@@ -0,0 +1,142 @@
+package boshdeployment_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	qstsv1a1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/quarksstatefulset/v1alpha1"
+	cfd "code.cloudfoundry.org/cf-operator/pkg/kube/controllers/boshdeployment"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/controllers/fakes"
+	cfcfg "code.cloudfoundry.org/quarks-utils/pkg/config"
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+	"code.cloudfoundry.org/quarks-utils/pkg/pointers"
+	helper "code.cloudfoundry.org/quarks-utils/testing/testhelper"
+)
+
+var _ = Describe("ReconcileTermination", func() {
+	var (
+		manager       *fakes.FakeManager
+		client        *fakes.FakeClient
+		resolver      fakes.FakeDesiredManifest
+		recorder      *record.FakeRecorder
+		reconciler    reconcile.Reconciler
+		request       reconcile.Request
+		bdpl          *bdv1.BOSHDeployment
+		readyReplicas int32
+	)
+
+	BeforeEach(func() {
+		manager = &fakes.FakeManager{}
+		recorder = record.NewFakeRecorder(20)
+		request = reconcile.Request{NamespacedName: types.NamespacedName{Name: "foo", Namespace: "default"}}
+		bdpl = &bdv1.BOSHDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+			Spec: bdv1.BOSHDeploymentSpec{
+				DeploymentStrategy: bdv1.DeploymentStrategy{
+					TerminationPolicy: bdv1.TerminationPolicyDeleteAfterReady,
+				},
+			},
+		}
+		readyReplicas = 2
+
+		resolver = fakes.FakeDesiredManifest{}
+		resolver.DesiredManifestReturns(&bdm.Manifest{
+			InstanceGroups: bdm.InstanceGroups{
+				{Name: "newpod", Instances: 2},
+				{Name: "errand", Instances: 1, LifeCycle: bdm.IGTypeErrand},
+			},
+		}, nil)
+
+		client = &fakes.FakeClient{}
+		client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+			switch object := object.(type) {
+			case *bdv1.BOSHDeployment:
+				bdpl.DeepCopyInto(object)
+			}
+			return nil
+		})
+		client.ListCalls(func(context context.Context, object runtime.Object, _ ...crc.ListOption) error {
+			switch object := object.(type) {
+			case *appsv1.StatefulSetList:
+				object.Items = []appsv1.StatefulSet{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "foo-newpod-v1", Namespace: "default"},
+						Spec:       appsv1.StatefulSetSpec{Replicas: pointers.Int32(2)},
+						Status:     appsv1.StatefulSetStatus{ReadyReplicas: readyReplicas},
+					},
+				}
+			case *qstsv1a1.QuarksStatefulSetList:
+				for _, ig := range []string{"newpod", "oldpod"} {
+					object.Items = append(object.Items, qstsv1a1.QuarksStatefulSet{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      "foo-" + ig,
+							Namespace: "default",
+							Labels: map[string]string{
+								bdm.LabelDeploymentName:    "foo",
+								bdm.LabelInstanceGroupName: ig,
+							},
+						},
+					})
+				}
+			}
+			return nil
+		})
+		manager.GetClientReturns(client)
+	})
+
+	JustBeforeEach(func() {
+		_, log := helper.NewTestLogger()
+		ctx := ctxlog.NewParentContext(log)
+		ctx = ctxlog.NewContextWithRecorder(ctx, "TestRecorder", recorder)
+		config := &cfcfg.Config{CtxTimeOut: 10 * time.Second}
+		reconciler = cfd.NewTerminationReconciler(ctx, config, manager, &resolver)
+	})
+
+	It("deletes the QuarksStatefulSets of removed instance groups, once all instance groups are ready", func() {
+		_, err := reconciler.Reconcile(request)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(client.DeleteCallCount()).To(Equal(1))
+		_, object, _ := client.DeleteArgsForCall(0)
+		Expect(object.(*qstsv1a1.QuarksStatefulSet).Name).To(Equal("foo-oldpod"))
+		Expect(<-recorder.Events).To(ContainSubstring("InstanceGroupTerminated"))
+	})
+
+	It("waits for instance groups, which are not ready", func() {
+		readyReplicas = 1
+
+		_, err := reconciler.Reconcile(request)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(client.DeleteCallCount()).To(Equal(0))
+	})
+
+	It("keeps removed instance groups, which are ignored", func() {
+		bdpl.Spec.IgnoredInstanceGroups = []string{"oldpod"}
+
+		_, err := reconciler.Reconcile(request)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(client.DeleteCallCount()).To(Equal(0))
+	})
+
+	It("skips deployments with another termination policy", func() {
+		bdpl.Spec.DeploymentStrategy.TerminationPolicy = bdv1.TerminationPolicyRetain
+
+		_, err := reconciler.Reconcile(request)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resolver.DesiredManifestCallCount()).To(Equal(0))
+		Expect(client.DeleteCallCount()).To(Equal(0))
+	})
+})
@@ -0,0 +1,83 @@
+package boshdeployment
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+
+	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/withops"
+	qjv1a1 "code.cloudfoundry.org/quarks-job/pkg/kube/apis/quarksjob/v1alpha1"
+)
+
+// triggerManifest returns the manifest in the variable interpolation trigger
+// secret of the deployment and the SHA1 of its normalized content. It
+// returns nil, if the deployment has no trigger secret. A missing secret is
+// reported as a withops.SecretNotFoundError, so the reconcile waits for it.
+func triggerManifest(ctx context.Context, client crc.Client, instance *bdv1.BOSHDeployment) (*bdm.Manifest, string, error) {
+	name := instance.Spec.VariableInterpolationTriggerSecret
+	if name == "" {
+		return nil, "", nil
+	}
+
+	secret := &corev1.Secret{}
+	err := client.Get(ctx, crc.ObjectKey{Name: name, Namespace: instance.Namespace}, secret)
+	if apierrors.IsNotFound(err) {
+		err = &withops.SecretNotFoundError{Namespace: instance.Namespace, Name: name}
+	}
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "getting trigger Secret '%s'", name)
+	}
+
+	data, err := bdm.SecretData(secret.Data)
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "reading manifest of trigger Secret '%s'", name)
+	}
+	if data == nil {
+		return nil, "", errors.Errorf("trigger Secret '%s' doesn't contain key %s or %s", name, bdm.DesiredManifestKeyName, bdm.CompressedManifestKeyName)
+	}
+
+	m, err := bdm.LoadYAML(data)
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "loading manifest of trigger Secret '%s'", name)
+	}
+	sha1, err := m.NormalizedSHA1(manifestNormalization)
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "hashing manifest of trigger Secret '%s'", name)
+	}
+
+	return m, sha1, nil
+}
+
+// setTriggerManifestSHA1 records the trigger manifest on the pod template of
+// the variable interpolation job, so a changed trigger manifest changes the
+// job's spec
+func setTriggerManifestSHA1(dmQJob *qjv1a1.QuarksJob, sha1 string) {
+	if sha1 == "" {
+		return
+	}
+
+	template := &dmQJob.Spec.Template.Spec.Template
+	if template.Annotations == nil {
+		template.Annotations = map[string]string{}
+	}
+	template.Annotations[bdv1.AnnotationTriggerManifestSHA1] = sha1
+}
+
+// interpolationInputChanged returns true, if the existing variable
+// interpolation job ran on another manifest than dmQJob will. That is the
+// trigger manifest, if the deployment has a trigger secret, otherwise the
+// with-ops manifest in manifestSecret.
+func (r *ReconcileBOSHDeployment) interpolationInputChanged(ctx context.Context, instance *bdv1.BOSHDeployment, existing *qjv1a1.QuarksJob, dmQJob *qjv1a1.QuarksJob, manifestSecret *corev1.Secret) (bool, error) {
+	if instance.Spec.VariableInterpolationTriggerSecret == "" {
+		return r.manifestWithOpsChanged(ctx, manifestSecret)
+	}
+
+	return existing.Spec.Template.Spec.Template.Annotations[bdv1.AnnotationTriggerManifestSHA1] !=
+		dmQJob.Spec.Template.Spec.Template.Annotations[bdv1.AnnotationTriggerManifestSHA1], nil
+}
@@ -0,0 +1,207 @@
+package boshdeployment
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+
+	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	log "code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+// updateNotReadyRequeueAfter is the delay, after which an instance group
+// waiting for a preceding instance group to become ready is reconciled again
+const updateNotReadyRequeueAfter = 10 * time.Second
+
+// updateWatch is the result of checking the preceding instance groups of an
+// instance group against the update watch times
+type updateWatch struct {
+	// instanceGroup is the preceding instance group, which is not stable yet
+	instanceGroup string
+	// until is the time, when it is stable. It is zero, if it isn't ready yet.
+	until time.Time
+}
+
+// waits returns true, if the instance group has to wait for a preceding instance group
+func (w updateWatch) waits() bool {
+	return w.instanceGroup != ""
+}
+
+// requeueAfter returns the delay until the instance group is checked again
+func (w updateWatch) requeueAfter(now time.Time) time.Duration {
+	if w.until.IsZero() {
+		return updateNotReadyRequeueAfter
+	}
+	return w.until.Sub(now)
+}
+
+// checkUpdateWatch checks, if the instance groups before the given one in
+// the manifest are stable. An instance group is stable, once all of its
+// StatefulSets are ready and its pods have been ready for the canary watch
+// time, if it is the first instance group, or else for the update watch time.
+// Errands, suspended, ignored and empty instance groups are not waited for.
+func checkUpdateWatch(ctx context.Context, client crc.Client, bdpl *bdv1.BOSHDeployment, manifest *bdm.Manifest, instanceGroupName string, now time.Time) (updateWatch, error) {
+	config := bdpl.Spec.UpdateConfig
+	if config == nil {
+		return updateWatch{}, nil
+	}
+
+	watch := updateWatch{}
+	first := true
+	for _, ig := range manifest.InstanceGroups {
+		if ig.Name == instanceGroupName {
+			break
+		}
+		if ig.LifeCycle != bdm.IGTypeService && ig.LifeCycle != bdm.IGTypeDefault {
+			continue
+		}
+		if ig.Instances == 0 ||
+			containsInstanceGroup(bdpl.SuspendedInstanceGroups(), ig.Name) ||
+			containsInstanceGroup(bdpl.Spec.IgnoredInstanceGroups, ig.Name) {
+			continue
+		}
+
+		watchTime := config.UpdateWatchTime
+		if first {
+			watchTime = config.CanaryWatchTime
+		}
+		first = false
+
+		readySince, ready, err := instanceGroupReadySince(ctx, client, bdpl, ig.Name)
+		if err != nil {
+			return updateWatch{}, err
+		}
+		if !ready {
+			return updateWatch{instanceGroup: ig.Name}, nil
+		}
+		if watchTime == nil {
+			continue
+		}
+
+		until := readySince.Add(watchTime.Duration)
+		if until.After(now) && until.After(watch.until) {
+			watch = updateWatch{instanceGroup: ig.Name, until: until}
+		}
+	}
+
+	return watch, nil
+}
+
+// instanceGroupReadySince returns whether all StatefulSets of the instance
+// group are ready and since when its last pod is ready
+func instanceGroupReadySince(ctx context.Context, client crc.Client, bdpl *bdv1.BOSHDeployment, instanceGroupName string) (time.Time, bool, error) {
+	labels := crc.MatchingLabels{
+		bdm.LabelDeploymentName:    bdpl.Name,
+		bdm.LabelInstanceGroupName: instanceGroupName,
+	}
+
+	stsList := &appsv1.StatefulSetList{}
+	err := client.List(ctx, stsList, crc.InNamespace(bdpl.Namespace), labels)
+	if err != nil {
+		return time.Time{}, false, errors.Wrapf(err, "failed to list StatefulSets of instance group '%s'", instanceGroupName)
+	}
+	if len(stsList.Items) == 0 {
+		log.Debugf(ctx, "Instance group '%s' has no StatefulSets yet", instanceGroupName)
+		return time.Time{}, false, nil
+	}
+	for _, sts := range stsList.Items {
+		if !statefulSetReady(&sts) {
+			log.Debugf(ctx, "StatefulSet '%s' of instance group '%s' is not ready: %d ready replicas", sts.Name, instanceGroupName, sts.Status.ReadyReplicas)
+			return time.Time{}, false, nil
+		}
+	}
+
+	pods := &corev1.PodList{}
+	err = client.List(ctx, pods, crc.InNamespace(bdpl.Namespace), labels)
+	if err != nil {
+		return time.Time{}, false, errors.Wrapf(err, "failed to list pods of instance group '%s'", instanceGroupName)
+	}
+
+	readySince := time.Time{}
+	for _, pod := range pods.Items {
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == corev1.PodReady && condition.Status == corev1.ConditionTrue && condition.LastTransitionTime.After(readySince) {
+				readySince = condition.LastTransitionTime.Time
+			}
+		}
+	}
+
+	return readySince, true, nil
+}
+
+// updateWaitUntil records the end of the current update watch in the
+// status of the BOSHDeployment. An expired wait is cleared. Later waits of
+// concurrently rolled out instance groups take precedence.
+func updateWaitUntil(ctx context.Context, client crc.Client, bdpl *bdv1.BOSHDeployment, until time.Time, now time.Time) error {
+	attempt := 0
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		if attempt > 0 {
+			key := crc.ObjectKey{Name: bdpl.Name, Namespace: bdpl.Namespace}
+			if err := client.Get(ctx, key, bdpl); err != nil {
+				return errors.Wrapf(err, "getting latest BOSHDeployment '%s'", key)
+			}
+		}
+		attempt++
+
+		current := bdpl.Status.UpdateWaitUntil
+		switch {
+		case !until.IsZero() && (current == nil || current.Time.Before(until)):
+			waitUntil := metav1.NewTime(until)
+			bdpl.Status.UpdateWaitUntil = &waitUntil
+		case until.IsZero() && current != nil && !current.Time.After(now):
+			bdpl.Status.UpdateWaitUntil = nil
+		default:
+			return nil
+		}
+		return client.Status().Update(ctx, bdpl)
+	})
+}
+
+// upgradeTimedOut returns true, if the rollout of the current desired
+// manifest has been running longer than the graceful upgrade timeout
+func upgradeTimedOut(bdpl *bdv1.BOSHDeployment, now time.Time) bool {
+	timeout := bdpl.Spec.GracefulUpgradeTimeout
+	startedAt := bdpl.Status.UpdateStartedAt
+	if timeout == nil || timeout.Duration <= 0 || startedAt == nil {
+		return false
+	}
+	return now.Sub(startedAt.Time) > timeout.Duration
+}
+
+// updateFailed stops the rollout by setting the 'UpdateFailed' phase. It
+// returns false, if the rollout was already stopped.
+func updateFailed(ctx context.Context, client crc.Client, bdpl *bdv1.BOSHDeployment, reason string) (bool, error) {
+	attempt := 0
+	var previous bdv1.Phase
+	err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		if attempt > 0 {
+			key := crc.ObjectKey{Name: bdpl.Name, Namespace: bdpl.Namespace}
+			if err := client.Get(ctx, key, bdpl); err != nil {
+				return errors.Wrapf(err, "getting latest BOSHDeployment '%s'", key)
+			}
+		}
+		attempt++
+
+		previous = bdpl.Status.Phase
+		if previous == bdv1.PhaseUpdateFailed {
+			return nil
+		}
+		bdpl.Status.Phase = bdv1.PhaseUpdateFailed
+		bdpl.Status.LastError = reason
+		bdpl.Status.UpdateWaitUntil = nil
+		return client.Status().Update(ctx, bdpl)
+	})
+	if err != nil || previous == bdv1.PhaseUpdateFailed {
+		return false, err
+	}
+
+	notifyPhaseTransition(ctx, bdpl, previous)
+	return true, nil
+}
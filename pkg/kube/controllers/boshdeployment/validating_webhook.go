@@ -6,18 +6,22 @@ import (
 	"strings"
 	"time"
 
+	"github.com/pkg/errors"
 	"go.uber.org/zap"
 
 	"k8s.io/api/admission/v1beta1"
 	admissionregistration "k8s.io/api/admissionregistration/v1beta1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/runtime/inject"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
+	"code.cloudfoundry.org/cf-operator/pkg/bosh/converter"
 	"code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
 	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+	"code.cloudfoundry.org/cf-operator/pkg/bosh/qjobs"
 	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/controllers/statefulset"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/util/boshdns"
@@ -145,6 +149,10 @@ func (v *Validator) OpsResourcesExist(ctx context.Context, specOpsResource []bdv
 						break
 					}
 				}
+
+			case bdv1.GitReference:
+				// git references are fetched from their repository, not from the namespace
+				found = true
 			}
 
 			missingResources[resourceName] = !found
@@ -177,14 +185,51 @@ func (v *Validator) Handle(ctx context.Context, req admission.Request) admission
 		}
 	}
 
+	err = validateJobDNS(boshDeployment.Spec.JobDNS)
+	if err != nil {
+		return admission.Response{
+			AdmissionResponse: v1beta1.AdmissionResponse{
+				Allowed: false,
+				Result: &metav1.Status{
+					Message: fmt.Sprintf("Failed to validate job DNS: %s", err.Error()),
+				},
+			},
+		}
+	}
+
+	err = validateDeploymentTags(boshDeployment.Spec.DeploymentTags)
+	if err != nil {
+		return admission.Response{
+			AdmissionResponse: v1beta1.AdmissionResponse{
+				Allowed: false,
+				Result: &metav1.Status{
+					Message: fmt.Sprintf("Failed to validate deployment tags: %s", err.Error()),
+				},
+			},
+		}
+	}
+
+	err = v.validateTriggerSecret(ctx, boshDeployment.Spec.VariableInterpolationTriggerSecret, boshDeployment.Namespace)
+	if err != nil {
+		return admission.Response{
+			AdmissionResponse: v1beta1.AdmissionResponse{
+				Allowed: false,
+				Result: &metav1.Status{
+					Message: fmt.Sprintf("Failed to validate variable interpolation trigger secret: %s", err.Error()),
+				},
+			},
+		}
+	}
+
 	v.log.Infof("Verifying dependencies for deployment '%s'", boshDeployment.Name)
-	withops := withops.NewResolver(
+	resolver := withops.NewResolver(
 		v.client,
 		func() withops.Interpolator { return withops.NewInterpolator() },
 		func(deploymentName string, m bdm.Manifest) (withops.DomainNameService, error) {
 			return boshdns.NewDNS(deploymentName, m)
 		},
-	)
+		secretNamer,
+	).WithCachedGitReferences()
 	resourceExist, msg := v.OpsResourcesExist(ctx, boshDeployment.Spec.Ops, boshDeployment.Namespace)
 	if !resourceExist {
 		return admission.Response{
@@ -197,8 +242,46 @@ func (v *Validator) Handle(ctx context.Context, req admission.Request) admission
 		}
 	}
 
+	err = v.validateConfigMapContent(ctx, boshDeployment.Spec, boshDeployment.Namespace)
+	if err != nil && environmentProfile(boshDeployment).LenientValidation {
+		v.log.Warnf("Deployment '%s' has sensitive configmap content: %s", boshDeployment.Name, err)
+	} else if err != nil {
+		return admission.Response{
+			AdmissionResponse: v1beta1.AdmissionResponse{
+				Allowed: false,
+				Result: &metav1.Status{
+					Message: fmt.Sprintf("Failed to validate configmap content: %s", err.Error()),
+				},
+			},
+		}
+	}
+
+	// The reconcile preview timeout bounds the resolution and the preview
+	resolveCtx := ctx
+	if reconcilePreviewTimeout > 0 {
+		var cancel context.CancelFunc
+		resolveCtx, cancel = context.WithTimeout(ctx, reconcilePreviewTimeout)
+		defer cancel()
+	}
+
 	v.log.Infof("Resolving deployment '%s'", boshDeployment.Name)
-	manifest, _, err := withops.ManifestDetailed(boshDeployment, boshDeployment.GetNamespace())
+	manifest, _, err := resolver.WithContext(resolveCtx).ManifestDetailed(boshDeployment, boshDeployment.GetNamespace())
+	if withops.IsGitReferenceNotCached(err) {
+		v.log.Infof("Skipping manifest validation of deployment '%s', its git references are fetched by the reconciler: %s", boshDeployment.Name, err)
+		return admission.Response{
+			AdmissionResponse: v1beta1.AdmissionResponse{
+				Allowed: true,
+			},
+		}
+	}
+	if err != nil && resolveCtx.Err() != nil {
+		v.log.Warnf("Resolving deployment '%s' didn't complete within %s, admitting it: %s", boshDeployment.Name, reconcilePreviewTimeout, err)
+		return admission.Response{
+			AdmissionResponse: v1beta1.AdmissionResponse{
+				Allowed: true,
+			},
+		}
+	}
 	if err != nil {
 		return admission.Response{
 			AdmissionResponse: v1beta1.AdmissionResponse{
@@ -220,6 +303,54 @@ func (v *Validator) Handle(ctx context.Context, req admission.Request) admission
 			},
 		}
 	}
+	err = validateStemcells(*manifest)
+	if err != nil {
+		return admission.Response{
+			AdmissionResponse: v1beta1.AdmissionResponse{
+				Allowed: false,
+				Result: &metav1.Status{
+					Message: fmt.Sprintf("Failed to validate stemcells: %s", err.Error()),
+				},
+			},
+		}
+	}
+	err = v.validateTenantQuota(ctx, boshDeployment.Namespace, manifest)
+	if err != nil {
+		return admission.Response{
+			AdmissionResponse: v1beta1.AdmissionResponse{
+				Allowed: false,
+				Result: &metav1.Status{
+					Message: fmt.Sprintf("Failed to validate tenant quota: %s", err.Error()),
+				},
+			},
+		}
+	}
+
+	if reconcilePreviewTimeout > 0 {
+		v.log.Infof("Previewing reconcile of deployment '%s'", boshDeployment.Name)
+		preview := reconcilePreview{
+			client:     v.client,
+			withops:    resolver.WithContext(resolveCtx),
+			jobFactory: qjobs.NewJobFactory(v.config.Namespace, secretNamer),
+			converter:  converter.NewVariablesConverter(v.config.Namespace, secretNamer),
+		}
+		err := preview.Run(resolveCtx, boshDeployment, manifest)
+		if resolveCtx.Err() != nil {
+			v.log.Warnf("Reconcile preview of deployment '%s' didn't complete within %s, admitting it", boshDeployment.Name, reconcilePreviewTimeout)
+		} else if secretName, ok := withops.MissingSecret(err); ok {
+			v.log.Infof("Skipping reconcile preview of deployment '%s', the reconciler waits for secret '%s': %s", boshDeployment.Name, secretName, err)
+		} else if err != nil {
+			return admission.Response{
+				AdmissionResponse: v1beta1.AdmissionResponse{
+					Allowed: false,
+					Result: &metav1.Status{
+						Message: fmt.Sprintf("Failed to preview reconcile: %s", err.Error()),
+					},
+				},
+			}
+		}
+	}
+
 	return admission.Response{
 		AdmissionResponse: v1beta1.AdmissionResponse{
 			Allowed: true,
@@ -238,6 +369,57 @@ func validateUpdateBlock(manifest manifest.Manifest) error {
 	return err
 }
 
+// validateStemcells checks that instance groups have a stemcell, if one of
+// their jobs' releases doesn't specify its own stemcell. Their release
+// images can't be resolved otherwise.
+func validateStemcells(m manifest.Manifest) error {
+	releases := map[string]*manifest.Release{}
+	for _, release := range m.Releases {
+		releases[release.Name] = release
+	}
+
+	for _, ig := range m.InstanceGroups {
+		for _, job := range ig.Jobs {
+			release, ok := releases[job.Release]
+			if !ok || release.Stemcell != nil {
+				continue
+			}
+			if _, err := m.GetStemcellForIG(ig.Name); err != nil {
+				return errors.Wrapf(err, "job '%s' of release '%s' needs a stemcell", job.Name, release.Name)
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// validateTriggerSecret checks that the variable interpolation trigger
+// secret exists and contains a manifest, the job's pod couldn't start or
+// interpolate otherwise
+func (v *Validator) validateTriggerSecret(ctx context.Context, name string, namespace string) error {
+	if name == "" {
+		return nil
+	}
+
+	secret := &corev1.Secret{}
+	err := v.client.Get(ctx, client.ObjectKey{Name: name, Namespace: namespace}, secret)
+	if apierrors.IsNotFound(err) {
+		return errors.Errorf("secret '%s/%s' doesn't exist", namespace, name)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "getting secret '%s/%s'", namespace, name)
+	}
+
+	data, err := manifest.SecretData(secret.Data)
+	if err != nil {
+		return errors.Wrapf(err, "reading manifest of secret '%s/%s'", namespace, name)
+	}
+	if data == nil {
+		return errors.Errorf("secret '%s/%s' doesn't contain key %s or %s", namespace, name, manifest.DesiredManifestKeyName, manifest.CompressedManifestKeyName)
+	}
+	return nil
+}
+
 // Validator implements inject.Client.
 // A client will be automatically injected.
 var _ inject.Client = &Validator{}
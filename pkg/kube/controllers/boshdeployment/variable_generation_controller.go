@@ -0,0 +1,81 @@
+package boshdeployment
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	qsv1a1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/quarkssecret/v1alpha1"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/backpressure"
+	"code.cloudfoundry.org/quarks-utils/pkg/config"
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+// AddVariableGeneration creates a new controller, which counts the generated
+// QuarksSecrets of the BOSHDeployments' explicit variables in their status.
+func AddVariableGeneration(ctx context.Context, config *config.Config, mgr manager.Manager) error {
+	ctx = ctxlog.NewContextWithRecorder(ctx, "variable-generation-reconciler", mgr.GetEventRecorderFor("variable-generation-recorder"))
+	r := NewVariableGenerationReconciler(ctx, config, mgr)
+
+	// Create a new controller
+	c, err := controller.New("variable-generation-controller", mgr, controller.Options{
+		Reconciler:              backpressure.NewReconciler(ctx, r),
+		MaxConcurrentReconciles: config.MaxBoshDeploymentWorkers,
+	})
+	if err != nil {
+		return errors.Wrap(err, "Adding variable generation controller to manager failed.")
+	}
+
+	// Watch for QuarksSecrets of variables, which are created, deleted or generated
+	p := predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return isVariableQuarksSecret(e.Object.(*qsv1a1.QuarksSecret))
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return isVariableQuarksSecret(e.Object.(*qsv1a1.QuarksSecret))
+		},
+		GenericFunc: func(e event.GenericEvent) bool { return false },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			o := e.ObjectOld.(*qsv1a1.QuarksSecret)
+			n := e.ObjectNew.(*qsv1a1.QuarksSecret)
+			if !isVariableQuarksSecret(n) || o.Status.Generated == n.Status.Generated {
+				return false
+			}
+
+			ctxlog.NewPredicateEvent(n).Debug(
+				ctx, e.MetaNew, "qsv1a1.QuarksSecret",
+				fmt.Sprintf("Update predicate passed for '%s', generated changed to %t", e.MetaNew.GetName(), n.Status.Generated),
+			)
+			return true
+		},
+	}
+
+	err = c.Watch(&source.Kind{Type: &qsv1a1.QuarksSecret{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(a handler.MapObject) []reconcile.Request {
+			request := reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Namespace: a.Meta.GetNamespace(),
+					Name:      a.Meta.GetLabels()[bdv1.LabelDeploymentName],
+				},
+			}
+			ctxlog.NewMappingEvent(a.Object).Debug(ctx, request, "BOSHDeployment", a.Meta.GetName(), "QuarksSecretOfVariable")
+
+			return []reconcile.Request{request}
+		}),
+	}, p)
+	if err != nil {
+		return errors.Wrapf(err, "Watching quarks secrets failed in variable generation controller.")
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package boshdeployment
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"code.cloudfoundry.org/cf-operator/pkg/bosh/converter"
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	qsv1a1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/quarkssecret/v1alpha1"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/audit"
+	"code.cloudfoundry.org/quarks-utils/pkg/config"
+	log "code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+// isVariableQuarksSecret returns true, if the QuarksSecret was created for an
+// explicit variable of a BOSHDeployment
+func isVariableQuarksSecret(qsec *qsv1a1.QuarksSecret) bool {
+	labels := qsec.GetLabels()
+	return labels[bdv1.LabelDeploymentName] != "" && labels[converter.LabelVariableName] != ""
+}
+
+// countGeneratedVariables returns the number of generated QuarksSecrets of
+// the deployment's explicit variables
+func countGeneratedVariables(ctx context.Context, client crc.Client, instance *bdv1.BOSHDeployment) (int, error) {
+	list := &qsv1a1.QuarksSecretList{}
+	err := client.List(ctx, list,
+		crc.InNamespace(instance.Namespace),
+		crc.MatchingLabels{bdv1.LabelDeploymentName: instance.Name},
+	)
+	if err != nil {
+		return 0, errors.Wrapf(err, "listing quarks secrets of BOSHDeployment '%s/%s'", instance.Namespace, instance.Name)
+	}
+
+	complete := 0
+	for i := range list.Items {
+		if isVariableQuarksSecret(&list.Items[i]) && list.Items[i].Status.Generated {
+			complete++
+		}
+	}
+	return complete, nil
+}
+
+// variableGenerationPhase returns the phase of an applied deployment, which
+// is 'GeneratingVariables' until all QuarksSecrets of its variables are
+// generated. Other phases are returned unchanged.
+func variableGenerationPhase(status bdv1.BOSHDeploymentStatus) bdv1.Phase {
+	switch {
+	case status.Phase == bdv1.PhaseApplied && status.VariableGenerationComplete < status.VariableGenerationTotal:
+		return bdv1.PhaseGeneratingVariables
+	case status.Phase == bdv1.PhaseGeneratingVariables && status.VariableGenerationComplete >= status.VariableGenerationTotal:
+		return bdv1.PhaseApplied
+	}
+	return status.Phase
+}
+
+// NewVariableGenerationReconciler returns a new reconcile.Reconciler
+func NewVariableGenerationReconciler(ctx context.Context, config *config.Config, mgr manager.Manager) reconcile.Reconciler {
+	return &ReconcileVariableGeneration{
+		ctx:    ctx,
+		config: config,
+		client: audit.NewClient(mgr.GetClient()),
+	}
+}
+
+// ReconcileVariableGeneration reconciles the variable generation progress in
+// the status of BOSHDeployments
+type ReconcileVariableGeneration struct {
+	ctx    context.Context
+	config *config.Config
+	client crc.Client
+}
+
+// Reconcile counts the generated QuarksSecrets of the BOSHDeployment's
+// variables and updates its status and phase. The total is set by the
+// BOSHDeployment reconciler, when it creates the QuarksSecrets.
+func (r *ReconcileVariableGeneration) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	ctx, cancel := context.WithTimeout(r.ctx, r.config.CtxTimeOut)
+	defer cancel()
+	ctx = audit.NewContext(ctx, request.NamespacedName)
+
+	log.Debugf(ctx, "Reconciling variable generation of BOSHDeployment '%s'", request.NamespacedName)
+	instance := &bdv1.BOSHDeployment{}
+	err := r.client.Get(ctx, request.NamespacedName, instance)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			log.Debug(ctx, "Skip reconcile: BOSHDeployment not found")
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, log.WithEvent(instance, "GetBOSHDeploymentError").Errorf(ctx, "failed to get BOSHDeployment '%s': %v", request.NamespacedName, err)
+	}
+
+	complete, err := countGeneratedVariables(ctx, r.client, instance)
+	if err != nil {
+		return reconcile.Result{}, log.WithEvent(instance, "VariableGenerationError").Errorf(ctx, "failed to count generated variables: %v", err)
+	}
+	if complete == instance.Status.VariableGenerationComplete && variableGenerationPhase(instance.Status) == instance.Status.Phase {
+		return reconcile.Result{}, nil
+	}
+
+	err = updateDeploymentStatus(ctx, r.client, instance, func(bdpl *bdv1.BOSHDeployment) {
+		bdpl.Status.VariableGenerationComplete = complete
+		bdpl.Status.Phase = variableGenerationPhase(bdpl.Status)
+		updateVariablesGeneratedCondition(bdpl)
+	})
+	if err != nil {
+		return reconcile.Result{}, log.WithEvent(instance, "UpdateError").Errorf(ctx, "failed to update variable generation status of BOSHDeployment '%s': %v", request.NamespacedName, err)
+	}
+
+	log.Debugf(ctx, "Generated %d of %d variables of BOSHDeployment '%s'", complete, instance.Status.VariableGenerationTotal, request.NamespacedName)
+	return reconcile.Result{}, nil
+}
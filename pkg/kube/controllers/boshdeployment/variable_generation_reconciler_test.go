@@ -0,0 +1,192 @@
+package boshdeployment_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"code.cloudfoundry.org/cf-operator/pkg/bosh/converter"
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	qsv1a1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/quarkssecret/v1alpha1"
+	cfd "code.cloudfoundry.org/cf-operator/pkg/kube/controllers/boshdeployment"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/controllers/fakes"
+	cfcfg "code.cloudfoundry.org/quarks-utils/pkg/config"
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+	helper "code.cloudfoundry.org/quarks-utils/testing/testhelper"
+)
+
+var _ = Describe("ReconcileVariableGeneration", func() {
+	var (
+		manager      *fakes.FakeManager
+		client       *fakes.FakeClient
+		statusWriter *fakes.FakeStatusWriter
+		reconciler   reconcile.Reconciler
+		request      reconcile.Request
+		instance     *bdv1.BOSHDeployment
+		qsecs        []qsv1a1.QuarksSecret
+	)
+
+	variable := func(name string, generated bool) qsv1a1.QuarksSecret {
+		return qsv1a1.QuarksSecret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "foo.var-" + name,
+				Namespace: "default",
+				Labels: map[string]string{
+					bdv1.LabelDeploymentName:    "foo",
+					converter.LabelVariableName: name,
+				},
+			},
+			Status: qsv1a1.QuarksSecretStatus{Generated: generated},
+		}
+	}
+
+	BeforeEach(func() {
+		manager = &fakes.FakeManager{}
+		request = reconcile.Request{NamespacedName: types.NamespacedName{Name: "foo", Namespace: "default"}}
+		instance = &bdv1.BOSHDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+			Status: bdv1.BOSHDeploymentStatus{
+				Phase:                   bdv1.PhaseGeneratingVariables,
+				VariableGenerationTotal: 3,
+			},
+		}
+		qsecs = []qsv1a1.QuarksSecret{variable("a", true), variable("b", false), variable("c", false)}
+
+		statusWriter = &fakes.FakeStatusWriter{}
+		client = &fakes.FakeClient{}
+		client.StatusCalls(func() crc.StatusWriter { return statusWriter })
+		client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+			switch object := object.(type) {
+			case *bdv1.BOSHDeployment:
+				if instance == nil {
+					return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+				}
+				instance.DeepCopyInto(object)
+			}
+			return nil
+		})
+		client.ListCalls(func(context context.Context, object runtime.Object, _ ...crc.ListOption) error {
+			switch object := object.(type) {
+			case *qsv1a1.QuarksSecretList:
+				object.Items = qsecs
+			}
+			return nil
+		})
+		manager.GetClientReturns(client)
+	})
+
+	JustBeforeEach(func() {
+		_, log := helper.NewTestLogger()
+		ctx := ctxlog.NewParentContext(log)
+		ctx = ctxlog.NewContextWithRecorder(ctx, "TestRecorder", record.NewFakeRecorder(20))
+		config := &cfcfg.Config{CtxTimeOut: 10 * time.Second}
+		reconciler = cfd.NewVariableGenerationReconciler(ctx, config, manager)
+	})
+
+	It("counts the generated variables", func() {
+		_, err := reconciler.Reconcile(request)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(statusWriter.UpdateCallCount()).To(Equal(1))
+		_, object, _ := statusWriter.UpdateArgsForCall(0)
+		status := object.(*bdv1.BOSHDeployment).Status
+		Expect(status.VariableGenerationComplete).To(Equal(1))
+		Expect(status.VariableGenerationTotal).To(Equal(3))
+		Expect(status.Phase).To(Equal(bdv1.PhaseGeneratingVariables))
+	})
+
+	It("ignores QuarksSecrets, which are not variables", func() {
+		other := variable("d", true)
+		delete(other.Labels, converter.LabelVariableName)
+		qsecs = append(qsecs, other)
+
+		_, err := reconciler.Reconcile(request)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, object, _ := statusWriter.UpdateArgsForCall(0)
+		Expect(object.(*bdv1.BOSHDeployment).Status.VariableGenerationComplete).To(Equal(1))
+	})
+
+	It("sets the phase to applied, once all variables are generated", func() {
+		qsecs = []qsv1a1.QuarksSecret{variable("a", true), variable("b", true), variable("c", true)}
+
+		_, err := reconciler.Reconcile(request)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, object, _ := statusWriter.UpdateArgsForCall(0)
+		status := object.(*bdv1.BOSHDeployment).Status
+		Expect(status.VariableGenerationComplete).To(Equal(3))
+		Expect(status.Phase).To(Equal(bdv1.PhaseApplied))
+	})
+
+	It("doesn't change other phases", func() {
+		instance.Status.Phase = bdv1.PhaseDegraded
+
+		_, err := reconciler.Reconcile(request)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, object, _ := statusWriter.UpdateArgsForCall(0)
+		Expect(object.(*bdv1.BOSHDeployment).Status.Phase).To(Equal(bdv1.PhaseDegraded))
+	})
+
+	It("updates the VariablesGenerated condition with the progress", func() {
+		qsecs = []qsv1a1.QuarksSecret{variable("a", true), variable("b", true), variable("c", true)}
+		instance.Status.Conditions = []bdv1.Condition{{
+			Type:    bdv1.ConditionVariablesGenerated,
+			Status:  metav1.ConditionFalse,
+			Reason:  "GeneratingVariables",
+			Message: "0 of 3 variables generated",
+		}}
+
+		_, err := reconciler.Reconcile(request)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, object, _ := statusWriter.UpdateArgsForCall(0)
+		condition := bdv1.FindStatusCondition(object.(*bdv1.BOSHDeployment).Status.Conditions, bdv1.ConditionVariablesGenerated)
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.Reason).To(Equal("VariablesGenerated"))
+		Expect(condition.Message).To(Equal("3 of 3 variables generated"))
+	})
+
+	It("keeps a VariablesGenerated condition set by a failed reconcile", func() {
+		instance.Status.Conditions = []bdv1.Condition{{
+			Type:   bdv1.ConditionVariablesGenerated,
+			Status: metav1.ConditionFalse,
+			Reason: "VariablesMappingError",
+		}}
+
+		_, err := reconciler.Reconcile(request)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, object, _ := statusWriter.UpdateArgsForCall(0)
+		condition := bdv1.FindStatusCondition(object.(*bdv1.BOSHDeployment).Status.Conditions, bdv1.ConditionVariablesGenerated)
+		Expect(condition.Reason).To(Equal("VariablesMappingError"))
+	})
+
+	It("doesn't update an unchanged status", func() {
+		instance.Status.VariableGenerationComplete = 1
+
+		_, err := reconciler.Reconcile(request)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(statusWriter.UpdateCallCount()).To(Equal(0))
+	})
+
+	It("skips deleted deployments", func() {
+		instance = nil
+
+		_, err := reconciler.Reconcile(request)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(statusWriter.UpdateCallCount()).To(Equal(0))
+	})
+})
@@ -0,0 +1,18 @@
+package boshdeployment
+
+import (
+	"github.com/pkg/errors"
+)
+
+// variableWorkers is the number of QuarksSecrets, which are created or
+// updated concurrently for the variables of a BOSHDeployment
+var variableWorkers = 1
+
+// SetVariableWorkers initializes the package scoped variableWorkers variable.
+func SetVariableWorkers(workers int) error {
+	if workers < 1 {
+		return errors.Errorf("invalid number of variable workers '%d', must be at least 1", workers)
+	}
+	variableWorkers = workers
+	return nil
+}
@@ -0,0 +1,75 @@
+package boshdeployment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"code.cloudfoundry.org/cf-operator/pkg/bosh/converter"
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	qsv1a1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/quarkssecret/v1alpha1"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/mutate"
+	log "code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+// VariablesMappingKey is the config map key holding the variables mapping as JSON
+const VariablesMappingKey = "variables.json"
+
+// VariableMapping locates the kubernetes resources of a BOSH variable
+type VariableMapping struct {
+	QuarksSecret string `json:"quarksSecret"`
+	Secret       string `json:"secret"`
+}
+
+// VariablesMappingConfigMapName returns the name of the config map, which maps
+// the deployment's BOSH variables to their secrets
+func VariablesMappingConfigMapName(deploymentName string) string {
+	return fmt.Sprintf("%s-variables", deploymentName)
+}
+
+// createVariablesMapping creates or updates the config map, which maps each
+// BOSH variable name to its QuarksSecret and secret name. It contains no secret values.
+func (r *ReconcileBOSHDeployment) createVariablesMapping(ctx context.Context, instance *bdv1.BOSHDeployment, secrets []qsv1a1.QuarksSecret) error {
+	mapping := map[string]VariableMapping{}
+	for _, s := range secrets {
+		mapping[s.GetLabels()[converter.LabelVariableName]] = VariableMapping{
+			QuarksSecret: s.Name,
+			Secret:       s.Spec.SecretName,
+		}
+	}
+
+	data, err := json.Marshal(mapping)
+	if err != nil {
+		return errors.Wrapf(err, "marshalling variables mapping for '%s'", instance.Name)
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      VariablesMappingConfigMapName(instance.Name),
+			Namespace: instance.Namespace,
+			Labels: map[string]string{
+				bdv1.LabelDeploymentName: instance.Name,
+			},
+		},
+		Data: map[string]string{
+			VariablesMappingKey: string(data),
+		},
+	}
+	if err := r.setReference(instance, cm, r.scheme); err != nil {
+		return errors.Wrapf(err, "failed to set ownerReference for ConfigMap '%s'", cm.Name)
+	}
+
+	op, err := controllerutil.CreateOrUpdate(ctx, r.client, cm, withOwnerReference(ctx, instance, cm, r.setReference, r.scheme, mutate.ConfigMapMutateFn(cm)))
+	if err != nil {
+		return errors.Wrapf(err, "creating or updating ConfigMap '%s'", cm.Name)
+	}
+	log.Debugf(ctx, "Variables mapping ConfigMap '%s' has been %s", cm.Name, op)
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package boshdeployment
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	log "code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+// secretWaitRequeueAfter is the delay, after which a deployment waiting for
+// a missing secret is reconciled again, in case the secret watch misses it
+const secretWaitRequeueAfter = time.Minute
+
+// waitForSecret pauses the reconcile until the missing secret is created,
+// e.g. by a separate bootstrap process. The secret is added to the watched
+// secrets, so its creation triggers the next reconcile. The wait is neither
+// a failure nor a reconcile, so it doesn't start a meltdown and the failure
+// policy doesn't apply.
+func (r *ReconcileBOSHDeployment) waitForSecret(ctx context.Context, instance *bdv1.BOSHDeployment, secretName string, cause error) (reconcile.Result, error) {
+	r.watchedSecretsIndex.add(types.NamespacedName{Namespace: instance.Namespace, Name: instance.Name}, secretName)
+	setCondition(ctx, bdv1.ConditionManifestResolved, metav1.ConditionFalse, "WaitingForSecret", fmt.Sprintf("waiting for secret '%s'", secretName))
+
+	if instance.Status.Phase == bdv1.PhaseWaitingForSecret && instance.Status.WaitingForSecret == secretName {
+		log.Debugf(ctx, "BOSHDeployment '%s/%s' is still waiting for secret '%s'", instance.Namespace, instance.Name, secretName)
+		return reconcile.Result{RequeueAfter: secretWaitRequeueAfter}, nil
+	}
+
+	log.WithEvent(instance, "WaitingForSecret").Infof(ctx, "BOSHDeployment '%s/%s' waits for secret '%s': %v", instance.Namespace, instance.Name, secretName, cause)
+	err := r.updateStatus(ctx, instance, func(bdpl *bdv1.BOSHDeployment) {
+		bdpl.Status.Phase = bdv1.PhaseWaitingForSecret
+		bdpl.Status.WaitingForSecret = secretName
+	})
+	if err != nil {
+		log.WithEvent(instance, "UpdateError").Errorf(ctx, "failed to update waiting phase on bdpl '%s' (%v): %s", instance.Name, instance.ResourceVersion, err)
+		return reconcile.Result{Requeue: true}, nil
+	}
+
+	return reconcile.Result{RequeueAfter: secretWaitRequeueAfter}, nil
+}
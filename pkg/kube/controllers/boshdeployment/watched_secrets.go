@@ -0,0 +1,163 @@
+package boshdeployment
+
+import (
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	vss "code.cloudfoundry.org/quarks-utils/pkg/versionedsecretstore"
+)
+
+// watchedSecretsIndex is an in-memory inverse index from secrets to the
+// BOSHDeployments, which reference them as manifest, ops file, cloud config,
+// implicit variable or variable interpolation trigger. It is populated by each reconcile, once it resolved the
+// manifest or failed to, so the secret watch can map events to deployments
+// without listing and resolving all of them.
+type watchedSecretsIndex struct {
+	// secrets maps the namespaced name of a secret to a sets.String of
+	// deployment names. Sets are replaced, never modified, so readers
+	// don't need to lock.
+	secrets sync.Map
+
+	// mu serializes writers and guards deployments
+	mu sync.Mutex
+	// deployments maps each deployment to the names of the secrets it watches
+	deployments map[types.NamespacedName]sets.String
+}
+
+// newWatchedSecretsIndex returns an empty index
+func newWatchedSecretsIndex() *watchedSecretsIndex {
+	return &watchedSecretsIndex{
+		deployments: map[types.NamespacedName]sets.String{},
+	}
+}
+
+// watchedSecretNames returns the names of the secrets a deployment watches
+func watchedSecretNames(instance *bdv1.BOSHDeployment, implicitVars []string) sets.String {
+	secretNames := sets.NewString(implicitVars...)
+	if instance.Spec.VariableInterpolationTriggerSecret != "" {
+		secretNames.Insert(instance.Spec.VariableInterpolationTriggerSecret)
+	}
+	refs := append([]bdv1.ResourceReference{instance.Spec.Manifest}, instance.Spec.Ops...)
+	if instance.Spec.CloudConfig != nil {
+		refs = append(refs, *instance.Spec.CloudConfig)
+	}
+	for _, ref := range refs {
+		if ref.Type == bdv1.SecretReference {
+			secretNames.Insert(ref.Name)
+		}
+	}
+	return secretNames
+}
+
+// watchSecrets updates the secrets the deployment watches, after its manifest
+// was resolved. Changes of the manifest, ops, implicit variable and trigger
+// secrets trigger the next reconcile. If the resolution failed, e.g. because of a
+// broken ops file, the implicit variables aren't known, so the previously
+// watched secrets are kept in addition to the referenced ones. Fixing any of
+// them reconciles the deployment again.
+func (r *ReconcileBOSHDeployment) watchSecrets(deployment types.NamespacedName, instance *bdv1.BOSHDeployment, implicitVars []string, resolveErr error) {
+	secretNames := watchedSecretNames(instance, implicitVars)
+	if resolveErr != nil {
+		secretNames = secretNames.Union(r.watchedSecretsIndex.secretNames(deployment))
+	}
+	r.watchedSecretsIndex.update(deployment, secretNames)
+}
+
+// secretNames returns a copy of the names of the secrets the deployment watches
+func (i *watchedSecretsIndex) secretNames(deployment types.NamespacedName) sets.String {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return sets.NewString(i.deployments[deployment].UnsortedList()...)
+}
+
+// update replaces the secrets the deployment watches
+func (i *watchedSecretsIndex) update(deployment types.NamespacedName, secretNames sets.String) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	previous := i.deployments[deployment]
+	for _, name := range previous.Difference(secretNames).UnsortedList() {
+		i.removeWatcher(types.NamespacedName{Namespace: deployment.Namespace, Name: name}, deployment.Name)
+	}
+	for _, name := range secretNames.Difference(previous).UnsortedList() {
+		i.addWatcher(types.NamespacedName{Namespace: deployment.Namespace, Name: name}, deployment.Name)
+	}
+
+	if secretNames.Len() == 0 {
+		delete(i.deployments, deployment)
+		return
+	}
+	i.deployments[deployment] = sets.NewString(secretNames.UnsortedList()...)
+}
+
+// add adds a secret to the secrets the deployment watches
+func (i *watchedSecretsIndex) add(deployment types.NamespacedName, secretName string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	secretNames, ok := i.deployments[deployment]
+	if !ok {
+		secretNames = sets.NewString()
+		i.deployments[deployment] = secretNames
+	}
+	if secretNames.Has(secretName) {
+		return
+	}
+	secretNames.Insert(secretName)
+	i.addWatcher(types.NamespacedName{Namespace: deployment.Namespace, Name: secretName}, deployment.Name)
+}
+
+// remove drops a deployment, e.g. after it was deleted
+func (i *watchedSecretsIndex) remove(deployment types.NamespacedName) {
+	i.update(deployment, sets.NewString())
+}
+
+// reconciles returns a request for each deployment, which watches the
+// secret. Versioned secrets are also matched by their unversioned name.
+func (i *watchedSecretsIndex) reconciles(secret *corev1.Secret) []reconcile.Request {
+	deployments := i.watchers(types.NamespacedName{Namespace: secret.Namespace, Name: secret.Name})
+	if vss.IsVersionedSecret(*secret) {
+		if prefix := vss.NamePrefix(secret.Name); prefix != "" {
+			deployments = deployments.Union(i.watchers(types.NamespacedName{Namespace: secret.Namespace, Name: prefix}))
+		}
+	}
+
+	reconciles := make([]reconcile.Request, 0, deployments.Len())
+	for _, name := range deployments.List() {
+		reconciles = append(reconciles, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: secret.Namespace, Name: name},
+		})
+	}
+	return reconciles
+}
+
+// watchers returns the names of the deployments watching the secret
+func (i *watchedSecretsIndex) watchers(secret types.NamespacedName) sets.String {
+	if deployments, ok := i.secrets.Load(secret); ok {
+		return deployments.(sets.String)
+	}
+	return sets.NewString()
+}
+
+// addWatcher stores a copy of the secret's set with the deployment added, must be called with mu held
+func (i *watchedSecretsIndex) addWatcher(secret types.NamespacedName, deployment string) {
+	deployments := sets.NewString(i.watchers(secret).UnsortedList()...)
+	deployments.Insert(deployment)
+	i.secrets.Store(secret, deployments)
+}
+
+// removeWatcher stores a copy of the secret's set without the deployment, must be called with mu held
+func (i *watchedSecretsIndex) removeWatcher(secret types.NamespacedName, deployment string) {
+	deployments := sets.NewString(i.watchers(secret).UnsortedList()...)
+	deployments.Delete(deployment)
+	if deployments.Len() == 0 {
+		i.secrets.Delete(secret)
+		return
+	}
+	i.secrets.Store(secret, deployments)
+}
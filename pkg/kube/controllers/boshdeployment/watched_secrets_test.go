@@ -0,0 +1,239 @@
+package boshdeployment
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-utils/pkg/config"
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+	vss "code.cloudfoundry.org/quarks-utils/pkg/versionedsecretstore"
+	helper "code.cloudfoundry.org/quarks-utils/testing/testhelper"
+)
+
+// resolveErrorWithOps fails to resolve any manifest with err
+type resolveErrorWithOps struct {
+	err error
+}
+
+func (w *resolveErrorWithOps) Manifest(*bdv1.BOSHDeployment, string) (*bdm.Manifest, []string, error) {
+	return nil, nil, w.err
+}
+
+func (w *resolveErrorWithOps) NoOpOps(*bdv1.BOSHDeployment, string) ([]string, error) {
+	return nil, nil
+}
+
+func (w *resolveErrorWithOps) CloudConfig(*bdv1.BOSHDeployment, string) (*bdm.CloudConfig, error) {
+	return nil, nil
+}
+
+var _ = Describe("watchedSecretsIndex", func() {
+	var (
+		index *watchedSecretsIndex
+		foo   types.NamespacedName
+		bar   types.NamespacedName
+	)
+
+	secret := func(namespace, name string) *corev1.Secret {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+	}
+
+	request := func(namespace, name string) reconcile.Request {
+		return reconcile.Request{NamespacedName: types.NamespacedName{Namespace: namespace, Name: name}}
+	}
+
+	BeforeEach(func() {
+		index = newWatchedSecretsIndex()
+		foo = types.NamespacedName{Namespace: "default", Name: "foo"}
+		bar = types.NamespacedName{Namespace: "default", Name: "bar"}
+	})
+
+	It("returns no reconciles for unknown secrets", func() {
+		Expect(index.reconciles(secret("default", "ops"))).To(BeEmpty())
+	})
+
+	It("returns a reconcile for each deployment watching the secret", func() {
+		index.update(foo, sets.NewString("ops", "manifest"))
+		index.update(bar, sets.NewString("ops"))
+
+		Expect(index.reconciles(secret("default", "ops"))).To(Equal([]reconcile.Request{
+			request("default", "bar"),
+			request("default", "foo"),
+		}))
+		Expect(index.reconciles(secret("default", "manifest"))).To(Equal([]reconcile.Request{request("default", "foo")}))
+		Expect(index.reconciles(secret("other", "ops"))).To(BeEmpty())
+	})
+
+	It("forgets secrets a deployment no longer references", func() {
+		index.update(foo, sets.NewString("ops", "manifest"))
+		index.update(foo, sets.NewString("manifest"))
+
+		Expect(index.reconciles(secret("default", "ops"))).To(BeEmpty())
+		Expect(index.reconciles(secret("default", "manifest"))).To(Equal([]reconcile.Request{request("default", "foo")}))
+	})
+
+	It("adds secrets to the ones a deployment watches", func() {
+		index.update(foo, sets.NewString("ops"))
+		index.add(foo, "bootstrap")
+		index.add(foo, "bootstrap")
+
+		Expect(index.reconciles(secret("default", "ops"))).To(Equal([]reconcile.Request{request("default", "foo")}))
+		Expect(index.reconciles(secret("default", "bootstrap"))).To(Equal([]reconcile.Request{request("default", "foo")}))
+
+		index.update(foo, sets.NewString("ops"))
+		Expect(index.reconciles(secret("default", "bootstrap"))).To(BeEmpty())
+	})
+
+	It("forgets removed deployments", func() {
+		index.update(foo, sets.NewString("ops"))
+		index.update(bar, sets.NewString("ops"))
+		index.remove(foo)
+
+		Expect(index.reconciles(secret("default", "ops"))).To(Equal([]reconcile.Request{request("default", "bar")}))
+	})
+
+	It("matches versioned secrets by their unversioned name", func() {
+		index.update(foo, sets.NewString("ops"))
+
+		versioned := secret("default", "ops-v2")
+		versioned.Labels = map[string]string{vss.LabelSecretKind: vss.VersionSecretKind}
+		Expect(index.reconciles(versioned)).To(Equal([]reconcile.Request{request("default", "foo")}))
+	})
+
+	It("is consistent under concurrent updates", func() {
+		wg := sync.WaitGroup{}
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				deployment := types.NamespacedName{Namespace: "default", Name: fmt.Sprintf("deployment-%d", i)}
+				index.update(deployment, sets.NewString("ops", fmt.Sprintf("manifest-%d", i)))
+				index.reconciles(secret("default", "ops"))
+			}(i)
+		}
+		wg.Wait()
+
+		Expect(index.reconciles(secret("default", "ops"))).To(HaveLen(20))
+		Expect(index.reconciles(secret("default", "manifest-7"))).To(Equal([]reconcile.Request{request("default", "deployment-7")}))
+	})
+
+	Describe("watchedSecretNames", func() {
+		It("contains the secret references and implicit variables of the deployment", func() {
+			instance := &bdv1.BOSHDeployment{
+				Spec: bdv1.BOSHDeploymentSpec{
+					Manifest: bdv1.ResourceReference{Name: "manifest", Type: bdv1.SecretReference},
+					Ops: []bdv1.ResourceReference{
+						{Name: "ops", Type: bdv1.SecretReference},
+						{Name: "ops-configmap", Type: bdv1.ConfigMapReference},
+					},
+				},
+			}
+
+			Expect(watchedSecretNames(instance, []string{"foo.var-system-domain"}).List()).To(Equal([]string{
+				"foo.var-system-domain", "manifest", "ops",
+			}))
+		})
+
+		It("contains the variable interpolation trigger secret", func() {
+			instance := &bdv1.BOSHDeployment{
+				Spec: bdv1.BOSHDeploymentSpec{
+					Manifest:                           bdv1.ResourceReference{Name: "manifest", Type: bdv1.ConfigMapReference},
+					VariableInterpolationTriggerSecret: "first-pass",
+				},
+			}
+
+			Expect(watchedSecretNames(instance, nil).List()).To(Equal([]string{"first-pass"}))
+		})
+	})
+
+	Describe("watchSecrets", func() {
+		var (
+			r        *ReconcileBOSHDeployment
+			instance *bdv1.BOSHDeployment
+		)
+
+		BeforeEach(func() {
+			r = &ReconcileBOSHDeployment{watchedSecretsIndex: index}
+			instance = &bdv1.BOSHDeployment{
+				Spec: bdv1.BOSHDeploymentSpec{
+					Manifest: bdv1.ResourceReference{Name: "manifest", Type: bdv1.SecretReference},
+					Ops:      []bdv1.ResourceReference{{Name: "ops", Type: bdv1.SecretReference}},
+				},
+			}
+		})
+
+		It("watches the referenced secrets and implicit variables", func() {
+			r.watchSecrets(foo, instance, []string{"foo.var-system-domain"}, nil)
+			Expect(index.secretNames(foo).List()).To(Equal([]string{"foo.var-system-domain", "manifest", "ops"}))
+
+			instance.Spec.Ops = nil
+			r.watchSecrets(foo, instance, nil, nil)
+			Expect(index.secretNames(foo).List()).To(Equal([]string{"manifest"}))
+		})
+
+		It("keeps the previously watched secrets, if the manifest can't be resolved", func() {
+			r.watchSecrets(foo, instance, []string{"foo.var-system-domain"}, nil)
+
+			instance.Spec.Ops = append(instance.Spec.Ops, bdv1.ResourceReference{Name: "broken-ops", Type: bdv1.SecretReference})
+			r.watchSecrets(foo, instance, nil, fmt.Errorf("resolver error"))
+			Expect(index.secretNames(foo).List()).To(Equal([]string{"broken-ops", "foo.var-system-domain", "manifest", "ops"}))
+		})
+	})
+
+	Context("when a reconcile fails to resolve the manifest", func() {
+		var r *ReconcileBOSHDeployment
+
+		BeforeEach(func() {
+			scheme := runtime.NewScheme()
+			Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+			Expect(bdv1.AddToScheme(scheme)).To(Succeed())
+
+			instance := &bdv1.BOSHDeployment{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "foo"},
+				Spec: bdv1.BOSHDeploymentSpec{
+					Manifest: bdv1.ResourceReference{Name: "manifest", Type: bdv1.SecretReference},
+					Ops:      []bdv1.ResourceReference{{Name: "ops", Type: bdv1.SecretReference}},
+				},
+			}
+
+			_, log := helper.NewTestLogger()
+			ctx := ctxlog.NewParentContext(log)
+			ctx = ctxlog.NewContextWithRecorder(ctx, "TestRecorder", record.NewFakeRecorder(100))
+
+			r = &ReconcileBOSHDeployment{
+				ctx:                 ctx,
+				config:              &config.Config{CtxTimeOut: 10 * time.Second},
+				client:              fake.NewFakeClientWithScheme(scheme, instance),
+				scheme:              scheme,
+				withops:             &resolveErrorWithOps{err: fmt.Errorf("broken ops file")},
+				qJobConflicts:       newQJobConflicts(),
+				ignoredFailures:     newIgnoredFailures(),
+				watchedSecretsIndex: index,
+			}
+		})
+
+		It("reconciles the deployment, once a referenced secret is fixed", func() {
+			_, err := r.Reconcile(request("default", "foo"))
+			Expect(err).To(MatchError(ContainSubstring("broken ops file")))
+
+			Expect(index.reconciles(secret("default", "ops"))).To(Equal([]reconcile.Request{request("default", "foo")}))
+			Expect(index.reconciles(secret("default", "manifest"))).To(Equal([]reconcile.Request{request("default", "foo")}))
+		})
+	})
+})
@@ -47,10 +47,17 @@ const (
 var addToManagerFuncs = []func(context.Context, *config.Config, manager.Manager) error{
 	watchnamespace.AddTerminate,
 	boshdeployment.AddDeployment,
+	boshdeployment.AddDeploymentTemplate,
 	boshdeployment.AddBPM,
+	boshdeployment.AddProvenance,
+	boshdeployment.AddVariableGeneration,
+	boshdeployment.AddTermination,
+	boshdeployment.AddLinkCycleCheck,
 	quarkssecret.AddQuarksSecret,
 	quarkssecret.AddCertificateSigningRequest,
 	quarkssecret.AddSecretRotation,
+	quarkssecret.AddRegenerateOnChange,
+	quarkssecret.AddCredHubSync,
 	quarksstatefulset.AddQuarksStatefulSet,
 	statefulset.AddStatefulSetRollout,
 	quarkslink.AddRestart,
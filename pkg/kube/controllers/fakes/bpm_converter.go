@@ -8,9 +8,49 @@ import (
 	"code.cloudfoundry.org/cf-operator/pkg/bosh/bpmconverter"
 	"code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/controllers/boshdeployment"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
 type FakeBPMConverter struct {
+	GenerateServiceExportStub        func(v1.Service) *unstructured.Unstructured
+	generateServiceExportMutex       sync.RWMutex
+	generateServiceExportArgsForCall []struct {
+		arg1 v1.Service
+	}
+	generateServiceExportReturns struct {
+		result1 *unstructured.Unstructured
+	}
+	generateServiceExportReturnsOnCall map[int]struct {
+		result1 *unstructured.Unstructured
+	}
+	GenerateServiceMonitorStub        func(string, string, int, map[string]string) *unstructured.Unstructured
+	generateServiceMonitorMutex       sync.RWMutex
+	generateServiceMonitorArgsForCall []struct {
+		arg1 string
+		arg2 string
+		arg3 int
+		arg4 map[string]string
+	}
+	generateServiceMonitorReturns struct {
+		result1 *unstructured.Unstructured
+	}
+	generateServiceMonitorReturnsOnCall map[int]struct {
+		result1 *unstructured.Unstructured
+	}
+	GenerateServicePerIGStub        func(manifest.InstanceGroup, string, []int32) *v1.Service
+	generateServicePerIGMutex       sync.RWMutex
+	generateServicePerIGArgsForCall []struct {
+		arg1 manifest.InstanceGroup
+		arg2 string
+		arg3 []int32
+	}
+	generateServicePerIGReturns struct {
+		result1 *v1.Service
+	}
+	generateServicePerIGReturnsOnCall map[int]struct {
+		result1 *v1.Service
+	}
 	ResourcesStub        func(string, bpmconverter.DomainNameService, string, *manifest.InstanceGroup, manifest.ReleaseImageProvider, bpm.Configs, string) (*bpmconverter.Resources, error)
 	resourcesMutex       sync.RWMutex
 	resourcesArgsForCall []struct {
@@ -34,6 +74,196 @@ type FakeBPMConverter struct {
 	invocationsMutex sync.RWMutex
 }
 
+func (fake *FakeBPMConverter) GenerateServiceExport(arg1 v1.Service) *unstructured.Unstructured {
+	fake.generateServiceExportMutex.Lock()
+	ret, specificReturn := fake.generateServiceExportReturnsOnCall[len(fake.generateServiceExportArgsForCall)]
+	fake.generateServiceExportArgsForCall = append(fake.generateServiceExportArgsForCall, struct {
+		arg1 v1.Service
+	}{arg1})
+	fake.recordInvocation("GenerateServiceExport", []interface{}{arg1})
+	fake.generateServiceExportMutex.Unlock()
+	if fake.GenerateServiceExportStub != nil {
+		return fake.GenerateServiceExportStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.generateServiceExportReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeBPMConverter) GenerateServiceExportCallCount() int {
+	fake.generateServiceExportMutex.RLock()
+	defer fake.generateServiceExportMutex.RUnlock()
+	return len(fake.generateServiceExportArgsForCall)
+}
+
+func (fake *FakeBPMConverter) GenerateServiceExportCalls(stub func(v1.Service) *unstructured.Unstructured) {
+	fake.generateServiceExportMutex.Lock()
+	defer fake.generateServiceExportMutex.Unlock()
+	fake.GenerateServiceExportStub = stub
+}
+
+func (fake *FakeBPMConverter) GenerateServiceExportArgsForCall(i int) v1.Service {
+	fake.generateServiceExportMutex.RLock()
+	defer fake.generateServiceExportMutex.RUnlock()
+	argsForCall := fake.generateServiceExportArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeBPMConverter) GenerateServiceExportReturns(result1 *unstructured.Unstructured) {
+	fake.generateServiceExportMutex.Lock()
+	defer fake.generateServiceExportMutex.Unlock()
+	fake.GenerateServiceExportStub = nil
+	fake.generateServiceExportReturns = struct {
+		result1 *unstructured.Unstructured
+	}{result1}
+}
+
+func (fake *FakeBPMConverter) GenerateServiceExportReturnsOnCall(i int, result1 *unstructured.Unstructured) {
+	fake.generateServiceExportMutex.Lock()
+	defer fake.generateServiceExportMutex.Unlock()
+	fake.GenerateServiceExportStub = nil
+	if fake.generateServiceExportReturnsOnCall == nil {
+		fake.generateServiceExportReturnsOnCall = make(map[int]struct {
+			result1 *unstructured.Unstructured
+		})
+	}
+	fake.generateServiceExportReturnsOnCall[i] = struct {
+		result1 *unstructured.Unstructured
+	}{result1}
+}
+
+func (fake *FakeBPMConverter) GenerateServiceMonitor(arg1 string, arg2 string, arg3 int, arg4 map[string]string) *unstructured.Unstructured {
+	fake.generateServiceMonitorMutex.Lock()
+	ret, specificReturn := fake.generateServiceMonitorReturnsOnCall[len(fake.generateServiceMonitorArgsForCall)]
+	fake.generateServiceMonitorArgsForCall = append(fake.generateServiceMonitorArgsForCall, struct {
+		arg1 string
+		arg2 string
+		arg3 int
+		arg4 map[string]string
+	}{arg1, arg2, arg3, arg4})
+	fake.recordInvocation("GenerateServiceMonitor", []interface{}{arg1, arg2, arg3, arg4})
+	fake.generateServiceMonitorMutex.Unlock()
+	if fake.GenerateServiceMonitorStub != nil {
+		return fake.GenerateServiceMonitorStub(arg1, arg2, arg3, arg4)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.generateServiceMonitorReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeBPMConverter) GenerateServiceMonitorCallCount() int {
+	fake.generateServiceMonitorMutex.RLock()
+	defer fake.generateServiceMonitorMutex.RUnlock()
+	return len(fake.generateServiceMonitorArgsForCall)
+}
+
+func (fake *FakeBPMConverter) GenerateServiceMonitorCalls(stub func(string, string, int, map[string]string) *unstructured.Unstructured) {
+	fake.generateServiceMonitorMutex.Lock()
+	defer fake.generateServiceMonitorMutex.Unlock()
+	fake.GenerateServiceMonitorStub = stub
+}
+
+func (fake *FakeBPMConverter) GenerateServiceMonitorArgsForCall(i int) (string, string, int, map[string]string) {
+	fake.generateServiceMonitorMutex.RLock()
+	defer fake.generateServiceMonitorMutex.RUnlock()
+	argsForCall := fake.generateServiceMonitorArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeBPMConverter) GenerateServiceMonitorReturns(result1 *unstructured.Unstructured) {
+	fake.generateServiceMonitorMutex.Lock()
+	defer fake.generateServiceMonitorMutex.Unlock()
+	fake.GenerateServiceMonitorStub = nil
+	fake.generateServiceMonitorReturns = struct {
+		result1 *unstructured.Unstructured
+	}{result1}
+}
+
+func (fake *FakeBPMConverter) GenerateServiceMonitorReturnsOnCall(i int, result1 *unstructured.Unstructured) {
+	fake.generateServiceMonitorMutex.Lock()
+	defer fake.generateServiceMonitorMutex.Unlock()
+	fake.GenerateServiceMonitorStub = nil
+	if fake.generateServiceMonitorReturnsOnCall == nil {
+		fake.generateServiceMonitorReturnsOnCall = make(map[int]struct {
+			result1 *unstructured.Unstructured
+		})
+	}
+	fake.generateServiceMonitorReturnsOnCall[i] = struct {
+		result1 *unstructured.Unstructured
+	}{result1}
+}
+
+func (fake *FakeBPMConverter) GenerateServicePerIG(arg1 manifest.InstanceGroup, arg2 string, arg3 []int32) *v1.Service {
+	var arg3Copy []int32
+	if arg3 != nil {
+		arg3Copy = make([]int32, len(arg3))
+		copy(arg3Copy, arg3)
+	}
+	fake.generateServicePerIGMutex.Lock()
+	ret, specificReturn := fake.generateServicePerIGReturnsOnCall[len(fake.generateServicePerIGArgsForCall)]
+	fake.generateServicePerIGArgsForCall = append(fake.generateServicePerIGArgsForCall, struct {
+		arg1 manifest.InstanceGroup
+		arg2 string
+		arg3 []int32
+	}{arg1, arg2, arg3Copy})
+	fake.recordInvocation("GenerateServicePerIG", []interface{}{arg1, arg2, arg3Copy})
+	fake.generateServicePerIGMutex.Unlock()
+	if fake.GenerateServicePerIGStub != nil {
+		return fake.GenerateServicePerIGStub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.generateServicePerIGReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeBPMConverter) GenerateServicePerIGCallCount() int {
+	fake.generateServicePerIGMutex.RLock()
+	defer fake.generateServicePerIGMutex.RUnlock()
+	return len(fake.generateServicePerIGArgsForCall)
+}
+
+func (fake *FakeBPMConverter) GenerateServicePerIGCalls(stub func(manifest.InstanceGroup, string, []int32) *v1.Service) {
+	fake.generateServicePerIGMutex.Lock()
+	defer fake.generateServicePerIGMutex.Unlock()
+	fake.GenerateServicePerIGStub = stub
+}
+
+func (fake *FakeBPMConverter) GenerateServicePerIGArgsForCall(i int) (manifest.InstanceGroup, string, []int32) {
+	fake.generateServicePerIGMutex.RLock()
+	defer fake.generateServicePerIGMutex.RUnlock()
+	argsForCall := fake.generateServicePerIGArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeBPMConverter) GenerateServicePerIGReturns(result1 *v1.Service) {
+	fake.generateServicePerIGMutex.Lock()
+	defer fake.generateServicePerIGMutex.Unlock()
+	fake.GenerateServicePerIGStub = nil
+	fake.generateServicePerIGReturns = struct {
+		result1 *v1.Service
+	}{result1}
+}
+
+func (fake *FakeBPMConverter) GenerateServicePerIGReturnsOnCall(i int, result1 *v1.Service) {
+	fake.generateServicePerIGMutex.Lock()
+	defer fake.generateServicePerIGMutex.Unlock()
+	fake.GenerateServicePerIGStub = nil
+	if fake.generateServicePerIGReturnsOnCall == nil {
+		fake.generateServicePerIGReturnsOnCall = make(map[int]struct {
+			result1 *v1.Service
+		})
+	}
+	fake.generateServicePerIGReturnsOnCall[i] = struct {
+		result1 *v1.Service
+	}{result1}
+}
+
 func (fake *FakeBPMConverter) Resources(arg1 string, arg2 bpmconverter.DomainNameService, arg3 string, arg4 *manifest.InstanceGroup, arg5 manifest.ReleaseImageProvider, arg6 bpm.Configs, arg7 string) (*bpmconverter.Resources, error) {
 	fake.resourcesMutex.Lock()
 	ret, specificReturn := fake.resourcesReturnsOnCall[len(fake.resourcesArgsForCall)]
@@ -106,6 +336,12 @@ func (fake *FakeBPMConverter) ResourcesReturnsOnCall(i int, result1 *bpmconverte
 func (fake *FakeBPMConverter) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
+	fake.generateServiceExportMutex.RLock()
+	defer fake.generateServiceExportMutex.RUnlock()
+	fake.generateServiceMonitorMutex.RLock()
+	defer fake.generateServiceMonitorMutex.RUnlock()
+	fake.generateServicePerIGMutex.RLock()
+	defer fake.generateServicePerIGMutex.RUnlock()
 	fake.resourcesMutex.RLock()
 	defer fake.resourcesMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
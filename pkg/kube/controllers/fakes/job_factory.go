@@ -6,18 +6,20 @@ import (
 
 	"code.cloudfoundry.org/cf-operator/pkg/bosh/converter"
 	"code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+	v1alpha1a "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/controllers/boshdeployment"
 	"code.cloudfoundry.org/quarks-job/pkg/kube/apis/quarksjob/v1alpha1"
 )
 
 type FakeJobFactory struct {
-	InstanceGroupManifestJobStub        func(string, manifest.Manifest, converter.LinkInfos, bool) (*v1alpha1.QuarksJob, error)
+	InstanceGroupManifestJobStub        func(string, manifest.Manifest, converter.LinkInfos, bool, *v1alpha1a.JobDNS) (*v1alpha1.QuarksJob, error)
 	instanceGroupManifestJobMutex       sync.RWMutex
 	instanceGroupManifestJobArgsForCall []struct {
 		arg1 string
 		arg2 manifest.Manifest
 		arg3 converter.LinkInfos
 		arg4 bool
+		arg5 *v1alpha1a.JobDNS
 	}
 	instanceGroupManifestJobReturns struct {
 		result1 *v1alpha1.QuarksJob
@@ -27,11 +29,14 @@ type FakeJobFactory struct {
 		result1 *v1alpha1.QuarksJob
 		result2 error
 	}
-	VariableInterpolationJobStub        func(string, manifest.Manifest) (*v1alpha1.QuarksJob, error)
+	VariableInterpolationJobStub        func(string, manifest.Manifest, bool, *v1alpha1a.JobDNS, string) (*v1alpha1.QuarksJob, error)
 	variableInterpolationJobMutex       sync.RWMutex
 	variableInterpolationJobArgsForCall []struct {
 		arg1 string
 		arg2 manifest.Manifest
+		arg3 bool
+		arg4 *v1alpha1a.JobDNS
+		arg5 string
 	}
 	variableInterpolationJobReturns struct {
 		result1 *v1alpha1.QuarksJob
@@ -45,7 +50,7 @@ type FakeJobFactory struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeJobFactory) InstanceGroupManifestJob(arg1 string, arg2 manifest.Manifest, arg3 converter.LinkInfos, arg4 bool) (*v1alpha1.QuarksJob, error) {
+func (fake *FakeJobFactory) InstanceGroupManifestJob(arg1 string, arg2 manifest.Manifest, arg3 converter.LinkInfos, arg4 bool, arg5 *v1alpha1a.JobDNS) (*v1alpha1.QuarksJob, error) {
 	fake.instanceGroupManifestJobMutex.Lock()
 	ret, specificReturn := fake.instanceGroupManifestJobReturnsOnCall[len(fake.instanceGroupManifestJobArgsForCall)]
 	fake.instanceGroupManifestJobArgsForCall = append(fake.instanceGroupManifestJobArgsForCall, struct {
@@ -53,11 +58,12 @@ func (fake *FakeJobFactory) InstanceGroupManifestJob(arg1 string, arg2 manifest.
 		arg2 manifest.Manifest
 		arg3 converter.LinkInfos
 		arg4 bool
-	}{arg1, arg2, arg3, arg4})
-	fake.recordInvocation("InstanceGroupManifestJob", []interface{}{arg1, arg2, arg3, arg4})
+		arg5 *v1alpha1a.JobDNS
+	}{arg1, arg2, arg3, arg4, arg5})
+	fake.recordInvocation("InstanceGroupManifestJob", []interface{}{arg1, arg2, arg3, arg4, arg5})
 	fake.instanceGroupManifestJobMutex.Unlock()
 	if fake.InstanceGroupManifestJobStub != nil {
-		return fake.InstanceGroupManifestJobStub(arg1, arg2, arg3, arg4)
+		return fake.InstanceGroupManifestJobStub(arg1, arg2, arg3, arg4, arg5)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -72,17 +78,17 @@ func (fake *FakeJobFactory) InstanceGroupManifestJobCallCount() int {
 	return len(fake.instanceGroupManifestJobArgsForCall)
 }
 
-func (fake *FakeJobFactory) InstanceGroupManifestJobCalls(stub func(string, manifest.Manifest, converter.LinkInfos, bool) (*v1alpha1.QuarksJob, error)) {
+func (fake *FakeJobFactory) InstanceGroupManifestJobCalls(stub func(string, manifest.Manifest, converter.LinkInfos, bool, *v1alpha1a.JobDNS) (*v1alpha1.QuarksJob, error)) {
 	fake.instanceGroupManifestJobMutex.Lock()
 	defer fake.instanceGroupManifestJobMutex.Unlock()
 	fake.InstanceGroupManifestJobStub = stub
 }
 
-func (fake *FakeJobFactory) InstanceGroupManifestJobArgsForCall(i int) (string, manifest.Manifest, converter.LinkInfos, bool) {
+func (fake *FakeJobFactory) InstanceGroupManifestJobArgsForCall(i int) (string, manifest.Manifest, converter.LinkInfos, bool, *v1alpha1a.JobDNS) {
 	fake.instanceGroupManifestJobMutex.RLock()
 	defer fake.instanceGroupManifestJobMutex.RUnlock()
 	argsForCall := fake.instanceGroupManifestJobArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5
 }
 
 func (fake *FakeJobFactory) InstanceGroupManifestJobReturns(result1 *v1alpha1.QuarksJob, result2 error) {
@@ -111,17 +117,20 @@ func (fake *FakeJobFactory) InstanceGroupManifestJobReturnsOnCall(i int, result1
 	}{result1, result2}
 }
 
-func (fake *FakeJobFactory) VariableInterpolationJob(arg1 string, arg2 manifest.Manifest) (*v1alpha1.QuarksJob, error) {
+func (fake *FakeJobFactory) VariableInterpolationJob(arg1 string, arg2 manifest.Manifest, arg3 bool, arg4 *v1alpha1a.JobDNS, arg5 string) (*v1alpha1.QuarksJob, error) {
 	fake.variableInterpolationJobMutex.Lock()
 	ret, specificReturn := fake.variableInterpolationJobReturnsOnCall[len(fake.variableInterpolationJobArgsForCall)]
 	fake.variableInterpolationJobArgsForCall = append(fake.variableInterpolationJobArgsForCall, struct {
 		arg1 string
 		arg2 manifest.Manifest
-	}{arg1, arg2})
-	fake.recordInvocation("VariableInterpolationJob", []interface{}{arg1, arg2})
+		arg3 bool
+		arg4 *v1alpha1a.JobDNS
+		arg5 string
+	}{arg1, arg2, arg3, arg4, arg5})
+	fake.recordInvocation("VariableInterpolationJob", []interface{}{arg1, arg2, arg3, arg4, arg5})
 	fake.variableInterpolationJobMutex.Unlock()
 	if fake.VariableInterpolationJobStub != nil {
-		return fake.VariableInterpolationJobStub(arg1, arg2)
+		return fake.VariableInterpolationJobStub(arg1, arg2, arg3, arg4, arg5)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -136,17 +145,17 @@ func (fake *FakeJobFactory) VariableInterpolationJobCallCount() int {
 	return len(fake.variableInterpolationJobArgsForCall)
 }
 
-func (fake *FakeJobFactory) VariableInterpolationJobCalls(stub func(string, manifest.Manifest) (*v1alpha1.QuarksJob, error)) {
+func (fake *FakeJobFactory) VariableInterpolationJobCalls(stub func(string, manifest.Manifest, bool, *v1alpha1a.JobDNS, string) (*v1alpha1.QuarksJob, error)) {
 	fake.variableInterpolationJobMutex.Lock()
 	defer fake.variableInterpolationJobMutex.Unlock()
 	fake.VariableInterpolationJobStub = stub
 }
 
-func (fake *FakeJobFactory) VariableInterpolationJobArgsForCall(i int) (string, manifest.Manifest) {
+func (fake *FakeJobFactory) VariableInterpolationJobArgsForCall(i int) (string, manifest.Manifest, bool, *v1alpha1a.JobDNS, string) {
 	fake.variableInterpolationJobMutex.RLock()
 	defer fake.variableInterpolationJobMutex.RUnlock()
 	argsForCall := fake.variableInterpolationJobArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5
 }
 
 func (fake *FakeJobFactory) VariableInterpolationJobReturns(result1 *v1alpha1.QuarksJob, result2 error) {
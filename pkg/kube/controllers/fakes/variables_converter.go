@@ -10,6 +10,19 @@ import (
 )
 
 type FakeVariablesConverter struct {
+	VariableOrderStub        func([]manifest.Variable) ([]manifest.Variable, error)
+	variableOrderMutex       sync.RWMutex
+	variableOrderArgsForCall []struct {
+		arg1 []manifest.Variable
+	}
+	variableOrderReturns struct {
+		result1 []manifest.Variable
+		result2 error
+	}
+	variableOrderReturnsOnCall map[int]struct {
+		result1 []manifest.Variable
+		result2 error
+	}
 	VariablesStub        func(string, []manifest.Variable) ([]v1alpha1.QuarksSecret, error)
 	variablesMutex       sync.RWMutex
 	variablesArgsForCall []struct {
@@ -28,6 +41,74 @@ type FakeVariablesConverter struct {
 	invocationsMutex sync.RWMutex
 }
 
+func (fake *FakeVariablesConverter) VariableOrder(arg1 []manifest.Variable) ([]manifest.Variable, error) {
+	var arg1Copy []manifest.Variable
+	if arg1 != nil {
+		arg1Copy = make([]manifest.Variable, len(arg1))
+		copy(arg1Copy, arg1)
+	}
+	fake.variableOrderMutex.Lock()
+	ret, specificReturn := fake.variableOrderReturnsOnCall[len(fake.variableOrderArgsForCall)]
+	fake.variableOrderArgsForCall = append(fake.variableOrderArgsForCall, struct {
+		arg1 []manifest.Variable
+	}{arg1Copy})
+	fake.recordInvocation("VariableOrder", []interface{}{arg1Copy})
+	fake.variableOrderMutex.Unlock()
+	if fake.VariableOrderStub != nil {
+		return fake.VariableOrderStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.variableOrderReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeVariablesConverter) VariableOrderCallCount() int {
+	fake.variableOrderMutex.RLock()
+	defer fake.variableOrderMutex.RUnlock()
+	return len(fake.variableOrderArgsForCall)
+}
+
+func (fake *FakeVariablesConverter) VariableOrderCalls(stub func([]manifest.Variable) ([]manifest.Variable, error)) {
+	fake.variableOrderMutex.Lock()
+	defer fake.variableOrderMutex.Unlock()
+	fake.VariableOrderStub = stub
+}
+
+func (fake *FakeVariablesConverter) VariableOrderArgsForCall(i int) []manifest.Variable {
+	fake.variableOrderMutex.RLock()
+	defer fake.variableOrderMutex.RUnlock()
+	argsForCall := fake.variableOrderArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeVariablesConverter) VariableOrderReturns(result1 []manifest.Variable, result2 error) {
+	fake.variableOrderMutex.Lock()
+	defer fake.variableOrderMutex.Unlock()
+	fake.VariableOrderStub = nil
+	fake.variableOrderReturns = struct {
+		result1 []manifest.Variable
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeVariablesConverter) VariableOrderReturnsOnCall(i int, result1 []manifest.Variable, result2 error) {
+	fake.variableOrderMutex.Lock()
+	defer fake.variableOrderMutex.Unlock()
+	fake.VariableOrderStub = nil
+	if fake.variableOrderReturnsOnCall == nil {
+		fake.variableOrderReturnsOnCall = make(map[int]struct {
+			result1 []manifest.Variable
+			result2 error
+		})
+	}
+	fake.variableOrderReturnsOnCall[i] = struct {
+		result1 []manifest.Variable
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeVariablesConverter) Variables(arg1 string, arg2 []manifest.Variable) ([]v1alpha1.QuarksSecret, error) {
 	var arg2Copy []manifest.Variable
 	if arg2 != nil {
@@ -100,6 +181,8 @@ func (fake *FakeVariablesConverter) VariablesReturnsOnCall(i int, result1 []v1al
 func (fake *FakeVariablesConverter) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
+	fake.variableOrderMutex.RLock()
+	defer fake.variableOrderMutex.RUnlock()
 	fake.variablesMutex.RLock()
 	defer fake.variablesMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
@@ -10,6 +10,20 @@ import (
 )
 
 type FakeWithOps struct {
+	CloudConfigStub        func(*v1alpha1.BOSHDeployment, string) (*manifest.CloudConfig, error)
+	cloudConfigMutex       sync.RWMutex
+	cloudConfigArgsForCall []struct {
+		arg1 *v1alpha1.BOSHDeployment
+		arg2 string
+	}
+	cloudConfigReturns struct {
+		result1 *manifest.CloudConfig
+		result2 error
+	}
+	cloudConfigReturnsOnCall map[int]struct {
+		result1 *manifest.CloudConfig
+		result2 error
+	}
 	ManifestStub        func(*v1alpha1.BOSHDeployment, string) (*manifest.Manifest, []string, error)
 	manifestMutex       sync.RWMutex
 	manifestArgsForCall []struct {
@@ -26,10 +40,88 @@ type FakeWithOps struct {
 		result2 []string
 		result3 error
 	}
+	NoOpOpsStub        func(*v1alpha1.BOSHDeployment, string) ([]string, error)
+	noOpOpsMutex       sync.RWMutex
+	noOpOpsArgsForCall []struct {
+		arg1 *v1alpha1.BOSHDeployment
+		arg2 string
+	}
+	noOpOpsReturns struct {
+		result1 []string
+		result2 error
+	}
+	noOpOpsReturnsOnCall map[int]struct {
+		result1 []string
+		result2 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
 
+func (fake *FakeWithOps) CloudConfig(arg1 *v1alpha1.BOSHDeployment, arg2 string) (*manifest.CloudConfig, error) {
+	fake.cloudConfigMutex.Lock()
+	ret, specificReturn := fake.cloudConfigReturnsOnCall[len(fake.cloudConfigArgsForCall)]
+	fake.cloudConfigArgsForCall = append(fake.cloudConfigArgsForCall, struct {
+		arg1 *v1alpha1.BOSHDeployment
+		arg2 string
+	}{arg1, arg2})
+	fake.recordInvocation("CloudConfig", []interface{}{arg1, arg2})
+	fake.cloudConfigMutex.Unlock()
+	if fake.CloudConfigStub != nil {
+		return fake.CloudConfigStub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.cloudConfigReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeWithOps) CloudConfigCallCount() int {
+	fake.cloudConfigMutex.RLock()
+	defer fake.cloudConfigMutex.RUnlock()
+	return len(fake.cloudConfigArgsForCall)
+}
+
+func (fake *FakeWithOps) CloudConfigCalls(stub func(*v1alpha1.BOSHDeployment, string) (*manifest.CloudConfig, error)) {
+	fake.cloudConfigMutex.Lock()
+	defer fake.cloudConfigMutex.Unlock()
+	fake.CloudConfigStub = stub
+}
+
+func (fake *FakeWithOps) CloudConfigArgsForCall(i int) (*v1alpha1.BOSHDeployment, string) {
+	fake.cloudConfigMutex.RLock()
+	defer fake.cloudConfigMutex.RUnlock()
+	argsForCall := fake.cloudConfigArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeWithOps) CloudConfigReturns(result1 *manifest.CloudConfig, result2 error) {
+	fake.cloudConfigMutex.Lock()
+	defer fake.cloudConfigMutex.Unlock()
+	fake.CloudConfigStub = nil
+	fake.cloudConfigReturns = struct {
+		result1 *manifest.CloudConfig
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeWithOps) CloudConfigReturnsOnCall(i int, result1 *manifest.CloudConfig, result2 error) {
+	fake.cloudConfigMutex.Lock()
+	defer fake.cloudConfigMutex.Unlock()
+	fake.CloudConfigStub = nil
+	if fake.cloudConfigReturnsOnCall == nil {
+		fake.cloudConfigReturnsOnCall = make(map[int]struct {
+			result1 *manifest.CloudConfig
+			result2 error
+		})
+	}
+	fake.cloudConfigReturnsOnCall[i] = struct {
+		result1 *manifest.CloudConfig
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeWithOps) Manifest(arg1 *v1alpha1.BOSHDeployment, arg2 string) (*manifest.Manifest, []string, error) {
 	fake.manifestMutex.Lock()
 	ret, specificReturn := fake.manifestReturnsOnCall[len(fake.manifestArgsForCall)]
@@ -97,11 +189,79 @@ func (fake *FakeWithOps) ManifestReturnsOnCall(i int, result1 *manifest.Manifest
 	}{result1, result2, result3}
 }
 
+func (fake *FakeWithOps) NoOpOps(arg1 *v1alpha1.BOSHDeployment, arg2 string) ([]string, error) {
+	fake.noOpOpsMutex.Lock()
+	ret, specificReturn := fake.noOpOpsReturnsOnCall[len(fake.noOpOpsArgsForCall)]
+	fake.noOpOpsArgsForCall = append(fake.noOpOpsArgsForCall, struct {
+		arg1 *v1alpha1.BOSHDeployment
+		arg2 string
+	}{arg1, arg2})
+	fake.recordInvocation("NoOpOps", []interface{}{arg1, arg2})
+	fake.noOpOpsMutex.Unlock()
+	if fake.NoOpOpsStub != nil {
+		return fake.NoOpOpsStub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.noOpOpsReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeWithOps) NoOpOpsCallCount() int {
+	fake.noOpOpsMutex.RLock()
+	defer fake.noOpOpsMutex.RUnlock()
+	return len(fake.noOpOpsArgsForCall)
+}
+
+func (fake *FakeWithOps) NoOpOpsCalls(stub func(*v1alpha1.BOSHDeployment, string) ([]string, error)) {
+	fake.noOpOpsMutex.Lock()
+	defer fake.noOpOpsMutex.Unlock()
+	fake.NoOpOpsStub = stub
+}
+
+func (fake *FakeWithOps) NoOpOpsArgsForCall(i int) (*v1alpha1.BOSHDeployment, string) {
+	fake.noOpOpsMutex.RLock()
+	defer fake.noOpOpsMutex.RUnlock()
+	argsForCall := fake.noOpOpsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeWithOps) NoOpOpsReturns(result1 []string, result2 error) {
+	fake.noOpOpsMutex.Lock()
+	defer fake.noOpOpsMutex.Unlock()
+	fake.NoOpOpsStub = nil
+	fake.noOpOpsReturns = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeWithOps) NoOpOpsReturnsOnCall(i int, result1 []string, result2 error) {
+	fake.noOpOpsMutex.Lock()
+	defer fake.noOpOpsMutex.Unlock()
+	fake.NoOpOpsStub = nil
+	if fake.noOpOpsReturnsOnCall == nil {
+		fake.noOpOpsReturnsOnCall = make(map[int]struct {
+			result1 []string
+			result2 error
+		})
+	}
+	fake.noOpOpsReturnsOnCall[i] = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeWithOps) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
+	fake.cloudConfigMutex.RLock()
+	defer fake.cloudConfigMutex.RUnlock()
 	fake.manifestMutex.RLock()
 	defer fake.manifestMutex.RUnlock()
+	fake.noOpOpsMutex.RLock()
+	defer fake.noOpOpsMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
@@ -18,6 +18,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/backpressure"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/util/reference"
 	"code.cloudfoundry.org/quarks-utils/pkg/config"
 	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
@@ -34,7 +35,7 @@ func AddRestart(ctx context.Context, config *config.Config, mgr manager.Manager)
 	r := NewRestartReconciler(ctx, config, mgr)
 
 	c, err := controller.New(name+"-controller", mgr, controller.Options{
-		Reconciler: r,
+		Reconciler: backpressure.NewReconciler(ctx, r),
 	})
 	if err != nil {
 		return errors.Wrap(err, "Adding restart controller to manager failed.")
@@ -0,0 +1,85 @@
+package quarkssecret
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+
+	"github.com/pkg/errors"
+)
+
+// verifyCertificateChain checks that the PEM encoded certificate can be
+// verified up to the configured CA. Any intermediates are expected to be
+// appended to the certificate or to the CA bundle. Self-signed certificates
+// in the CA bundle are used as roots, if there are none all of the bundle is
+// trusted.
+func verifyCertificateChain(certificate []byte, ca []byte) error {
+	certs, err := parseCertificates(certificate)
+	if err != nil {
+		return errors.Wrap(err, "parsing certificate")
+	}
+	if len(certs) == 0 {
+		return errors.New("no certificate found")
+	}
+
+	cas, err := parseCertificates(ca)
+	if err != nil {
+		return errors.Wrap(err, "parsing CA")
+	}
+	if len(cas) == 0 {
+		return errors.New("no CA certificate found")
+	}
+
+	roots := x509.NewCertPool()
+	intermediates := x509.NewCertPool()
+	for _, c := range certs[1:] {
+		intermediates.AddCert(c)
+	}
+
+	foundRoot := false
+	for _, c := range cas {
+		if isSelfSigned(c) {
+			roots.AddCert(c)
+			foundRoot = true
+		} else {
+			intermediates.AddCert(c)
+		}
+	}
+	if !foundRoot {
+		for _, c := range cas {
+			roots.AddCert(c)
+		}
+	}
+
+	_, err = certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	return err
+}
+
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	certs := []*x509.Certificate{}
+	rest := data
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
+
+func isSelfSigned(cert *x509.Certificate) bool {
+	return bytes.Equal(cert.RawIssuer, cert.RawSubject) && cert.CheckSignatureFrom(cert) == nil
+}
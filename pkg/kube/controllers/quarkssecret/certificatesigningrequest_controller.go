@@ -14,6 +14,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	qsv1a1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/quarkssecret/v1alpha1"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/backpressure"
 	"code.cloudfoundry.org/quarks-utils/pkg/config"
 	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
 )
@@ -30,7 +31,7 @@ func AddCertificateSigningRequest(ctx context.Context, config *config.Config, mg
 
 	// Create a new controller
 	c, err := controller.New("certificate-signing-request-controller", mgr, controller.Options{
-		Reconciler:              r,
+		Reconciler:              backpressure.NewReconciler(ctx, r),
 		MaxConcurrentReconciles: config.MaxQuarksSecretWorkers,
 	})
 	if err != nil {
@@ -0,0 +1,81 @@
+package quarkssecret
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	qsv1a1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/quarkssecret/v1alpha1"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/backpressure"
+	"code.cloudfoundry.org/quarks-utils/pkg/config"
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+// AddCredHubSync creates a new controller, which syncs the secrets of
+// 'credhub' QuarksSecrets from CredHub. It is only added, if a CredHub URL
+// is configured.
+func AddCredHubSync(ctx context.Context, config *config.Config, mgr manager.Manager) error {
+	if credHubURL == "" {
+		return nil
+	}
+
+	ctx = ctxlog.NewContextWithRecorder(ctx, "credhub-sync-reconciler", mgr.GetEventRecorderFor("quarks-secret-recorder"))
+	r := NewCredHubSyncReconciler(ctx, config, mgr, NewCredHubClient, controllerutil.SetControllerReference)
+
+	// Create a new controller
+	c, err := controller.New("credhub-sync-controller", mgr, controller.Options{
+		Reconciler:              backpressure.NewReconciler(ctx, r),
+		MaxConcurrentReconciles: config.MaxQuarksSecretWorkers,
+	})
+	if err != nil {
+		return errors.Wrap(err, "Adding credhub sync controller to manager failed.")
+	}
+
+	// Watch for changes to CredHub QuarksSecrets
+	p := predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			o := e.Object.(*qsv1a1.QuarksSecret)
+			if o.Spec.Type != qsv1a1.CredHub {
+				return false
+			}
+			ctxlog.NewPredicateEvent(e.Object).Debug(
+				ctx, e.Meta, "qsv1a1.QuarksSecret",
+				fmt.Sprintf("Create predicate passed for '%s'", e.Meta.GetName()),
+			)
+			return true
+		},
+		DeleteFunc:  func(e event.DeleteEvent) bool { return false },
+		GenericFunc: func(e event.GenericEvent) bool { return false },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			o := e.ObjectOld.(*qsv1a1.QuarksSecret)
+			n := e.ObjectNew.(*qsv1a1.QuarksSecret)
+			if n.Spec.Type != qsv1a1.CredHub {
+				return false
+			}
+			if !n.Status.Generated ||
+				o.Spec.SecretName != n.Spec.SecretName ||
+				o.GetAnnotations()[qsv1a1.AnnotationCredHubPath] != n.GetAnnotations()[qsv1a1.AnnotationCredHubPath] {
+				ctxlog.NewPredicateEvent(e.ObjectNew).Debug(
+					ctx, e.MetaNew, "qsv1a1.QuarksSecret",
+					fmt.Sprintf("Update predicate passed for '%s'", e.MetaNew.GetName()),
+				)
+				return true
+			}
+			return false
+		},
+	}
+	err = c.Watch(&source.Kind{Type: &qsv1a1.QuarksSecret{}}, &handler.EnqueueRequestForObject{}, p)
+	if err != nil {
+		return errors.Wrapf(err, "Watching quarks secrets failed in credhub sync controller.")
+	}
+
+	return nil
+}
@@ -0,0 +1,183 @@
+package quarkssecret
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"code.cloudfoundry.org/cf-operator/pkg/credhub"
+	qsv1a1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/quarkssecret/v1alpha1"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/mutate"
+	"code.cloudfoundry.org/quarks-utils/pkg/config"
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+var (
+	// credHubURL is the URL of the CredHub server, empty disables the CredHub sync
+	credHubURL string
+	// credHubClientSecret is the name of the secret containing the mTLS client certificate for CredHub
+	credHubClientSecret string
+	// credHubSyncInterval is the time after which credentials are read from CredHub again
+	credHubSyncInterval = 5 * time.Minute
+)
+
+// SetCredHub initializes the package scoped CredHub variables. An empty url disables the CredHub sync.
+func SetCredHub(url string, clientSecret string, syncInterval time.Duration) error {
+	if url != "" && clientSecret == "" {
+		return errors.New("the CredHub client secret is required, if a CredHub URL is set")
+	}
+	if syncInterval <= 0 {
+		return errors.Errorf("invalid CredHub sync interval '%s', must be positive", syncInterval)
+	}
+
+	credHubURL = url
+	credHubClientSecret = clientSecret
+	credHubSyncInterval = syncInterval
+	return nil
+}
+
+// NewCredHubClientFunc returns a CredHub client, which authenticates with the client certificate in namespace
+type NewCredHubClientFunc func(ctx context.Context, c client.Client, namespace string) (credhub.Client, error)
+
+// NewCredHubClient returns a client for the configured CredHub server
+func NewCredHubClient(ctx context.Context, c client.Client, namespace string) (credhub.Client, error) {
+	secret := &corev1.Secret{}
+	err := c.Get(ctx, types.NamespacedName{Name: credHubClientSecret, Namespace: namespace}, secret)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting CredHub client secret '%s'", credHubClientSecret)
+	}
+
+	tlsConfig, err := credhub.TLSConfig(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return credhub.NewClient(credHubURL, tlsConfig), nil
+}
+
+// NewCredHubSyncReconciler returns a new ReconcileCredHubSync
+func NewCredHubSyncReconciler(ctx context.Context, config *config.Config, mgr manager.Manager, newClient NewCredHubClientFunc, srf setReferenceFunc) reconcile.Reconciler {
+	return &ReconcileCredHubSync{
+		ctx:          ctx,
+		config:       config,
+		client:       mgr.GetClient(),
+		scheme:       mgr.GetScheme(),
+		newClient:    newClient,
+		setReference: srf,
+	}
+}
+
+// ReconcileCredHubSync writes CredHub credentials into the secrets of 'credhub' QuarksSecrets
+type ReconcileCredHubSync struct {
+	ctx          context.Context
+	client       client.Client
+	scheme       *runtime.Scheme
+	newClient    NewCredHubClientFunc
+	setReference setReferenceFunc
+	config       *config.Config
+}
+
+// Reconcile reads the credential of a 'credhub' QuarksSecret from CredHub and
+// writes it into its secret. It requeues to pick up changes in CredHub.
+func (r *ReconcileCredHubSync) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	instance := &qsv1a1.QuarksSecret{}
+
+	// Set the ctx to be Background, as the top-level context for incoming requests.
+	ctx, cancel := context.WithTimeout(r.ctx, r.config.CtxTimeOut)
+	defer cancel()
+
+	ctxlog.Infof(ctx, "Reconciling CredHub QuarksSecret %s", request.NamespacedName)
+	err := r.client.Get(ctx, request.NamespacedName, instance)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			ctxlog.Info(ctx, "Skip reconcile: quarks secret not found")
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, errors.Wrap(err, "Error reading quarksSecret")
+	}
+
+	if instance.Spec.Type != qsv1a1.CredHub {
+		ctxlog.Debugf(ctx, "Skip reconcile: quarksSecret '%s' is not of type '%s'", instance.Name, qsv1a1.CredHub)
+		return reconcile.Result{}, nil
+	}
+
+	path := instance.GetAnnotations()[qsv1a1.AnnotationCredHubPath]
+	if path == "" {
+		return reconcile.Result{},
+			ctxlog.WithEvent(instance, "CredHubSyncError").Errorf(ctx, "QuarksSecret '%s' has no '%s' annotation", instance.Name, qsv1a1.AnnotationCredHubPath)
+	}
+
+	c, err := r.newClient(ctx, r.client, instance.Namespace)
+	if err != nil {
+		return reconcile.Result{},
+			ctxlog.WithEvent(instance, "CredHubSyncError").Errorf(ctx, "Failed to create CredHub client for QuarksSecret '%s': %v", instance.Name, err)
+	}
+
+	cred, err := c.Get(ctx, path)
+	if err != nil {
+		return reconcile.Result{},
+			ctxlog.WithEvent(instance, "CredHubSyncError").Errorf(ctx, "Failed to read credential '%s' for QuarksSecret '%s': %v", path, instance.Name, err)
+	}
+
+	data, err := credhub.SecretData(cred)
+	if err != nil {
+		return reconcile.Result{},
+			ctxlog.WithEvent(instance, "CredHubSyncError").Errorf(ctx, "Failed to convert credential '%s' for QuarksSecret '%s': %v", path, instance.Name, err)
+	}
+
+	err = r.applySecret(ctx, instance, data)
+	if err != nil {
+		return reconcile.Result{},
+			ctxlog.WithEvent(instance, "CredHubSyncError").Errorf(ctx, "Failed to write credential '%s' for QuarksSecret '%s': %v", path, instance.Name, err)
+	}
+
+	instance.Status.Generated = true
+	now := metav1.Now()
+	instance.Status.LastReconcile = &now
+	err = r.client.Status().Update(ctx, instance)
+	if err != nil {
+		return reconcile.Result{}, errors.Wrapf(err, "could not update QuarksSecret status '%s'", instance.GetName())
+	}
+
+	return reconcile.Result{RequeueAfter: credHubSyncInterval}, nil
+}
+
+// applySecret creates or updates the secret of the QuarksSecret with data, replacing all existing keys
+func (r *ReconcileCredHubSync) applySecret(ctx context.Context, instance *qsv1a1.QuarksSecret, data map[string]string) error {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      instance.Spec.SecretName,
+			Namespace: instance.GetNamespace(),
+			Labels: map[string]string{
+				qsv1a1.LabelKind: qsv1a1.GeneratedSecretKind,
+			},
+		},
+		Data: map[string][]byte{},
+	}
+	for k, v := range data {
+		secret.Data[k] = []byte(v)
+	}
+
+	if err := r.setReference(instance, secret, r.scheme); err != nil {
+		return errors.Wrapf(err, "error setting owner for secret '%s' to QuarksSecret '%s'", secret.GetName(), instance.GetName())
+	}
+
+	op, err := controllerutil.CreateOrUpdate(ctx, r.client, secret, mutate.SecretMutateFn(secret))
+	if err != nil {
+		return errors.Wrapf(err, "could not create or update secret '%s'", secret.GetName())
+	}
+
+	ctxlog.Debugf(ctx, "Secret '%s' has been %s", secret.Name, op)
+	return nil
+}
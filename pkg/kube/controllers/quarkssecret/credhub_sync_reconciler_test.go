@@ -0,0 +1,152 @@
+package quarkssecret_test
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"code.cloudfoundry.org/cf-operator/pkg/credhub"
+	credhubfakes "code.cloudfoundry.org/cf-operator/pkg/credhub/fakes"
+	qsv1a1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/quarkssecret/v1alpha1"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/client/clientset/versioned/scheme"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/controllers"
+	cfakes "code.cloudfoundry.org/cf-operator/pkg/kube/controllers/fakes"
+	qscontroller "code.cloudfoundry.org/cf-operator/pkg/kube/controllers/quarkssecret"
+	cfcfg "code.cloudfoundry.org/quarks-utils/pkg/config"
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+	helper "code.cloudfoundry.org/quarks-utils/testing/testhelper"
+)
+
+var _ = Describe("ReconcileCredHubSync", func() {
+	var (
+		manager          *cfakes.FakeManager
+		reconciler       reconcile.Reconciler
+		request          reconcile.Request
+		ctx              context.Context
+		config           *cfcfg.Config
+		client           *cfakes.FakeClient
+		statusWriter     *cfakes.FakeStatusWriter
+		credHubClient    *credhubfakes.FakeClient
+		recorder         *record.FakeRecorder
+		qSecret          *qsv1a1.QuarksSecret
+		setReferenceFunc func(owner, object metav1.Object, scheme *runtime.Scheme) error = func(owner, object metav1.Object, scheme *runtime.Scheme) error { return nil }
+	)
+
+	BeforeEach(func() {
+		controllers.AddToScheme(scheme.Scheme)
+		manager = &cfakes.FakeManager{}
+		request = reconcile.Request{NamespacedName: types.NamespacedName{Name: "foo", Namespace: "default"}}
+		config = &cfcfg.Config{CtxTimeOut: 10 * time.Second}
+		_, log := helper.NewTestLogger()
+		recorder = record.NewFakeRecorder(20)
+		ctx = ctxlog.NewParentContext(log)
+		ctx = ctxlog.NewContextWithRecorder(ctx, "TestRecorder", recorder)
+		qSecret = &qsv1a1.QuarksSecret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "foo",
+				Namespace:   "default",
+				Annotations: map[string]string{qsv1a1.AnnotationCredHubPath: "/team/admin-password"},
+			},
+			Spec: qsv1a1.QuarksSecretSpec{
+				Type:       qsv1a1.CredHub,
+				SecretName: "var-admin-password",
+			},
+		}
+		credHubClient = &credhubfakes.FakeClient{}
+		credHubClient.GetReturns(credhub.Credential{
+			Name:  "/team/admin-password",
+			Type:  credhub.TypePassword,
+			Value: json.RawMessage(`"secret"`),
+		}, nil)
+
+		client = &cfakes.FakeClient{}
+		client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+			switch object := object.(type) {
+			case *qsv1a1.QuarksSecret:
+				qSecret.DeepCopyInto(object)
+			case *corev1.Secret:
+				return apierrors.NewNotFound(schema.GroupResource{}, "not found")
+			}
+			return nil
+		})
+		statusWriter = &cfakes.FakeStatusWriter{}
+		client.StatusCalls(func() crc.StatusWriter { return statusWriter })
+		manager.GetClientReturns(client)
+	})
+
+	JustBeforeEach(func() {
+		newClient := func(context.Context, crc.Client, string) (credhub.Client, error) { return credHubClient, nil }
+		reconciler = qscontroller.NewCredHubSyncReconciler(ctx, config, manager, newClient, setReferenceFunc)
+	})
+
+	It("writes the credential into the secret and requeues", func() {
+		result, err := reconciler.Reconcile(request)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(5 * time.Minute))
+
+		_, path := credHubClient.GetArgsForCall(0)
+		Expect(path).To(Equal("/team/admin-password"))
+
+		Expect(client.CreateCallCount()).To(Equal(1))
+		_, object, _ := client.CreateArgsForCall(0)
+		secret := object.(*corev1.Secret)
+		Expect(secret.Name).To(Equal("var-admin-password"))
+		Expect(secret.Labels).To(HaveKeyWithValue(qsv1a1.LabelKind, qsv1a1.GeneratedSecretKind))
+		Expect(secret.Data).To(Equal(map[string][]byte{"password": []byte("secret")}))
+
+		Expect(statusWriter.UpdateCallCount()).To(Equal(1))
+		_, object, _ = statusWriter.UpdateArgsForCall(0)
+		Expect(object.(*qsv1a1.QuarksSecret).Status.Generated).To(BeTrue())
+	})
+
+	It("skips QuarksSecrets of other types", func() {
+		qSecret.Spec.Type = qsv1a1.Password
+
+		result, err := reconciler.Reconcile(request)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(Equal(reconcile.Result{}))
+		Expect(credHubClient.GetCallCount()).To(Equal(0))
+	})
+
+	It("fails if the CredHub path annotation is missing", func() {
+		qSecret.Annotations = nil
+
+		_, err := reconciler.Reconcile(request)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("has no 'quarks.cloudfoundry.org/credhub-path' annotation"))
+		Expect(<-recorder.Events).To(ContainSubstring("CredHubSyncError"))
+	})
+
+	It("fails if the credential can't be read", func() {
+		credHubClient.GetReturns(credhub.Credential{}, errors.New("connection refused"))
+
+		_, err := reconciler.Reconcile(request)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("failed to read credential '/team/admin-password'"))
+		Expect(<-recorder.Events).To(ContainSubstring("CredHubSyncError"))
+		Expect(client.CreateCallCount()).To(Equal(0))
+	})
+
+	It("fails if the credential type is not supported", func() {
+		credHubClient.GetReturns(credhub.Credential{Name: "/team/ssh", Type: "ssh"}, nil)
+
+		_, err := reconciler.Reconcile(request)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("unsupported type 'ssh'"))
+		Expect(client.CreateCallCount()).To(Equal(0))
+	})
+})
@@ -18,6 +18,8 @@ import (
 
 	credsgen "code.cloudfoundry.org/cf-operator/pkg/credsgen/in_memory_generator"
 	qsv1a1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/quarkssecret/v1alpha1"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/backpressure"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/watchdog"
 	"code.cloudfoundry.org/quarks-utils/pkg/config"
 	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
 )
@@ -31,7 +33,7 @@ func AddQuarksSecret(ctx context.Context, config *config.Config, mgr manager.Man
 
 	// Create a new controller
 	c, err := controller.New("quarks-secret-controller", mgr, controller.Options{
-		Reconciler:              r,
+		Reconciler:              watchdog.NewReconciler(ctx, backpressure.NewReconciler(ctx, r), &qsv1a1.QuarksSecret{}),
 		MaxConcurrentReconciles: config.MaxQuarksSecretWorkers,
 	})
 	if err != nil {
@@ -149,6 +149,9 @@ func (r *ReconcileQuarksSecret) Reconcile(request reconcile.Request) (reconcile.
 			ctxlog.Info(ctx, "Error generating certificate secret: "+err.Error())
 			return reconcile.Result{}, errors.Wrap(err, "generating certificate secret.")
 		}
+	case qsv1a1.CredHub:
+		ctxlog.Debugf(ctx, "Skip reconcile: quarksSecret '%s' is synced from CredHub", instance.Name)
+		return reconcile.Result{}, nil
 	default:
 		err = ctxlog.WithEvent(instance, "InvalidTypeError").Errorf(ctx, "Invalid type: %s", instance.Spec.Type)
 		return reconcile.Result{}, err
@@ -321,6 +324,13 @@ func (r *ReconcileQuarksSecret) createCertificateSecret(ctx context.Context, ins
 
 		if len(generationRequest.CA.Certificate) > 0 {
 			secret.StringData["ca"] = string(generationRequest.CA.Certificate)
+
+			err = verifyCertificateChain(cert.Certificate, generationRequest.CA.Certificate)
+			if err != nil {
+				msg := fmt.Sprintf("Certificate for QuarksSecret '%s/%s' can't be verified against its CA: %s", instance.Namespace, instance.Name, err)
+				ctxlog.ExtractLogger(ctx).Warn(msg)
+				ctxlog.WarningEvent(ctx, instance, "IncompleteCertChain", msg)
+			}
 		}
 
 		return r.createSecret(ctx, instance, secret)
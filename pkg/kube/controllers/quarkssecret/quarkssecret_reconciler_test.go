@@ -14,11 +14,13 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	crc "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"code.cloudfoundry.org/cf-operator/pkg/credsgen"
 	generatorfakes "code.cloudfoundry.org/cf-operator/pkg/credsgen/fakes"
+	inmemorygenerator "code.cloudfoundry.org/cf-operator/pkg/credsgen/in_memory_generator"
 	qsv1a1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/quarkssecret/v1alpha1"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/client/clientset/versioned/scheme"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/controllers"
@@ -100,6 +102,17 @@ var _ = Describe("ReconcileQuarksSecret", func() {
 		})
 	})
 
+	Context("if the secret is synced from CredHub", func() {
+		It("doesn't generate a secret", func() {
+			qSecret.Spec.Type = qsv1a1.CredHub
+
+			result, err := reconciler.Reconcile(request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(client.CreateCallCount()).To(Equal(0))
+			Expect(reconcile.Result{}).To(Equal(result))
+		})
+	})
+
 	Context("when generating passwords", func() {
 		BeforeEach(func() {
 			generator.GeneratePasswordReturns("securepassword")
@@ -305,6 +318,73 @@ var _ = Describe("ReconcileQuarksSecret", func() {
 				})
 			})
 		})
+
+		Context("when verifying the certificate chain", func() {
+			var (
+				recorder   *record.FakeRecorder
+				memGen     *inmemorygenerator.InMemoryGenerator
+				signingCA  credsgen.Certificate
+				configured credsgen.Certificate
+			)
+
+			BeforeEach(func() {
+				recorder = record.NewFakeRecorder(10)
+				ctx = ctxlog.NewContextWithRecorder(ctx, "test", recorder)
+
+				memGen = inmemorygenerator.NewInMemoryGenerator(log)
+				var err error
+				signingCA, err = memGen.GenerateCertificate("signing-ca", credsgen.CertificateGenerationRequest{CommonName: "signing-ca", IsCA: true})
+				Expect(err).ToNot(HaveOccurred())
+				configured = signingCA
+
+				client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+					switch object := object.(type) {
+					case *qsv1a1.QuarksSecret:
+						qSecret.DeepCopyInto(object)
+					case *corev1.Secret:
+						if nn.Name == "mysecret" {
+							ca := &corev1.Secret{
+								ObjectMeta: metav1.ObjectMeta{Name: "mysecret", Namespace: "default"},
+								Data: map[string][]byte{
+									"ca":  configured.Certificate,
+									"key": configured.PrivateKey,
+								},
+							}
+							ca.DeepCopyInto(object)
+						} else {
+							return errors.NewNotFound(schema.GroupResource{}, "not found is requeued")
+						}
+					}
+					return nil
+				})
+				generator.GenerateCertificateCalls(func(name string, request credsgen.CertificateGenerationRequest) (credsgen.Certificate, error) {
+					request.CA = signingCA
+					return memGen.GenerateCertificate(name, request)
+				})
+			})
+
+			It("doesn't emit an event if the chain is complete", func() {
+				_, err := reconciler.Reconcile(request)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(client.CreateCallCount()).To(Equal(1))
+				Expect(recorder.Events).To(BeEmpty())
+			})
+
+			Context("and the certificate isn't signed by the configured CA", func() {
+				BeforeEach(func() {
+					var err error
+					configured, err = memGen.GenerateCertificate("other-ca", credsgen.CertificateGenerationRequest{CommonName: "other-ca", IsCA: true})
+					Expect(err).ToNot(HaveOccurred())
+				})
+
+				It("emits an IncompleteCertChain event and still creates the secret", func() {
+					_, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(client.CreateCallCount()).To(Equal(1))
+					Expect(recorder.Events).To(Receive(ContainSubstring("IncompleteCertChain")))
+				})
+			})
+		})
 	})
 
 	Context("when secret is set manually", func() {
@@ -0,0 +1,63 @@
+package quarkssecret
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	qsv1a1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/quarkssecret/v1alpha1"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/backpressure"
+	"code.cloudfoundry.org/quarks-utils/pkg/config"
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+// AddRegenerateOnChange creates a new controller, which regenerates the
+// QuarksSecrets listing a regenerated QuarksSecret in their
+// spec.regenerateOnChange
+func AddRegenerateOnChange(ctx context.Context, config *config.Config, mgr manager.Manager) error {
+	ctx = ctxlog.NewContextWithRecorder(ctx, "regenerate-on-change-reconciler", mgr.GetEventRecorderFor("quarks-secret-recorder"))
+	r := NewRegenerateOnChangeReconciler(ctx, config, mgr)
+
+	// Create a new controller
+	c, err := controller.New("regenerate-on-change-controller", mgr, controller.Options{
+		Reconciler:              backpressure.NewReconciler(ctx, r),
+		MaxConcurrentReconciles: config.MaxQuarksSecretWorkers,
+	})
+	if err != nil {
+		return errors.Wrap(err, "Adding regenerate on change controller to manager failed.")
+	}
+
+	// Watch for QuarksSecrets, which were regenerated. Secrets, which are
+	// generated for the first time, don't have a last reconcile yet.
+	p := predicate.Funcs{
+		CreateFunc:  func(e event.CreateEvent) bool { return false },
+		DeleteFunc:  func(e event.DeleteEvent) bool { return false },
+		GenericFunc: func(e event.GenericEvent) bool { return false },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			o := e.ObjectOld.(*qsv1a1.QuarksSecret)
+			n := e.ObjectNew.(*qsv1a1.QuarksSecret)
+			if !o.Status.Generated && n.Status.Generated && o.Status.LastReconcile != nil {
+				ctxlog.NewPredicateEvent(e.ObjectNew).Debug(
+					ctx, e.MetaNew, "qsv1a1.QuarksSecret",
+					fmt.Sprintf("Update predicate passed for '%s'", e.MetaNew.GetName()),
+				)
+				return true
+			}
+			return false
+		},
+	}
+	err = c.Watch(&source.Kind{Type: &qsv1a1.QuarksSecret{}}, &handler.EnqueueRequestForObject{}, p)
+	if err != nil {
+		return errors.Wrapf(err, "Watching quarks secrets failed in regenerate on change controller.")
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package quarkssecret
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	qsv1a1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/quarkssecret/v1alpha1"
+	"code.cloudfoundry.org/quarks-utils/pkg/config"
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+// NewRegenerateOnChangeReconciler returns a new ReconcileRegenerateOnChange
+func NewRegenerateOnChangeReconciler(ctx context.Context, config *config.Config, mgr manager.Manager) reconcile.Reconciler {
+	return &ReconcileRegenerateOnChange{
+		ctx:    ctx,
+		config: config,
+		client: mgr.GetClient(),
+	}
+}
+
+// ReconcileRegenerateOnChange reconciles a regenerated QuarksSecret
+type ReconcileRegenerateOnChange struct {
+	ctx    context.Context
+	client client.Client
+	config *config.Config
+}
+
+// Reconcile sets .status.generated to false for all generated QuarksSecrets,
+// which list the regenerated QuarksSecret in their spec.regenerateOnChange.
+// Dependents, whose regeneration would cascade back to the regenerated
+// QuarksSecret, are skipped to break the cycle.
+func (r *ReconcileRegenerateOnChange) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	instance := &qsv1a1.QuarksSecret{}
+
+	// Set the ctx to be Background, as the top-level context for incoming requests.
+	ctx, cancel := context.WithTimeout(r.ctx, r.config.CtxTimeOut)
+	defer cancel()
+
+	ctxlog.Infof(ctx, "Reconciling regeneration of QuarksSecret %s", request.NamespacedName)
+	err := r.client.Get(ctx, request.NamespacedName, instance)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			ctxlog.Info(ctx, "Skip reconcile: quarks secret not found")
+			return reconcile.Result{}, nil
+		}
+		ctxlog.Info(ctx, "Error reading the object")
+		return reconcile.Result{}, errors.Wrap(err, "Error reading quarksSecret")
+	}
+
+	qsecs := &qsv1a1.QuarksSecretList{}
+	err = r.client.List(ctx, qsecs, client.InNamespace(instance.Namespace))
+	if err != nil {
+		return reconcile.Result{}, errors.Wrapf(err, "Error listing QuarksSecrets in namespace '%s'", instance.Namespace)
+	}
+
+	dependents := map[string][]string{}
+	byName := map[string]*qsv1a1.QuarksSecret{}
+	for i, qsec := range qsecs.Items {
+		byName[qsec.Name] = &qsecs.Items[i]
+		for _, name := range qsec.Spec.RegenerateOnChange {
+			dependents[name] = append(dependents[name], qsec.Name)
+		}
+	}
+
+	for _, name := range dependents[instance.Name] {
+		if reaches(dependents, name, instance.Name) {
+			msg := fmt.Sprintf("Skipping regeneration of QuarksSecret '%s' on change of '%s', it would regenerate '%s' again", name, instance.Name, instance.Name)
+			ctxlog.WarningEvent(ctx, instance, "RegenerationCycle", msg)
+			continue
+		}
+
+		qsec := byName[name]
+		// skip manual secrets and secrets, which are regenerated already
+		if !qsec.Status.Generated {
+			ctxlog.Debugf(ctx, "QuarksSecret '%s' is not regenerated, it is not generated by quarks secret", qsec.Name)
+			continue
+		}
+
+		qsec.Status.Generated = false
+		err = r.client.Status().Update(ctx, qsec)
+		if err != nil {
+			return reconcile.Result{}, errors.Wrapf(err, "Error updating QuarksSecret status of '%s'", qsec.Name)
+		}
+		ctxlog.WithEvent(qsec, "RegenerateOnChange").Infof(ctx, "Regenerating QuarksSecret '%s' on change of '%s'", qsec.Name, instance.Name)
+	}
+
+	return reconcile.Result{}, nil
+}
+
+// reaches does a depth first search for target in the dependents of from,
+// including from itself
+func reaches(dependents map[string][]string, from string, target string) bool {
+	visited := map[string]bool{}
+	var visit func(name string) bool
+	visit = func(name string) bool {
+		if name == target {
+			return true
+		}
+		if visited[name] {
+			return false
+		}
+		visited[name] = true
+		for _, dependent := range dependents[name] {
+			if visit(dependent) {
+				return true
+			}
+		}
+		return false
+	}
+	return visit(from)
+}
@@ -0,0 +1,143 @@
+package quarkssecret_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	qsv1a1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/quarkssecret/v1alpha1"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/client/clientset/versioned/scheme"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/controllers"
+	cfakes "code.cloudfoundry.org/cf-operator/pkg/kube/controllers/fakes"
+	qscontroller "code.cloudfoundry.org/cf-operator/pkg/kube/controllers/quarkssecret"
+	cfcfg "code.cloudfoundry.org/quarks-utils/pkg/config"
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+	helper "code.cloudfoundry.org/quarks-utils/testing/testhelper"
+)
+
+var _ = Describe("ReconcileRegenerateOnChange", func() {
+	var (
+		manager      *cfakes.FakeManager
+		reconciler   reconcile.Reconciler
+		request      reconcile.Request
+		ctx          context.Context
+		config       *cfcfg.Config
+		client       *cfakes.FakeClient
+		statusWriter *cfakes.FakeStatusWriter
+		recorder     *record.FakeRecorder
+		qSecrets     []qsv1a1.QuarksSecret
+	)
+
+	newQuarksSecret := func(name string, regenerateOnChange ...string) qsv1a1.QuarksSecret {
+		return qsv1a1.QuarksSecret{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: qsv1a1.QuarksSecretSpec{
+				Type:               qsv1a1.Certificate,
+				SecretName:         name,
+				RegenerateOnChange: regenerateOnChange,
+			},
+			Status: qsv1a1.QuarksSecretStatus{Generated: true},
+		}
+	}
+
+	regenerated := func() []string {
+		names := []string{}
+		for i := 0; i < statusWriter.UpdateCallCount(); i++ {
+			_, object, _ := statusWriter.UpdateArgsForCall(i)
+			qsec := object.(*qsv1a1.QuarksSecret)
+			Expect(qsec.Status.Generated).To(BeFalse())
+			names = append(names, qsec.Name)
+		}
+		return names
+	}
+
+	BeforeEach(func() {
+		controllers.AddToScheme(scheme.Scheme)
+		manager = &cfakes.FakeManager{}
+		request = reconcile.Request{NamespacedName: types.NamespacedName{Name: "ca", Namespace: "default"}}
+		config = &cfcfg.Config{CtxTimeOut: 10 * time.Second}
+		_, log := helper.NewTestLogger()
+		recorder = record.NewFakeRecorder(20)
+		ctx = ctxlog.NewParentContext(log)
+		ctx = ctxlog.NewContextWithRecorder(ctx, "TestRecorder", recorder)
+
+		qSecrets = []qsv1a1.QuarksSecret{
+			newQuarksSecret("ca"),
+			newQuarksSecret("leaf", "ca"),
+			newQuarksSecret("other"),
+		}
+
+		client = &cfakes.FakeClient{}
+		client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+			switch object := object.(type) {
+			case *qsv1a1.QuarksSecret:
+				for _, qsec := range qSecrets {
+					if qsec.Name == nn.Name {
+						qsec.DeepCopyInto(object)
+					}
+				}
+			}
+			return nil
+		})
+		client.ListCalls(func(context context.Context, object runtime.Object, _ ...crc.ListOption) error {
+			switch object := object.(type) {
+			case *qsv1a1.QuarksSecretList:
+				list := qsv1a1.QuarksSecretList{Items: qSecrets}
+				list.DeepCopyInto(object)
+			}
+			return nil
+		})
+		statusWriter = &cfakes.FakeStatusWriter{}
+		client.StatusCalls(func() crc.StatusWriter { return statusWriter })
+		manager.GetClientReturns(client)
+	})
+
+	JustBeforeEach(func() {
+		reconciler = qscontroller.NewRegenerateOnChangeReconciler(ctx, config, manager)
+	})
+
+	It("regenerates the QuarksSecrets, which regenerate on change of the QuarksSecret", func() {
+		_, err := reconciler.Reconcile(request)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(regenerated()).To(Equal([]string{"leaf"}))
+		Expect(<-recorder.Events).To(ContainSubstring("RegenerateOnChange"))
+	})
+
+	It("skips QuarksSecrets, which are not generated", func() {
+		qSecrets[1].Status.Generated = false
+
+		_, err := reconciler.Reconcile(request)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(regenerated()).To(BeEmpty())
+	})
+
+	It("breaks cycles, which lead back to the QuarksSecret", func() {
+		qSecrets = append(qSecrets, newQuarksSecret("intermediate", "leaf"))
+		qSecrets[0].Spec.RegenerateOnChange = []string{"intermediate"}
+		qSecrets[2].Spec.RegenerateOnChange = []string{"ca"}
+
+		_, err := reconciler.Reconcile(request)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(regenerated()).To(Equal([]string{"other"}))
+		Expect(<-recorder.Events).To(ContainSubstring("RegenerationCycle"))
+	})
+
+	It("skips QuarksSecrets, which regenerate on their own change", func() {
+		qSecrets[0].Spec.RegenerateOnChange = []string{"ca"}
+		qSecrets[1].Spec.RegenerateOnChange = nil
+
+		_, err := reconciler.Reconcile(request)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(regenerated()).To(BeEmpty())
+		Expect(<-recorder.Events).To(ContainSubstring("RegenerationCycle"))
+	})
+})
@@ -0,0 +1,15 @@
+package quarkssecret
+
+import (
+	bdnames "code.cloudfoundry.org/cf-operator/pkg/kube/util/names"
+)
+
+// secretNamer names the secrets, which are generated for BOSHDeployments
+var secretNamer bdnames.SecretNamer = bdnames.DefaultSecretNamer{}
+
+// SetSecretNamer initializes the package scoped secret namer, which the
+// secret rotation reconciler uses to find the with-ops manifest and the
+// variable QuarksSecrets of a BOSHDeployment.
+func SetSecretNamer(namer bdnames.SecretNamer) {
+	secretNamer = namer
+}
@@ -15,6 +15,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	qsv1a1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/quarkssecret/v1alpha1"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/backpressure"
 	"code.cloudfoundry.org/quarks-utils/pkg/config"
 	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
 )
@@ -26,7 +27,7 @@ func AddSecretRotation(ctx context.Context, config *config.Config, mgr manager.M
 
 	// Create a new controller
 	c, err := controller.New("secret-rotation-controller", mgr, controller.Options{
-		Reconciler:              r,
+		Reconciler:              backpressure.NewReconciler(ctx, r),
 		MaxConcurrentReconciles: config.MaxQuarksSecretWorkers,
 	})
 	if err != nil {
@@ -14,9 +14,12 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	"code.cloudfoundry.org/cf-operator/pkg/bosh/converter"
+	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
 	qsv1a1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/quarkssecret/v1alpha1"
 	"code.cloudfoundry.org/quarks-utils/pkg/config"
 	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+	"code.cloudfoundry.org/quarks-utils/pkg/names"
 )
 
 // NewSecretRotationReconciler returns a new ReconcileQuarksSecret
@@ -38,7 +41,7 @@ type ReconcileSecretRotation struct {
 }
 
 // Reconcile reads that state of the cluster and trigger secret rotation for
-// all listed deployments.
+// all listed QuarksSecrets and the certificates of all listed deployments.
 func (r *ReconcileSecretRotation) Reconcile(request reconcile.Request) (reconcile.Result, error) {
 	instance := &corev1.ConfigMap{}
 
@@ -57,23 +60,41 @@ func (r *ReconcileSecretRotation) Reconcile(request reconcile.Request) (reconcil
 		return reconcile.Result{}, errors.Wrap(err, "Error reading quarksSecret")
 	}
 
-	data, found := instance.Data[qsv1a1.RotateQSecretListName]
-	if !found {
-		ctxlog.Debugf(ctx, "QuarksSecret rotation config didn't list any names, key %s not found", qsv1a1.RotateQSecretListName)
-		return reconcile.Result{}, nil
+	names := []string{}
+	if data, found := instance.Data[qsv1a1.RotateQSecretListName]; found {
+		err = json.Unmarshal([]byte(data), &names)
+		if err != nil {
+			return reconcile.Result{}, errors.Wrapf(err, "Error unmarshalling list of secrets to rotate from '%s'", instance.Name)
+		}
 	}
 
-	names := []string{}
-	err = json.Unmarshal([]byte(data), &names)
-	if err != nil {
-		return reconcile.Result{}, errors.Wrapf(err, "Error unmarshalling list of secrets to rotate from '%s'", instance.Name)
+	if data, found := instance.Data[qsv1a1.RotateDeploymentCertificatesListName]; found {
+		deployments := []string{}
+		err = json.Unmarshal([]byte(data), &deployments)
+		if err != nil {
+			return reconcile.Result{}, errors.Wrapf(err, "Error unmarshalling list of deployments to rotate certificates for from '%s'", instance.Name)
+		}
+
+		for _, deploymentName := range deployments {
+			certificates, err := r.deploymentCertificates(ctx, instance.Namespace, deploymentName)
+			if err != nil {
+				ctxlog.Errorf(ctx, "Error listing certificates of deployment '%s', skipping certificate rotation: %s", deploymentName, err)
+				continue
+			}
+			names = append(names, certificates...)
+		}
+	}
+
+	if len(names) == 0 {
+		ctxlog.Debugf(ctx, "QuarksSecret rotation config didn't list any names, keys %s and %s not found", qsv1a1.RotateQSecretListName, qsv1a1.RotateDeploymentCertificatesListName)
+		return reconcile.Result{}, nil
 	}
 
 	for _, name := range names {
 		qsec := &qsv1a1.QuarksSecret{}
 		err := r.client.Get(ctx, types.NamespacedName{Name: name, Namespace: instance.Namespace}, qsec)
 		if err != nil {
-			ctxlog.Errorf(ctx, "Error getting QuarksSecret the object '%s', skipping secret rotation", qsec.Name)
+			ctxlog.Errorf(ctx, "Error getting QuarksSecret the object '%s', skipping secret rotation", name)
 			continue
 		}
 
@@ -92,3 +113,36 @@ func (r *ReconcileSecretRotation) Reconcile(request reconcile.Request) (reconcil
 
 	return reconcile.Result{}, nil
 }
+
+// deploymentCertificates returns the names of the QuarksSecrets, which the
+// certificate variables of a BOSHDeployment's with-ops manifest convert to
+func (r *ReconcileSecretRotation) deploymentCertificates(ctx context.Context, namespace string, deploymentName string) ([]string, error) {
+	secretName := secretNamer.DeploymentSecretName(names.DeploymentSecretTypeManifestWithOps, deploymentName, "")
+	secret := &corev1.Secret{}
+	err := r.client.Get(ctx, types.NamespacedName{Name: secretName, Namespace: namespace}, secret)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting with-ops manifest secret '%s'", secretName)
+	}
+
+	data, err := bdm.SecretData(secret.Data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading with-ops manifest secret '%s'", secretName)
+	}
+
+	manifest, err := bdm.LoadYAML(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "loading with-ops manifest of deployment '%s'", deploymentName)
+	}
+
+	variables, err := converter.NewVariablesConverter(namespace, secretNamer).VariablesByType(deploymentName, manifest.Variables)
+	if err != nil {
+		return nil, errors.Wrapf(err, "converting variables of deployment '%s'", deploymentName)
+	}
+
+	certificates := []string{}
+	for _, qsec := range variables["certificate"] {
+		certificates = append(certificates, qsec.Name)
+	}
+
+	return certificates, nil
+}
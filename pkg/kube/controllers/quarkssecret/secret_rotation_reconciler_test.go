@@ -0,0 +1,189 @@
+package quarkssecret_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+	qsv1a1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/quarkssecret/v1alpha1"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/client/clientset/versioned/scheme"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/controllers"
+	cfakes "code.cloudfoundry.org/cf-operator/pkg/kube/controllers/fakes"
+	qscontroller "code.cloudfoundry.org/cf-operator/pkg/kube/controllers/quarkssecret"
+	cfcfg "code.cloudfoundry.org/quarks-utils/pkg/config"
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+	"code.cloudfoundry.org/quarks-utils/pkg/names"
+	helper "code.cloudfoundry.org/quarks-utils/testing/testhelper"
+)
+
+var _ = Describe("ReconcileSecretRotation", func() {
+	var (
+		manager      *cfakes.FakeManager
+		reconciler   reconcile.Reconciler
+		request      reconcile.Request
+		ctx          context.Context
+		config       *cfcfg.Config
+		client       *cfakes.FakeClient
+		statusWriter *cfakes.FakeStatusWriter
+		configMap    *corev1.ConfigMap
+		secrets      []corev1.Secret
+		qSecrets     []qsv1a1.QuarksSecret
+	)
+
+	const manifest = `---
+name: foo
+variables:
+- name: ca
+  type: certificate
+  options:
+    is_ca: true
+    common_name: example.com
+- name: cert
+  type: certificate
+  options:
+    ca: ca
+    common_name: cert.example.com
+- name: adminpass
+  type: password
+`
+
+	newQuarksSecret := func(name string) qsv1a1.QuarksSecret {
+		return qsv1a1.QuarksSecret{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Status:     qsv1a1.QuarksSecretStatus{Generated: true},
+		}
+	}
+
+	rotated := func() []string {
+		names := []string{}
+		for i := 0; i < statusWriter.UpdateCallCount(); i++ {
+			_, object, _ := statusWriter.UpdateArgsForCall(i)
+			qsec := object.(*qsv1a1.QuarksSecret)
+			Expect(qsec.Status.Generated).To(BeFalse())
+			names = append(names, qsec.Name)
+		}
+		return names
+	}
+
+	BeforeEach(func() {
+		controllers.AddToScheme(scheme.Scheme)
+		manager = &cfakes.FakeManager{}
+		request = reconcile.Request{NamespacedName: types.NamespacedName{Name: "rotate", Namespace: "default"}}
+		config = &cfcfg.Config{CtxTimeOut: 10 * time.Second}
+		_, log := helper.NewTestLogger()
+		ctx = ctxlog.NewParentContext(log)
+
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "rotate", Namespace: "default"},
+			Data:       map[string]string{},
+		}
+		secrets = []corev1.Secret{
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      names.DeploymentSecretName(names.DeploymentSecretTypeManifestWithOps, "foo", ""),
+					Namespace: "default",
+				},
+				Data: map[string][]byte{bdm.DesiredManifestKeyName: []byte(manifest)},
+			},
+		}
+		qSecrets = []qsv1a1.QuarksSecret{
+			newQuarksSecret("foo.var-ca"),
+			newQuarksSecret("foo.var-cert"),
+			newQuarksSecret("foo.var-adminpass"),
+			newQuarksSecret("other"),
+		}
+
+		client = &cfakes.FakeClient{}
+		client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+			switch object := object.(type) {
+			case *corev1.ConfigMap:
+				configMap.DeepCopyInto(object)
+				return nil
+			case *corev1.Secret:
+				for _, secret := range secrets {
+					if secret.Name == nn.Name {
+						secret.DeepCopyInto(object)
+						return nil
+					}
+				}
+			case *qsv1a1.QuarksSecret:
+				for _, qsec := range qSecrets {
+					if qsec.Name == nn.Name {
+						qsec.DeepCopyInto(object)
+						return nil
+					}
+				}
+			}
+			return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+		})
+		statusWriter = &cfakes.FakeStatusWriter{}
+		client.StatusCalls(func() crc.StatusWriter { return statusWriter })
+		manager.GetClientReturns(client)
+	})
+
+	JustBeforeEach(func() {
+		reconciler = qscontroller.NewSecretRotationReconciler(ctx, config, manager)
+	})
+
+	It("rotates the listed QuarksSecrets", func() {
+		configMap.Data[qsv1a1.RotateQSecretListName] = `["other", "missing"]`
+
+		_, err := reconciler.Reconcile(request)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rotated()).To(Equal([]string{"other"}))
+	})
+
+	It("rotates only the certificates of the listed deployments", func() {
+		configMap.Data[qsv1a1.RotateDeploymentCertificatesListName] = `["foo"]`
+
+		_, err := reconciler.Reconcile(request)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rotated()).To(Equal([]string{"foo.var-ca", "foo.var-cert"}))
+	})
+
+	It("rotates listed QuarksSecrets and certificates of listed deployments together", func() {
+		configMap.Data[qsv1a1.RotateQSecretListName] = `["other"]`
+		configMap.Data[qsv1a1.RotateDeploymentCertificatesListName] = `["foo"]`
+
+		_, err := reconciler.Reconcile(request)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rotated()).To(Equal([]string{"other", "foo.var-ca", "foo.var-cert"}))
+	})
+
+	It("skips deployments without a with-ops manifest", func() {
+		configMap.Data[qsv1a1.RotateDeploymentCertificatesListName] = `["bar", "foo"]`
+
+		_, err := reconciler.Reconcile(request)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rotated()).To(Equal([]string{"foo.var-ca", "foo.var-cert"}))
+	})
+
+	It("skips certificates, which were not generated", func() {
+		qSecrets[0].Status.Generated = false
+		configMap.Data[qsv1a1.RotateDeploymentCertificatesListName] = `["foo"]`
+
+		_, err := reconciler.Reconcile(request)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rotated()).To(Equal([]string{"foo.var-cert"}))
+	})
+
+	It("returns an error, if the list of deployments is invalid", func() {
+		configMap.Data[qsv1a1.RotateDeploymentCertificatesListName] = `foo`
+
+		_, err := reconciler.Reconcile(request)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Error unmarshalling list of deployments"))
+	})
+})
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	qstsv1a1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/quarksstatefulset/v1alpha1"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/backpressure"
 	"code.cloudfoundry.org/quarks-utils/pkg/config"
 	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
 	"github.com/pkg/errors"
@@ -32,7 +33,7 @@ func AddStatefulSetActivePassive(ctx context.Context, config *config.Config, mgr
 
 	// Create new controller
 	c, err := controller.New("active-passive-controller", mgr, controller.Options{
-		Reconciler:              r,
+		Reconciler:              backpressure.NewReconciler(ctx, r),
 		MaxConcurrentReconciles: config.MaxQuarksStatefulSetWorkers,
 	})
 	if err != nil {
@@ -18,7 +18,9 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	qstsv1a1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/quarksstatefulset/v1alpha1"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/backpressure"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/util/reference"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/watchdog"
 	"code.cloudfoundry.org/quarks-utils/pkg/config"
 	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
 	vss "code.cloudfoundry.org/quarks-utils/pkg/versionedsecretstore"
@@ -33,7 +35,7 @@ func AddQuarksStatefulSet(ctx context.Context, config *config.Config, mgr manage
 
 	// Create a new controller
 	c, err := controller.New("quarks-statefulset-controller", mgr, controller.Options{
-		Reconciler:              r,
+		Reconciler:              watchdog.NewReconciler(ctx, backpressure.NewReconciler(ctx, r), &qstsv1a1.QuarksStatefulSet{}),
 		MaxConcurrentReconciles: config.MaxQuarksStatefulSetWorkers,
 	})
 	if err != nil {
@@ -21,9 +21,11 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
 	qstsv1a1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/quarksstatefulset/v1alpha1"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/controllers/statefulset"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/util"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/changewindow"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/util/mutate"
 	"code.cloudfoundry.org/quarks-utils/pkg/config"
 	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
@@ -100,6 +102,14 @@ func (r *ReconcileQuarksStatefulSet) Reconcile(request reconcile.Request) (recon
 		return reconcile.Result{}, err
 	}
 
+	// QuarksStatefulSets of BOSHDeployments are only written inside the change window
+	if deploymentName, ok := qStatefulSet.Labels[bdm.LabelDeploymentName]; ok {
+		if requeueAfter := changewindow.Deferral(time.Now()); requeueAfter > 0 {
+			ctxlog.WithEvent(qStatefulSet, "PendingWindow").Infof(ctx, "Deferring QuarksStatefulSet '%s' of BOSHDeployment '%s' until change window '%s' opens in %s", request.NamespacedName, deploymentName, changewindow.Current(), requeueAfter)
+			return reconcile.Result{RequeueAfter: requeueAfter}, nil
+		}
+	}
+
 	// Update labels of versioned secrets in quarksStatefulSet spec
 	err = r.UpdateVersions(ctx, qStatefulSet)
 	if err != nil {
@@ -21,10 +21,12 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
 	qstsv1a1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/quarksstatefulset/v1alpha1"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/controllers"
 	cfakes "code.cloudfoundry.org/cf-operator/pkg/kube/controllers/fakes"
 	qstscontroller "code.cloudfoundry.org/cf-operator/pkg/kube/controllers/quarksstatefulset"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/changewindow"
 	cfcfg "code.cloudfoundry.org/quarks-utils/pkg/config"
 	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
 	"code.cloudfoundry.org/quarks-utils/pkg/pointers"
@@ -147,6 +149,49 @@ var _ = Describe("ReconcileQuarksStatefulSet", func() {
 				Expect(err).ToNot(HaveOccurred())
 			})
 
+			Context("when the operator has a change window", func() {
+				clock := func(t time.Time) string {
+					return t.UTC().Format("15:04")
+				}
+
+				BeforeEach(func() {
+					now := time.Now()
+					Expect(changewindow.Set(clock(now.Add(2*time.Hour)) + "-" + clock(now.Add(3*time.Hour)))).To(Succeed())
+				})
+
+				AfterEach(func() {
+					Expect(changewindow.Set("")).To(Succeed())
+				})
+
+				Context("when the quarksStatefulSet belongs to a BOSHDeployment", func() {
+					BeforeEach(func() {
+						desiredQStatefulSet.Labels = map[string]string{bdm.LabelDeploymentName: "bar"}
+						client = fake.NewFakeClient(desiredQStatefulSet)
+						manager.GetClientReturns(client)
+					})
+
+					It("defers the statefulSet outside the window", func() {
+						result, err := reconciler.Reconcile(request)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(result.RequeueAfter).To(BeNumerically(">", time.Hour))
+
+						ss := &appsv1.StatefulSet{}
+						err = client.Get(context.Background(), types.NamespacedName{Name: "foo", Namespace: "default"}, ss)
+						Expect(errors.IsNotFound(err)).To(BeTrue())
+					})
+				})
+
+				It("creates the statefulSet of a QuarksStatefulSet without a BOSHDeployment", func() {
+					result, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(result).To(Equal(reconcile.Result{}))
+
+					ss := &appsv1.StatefulSet{}
+					err = client.Get(context.Background(), types.NamespacedName{Name: "foo", Namespace: "default"}, ss)
+					Expect(err).ToNot(HaveOccurred())
+				})
+			})
+
 			Context("with multiple replicas", func() {
 				var ss *appsv1.StatefulSet
 				BeforeEach(func() {
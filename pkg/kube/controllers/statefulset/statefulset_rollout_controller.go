@@ -14,6 +14,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/backpressure"
 	"code.cloudfoundry.org/quarks-utils/pkg/config"
 	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
 )
@@ -26,7 +27,7 @@ func AddStatefulSetRollout(ctx context.Context, config *config.Config, mgr manag
 
 	// Create a new controller
 	c, err := controller.New("statefulset-rollout-controller", mgr, controller.Options{
-		Reconciler:              r,
+		Reconciler:              backpressure.NewReconciler(ctx, r),
 		MaxConcurrentReconciles: config.MaxQuarksStatefulSetWorkers,
 	})
 	if err != nil {
@@ -79,6 +79,14 @@ func ApplyCRDs(config *rest.Config) error {
 			bdv1.SchemeGroupVersion,
 			&bdv1.BOSHDeploymentValidation,
 		},
+		{
+			bdv1.BOSHDeploymentTemplateResourceName,
+			bdv1.BOSHDeploymentTemplateResourceKind,
+			bdv1.BOSHDeploymentTemplateResourcePlural,
+			bdv1.BOSHDeploymentTemplateResourceShortNames,
+			bdv1.SchemeGroupVersion,
+			&bdv1.BOSHDeploymentTemplateValidation,
+		},
 		{
 			qjv1a1.QuarksJobResourceName,
 			qjv1a1.QuarksJobResourceKind,
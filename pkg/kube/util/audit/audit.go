@@ -0,0 +1,170 @@
+// Package audit records the write operations of the operator in a dedicated log
+package audit
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/correlation"
+)
+
+// Actor is the identity recorded for all write operations of the operator
+const Actor = "operator"
+
+// Operations recorded in the audit log
+const (
+	OperationCreate       = "create"
+	OperationUpdate       = "update"
+	OperationPatch        = "patch"
+	OperationDelete       = "delete"
+	OperationDeleteAll    = "delete-all-of"
+	OperationStatusUpdate = "status-update"
+	OperationStatusPatch  = "status-patch"
+)
+
+// logger is the dedicated audit logger, it discards all entries until SetupLogger is called
+var logger = zap.NewNop()
+
+// SetupLogger initializes the package scoped audit logger. It writes JSON
+// entries to output, which is a file path, 'stdout' or 'stderr'. The audit
+// logger is independent of the operator's log level. An empty output disables
+// audit logs.
+func SetupLogger(output string) error {
+	if output == "" {
+		logger = zap.NewNop()
+		return nil
+	}
+
+	cfg := zap.NewProductionConfig()
+	cfg.OutputPaths = []string{output}
+	cfg.ErrorOutputPaths = []string{"stderr"}
+	cfg.Sampling = nil
+	cfg.DisableCaller = true
+	cfg.DisableStacktrace = true
+
+	l, err := cfg.Build()
+	if err != nil {
+		return errors.Wrapf(err, "building audit logger for '%s'", output)
+	}
+	logger = l.Named("audit")
+	return nil
+}
+
+type deploymentKey struct{}
+
+// NewContext returns a context, which associates the audit log entries with the deployment
+func NewContext(ctx context.Context, deployment types.NamespacedName) context.Context {
+	return context.WithValue(ctx, deploymentKey{}, deployment)
+}
+
+// Log records a write operation on obj and its result
+func Log(ctx context.Context, operation string, obj runtime.Object, err error) {
+	fields := []zap.Field{
+		zap.String("actor", Actor),
+		zap.String("operation", operation),
+		zap.String("kind", kindOf(obj)),
+	}
+	if accessor, aErr := meta.Accessor(obj); aErr == nil {
+		fields = append(fields,
+			zap.String("namespace", accessor.GetNamespace()),
+			zap.String("name", accessor.GetName()),
+		)
+	}
+	if deployment, ok := ctx.Value(deploymentKey{}).(types.NamespacedName); ok {
+		fields = append(fields, zap.String("deployment", deployment.String()))
+	}
+	if id := correlation.ID(ctx); id != "" {
+		fields = append(fields, zap.String("correlation-id", id))
+	}
+
+	if err != nil {
+		fields = append(fields, zap.String("result", "failure"), zap.Error(err))
+	} else {
+		fields = append(fields, zap.String("result", "success"))
+	}
+
+	logger.Info("write operation", fields...)
+}
+
+// kindOf returns the Go type name of obj, since typed objects usually don't have their TypeMeta set
+func kindOf(obj runtime.Object) string {
+	if kind := obj.GetObjectKind().GroupVersionKind().Kind; kind != "" {
+		return kind
+	}
+	return reflect.Indirect(reflect.ValueOf(obj)).Type().Name()
+}
+
+// NewClient returns a client, which records all write operations in the audit log
+func NewClient(c crc.Client) crc.Client {
+	return &auditClient{Client: c}
+}
+
+type auditClient struct {
+	crc.Client
+}
+
+// Create records the creation of obj
+func (c *auditClient) Create(ctx context.Context, obj runtime.Object, opts ...crc.CreateOption) error {
+	err := c.Client.Create(ctx, obj, opts...)
+	Log(ctx, OperationCreate, obj, err)
+	return err
+}
+
+// Update records the update of obj
+func (c *auditClient) Update(ctx context.Context, obj runtime.Object, opts ...crc.UpdateOption) error {
+	err := c.Client.Update(ctx, obj, opts...)
+	Log(ctx, OperationUpdate, obj, err)
+	return err
+}
+
+// Patch records the patch of obj
+func (c *auditClient) Patch(ctx context.Context, obj runtime.Object, patch crc.Patch, opts ...crc.PatchOption) error {
+	err := c.Client.Patch(ctx, obj, patch, opts...)
+	Log(ctx, OperationPatch, obj, err)
+	return err
+}
+
+// Delete records the deletion of obj
+func (c *auditClient) Delete(ctx context.Context, obj runtime.Object, opts ...crc.DeleteOption) error {
+	err := c.Client.Delete(ctx, obj, opts...)
+	Log(ctx, OperationDelete, obj, err)
+	return err
+}
+
+// DeleteAllOf records the deletion of all objects of obj's type
+func (c *auditClient) DeleteAllOf(ctx context.Context, obj runtime.Object, opts ...crc.DeleteAllOfOption) error {
+	err := c.Client.DeleteAllOf(ctx, obj, opts...)
+	Log(ctx, OperationDeleteAll, obj, err)
+	return err
+}
+
+// Status returns a status writer, which records the status updates
+func (c *auditClient) Status() crc.StatusWriter {
+	return &auditStatusWriter{StatusWriter: c.Client.Status()}
+}
+
+type auditStatusWriter struct {
+	crc.StatusWriter
+}
+
+// Update records the status update of obj
+func (w *auditStatusWriter) Update(ctx context.Context, obj runtime.Object, opts ...crc.UpdateOption) error {
+	err := w.StatusWriter.Update(ctx, obj, opts...)
+	Log(ctx, OperationStatusUpdate, obj, err)
+	return err
+}
+
+// Patch records the status patch of obj
+func (w *auditStatusWriter) Patch(ctx context.Context, obj runtime.Object, patch crc.Patch, opts ...crc.PatchOption) error {
+	err := w.StatusWriter.Patch(ctx, obj, patch, opts...)
+	Log(ctx, OperationStatusPatch, obj, err)
+	return err
+}
@@ -0,0 +1,130 @@
+package audit_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"code.cloudfoundry.org/cf-operator/pkg/kube/controllers/fakes"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/audit"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/correlation"
+)
+
+var _ = Describe("Audit", func() {
+	var (
+		dir          string
+		output       string
+		client       *fakes.FakeClient
+		statusWriter *fakes.FakeStatusWriter
+		ctx          context.Context
+		secret       *corev1.Secret
+	)
+
+	entries := func() []map[string]interface{} {
+		content, err := ioutil.ReadFile(output)
+		Expect(err).ToNot(HaveOccurred())
+
+		result := []map[string]interface{}{}
+		for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+			if line == "" {
+				continue
+			}
+			entry := map[string]interface{}{}
+			Expect(json.Unmarshal([]byte(line), &entry)).To(Succeed())
+			result = append(result, entry)
+		}
+		return result
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "audit")
+		Expect(err).ToNot(HaveOccurred())
+		output = filepath.Join(dir, "audit.log")
+		Expect(audit.SetupLogger(output)).To(Succeed())
+
+		client = &fakes.FakeClient{}
+		statusWriter = &fakes.FakeStatusWriter{}
+		client.StatusReturns(statusWriter)
+
+		ctx = audit.NewContext(context.Background(), types.NamespacedName{Namespace: "default", Name: "foo"})
+		secret = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "foo.with-ops", Namespace: "default"}}
+	})
+
+	AfterEach(func() {
+		Expect(audit.SetupLogger("")).To(Succeed())
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	Describe("NewClient", func() {
+		It("records successful write operations", func() {
+			c := audit.NewClient(client)
+			Expect(c.Create(ctx, secret)).To(Succeed())
+			Expect(c.Delete(ctx, secret)).To(Succeed())
+			Expect(client.CreateCallCount()).To(Equal(1))
+			Expect(client.DeleteCallCount()).To(Equal(1))
+
+			logged := entries()
+			Expect(logged).To(HaveLen(2))
+			Expect(logged[0]).To(HaveKeyWithValue("logger", "audit"))
+			Expect(logged[0]).To(HaveKeyWithValue("actor", "operator"))
+			Expect(logged[0]).To(HaveKeyWithValue("operation", "create"))
+			Expect(logged[0]).To(HaveKeyWithValue("kind", "Secret"))
+			Expect(logged[0]).To(HaveKeyWithValue("namespace", "default"))
+			Expect(logged[0]).To(HaveKeyWithValue("name", "foo.with-ops"))
+			Expect(logged[0]).To(HaveKeyWithValue("deployment", "default/foo"))
+			Expect(logged[0]).To(HaveKeyWithValue("result", "success"))
+			Expect(logged[1]).To(HaveKeyWithValue("operation", "delete"))
+		})
+
+		It("records failed write operations", func() {
+			client.UpdateReturns(errors.New("fake-error"))
+
+			err := audit.NewClient(client).Update(ctx, secret)
+			Expect(err).To(MatchError("fake-error"))
+
+			logged := entries()
+			Expect(logged).To(HaveLen(1))
+			Expect(logged[0]).To(HaveKeyWithValue("operation", "update"))
+			Expect(logged[0]).To(HaveKeyWithValue("result", "failure"))
+			Expect(logged[0]).To(HaveKeyWithValue("error", "fake-error"))
+		})
+
+		It("records the correlation ID of the reconcile", func() {
+			ctx = correlation.NewContext(ctx)
+			Expect(audit.NewClient(client).Create(ctx, secret)).To(Succeed())
+
+			logged := entries()
+			Expect(logged).To(HaveLen(1))
+			Expect(logged[0]).To(HaveKeyWithValue("correlation-id", correlation.ID(ctx)))
+		})
+
+		It("records status updates", func() {
+			Expect(audit.NewClient(client).Status().Update(ctx, secret)).To(Succeed())
+			Expect(statusWriter.UpdateCallCount()).To(Equal(1))
+
+			logged := entries()
+			Expect(logged).To(HaveLen(1))
+			Expect(logged[0]).To(HaveKeyWithValue("operation", "status-update"))
+		})
+
+		It("doesn't record reads", func() {
+			Expect(audit.NewClient(client).Get(ctx, crc.ObjectKey{Name: "foo.with-ops", Namespace: "default"}, secret)).To(Succeed())
+			Expect(client.GetCallCount()).To(Equal(1))
+
+			Expect(entries()).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,214 @@
+// Package backpressure increases the requeue delays of reconciles, while the
+// latency of the Kubernetes API server is high
+package backpressure
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+const (
+	// DefaultFactor is the default factor, by which requeue delays are scaled under backpressure
+	DefaultFactor = 2.0
+	// DefaultMaxDelay is the default upper limit of scaled requeue delays
+	DefaultMaxDelay = 5 * time.Minute
+
+	// sampleWeight is the weight of a new sample in the moving average of the latency
+	sampleWeight = 0.2
+	// staleAfter is the age, after which the latency average is ignored, so
+	// the backpressure ends, if no API requests are made
+	staleAfter = time.Minute
+	// minRequeueAfter is the delay, which is scaled for immediate requeues
+	minRequeueAfter = time.Second
+)
+
+var (
+	// latencyThreshold is the API latency, above which requeue delays are scaled. Zero disables the backpressure.
+	latencyThreshold time.Duration
+	factor           = DefaultFactor
+	maxDelay         = DefaultMaxDelay
+
+	tracker = &latencyTracker{}
+)
+
+// SetBackpressure initializes the package scoped backpressure settings and
+// resets the measured latency. While the average API latency exceeds
+// threshold, requeue delays are multiplied by scaleFactor and by the ratio of
+// latency and threshold, up to maxRequeueAfter. A zero threshold disables
+// the backpressure.
+func SetBackpressure(threshold time.Duration, scaleFactor float64, maxRequeueAfter time.Duration) error {
+	if threshold < 0 {
+		return errors.Errorf("invalid backpressure latency threshold '%s', must not be negative", threshold)
+	}
+	if scaleFactor < 1 {
+		return errors.Errorf("invalid backpressure factor '%v', must be at least 1", scaleFactor)
+	}
+	if maxRequeueAfter <= 0 {
+		return errors.Errorf("invalid backpressure max delay '%s', must be positive", maxRequeueAfter)
+	}
+
+	latencyThreshold = threshold
+	factor = scaleFactor
+	maxDelay = maxRequeueAfter
+	tracker.reset()
+	return nil
+}
+
+// latencyTracker keeps an exponential moving average of the API latency
+type latencyTracker struct {
+	sync.Mutex
+	average    time.Duration
+	last       time.Time
+	overloaded bool
+}
+
+func (t *latencyTracker) observe(latency time.Duration) {
+	t.Lock()
+	defer t.Unlock()
+
+	if t.last.IsZero() || time.Since(t.last) > staleAfter {
+		t.average = latency
+	} else {
+		t.average = time.Duration(sampleWeight*float64(latency) + (1-sampleWeight)*float64(t.average))
+	}
+	t.last = time.Now()
+}
+
+func (t *latencyTracker) latency() time.Duration {
+	t.Lock()
+	defer t.Unlock()
+
+	if t.last.IsZero() || time.Since(t.last) > staleAfter {
+		return 0
+	}
+	return t.average
+}
+
+// transition records whether the API server is overloaded and returns true,
+// if that changed
+func (t *latencyTracker) transition(overloaded bool) bool {
+	t.Lock()
+	defer t.Unlock()
+
+	changed := t.overloaded != overloaded
+	t.overloaded = overloaded
+	return changed
+}
+
+func (t *latencyTracker) reset() {
+	t.Lock()
+	defer t.Unlock()
+
+	t.average = 0
+	t.last = time.Time{}
+	t.overloaded = false
+}
+
+// Observe records the latency of an API request
+func Observe(latency time.Duration) {
+	tracker.observe(latency)
+}
+
+// Latency returns the moving average of the latency of recent API requests
+func Latency() time.Duration {
+	return tracker.latency()
+}
+
+// RequeueAfter returns the requeue delay scaled by the current backpressure.
+// Delays are only increased, never decreased.
+func RequeueAfter(delay time.Duration) time.Duration {
+	latency := Latency()
+	if latencyThreshold == 0 || latency <= latencyThreshold {
+		return delay
+	}
+
+	base := delay
+	if base < minRequeueAfter {
+		base = minRequeueAfter
+	}
+	scaled := time.Duration(float64(base) * factor * float64(latency) / float64(latencyThreshold))
+	if scaled > maxDelay {
+		scaled = maxDelay
+	}
+	if scaled < delay {
+		return delay
+	}
+	return scaled
+}
+
+// WrapTransport measures the latency of all API requests made with config.
+// Watches are long running, so they are left out.
+func WrapTransport(config *rest.Config) {
+	config.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		return &latencyRoundTripper{rt: rt}
+	})
+}
+
+type latencyRoundTripper struct {
+	rt http.RoundTripper
+}
+
+// RoundTrip observes the time until the response headers are received
+func (l *latencyRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if watch := req.URL.Query().Get("watch"); watch == "true" || watch == "1" {
+		return l.rt.RoundTrip(req)
+	}
+
+	start := time.Now()
+	resp, err := l.rt.RoundTrip(req)
+	Observe(time.Since(start))
+	return resp, err
+}
+
+// Reconciler scales the requeue delays of the wrapped reconciler
+type Reconciler struct {
+	ctx        context.Context
+	reconciler reconcile.Reconciler
+}
+
+// NewReconciler wraps reconciler, so its requeues are delayed further, while
+// the API latency exceeds the threshold. Failed reconciles are left to the
+// exponential backoff of the controller's rate limiter.
+func NewReconciler(ctx context.Context, reconciler reconcile.Reconciler) reconcile.Reconciler {
+	return &Reconciler{
+		ctx:        ctx,
+		reconciler: reconciler,
+	}
+}
+
+// Reconcile calls the wrapped reconciler and scales the requeue delay of its result
+func (r *Reconciler) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	result, err := r.reconciler.Reconcile(request)
+	if latencyThreshold == 0 {
+		return result, err
+	}
+
+	latency := Latency()
+	overloaded := latency > latencyThreshold
+	if tracker.transition(overloaded) {
+		if overloaded {
+			ctxlog.Infof(r.ctx, "API latency %s exceeds %s, delaying requeues", latency, latencyThreshold)
+		} else {
+			ctxlog.Infof(r.ctx, "API latency %s recovered below %s, requeues are no longer delayed", latency, latencyThreshold)
+		}
+	}
+
+	if err != nil || (!result.Requeue && result.RequeueAfter == 0) {
+		return result, err
+	}
+
+	delay := RequeueAfter(result.RequeueAfter)
+	if delay != result.RequeueAfter {
+		ctxlog.Debugf(r.ctx, "Delaying requeue of '%s' to %s due to API latency %s", request.NamespacedName, delay, latency)
+		result.RequeueAfter = delay
+	}
+	return result, nil
+}
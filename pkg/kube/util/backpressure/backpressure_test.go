@@ -0,0 +1,204 @@
+package backpressure_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/backpressure"
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+	helper "code.cloudfoundry.org/quarks-utils/testing/testhelper"
+)
+
+// resultReconciler returns the given result for every reconcile
+type resultReconciler struct {
+	result reconcile.Result
+	err    error
+}
+
+func (r resultReconciler) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	return r.result, r.err
+}
+
+var _ = Describe("Backpressure", func() {
+	var (
+		logs    *observer.ObservedLogs
+		ctx     context.Context
+		request reconcile.Request
+	)
+
+	BeforeEach(func() {
+		var log *zap.SugaredLogger
+		logs, log = helper.NewTestLogger()
+		ctx = ctxlog.NewParentContext(log)
+		request = reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "foo"}}
+
+		Expect(backpressure.SetBackpressure(100*time.Millisecond, 2, time.Minute)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(backpressure.SetBackpressure(0, backpressure.DefaultFactor, backpressure.DefaultMaxDelay)).To(Succeed())
+	})
+
+	Describe("SetBackpressure", func() {
+		It("rejects negative thresholds", func() {
+			Expect(backpressure.SetBackpressure(-time.Second, 2, time.Minute)).To(MatchError(ContainSubstring("must not be negative")))
+		})
+
+		It("rejects factors below 1", func() {
+			Expect(backpressure.SetBackpressure(time.Second, 0.5, time.Minute)).To(MatchError(ContainSubstring("must be at least 1")))
+		})
+
+		It("rejects max delays, which aren't positive", func() {
+			Expect(backpressure.SetBackpressure(time.Second, 2, 0)).To(MatchError(ContainSubstring("must be positive")))
+		})
+
+		It("resets the measured latency", func() {
+			backpressure.Observe(time.Second)
+			Expect(backpressure.SetBackpressure(time.Second, 2, time.Minute)).To(Succeed())
+			Expect(backpressure.Latency()).To(BeZero())
+		})
+	})
+
+	Describe("Latency", func() {
+		It("averages the observed latencies", func() {
+			backpressure.Observe(100 * time.Millisecond)
+			Expect(backpressure.Latency()).To(Equal(100 * time.Millisecond))
+
+			backpressure.Observe(600 * time.Millisecond)
+			Expect(backpressure.Latency()).To(Equal(200 * time.Millisecond))
+		})
+	})
+
+	Describe("RequeueAfter", func() {
+		It("doesn't change delays, while the latency is below the threshold", func() {
+			backpressure.Observe(50 * time.Millisecond)
+			Expect(backpressure.RequeueAfter(5 * time.Second)).To(Equal(5 * time.Second))
+		})
+
+		It("scales delays by the factor and the ratio of latency and threshold", func() {
+			backpressure.Observe(300 * time.Millisecond)
+			Expect(backpressure.RequeueAfter(5 * time.Second)).To(Equal(30 * time.Second))
+		})
+
+		It("scales immediate requeues from a second", func() {
+			backpressure.Observe(200 * time.Millisecond)
+			Expect(backpressure.RequeueAfter(0)).To(Equal(4 * time.Second))
+		})
+
+		It("limits the delays to the max delay", func() {
+			backpressure.Observe(10 * time.Second)
+			Expect(backpressure.RequeueAfter(5 * time.Second)).To(Equal(time.Minute))
+		})
+
+		It("never decreases delays", func() {
+			backpressure.Observe(10 * time.Second)
+			Expect(backpressure.RequeueAfter(time.Hour)).To(Equal(time.Hour))
+		})
+
+		It("doesn't change delays, if the backpressure is disabled", func() {
+			Expect(backpressure.SetBackpressure(0, 2, time.Minute)).To(Succeed())
+			backpressure.Observe(10 * time.Second)
+			Expect(backpressure.RequeueAfter(5 * time.Second)).To(Equal(5 * time.Second))
+		})
+	})
+
+	Describe("WrapTransport", func() {
+		var server *httptest.Server
+
+		BeforeEach(func() {
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(20 * time.Millisecond)
+			}))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		get := func(url string) {
+			config := &rest.Config{Host: server.URL}
+			backpressure.WrapTransport(config)
+			transport, err := rest.TransportFor(config)
+			Expect(err).ToNot(HaveOccurred())
+			client := &http.Client{Transport: transport}
+			resp, err := client.Get(url)
+			Expect(err).ToNot(HaveOccurred())
+			resp.Body.Close()
+		}
+
+		It("observes the latency of requests", func() {
+			get(server.URL + "/api/v1/namespaces/default/secrets")
+			Expect(backpressure.Latency()).To(BeNumerically(">=", 20*time.Millisecond))
+		})
+
+		It("doesn't observe watches", func() {
+			get(server.URL + "/api/v1/namespaces/default/secrets?watch=true")
+			Expect(backpressure.Latency()).To(BeZero())
+		})
+	})
+
+	Describe("NewReconciler", func() {
+		It("delays requeues, while the latency exceeds the threshold", func() {
+			backpressure.Observe(200 * time.Millisecond)
+			r := backpressure.NewReconciler(ctx, resultReconciler{result: reconcile.Result{RequeueAfter: 5 * time.Second}})
+
+			result, err := r.Reconcile(request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(reconcile.Result{RequeueAfter: 20 * time.Second}))
+			Expect(logs.FilterMessageSnippet("API latency 200ms exceeds 100ms, delaying requeues").Len()).To(Equal(1))
+		})
+
+		It("logs when the latency recovers", func() {
+			backpressure.Observe(200 * time.Millisecond)
+			r := backpressure.NewReconciler(ctx, resultReconciler{})
+			_, err := r.Reconcile(request)
+			Expect(err).ToNot(HaveOccurred())
+
+			for i := 0; i < 10; i++ {
+				backpressure.Observe(10 * time.Millisecond)
+			}
+			_, err = r.Reconcile(request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(logs.FilterMessageSnippet("recovered below 100ms, requeues are no longer delayed").Len()).To(Equal(1))
+		})
+
+		It("doesn't delay requeues, while the latency is below the threshold", func() {
+			backpressure.Observe(50 * time.Millisecond)
+			r := backpressure.NewReconciler(ctx, resultReconciler{result: reconcile.Result{Requeue: true}})
+
+			result, err := r.Reconcile(request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(reconcile.Result{Requeue: true}))
+		})
+
+		It("doesn't requeue results, which aren't requeued", func() {
+			backpressure.Observe(200 * time.Millisecond)
+			r := backpressure.NewReconciler(ctx, resultReconciler{})
+
+			result, err := r.Reconcile(request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(reconcile.Result{}))
+		})
+
+		It("leaves failed reconciles to the rate limiter", func() {
+			backpressure.Observe(200 * time.Millisecond)
+			r := backpressure.NewReconciler(ctx, resultReconciler{err: fmt.Errorf("failed")})
+
+			result, err := r.Reconcile(request)
+			Expect(err).To(MatchError("failed"))
+			Expect(result).To(Equal(reconcile.Result{}))
+		})
+	})
+})
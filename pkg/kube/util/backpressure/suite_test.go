@@ -0,0 +1,13 @@
+package backpressure_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestBackpressure(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Backpressure Suite")
+}
@@ -0,0 +1,125 @@
+package changewindow
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Window is a daily time window in UTC, during which changes may be applied.
+// A window whose end is before its start spans midnight.
+type Window struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+const day = 24 * time.Hour
+
+// Parse returns the window for a schedule like '22:00-06:00'.
+// An empty schedule returns nil, which means changes are always allowed.
+func Parse(schedule string) (*Window, error) {
+	if schedule == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(schedule, "-")
+	if len(parts) != 2 {
+		return nil, errors.Errorf("invalid change window '%s', expected format 'HH:MM-HH:MM'", schedule)
+	}
+
+	start, err := parseClock(parts[0])
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid start of change window '%s'", schedule)
+	}
+	end, err := parseClock(parts[1])
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid end of change window '%s'", schedule)
+	}
+	if start == end {
+		return nil, errors.Errorf("invalid change window '%s', start and end are equal", schedule)
+	}
+
+	return &Window{Start: start, End: end}, nil
+}
+
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Contains returns true if the given time is inside the window.
+// A nil window contains all times.
+func (w *Window) Contains(now time.Time) bool {
+	if w == nil {
+		return true
+	}
+
+	offset := sinceMidnight(now)
+	if w.Start < w.End {
+		return offset >= w.Start && offset < w.End
+	}
+	return offset >= w.Start || offset < w.End
+}
+
+// Next returns the time at which the window opens next.
+// If the window is open, the given time is returned.
+func (w *Window) Next(now time.Time) time.Time {
+	if w.Contains(now) {
+		return now
+	}
+
+	offset := sinceMidnight(now)
+	wait := w.Start - offset
+	if wait < 0 {
+		wait += day
+	}
+	return now.Add(wait)
+}
+
+// String returns the schedule of the window
+func (w *Window) String() string {
+	if w == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s-%s", clock(w.Start), clock(w.End))
+}
+
+func clock(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+}
+
+func sinceMidnight(t time.Time) time.Duration {
+	t = t.UTC()
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond())
+}
+
+// operatorWindow is the daily window, in which the operator writes the
+// objects of BOSHDeployments. A nil window allows writes at any time.
+var operatorWindow *Window
+
+// Set initializes the operator wide change window from a schedule like
+// '22:00-06:00' (UTC). An empty schedule disables the window.
+func Set(schedule string) error {
+	w, err := Parse(schedule)
+	if err != nil {
+		return err
+	}
+	operatorWindow = w
+	return nil
+}
+
+// Current returns the operator wide change window, nil if it is disabled
+func Current() *Window {
+	return operatorWindow
+}
+
+// Deferral returns how long writes are deferred at the given time, until the
+// operator wide change window opens. It returns zero inside the window.
+func Deferral(now time.Time) time.Duration {
+	return operatorWindow.Next(now).Sub(now)
+}
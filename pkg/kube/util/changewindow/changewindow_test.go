@@ -0,0 +1,104 @@
+package changewindow_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/changewindow"
+)
+
+var _ = Describe("Window", func() {
+	at := func(clock string) time.Time {
+		t, err := time.Parse(time.RFC3339, "2020-03-01T"+clock+":00Z")
+		Expect(err).ToNot(HaveOccurred())
+		return t
+	}
+
+	Describe("Parse", func() {
+		It("returns nil for an empty schedule", func() {
+			w, err := changewindow.Parse("")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(w).To(BeNil())
+		})
+
+		It("parses a schedule", func() {
+			w, err := changewindow.Parse("22:00-06:30")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(w.Start).To(Equal(22 * time.Hour))
+			Expect(w.End).To(Equal(6*time.Hour + 30*time.Minute))
+			Expect(w.String()).To(Equal("22:00-06:30"))
+		})
+
+		It("rejects invalid schedules", func() {
+			for _, schedule := range []string{"22:00", "25:00-01:00", "01:00-01:00", "a-b"} {
+				_, err := changewindow.Parse(schedule)
+				Expect(err).To(HaveOccurred(), schedule)
+			}
+		})
+	})
+
+	Describe("Contains", func() {
+		It("always contains for a nil window", func() {
+			var w *changewindow.Window
+			Expect(w.Contains(at("12:00"))).To(BeTrue())
+		})
+
+		It("handles windows within a day", func() {
+			w, _ := changewindow.Parse("09:00-17:00")
+			Expect(w.Contains(at("08:59"))).To(BeFalse())
+			Expect(w.Contains(at("09:00"))).To(BeTrue())
+			Expect(w.Contains(at("16:59"))).To(BeTrue())
+			Expect(w.Contains(at("17:00"))).To(BeFalse())
+		})
+
+		It("handles windows spanning midnight", func() {
+			w, _ := changewindow.Parse("22:00-06:00")
+			Expect(w.Contains(at("21:59"))).To(BeFalse())
+			Expect(w.Contains(at("23:00"))).To(BeTrue())
+			Expect(w.Contains(at("05:59"))).To(BeTrue())
+			Expect(w.Contains(at("06:00"))).To(BeFalse())
+		})
+	})
+
+	Describe("Next", func() {
+		It("returns the given time if the window is open", func() {
+			w, _ := changewindow.Parse("09:00-17:00")
+			Expect(w.Next(at("10:00"))).To(Equal(at("10:00")))
+		})
+
+		It("returns the start of the window later that day", func() {
+			w, _ := changewindow.Parse("22:00-06:00")
+			Expect(w.Next(at("12:00"))).To(Equal(at("22:00")))
+		})
+
+		It("returns the start of the window the next day", func() {
+			w, _ := changewindow.Parse("09:00-17:00")
+			Expect(w.Next(at("18:00"))).To(Equal(at("09:00").Add(24 * time.Hour)))
+		})
+	})
+
+	Describe("Deferral", func() {
+		AfterEach(func() {
+			Expect(changewindow.Set("")).To(Succeed())
+		})
+
+		It("never defers without a change window", func() {
+			Expect(changewindow.Set("")).To(Succeed())
+			Expect(changewindow.Current()).To(BeNil())
+			Expect(changewindow.Deferral(at("12:00"))).To(BeZero())
+		})
+
+		It("defers until the change window opens", func() {
+			Expect(changewindow.Set("22:00-06:00")).To(Succeed())
+			Expect(changewindow.Current().String()).To(Equal("22:00-06:00"))
+			Expect(changewindow.Deferral(at("12:00"))).To(Equal(10 * time.Hour))
+			Expect(changewindow.Deferral(at("23:00"))).To(BeZero())
+		})
+
+		It("rejects invalid schedules", func() {
+			Expect(changewindow.Set("22:00")).To(HaveOccurred())
+		})
+	})
+})
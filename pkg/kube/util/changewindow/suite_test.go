@@ -0,0 +1,13 @@
+package changewindow_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestChangeWindow(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Change Window Suite")
+}
@@ -0,0 +1,81 @@
+// Package correlation tags the logs and events of a single reconcile pass with a correlation ID
+package correlation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+
+	"code.cloudfoundry.org/cf-operator/pkg/kube/apis"
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+// AnnotationCorrelationID is the annotation on events, which contains the
+// correlation ID of the reconcile pass, which emitted them
+var AnnotationCorrelationID = fmt.Sprintf("%s/correlation-id", apis.GroupName)
+
+type idKey struct{}
+
+// NewID returns a new random correlation ID of 8 hex characters
+func NewID() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "00000000"
+	}
+	return hex.EncodeToString(b)
+}
+
+// NewContext returns a context for one reconcile pass with a new correlation ID.
+// The ID is appended to the name of the context's logger and added as an
+// annotation to all events recorded with the context's recorder.
+func NewContext(ctx context.Context) context.Context {
+	id := NewID()
+	ctx = context.WithValue(ctx, idKey{}, id)
+	recorder := &recorder{EventRecorder: ctxlog.ExtractRecorder(ctx), id: id}
+	return ctxlog.NewContextWithRecorder(ctx, id, recorder)
+}
+
+// ID returns the correlation ID of the context, or an empty string if it has none
+func ID(ctx context.Context) string {
+	id, _ := ctx.Value(idKey{}).(string)
+	return id
+}
+
+// recorder annotates all events with a correlation ID
+type recorder struct {
+	record.EventRecorder
+	id string
+}
+
+// Event records an event annotated with the correlation ID
+func (r *recorder) Event(object runtime.Object, eventtype, reason, message string) {
+	r.EventRecorder.AnnotatedEventf(object, r.annotations(nil), eventtype, reason, "%s", message)
+}
+
+// Eventf records an event annotated with the correlation ID
+func (r *recorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.EventRecorder.AnnotatedEventf(object, r.annotations(nil), eventtype, reason, messageFmt, args...)
+}
+
+// PastEventf records an event with a timestamp, annotations can't be set on those
+func (r *recorder) PastEventf(object runtime.Object, timestamp metav1.Time, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.EventRecorder.PastEventf(object, timestamp, eventtype, reason, messageFmt, args...)
+}
+
+// AnnotatedEventf records an event, whose annotations include the correlation ID
+func (r *recorder) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.EventRecorder.AnnotatedEventf(object, r.annotations(annotations), eventtype, reason, messageFmt, args...)
+}
+
+func (r *recorder) annotations(annotations map[string]string) map[string]string {
+	result := map[string]string{AnnotationCorrelationID: r.id}
+	for k, v := range annotations {
+		result[k] = v
+	}
+	return result
+}
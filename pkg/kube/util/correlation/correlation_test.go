@@ -0,0 +1,82 @@
+package correlation_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/correlation"
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+	helper "code.cloudfoundry.org/quarks-utils/testing/testhelper"
+)
+
+// annotationRecorder keeps the annotations of the recorded events
+type annotationRecorder struct {
+	record.FakeRecorder
+	annotations []map[string]string
+}
+
+func (r *annotationRecorder) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.annotations = append(r.annotations, annotations)
+}
+
+var _ = Describe("Correlation", func() {
+	var (
+		logs     *observer.ObservedLogs
+		recorder *annotationRecorder
+		ctx      context.Context
+		secret   *corev1.Secret
+	)
+
+	BeforeEach(func() {
+		var log *zap.SugaredLogger
+		logs, log = helper.NewTestLogger()
+		recorder = &annotationRecorder{}
+		ctx = ctxlog.NewContextWithRecorder(ctxlog.NewParentContext(log), "test", recorder)
+		secret = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"}}
+	})
+
+	Describe("NewContext", func() {
+		It("generates a new ID for each context", func() {
+			first := correlation.ID(correlation.NewContext(ctx))
+			second := correlation.ID(correlation.NewContext(ctx))
+
+			Expect(first).To(MatchRegexp("^[0-9a-f]{8}$"))
+			Expect(second).To(MatchRegexp("^[0-9a-f]{8}$"))
+			Expect(first).ToNot(Equal(second))
+		})
+
+		It("adds the ID to the logger name", func() {
+			cctx := correlation.NewContext(ctx)
+			ctxlog.Info(cctx, "reconciling")
+
+			Expect(logs.FilterMessage("reconciling").All()).To(HaveLen(1))
+			Expect(logs.FilterMessage("reconciling").All()[0].LoggerName).To(HaveSuffix("test." + correlation.ID(cctx)))
+		})
+
+		It("annotates events with the ID", func() {
+			cctx := correlation.NewContext(ctx)
+			ctxlog.WithEvent(secret, "Reason").Infof(cctx, "message")
+			ctxlog.ExtractRecorder(cctx).AnnotatedEventf(secret, map[string]string{"foo": "bar"}, corev1.EventTypeNormal, "Reason", "message")
+
+			Expect(recorder.annotations).To(HaveLen(2))
+			Expect(recorder.annotations[0]).To(Equal(map[string]string{correlation.AnnotationCorrelationID: correlation.ID(cctx)}))
+			Expect(recorder.annotations[1]).To(HaveKeyWithValue("foo", "bar"))
+			Expect(recorder.annotations[1]).To(HaveKeyWithValue(correlation.AnnotationCorrelationID, correlation.ID(cctx)))
+		})
+	})
+
+	Describe("ID", func() {
+		It("is empty without a correlation context", func() {
+			Expect(correlation.ID(ctx)).To(BeEmpty())
+		})
+	})
+})
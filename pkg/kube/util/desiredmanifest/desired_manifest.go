@@ -8,7 +8,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
-	"code.cloudfoundry.org/quarks-utils/pkg/names"
+	bdnames "code.cloudfoundry.org/cf-operator/pkg/kube/util/names"
 	"code.cloudfoundry.org/quarks-utils/pkg/versionedsecretstore"
 )
 
@@ -16,13 +16,15 @@ import (
 type DesiredManifest struct {
 	client               client.Client
 	versionedSecretStore versionedsecretstore.VersionedSecretStore
+	secretNamer          bdnames.SecretNamer
 }
 
 // NewDesiredManifest constructs a resolver
-func NewDesiredManifest(client client.Client) *DesiredManifest {
+func NewDesiredManifest(client client.Client, secretNamer bdnames.SecretNamer) *DesiredManifest {
 	return &DesiredManifest{
 		client:               client,
 		versionedSecretStore: versionedsecretstore.NewVersionedSecretStore(client),
+		secretNamer:          secretNamer,
 	}
 }
 
@@ -30,7 +32,7 @@ func NewDesiredManifest(client client.Client) *DesiredManifest {
 // and unmarshals it into a Manifest object
 func (r *DesiredManifest) DesiredManifest(ctx context.Context, boshDeploymentName, namespace string) (*bdm.Manifest, error) {
 	// unversioned desired manifest name
-	secretName := names.DesiredManifestName(boshDeploymentName, "")
+	secretName := r.secretNamer.DesiredManifestName(boshDeploymentName, "")
 
 	secret, err := r.versionedSecretStore.Latest(ctx, namespace, secretName)
 	if err != nil {
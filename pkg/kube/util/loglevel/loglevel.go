@@ -0,0 +1,68 @@
+// Package loglevel overrides the log level of the context's logger for single reconciles
+package loglevel
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+// NewContext returns a context, whose logger logs from level onward,
+// independent of the level of the parent context's logger. Level is one of
+// zap's level names, e.g. 'debug'.
+func NewContext(ctx context.Context, level string) (context.Context, error) {
+	var l zapcore.Level
+	if err := l.Set(level); err != nil {
+		return ctx, errors.Wrapf(err, "invalid log level '%s'", level)
+	}
+
+	log := ctxlog.ExtractLoggerWithOptions(ctx, zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return &levelCore{Core: core, level: l}
+	}))
+	return &loggerContext{Context: ctx, logger: ctxlog.NewParentContext(log)}, nil
+}
+
+// loggerContext replaces the logger of its parent context. The logger key of
+// the ctxlog package isn't exported, so the logger is looked up in a separate
+// context first.
+type loggerContext struct {
+	context.Context
+	logger context.Context
+}
+
+// Value returns the logger of the logger context, or the parent's value
+func (c *loggerContext) Value(key interface{}) interface{} {
+	if v := c.logger.Value(key); v != nil {
+		return v
+	}
+	return c.Context.Value(key)
+}
+
+// levelCore checks the level of the entries against its own level instead
+// of the level of the wrapped core
+type levelCore struct {
+	zapcore.Core
+	level zapcore.Level
+}
+
+// Enabled returns true, if the level is enabled
+func (c *levelCore) Enabled(l zapcore.Level) bool {
+	return c.level.Enabled(l)
+}
+
+// With adds structured context to the wrapped core
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields), level: c.level}
+}
+
+// Check adds the core to the checked entry, if the entry's level is enabled
+func (c *levelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
@@ -0,0 +1,80 @@
+package loglevel_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/loglevel"
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+type key struct{}
+
+var _ = Describe("NewContext", func() {
+	var (
+		logs *observer.ObservedLogs
+		ctx  context.Context
+	)
+
+	BeforeEach(func() {
+		var observed zapcore.Core
+		observed, logs = observer.New(zap.InfoLevel)
+		ctx = ctxlog.NewParentContext(zap.New(observed).Sugar())
+	})
+
+	It("logs below the level of the parent logger", func() {
+		lctx, err := loglevel.NewContext(ctx, "debug")
+		Expect(err).ToNot(HaveOccurred())
+
+		ctxlog.Debug(lctx, "details")
+		ctxlog.Debug(ctx, "hidden")
+
+		Expect(logs.FilterMessage("details").All()).To(HaveLen(1))
+		Expect(logs.FilterMessage("hidden").All()).To(BeEmpty())
+	})
+
+	It("drops entries below its level", func() {
+		lctx, err := loglevel.NewContext(ctx, "error")
+		Expect(err).ToNot(HaveOccurred())
+
+		ctxlog.Info(lctx, "noise")
+		ctxlog.Error(lctx, "failure")
+
+		Expect(logs.FilterMessage("noise").All()).To(BeEmpty())
+		Expect(logs.FilterMessage("failure").All()).To(HaveLen(1))
+	})
+
+	It("keeps the level for named loggers and fields", func() {
+		lctx, err := loglevel.NewContext(ctx, "debug")
+		Expect(err).ToNot(HaveOccurred())
+
+		ctxlog.ExtractLogger(lctx).Named("child").With("foo", "bar").Debug("details")
+
+		Expect(logs.FilterMessage("details").All()).To(HaveLen(1))
+		Expect(logs.FilterMessage("details").All()[0].LoggerName).To(Equal("child"))
+	})
+
+	It("keeps the values and deadline of the parent context", func() {
+		parent, cancel := context.WithTimeout(context.WithValue(ctx, key{}, "value"), time.Minute)
+		defer cancel()
+
+		lctx, err := loglevel.NewContext(parent, "debug")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(lctx.Value(key{})).To(Equal("value"))
+		_, ok := lctx.Deadline()
+		Expect(ok).To(BeTrue())
+	})
+
+	It("fails for invalid levels", func() {
+		lctx, err := loglevel.NewContext(ctx, "verbose")
+		Expect(err).To(MatchError(ContainSubstring("invalid log level 'verbose'")))
+		Expect(lctx).To(Equal(ctx))
+	})
+})
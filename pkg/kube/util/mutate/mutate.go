@@ -5,6 +5,7 @@ import (
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
@@ -94,6 +95,7 @@ func QuarksSecretMutateFn(qSec *qsv1a1.QuarksSecret) controllerutil.MutateFn {
 // SecretMutateFn returns MutateFn which mutates Secret including:
 // - labels, annotations
 // - stringData
+// - data, which replaces all existing keys once one of them changed
 func SecretMutateFn(s *corev1.Secret) controllerutil.MutateFn {
 	updated := s.DeepCopy()
 	return func() error {
@@ -109,6 +111,16 @@ func SecretMutateFn(s *corev1.Secret) controllerutil.MutateFn {
 				break
 			}
 		}
+		// Binary data replaces all existing data
+		for key, data := range updated.Data {
+			oriData, ok := s.Data[key]
+			if ok && reflect.DeepEqual(oriData, data) {
+				continue
+			} else {
+				s.Data = updated.Data
+				break
+			}
+		}
 		return nil
 	}
 }
@@ -127,3 +139,40 @@ func ServiceMutateFn(svc *corev1.Service) controllerutil.MutateFn {
 		return nil
 	}
 }
+
+// ConfigMapMutateFn returns MutateFn which mutates ConfigMap including:
+// - labels, annotations
+// - data
+func ConfigMapMutateFn(cm *corev1.ConfigMap) controllerutil.MutateFn {
+	updated := cm.DeepCopy()
+	return func() error {
+		cm.Labels = updated.Labels
+		cm.Annotations = updated.Annotations
+		cm.Data = updated.Data
+		return nil
+	}
+}
+
+// ServiceMonitorMutateFn returns MutateFn which mutates an unstructured ServiceMonitor including:
+// - labels, annotations
+// - spec
+func ServiceMonitorMutateFn(sm *unstructured.Unstructured) controllerutil.MutateFn {
+	updated := sm.DeepCopy()
+	return func() error {
+		sm.SetLabels(updated.GetLabels())
+		sm.SetAnnotations(updated.GetAnnotations())
+		sm.Object["spec"] = updated.Object["spec"]
+		return nil
+	}
+}
+
+// ServiceExportMutateFn returns MutateFn which mutates an unstructured ServiceExport including:
+// - labels, annotations
+func ServiceExportMutateFn(se *unstructured.Unstructured) controllerutil.MutateFn {
+	updated := se.DeepCopy()
+	return func() error {
+		se.SetLabels(updated.GetLabels())
+		se.SetAnnotations(updated.GetAnnotations())
+		return nil
+	}
+}
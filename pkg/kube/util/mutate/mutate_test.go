@@ -10,6 +10,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
@@ -450,6 +451,34 @@ var _ = Describe("Mutate", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(ops).To(Equal(controllerutil.OperationResultNone))
 			})
+
+			It("replaces all data when binary data is changed", func() {
+				sec.StringData = nil
+				sec.Data = map[string][]byte{"dummy.gz": []byte("new-value")}
+				client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+					switch object := object.(type) {
+					case *corev1.Secret:
+						existing := &corev1.Secret{
+							ObjectMeta: metav1.ObjectMeta{
+								Name:      "foo",
+								Namespace: "default",
+							},
+							Data: map[string][]byte{
+								"dummy": []byte("foo-value"),
+							},
+						}
+						existing.DeepCopyInto(object)
+
+						return nil
+					}
+
+					return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+				})
+				ops, err := controllerutil.CreateOrUpdate(ctx, client, sec, mutate.SecretMutateFn(sec))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ops).To(Equal(controllerutil.OperationResultUpdated))
+				Expect(sec.Data).To(Equal(map[string][]byte{"dummy.gz": []byte("new-value")}))
+			})
 		})
 	})
 
@@ -562,4 +591,171 @@ var _ = Describe("Mutate", func() {
 			})
 		})
 	})
+
+	Describe("ConfigMapMutateFn", func() {
+		var (
+			cm *corev1.ConfigMap
+		)
+
+		BeforeEach(func() {
+			cm = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo",
+					Namespace: "default",
+				},
+				Data: map[string]string{
+					"foo": "bar",
+				},
+			}
+		})
+
+		Context("when the config map is not found", func() {
+			It("creates the config map", func() {
+				client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+					return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+				})
+
+				ops, err := controllerutil.CreateOrUpdate(ctx, client, cm, mutate.ConfigMapMutateFn(cm))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ops).To(Equal(controllerutil.OperationResultCreated))
+			})
+		})
+
+		Context("when the config map is found", func() {
+			var existing *corev1.ConfigMap
+
+			BeforeEach(func() {
+				existing = &corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "foo",
+						Namespace: "default",
+					},
+					Data: map[string]string{
+						"foo": "bar",
+					},
+				}
+				client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+					switch object := object.(type) {
+					case *corev1.ConfigMap:
+						existing.DeepCopyInto(object)
+						return nil
+					}
+
+					return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+				})
+			})
+
+			It("updates the config map when data is changed", func() {
+				existing.Data["foo"] = "baz"
+
+				ops, err := controllerutil.CreateOrUpdate(ctx, client, cm, mutate.ConfigMapMutateFn(cm))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ops).To(Equal(controllerutil.OperationResultUpdated))
+			})
+
+			It("does not update the config map when nothing is changed", func() {
+				ops, err := controllerutil.CreateOrUpdate(ctx, client, cm, mutate.ConfigMapMutateFn(cm))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ops).To(Equal(controllerutil.OperationResultNone))
+			})
+		})
+	})
+
+	Describe("ServiceMonitorMutateFn", func() {
+		var (
+			sm       *unstructured.Unstructured
+			existing *unstructured.Unstructured
+		)
+
+		BeforeEach(func() {
+			sm = &unstructured.Unstructured{}
+			sm.SetName("foo")
+			sm.SetNamespace("default")
+			sm.SetLabels(map[string]string{"foo": "bar"})
+			sm.Object["spec"] = map[string]interface{}{
+				"endpoints": []interface{}{map[string]interface{}{"targetPort": int64(9090)}},
+			}
+			existing = sm.DeepCopy()
+		})
+
+		Context("when the service monitor is not found", func() {
+			It("creates the service monitor", func() {
+				client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+					return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+				})
+
+				ops, err := controllerutil.CreateOrUpdate(ctx, client, sm, mutate.ServiceMonitorMutateFn(sm))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ops).To(Equal(controllerutil.OperationResultCreated))
+			})
+		})
+
+		Context("when the service monitor is found", func() {
+			BeforeEach(func() {
+				client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+					switch object := object.(type) {
+					case *unstructured.Unstructured:
+						existing.DeepCopyInto(object)
+						return nil
+					}
+
+					return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+				})
+			})
+
+			It("updates the service monitor when the spec is changed", func() {
+				existing.Object["spec"] = map[string]interface{}{
+					"endpoints": []interface{}{map[string]interface{}{"targetPort": int64(8080)}},
+				}
+
+				ops, err := controllerutil.CreateOrUpdate(ctx, client, sm, mutate.ServiceMonitorMutateFn(sm))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ops).To(Equal(controllerutil.OperationResultUpdated))
+			})
+
+			It("does not update the service monitor when nothing is changed", func() {
+				ops, err := controllerutil.CreateOrUpdate(ctx, client, sm, mutate.ServiceMonitorMutateFn(sm))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ops).To(Equal(controllerutil.OperationResultNone))
+			})
+		})
+	})
+
+	Describe("ServiceExportMutateFn", func() {
+		var (
+			se       *unstructured.Unstructured
+			existing *unstructured.Unstructured
+		)
+
+		BeforeEach(func() {
+			se = &unstructured.Unstructured{}
+			se.SetName("foo")
+			se.SetNamespace("default")
+			se.SetLabels(map[string]string{"foo": "bar"})
+			existing = se.DeepCopy()
+			client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+				switch object := object.(type) {
+				case *unstructured.Unstructured:
+					existing.DeepCopyInto(object)
+					return nil
+				}
+
+				return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+			})
+		})
+
+		It("updates the service export when the labels are changed", func() {
+			existing.SetLabels(map[string]string{"foo": "baz"})
+
+			ops, err := controllerutil.CreateOrUpdate(ctx, client, se, mutate.ServiceExportMutateFn(se))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ops).To(Equal(controllerutil.OperationResultUpdated))
+		})
+
+		It("does not update the service export when nothing is changed", func() {
+			ops, err := controllerutil.CreateOrUpdate(ctx, client, se, mutate.ServiceExportMutateFn(se))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ops).To(Equal(controllerutil.OperationResultNone))
+		})
+	})
 })
@@ -0,0 +1,69 @@
+package names_test
+
+import (
+	"strings"
+	"testing"
+
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/names"
+	qnames "code.cloudfoundry.org/quarks-utils/pkg/names"
+)
+
+// resourceName returns a valid kubernetes resource name of the given length
+func resourceName(n int) string {
+	return strings.Repeat("abcd-", n/5+1)[:n-1] + "z"
+}
+
+// manifestName returns a BOSH manifest name of the given length, e.g. of a
+// variable or an instance group, which has to be sanitized by the generators
+func manifestName(n int) string {
+	return strings.Repeat("ab_cd", n/5+1)[:n-1] + "z"
+}
+
+func TestGeneratedNamesAreValid(t *testing.T) {
+	deploymentName := resourceName(253)
+	name := manifestName(253)
+	version := "2147483647"
+
+	jobName, err := qnames.JobName(resourceName(253))
+	if err != nil {
+		t.Fatalf("generating job name: %s", err)
+	}
+	csrName := qnames.CSRName(resourceName(63), resourceName(253))
+
+	tests := []struct {
+		generator string
+		name      string
+	}{
+		{"DefaultSecretNamer.DeploymentSecretName", names.DefaultSecretNamer{}.DeploymentSecretName(qnames.DeploymentSecretTypeVariable, deploymentName, name)},
+		{"DesiredManifestName", qnames.DesiredManifestName(deploymentName, version)},
+		{"DesiredManifestPrefix", strings.TrimSuffix(qnames.DesiredManifestPrefix(deploymentName), ".")},
+		{"QuarksLinkSecretName", qnames.QuarksLinkSecretName(deploymentName, name)},
+		{"DeploymentSecretPrefix", strings.TrimSuffix(qnames.DeploymentSecretPrefix(qnames.DeploymentSecretTypeManifestWithOps, deploymentName), ".")},
+		{"InstanceGroupSecretName", qnames.InstanceGroupSecretName(qnames.DeploymentSecretTypeInstanceGroupResolvedProperties, deploymentName, name, version)},
+		{"Sanitize", qnames.Sanitize(name)},
+		{"SanitizeSubdomain", qnames.SanitizeSubdomain(name)},
+		{"JobName", jobName},
+		{"VolumeName", qnames.VolumeName(qnames.InstanceGroupSecretName(qnames.DeploymentSecretBpmInformation, deploymentName, name, version))},
+		{"CSRName", csrName},
+		{"CsrPrivateKeySecretName", qnames.CsrPrivateKeySecretName(csrName)},
+	}
+
+	for _, secretType := range []qnames.DeploymentSecretType{
+		qnames.DeploymentSecretTypeManifestWithOps,
+		qnames.DeploymentSecretTypeDesiredManifest,
+		qnames.DeploymentSecretTypeVariable,
+		qnames.DeploymentSecretTypeInstanceGroupResolvedProperties,
+		qnames.DeploymentSecretBpmInformation,
+	} {
+		tests = append(tests, struct {
+			generator string
+			name      string
+		}{"DeploymentSecretName/" + secretType.String(), qnames.DeploymentSecretName(secretType, deploymentName, name)})
+	}
+
+	for _, tt := range tests {
+		if !names.IsValidResourceName(tt.name) {
+			t.Errorf("%s generated invalid resource name '%s'", tt.generator, tt.name)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+// Package names decodes the names of the secrets, which are generated for a BOSHDeployment
+package names
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"code.cloudfoundry.org/quarks-utils/pkg/names"
+)
+
+// secretTypes lists the deployment secret types, longest name first, so
+// a type is not mistaken for another type with the same prefix
+var secretTypes = []names.DeploymentSecretType{
+	names.DeploymentSecretTypeInstanceGroupResolvedProperties,
+	names.DeploymentSecretTypeManifestWithOps,
+	names.DeploymentSecretTypeDesiredManifest,
+	names.DeploymentSecretBpmInformation,
+	names.DeploymentSecretTypeVariable,
+}
+
+var versionSuffix = regexp.MustCompile(`-v([0-9]+)$`)
+
+// dns1123SubdomainRegex matches lowercase alphanumeric names, which may
+// contain dashes and dots, but have to start and end with an alphanumeric character
+var dns1123SubdomainRegex = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+
+// dns1123SubdomainMaxLength is the maximum length of a kubernetes resource name
+const dns1123SubdomainMaxLength = 253
+
+// IsValidResourceName returns true, if name is a valid kubernetes resource
+// name, i.e. a DNS-1123 subdomain of at most 253 characters
+func IsValidResourceName(name string) bool {
+	return len(name) <= dns1123SubdomainMaxLength && dns1123SubdomainRegex.MatchString(name)
+}
+
+// ParseDeploymentSecretName decodes a secret name generated by names.DeploymentSecretName,
+// names.InstanceGroupSecretName or names.DesiredManifestName into its deployment name,
+// secret type and version. The version is 0 for unversioned secrets.
+func ParseDeploymentSecretName(name string) (deploymentName string, secretType names.DeploymentSecretType, version int, err error) {
+	parts := strings.SplitN(name, ".", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return "", 0, 0, errors.Errorf("secret name '%s' has no deployment name", name)
+	}
+	deploymentName = parts[0]
+
+	found := false
+	for _, t := range secretTypes {
+		rest := strings.TrimPrefix(parts[1], t.String())
+		if rest == parts[1] {
+			continue
+		}
+		if rest == "" || rest[0] == '-' || rest[0] == '.' {
+			secretType = t
+			found = true
+			break
+		}
+	}
+	if !found {
+		return "", 0, 0, errors.Errorf("secret name '%s' has no known secret type", name)
+	}
+
+	if m := versionSuffix.FindStringSubmatch(parts[1]); m != nil {
+		version, err = strconv.Atoi(m[1])
+		if err != nil {
+			return "", 0, 0, errors.Wrapf(err, "invalid version of secret name '%s'", name)
+		}
+	}
+
+	return deploymentName, secretType, version, nil
+}
@@ -0,0 +1,70 @@
+package names_test
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/names"
+	qnames "code.cloudfoundry.org/quarks-utils/pkg/names"
+)
+
+var _ = Describe("ParseDeploymentSecretName", func() {
+	expectParsed := func(name string, deploymentName string, secretType qnames.DeploymentSecretType, version int) {
+		d, t, v, err := names.ParseDeploymentSecretName(name)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(d).To(Equal(deploymentName))
+		Expect(t).To(Equal(secretType))
+		Expect(v).To(Equal(version))
+	}
+
+	It("decodes the with-ops manifest name", func() {
+		expectParsed(qnames.DeploymentSecretName(qnames.DeploymentSecretTypeManifestWithOps, "foo", ""), "foo", qnames.DeploymentSecretTypeManifestWithOps, 0)
+	})
+
+	It("decodes variable names", func() {
+		expectParsed(qnames.DeploymentSecretName(qnames.DeploymentSecretTypeVariable, "foo", "admin_pass"), "foo", qnames.DeploymentSecretTypeVariable, 0)
+	})
+
+	It("decodes the desired manifest name", func() {
+		expectParsed(qnames.DesiredManifestName("foo", "3"), "foo", qnames.DeploymentSecretTypeDesiredManifest, 3)
+	})
+
+	It("decodes instance group secret names", func() {
+		expectParsed(qnames.InstanceGroupSecretName(qnames.DeploymentSecretTypeInstanceGroupResolvedProperties, "foo", "nats", "2"), "foo", qnames.DeploymentSecretTypeInstanceGroupResolvedProperties, 2)
+		expectParsed(qnames.InstanceGroupSecretName(qnames.DeploymentSecretBpmInformation, "foo", "nats", "12"), "foo", qnames.DeploymentSecretBpmInformation, 12)
+		expectParsed("foo.bpm.nats", "foo", qnames.DeploymentSecretBpmInformation, 0)
+	})
+
+	It("fails for names without deployment", func() {
+		_, _, _, err := names.ParseDeploymentSecretName("bpm-nats")
+		Expect(err).To(MatchError(ContainSubstring("has no deployment name")))
+	})
+
+	It("fails for unknown secret types", func() {
+		_, _, _, err := names.ParseDeploymentSecretName("foo.bpmx.nats")
+		Expect(err).To(MatchError(ContainSubstring("has no known secret type")))
+
+		_, _, _, err = names.ParseDeploymentSecretName("foo.other")
+		Expect(err).To(MatchError(ContainSubstring("has no known secret type")))
+	})
+})
+
+var _ = Describe("IsValidResourceName", func() {
+	It("accepts DNS-1123 subdomains", func() {
+		Expect(names.IsValidResourceName("foo")).To(BeTrue())
+		Expect(names.IsValidResourceName("foo.desired-manifest-v1")).To(BeTrue())
+		Expect(names.IsValidResourceName(strings.Repeat("a", 253))).To(BeTrue())
+	})
+
+	It("rejects invalid names", func() {
+		Expect(names.IsValidResourceName("")).To(BeFalse())
+		Expect(names.IsValidResourceName("Foo")).To(BeFalse())
+		Expect(names.IsValidResourceName("foo_bar")).To(BeFalse())
+		Expect(names.IsValidResourceName("-foo")).To(BeFalse())
+		Expect(names.IsValidResourceName("foo.")).To(BeFalse())
+		Expect(names.IsValidResourceName("foo..bar")).To(BeFalse())
+		Expect(names.IsValidResourceName(strings.Repeat("a", 254))).To(BeFalse())
+	})
+})
@@ -0,0 +1,117 @@
+package names
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"code.cloudfoundry.org/quarks-utils/pkg/names"
+)
+
+// SecretNamer names the secrets, which are generated for a BOSHDeployment
+// from its manifest, i.e. the secrets of its variables, the secret of its
+// manifest with ops files applied, the versioned desired manifest and the
+// versioned instance group secrets. The same namer must be used by all
+// components, which read or write these secrets.
+//
+// Custom implementations must be:
+//   - deterministic, the same arguments always return the same name, as the
+//     name is computed again on every reconcile and by every reader
+//   - collision resistant, different secret types, deployment names,
+//     instance group names or variable names must not map to the same name,
+//     as secrets of one deployment would otherwise overwrite each other or the
+//     secrets of another deployment in the namespace. Shortened names should
+//     keep a hash of the full arguments, like names.DeploymentSecretName does.
+//   - reversible, ParseDeploymentSecretName has to decode every name returned
+//     by the other functions into its deployment name, secret type and version
+//   - valid kubernetes secret names, i.e. DNS-1123 subdomains of at most 253 characters
+//
+// Versioned secrets are written by QuarksJob, which appends '-v<version>' to
+// the unversioned name. Names with a version have to follow that format.
+type SecretNamer interface {
+	DeploymentSecretName(secretType names.DeploymentSecretType, deploymentName, name string) string
+	InstanceGroupSecretName(secretType names.DeploymentSecretType, deploymentName, igName, version string) string
+	DesiredManifestName(deploymentName, version string) string
+	ParseDeploymentSecretName(name string) (deploymentName string, secretType names.DeploymentSecretType, version int, err error)
+}
+
+// NewSecretNamer returns the DefaultSecretNamer, or a PrefixSecretNamer if
+// prefix is not empty
+func NewSecretNamer(prefix string) (SecretNamer, error) {
+	if prefix == "" {
+		return DefaultSecretNamer{}, nil
+	}
+
+	if len(prefix) > secretNamePrefixMaxLength || !dns1123LabelRegex.MatchString(prefix) {
+		return nil, errors.Errorf("invalid secret name prefix '%s', must be a DNS-1123 label of at most %d characters", prefix, secretNamePrefixMaxLength)
+	}
+
+	return PrefixSecretNamer{Prefix: prefix}, nil
+}
+
+// DefaultSecretNamer names secrets with the functions of the quarks-utils names package
+type DefaultSecretNamer struct{}
+
+// DeploymentSecretName returns the name of a secret of the deployment
+func (DefaultSecretNamer) DeploymentSecretName(secretType names.DeploymentSecretType, deploymentName, name string) string {
+	return names.DeploymentSecretName(secretType, deploymentName, name)
+}
+
+// InstanceGroupSecretName returns the name of a secret of an instance group
+// of the deployment, e.g. 'test.ig-resolved.ig-v1'
+func (DefaultSecretNamer) InstanceGroupSecretName(secretType names.DeploymentSecretType, deploymentName, igName, version string) string {
+	return names.InstanceGroupSecretName(secretType, deploymentName, igName, version)
+}
+
+// DesiredManifestName returns the name of the desired manifest secret, e.g. 'test.desired-manifest-v1'
+func (DefaultSecretNamer) DesiredManifestName(deploymentName, version string) string {
+	return names.DesiredManifestName(deploymentName, version)
+}
+
+// ParseDeploymentSecretName decodes a name returned by the other functions
+func (DefaultSecretNamer) ParseDeploymentSecretName(name string) (string, names.DeploymentSecretType, int, error) {
+	return ParseDeploymentSecretName(name)
+}
+
+// secretNamePrefixMaxLength keeps prefixed names well below the maximum
+// length of a secret name
+const secretNamePrefixMaxLength = 30
+
+var dns1123LabelRegex = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// PrefixSecretNamer names secrets like the DefaultSecretNamer, but prepends
+// '<prefix>-' to every name, e.g. 'team-a-test.var-password'
+type PrefixSecretNamer struct {
+	Prefix string
+}
+
+// DeploymentSecretName returns the name of a secret of the deployment
+func (n PrefixSecretNamer) DeploymentSecretName(secretType names.DeploymentSecretType, deploymentName, name string) string {
+	return n.prefixed(names.DeploymentSecretName(secretType, deploymentName, name))
+}
+
+// InstanceGroupSecretName returns the name of a secret of an instance group of the deployment
+func (n PrefixSecretNamer) InstanceGroupSecretName(secretType names.DeploymentSecretType, deploymentName, igName, version string) string {
+	return n.prefixed(names.InstanceGroupSecretName(secretType, deploymentName, igName, version))
+}
+
+// DesiredManifestName returns the name of the desired manifest secret
+func (n PrefixSecretNamer) DesiredManifestName(deploymentName, version string) string {
+	return n.prefixed(names.DesiredManifestName(deploymentName, version))
+}
+
+// ParseDeploymentSecretName decodes a name returned by the other functions.
+// Names without the prefix are not generated by this namer.
+func (n PrefixSecretNamer) ParseDeploymentSecretName(name string) (string, names.DeploymentSecretType, int, error) {
+	unprefixed := strings.TrimPrefix(name, n.prefixed(""))
+	if unprefixed == name {
+		return "", 0, 0, errors.Errorf("secret name '%s' has no prefix '%s'", name, n.Prefix)
+	}
+
+	return ParseDeploymentSecretName(unprefixed)
+}
+
+func (n PrefixSecretNamer) prefixed(name string) string {
+	return n.Prefix + "-" + name
+}
@@ -0,0 +1,55 @@
+package names_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/names"
+	qnames "code.cloudfoundry.org/quarks-utils/pkg/names"
+)
+
+var _ = Describe("SecretNamer", func() {
+	Describe("NewSecretNamer", func() {
+		It("returns the default namer without a prefix", func() {
+			namer, err := names.NewSecretNamer("")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(namer).To(Equal(names.DefaultSecretNamer{}))
+		})
+
+		It("returns a prefix namer with a prefix", func() {
+			namer, err := names.NewSecretNamer("team-a")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(namer).To(Equal(names.PrefixSecretNamer{Prefix: "team-a"}))
+		})
+
+		It("rejects invalid prefixes", func() {
+			for _, prefix := range []string{"Team", "team.a", "team-", "a-very-long-prefix-of-more-than-30-characters"} {
+				_, err := names.NewSecretNamer(prefix)
+				Expect(err).To(HaveOccurred(), prefix)
+			}
+		})
+	})
+
+	Describe("PrefixSecretNamer", func() {
+		namer := names.PrefixSecretNamer{Prefix: "team-a"}
+
+		It("prefixes the default names", func() {
+			Expect(namer.DeploymentSecretName(qnames.DeploymentSecretTypeVariable, "foo", "admin_pass")).To(Equal("team-a-foo.var-admin-pass"))
+			Expect(namer.InstanceGroupSecretName(qnames.DeploymentSecretBpmInformation, "foo", "ig", "2")).To(Equal("team-a-foo.bpm.ig-v2"))
+			Expect(namer.DesiredManifestName("foo", "1")).To(Equal("team-a-foo.desired-manifest-v1"))
+		})
+
+		It("parses its names", func() {
+			d, t, v, err := namer.ParseDeploymentSecretName(namer.InstanceGroupSecretName(qnames.DeploymentSecretBpmInformation, "foo", "ig", "2"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(d).To(Equal("foo"))
+			Expect(t).To(Equal(qnames.DeploymentSecretBpmInformation))
+			Expect(v).To(Equal(2))
+		})
+
+		It("doesn't parse names without the prefix", func() {
+			_, _, _, err := namer.ParseDeploymentSecretName("foo.bpm.ig-v2")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,168 @@
+// Package podsecurity checks pod specs against the Pod Security Standards
+// enforced on a namespace, before they are rejected at admission
+package podsecurity
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// LabelEnforce is the namespace label, which names the enforced Pod Security Standard level
+const LabelEnforce = "pod-security.kubernetes.io/enforce"
+
+// Pod Security Standard levels
+const (
+	LevelPrivileged = "privileged"
+	LevelBaseline   = "baseline"
+	LevelRestricted = "restricted"
+)
+
+// baselineCapabilities are the capabilities, which may be added on the baseline level
+var baselineCapabilities = map[corev1.Capability]bool{
+	"AUDIT_WRITE":      true,
+	"CHOWN":            true,
+	"DAC_OVERRIDE":     true,
+	"FOWNER":           true,
+	"FSETID":           true,
+	"KILL":             true,
+	"MKNOD":            true,
+	"NET_BIND_SERVICE": true,
+	"SETFCAP":          true,
+	"SETGID":           true,
+	"SETPCAP":          true,
+	"SETUID":           true,
+	"SYS_CHROOT":       true,
+}
+
+// Check returns the violations of spec against the Pod Security Standard
+// level. Each violation describes what has to be changed. Unknown levels are
+// treated like the privileged level, which allows everything.
+// The seccomp profile is not checked, since the pod API of the operator has
+// no field for it.
+func Check(level string, spec corev1.PodSpec) []string {
+	switch level {
+	case LevelBaseline:
+		return checkBaseline(spec)
+	case LevelRestricted:
+		return append(checkBaseline(spec), checkRestricted(spec)...)
+	}
+	return nil
+}
+
+// containers returns the init containers and containers of spec
+func containers(spec corev1.PodSpec) []corev1.Container {
+	return append(append([]corev1.Container{}, spec.InitContainers...), spec.Containers...)
+}
+
+func checkBaseline(spec corev1.PodSpec) []string {
+	violations := []string{}
+
+	if spec.HostNetwork {
+		violations = append(violations, "pod must not set hostNetwork")
+	}
+	if spec.HostPID {
+		violations = append(violations, "pod must not set hostPID")
+	}
+	if spec.HostIPC {
+		violations = append(violations, "pod must not set hostIPC")
+	}
+	for _, v := range spec.Volumes {
+		if v.HostPath != nil {
+			violations = append(violations, fmt.Sprintf("volume '%s' must not be a hostPath volume", v.Name))
+		}
+	}
+
+	for _, c := range containers(spec) {
+		for _, p := range c.Ports {
+			if p.HostPort != 0 {
+				violations = append(violations, fmt.Sprintf("container '%s' must not use hostPort %d", c.Name, p.HostPort))
+			}
+		}
+
+		sc := c.SecurityContext
+		if sc == nil {
+			continue
+		}
+		if sc.Privileged != nil && *sc.Privileged {
+			violations = append(violations, fmt.Sprintf("container '%s' must not set securityContext.privileged=true", c.Name))
+		}
+		if sc.ProcMount != nil && *sc.ProcMount != corev1.DefaultProcMount {
+			violations = append(violations, fmt.Sprintf("container '%s' must not set securityContext.procMount=%s", c.Name, *sc.ProcMount))
+		}
+		if sc.Capabilities != nil {
+			for _, capability := range sc.Capabilities.Add {
+				if !baselineCapabilities[capability] {
+					violations = append(violations, fmt.Sprintf("container '%s' must not add capability '%s'", c.Name, capability))
+				}
+			}
+		}
+	}
+
+	return violations
+}
+
+func checkRestricted(spec corev1.PodSpec) []string {
+	violations := []string{}
+
+	for _, v := range spec.Volumes {
+		if !restrictedVolume(v.VolumeSource) {
+			violations = append(violations, fmt.Sprintf("volume '%s' must be a configMap, csi, downwardAPI, emptyDir, persistentVolumeClaim, projected or secret volume", v.Name))
+		}
+	}
+
+	podRunAsNonRoot := false
+	if psc := spec.SecurityContext; psc != nil {
+		podRunAsNonRoot = psc.RunAsNonRoot != nil && *psc.RunAsNonRoot
+		if psc.RunAsUser != nil && *psc.RunAsUser == 0 {
+			violations = append(violations, "pod must not set securityContext.runAsUser=0")
+		}
+	}
+
+	for _, c := range containers(spec) {
+		sc := c.SecurityContext
+		if sc == nil {
+			sc = &corev1.SecurityContext{}
+		}
+
+		if sc.AllowPrivilegeEscalation == nil || *sc.AllowPrivilegeEscalation {
+			violations = append(violations, fmt.Sprintf("container '%s' must set securityContext.allowPrivilegeEscalation=false", c.Name))
+		}
+		if (sc.RunAsNonRoot != nil && !*sc.RunAsNonRoot) || (sc.RunAsNonRoot == nil && !podRunAsNonRoot) {
+			violations = append(violations, fmt.Sprintf("container '%s' must set securityContext.runAsNonRoot=true", c.Name))
+		}
+		if sc.RunAsUser != nil && *sc.RunAsUser == 0 {
+			violations = append(violations, fmt.Sprintf("container '%s' must not set securityContext.runAsUser=0", c.Name))
+		}
+
+		dropsAll := false
+		if sc.Capabilities != nil {
+			for _, capability := range sc.Capabilities.Drop {
+				if capability == "ALL" {
+					dropsAll = true
+				}
+			}
+			for _, capability := range sc.Capabilities.Add {
+				if capability != "NET_BIND_SERVICE" {
+					violations = append(violations, fmt.Sprintf("container '%s' may only add capability 'NET_BIND_SERVICE', not '%s'", c.Name, capability))
+				}
+			}
+		}
+		if !dropsAll {
+			violations = append(violations, fmt.Sprintf("container '%s' must set securityContext.capabilities.drop=[\"ALL\"]", c.Name))
+		}
+	}
+
+	return violations
+}
+
+// restrictedVolume returns true, if the volume type is allowed on the restricted level
+func restrictedVolume(v corev1.VolumeSource) bool {
+	return v.ConfigMap != nil ||
+		v.CSI != nil ||
+		v.DownwardAPI != nil ||
+		v.EmptyDir != nil ||
+		v.PersistentVolumeClaim != nil ||
+		v.Projected != nil ||
+		v.Secret != nil
+}
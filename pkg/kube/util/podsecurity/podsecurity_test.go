@@ -0,0 +1,90 @@
+package podsecurity_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/podsecurity"
+	"code.cloudfoundry.org/quarks-utils/pkg/pointers"
+)
+
+var _ = Describe("Check", func() {
+	var spec corev1.PodSpec
+
+	BeforeEach(func() {
+		spec = corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "interpolation"}},
+			Volumes: []corev1.Volume{
+				{Name: "with-ops", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{}}},
+			},
+		}
+	})
+
+	It("allows everything on the privileged level", func() {
+		spec.HostNetwork = true
+		Expect(podsecurity.Check(podsecurity.LevelPrivileged, spec)).To(BeEmpty())
+		Expect(podsecurity.Check("", spec)).To(BeEmpty())
+	})
+
+	Context("on the baseline level", func() {
+		It("allows unprivileged pods", func() {
+			Expect(podsecurity.Check(podsecurity.LevelBaseline, spec)).To(BeEmpty())
+		})
+
+		It("rejects host namespaces, hostPath volumes and privileged containers", func() {
+			spec.HostPID = true
+			spec.Volumes = append(spec.Volumes, corev1.Volume{Name: "host", VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{}}})
+			spec.InitContainers = []corev1.Container{{
+				Name: "init",
+				SecurityContext: &corev1.SecurityContext{
+					Privileged:   pointers.Bool(true),
+					Capabilities: &corev1.Capabilities{Add: []corev1.Capability{"NET_ADMIN", "CHOWN"}},
+				},
+			}}
+
+			Expect(podsecurity.Check(podsecurity.LevelBaseline, spec)).To(ConsistOf(
+				"pod must not set hostPID",
+				"volume 'host' must not be a hostPath volume",
+				"container 'init' must not set securityContext.privileged=true",
+				"container 'init' must not add capability 'NET_ADMIN'",
+			))
+		})
+	})
+
+	Context("on the restricted level", func() {
+		It("describes the missing security context settings", func() {
+			Expect(podsecurity.Check(podsecurity.LevelRestricted, spec)).To(ConsistOf(
+				"container 'interpolation' must set securityContext.allowPrivilegeEscalation=false",
+				"container 'interpolation' must set securityContext.runAsNonRoot=true",
+				"container 'interpolation' must set securityContext.capabilities.drop=[\"ALL\"]",
+			))
+		})
+
+		It("allows pods complying with the restricted level", func() {
+			spec.SecurityContext = &corev1.PodSecurityContext{RunAsNonRoot: pointers.Bool(true)}
+			spec.Containers[0].SecurityContext = &corev1.SecurityContext{
+				AllowPrivilegeEscalation: pointers.Bool(false),
+				Capabilities: &corev1.Capabilities{
+					Drop: []corev1.Capability{"ALL"},
+					Add:  []corev1.Capability{"NET_BIND_SERVICE"},
+				},
+			}
+
+			Expect(podsecurity.Check(podsecurity.LevelRestricted, spec)).To(BeEmpty())
+		})
+
+		It("rejects volume types other than the restricted ones", func() {
+			spec.SecurityContext = &corev1.PodSecurityContext{RunAsNonRoot: pointers.Bool(true), RunAsUser: pointers.Int64(0)}
+			spec.Volumes = append(spec.Volumes, corev1.Volume{Name: "nfs", VolumeSource: corev1.VolumeSource{NFS: &corev1.NFSVolumeSource{}}})
+
+			Expect(podsecurity.Check(podsecurity.LevelRestricted, spec)).To(ContainElement(
+				"volume 'nfs' must be a configMap, csi, downwardAPI, emptyDir, persistentVolumeClaim, projected or secret volume",
+			))
+			Expect(podsecurity.Check(podsecurity.LevelRestricted, spec)).To(ContainElement(
+				"pod must not set securityContext.runAsUser=0",
+			))
+		})
+	})
+})
@@ -0,0 +1,13 @@
+package podsecurity_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestPodSecurity(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "PodSecurity Suite")
+}
@@ -0,0 +1,204 @@
+package priorityqueue
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+// jitterPeriod is the pause of a worker, after a reconcile failed
+const jitterPeriod = time.Second
+
+// Options are the options of a priority queue controller
+type Options struct {
+	// MaxConcurrentReconciles is the maximum number of concurrent reconciles. Defaults to 1.
+	MaxConcurrentReconciles int
+	// Reconciler reconciles an object
+	Reconciler reconcile.Reconciler
+	// Priority returns the priority of a reconcile request
+	Priority func(reconcile.Request) int
+}
+
+// Controller is a controller, which works like the controller-runtime
+// controller, but reconciles the requests with the highest priority first.
+// The controller-runtime controller doesn't allow to replace its queue.
+type Controller struct {
+	ctx                     context.Context
+	name                    string
+	mgr                     manager.Manager
+	do                      reconcile.Reconciler
+	priority                func(reconcile.Request) int
+	maxConcurrentReconciles int
+
+	mu      sync.Mutex
+	queue   *Queue
+	watches []watchDescription
+	started bool
+}
+
+type watchDescription struct {
+	src        source.Source
+	handler    handler.EventHandler
+	predicates []predicate.Predicate
+}
+
+var _ controller.Controller = &Controller{}
+
+// NewController returns a new priority queue controller and adds it to the manager
+func NewController(ctx context.Context, name string, mgr manager.Manager, options Options) (*Controller, error) {
+	if options.Reconciler == nil {
+		return nil, fmt.Errorf("must specify Reconciler")
+	}
+	if options.Priority == nil {
+		return nil, fmt.Errorf("must specify Priority")
+	}
+	if len(name) == 0 {
+		return nil, fmt.Errorf("must specify Name for Controller")
+	}
+	if options.MaxConcurrentReconciles <= 0 {
+		options.MaxConcurrentReconciles = 1
+	}
+
+	if err := mgr.SetFields(options.Reconciler); err != nil {
+		return nil, err
+	}
+
+	c := &Controller{
+		ctx:                     ctx,
+		name:                    name,
+		mgr:                     mgr,
+		do:                      options.Reconciler,
+		priority:                options.Priority,
+		maxConcurrentReconciles: options.MaxConcurrentReconciles,
+	}
+	return c, mgr.Add(c)
+}
+
+// Reconcile implements reconcile.Reconciler
+func (c *Controller) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	return c.do.Reconcile(request)
+}
+
+// Watch implements controller.Controller
+func (c *Controller) Watch(src source.Source, evthdler handler.EventHandler, prct ...predicate.Predicate) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.mgr.SetFields(src); err != nil {
+		return err
+	}
+	if err := c.mgr.SetFields(evthdler); err != nil {
+		return err
+	}
+	for _, pr := range prct {
+		if err := c.mgr.SetFields(pr); err != nil {
+			return err
+		}
+	}
+
+	c.watches = append(c.watches, watchDescription{src: src, handler: evthdler, predicates: prct})
+	if c.started {
+		return src.Start(evthdler, c.queue, prct...)
+	}
+	return nil
+}
+
+// Start implements controller.Controller. It blocks until stop is closed.
+func (c *Controller) Start(stop <-chan struct{}) error {
+	c.mu.Lock()
+
+	c.queue = New(c.itemPriority, workqueue.DefaultControllerRateLimiter())
+	defer c.queue.ShutDown()
+
+	err := func() error {
+		defer c.mu.Unlock()
+
+		for _, watch := range c.watches {
+			if err := watch.src.Start(watch.handler, c.queue, watch.predicates...); err != nil {
+				return err
+			}
+		}
+
+		if ok := c.mgr.GetCache().WaitForCacheSync(stop); !ok {
+			return errors.Errorf("failed to wait for %s caches to sync", c.name)
+		}
+
+		ctxlog.Infof(c.ctx, "Starting %d workers for '%s'", c.maxConcurrentReconciles, c.name)
+		for i := 0; i < c.maxConcurrentReconciles; i++ {
+			go wait.Until(c.worker, jitterPeriod, stop)
+		}
+
+		c.started = true
+		return nil
+	}()
+	if err != nil {
+		return err
+	}
+
+	<-stop
+	ctxlog.Infof(c.ctx, "Stopping workers for '%s'", c.name)
+	return nil
+}
+
+// itemPriority returns the priority of reconcile requests and the default
+// priority of other items
+func (c *Controller) itemPriority(item interface{}) int {
+	request, ok := item.(reconcile.Request)
+	if !ok {
+		return 0
+	}
+	return c.priority(request)
+}
+
+func (c *Controller) worker() {
+	for c.processNextWorkItem() {
+	}
+}
+
+// processNextWorkItem reconciles the next request and requeues it like the
+// controller-runtime controller. It returns false, if the worker should pause
+// or stop.
+func (c *Controller) processNextWorkItem() bool {
+	item, shutdown := c.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer c.queue.Done(item)
+
+	request, ok := item.(reconcile.Request)
+	if !ok {
+		c.queue.Forget(item)
+		ctxlog.Errorf(c.ctx, "Queue item of '%s' was not a request: %#v", c.name, item)
+		return true
+	}
+
+	result, err := c.do.Reconcile(request)
+	switch {
+	case err != nil:
+		c.queue.AddRateLimited(request)
+		ctxlog.Errorf(c.ctx, "Reconciler error in '%s' for '%s': %v", c.name, request, err)
+		return false
+	case result.RequeueAfter > 0:
+		c.queue.Forget(request)
+		c.queue.AddAfter(request, result.RequeueAfter)
+	case result.Requeue:
+		c.queue.AddRateLimited(request)
+	default:
+		c.queue.Forget(request)
+	}
+	return true
+}
@@ -0,0 +1,220 @@
+// Package priorityqueue provides a workqueue, which hands out the items with
+// the highest priority first, and a controller using it
+package priorityqueue
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/util/workqueue"
+)
+
+// PriorityFunc returns the priority of a queue item. Items with a higher
+// priority are handed out first.
+type PriorityFunc func(item interface{}) int
+
+// Queue is a rate limiting workqueue, which hands out the item with the
+// highest priority first. Items of the same priority are handed out in the
+// order they were added.
+//
+// Like the client-go workqueue, an item is never handed out twice at the same
+// time. If an item is added while it is processed, it is queued again once
+// it is done, with the priority it had when it was last added. Adding an item,
+// which is already queued, doesn't add it twice, but raises its priority if
+// the new one is higher.
+type Queue struct {
+	cond        *sync.Cond
+	priority    PriorityFunc
+	rateLimiter workqueue.RateLimiter
+
+	items      items
+	queued     map[interface{}]*entry
+	dirty      map[interface{}]int
+	processing map[interface{}]bool
+	seq        uint64
+
+	shuttingDown bool
+}
+
+var _ workqueue.RateLimitingInterface = &Queue{}
+
+// New returns a new priority queue. The priority of an item is determined each
+// time it is added.
+func New(priority PriorityFunc, rateLimiter workqueue.RateLimiter) *Queue {
+	return &Queue{
+		cond:        sync.NewCond(&sync.Mutex{}),
+		priority:    priority,
+		rateLimiter: rateLimiter,
+		queued:      map[interface{}]*entry{},
+		dirty:       map[interface{}]int{},
+		processing:  map[interface{}]bool{},
+	}
+}
+
+// Add marks the item as needing processing
+func (q *Queue) Add(item interface{}) {
+	priority := q.priority(item)
+
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+
+	if q.shuttingDown {
+		return
+	}
+
+	if p, ok := q.dirty[item]; ok {
+		if priority <= p {
+			return
+		}
+		q.dirty[item] = priority
+		if e, ok := q.queued[item]; ok {
+			e.priority = priority
+			heap.Fix(&q.items, e.index)
+		}
+		return
+	}
+
+	q.dirty[item] = priority
+	if q.processing[item] {
+		return
+	}
+	q.push(item, priority)
+}
+
+// Len returns the number of queued items
+func (q *Queue) Len() int {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+
+	return q.items.Len()
+}
+
+// Get blocks until it can return the item with the highest priority. The
+// item has to be marked as done, once it was processed. If shutdown is true,
+// the caller should stop.
+func (q *Queue) Get() (interface{}, bool) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+
+	for q.items.Len() == 0 && !q.shuttingDown {
+		q.cond.Wait()
+	}
+	if q.items.Len() == 0 {
+		return nil, true
+	}
+
+	e := heap.Pop(&q.items).(*entry)
+	delete(q.queued, e.item)
+	delete(q.dirty, e.item)
+	q.processing[e.item] = true
+
+	return e.item, false
+}
+
+// Done marks the item as processed. If it was added again while it was
+// processed, it is queued again.
+func (q *Queue) Done(item interface{}) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+
+	delete(q.processing, item)
+	if priority, ok := q.dirty[item]; ok {
+		q.push(item, priority)
+	}
+}
+
+// ShutDown makes Get return, once all queued items were handed out, and
+// ignores new items
+func (q *Queue) ShutDown() {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+
+	q.shuttingDown = true
+	q.cond.Broadcast()
+}
+
+// ShuttingDown returns true, if the queue is shutting down
+func (q *Queue) ShuttingDown() bool {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+
+	return q.shuttingDown
+}
+
+// AddAfter adds the item, after the duration has passed
+func (q *Queue) AddAfter(item interface{}, duration time.Duration) {
+	if q.ShuttingDown() {
+		return
+	}
+	if duration <= 0 {
+		q.Add(item)
+		return
+	}
+	time.AfterFunc(duration, func() { q.Add(item) })
+}
+
+// AddRateLimited adds the item, after the rate limiter says it's ok
+func (q *Queue) AddRateLimited(item interface{}) {
+	q.AddAfter(item, q.rateLimiter.When(item))
+}
+
+// Forget stops the rate limiter from tracking the item
+func (q *Queue) Forget(item interface{}) {
+	q.rateLimiter.Forget(item)
+}
+
+// NumRequeues returns how often the item was requeued by the rate limiter
+func (q *Queue) NumRequeues(item interface{}) int {
+	return q.rateLimiter.NumRequeues(item)
+}
+
+// push queues the item, the caller has to hold the lock
+func (q *Queue) push(item interface{}, priority int) {
+	q.seq++
+	e := &entry{item: item, priority: priority, seq: q.seq}
+	heap.Push(&q.items, e)
+	q.queued[item] = e
+	q.cond.Signal()
+}
+
+type entry struct {
+	item     interface{}
+	priority int
+	seq      uint64
+	index    int
+}
+
+// items implements heap.Interface, ordered by descending priority and the
+// order in which the items were added
+type items []*entry
+
+func (it items) Len() int { return len(it) }
+
+func (it items) Less(i, j int) bool {
+	if it[i].priority != it[j].priority {
+		return it[i].priority > it[j].priority
+	}
+	return it[i].seq < it[j].seq
+}
+
+func (it items) Swap(i, j int) {
+	it[i], it[j] = it[j], it[i]
+	it[i].index = i
+	it[j].index = j
+}
+
+func (it *items) Push(x interface{}) {
+	e := x.(*entry)
+	e.index = len(*it)
+	*it = append(*it, e)
+}
+
+func (it *items) Pop() interface{} {
+	old := *it
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	*it = old[:n-1]
+	return e
+}
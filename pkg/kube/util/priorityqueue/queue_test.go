@@ -0,0 +1,104 @@
+package priorityqueue_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/client-go/util/workqueue"
+
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/priorityqueue"
+)
+
+var _ = Describe("Queue", func() {
+	var (
+		priorities map[string]int
+		queue      *priorityqueue.Queue
+	)
+
+	get := func() interface{} {
+		item, shutdown := queue.Get()
+		Expect(shutdown).To(BeFalse())
+		return item
+	}
+
+	BeforeEach(func() {
+		priorities = map[string]int{"high": 10, "low": -10}
+		queue = priorityqueue.New(func(item interface{}) int {
+			return priorities[item.(string)]
+		}, workqueue.DefaultControllerRateLimiter())
+	})
+
+	AfterEach(func() {
+		queue.ShutDown()
+	})
+
+	It("hands out the items with the highest priority first", func() {
+		queue.Add("low")
+		queue.Add("default")
+		queue.Add("high")
+
+		Expect(queue.Len()).To(Equal(3))
+		Expect(get()).To(Equal("high"))
+		Expect(get()).To(Equal("default"))
+		Expect(get()).To(Equal("low"))
+	})
+
+	It("hands out items of the same priority in the order they were added", func() {
+		queue.Add("b")
+		queue.Add("a")
+		queue.Add("c")
+
+		Expect(get()).To(Equal("b"))
+		Expect(get()).To(Equal("a"))
+		Expect(get()).To(Equal("c"))
+	})
+
+	It("doesn't queue items twice", func() {
+		queue.Add("a")
+		queue.Add("a")
+
+		Expect(queue.Len()).To(Equal(1))
+	})
+
+	It("raises the priority of queued items", func() {
+		queue.Add("a")
+		queue.Add("b")
+		priorities["b"] = 5
+		queue.Add("b")
+
+		Expect(queue.Len()).To(Equal(2))
+		Expect(get()).To(Equal("b"))
+		Expect(get()).To(Equal("a"))
+	})
+
+	It("doesn't hand out an item again, before it is done", func() {
+		queue.Add("high")
+		Expect(get()).To(Equal("high"))
+
+		queue.Add("high")
+		queue.Add("default")
+		Expect(get()).To(Equal("default"))
+		Expect(queue.Len()).To(Equal(0))
+
+		queue.Done("high")
+		Expect(queue.Len()).To(Equal(1))
+		Expect(get()).To(Equal("high"))
+	})
+
+	It("adds items after a delay", func() {
+		queue.AddAfter("a", 10*time.Millisecond)
+		Expect(queue.Len()).To(Equal(0))
+		Eventually(queue.Len).Should(Equal(1))
+	})
+
+	It("returns from Get, once it is shut down", func() {
+		queue.ShutDown()
+		queue.Add("a")
+
+		_, shutdown := queue.Get()
+		Expect(shutdown).To(BeTrue())
+		Expect(queue.ShuttingDown()).To(BeTrue())
+	})
+})
@@ -0,0 +1,13 @@
+package priorityqueue_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestPriorityqueue(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Priorityqueue Suite")
+}
@@ -35,6 +35,10 @@ func getConfMapRefFromBdpl(object bdv1.BOSHDeployment) map[string]bool {
 		}
 	}
 
+	if cloudConfig := object.Spec.CloudConfig; cloudConfig != nil && cloudConfig.Type == bdv1.ConfigMapReference {
+		result[cloudConfig.Name] = true
+	}
+
 	return result
 }
 
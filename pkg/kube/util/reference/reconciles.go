@@ -6,6 +6,8 @@ import (
 
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	crc "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -138,6 +140,37 @@ func GetReconciles(ctx context.Context, client crc.Client, reconcileType Reconci
 	return result, nil
 }
 
+// GetExternalSecretReconciles returns reconciliation requests for the BOSHDeployments,
+// whose external secret selector matches the labels of the secret
+func GetExternalSecretReconciles(ctx context.Context, client crc.Client, secret *corev1.Secret) ([]reconcile.Request, error) {
+	boshDeployments, err := listBOSHDeployments(ctx, client, secret.Namespace)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list BOSHDeployments for external secret reconciles")
+	}
+
+	result := []reconcile.Request{}
+	for _, boshDeployment := range boshDeployments.Items {
+		if boshDeployment.Namespace != secret.Namespace || boshDeployment.Spec.ExternalSecretSelector == nil {
+			continue
+		}
+
+		selector, err := metav1.LabelSelectorAsSelector(boshDeployment.Spec.ExternalSecretSelector)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid external secret selector of BOSHDeployment '%s'", boshDeployment.Name)
+		}
+
+		if selector.Matches(labels.Set(secret.Labels)) {
+			result = append(result, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      boshDeployment.Name,
+					Namespace: boshDeployment.Namespace,
+				}})
+		}
+	}
+
+	return result, nil
+}
+
 // SkipReconciles returns true if the object is stale, and shouldn't be enqueued for reconciliation
 // The object can be a ConfigMap or a Secret
 func SkipReconciles(ctx context.Context, client crc.Client, object apis.Object) bool {
@@ -6,10 +6,12 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/scheme"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
 	qstsv1a1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/quarksstatefulset/v1alpha1"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/controllers"
 	cfakes "code.cloudfoundry.org/cf-operator/pkg/kube/controllers/fakes"
@@ -96,3 +98,58 @@ var _ = Describe("GetReconciles", func() {
 		})
 	})
 })
+
+var _ = Describe("GetExternalSecretReconciles", func() {
+	var (
+		deployment bdv1.BOSHDeployment
+		secret     corev1.Secret
+		env        testing.Catalog
+		client     client.Client
+	)
+
+	BeforeEach(func() {
+		controllers.AddToScheme(scheme.Scheme)
+
+		deployment = env.DefaultBOSHDeployment("foo", "manifest")
+		deployment.Spec.ExternalSecretSelector = &metav1.LabelSelector{
+			MatchLabels: map[string]string{"external": "foo"},
+		}
+		secret = env.DefaultSecret("external")
+		secret.Labels = map[string]string{"external": "foo"}
+	})
+
+	JustBeforeEach(func() {
+		client = fake.NewFakeClient(&deployment, &secret)
+	})
+
+	It("triggers a reconcile when the secret matches the selector", func() {
+		requests, err := reference.GetExternalSecretReconciles(context.Background(), client, &secret)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(requests).To(HaveLen(1))
+		Expect(requests[0].Name).To(Equal("foo"))
+	})
+
+	Context("when the secret doesn't match the selector", func() {
+		BeforeEach(func() {
+			secret.Labels = map[string]string{"external": "bar"}
+		})
+
+		It("doesn't trigger a reconcile", func() {
+			requests, err := reference.GetExternalSecretReconciles(context.Background(), client, &secret)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(requests).To(BeEmpty())
+		})
+	})
+
+	Context("when the deployment has no selector", func() {
+		BeforeEach(func() {
+			deployment.Spec.ExternalSecretSelector = nil
+		})
+
+		It("doesn't trigger a reconcile", func() {
+			requests, err := reference.GetExternalSecretReconciles(context.Background(), client, &secret)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(requests).To(BeEmpty())
+		})
+	})
+})
@@ -12,9 +12,19 @@ import (
 	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
 	qstsv1a1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/quarksstatefulset/v1alpha1"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/util/boshdns"
+	bdnames "code.cloudfoundry.org/cf-operator/pkg/kube/util/names"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/util/withops"
 )
 
+// secretNamer names the implicit variable secrets of BOSHDeployments
+var secretNamer bdnames.SecretNamer = bdnames.DefaultSecretNamer{}
+
+// SetSecretNamer initializes the package scoped secret namer. It has to be
+// the namer of the BOSHDeployment controller.
+func SetSecretNamer(namer bdnames.SecretNamer) {
+	secretNamer = namer
+}
+
 // GetSecretsReferencedBy returns a list of all names for Secrets referenced by the object
 // The object can be an QuarksStatefulSet or a BOSHDeployment
 func GetSecretsReferencedBy(ctx context.Context, client crc.Client, object interface{}) (map[string]bool, error) {
@@ -41,6 +51,10 @@ func getSecretRefFromBdpl(ctx context.Context, client crc.Client, object bdv1.BO
 		}
 	}
 
+	if cloudConfig := object.Spec.CloudConfig; cloudConfig != nil && cloudConfig.Type == bdv1.SecretReference {
+		result[cloudConfig.Name] = true
+	}
+
 	// Include secrets of implicit vars
 	withops := withops.NewResolver(
 		client,
@@ -48,6 +62,7 @@ func getSecretRefFromBdpl(ctx context.Context, client crc.Client, object bdv1.BO
 		func(deploymentName string, m bdm.Manifest) (withops.DomainNameService, error) {
 			return boshdns.NewDNS(deploymentName, m)
 		},
+		secretNamer,
 	)
 	_, implicitVars, err := withops.Manifest(&object, object.Namespace)
 	if err != nil {
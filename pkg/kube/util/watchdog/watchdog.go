@@ -0,0 +1,113 @@
+// Package watchdog reports reconciles, which take longer than the reconcile warn threshold
+package watchdog
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	kruntime "k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+const (
+	// DefaultReconcileWarnThreshold is the default duration of a reconcile, after which it is reported as slow
+	DefaultReconcileWarnThreshold = 30 * time.Second
+
+	// maxStackSize limits the size of the goroutine stack dump
+	maxStackSize = 1 << 20
+)
+
+// reconcileWarnThreshold is the duration of a reconcile, after which it is reported as slow. Zero disables the watchdog.
+var reconcileWarnThreshold = DefaultReconcileWarnThreshold
+
+// SetReconcileWarnThreshold initializes the package scoped reconcile warn threshold
+func SetReconcileWarnThreshold(threshold time.Duration) error {
+	if threshold < 0 {
+		return errors.Errorf("invalid reconcile warn threshold '%s', must not be negative", threshold)
+	}
+
+	reconcileWarnThreshold = threshold
+	return nil
+}
+
+// Start starts a watchdog for the reconcile of object. If the reconcile is
+// still running after the reconcile warn threshold, it logs a warning with a
+// stack dump of all goroutines and records a 'SlowReconcile' event on object.
+// The returned function stops the watchdog and must be called, when the
+// reconcile returns.
+func Start(ctx context.Context, object kruntime.Object, name string) context.CancelFunc {
+	ctx, cancel := context.WithCancel(ctx)
+	threshold := reconcileWarnThreshold
+	if threshold == 0 {
+		return cancel
+	}
+
+	go func() {
+		timer := time.NewTimer(threshold)
+		defer timer.Stop()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		ctxlog.ExtractLogger(ctx).Warnf("Reconcile of '%s' is running for more than %s, goroutines:\n%s", name, threshold, stack())
+		ctxlog.WarningEvent(ctx, object, "SlowReconcile", fmt.Sprintf("Reconcile of '%s' is running for more than %s", name, threshold))
+	}()
+
+	return cancel
+}
+
+// stack returns the stack traces of all goroutines, truncated to maxStackSize
+func stack() []byte {
+	buf := make([]byte, 64*1024)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) || len(buf) >= maxStackSize {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// Reconciler runs a watchdog for every reconcile of the wrapped reconciler
+type Reconciler struct {
+	ctx        context.Context
+	reconciler reconcile.Reconciler
+	object     kruntime.Object
+}
+
+// NewReconciler wraps reconciler with a watchdog. Slow reconciles are
+// recorded as events on a copy of object, which is named after the request.
+func NewReconciler(ctx context.Context, reconciler reconcile.Reconciler, object kruntime.Object) reconcile.Reconciler {
+	return &Reconciler{
+		ctx:        ctx,
+		reconciler: reconciler,
+		object:     object,
+	}
+}
+
+// Reconcile calls the wrapped reconciler, while the watchdog is running
+func (r *Reconciler) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	cancel := Start(r.ctx, r.objectFor(request), request.NamespacedName.String())
+	defer cancel()
+
+	return r.reconciler.Reconcile(request)
+}
+
+// objectFor returns a copy of the reconciler's object with the namespace and name of the request
+func (r *Reconciler) objectFor(request reconcile.Request) kruntime.Object {
+	object := r.object.DeepCopyObject()
+	if accessor, err := meta.Accessor(object); err == nil {
+		accessor.SetNamespace(request.Namespace)
+		accessor.SetName(request.Name)
+	}
+	return object
+}
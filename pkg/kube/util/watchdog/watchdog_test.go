@@ -0,0 +1,106 @@
+package watchdog_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/watchdog"
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+	helper "code.cloudfoundry.org/quarks-utils/testing/testhelper"
+)
+
+// sleepingReconciler takes the given duration for every reconcile
+type sleepingReconciler struct {
+	duration time.Duration
+}
+
+func (r sleepingReconciler) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	time.Sleep(r.duration)
+	return reconcile.Result{Requeue: true}, nil
+}
+
+var _ = Describe("Watchdog", func() {
+	var (
+		logs     *observer.ObservedLogs
+		recorder *record.FakeRecorder
+		ctx      context.Context
+		request  reconcile.Request
+	)
+
+	BeforeEach(func() {
+		var log *zap.SugaredLogger
+		logs, log = helper.NewTestLogger()
+		recorder = record.NewFakeRecorder(10)
+		ctx = ctxlog.NewContextWithRecorder(ctxlog.NewParentContext(log), "test", recorder)
+		request = reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "foo"}}
+
+		Expect(watchdog.SetReconcileWarnThreshold(50 * time.Millisecond)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(watchdog.SetReconcileWarnThreshold(watchdog.DefaultReconcileWarnThreshold)).To(Succeed())
+	})
+
+	Describe("SetReconcileWarnThreshold", func() {
+		It("rejects negative thresholds", func() {
+			Expect(watchdog.SetReconcileWarnThreshold(-time.Second)).To(MatchError(ContainSubstring("must not be negative")))
+		})
+	})
+
+	Describe("NewReconciler", func() {
+		It("reports reconciles, which take longer than the threshold", func() {
+			r := watchdog.NewReconciler(ctx, sleepingReconciler{duration: 200 * time.Millisecond}, &bdv1.BOSHDeployment{})
+
+			result, err := r.Reconcile(request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(reconcile.Result{Requeue: true}))
+
+			Eventually(recorder.Events).Should(Receive(Equal("Warning SlowReconcile Reconcile of 'default/foo' is running for more than 50ms")))
+			Eventually(func() int {
+				return logs.FilterMessageSnippet("Reconcile of 'default/foo' is running for more than 50ms").Len()
+			}).Should(Equal(1))
+			Expect(logs.FilterMessageSnippet("goroutine").All()[0].Message).To(ContainSubstring("sleepingReconciler"))
+		})
+
+		It("doesn't report reconciles, which finish within the threshold", func() {
+			r := watchdog.NewReconciler(ctx, sleepingReconciler{}, &bdv1.BOSHDeployment{})
+
+			_, err := r.Reconcile(request)
+			Expect(err).ToNot(HaveOccurred())
+
+			Consistently(recorder.Events, 100*time.Millisecond).ShouldNot(Receive())
+			Expect(logs.Len()).To(Equal(0))
+		})
+
+		It("is disabled by a zero threshold", func() {
+			Expect(watchdog.SetReconcileWarnThreshold(0)).To(Succeed())
+			r := watchdog.NewReconciler(ctx, sleepingReconciler{duration: 100 * time.Millisecond}, &bdv1.BOSHDeployment{})
+
+			_, err := r.Reconcile(request)
+			Expect(err).ToNot(HaveOccurred())
+
+			Consistently(recorder.Events, 100*time.Millisecond).ShouldNot(Receive())
+		})
+	})
+
+	Describe("Start", func() {
+		It("records the event on the object", func() {
+			instance := &bdv1.BOSHDeployment{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "foo"}}
+			cancel := watchdog.Start(ctx, instance, "default/foo")
+			defer cancel()
+
+			Eventually(recorder.Events).Should(Receive(ContainSubstring("SlowReconcile")))
+		})
+	})
+})
@@ -0,0 +1,238 @@
+package withops
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+)
+
+const (
+	// GitKnownHostsKey is the key of the SSH known hosts in the auth secret of a git reference
+	GitKnownHostsKey = "known_hosts"
+
+	// gitTimeout limits the time for fetching a git reference
+	gitTimeout = 2 * time.Minute
+
+	// gitCacheMaxEntries bounds the number of files in the git cache
+	gitCacheMaxEntries = 256
+)
+
+// gitCache keeps the files of the git references, which were fetched last.
+// Resolvers, which don't fetch git references, read them from the cache.
+var gitCache = &gitDataCache{data: map[gitCacheKey]string{}}
+
+// gitCacheKey identifies a file of a git reference. Unpinned references are
+// cached by their ref, so a fetch replaces the file of an earlier revision.
+type gitCacheKey struct {
+	namespace  string
+	repository string
+	revision   string
+	path       string
+	authSecret string
+}
+
+type gitDataCache struct {
+	sync.Mutex
+	data map[gitCacheKey]string
+}
+
+func (c *gitDataCache) get(key gitCacheKey) (string, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	data, ok := c.data[key]
+	return data, ok
+}
+
+func (c *gitDataCache) set(key gitCacheKey, data string) {
+	c.Lock()
+	defer c.Unlock()
+
+	if _, ok := c.data[key]; !ok && len(c.data) >= gitCacheMaxEntries {
+		c.data = map[gitCacheKey]string{}
+	}
+	c.data[key] = data
+}
+
+// GitReferenceNotCachedError is returned by resolvers, which don't fetch git
+// references, when a git reference wasn't fetched yet
+type GitReferenceNotCachedError struct {
+	Repository string
+	Revision   string
+}
+
+func (e *GitReferenceNotCachedError) Error() string {
+	return fmt.Sprintf("revision '%s' of git repository '%s' wasn't fetched yet", e.Revision, e.Repository)
+}
+
+// IsGitReferenceNotCached returns true, if the error is caused by a git reference, which wasn't fetched yet
+func IsGitReferenceNotCached(err error) bool {
+	_, ok := errors.Cause(err).(*GitReferenceNotCachedError)
+	return ok
+}
+
+// gitData fetches the revision of a git reference shallowly and returns the
+// content of the referenced file. The repository is fetched into a temporary
+// directory, which is removed afterwards. The file is cached for resolvers,
+// which don't fetch git references.
+func (r *Resolver) gitData(namespace string, ref bdv1.ResourceReference, key string) (string, error) {
+	if ref.Git == nil || ref.Git.Path == "" {
+		return "", fmt.Errorf("%s git reference '%s' has no path", key, ref.Name)
+	}
+
+	revision := "HEAD"
+	if ref.Git.Pinned() {
+		revision = ref.Git.Commit
+	} else if ref.Git.Ref != "" {
+		revision = ref.Git.Ref
+	}
+
+	cacheKey := gitCacheKey{
+		namespace:  namespace,
+		repository: ref.Name,
+		revision:   revision,
+		path:       ref.Git.Path,
+		authSecret: ref.Git.AuthSecretName,
+	}
+	if r.cachedGitReferences {
+		data, ok := gitCache.get(cacheKey)
+		if !ok {
+			return "", errors.Wrapf(&GitReferenceNotCachedError{Repository: ref.Name, Revision: revision}, "failed to read %s git reference '%s'", key, ref.Name)
+		}
+		return data, nil
+	}
+
+	data, err := r.fetchGitData(namespace, ref, key, revision)
+	if err != nil {
+		return "", err
+	}
+
+	gitCache.set(cacheKey, data)
+	return data, nil
+}
+
+func (r *Resolver) fetchGitData(namespace string, ref bdv1.ResourceReference, key string, revision string) (string, error) {
+	dir, err := ioutil.TempDir("", "git-reference-")
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to create directory for %s git reference '%s'", key, ref.Name)
+	}
+	defer os.RemoveAll(dir)
+
+	config, env, err := r.gitAuth(namespace, ref.Git.AuthSecretName, dir)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get credentials for %s git reference '%s'", key, ref.Name)
+	}
+
+	caConfig, err := r.gitCAConfig(namespace, dir)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get trusted CA bundle for %s git reference '%s'", key, ref.Name)
+	}
+	config = append(config, caConfig...)
+
+	ctx, cancel := context.WithTimeout(r.ctx, gitTimeout)
+	defer cancel()
+
+	repo := filepath.Join(dir, "repo")
+	if _, err := git(ctx, env, "init", "-q", repo); err != nil {
+		return "", errors.Wrapf(err, "failed to initialize repository for %s git reference '%s'", key, ref.Name)
+	}
+
+	args := append(config, "-C", repo, "fetch", "-q", "--depth", "1", "--", ref.Name, revision)
+	if _, err := git(ctx, env, args...); err != nil {
+		return "", errors.Wrapf(err, "failed to fetch '%s' of %s git reference '%s'", revision, key, ref.Name)
+	}
+
+	if ref.Git.Pinned() {
+		commit, err := git(ctx, env, "-C", repo, "rev-parse", "FETCH_HEAD")
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to resolve fetched commit of %s git reference '%s'", key, ref.Name)
+		}
+		if strings.TrimSpace(commit) != ref.Git.Commit {
+			return "", fmt.Errorf("%s git reference '%s' fetched commit '%s' instead of pinned commit '%s'", key, ref.Name, strings.TrimSpace(commit), ref.Git.Commit)
+		}
+	}
+
+	data, err := git(ctx, env, "-C", repo, "show", "FETCH_HEAD:"+strings.TrimPrefix(ref.Git.Path, "/"))
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read '%s' of %s git reference '%s'", ref.Git.Path, key, ref.Name)
+	}
+
+	return data, nil
+}
+
+// gitAuth returns the git configuration arguments and environment for the
+// credentials in the named secret. SSH keys are written into dir.
+func (r *Resolver) gitAuth(namespace string, secretName string, dir string) ([]string, []string, error) {
+	env := []string{"GIT_TERMINAL_PROMPT=0"}
+	if secretName == "" {
+		return []string{}, env, nil
+	}
+
+	secret := &corev1.Secret{}
+	err := r.client.Get(r.ctx, types.NamespacedName{Name: secretName, Namespace: namespace}, secret)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to retrieve git auth secret '%s/%s' via client.Get", namespace, secretName)
+	}
+
+	if key, ok := secret.Data[corev1.SSHAuthPrivateKey]; ok {
+		knownHosts, ok := secret.Data[GitKnownHostsKey]
+		if !ok {
+			return nil, nil, fmt.Errorf("git auth secret '%s/%s' has an SSH key, but doesn't contain key %s", namespace, secretName, GitKnownHostsKey)
+		}
+
+		keyPath := filepath.Join(dir, "id")
+		knownHostsPath := filepath.Join(dir, "known_hosts")
+		if err := ioutil.WriteFile(keyPath, key, 0600); err != nil {
+			return nil, nil, errors.Wrap(err, "failed to write SSH key")
+		}
+		if err := ioutil.WriteFile(knownHostsPath, knownHosts, 0600); err != nil {
+			return nil, nil, errors.Wrap(err, "failed to write SSH known hosts")
+		}
+
+		env = append(env, fmt.Sprintf("GIT_SSH_COMMAND=ssh -i %s -o IdentitiesOnly=yes -o UserKnownHostsFile=%s -o StrictHostKeyChecking=yes", keyPath, knownHostsPath))
+		return []string{}, env, nil
+	}
+
+	username, hasUsername := secret.Data[corev1.BasicAuthUsernameKey]
+	password, hasPassword := secret.Data[corev1.BasicAuthPasswordKey]
+	if !hasUsername || !hasPassword {
+		return nil, nil, fmt.Errorf("git auth secret '%s/%s' must contain either key %s or keys %s and %s", namespace, secretName, corev1.SSHAuthPrivateKey, corev1.BasicAuthUsernameKey, corev1.BasicAuthPasswordKey)
+	}
+
+	credentials := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", username, password)))
+	return []string{"-c", "http.extraHeader=Authorization: Basic " + credentials}, env, nil
+}
+
+// git runs the git binary and returns its output. Errors contain git's error output.
+func git(ctx context.Context, env []string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", errors.Wrap(err, msg)
+		}
+		return "", err
+	}
+
+	return stdout.String(), nil
+}
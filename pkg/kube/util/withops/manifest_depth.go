@@ -0,0 +1,85 @@
+package withops
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultMaxManifestDepth is the default maximum nesting depth of resolved
+// manifests. Real BOSH manifests rarely nest deeper than 20 levels.
+const DefaultMaxManifestDepth = 100
+
+// maxManifestDepth is the maximum nesting depth of maps and lists in resolved manifests, zero disables the check
+var maxManifestDepth = DefaultMaxManifestDepth
+
+// SetMaxManifestDepth initializes the package scoped maximum manifest nesting depth
+func SetMaxManifestDepth(depth int) error {
+	if depth < 0 {
+		return errors.Errorf("invalid maximum manifest depth '%d', must not be negative", depth)
+	}
+
+	maxManifestDepth = depth
+	return nil
+}
+
+// ManifestTooDeepError is returned by the resolver, when a manifest is nested
+// deeper than the maximum manifest depth
+type ManifestTooDeepError struct {
+	MaxDepth int
+}
+
+func (e *ManifestTooDeepError) Error() string {
+	return fmt.Sprintf("manifest is nested deeper than the maximum depth of %d", e.MaxDepth)
+}
+
+// IsManifestTooDeep returns true, if the error is caused by a manifest exceeding the maximum depth
+func IsManifestTooDeep(err error) bool {
+	_, ok := errors.Cause(err).(*ManifestTooDeepError)
+	return ok
+}
+
+// checkManifestDepth returns a ManifestTooDeepError, if the YAML document
+// nests maps and lists deeper than the maximum manifest depth
+func checkManifestDepth(data []byte) error {
+	if maxManifestDepth == 0 {
+		return nil
+	}
+
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return errors.Wrap(err, "failed to unmarshal manifest for depth check")
+	}
+
+	if exceedsDepth(doc, 0) {
+		return &ManifestTooDeepError{MaxDepth: maxManifestDepth}
+	}
+	return nil
+}
+
+// exceedsDepth returns true, if v nests deeper than the maximum manifest
+// depth. It stops descending once the maximum is exceeded.
+func exceedsDepth(v interface{}, depth int) bool {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		if depth+1 > maxManifestDepth {
+			return true
+		}
+		for _, value := range v {
+			if exceedsDepth(value, depth+1) {
+				return true
+			}
+		}
+	case []interface{}:
+		if depth+1 > maxManifestDepth {
+			return true
+		}
+		for _, value := range v {
+			if exceedsDepth(value, depth+1) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,176 @@
+package withops
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// OAuthRefreshTokenKey is the key of the refresh token in the secret selected by an oauthTokenSecretRef
+	OAuthRefreshTokenKey = "refresh_token"
+	// OAuthTokenURLKey is the key of the token endpoint in the secret selected by an oauthTokenSecretRef
+	OAuthTokenURLKey = "token_url"
+	// OAuthTokenExpiresAtKey is the key of the expiry time of an opaque token in the secret selected by an
+	// oauthTokenSecretRef. It is set from the 'expires_in' of the token response, when the token is refreshed.
+	OAuthTokenExpiresAtKey = "expires_at"
+
+	// oauthTokenExpirySkew refreshes tokens shortly before they expire
+	oauthTokenExpirySkew = 30 * time.Second
+)
+
+// oauthToken returns the bearer token selected by ref. An expired token is
+// refreshed and the new token is stored in the secret.
+func (r *Resolver) oauthToken(namespace string, ref *corev1.SecretKeySelector) (string, error) {
+	secret := &corev1.Secret{}
+	err := r.client.Get(r.ctx, types.NamespacedName{Name: ref.Name, Namespace: namespace}, secret)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to retrieve oauth token secret '%s/%s' via client.Get", namespace, ref.Name)
+	}
+
+	token, ok := secret.Data[ref.Key]
+	if !ok {
+		return "", fmt.Errorf("secret '%s/%s' doesn't contain key %s", namespace, ref.Name, ref.Key)
+	}
+
+	expiry, err := tokenExpiry(string(token), secret.Data[OAuthTokenExpiresAtKey])
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to parse oauth token expiry from secret '%s/%s'", namespace, ref.Name)
+	}
+	if expiry.IsZero() || time.Now().Add(oauthTokenExpirySkew).Before(expiry) {
+		return string(token), nil
+	}
+
+	refreshToken, ok := secret.Data[OAuthRefreshTokenKey]
+	if !ok {
+		return "", fmt.Errorf("oauth token in secret '%s/%s' expired and there is no %s", namespace, ref.Name, OAuthRefreshTokenKey)
+	}
+	tokenURL, ok := secret.Data[OAuthTokenURLKey]
+	if !ok {
+		return "", fmt.Errorf("oauth token in secret '%s/%s' expired and there is no %s", namespace, ref.Name, OAuthTokenURLKey)
+	}
+
+	httpClient, err := r.httpClient(namespace)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to build client for oauth token endpoint '%s'", tokenURL)
+	}
+
+	refreshed, err := refreshOAuthToken(r.ctx, httpClient, string(tokenURL), string(refreshToken))
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to refresh oauth token from secret '%s/%s'", namespace, ref.Name)
+	}
+
+	secret.Data[ref.Key] = []byte(refreshed.AccessToken)
+	if refreshed.RefreshToken != "" {
+		secret.Data[OAuthRefreshTokenKey] = []byte(refreshed.RefreshToken)
+	}
+	delete(secret.Data, OAuthTokenExpiresAtKey)
+	if refreshed.ExpiresIn > 0 {
+		expiresAt := time.Now().Add(time.Duration(refreshed.ExpiresIn) * time.Second)
+		secret.Data[OAuthTokenExpiresAtKey] = []byte(expiresAt.UTC().Format(time.RFC3339))
+	}
+	err = r.client.Update(r.ctx, secret)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to store refreshed oauth token in secret '%s/%s'", namespace, ref.Name)
+	}
+
+	return refreshed.AccessToken, nil
+}
+
+// tokenExpiry returns the expiry of the token. OAuth access tokens need not
+// be JWTs, so for opaque tokens and JWTs without 'exp' claim, the expiry
+// stored with the token is used. The returned time is zero, if the token
+// doesn't expire or its expiry is unknown.
+func tokenExpiry(token string, expiresAt []byte) (time.Time, error) {
+	expiry, err := jwtExpiry(token)
+	if err == nil && !expiry.IsZero() {
+		return expiry, nil
+	}
+
+	if len(expiresAt) == 0 {
+		return time.Time{}, nil
+	}
+	expiry, err = time.Parse(time.RFC3339, string(expiresAt))
+	if err != nil {
+		return time.Time{}, errors.Wrapf(err, "failed to parse %s", OAuthTokenExpiresAtKey)
+	}
+	return expiry, nil
+}
+
+// jwtExpiry returns the time of the token's 'exp' claim. The returned
+// time is zero, if the token doesn't expire.
+func jwtExpiry(token string) (time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, fmt.Errorf("expected a JWT with 3 parts, have %d", len(parts))
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "failed to decode JWT payload")
+	}
+
+	claims := struct {
+		Exp int64 `json:"exp"`
+	}{}
+	err = json.Unmarshal(payload, &claims)
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "failed to unmarshal JWT claims")
+	}
+
+	if claims.Exp == 0 {
+		return time.Time{}, nil
+	}
+	return time.Unix(claims.Exp, 0), nil
+}
+
+type oauthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// refreshOAuthToken requests a new access token with the refresh token grant
+func refreshOAuthToken(ctx context.Context, httpClient *http.Client, tokenURL string, refreshToken string) (oauthTokenResponse, error) {
+	resp := oauthTokenResponse{}
+
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return resp, errors.Wrapf(err, "failed to build token request for '%s'", tokenURL)
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	httpResponse, err := httpClient.Do(request)
+	if err != nil {
+		return resp, errors.Wrapf(err, "failed to request token from '%s'", tokenURL)
+	}
+	defer httpResponse.Body.Close()
+
+	if httpResponse.StatusCode != http.StatusOK {
+		return resp, fmt.Errorf("token endpoint '%s' responded with %s", tokenURL, httpResponse.Status)
+	}
+
+	err = json.NewDecoder(httpResponse.Body).Decode(&resp)
+	if err != nil {
+		return resp, errors.Wrapf(err, "failed to decode token response from '%s'", tokenURL)
+	}
+	if resp.AccessToken == "" {
+		return resp, fmt.Errorf("token response from '%s' doesn't contain an access_token", tokenURL)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,153 @@
+package withops
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+)
+
+// trustedOpsKeys are the public keys of the approved ops file signers, no keys disable the verification
+var trustedOpsKeys []ed25519.PublicKey
+
+// SetTrustedOpsKeys initializes the package scoped trusted ops keys from a
+// file of PEM encoded ed25519 public keys. An empty path disables the
+// verification of ops file signatures.
+func SetTrustedOpsKeys(path string) error {
+	if path == "" {
+		trustedOpsKeys = nil
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read trusted ops keys '%s'", path)
+	}
+
+	keys, err := parseTrustedOpsKeys(data)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse trusted ops keys '%s'", path)
+	}
+
+	trustedOpsKeys = keys
+	return nil
+}
+
+// parseTrustedOpsKeys returns the ed25519 public keys of the PEM blocks
+func parseTrustedOpsKeys(data []byte) ([]ed25519.PublicKey, error) {
+	keys := []ed25519.PublicKey{}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "PUBLIC KEY" {
+			continue
+		}
+
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		edKey, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return nil, errors.Errorf("unsupported public key type %T, only ed25519 keys are supported", key)
+		}
+		keys = append(keys, edKey)
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("no public keys found")
+	}
+	return keys, nil
+}
+
+// UnsignedOpsError is returned by the resolver, when an ops file isn't
+// signed by a trusted signer
+type UnsignedOpsError struct {
+	Name   string
+	Reason string
+}
+
+func (e *UnsignedOpsError) Error() string {
+	return fmt.Sprintf("ops file '%s' is not signed by a trusted signer: %s", e.Name, e.Reason)
+}
+
+// IsUnsignedOps returns true, if the error is caused by an ops file without a trusted signature
+func IsUnsignedOps(err error) bool {
+	_, ok := errors.Cause(err).(*UnsignedOpsError)
+	return ok
+}
+
+// opsData returns the data of an ops file. If trusted ops keys are
+// configured, the signature annotation of the ops configmap or secret has to
+// verify the data. URL and git references can't carry the annotation, so
+// they are rejected then.
+func (r *Resolver) opsData(namespace string, ref bdv1.ResourceReference) (string, error) {
+	data, err := r.resourceRefData(namespace, ref, bdv1.OpsSpecName)
+	if err != nil || len(trustedOpsKeys) == 0 {
+		return data, err
+	}
+
+	annotations, err := r.opsAnnotations(namespace, ref)
+	if err != nil {
+		return "", err
+	}
+
+	err = verifyOpsSignature(ref.Name, []byte(data), annotations[bdv1.AnnotationOpsSignature])
+	if err != nil {
+		return "", err
+	}
+	return data, nil
+}
+
+// opsAnnotations returns the annotations of an ops configmap or secret
+func (r *Resolver) opsAnnotations(namespace string, ref bdv1.ResourceReference) (map[string]string, error) {
+	key := types.NamespacedName{Name: ref.Name, Namespace: namespace}
+	switch ref.Type {
+	case bdv1.ConfigMapReference:
+		configMap := &corev1.ConfigMap{}
+		if err := r.client.Get(r.ctx, key, configMap); err != nil {
+			return nil, errors.Wrapf(err, "failed to retrieve ops signature from configmap '%s'", key)
+		}
+		return configMap.GetAnnotations(), nil
+	case bdv1.SecretReference:
+		secret := &corev1.Secret{}
+		if err := r.client.Get(r.ctx, key, secret); err != nil {
+			return nil, errors.Wrapf(err, "failed to retrieve ops signature from secret '%s'", key)
+		}
+		return secret.GetAnnotations(), nil
+	default:
+		return nil, &UnsignedOpsError{Name: ref.Name, Reason: fmt.Sprintf("%s references can't be signed", ref.Type)}
+	}
+}
+
+// verifyOpsSignature returns an UnsignedOpsError, if the signature doesn't
+// verify the ops data with any of the trusted ops keys
+func verifyOpsSignature(name string, data []byte, signature string) error {
+	if signature == "" {
+		return &UnsignedOpsError{Name: name, Reason: fmt.Sprintf("missing '%s' annotation", bdv1.AnnotationOpsSignature)}
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return &UnsignedOpsError{Name: name, Reason: "signature is not base64 encoded"}
+	}
+
+	for _, key := range trustedOpsKeys {
+		if ed25519.Verify(key, data, sig) {
+			return nil
+		}
+	}
+	return &UnsignedOpsError{Name: name, Reason: "signature doesn't match any trusted key"}
+}
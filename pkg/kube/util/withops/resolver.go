@@ -11,11 +11,13 @@ import (
 	"github.com/pkg/errors"
 
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
 	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	bdnames "code.cloudfoundry.org/cf-operator/pkg/kube/util/names"
 	"code.cloudfoundry.org/quarks-utils/pkg/names"
 	"code.cloudfoundry.org/quarks-utils/pkg/versionedsecretstore"
 )
@@ -28,10 +30,13 @@ type DomainNameService interface {
 
 // Resolver resolves references from bdpl CR to a BOSH manifest
 type Resolver struct {
+	ctx                  context.Context
 	client               client.Client
 	versionedSecretStore versionedsecretstore.VersionedSecretStore
 	newInterpolatorFunc  NewInterpolatorFunc
 	newDNSFunc           NewDNSFunc
+	secretNamer          bdnames.SecretNamer
+	cachedGitReferences  bool
 }
 
 // NewInterpolatorFunc returns a fresh Interpolator
@@ -41,15 +46,35 @@ type NewInterpolatorFunc func() Interpolator
 type NewDNSFunc func(deploymentName string, m bdm.Manifest) (DomainNameService, error)
 
 // NewResolver constructs a resolver
-func NewResolver(client client.Client, f NewInterpolatorFunc, dns NewDNSFunc) *Resolver {
+func NewResolver(client client.Client, f NewInterpolatorFunc, dns NewDNSFunc, secretNamer bdnames.SecretNamer) *Resolver {
 	return &Resolver{
+		ctx:                  context.Background(),
 		client:               client,
 		newInterpolatorFunc:  f,
 		newDNSFunc:           dns,
+		secretNamer:          secretNamer,
 		versionedSecretStore: versionedsecretstore.NewVersionedSecretStore(client),
 	}
 }
 
+// WithContext returns a copy of the resolver, whose requests to the cluster,
+// to URLs and to git repositories are canceled with ctx
+func (r *Resolver) WithContext(ctx context.Context) *Resolver {
+	resolver := *r
+	resolver.ctx = ctx
+	return &resolver
+}
+
+// WithCachedGitReferences returns a copy of the resolver, which doesn't fetch
+// git references, but reads them from the files fetched by other resolvers.
+// It fails with a GitReferenceNotCachedError for references, which weren't
+// fetched yet.
+func (r *Resolver) WithCachedGitReferences() *Resolver {
+	resolver := *r
+	resolver.cachedGitReferences = true
+	return &resolver
+}
+
 // Manifest returns manifest and a list of implicit variables referenced by our bdpl CRD
 // The resulting manifest has variables interpolated and ops files applied.
 // It is the 'with-ops' manifest.
@@ -61,7 +86,11 @@ func (r *Resolver) Manifest(bdpl *bdv1.BOSHDeployment, namespace string) (*bdm.M
 		err error
 	)
 
-	m, err = r.resourceData(namespace, spec.Manifest.Type, spec.Manifest.Name, bdv1.ManifestSpecName)
+	m, err = r.resourceRefData(namespace, spec.Manifest, bdv1.ManifestSpecName)
+	if err != nil {
+		return nil, []string{}, errors.Wrapf(err, "Interpolation failed for bosh deployment %s", bdpl.GetName())
+	}
+	err = checkManifestDepth([]byte(m))
 	if err != nil {
 		return nil, []string{}, errors.Wrapf(err, "Interpolation failed for bosh deployment %s", bdpl.GetName())
 	}
@@ -70,7 +99,7 @@ func (r *Resolver) Manifest(bdpl *bdv1.BOSHDeployment, namespace string) (*bdm.M
 	ops := spec.Ops
 
 	for _, op := range ops {
-		opsData, err := r.resourceData(namespace, op.Type, op.Name, bdv1.OpsSpecName)
+		opsData, err := r.opsData(namespace, op)
 		if err != nil {
 			return nil, []string{}, errors.Wrapf(err, "Interpolation failed for bosh deployment %s", bdpl.GetName())
 		}
@@ -88,6 +117,14 @@ func (r *Resolver) Manifest(bdpl *bdv1.BOSHDeployment, namespace string) (*bdm.M
 		}
 	}
 
+	// Ops files may nest the manifest deeper
+	if len(ops) != 0 {
+		err = checkManifestDepth(bytes)
+		if err != nil {
+			return nil, []string{}, errors.Wrapf(err, "Interpolation failed for bosh deployment %s", bdpl.GetName())
+		}
+	}
+
 	// Reload the manifest after interpolation, and apply implicit variables
 	manifest, err := bdm.LoadYAML(bytes)
 	if err != nil {
@@ -110,11 +147,11 @@ func (r *Resolver) Manifest(bdpl *bdv1.BOSHDeployment, namespace string) (*bdm.M
 				return nil, []string{}, fmt.Errorf("expected one / separator for implicit variable/key name, have %d", len(parts))
 			}
 
-			varSecretName = names.DeploymentSecretName(names.DeploymentSecretTypeVariable, bdpl.GetName(), parts[0])
+			varSecretName = r.secretNamer.DeploymentSecretName(names.DeploymentSecretTypeVariable, bdpl.GetName(), parts[0])
 			varKeyName = parts[1]
 		} else {
 			varKeyName = bdv1.ImplicitVariableKeyName
-			varSecretName = names.DeploymentSecretName(names.DeploymentSecretTypeVariable, bdpl.GetName(), v)
+			varSecretName = r.secretNamer.DeploymentSecretName(names.DeploymentSecretTypeVariable, bdpl.GetName(), v)
 		}
 
 		varData, err := r.resourceData(namespace, bdv1.SecretReference, varSecretName, varKeyName)
@@ -141,6 +178,61 @@ func (r *Resolver) Manifest(bdpl *bdv1.BOSHDeployment, namespace string) (*bdm.M
 	return manifest, varSecrets, err
 }
 
+// NoOpOps returns the names of the ops files of the deployment, which leave
+// the manifest unchanged, when they are applied in order. Such ops files
+// usually target the wrong path or were already applied to the manifest.
+func (r *Resolver) NoOpOps(bdpl *bdv1.BOSHDeployment, namespace string) ([]string, error) {
+	m, err := r.resourceRefData(namespace, bdpl.Spec.Manifest, bdv1.ManifestSpecName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to get manifest of bosh deployment '%s'", bdpl.GetName())
+	}
+
+	// Marshal the manifest like the interpolator, so it compares to its output
+	bytes, err := r.newInterpolatorFunc().Interpolate([]byte(m))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to normalize manifest of bosh deployment '%s'", bdpl.GetName())
+	}
+
+	noOps := []string{}
+	for _, op := range bdpl.Spec.Ops {
+		interpolator := r.newInterpolatorFunc()
+
+		opsData, err := r.opsData(namespace, op)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to get resource data for interpolation of bosh deployment '%s' and ops '%s'", bdpl.GetName(), op.Name)
+		}
+		err = interpolator.BuildOps([]byte(opsData))
+		if err != nil {
+			return nil, errors.Wrapf(err, "Interpolation failed for bosh deployment '%s' and ops '%s'", bdpl.GetName(), op.Name)
+		}
+
+		result, err := interpolator.Interpolate(bytes)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to interpolate ops '%s' for manifest '%s'", op.Name, bdpl.Name)
+		}
+		if string(result) == string(bytes) {
+			noOps = append(noOps, op.Name)
+		}
+		bytes = result
+	}
+
+	return noOps, nil
+}
+
+// CloudConfig returns the cloud config referenced by our bdpl CRD, or nil, if it references none
+func (r *Resolver) CloudConfig(bdpl *bdv1.BOSHDeployment, namespace string) (*bdm.CloudConfig, error) {
+	if bdpl.Spec.CloudConfig == nil {
+		return nil, nil
+	}
+
+	data, err := r.resourceRefData(namespace, *bdpl.Spec.CloudConfig, bdv1.CloudConfigSpecName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to get cloud config of bosh deployment '%s'", bdpl.GetName())
+	}
+
+	return bdm.LoadCloudConfig([]byte(data))
+}
+
 // ManifestDetailed returns manifest and a list of implicit variables referenced by our bdpl CRD
 // The resulting manifest has variables interpolated and ops files applied.
 // It is the 'with-ops' manifest. This variant processes each ops file individually, so it's more debuggable - but slower.
@@ -151,7 +243,11 @@ func (r *Resolver) ManifestDetailed(bdpl *bdv1.BOSHDeployment, namespace string)
 		err error
 	)
 
-	m, err = r.resourceData(namespace, spec.Manifest.Type, spec.Manifest.Name, bdv1.ManifestSpecName)
+	m, err = r.resourceRefData(namespace, spec.Manifest, bdv1.ManifestSpecName)
+	if err != nil {
+		return nil, []string{}, errors.Wrapf(err, "Interpolation failed for bosh deployment %s", bdpl.GetName())
+	}
+	err = checkManifestDepth([]byte(m))
 	if err != nil {
 		return nil, []string{}, errors.Wrapf(err, "Interpolation failed for bosh deployment %s", bdpl.GetName())
 	}
@@ -163,7 +259,7 @@ func (r *Resolver) ManifestDetailed(bdpl *bdv1.BOSHDeployment, namespace string)
 	for _, op := range ops {
 		interpolator := r.newInterpolatorFunc()
 
-		opsData, err := r.resourceData(namespace, op.Type, op.Name, bdv1.OpsSpecName)
+		opsData, err := r.opsData(namespace, op)
 		if err != nil {
 			return nil, []string{}, errors.Wrapf(err, "Failed to get resource data for interpolation of bosh deployment '%s' and ops '%s'", bdpl.GetName(), op.Name)
 		}
@@ -178,6 +274,14 @@ func (r *Resolver) ManifestDetailed(bdpl *bdv1.BOSHDeployment, namespace string)
 		}
 	}
 
+	// Ops files may nest the manifest deeper
+	if len(ops) != 0 {
+		err = checkManifestDepth(bytes)
+		if err != nil {
+			return nil, []string{}, errors.Wrapf(err, "Interpolation failed for bosh deployment %s", bdpl.GetName())
+		}
+	}
+
 	// Reload the manifest after interpolation, and apply implicit variables
 	manifest, err := bdm.LoadYAML(bytes)
 	if err != nil {
@@ -200,11 +304,11 @@ func (r *Resolver) ManifestDetailed(bdpl *bdv1.BOSHDeployment, namespace string)
 				return nil, []string{}, fmt.Errorf("expected one / separator for implicit variable/key name, have %d", len(parts))
 			}
 
-			varSecretName = names.DeploymentSecretName(names.DeploymentSecretTypeVariable, bdpl.GetName(), parts[0])
+			varSecretName = r.secretNamer.DeploymentSecretName(names.DeploymentSecretTypeVariable, bdpl.GetName(), parts[0])
 			varKeyName = parts[1]
 		} else {
 			varKeyName = bdv1.ImplicitVariableKeyName
-			varSecretName = names.DeploymentSecretName(names.DeploymentSecretTypeVariable, bdpl.GetName(), v)
+			varSecretName = r.secretNamer.DeploymentSecretName(names.DeploymentSecretTypeVariable, bdpl.GetName(), v)
 		}
 
 		varData, err := r.resourceData(namespace, bdv1.SecretReference, varSecretName, varKeyName)
@@ -294,6 +398,51 @@ func (r *Resolver) replaceVarRecursive(copy, v reflect.Value, varName, varValue
 	}
 }
 
+// resourceRefData resolves a manifest or ops reference and returns the resource's data.
+// URL references with an OAuth token are requested with the token as bearer.
+// Git references are fetched from their repository.
+func (r *Resolver) resourceRefData(namespace string, ref bdv1.ResourceReference, key string) (string, error) {
+	if ref.Type == bdv1.GitReference {
+		return r.gitData(namespace, ref, key)
+	}
+
+	if ref.Type != bdv1.URLReference || ref.OAuthTokenSecretRef == nil {
+		return r.resourceData(namespace, ref.Type, ref.Name, key)
+	}
+
+	token, err := r.oauthToken(namespace, ref.OAuthTokenSecretRef)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get oauth token for %s url '%s'", key, ref.Name)
+	}
+
+	request, err := http.NewRequestWithContext(r.ctx, http.MethodGet, ref.Name, nil)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to build request for %s url '%s'", key, ref.Name)
+	}
+	request.Header.Set("Authorization", "Bearer "+token)
+
+	httpClient, err := r.httpClient(namespace)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to build client for %s url '%s'", key, ref.Name)
+	}
+	httpResponse, err := httpClient.Do(request)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to resolve %s from url '%s' via http.Get", key, ref.Name)
+	}
+	defer httpResponse.Body.Close()
+
+	if httpResponse.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to resolve %s from url '%s': %s", key, ref.Name, httpResponse.Status)
+	}
+
+	body, err := ioutil.ReadAll(httpResponse.Body)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read %s response body '%s' via ioutil", key, ref.Name)
+	}
+
+	return string(body), nil
+}
+
 // resourceData resolves different manifest reference types and returns the resource's data
 func (r *Resolver) resourceData(namespace string, resType bdv1.ReferenceType, name string, key string) (string, error) {
 	var (
@@ -304,7 +453,7 @@ func (r *Resolver) resourceData(namespace string, resType bdv1.ReferenceType, na
 	switch resType {
 	case bdv1.ConfigMapReference:
 		opsConfig := &corev1.ConfigMap{}
-		err := r.client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: namespace}, opsConfig)
+		err := r.client.Get(r.ctx, types.NamespacedName{Name: name, Namespace: namespace}, opsConfig)
 		if err != nil {
 			return data, errors.Wrapf(err, "failed to retrieve %s from configmap '%s/%s' via client.Get", key, namespace, name)
 		}
@@ -314,7 +463,10 @@ func (r *Resolver) resourceData(namespace string, resType bdv1.ReferenceType, na
 		}
 	case bdv1.SecretReference:
 		opsSecret := &corev1.Secret{}
-		err := r.client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: namespace}, opsSecret)
+		err := r.client.Get(r.ctx, types.NamespacedName{Name: name, Namespace: namespace}, opsSecret)
+		if apierrors.IsNotFound(err) {
+			err = &SecretNotFoundError{Namespace: namespace, Name: name}
+		}
 		if err != nil {
 			return data, errors.Wrapf(err, "failed to retrieve %s from secret '%s/%s' via client.Get", key, namespace, name)
 		}
@@ -324,10 +476,19 @@ func (r *Resolver) resourceData(namespace string, resType bdv1.ReferenceType, na
 		}
 		data = string(encodedData)
 	case bdv1.URLReference:
-		httpResponse, err := http.Get(name)
+		request, err := http.NewRequestWithContext(r.ctx, http.MethodGet, name, nil)
+		if err != nil {
+			return data, errors.Wrapf(err, "failed to build request for %s url '%s'", key, name)
+		}
+		httpClient, err := r.httpClient(namespace)
+		if err != nil {
+			return data, errors.Wrapf(err, "failed to build client for %s url '%s'", key, name)
+		}
+		httpResponse, err := httpClient.Do(request)
 		if err != nil {
 			return data, errors.Wrapf(err, "failed to resolve %s from url '%s' via http.Get", key, name)
 		}
+		defer httpResponse.Body.Close()
 		body, err := ioutil.ReadAll(httpResponse.Body)
 		if err != nil {
 			return data, errors.Wrapf(err, "failed to read %s response body '%s' via ioutil", key, name)
@@ -1,7 +1,19 @@
 package withops_test
 
 import (
+	"context"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
 	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -10,6 +22,7 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	fakeClient "sigs.k8s.io/controller-runtime/pkg/client/fake"
 
@@ -17,9 +30,31 @@ import (
 	bdc "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/controllers/fakes"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/util/boshdns"
+	bdnames "code.cloudfoundry.org/cf-operator/pkg/kube/util/names"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/util/withops"
 )
 
+// gitCommit commits content to path in the repository at dir and returns the commit SHA
+func gitCommit(dir string, path string, content string) string {
+	run := func(args ...string) string {
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		Expect(err).ToNot(HaveOccurred(), string(out))
+		return strings.TrimSpace(string(out))
+	}
+
+	Expect(os.MkdirAll(filepath.Dir(filepath.Join(dir, path)), 0755)).To(Succeed())
+	Expect(ioutil.WriteFile(filepath.Join(dir, path), []byte(content), 0644)).To(Succeed())
+	run("add", path)
+	run("commit", "-q", "-m", "update "+path)
+	return run("rev-parse", "HEAD")
+}
+
+func jwt(exp time.Time) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"exp":%d}`, exp.Unix())))
+	return "eyJhbGciOiJub25lIn0." + payload + ".c2ln"
+}
+
 var _ = Describe("WithOps", func() {
 	var (
 		replaceOpsStr string
@@ -335,7 +370,7 @@ instance_groups:
 		newDNSFunc := func(n string, m bdm.Manifest) (withops.DomainNameService, error) {
 			return boshdns.NewSimpleDomainNameService(""), nil
 		}
-		resolver = withops.NewResolver(client, newInterpolatorFunc, newDNSFunc)
+		resolver = withops.NewResolver(client, newInterpolatorFunc, newDNSFunc, bdnames.DefaultSecretNamer{})
 	})
 
 	Describe("ResolveCRD", func() {
@@ -371,6 +406,146 @@ instance_groups:
 			Expect(len(implicitVars)).To(Equal(0))
 		})
 
+		Context("when the maximum manifest depth is exceeded", func() {
+			var deployment *bdc.BOSHDeployment
+
+			BeforeEach(func() {
+				deployment = &bdc.BOSHDeployment{
+					Spec: bdc.BOSHDeploymentSpec{
+						Manifest: bdc.ResourceReference{
+							Type: bdc.ConfigMapReference,
+							Name: "base-manifest",
+						},
+					},
+				}
+			})
+
+			AfterEach(func() {
+				Expect(withops.SetMaxManifestDepth(withops.DefaultMaxManifestDepth)).To(Succeed())
+			})
+
+			It("rejects the manifest", func() {
+				Expect(withops.SetMaxManifestDepth(2)).To(Succeed())
+
+				_, _, err := resolver.Manifest(deployment, "default")
+				Expect(err).To(HaveOccurred())
+				Expect(withops.IsManifestTooDeep(err)).To(BeTrue())
+				Expect(err.Error()).To(ContainSubstring("nested deeper than the maximum depth of 2"))
+			})
+
+			It("rejects manifests nested deeper by ops files", func() {
+				Expect(withops.SetMaxManifestDepth(3)).To(Succeed())
+				deployment.Spec.Ops = []bdc.ResourceReference{{Name: "replace-ops", Type: bdc.ConfigMapReference}}
+				interpolator.InterpolateReturns([]byte(`---
+instance_groups:
+  - name: component1
+    properties:
+      nested: true
+`), nil)
+
+				_, _, err := resolver.Manifest(deployment, "default")
+				Expect(err).To(HaveOccurred())
+				Expect(withops.IsManifestTooDeep(err)).To(BeTrue())
+			})
+
+			It("accepts the manifest, if the check is disabled", func() {
+				Expect(withops.SetMaxManifestDepth(0)).To(Succeed())
+
+				_, _, err := resolver.Manifest(deployment, "default")
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("rejects negative depths", func() {
+				Expect(withops.SetMaxManifestDepth(-1)).To(MatchError(ContainSubstring("invalid maximum manifest depth")))
+			})
+		})
+
+		Context("when trusted ops keys are configured", func() {
+			var (
+				deployment *bdc.BOSHDeployment
+				privateKey ed25519.PrivateKey
+				opsMap     *corev1.ConfigMap
+			)
+
+			BeforeEach(func() {
+				publicKey, key, err := ed25519.GenerateKey(nil)
+				Expect(err).ToNot(HaveOccurred())
+				privateKey = key
+
+				der, err := x509.MarshalPKIXPublicKey(publicKey)
+				Expect(err).ToNot(HaveOccurred())
+				keyFile, err := ioutil.TempFile("", "trusted-ops-keys")
+				Expect(err).ToNot(HaveOccurred())
+				defer keyFile.Close()
+				Expect(pem.Encode(keyFile, &pem.Block{Type: "PUBLIC KEY", Bytes: der})).To(Succeed())
+				Expect(withops.SetTrustedOpsKeys(keyFile.Name())).To(Succeed())
+				Expect(os.Remove(keyFile.Name())).To(Succeed())
+
+				opsMap = &corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "signed-ops",
+						Namespace:   "default",
+						Annotations: map[string]string{bdc.AnnotationOpsSignature: base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, []byte(replaceOpsStr)))},
+					},
+					Data: map[string]string{bdc.OpsSpecName: replaceOpsStr},
+				}
+
+				deployment = &bdc.BOSHDeployment{
+					Spec: bdc.BOSHDeploymentSpec{
+						Manifest: bdc.ResourceReference{Type: bdc.ConfigMapReference, Name: "base-manifest"},
+						Ops:      []bdc.ResourceReference{{Type: bdc.ConfigMapReference, Name: "signed-ops"}},
+					},
+				}
+				Expect(client.Create(context.Background(), opsMap)).To(Succeed())
+			})
+
+			AfterEach(func() {
+				Expect(withops.SetTrustedOpsKeys("")).To(Succeed())
+			})
+
+			It("accepts ops files signed by a trusted key", func() {
+				_, _, err := resolver.Manifest(deployment, "default")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(interpolator.BuildOpsCallCount()).To(Equal(1))
+			})
+
+			It("rejects unsigned ops files", func() {
+				opsMap.Annotations = nil
+				Expect(client.Update(context.Background(), opsMap)).To(Succeed())
+
+				_, _, err := resolver.Manifest(deployment, "default")
+				Expect(withops.IsUnsignedOps(err)).To(BeTrue())
+				Expect(err.Error()).To(ContainSubstring("ops file 'signed-ops' is not signed by a trusted signer: missing 'quarks.cloudfoundry.org/ops-signature' annotation"))
+				Expect(interpolator.BuildOpsCallCount()).To(Equal(0))
+			})
+
+			It("rejects ops files, whose signature doesn't match their data", func() {
+				opsMap.Data[bdc.OpsSpecName] = removeOpsStr
+				Expect(client.Update(context.Background(), opsMap)).To(Succeed())
+
+				_, _, err := resolver.ManifestDetailed(deployment, "default")
+				Expect(withops.IsUnsignedOps(err)).To(BeTrue())
+				Expect(err.Error()).To(ContainSubstring("signature doesn't match any trusted key"))
+			})
+
+			It("rejects url ops references", func() {
+				deployment.Spec.Ops = []bdc.ResourceReference{{Type: bdc.URLReference, Name: remoteFileServer.URL() + validOpsPath}}
+
+				_, _, err := resolver.Manifest(deployment, "default")
+				Expect(withops.IsUnsignedOps(err)).To(BeTrue())
+				Expect(err.Error()).To(ContainSubstring("url references can't be signed"))
+			})
+
+			It("rejects files without ed25519 keys", func() {
+				keyFile, err := ioutil.TempFile("", "trusted-ops-keys")
+				Expect(err).ToNot(HaveOccurred())
+				defer os.Remove(keyFile.Name())
+				Expect(keyFile.Close()).To(Succeed())
+
+				Expect(withops.SetTrustedOpsKeys(keyFile.Name())).To(MatchError(ContainSubstring("no public keys found")))
+			})
+		})
+
 		It("works for valid CRs by using secret", func() {
 			deployment := &bdc.BOSHDeployment{
 				Spec: bdc.BOSHDeploymentSpec{
@@ -771,6 +946,10 @@ instance_groups:
 			_, _, err := resolver.Manifest(deployment, "default")
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("failed to retrieve ops from secret"))
+
+			secretName, ok := withops.MissingSecret(err)
+			Expect(ok).To(BeTrue())
+			Expect(secretName).To(Equal("not-existing"))
 		})
 
 		It("throws an error if one url ref can not be found when contains multi-ops", func() {
@@ -834,7 +1013,7 @@ instance_groups:
 				dns, err = boshdns.NewDNS(n, m)
 				return dns, err
 			}
-			resolver = withops.NewResolver(client, newInterpolatorFunc, newDNSFunc)
+			resolver = withops.NewResolver(client, newInterpolatorFunc, newDNSFunc, bdnames.DefaultSecretNamer{})
 
 			deployment := &bdc.BOSHDeployment{
 				ObjectMeta: metav1.ObjectMeta{
@@ -919,5 +1098,441 @@ instance_groups:
 			Expect(sslProps["cert"]).To(Equal("the-cert"))
 			Expect(sslProps["key"]).To(Equal("the-key"))
 		})
+
+		Context("when a trusted CA bundle is configured", func() {
+			var (
+				tlsServer  *ghttp.Server
+				deployment *bdc.BOSHDeployment
+			)
+
+			BeforeEach(func() {
+				tlsServer = ghttp.NewTLSServer()
+				tlsServer.RouteToHandler("GET", "/internal-manifest.yml", ghttp.RespondWith(http.StatusOK, `---
+instance_groups:
+  - name: component7
+    instances: 1`))
+
+				deployment = &bdc.BOSHDeployment{
+					Spec: bdc.BOSHDeploymentSpec{
+						Manifest: bdc.ResourceReference{Type: bdc.URLReference, Name: tlsServer.URL() + "/internal-manifest.yml"},
+					},
+				}
+
+				withops.SetTrustedCABundle("internal-ca")
+			})
+
+			AfterEach(func() {
+				withops.SetTrustedCABundle("")
+				tlsServer.Close()
+			})
+
+			createCABundleSecret := func(bundle []byte) {
+				Expect(client.Create(context.Background(), &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "internal-ca", Namespace: "default"},
+					Data:       map[string][]byte{withops.TrustedCABundleKey: bundle},
+				})).To(Succeed())
+			}
+
+			It("trusts the CA bundle when requesting urls", func() {
+				cert := tlsServer.HTTPTestServer.Certificate()
+				createCABundleSecret(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+
+				manifest, _, err := resolver.Manifest(deployment, "default")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(manifest.InstanceGroups[0].Name).To(Equal("component7"))
+			})
+
+			It("doesn't trust the server without the CA bundle", func() {
+				withops.SetTrustedCABundle("")
+
+				_, _, err := resolver.Manifest(deployment, "default")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("certificate"))
+			})
+
+			It("throws an error if the CA bundle secret is missing", func() {
+				_, _, err := resolver.Manifest(deployment, "default")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("failed to retrieve trusted CA bundle secret 'default/internal-ca'"))
+			})
+		})
+
+		Context("when the url reference has an oauth token", func() {
+			var (
+				validToken   string
+				expiredToken string
+				refreshed    string
+				deployment   *bdc.BOSHDeployment
+			)
+
+			BeforeEach(func() {
+				validToken = jwt(time.Now().Add(time.Hour))
+				expiredToken = jwt(time.Now().Add(-time.Hour))
+				refreshed = jwt(time.Now().Add(2 * time.Hour))
+
+				remoteFileServer.RouteToHandler("POST", "/token", ghttp.CombineHandlers(
+					ghttp.VerifyFormKV("grant_type", "refresh_token"),
+					ghttp.VerifyFormKV("refresh_token", "the-refresh-token"),
+					ghttp.RespondWith(http.StatusOK, fmt.Sprintf(`{"access_token":"%s","refresh_token":"new-refresh-token"}`, refreshed)),
+				))
+
+				deployment = &bdc.BOSHDeployment{
+					Spec: bdc.BOSHDeploymentSpec{
+						Manifest: bdc.ResourceReference{
+							Type: bdc.URLReference,
+							Name: remoteFileServer.URL() + "/protected-manifest.yml",
+							OAuthTokenSecretRef: &corev1.SecretKeySelector{
+								LocalObjectReference: corev1.LocalObjectReference{Name: "oauth"},
+								Key:                  "token",
+							},
+						},
+					},
+				}
+			})
+
+			createTokenSecretWithExpiry := func(token string, expiresAt string) {
+				data := map[string][]byte{
+					"token":                      []byte(token),
+					withops.OAuthRefreshTokenKey: []byte("the-refresh-token"),
+					withops.OAuthTokenURLKey:     []byte(remoteFileServer.URL() + "/token"),
+				}
+				if expiresAt != "" {
+					data[withops.OAuthTokenExpiresAtKey] = []byte(expiresAt)
+				}
+				Expect(client.Create(context.Background(), &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "oauth", Namespace: "default"},
+					Data:       data,
+				})).To(Succeed())
+			}
+
+			createTokenSecret := func(token string) {
+				createTokenSecretWithExpiry(token, "")
+			}
+
+			routeProtectedManifest := func(token string) {
+				remoteFileServer.RouteToHandler("GET", "/protected-manifest.yml", ghttp.CombineHandlers(
+					ghttp.VerifyHeaderKV("Authorization", "Bearer "+token),
+					ghttp.RespondWith(http.StatusOK, `---
+instance_groups:
+  - name: component6
+    instances: 1`),
+				))
+			}
+
+			It("passes a valid token as bearer", func() {
+				createTokenSecret(validToken)
+				routeProtectedManifest(validToken)
+
+				manifest, _, err := resolver.Manifest(deployment, "default")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(manifest.InstanceGroups[0].Name).To(Equal("component6"))
+			})
+
+			It("refreshes an expired token and stores it", func() {
+				createTokenSecret(expiredToken)
+				routeProtectedManifest(refreshed)
+
+				manifest, _, err := resolver.Manifest(deployment, "default")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(manifest.InstanceGroups[0].Name).To(Equal("component6"))
+
+				secret := &corev1.Secret{}
+				Expect(client.Get(context.Background(), types.NamespacedName{Name: "oauth", Namespace: "default"}, secret)).To(Succeed())
+				Expect(string(secret.Data["token"])).To(Equal(refreshed))
+				Expect(string(secret.Data[withops.OAuthRefreshTokenKey])).To(Equal("new-refresh-token"))
+			})
+
+			It("passes an opaque token without known expiry as bearer", func() {
+				createTokenSecret("opaque-token")
+				routeProtectedManifest("opaque-token")
+
+				_, _, err := resolver.Manifest(deployment, "default")
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("passes an opaque token, which is not expired yet, as bearer", func() {
+				createTokenSecretWithExpiry("opaque-token", time.Now().Add(time.Hour).UTC().Format(time.RFC3339))
+				routeProtectedManifest("opaque-token")
+
+				_, _, err := resolver.Manifest(deployment, "default")
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("refreshes an expired opaque token and stores its expiry from expires_in", func() {
+				remoteFileServer.RouteToHandler("POST", "/token", ghttp.RespondWith(http.StatusOK, `{"access_token":"new-opaque-token","expires_in":3600}`))
+				createTokenSecretWithExpiry("opaque-token", time.Now().Add(-time.Minute).UTC().Format(time.RFC3339))
+				routeProtectedManifest("new-opaque-token")
+
+				_, _, err := resolver.Manifest(deployment, "default")
+				Expect(err).ToNot(HaveOccurred())
+
+				secret := &corev1.Secret{}
+				Expect(client.Get(context.Background(), types.NamespacedName{Name: "oauth", Namespace: "default"}, secret)).To(Succeed())
+				Expect(string(secret.Data["token"])).To(Equal("new-opaque-token"))
+				expiresAt, err := time.Parse(time.RFC3339, string(secret.Data[withops.OAuthTokenExpiresAtKey]))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(expiresAt).To(BeTemporally("~", time.Now().Add(time.Hour), time.Minute))
+			})
+
+			It("throws an error if the stored expiry is invalid", func() {
+				createTokenSecretWithExpiry("opaque-token", "tomorrow")
+
+				_, _, err := resolver.Manifest(deployment, "default")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("failed to parse expires_at"))
+			})
+
+			It("throws an error if the url rejects the token", func() {
+				createTokenSecret(validToken)
+				remoteFileServer.RouteToHandler("GET", "/protected-manifest.yml", ghttp.RespondWith(http.StatusUnauthorized, ""))
+
+				_, _, err := resolver.Manifest(deployment, "default")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("401 Unauthorized"))
+			})
+		})
+
+		Context("when the manifest is a git reference", func() {
+			var (
+				repo        string
+				firstCommit string
+				deployment  *bdc.BOSHDeployment
+			)
+
+			gitManifest := func(name string) string {
+				return fmt.Sprintf("---\ninstance_groups:\n  - name: %s\n    instances: 1\n", name)
+			}
+
+			BeforeEach(func() {
+				var err error
+				repo, err = ioutil.TempDir("", "git-reference-test-")
+				Expect(err).ToNot(HaveOccurred())
+
+				out, err := exec.Command("git", "init", "-q", repo).CombinedOutput()
+				Expect(err).ToNot(HaveOccurred(), string(out))
+				out, err = exec.Command("git", "-C", repo, "config", "uploadpack.allowAnySHA1InWant", "true").CombinedOutput()
+				Expect(err).ToNot(HaveOccurred(), string(out))
+
+				firstCommit = gitCommit(repo, "deploy/manifest.yml", gitManifest("component7"))
+				gitCommit(repo, "deploy/manifest.yml", gitManifest("component8"))
+
+				deployment = &bdc.BOSHDeployment{
+					Spec: bdc.BOSHDeploymentSpec{
+						Manifest: bdc.ResourceReference{
+							Type: bdc.GitReference,
+							Name: "file://" + repo,
+							Git:  &bdc.GitSource{Path: "deploy/manifest.yml"},
+						},
+					},
+				}
+			})
+
+			AfterEach(func() {
+				Expect(os.RemoveAll(repo)).To(Succeed())
+			})
+
+			It("reads the manifest from the remote's HEAD", func() {
+				manifest, _, err := resolver.Manifest(deployment, "default")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(manifest.InstanceGroups[0].Name).To(Equal("component8"))
+			})
+
+			It("reads the manifest from the pinned commit", func() {
+				deployment.Spec.Manifest.Git.Commit = firstCommit
+
+				manifest, _, err := resolver.Manifest(deployment, "default")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(manifest.InstanceGroups[0].Name).To(Equal("component7"))
+			})
+
+			It("reads the manifest from the ref", func() {
+				out, err := exec.Command("git", "-C", repo, "tag", "v1", firstCommit).CombinedOutput()
+				Expect(err).ToNot(HaveOccurred(), string(out))
+				deployment.Spec.Manifest.Git.Ref = "v1"
+
+				manifest, _, err := resolver.Manifest(deployment, "default")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(manifest.InstanceGroups[0].Name).To(Equal("component7"))
+			})
+
+			It("reads ops files from git references", func() {
+				gitCommit(repo, "deploy/ops.yml", urlOpsStr)
+				interpolator.InterpolateReturns([]byte(gitManifest("component8")), nil)
+				deployment.Spec.Ops = []bdc.ResourceReference{
+					{
+						Type: bdc.GitReference,
+						Name: "file://" + repo,
+						Git:  &bdc.GitSource{Path: "/deploy/ops.yml"},
+					},
+				}
+
+				_, _, err := resolver.Manifest(deployment, "default")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(interpolator.BuildOpsCallCount()).To(Equal(1))
+				Expect(string(interpolator.BuildOpsArgsForCall(0))).To(Equal(urlOpsStr))
+			})
+
+			It("throws an error if the file doesn't exist", func() {
+				deployment.Spec.Manifest.Git.Path = "missing.yml"
+
+				_, _, err := resolver.Manifest(deployment, "default")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("failed to read 'missing.yml' of manifest git reference"))
+			})
+
+			It("throws an error if the repository can't be fetched", func() {
+				deployment.Spec.Manifest.Name = "file://" + repo + "-missing"
+
+				_, _, err := resolver.Manifest(deployment, "default")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("failed to fetch 'HEAD' of manifest git reference"))
+			})
+
+			It("reads cached files without fetching", func() {
+				deployment.Spec.Manifest.Git.Commit = firstCommit
+				cached := resolver.WithCachedGitReferences()
+
+				_, _, err := cached.Manifest(deployment, "default")
+				Expect(err).To(HaveOccurred())
+				Expect(withops.IsGitReferenceNotCached(err)).To(BeTrue())
+
+				_, _, err = resolver.Manifest(deployment, "default")
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(os.RemoveAll(repo)).To(Succeed())
+				manifest, _, err := cached.Manifest(deployment, "default")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(manifest.InstanceGroups[0].Name).To(Equal("component7"))
+			})
+
+			It("throws an error if the reference has no path", func() {
+				deployment.Spec.Manifest.Git = nil
+
+				_, _, err := resolver.Manifest(deployment, "default")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("manifest git reference 'file://" + repo + "' has no path"))
+			})
+
+			It("throws an error if the auth secret has no credentials", func() {
+				Expect(client.Create(context.Background(), &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "git-auth", Namespace: "default"},
+					Data:       map[string][]byte{"token": []byte("foo")},
+				})).To(Succeed())
+				deployment.Spec.Manifest.Git.AuthSecretName = "git-auth"
+
+				_, _, err := resolver.Manifest(deployment, "default")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("must contain either key ssh-privatekey or keys username and password"))
+			})
+
+			It("throws an error if the auth secret has an SSH key without known hosts", func() {
+				Expect(client.Create(context.Background(), &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "git-auth", Namespace: "default"},
+					Data:       map[string][]byte{corev1.SSHAuthPrivateKey: []byte("key")},
+				})).To(Succeed())
+				deployment.Spec.Manifest.Git.AuthSecretName = "git-auth"
+
+				_, _, err := resolver.Manifest(deployment, "default")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("doesn't contain key known_hosts"))
+			})
+		})
+	})
+
+	Describe("NoOpOps", func() {
+		var deployment *bdc.BOSHDeployment
+
+		BeforeEach(func() {
+			resolver = withops.NewResolver(
+				client,
+				func() withops.Interpolator { return withops.NewInterpolator() },
+				func(n string, m bdm.Manifest) (withops.DomainNameService, error) {
+					return boshdns.NewSimpleDomainNameService(""), nil
+				},
+				bdnames.DefaultSecretNamer{},
+			)
+			deployment = &bdc.BOSHDeployment{
+				Spec: bdc.BOSHDeploymentSpec{
+					Manifest: bdc.ResourceReference{Type: bdc.ConfigMapReference, Name: "base-manifest"},
+				},
+			}
+		})
+
+		It("returns no ops files, if all of them change the manifest", func() {
+			deployment.Spec.Ops = []bdc.ResourceReference{
+				{Name: "replace-ops", Type: bdc.ConfigMapReference},
+				{Name: "remove-ops", Type: bdc.ConfigMapReference},
+			}
+
+			noOps, err := resolver.NoOpOps(deployment, "default")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(noOps).To(BeEmpty())
+		})
+
+		It("returns the ops files, which leave the manifest unchanged", func() {
+			deployment.Spec.Ops = []bdc.ResourceReference{
+				{Name: "replace-ops", Type: bdc.ConfigMapReference},
+				{Name: "opaque-ops", Type: bdc.SecretReference},
+				{Name: "remove-ops", Type: bdc.ConfigMapReference},
+				{Name: "remove-ops", Type: bdc.ConfigMapReference},
+			}
+
+			noOps, err := resolver.NoOpOps(deployment, "default")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(noOps).To(Equal([]string{"remove-ops"}))
+		})
+
+		It("fails for missing ops files", func() {
+			deployment.Spec.Ops = []bdc.ResourceReference{{Name: "missing-ops", Type: bdc.ConfigMapReference}}
+
+			_, err := resolver.NoOpOps(deployment, "default")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("CloudConfig", func() {
+		var deployment *bdc.BOSHDeployment
+
+		BeforeEach(func() {
+			deployment = &bdc.BOSHDeployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+				Spec: bdc.BOSHDeploymentSpec{
+					Manifest:    bdc.ResourceReference{Type: bdc.ConfigMapReference, Name: "base-manifest"},
+					CloudConfig: &bdc.ResourceReference{Type: bdc.ConfigMapReference, Name: "cloud-config"},
+				},
+			}
+		})
+
+		It("returns no cloud config, if the deployment references none", func() {
+			deployment.Spec.CloudConfig = nil
+
+			cloudConfig, err := resolver.CloudConfig(deployment, "default")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cloudConfig).To(BeNil())
+		})
+
+		It("loads the referenced cloud config", func() {
+			err := client.Create(context.Background(), &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "cloud-config", Namespace: "default"},
+				Data: map[string]string{bdc.CloudConfigSpecName: `---
+networks:
+- name: default
+cloud_properties:
+  default_vm_type: small
+`},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			cloudConfig, err := resolver.CloudConfig(deployment, "default")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cloudConfig.Networks).To(Equal([]bdm.CloudConfigNetwork{{Name: "default"}}))
+			Expect(cloudConfig.DefaultVMType()).To(Equal("small"))
+		})
+
+		It("fails for missing cloud configs", func() {
+			_, err := resolver.CloudConfig(deployment, "default")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Failed to get cloud config of bosh deployment 'foo'"))
+		})
 	})
 })
@@ -0,0 +1,26 @@
+package withops
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// SecretNotFoundError is returned by the resolver, when a secret referenced
+// as manifest, ops file or implicit variable doesn't exist
+type SecretNotFoundError struct {
+	Namespace string
+	Name      string
+}
+
+func (e *SecretNotFoundError) Error() string {
+	return fmt.Sprintf("secret '%s/%s' not found", e.Namespace, e.Name)
+}
+
+// MissingSecret returns the name of the missing secret, if the error is caused by a SecretNotFoundError
+func MissingSecret(err error) (string, bool) {
+	if e, ok := errors.Cause(err).(*SecretNotFoundError); ok {
+		return e.Name, true
+	}
+	return "", false
+}
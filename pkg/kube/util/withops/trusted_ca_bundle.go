@@ -0,0 +1,117 @@
+package withops
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// TrustedCABundleKey is the key of the CA bundle in the trusted CA bundle secret
+const TrustedCABundleKey = "ca.crt"
+
+// trustedCABundleSecret is the name of the secret in the watched namespace,
+// whose CA bundle is trusted when fetching URL and git references, in
+// addition to the system CAs. Empty disables the CA bundle.
+var trustedCABundleSecret string
+
+// SetTrustedCABundle initializes the package scoped trusted CA bundle secret name
+func SetTrustedCABundle(secret string) {
+	trustedCABundleSecret = secret
+}
+
+// systemCABundleFiles are the locations of the system CA bundle, which git
+// trusts unless http.sslCAInfo is configured
+var systemCABundleFiles = []string{
+	"/etc/ssl/ca-bundle.pem",
+	"/etc/ssl/certs/ca-certificates.crt",
+	"/etc/pki/tls/certs/ca-bundle.crt",
+	"/etc/pki/ca-trust/extracted/pem/tls-ca-bundle.pem",
+	"/etc/ssl/cert.pem",
+}
+
+// trustedCABundle returns the PEM encoded CA bundle of the trusted CA bundle
+// secret in namespace, or nil if no secret is configured
+func (r *Resolver) trustedCABundle(namespace string) ([]byte, error) {
+	if trustedCABundleSecret == "" {
+		return nil, nil
+	}
+
+	secret := &corev1.Secret{}
+	err := r.client.Get(r.ctx, types.NamespacedName{Name: trustedCABundleSecret, Namespace: namespace}, secret)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to retrieve trusted CA bundle secret '%s/%s' via client.Get", namespace, trustedCABundleSecret)
+	}
+
+	bundle, ok := secret.Data[TrustedCABundleKey]
+	if !ok {
+		return nil, fmt.Errorf("trusted CA bundle secret '%s/%s' doesn't contain key %s", namespace, trustedCABundleSecret, TrustedCABundleKey)
+	}
+
+	return bundle, nil
+}
+
+// httpClient returns the client for URL references in namespace. It trusts
+// the system CAs and the trusted CA bundle.
+func (r *Resolver) httpClient(namespace string) (*http.Client, error) {
+	bundle, err := r.trustedCABundle(namespace)
+	if err != nil {
+		return nil, err
+	}
+	if bundle == nil {
+		return http.DefaultClient, nil
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(bundle) {
+		return nil, fmt.Errorf("trusted CA bundle secret '%s/%s' doesn't contain PEM encoded certificates", namespace, trustedCABundleSecret)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	return &http.Client{Transport: transport}, nil
+}
+
+// gitCAConfig returns the git configuration arguments, which make git trust
+// the system CAs and the trusted CA bundle. The combined bundle is written
+// into dir, since http.sslCAInfo replaces git's system CA bundle.
+func (r *Resolver) gitCAConfig(namespace string, dir string) ([]string, error) {
+	bundle, err := r.trustedCABundle(namespace)
+	if err != nil {
+		return nil, err
+	}
+	if bundle == nil {
+		return []string{}, nil
+	}
+
+	combined := []byte{}
+	for _, path := range systemCABundleFiles {
+		system, err := ioutil.ReadFile(path)
+		if err == nil {
+			combined = append(system, '\n')
+			break
+		}
+		if !os.IsNotExist(err) {
+			return nil, errors.Wrapf(err, "failed to read system CA bundle '%s'", path)
+		}
+	}
+	combined = append(combined, bundle...)
+
+	bundlePath := filepath.Join(dir, TrustedCABundleKey)
+	if err := ioutil.WriteFile(bundlePath, combined, 0600); err != nil {
+		return nil, errors.Wrap(err, "failed to write trusted CA bundle")
+	}
+
+	return []string{"-c", "http.sslCAInfo=" + bundlePath}, nil
+}
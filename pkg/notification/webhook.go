@@ -0,0 +1,98 @@
+// Package notification notifies external systems about phase transitions of BOSHDeployments
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	log "code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+// PhaseTransition is the payload, which is posted when the phase of a BOSHDeployment changes
+type PhaseTransition struct {
+	Deployment    string    `json:"deployment"`
+	Namespace     string    `json:"namespace"`
+	Phase         string    `json:"phase"`
+	PreviousPhase string    `json:"previousPhase"`
+	Reason        string    `json:"reason,omitempty"`
+	Time          time.Time `json:"time"`
+}
+
+// Notifier notifies external systems about phase transitions
+type Notifier interface {
+	Notify(ctx context.Context, transition PhaseTransition)
+}
+
+// retryInterval is the delay before the first retry, it doubles with every retry
+var retryInterval = time.Second
+
+// NewWebhookNotifier returns a notifier, which posts phase transitions as
+// JSON to url. Each attempt times out after timeout, failed attempts are
+// retried up to retries times.
+func NewWebhookNotifier(url string, timeout time.Duration, retries int) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:     url,
+		retries: retries,
+		http:    &http.Client{Timeout: timeout},
+	}
+}
+
+// WebhookNotifier posts phase transitions to a webhook
+type WebhookNotifier struct {
+	url     string
+	retries int
+	http    *http.Client
+}
+
+// Notify posts the transition in the background. It doesn't block the
+// caller, failures are only logged.
+func (n *WebhookNotifier) Notify(ctx context.Context, transition PhaseTransition) {
+	go func() {
+		err := n.Post(ctx, transition)
+		if err != nil {
+			log.Errorf(ctx, "Failed to notify about phase transition of BOSHDeployment '%s/%s': %v", transition.Namespace, transition.Deployment, err)
+		}
+	}()
+}
+
+// Post posts the transition and retries failed attempts
+func (n *WebhookNotifier) Post(ctx context.Context, transition PhaseTransition) error {
+	body, err := json.Marshal(transition)
+	if err != nil {
+		return errors.Wrap(err, "marshalling phase transition")
+	}
+
+	interval := retryInterval
+	for attempt := 0; ; attempt++ {
+		err = n.post(body)
+		if err == nil || attempt >= n.retries {
+			return err
+		}
+
+		log.Debugf(ctx, "Retrying phase transition notification of BOSHDeployment '%s/%s' after %s: %v", transition.Namespace, transition.Deployment, interval, err)
+		time.Sleep(interval)
+		interval *= 2
+	}
+}
+
+func (n *WebhookNotifier) post(body []byte) error {
+	resp, err := n.http.Post(n.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "posting phase transition")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		respBody, _ := ioutil.ReadAll(resp.Body)
+		return errors.Errorf("posting phase transition failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	return nil
+}
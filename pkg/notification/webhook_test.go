@@ -0,0 +1,101 @@
+package notification_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/cf-operator/pkg/notification"
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+	helper "code.cloudfoundry.org/quarks-utils/testing/testhelper"
+)
+
+var _ = Describe("WebhookNotifier", func() {
+	var (
+		ctx        context.Context
+		server     *httptest.Server
+		mu         sync.Mutex
+		statuses   []int
+		bodies     []string
+		transition notification.PhaseTransition
+	)
+
+	requests := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string{}, bodies...)
+	}
+
+	BeforeEach(func() {
+		_, log := helper.NewTestLogger()
+		ctx = ctxlog.NewParentContext(log)
+		statuses = []int{}
+		bodies = []string{}
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, err := ioutil.ReadAll(r.Body)
+			Expect(err).ToNot(HaveOccurred())
+
+			mu.Lock()
+			defer mu.Unlock()
+			bodies = append(bodies, string(b))
+			status := http.StatusOK
+			if len(statuses) > 0 {
+				status, statuses = statuses[0], statuses[1:]
+			}
+			w.WriteHeader(status)
+		}))
+
+		transition = notification.PhaseTransition{
+			Deployment:    "foo",
+			Namespace:     "default",
+			Phase:         "Degraded",
+			PreviousPhase: "Applied",
+			Reason:        "fake-error",
+			Time:          time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC),
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("posts the phase transition", func() {
+		err := notification.NewWebhookNotifier(server.URL, time.Second, 0).Post(ctx, transition)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(requests()).To(HaveLen(1))
+		Expect(requests()[0]).To(MatchJSON(`{
+			"deployment": "foo",
+			"namespace": "default",
+			"phase": "Degraded",
+			"previousPhase": "Applied",
+			"reason": "fake-error",
+			"time": "2020-03-01T12:00:00Z"
+		}`))
+	})
+
+	It("retries failed attempts", func() {
+		statuses = []int{http.StatusServiceUnavailable}
+
+		err := notification.NewWebhookNotifier(server.URL, time.Second, 1).Post(ctx, transition)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(requests()).To(HaveLen(2))
+	})
+
+	It("fails once the retries are exhausted", func() {
+		statuses = []int{http.StatusInternalServerError}
+
+		err := notification.NewWebhookNotifier(server.URL, time.Second, 0).Post(ctx, transition)
+		Expect(err).To(MatchError(ContainSubstring("failed with status 500")))
+	})
+
+	It("notifies in the background", func() {
+		notification.NewWebhookNotifier(server.URL, time.Second, 0).Notify(ctx, transition)
+		Eventually(requests).Should(HaveLen(1))
+	})
+})